@@ -7,12 +7,29 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/gofiber/swagger"
 	"github.com/nihrom205/idm/docs"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/accountrecon"
+	"github.com/nihrom205/idm/inner/application"
+	"github.com/nihrom205/idm/inner/assignment"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/breakglass"
 	"github.com/nihrom205/idm/inner/common"
 	validator2 "github.com/nihrom205/idm/inner/common/validator"
 	database2 "github.com/nihrom205/idm/inner/database"
+	"github.com/nihrom205/idm/inner/delegation"
 	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/group"
 	"github.com/nihrom205/idm/inner/info"
+	"github.com/nihrom205/idm/inner/keycloak"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/nihrom205/idm/inner/me"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"github.com/nihrom205/idm/inner/reconcile"
 	"github.com/nihrom205/idm/inner/role"
+	"github.com/nihrom205/idm/inner/rule"
+	"github.com/nihrom205/idm/inner/scheduler"
+	"github.com/nihrom205/idm/inner/scim"
+	"github.com/nihrom205/idm/inner/scopedadmin"
 	"github.com/nihrom205/idm/inner/web"
 	"go.uber.org/zap"
 	"os/signal"
@@ -43,7 +60,14 @@ func main() {
 	// Отложенный вызов записи сообщений из буфера в лог. Необходимо вызывать перед выходом из приложения
 	defer func() { _ = logger.Sync() }()
 
-	server := build(cfg, logger)
+	server, infoController, jobs := build(cfg, logger)
+
+	// запускаем фоновое выполнение запланированных смен статуса сотрудников, начала и окончания замещений,
+	// истечения экстренного доступа, провижининга, сверки учётных записей, синхронизации ролей с Keycloak
+	// и очистки устаревших корзин лимитов и ключей идемпотентности
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	go jobs.Run(workerCtx)
+
 	go func() {
 		// загружаем сертификаты
 		cer, err := tls.LoadX509KeyPair(cfg.SslCert, cfg.SslKey)
@@ -70,13 +94,15 @@ func main() {
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
 	// Запускаем gracefulShutdown в отдельной горутине
-	go gracefulShutdown(server, wg, logger)
+	go gracefulShutdown(server, infoController, cfg.ShutdownDrainDelay, wg, logger)
 	// Ожидаем сигнал от горутины gracefulShutdown, что сервер завершил работу
 	wg.Wait()
+	stopWorker()
 	logger.Info("Graceful shutdown complete.")
 }
 
-func gracefulShutdown(server *web.Server, wg *sync.WaitGroup, logger *common.Logger) {
+func gracefulShutdown(server *web.Server, infoController *info.Controller, drainDelay time.Duration, wg *sync.WaitGroup,
+	logger *common.Logger) {
 	// Уведомить основную горутину о завершении работы
 	defer wg.Done()
 	// Создаём контекст, который слушает сигналы прерывания от операционной системы
@@ -85,6 +111,11 @@ func gracefulShutdown(server *web.Server, wg *sync.WaitGroup, logger *common.Log
 	// Слушаем сигнал прерывания от операционной системы
 	<-ctx.Done()
 	logger.Info("shutting down gracefully")
+	// сразу переводим readiness probe в состояние "недоступен", чтобы балансировщик вывел нас из-под нагрузки
+	infoController.SetShuttingDown()
+	// ждём, пока балансировщик увидит 503 от readiness probe, и только потом перестаём принимать соединения
+	logger.Info("draining connections", zap.Duration("delay", drainDelay))
+	time.Sleep(drainDelay)
 	// Контекст используется для информирования веб-сервера о том,
 	// что у него есть 5 секунд на выполнение запроса, который он обрабатывает в данный момент
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -95,13 +126,13 @@ func gracefulShutdown(server *web.Server, wg *sync.WaitGroup, logger *common.Log
 	logger.Info("Server exiting")
 }
 
-func build(cfg common.Config, logger *common.Logger) *web.Server {
+func build(cfg common.Config, logger *common.Logger) (*web.Server, *info.Controller, *scheduler.Scheduler) {
 
 	// Создаём подключение к базе данных
 	db := database2.ConnectDbWithCfg(cfg)
 
 	// создаём веб-сервер
-	server := web.NewServer()
+	server := web.NewServer(logger)
 
 	// Swagger UI
 	server.App.Use("/swagger/*", swagger.HandlerDefault)
@@ -109,10 +140,55 @@ func build(cfg common.Config, logger *common.Logger) *web.Server {
 	server.App.Use(requestid.New())
 	server.App.Use(recover.New())
 	server.GroupApi.Use(web.AuthMiddleware(logger))
+	server.GroupScim.Use(scim.ErrorMiddleware(logger), web.AuthMiddleware(logger))
+
+	// ограничение частоты запросов клиентов
+	rateLimitCfg, err := web.ParseRateLimitConfig(cfg.RateLimitDefault, cfg.RateLimitRoutes)
+	if err != nil {
+		logger.Panic("invalid rate limit config", zap.Error(err))
+	}
+	// фоновые задачи, каждая со своим интервалом
+	jobs := scheduler.NewScheduler(logger)
+
+	var rateLimitStore web.RateLimitStore = web.NewMemoryRateLimitStore()
+	if cfg.RateLimitStore == "postgres" {
+		postgresRateLimitStore := web.NewPostgresRateLimitStore(db)
+		jobs.Add("rate-limit-purge", cfg.RateLimitPurgeInterval, postgresRateLimitStore.DeleteExpired)
+		rateLimitStore = postgresRateLimitStore
+	}
+	server.GroupApi.Use(web.RateLimitMiddleware(rateLimitCfg, rateLimitStore, logger))
+	server.GroupScim.Use(web.RateLimitMiddleware(rateLimitCfg, rateLimitStore, logger))
+
+	// повторные POST/DELETE запросы с заголовком Idempotency-Key получают сохранённый ответ
+	var idempotencyStore web.IdempotencyStore = web.NewMemoryIdempotencyStore()
+	if cfg.IdempotencyStore == "postgres" {
+		postgresIdempotencyStore := web.NewPostgresIdempotencyStore(db)
+		jobs.Add("idempotency-purge", cfg.IdempotencyPurgeInterval, postgresIdempotencyStore.DeleteExpired)
+		idempotencyStore = postgresIdempotencyStore
+	}
+	server.GroupApi.Use(web.IdempotencyMiddleware(idempotencyStore, cfg.IdempotencyTtl, cfg.IdempotencyLockTtl, logger))
+	// ошибки обработчиков SCIM переводятся в формат SCIM до сохранения ответа
+	server.GroupScim.Use(web.IdempotencyMiddleware(idempotencyStore, cfg.IdempotencyTtl, cfg.IdempotencyLockTtl, logger),
+		scim.ErrorMiddleware(logger))
 
 	// создаём репозиторий
 	employeeRepo := employee.NewEmployeeRepository(db)
 	roleRepo := role.NewRoleRepository(db)
+	assignmentRepo := assignment.NewAssignmentRepository(db)
+	reconcileRepo := reconcile.NewReconcileRepository(db)
+	auditRepo := audit.NewAuditRepository(db)
+	lifecycleRepo := lifecycle.NewLifecycleRepository(db)
+	ruleRepo := rule.NewRuleRepository(db)
+	groupRepo := group.NewGroupRepository(db)
+	accessRepo := access.NewAccessRepository(db)
+	scopedAdminRepo := scopedadmin.NewScopedAdminRepository(db)
+	delegationRepo := delegation.NewDelegationRepository(db)
+	breakGlassRepo := breakglass.NewBreakGlassRepository(db)
+	applicationRepo := application.NewApplicationRepository(db)
+	provisioningRepo := provisioning.NewProvisioningRepository(db)
+	accountReconRepo := accountrecon.NewAccountReconRepository(db)
+	keycloakRepo := keycloak.NewKeycloakRepository(db)
+	scimRepo := scim.NewScimRepository(db)
 
 	// создаём валидатор
 	vld := validator2.NewValidator()
@@ -120,6 +196,66 @@ func build(cfg common.Config, logger *common.Logger) *web.Server {
 	// создаём сервис
 	employeeService := employee.NewService(employeeRepo, vld)
 	roleService := role.NewService(roleRepo, vld)
+	assignmentService := assignment.NewService(assignmentRepo, vld)
+	lifecycleService := lifecycle.NewService(lifecycleRepo, auditRepo, vld)
+	ruleService := rule.NewService(ruleRepo, auditRepo, vld)
+	// роли по правилам назначаются при создании сотрудника, смене его атрибутов и активации
+	employeeService.SetRoleRules(ruleService)
+	// удаление сотрудника заменяется увольнением с действиями жизненного цикла
+	employeeService.SetLifecycle(lifecycleService)
+	// пользователи видят только себя, свой отдел и подчинённых, администраторы - всех
+	visibility, err := employee.ParseVisibilityPolicies(cfg.VisibilityPolicies)
+	if err != nil {
+		logger.Panic("invalid visibility policies config", zap.Error(err))
+	}
+	employeeService.SetVisibility(visibility)
+	lifecycleService.AddHook(ruleService.Hook)
+	groupService := group.NewService(groupRepo, auditRepo, vld)
+	// уволенный сотрудник исключается из групп и теряет их роли
+	lifecycleService.AddHook(groupService.Hook)
+	accessService := access.NewService(accessRepo)
+	// права в целевых системах вычисляются по фактическим ролям сотрудника
+	applicationService := application.NewService(applicationRepo, auditRepo, accessService, vld)
+	// изменения ролей и статусов сотрудников отправляются в целевые системы через очередь провижининга
+	provisioningRegistry, err := provisioning.NewFileRegistry(cfg.ProvisioningDir, cfg.ProvisioningFormat,
+		cfg.ProvisioningApplications)
+	if err != nil {
+		logger.Panic("invalid provisioning config", zap.Error(err))
+	}
+	provisioningService := provisioning.NewService(provisioningRepo, auditRepo, applicationService, provisioningRegistry,
+		cfg.ProvisioningMaxAttempts)
+	assignmentService.SetChangeListener(provisioningService)
+	// назначения по правилам и группам, а также состав прав ролей меняют права сотрудников так же, как ручные назначения
+	ruleService.SetChangeListener(provisioningService)
+	groupService.SetChangeListener(provisioningService)
+	applicationService.SetChangeListener(provisioningService)
+	lifecycleService.AddHook(provisioningService.Hook)
+	// учётные записи целевых систем сверяются с правами сотрудников по расписанию
+	accountReconService := accountrecon.NewService(accountReconRepo, auditRepo, applicationService, provisioningRegistry, vld)
+	// администраторы отделов создают сотрудников и назначают разрешённые роли только в своих отделах
+	scopedAdminService := scopedadmin.NewService(scopedAdminRepo, auditRepo, accessService, vld)
+	employeeService.SetAuthorizer(scopedAdminService)
+	assignmentService.SetAuthorizer(scopedAdminService)
+	delegationService := delegation.NewService(delegationRepo, auditRepo, accessService, delegation.NewLogNotifier(logger), vld,
+		cfg.DelegationMaxDuration)
+	delegationService.SetChangeListener(provisioningService)
+	// замещения уволенного сотрудника отменяются
+	lifecycleService.AddHook(delegationService.Hook)
+	// экстренный доступ выдаётся сразу, отзывается по истечении срока и требует разбора после инцидента
+	breakGlassService := breakglass.NewService(breakGlassRepo, auditRepo, accessService, breakglass.NewLogAlerter(logger), vld,
+		breakglass.ParseRoles(cfg.BreakGlassRoles), cfg.BreakGlassMaxTtl)
+	breakGlassService.SetChangeListener(provisioningService)
+	meService := me.NewService(employeeService, accessService, cfg.MeUnknownSubject)
+	scimService := scim.NewService(scimRepo, employeeService, roleService, assignmentService, lifecycleService)
+	reconcileService := reconcile.NewService(reconcileRepo, auditRepo, lifecycleService, vld)
+	// роли и их назначения синхронизируются с ролями области Keycloak, если задан его адрес
+	var keycloakApi keycloak.Api
+	if cfg.KeycloakAdminUrl != "" {
+		keycloakApi = keycloak.NewClient(cfg.KeycloakAdminUrl, cfg.KeycloakRealm, cfg.KeycloakClientId,
+			cfg.KeycloakClientSecret, nil)
+	}
+	keycloakService := keycloak.NewService(keycloakRepo, auditRepo, accessService, keycloakApi, vld,
+		cfg.KeycloakSyncDirection)
 
 	// создаём контроллер employee
 	employeeController := employee.NewController(server, employeeService, logger)
@@ -129,9 +265,80 @@ func build(cfg common.Config, logger *common.Logger) *web.Server {
 	roleController := role.NewController(server, roleService, logger)
 	roleController.RegisterRoutes()
 
+	// создаём контроллер назначений ролей
+	assignmentController := assignment.NewController(server, assignmentService, logger)
+	assignmentController.RegisterRoutes()
+
+	// создаём контроллер правил назначения ролей
+	ruleController := rule.NewController(server, ruleService, logger)
+	ruleController.RegisterRoutes()
+
+	// создаём контроллер групп
+	groupController := group.NewController(server, groupService, logger)
+	groupController.RegisterRoutes()
+
+	// создаём контроллер фактического доступа сотрудников
+	accessController := access.NewController(server, accessService, employeeService, logger)
+	accessController.RegisterRoutes()
+
+	// создаём контроллер каталога целевых систем и их прав
+	applicationController := application.NewController(server, applicationService, employeeService, logger)
+	applicationController.RegisterRoutes()
+
+	// создаём контроллер очереди провижининга
+	provisioningController := provisioning.NewController(server, provisioningService, logger)
+	provisioningController.RegisterRoutes()
+
+	// создаём контроллер сверки учётных записей целевых систем
+	accountReconController := accountrecon.NewController(server, accountReconService, logger)
+	accountReconController.RegisterRoutes()
+
+	// создаём контроллер администраторов отделов
+	scopedAdminController := scopedadmin.NewController(server, scopedAdminService, logger)
+	scopedAdminController.RegisterRoutes()
+
+	// создаём контроллер замещений
+	delegationController := delegation.NewController(server, delegationService, logger)
+	delegationController.RegisterRoutes()
+
+	// создаём контроллер экстренного доступа
+	breakGlassController := breakglass.NewController(server, breakGlassService, logger)
+	breakGlassController.RegisterRoutes()
+
+	// создаём контроллер самообслуживания сотрудника по токену
+	meController := me.NewController(server, meService, logger)
+	meController.RegisterRoutes()
+
+	// создаём контроллер жизненного цикла сотрудников
+	lifecycleController := lifecycle.NewController(server, lifecycleService, logger)
+	lifecycleController.RegisterRoutes()
+
+	// создаём контроллер сверки с выгрузкой HR
+	reconcileController := reconcile.NewController(server, reconcileService, logger)
+	reconcileController.RegisterRoutes()
+
+	// создаём контроллер синхронизации ролей с Keycloak
+	keycloakController := keycloak.NewController(server, keycloakService, logger)
+	keycloakController.RegisterRoutes()
+
+	// создаём контроллер SCIM
+	scimController := scim.NewController(server, scimService, logger)
+	scimController.RegisterRoutes()
+
 	// создаём контроллер info
 	infoController := info.NewController(server, cfg, db)
+	infoController.AddCheck(info.NewJwksCheck(cfg.KeycloakJwkUrl, nil))
+	infoController.AddCheck(info.NewMigrationCheck(db))
 	infoController.RegisterRouters()
 
-	return server
+	jobs.Add("lifecycle", cfg.LifecycleInterval, lifecycleService.ExecuteDue)
+	jobs.Add("delegation", cfg.DelegationInterval, delegationService.ExecuteDue)
+	jobs.Add("break-glass", cfg.BreakGlassInterval, breakGlassService.ExecuteDue)
+	jobs.Add("provisioning", cfg.ProvisioningInterval, provisioningService.ExecuteDue)
+	jobs.Add("account-reconciliation", cfg.AccountReconciliationInterval,
+		accountrecon.ScheduledReconcile(accountReconService, logger))
+	if keycloakApi != nil {
+		jobs.Add("keycloak-sync", cfg.KeycloakSyncInterval, keycloak.ScheduledSync(keycloakService, logger))
+	}
+	return server, infoController, jobs
 }
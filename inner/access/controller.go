@@ -0,0 +1,94 @@
+package access
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server        *web.Server
+	accessService Svc
+	employees     EmployeeVisibility
+	logger        *common.Logger
+}
+
+// интерфейс сервиса access.Service
+type Svc interface {
+	Resolve(ctx context.Context, employeeId int64) (Response, error)
+}
+
+// EmployeeVisibility проверка, что сотрудник виден вызывающему
+type EmployeeVisibility interface {
+	CheckVisible(ctx context.Context, employeeId int64, principal common.Principal) error
+}
+
+func NewController(server *web.Server, svc Svc, employees EmployeeVisibility, logger *common.Logger) *Controller {
+	return &Controller{
+		server:        server,
+		accessService: svc,
+		employees:     employees,
+		logger:        logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/employees/:id/access", c.GetEmployeeAccess)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/access"
+// @Description Get effective access of employee: every role with all grants by which it was obtained -
+// @Description direct assignment, role rule or chain of nested groups.
+// @Summary get employee access
+// @ID get-employee-access
+// @Tags access
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Success 200 {object} common.Response[access.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/access [get]
+func (c *Controller) GetEmployeeAccess(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// невидимый вызывающему сотрудник считается не найденным
+	if err := c.employees.CheckVisible(ctx.Context(), employeeId, claims.Principal()); err != nil {
+		return err
+	}
+
+	// вызываем метод Resolve сервиса access.Service
+	response, err := c.accessService.Resolve(ctx.Context(), employeeId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee access", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee access", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+	return nil
+}
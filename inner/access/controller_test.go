@@ -0,0 +1,102 @@
+package access
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса access.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Resolve(ctx context.Context, employeeId int64) (Response, error) {
+	args := svc.Called(employeeId)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+// StubVisibility скрывает от вызывающего перечисленных сотрудников
+type StubVisibility struct {
+	hidden []int64
+}
+
+func (s StubVisibility) CheckVisible(ctx context.Context, employeeId int64, principal common.Principal) error {
+	if slices.Contains(s.hidden, employeeId) {
+		return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+	return nil
+}
+
+func newTestServer(svc Svc, employees EmployeeVisibility, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("", roles...))
+	NewController(server, svc, employees, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_GetEmployeeAccess(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should return access of employee", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmUser)
+		access := Response{EmployeeId: 7, EmployeeName: "john doe", Status: "active", Roles: []Role{
+			{RoleId: 1, RoleName: "developer", Grants: []Grant{{Source: SourceDirect, Explain: "assigned directly"}}},
+		}}
+		svc.On("Resolve", int64(7)).Return(access, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(access, got.Data)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 404 for unknown employee", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmAdmin)
+		svc.On("Resolve", int64(7)).Return(Response{}, common.NotFoundError{Message: "employee with id 7 not found"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("should return 404 for employee not visible to caller", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{hidden: []int64{7}}, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+		svc.AssertNotCalled(t, "Resolve", mock.Anything)
+	})
+
+	t.Run("should return 403 without idm roles", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/access", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Resolve", mock.Anything)
+	})
+}
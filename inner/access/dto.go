@@ -0,0 +1,137 @@
+package access
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+// Способы получения роли сотрудником
+const (
+	// роль назначена сотруднику вручную
+	SourceDirect = "direct"
+	// роль назначена правилом по атрибутам сотрудника
+	SourceRule = "rule"
+	// роль назначена группе, в которую входит сотрудник
+	SourceGroup = "group"
+	// роль временно передана сотруднику на время отсутствия другого сотрудника
+	SourceDelegation = "delegation"
+	// роль выдана сотруднику через экстренный доступ и будет отозвана по истечении срока
+	SourceBreakGlass = "break_glass"
+)
+
+// EmployeeEntity сотрудник, доступ которого вычисляется
+type EmployeeEntity struct {
+	Id     int64  `db:"id"`
+	Name   string `db:"name"`
+	Status string `db:"status"`
+}
+
+// AssignmentEntity роль, назначенная сотруднику в employee_role
+type AssignmentEntity struct {
+	RoleId   int64     `db:"role_id"`
+	RoleName string    `db:"role_name"`
+	Source   string    `db:"source"`
+	CreateAt time.Time `db:"create_at"`
+	// когда истекает экстренный доступ; только для break_glass
+	ExpiresAt sql.NullTime `db:"expires_at"`
+}
+
+// RuleEntity правило, которое подходит сотруднику и даёт роль
+type RuleEntity struct {
+	RoleId   int64  `db:"role_id"`
+	RuleId   int64  `db:"rule_id"`
+	RuleName string `db:"rule_name"`
+}
+
+// GroupEntity роль, полученная сотрудником через группу
+type GroupEntity struct {
+	RoleId   int64  `db:"role_id"`
+	RoleName string `db:"role_name"`
+	// цепочка групп от группы, в которую входит сотрудник, до группы, которой назначена роль
+	Path pq.StringArray `db:"path"`
+}
+
+// DelegationEntity роль, временно переданная сотруднику действующим замещением
+type DelegationEntity struct {
+	RoleId        int64     `db:"role_id"`
+	RoleName      string    `db:"role_name"`
+	DelegationId  int64     `db:"delegation_id"`
+	DelegatorName string    `db:"delegator_name"`
+	ValidFrom     time.Time `db:"valid_from"`
+	ValidTo       time.Time `db:"valid_to"`
+}
+
+// Response доступ сотрудника: все его роли и пути, которыми они получены
+type Response struct {
+	EmployeeId   int64  `json:"employee_id"`
+	EmployeeName string `json:"employee_name"`
+	Status       string `json:"status"`
+	Roles        []Role `json:"roles"`
+}
+
+type Role struct {
+	RoleId   int64   `json:"role_id"`
+	RoleName string  `json:"role_name"`
+	Grants   []Grant `json:"grants"`
+}
+
+// Grant путь, которым сотрудник получил роль
+type Grant struct {
+	// direct, rule, group, delegation или break_glass
+	Source string `json:"source"`
+	// для rule - правило, которое даёт роль
+	RuleId   int64  `json:"rule_id,omitempty"`
+	RuleName string `json:"rule_name,omitempty"`
+	// для group - цепочка групп от группы, в которую входит сотрудник, до группы, которой назначена роль
+	Groups []string `json:"groups,omitempty"`
+	// для delegation - замещение, которым передана роль
+	DelegationId int64 `json:"delegation_id,omitempty"`
+	// когда роль назначена сотруднику; для group не заполняется
+	GrantedAt *time.Time `json:"granted_at,omitempty"`
+	// когда роль будет отозвана; заполняется для delegation и break_glass
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// путь в читаемом виде, например "group backend > group engineering"
+	Explain string `json:"explain"`
+}
+
+func directGrant(assignment AssignmentEntity) Grant {
+	return Grant{Source: SourceDirect, GrantedAt: &assignment.CreateAt, Explain: "assigned directly"}
+}
+
+func breakGlassGrant(assignment AssignmentEntity) Grant {
+	grant := Grant{Source: SourceBreakGlass, GrantedAt: &assignment.CreateAt, Explain: "break-glass emergency access"}
+	if assignment.ExpiresAt.Valid {
+		grant.ExpiresAt = &assignment.ExpiresAt.Time
+	}
+	return grant
+}
+
+func ruleGrant(assignment AssignmentEntity, rule RuleEntity) Grant {
+	grant := Grant{Source: SourceRule, GrantedAt: &assignment.CreateAt, Explain: "granted by role rule"}
+	if rule.RuleId != 0 {
+		grant.RuleId = rule.RuleId
+		grant.RuleName = rule.RuleName
+		grant.Explain = "role rule " + rule.RuleName
+	}
+	return grant
+}
+
+func groupGrant(path []string) Grant {
+	steps := make([]string, 0, len(path))
+	for _, name := range path {
+		steps = append(steps, "group "+name)
+	}
+	return Grant{Source: SourceGroup, Groups: path, Explain: strings.Join(steps, " > ")}
+}
+
+func delegationGrant(delegation DelegationEntity) Grant {
+	return Grant{
+		Source:       SourceDelegation,
+		DelegationId: delegation.DelegationId,
+		GrantedAt:    &delegation.ValidFrom,
+		ExpiresAt:    &delegation.ValidTo,
+		Explain:      "delegated by " + delegation.DelegatorName,
+	}
+}
@@ -0,0 +1,95 @@
+package access
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewAccessRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// найти сотрудника
+func (r *Repository) FindEmployee(ctx context.Context, id int64) (employee EmployeeEntity, err error) {
+	query := "SELECT id, name, status FROM employee WHERE id = $1"
+	err = r.db.GetContext(ctx, &employee, query, id)
+	return employee, err
+}
+
+// найти роли, назначенные сотруднику вручную, по правилам и экстренным доступом;
+// для экстренного доступа - когда он истекает
+func (r *Repository) FindAssignments(ctx context.Context, employeeId int64) (assignments []AssignmentEntity, err error) {
+	query := `SELECT er.role_id, r.name AS role_name, er.source, er.create_at,
+(SELECT max(g.expires_at) FROM break_glass_grant g
+    WHERE er.source = 'break_glass' AND g.employee_id = er.employee_id AND g.role_id = er.role_id AND g.state = 'active'
+) AS expires_at
+FROM employee_role er
+JOIN role r ON r.id = er.role_id
+WHERE er.employee_id = $1`
+	err = r.db.SelectContext(ctx, &assignments, query, employeeId)
+	return assignments, err
+}
+
+// найти правила, которые подходят сотруднику, вместе с их ролями
+func (r *Repository) FindRules(ctx context.Context, employeeId int64) (rules []RuleEntity, err error) {
+	query := `SELECT rr.role_id, r.id AS rule_id, r.name AS rule_name FROM employee e
+JOIN role_rule r ON (r.org_unit IS NULL OR r.org_unit = e.org_unit) AND (r.job_title IS NULL OR r.job_title = e.job_title)
+JOIN role_rule_role rr ON rr.rule_id = r.id
+WHERE e.id = $1
+ORDER BY r.name`
+	err = r.db.SelectContext(ctx, &rules, query, employeeId)
+	return rules, err
+}
+
+// FindGroupRoles находит роли групп сотрудника, поднимаясь от его групп к родительским.
+// Для каждой роли возвращается цепочка групп, через которую она получена
+func (r *Repository) FindGroupRoles(ctx context.Context, employeeId int64) (roles []GroupEntity, err error) {
+	query := `WITH RECURSIVE membership (group_id, path) AS (
+    SELECT g.id, ARRAY[g.name] FROM group_member gm
+    JOIN employee_group g ON g.id = gm.group_id
+    WHERE gm.employee_id = $1
+    UNION ALL
+    SELECT p.id, m.path || p.name FROM membership m
+    JOIN group_child gc ON gc.child_id = m.group_id
+    JOIN employee_group p ON p.id = gc.parent_id
+)
+SELECT gr.role_id, r.name AS role_name, m.path FROM membership m
+JOIN group_role gr ON gr.group_id = m.group_id
+JOIN role r ON r.id = gr.role_id
+ORDER BY array_length(m.path, 1), m.path`
+	err = r.db.SelectContext(ctx, &roles, query, employeeId)
+	return roles, err
+}
+
+// FindDelegatedRoles находит роли, которые сотрудник получил как заместитель по действующим замещениям.
+// Роль передаётся, только пока отсутствующий не уволен и сам имеет её вручную, по правилу или через группу:
+// роли, полученные им по замещению или экстренным доступом, не передаются
+func (r *Repository) FindDelegatedRoles(ctx context.Context, employeeId int64) (roles []DelegationEntity, err error) {
+	query := `WITH RECURSIVE delegator (id) AS (
+    SELECT DISTINCT delegator_id FROM delegation WHERE deputy_id = $1
+), membership (employee_id, group_id) AS (
+    SELECT gm.employee_id, gm.group_id FROM group_member gm JOIN delegator d ON d.id = gm.employee_id
+    UNION
+    SELECT m.employee_id, gc.parent_id FROM membership m JOIN group_child gc ON gc.child_id = m.group_id
+), held (employee_id, role_id) AS (
+    SELECT er.employee_id, er.role_id FROM employee_role er JOIN delegator d ON d.id = er.employee_id
+    WHERE er.source <> 'break_glass'
+    UNION
+    SELECT m.employee_id, gr.role_id FROM membership m JOIN group_role gr ON gr.group_id = m.group_id
+)
+SELECT dr.role_id, r.name AS role_name, d.id AS delegation_id, e.name AS delegator_name, d.valid_from, d.valid_to
+FROM delegation d
+JOIN delegation_role dr ON dr.delegation_id = d.id
+JOIN held h ON h.employee_id = d.delegator_id AND h.role_id = dr.role_id
+JOIN role r ON r.id = dr.role_id
+JOIN employee e ON e.id = d.delegator_id
+WHERE d.deputy_id = $1 AND d.state IN ('scheduled', 'active') AND d.valid_from <= now() AND now() < d.valid_to
+AND e.status <> 'terminated'
+ORDER BY d.valid_from, d.id`
+	err = r.db.SelectContext(ctx, &roles, query, employeeId)
+	return roles, err
+}
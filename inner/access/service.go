@@ -0,0 +1,114 @@
+package access
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/nihrom205/idm/inner/common"
+	"sort"
+)
+
+type Repo interface {
+	FindEmployee(ctx context.Context, id int64) (EmployeeEntity, error)
+	FindAssignments(ctx context.Context, employeeId int64) ([]AssignmentEntity, error)
+	FindRules(ctx context.Context, employeeId int64) ([]RuleEntity, error)
+	FindGroupRoles(ctx context.Context, employeeId int64) ([]GroupEntity, error)
+	FindDelegatedRoles(ctx context.Context, employeeId int64) ([]DelegationEntity, error)
+}
+
+// Service вычисляет фактический доступ сотрудника из всех источников ролей. Это единственное место,
+// где собираются роли: Resolve используют отчёты (/employees/:id/access, /me/roles), проверки доступа
+// (права администратора отдела, экстренный доступ, делегирование) и данные, производные от ролей:
+// права в приложениях и синхронизация с Keycloak, из которой берутся роли токена
+type Service struct {
+	repo Repo
+}
+
+func NewService(repo Repo) *Service {
+	return &Service{repo: repo}
+}
+
+// Resolve возвращает все роли сотрудника и для каждой роли все пути, которыми она получена
+func (s *Service) Resolve(ctx context.Context, employeeId int64) (Response, error) {
+	employee, err := s.repo.FindEmployee(ctx, employeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+	assignments, err := s.repo.FindAssignments(ctx, employeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding roles of employee %d: %w", employeeId, err)
+	}
+	rules, err := s.repo.FindRules(ctx, employeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding role rules of employee %d: %w", employeeId, err)
+	}
+	groups, err := s.repo.FindGroupRoles(ctx, employeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group roles of employee %d: %w", employeeId, err)
+	}
+	delegations, err := s.repo.FindDelegatedRoles(ctx, employeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding delegated roles of employee %d: %w", employeeId, err)
+	}
+
+	// правила, которые дают каждую роль
+	rulesByRole := map[int64][]RuleEntity{}
+	for _, rule := range rules {
+		rulesByRole[rule.RoleId] = append(rulesByRole[rule.RoleId], rule)
+	}
+
+	roles := map[int64]*Role{}
+	add := func(roleId int64, roleName string, grant Grant) {
+		role, ok := roles[roleId]
+		if !ok {
+			role = &Role{RoleId: roleId, RoleName: roleName}
+			roles[roleId] = role
+		}
+		role.Grants = append(role.Grants, grant)
+	}
+	for _, assignment := range assignments {
+		switch assignment.Source {
+		case SourceRule:
+		case SourceBreakGlass:
+			add(assignment.RoleId, assignment.RoleName, breakGlassGrant(assignment))
+		default:
+			add(assignment.RoleId, assignment.RoleName, directGrant(assignment))
+		}
+		// подходящие правила показываем и у ручного назначения: роль останется, даже если его отозвать
+		matched := rulesByRole[assignment.RoleId]
+		if assignment.Source == SourceRule && len(matched) == 0 {
+			// правило уже не подходит, но пересчёт ещё не отозвал роль
+			add(assignment.RoleId, assignment.RoleName, ruleGrant(assignment, RuleEntity{}))
+		}
+		for _, rule := range matched {
+			add(assignment.RoleId, assignment.RoleName, ruleGrant(assignment, rule))
+		}
+	}
+	for _, item := range groups {
+		add(item.RoleId, item.RoleName, groupGrant(item.Path))
+	}
+	for _, item := range delegations {
+		add(item.RoleId, item.RoleName, delegationGrant(item))
+	}
+
+	response := Response{
+		EmployeeId:   employee.Id,
+		EmployeeName: employee.Name,
+		Status:       employee.Status,
+		Roles:        make([]Role, 0, len(roles)),
+	}
+	for _, role := range roles {
+		response.Roles = append(response.Roles, *role)
+	}
+	sort.Slice(response.Roles, func(i, j int) bool {
+		if response.Roles[i].RoleName != response.Roles[j].RoleName {
+			return response.Roles[i].RoleName < response.Roles[j].RoleName
+		}
+		return response.Roles[i].RoleId < response.Roles[j].RoleId
+	})
+	return response, nil
+}
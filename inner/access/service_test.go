@@ -0,0 +1,93 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	employeeQuery     = regexp.QuoteMeta("SELECT id, name, status FROM employee WHERE id = $1")
+	assignmentsQuery  = regexp.QuoteMeta("SELECT er.role_id, r.name AS role_name, er.source, er.create_at,")
+	rulesQuery        = regexp.QuoteMeta("SELECT rr.role_id, r.id AS rule_id, r.name AS rule_name FROM employee e")
+	groupsQuery       = regexp.QuoteMeta("WITH RECURSIVE membership (group_id, path) AS (")
+	delegationsQuery  = regexp.QuoteMeta("SELECT dr.role_id, r.name AS role_name, d.id AS delegation_id")
+	delegationColumns = []string{"role_id", "role_name", "delegation_id", "delegator_name", "valid_from", "valid_to"}
+)
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	return NewService(NewAccessRepository(sqlx.NewDb(db, "sqlmock"))), mock
+}
+
+func TestService_Resolve(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should explain every grant of every role", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		grantedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		expiresAt := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(employeeQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}).AddRow(7, "john doe", "active"))
+		mock.ExpectQuery(assignmentsQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"role_id", "role_name", "source", "create_at", "expires_at"}).
+				AddRow(1, "developer", "manual", grantedAt, nil).
+				AddRow(2, "vpn", "rule", grantedAt, nil).
+				AddRow(4, "wiki", "rule", grantedAt, nil).
+				AddRow(8, "prod-db", "break_glass", grantedAt, expiresAt))
+		mock.ExpectQuery(rulesQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"role_id", "rule_id", "rule_name"}).
+				AddRow(1, 5, "it").
+				AddRow(2, 5, "it"))
+		mock.ExpectQuery(groupsQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"role_id", "role_name", "path"}).
+				AddRow(3, "git", "{backend,engineering}"))
+		mock.ExpectQuery(delegationsQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(delegationColumns).AddRow(6, "approver", 11, "jane roe", grantedAt, expiresAt))
+
+		got, err := srv.Resolve(context.Background(), 7)
+
+		a.Nil(err)
+		a.Equal(Response{EmployeeId: 7, EmployeeName: "john doe", Status: "active", Roles: []Role{
+			{RoleId: 6, RoleName: "approver", Grants: []Grant{
+				{Source: SourceDelegation, DelegationId: 11, GrantedAt: &grantedAt, ExpiresAt: &expiresAt, Explain: "delegated by jane roe"},
+			}},
+			{RoleId: 1, RoleName: "developer", Grants: []Grant{
+				{Source: SourceDirect, GrantedAt: &grantedAt, Explain: "assigned directly"},
+				{Source: SourceRule, RuleId: 5, RuleName: "it", GrantedAt: &grantedAt, Explain: "role rule it"},
+			}},
+			{RoleId: 3, RoleName: "git", Grants: []Grant{
+				{Source: SourceGroup, Groups: []string{"backend", "engineering"}, Explain: "group backend > group engineering"},
+			}},
+			{RoleId: 8, RoleName: "prod-db", Grants: []Grant{
+				{Source: SourceBreakGlass, GrantedAt: &grantedAt, ExpiresAt: &expiresAt, Explain: "break-glass emergency access"},
+			}},
+			{RoleId: 2, RoleName: "vpn", Grants: []Grant{
+				{Source: SourceRule, RuleId: 5, RuleName: "it", GrantedAt: &grantedAt, Explain: "role rule it"},
+			}},
+			// правило перестало подходить, но роль ещё не отозвана пересчётом
+			{RoleId: 4, RoleName: "wiki", Grants: []Grant{
+				{Source: SourceRule, GrantedAt: &grantedAt, Explain: "granted by role rule"},
+			}},
+		}}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(employeeQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"id", "name", "status"}))
+
+		_, err := srv.Resolve(context.Background(), 7)
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
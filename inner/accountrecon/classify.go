@@ -0,0 +1,92 @@
+package accountrecon
+
+import (
+	"cmp"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"slices"
+	"strings"
+)
+
+// classify сравнивает учётные записи целевой системы с ожидаемыми IDM и возвращает расхождения
+// по возрастанию id учётной записи. Сотрудники из pending ещё синхронизируются и пропускаются
+func classify(application string, accounts []provisioning.Account, expected map[string]expectedAccount,
+	pending map[int64]bool) []Entity {
+	var findings []Entity
+	seen := map[string]bool{}
+	for _, account := range accounts {
+		seen[account.Id] = true
+		want, ok := expected[account.Id]
+		if !ok {
+			// заблокированная учётная запись без ожидаемых прав - итог провижининга, а не расхождение
+			if account.Disabled || pending[account.EmployeeId] {
+				continue
+			}
+			findings = append(findings, newFinding(application, account.Id, account.EmployeeId, TypeOrphan,
+				nil, account.Entitlements))
+			continue
+		}
+		if pending[want.EmployeeId] {
+			continue
+		}
+		if account.Disabled {
+			findings = append(findings, newFinding(application, account.Id, want.EmployeeId, TypeMissing,
+				want.Entitlements, nil))
+			continue
+		}
+		missing := difference(want.Entitlements, account.Entitlements)
+		extra := difference(account.Entitlements, want.Entitlements)
+		if len(missing) > 0 || len(extra) > 0 {
+			findings = append(findings, newFinding(application, account.Id, want.EmployeeId, TypeDrift, missing, extra))
+		}
+	}
+	for accountId, want := range expected {
+		if !seen[accountId] && !pending[want.EmployeeId] {
+			findings = append(findings, newFinding(application, accountId, want.EmployeeId, TypeMissing,
+				want.Entitlements, nil))
+		}
+	}
+	slices.SortFunc(findings, func(a, b Entity) int {
+		return cmp.Or(strings.Compare(a.AccountId, b.AccountId), strings.Compare(a.Type, b.Type))
+	})
+	return findings
+}
+
+func newFinding(application string, accountId string, employeeId int64, findingType string,
+	missing []string, extra []string) Entity {
+	return Entity{
+		Application:         application,
+		AccountId:           accountId,
+		EmployeeId:          sql.NullInt64{Int64: employeeId, Valid: employeeId > 0},
+		Type:                findingType,
+		MissingEntitlements: sorted(missing),
+		ExtraEntitlements:   sorted(extra),
+	}
+}
+
+// difference права из a, которых нет в b
+func difference(a []string, b []string) []string {
+	var result []string
+	for _, item := range a {
+		if !slices.Contains(b, item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func sorted(items []string) pq.StringArray {
+	result := pq.StringArray(slices.Clone(items))
+	if result == nil {
+		result = pq.StringArray{}
+	}
+	slices.Sort(result)
+	return result
+}
+
+// sameEntitlements совпадают ли права найденного расхождения с правами сохранённого
+func sameEntitlements(a Entity, b Entity) bool {
+	return slices.Equal(sorted(a.MissingEntitlements), sorted(b.MissingEntitlements)) &&
+		slices.Equal(sorted(a.ExtraEntitlements), sorted(b.ExtraEntitlements))
+}
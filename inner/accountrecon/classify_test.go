@@ -0,0 +1,58 @@
+package accountrecon
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should classify orphan, missing and drift", func(t *testing.T) {
+		accounts := []provisioning.Account{
+			{Id: "7", EmployeeId: 7, Entitlements: []string{"vpn", "admin"}},
+			{Id: "8", EmployeeId: 8, Disabled: true, Entitlements: []string{"vpn"}},
+			{Id: "99", EmployeeId: 99, Entitlements: []string{"vpn"}},
+			{Id: "svc-backup"},
+		}
+		expected := map[string]expectedAccount{
+			"7": {EmployeeId: 7, Entitlements: []string{"vpn", "mail"}},
+			"8": {EmployeeId: 8, Entitlements: []string{"vpn"}},
+			"9": {EmployeeId: 9, Entitlements: []string{"mail"}},
+		}
+
+		got := classify("ad", accounts, expected, map[int64]bool{})
+
+		a.Equal([]Entity{
+			{Application: "ad", AccountId: "7", EmployeeId: sql.NullInt64{Int64: 7, Valid: true}, Type: TypeDrift,
+				MissingEntitlements: pq.StringArray{"mail"}, ExtraEntitlements: pq.StringArray{"admin"}},
+			{Application: "ad", AccountId: "8", EmployeeId: sql.NullInt64{Int64: 8, Valid: true}, Type: TypeMissing,
+				MissingEntitlements: pq.StringArray{"vpn"}, ExtraEntitlements: pq.StringArray{}},
+			{Application: "ad", AccountId: "9", EmployeeId: sql.NullInt64{Int64: 9, Valid: true}, Type: TypeMissing,
+				MissingEntitlements: pq.StringArray{"mail"}, ExtraEntitlements: pq.StringArray{}},
+			{Application: "ad", AccountId: "99", EmployeeId: sql.NullInt64{Int64: 99, Valid: true}, Type: TypeOrphan,
+				MissingEntitlements: pq.StringArray{}, ExtraEntitlements: pq.StringArray{"vpn"}},
+			{Application: "ad", AccountId: "svc-backup", Type: TypeOrphan,
+				MissingEntitlements: pq.StringArray{}, ExtraEntitlements: pq.StringArray{}},
+		}, got)
+	})
+
+	t.Run("should skip matching, disabled and pending accounts", func(t *testing.T) {
+		accounts := []provisioning.Account{
+			{Id: "7", EmployeeId: 7, Entitlements: []string{"mail", "vpn"}},
+			{Id: "8", EmployeeId: 8, Disabled: true, Entitlements: []string{"vpn"}},
+			{Id: "10", EmployeeId: 10, Entitlements: []string{"vpn"}},
+		}
+		expected := map[string]expectedAccount{
+			"7":  {EmployeeId: 7, Entitlements: []string{"vpn", "mail"}},
+			"11": {EmployeeId: 11, Entitlements: []string{"vpn"}},
+		}
+
+		got := classify("ad", accounts, expected, map[int64]bool{10: true, 11: true})
+
+		a.Empty(got)
+	})
+}
@@ -0,0 +1,232 @@
+package accountrecon
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+	"time"
+)
+
+type Controller struct {
+	server              *web.Server
+	accountReconService Svc
+	logger              *common.Logger
+}
+
+// интерфейс сервиса accountrecon.Service
+type Svc interface {
+	Find(ctx context.Context, filter FilterRequest) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Reconcile(ctx context.Context, now time.Time) (Report, error)
+	Remediate(ctx context.Context, id int64, request RemediateRequest, actor string) (Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:              server,
+		accountReconService: svc,
+		logger:              logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/account-reconciliation", c.ReconcileAccounts)
+	c.server.GroupApiV1.Get("/account-findings", c.GetAllFindings)
+	c.server.GroupApiV1.Get("/account-findings/:id", c.GetFinding)
+	c.server.GroupApiV1.Post("/account-findings/:id/remediate", c.RemediateFinding)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/account-reconciliation"
+// @Description Reconcile accounts of all connected target systems with employee entitlements right now.
+// @Description Orphan accounts, missing accounts and entitlement drift are saved as findings.
+// @Summary reconcile accounts
+// @ID reconcile-accounts
+// @Tags account-reconciliation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[accountrecon.Report]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /account-reconciliation [post]
+func (c *Controller) ReconcileAccounts(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод Reconcile сервиса accountrecon.Service
+	response, err := c.accountReconService.Reconcile(ctx.Context(), time.Now())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "reconcile accounts", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "reconcile accounts", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/account-findings"
+// @Description Get account reconciliation findings filtered by application, type (orphan, missing, drift)
+// @Description and state (open, ignored, adopted, revoked, resolved).
+// @Summary get account findings
+// @ID get-all-account-findings
+// @Tags account-reconciliation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param application query string false "application name"
+// @Param type query string false "finding type"
+// @Param state query string false "finding state"
+// @Success 200 {object} common.Response[[]accountrecon.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /account-findings [get]
+func (c *Controller) GetAllFindings(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	filter := FilterRequest{
+		Application: ctx.Query("application"),
+		Type:        ctx.Query("type"),
+		State:       ctx.Query("state"),
+	}
+
+	// вызываем метод Find сервиса accountrecon.Service
+	response, err := c.accountReconService.Find(ctx.Context(), filter)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all account findings", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all account findings", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/account-findings/:id"
+// @Description Get account reconciliation finding by id.
+// @Summary get account finding
+// @ID get-account-finding
+// @Tags account-reconciliation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id account finding"
+// @Success 200 {object} common.Response[accountrecon.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /account-findings/{id} [get]
+func (c *Controller) GetFinding(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid account finding id")
+	}
+
+	// вызываем метод FindById сервиса accountrecon.Service
+	response, err := c.accountReconService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get account finding", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get account finding", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/account-findings/:id/remediate"
+// @Description Remediate open account finding. revoke removes entitlements not granted by the IDM
+// @Description (and disables orphan account), adopt links an orphan account to an owner employee
+// @Description (provisioning and reconciliation treat it as the employee account from now on and provisioning
+// @Description grants it the entitlements of the employee roles; drift cannot be adopted),
+// @Description ignore accepts the finding. Accepted findings are not reopened until they change.
+// @Summary remediate account finding
+// @ID remediate-account-finding
+// @Tags account-reconciliation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id account finding"
+// @Param request body accountrecon.RemediateRequest true "remediation"
+// @Success 200 {object} common.Response[accountrecon.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /account-findings/{id}/remediate [post]
+func (c *Controller) RemediateFinding(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid account finding id")
+	}
+	var request RemediateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// вызываем метод Remediate сервиса accountrecon.Service
+	response, err := c.accountReconService.Remediate(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remediate account finding", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remediate account finding", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package accountrecon
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Объявляем структуру мока сервиса accountrecon.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Find(ctx context.Context, filter FilterRequest) ([]Response, error) {
+	args := svc.Called(filter)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Reconcile(ctx context.Context, now time.Time) (Report, error) {
+	args := svc.Called(now)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+func (svc *MockService) Remediate(ctx context.Context, id int64, request RemediateRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_ReconcileAccounts(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should reconcile accounts", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Reconcile", mock.Anything).Return(Report{Applications: 1, Orphans: 1, Errors: []string{}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/account-reconciliation", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 without admin role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/account-reconciliation", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Reconcile", mock.Anything)
+	})
+}
+
+func TestController_GetAllFindings(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass filter to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Find", FilterRequest{Application: "ad", Type: TypeOrphan, State: StateOpen}).
+			Return([]Response{{Id: 1, Application: "ad", Type: TypeOrphan, State: StateOpen}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/account-findings?application=ad&type=orphan&state=open", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+}
+
+func TestController_RemediateFinding(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass request and actor to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		request := RemediateRequest{Action: ActionIgnore, Comment: "test account"}
+		svc.On("Remediate", int64(1), request, "kc-admin").Return(Response{Id: 1, State: StateIgnored}, nil)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/account-findings/1/remediate",
+			strings.NewReader(`{"action":"ignore","comment":"test account"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 409 for finding that is not open", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Remediate", int64(1), RemediateRequest{Action: ActionRevoke}, "kc-admin").
+			Return(Response{}, common.ConflictError{Message: "account finding 1 is already revoked"})
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/account-findings/1/remediate",
+			strings.NewReader(`{"action":"revoke"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("should return 400 for invalid finding id", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/account-findings/abc/remediate", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Remediate", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,115 @@
+package accountrecon
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Типы расхождений
+const (
+	// учётная запись есть в целевой системе, но IDM её не ожидает
+	TypeOrphan = "orphan"
+	// IDM ожидает учётную запись, но её нет или она заблокирована
+	TypeMissing = "missing"
+	// права учётной записи отличаются от прав сотрудника в IDM
+	TypeDrift = "drift"
+)
+
+// Состояния расхождения
+const (
+	StateOpen    = "open"
+	StateIgnored = "ignored"
+	StateAdopted = "adopted"
+	StateRevoked = "revoked"
+	// расхождение пропало при следующей сверке
+	StateResolved = "resolved"
+)
+
+// Действия по устранению расхождения
+const (
+	ActionRevoke = "revoke"
+	ActionAdopt  = "adopt"
+	ActionIgnore = "ignore"
+)
+
+// Entity расхождение учётной записи целевой системы
+type Entity struct {
+	Id                  int64          `db:"id"`
+	Application         string         `db:"application"`
+	AccountId           string         `db:"account_id"`
+	EmployeeId          sql.NullInt64  `db:"employee_id"`
+	Type                string         `db:"type"`
+	MissingEntitlements pq.StringArray `db:"missing_entitlements"`
+	ExtraEntitlements   pq.StringArray `db:"extra_entitlements"`
+	State               string         `db:"state"`
+	ResolvedBy          sql.NullString `db:"resolved_by"`
+	Comment             sql.NullString `db:"comment"`
+	ResolvedAt          sql.NullTime   `db:"resolved_at"`
+	FirstSeenAt         time.Time      `db:"first_seen_at"`
+	LastSeenAt          time.Time      `db:"last_seen_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	response := Response{
+		Id:                  e.Id,
+		Application:         e.Application,
+		AccountId:           e.AccountId,
+		EmployeeId:          e.EmployeeId.Int64,
+		Type:                e.Type,
+		MissingEntitlements: e.MissingEntitlements,
+		ExtraEntitlements:   e.ExtraEntitlements,
+		State:               e.State,
+		ResolvedBy:          e.ResolvedBy.String,
+		Comment:             e.Comment.String,
+		FirstSeenAt:         e.FirstSeenAt,
+		LastSeenAt:          e.LastSeenAt,
+	}
+	if response.MissingEntitlements == nil {
+		response.MissingEntitlements = []string{}
+	}
+	if response.ExtraEntitlements == nil {
+		response.ExtraEntitlements = []string{}
+	}
+	if e.ResolvedAt.Valid {
+		response.ResolvedAt = &e.ResolvedAt.Time
+	}
+	return response
+}
+
+type Response struct {
+	Id          int64  `json:"id"`
+	Application string `json:"application"`
+	AccountId   string `json:"account_id"`
+	EmployeeId  int64  `json:"employee_id,omitempty"`
+	Type        string `json:"type"`
+	// права, которые есть у сотрудника в IDM, но не выданы в целевой системе
+	MissingEntitlements []string `json:"missing_entitlements"`
+	// права, которые выданы в целевой системе, но не положены по IDM
+	ExtraEntitlements []string   `json:"extra_entitlements"`
+	State             string     `json:"state"`
+	ResolvedBy        string     `json:"resolved_by,omitempty"`
+	Comment           string     `json:"comment,omitempty"`
+	ResolvedAt        *time.Time `json:"resolved_at,omitempty"`
+	FirstSeenAt       time.Time  `json:"first_seen_at"`
+	LastSeenAt        time.Time  `json:"last_seen_at"`
+}
+
+// Report итог сверки учётных записей
+type Report struct {
+	Applications int `json:"applications"`
+	Accounts     int `json:"accounts"`
+	// открытые расхождения по типам, принятые (ignored, adopted) не считаются
+	Orphans  int `json:"orphans"`
+	Missing  int `json:"missing"`
+	Drift    int `json:"drift"`
+	Resolved int `json:"resolved"`
+	// целевые системы, которые не удалось сверить
+	Errors []string `json:"errors"`
+}
+
+// expectedAccount учётная запись, которую IDM ожидает в целевой системе
+type expectedAccount struct {
+	EmployeeId   int64
+	Entitlements []string
+}
@@ -0,0 +1,136 @@
+package accountrecon
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"time"
+)
+
+// ключ advisory lock, не дающий запустить две сверки учётных записей одновременно
+const lockKey = 20261020
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewAccountReconRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// LockTx ждёт окончания другой сверки; блокировка снимается по окончании транзакции
+func (r *Repository) LockTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey)
+	return err
+}
+
+// сотрудники, которым положены учётные записи в целевых системах
+func (r *Repository) FindActiveEmployeeIds(ctx context.Context) (ids []int64, err error) {
+	err = r.db.SelectContext(ctx, &ids, "SELECT id FROM employee WHERE status = 'active' ORDER BY id")
+	return ids, err
+}
+
+// учётные записи целевых систем, принятые как учётные записи сотрудников
+func (r *Repository) FindAccountLinks(ctx context.Context) (links []provisioning.AccountLinkEntity, err error) {
+	err = r.db.SelectContext(ctx, &links, "SELECT application, account_id, employee_id FROM provisioning_account")
+	return links, err
+}
+
+// LinkAccountTx делает учётную запись целевой системы учётной записью сотрудника. У сотрудника остаётся
+// одна учётная запись в системе, прежняя связь заменяется
+func (r *Repository) LinkAccountTx(ctx context.Context, tx *sqlx.Tx, link provisioning.AccountLinkEntity) error {
+	query := "DELETE FROM provisioning_account WHERE application = $1 AND employee_id = $2"
+	if _, err := tx.ExecContext(ctx, query, link.Application, link.EmployeeId); err != nil {
+		return err
+	}
+	query = `INSERT INTO provisioning_account (application, account_id, employee_id) VALUES ($1, $2, $3)
+ON CONFLICT (application, account_id) DO UPDATE SET employee_id = excluded.employee_id, create_at = now()`
+	_, err := tx.ExecContext(ctx, query, link.Application, link.AccountId, link.EmployeeId)
+	return err
+}
+
+// сотрудники, синхронизация которых ещё стоит в очереди провижининга: их расхождения ожидаемы
+func (r *Repository) FindPendingEmployeeIds(ctx context.Context) (ids []int64, err error) {
+	err = r.db.SelectContext(ctx, &ids, "SELECT employee_id FROM provisioning_task WHERE state = 'pending'")
+	return ids, err
+}
+
+// проверка существования сотрудника в рамках транзакции
+func (r *Repository) EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "SELECT EXISTS(SELECT * FROM employee WHERE id = $1)", employeeId)
+	return isExists, err
+}
+
+// найти расхождения по фильтру
+func (r *Repository) FindAll(ctx context.Context, filter FilterRequest) (findings []Entity, err error) {
+	query := `SELECT * FROM account_finding
+WHERE ($1 = '' OR application = $1) AND ($2 = '' OR type = $2) AND ($3 = '' OR state = $3)
+ORDER BY last_seen_at DESC, id DESC`
+	err = r.db.SelectContext(ctx, &findings, query, filter.Application, filter.Type, filter.State)
+	return findings, err
+}
+
+// найти расхождение по id
+func (r *Repository) FindById(ctx context.Context, id int64) (finding Entity, err error) {
+	err = r.db.GetContext(ctx, &finding, "SELECT * FROM account_finding WHERE id = $1", id)
+	return finding, err
+}
+
+// найти расхождение по id и заблокировать его до конца транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (finding Entity, err error) {
+	err = tx.GetContext(ctx, &finding, "SELECT * FROM account_finding WHERE id = $1 FOR UPDATE", id)
+	return finding, err
+}
+
+// действующие (open, ignored, adopted) расхождения целевой системы в рамках транзакции
+func (r *Repository) FindActiveTx(ctx context.Context, tx *sqlx.Tx, application string) (findings []Entity, err error) {
+	query := "SELECT * FROM account_finding WHERE application = $1 AND state IN ('open', 'ignored', 'adopted') ORDER BY id"
+	err = tx.SelectContext(ctx, &findings, query, application)
+	return findings, err
+}
+
+// добавить расхождение в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, finding Entity, now time.Time) error {
+	query := `INSERT INTO account_finding (application, account_id, employee_id, type, missing_entitlements,
+extra_entitlements, first_seen_at, last_seen_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $7)`
+	_, err := tx.ExecContext(ctx, query, finding.Application, finding.AccountId, finding.EmployeeId, finding.Type,
+		pq.StringArray(finding.MissingEntitlements), pq.StringArray(finding.ExtraEntitlements), now)
+	return err
+}
+
+// SeenTx отмечает, что принятое расхождение найдено снова без изменений
+func (r *Repository) SeenTx(ctx context.Context, tx *sqlx.Tx, id int64, now time.Time) error {
+	_, err := tx.ExecContext(ctx, "UPDATE account_finding SET last_seen_at = $2 WHERE id = $1", id, now)
+	return err
+}
+
+// ReopenTx обновляет права расхождения и открывает его заново, если оно было принято
+func (r *Repository) ReopenTx(ctx context.Context, tx *sqlx.Tx, finding Entity, now time.Time) error {
+	query := `UPDATE account_finding SET employee_id = $2, missing_entitlements = $3, extra_entitlements = $4,
+state = 'open', resolved_by = NULL, comment = NULL, resolved_at = NULL, last_seen_at = $5 WHERE id = $1`
+	_, err := tx.ExecContext(ctx, query, finding.Id, finding.EmployeeId, pq.StringArray(finding.MissingEntitlements),
+		pq.StringArray(finding.ExtraEntitlements), now)
+	return err
+}
+
+// закрыть расхождение, которое пропало при сверке, в рамках транзакции
+func (r *Repository) CloseTx(ctx context.Context, tx *sqlx.Tx, id int64, now time.Time) error {
+	query := "UPDATE account_finding SET state = 'resolved', resolved_at = $2 WHERE id = $1"
+	_, err := tx.ExecContext(ctx, query, id, now)
+	return err
+}
+
+// ResolveTx фиксирует действие администратора по расхождению
+func (r *Repository) ResolveTx(ctx context.Context, tx *sqlx.Tx, finding Entity) (resolved Entity, err error) {
+	query := `UPDATE account_finding SET state = $2, employee_id = $3, resolved_by = $4, comment = $5, resolved_at = now()
+WHERE id = $1 RETURNING *`
+	err = tx.GetContext(ctx, &resolved, query, finding.Id, finding.State, finding.EmployeeId, finding.ResolvedBy,
+		finding.Comment)
+	return resolved, err
+}
@@ -0,0 +1,19 @@
+package accountrecon
+
+// FilterRequest отбор расхождений; пустое поле - без отбора
+type FilterRequest struct {
+	Application string `json:"application" validate:"max=155"`
+	Type        string `json:"type" validate:"omitempty,oneof=orphan missing drift"`
+	State       string `json:"state" validate:"omitempty,oneof=open ignored adopted revoked resolved"`
+}
+
+// RemediateRequest устранение расхождения: revoke отзывает лишние права (orphan - ещё и блокирует учётную запись),
+// adopt связывает лишнюю учётную запись с владельцем (права ей дальше выдаёт провижининг по ролям владельца),
+// ignore принимает расхождение без владельца
+type RemediateRequest struct {
+	Action string `json:"action" validate:"required,oneof=revoke adopt ignore"`
+	// владелец учётной записи для adopt; по умолчанию - сотрудник учётной записи
+	EmployeeId int64 `json:"employee_id" validate:"gte=0"`
+	// обоснование, обязательно для adopt и ignore
+	Comment string `json:"comment" validate:"required_unless=Action revoke,max=1000"`
+}
@@ -0,0 +1,401 @@
+package accountrecon
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/application"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"slices"
+	"time"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	LockTx(ctx context.Context, tx *sqlx.Tx) error
+	FindActiveEmployeeIds(ctx context.Context) ([]int64, error)
+	FindPendingEmployeeIds(ctx context.Context) ([]int64, error)
+	FindAccountLinks(ctx context.Context) ([]provisioning.AccountLinkEntity, error)
+	LinkAccountTx(ctx context.Context, tx *sqlx.Tx, link provisioning.AccountLinkEntity) error
+	EmployeeExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error)
+	FindAll(ctx context.Context, filter FilterRequest) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindActiveTx(ctx context.Context, tx *sqlx.Tx, application string) ([]Entity, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, finding Entity, now time.Time) error
+	SeenTx(ctx context.Context, tx *sqlx.Tx, id int64, now time.Time) error
+	ReopenTx(ctx context.Context, tx *sqlx.Tx, finding Entity, now time.Time) error
+	CloseTx(ctx context.Context, tx *sqlx.Tx, id int64, now time.Time) error
+	ResolveTx(ctx context.Context, tx *sqlx.Tx, finding Entity) (Entity, error)
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// EntitlementSvc права сотрудника в целевых системах, реализуется application.Service
+type EntitlementSvc interface {
+	EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (application.EmployeeEntitlementsResponse, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+type Service struct {
+	repo         Repo
+	audit        AuditRepo
+	entitlements EntitlementSvc
+	registry     *provisioning.Registry
+	validator    Validator
+}
+
+func NewService(repo Repo, audit AuditRepo, entitlements EntitlementSvc, registry *provisioning.Registry,
+	validator Validator) *Service {
+	return &Service{
+		repo:         repo,
+		audit:        audit,
+		entitlements: entitlements,
+		registry:     registry,
+		validator:    validator,
+	}
+}
+
+// Find возвращает расхождения по фильтру
+func (s *Service) Find(ctx context.Context, filter FilterRequest) ([]Response, error) {
+	if err := s.validator.Validate(filter); err != nil {
+		return []Response{}, common.NewRequestValidatorError(err)
+	}
+	findings, err := s.repo.FindAll(ctx, filter)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error finding account findings: %w", err)
+	}
+	response := make([]Response, 0, len(findings))
+	for _, finding := range findings {
+		response = append(response, finding.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	finding, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("account finding with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding account finding with id %d: %w", id, err)
+	}
+	return finding.toResponse(), nil
+}
+
+// Reconcile сверяет учётные записи всех подключённых целевых систем с правами сотрудников в IDM
+// и сохраняет расхождения. Система, которую не удалось прочитать, попадает в Report.Errors,
+// её расхождения не меняются
+func (s *Service) Reconcile(ctx context.Context, now time.Time) (report Report, err error) {
+	report = Report{Errors: []string{}}
+	expected, err := s.expected(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	pendingIds, err := s.repo.FindPendingEmployeeIds(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("error finding pending provisioning: %w", err)
+	}
+	pending := map[int64]bool{}
+	for _, id := range pendingIds {
+		pending[id] = true
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Report{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reconciling accounts panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling accounts: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling accounts: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling accounts: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	if err = s.repo.LockTx(ctx, tx); err != nil {
+		return Report{}, fmt.Errorf("error locking account reconciliation: %w", err)
+	}
+	for _, name := range s.registry.Applications() {
+		connector, _ := s.registry.Get(name)
+		accounts, errList := connector.ListAccounts(ctx)
+		if errList != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", name, errList))
+			continue
+		}
+		report.Applications++
+		report.Accounts += len(accounts)
+		if err = s.saveTx(ctx, tx, name, classify(name, accounts, expected[name], pending), now, &report); err != nil {
+			return Report{}, err
+		}
+	}
+	return report, nil
+}
+
+// expected учётные записи, которые IDM ожидает в каждой целевой системе: по одной на активного сотрудника
+// с правами в этой системе. Учётная запись сотрудника - принятая при adopt или provisioning.AccountId
+func (s *Service) expected(ctx context.Context) (map[string]map[string]expectedAccount, error) {
+	employeeIds, err := s.repo.FindActiveEmployeeIds(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding active employees: %w", err)
+	}
+	links, err := s.repo.FindAccountLinks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding adopted accounts: %w", err)
+	}
+	accountIds := map[string]map[int64]string{}
+	for _, link := range links {
+		if accountIds[link.Application] == nil {
+			accountIds[link.Application] = map[int64]string{}
+		}
+		accountIds[link.Application][link.EmployeeId] = link.AccountId
+	}
+	expected := map[string]map[string]expectedAccount{}
+	for _, employeeId := range employeeIds {
+		entitlements, err := s.entitlements.EmployeeEntitlements(ctx, employeeId, 0)
+		var notFound common.NotFoundError
+		if errors.As(err, &notFound) {
+			// сотрудника удалили после выборки
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error finding entitlements of employee %d: %w", employeeId, err)
+		}
+		for _, item := range entitlements.Entitlements {
+			accountId := cmp.Or(accountIds[item.ApplicationName][employeeId], provisioning.AccountId(employeeId))
+			if expected[item.ApplicationName] == nil {
+				expected[item.ApplicationName] = map[string]expectedAccount{}
+			}
+			account := expected[item.ApplicationName][accountId]
+			account.EmployeeId = employeeId
+			account.Entitlements = append(account.Entitlements, item.EntitlementName)
+			expected[item.ApplicationName][accountId] = account
+		}
+	}
+	return expected, nil
+}
+
+// saveTx сохраняет расхождения целевой системы: новые добавляются, изменившиеся открываются заново,
+// принятые без изменений остаются принятыми, пропавшие закрываются
+func (s *Service) saveTx(ctx context.Context, tx *sqlx.Tx, application string, findings []Entity, now time.Time,
+	report *Report) error {
+	active, err := s.repo.FindActiveTx(ctx, tx, application)
+	if err != nil {
+		return fmt.Errorf("error finding findings of application %s: %w", application, err)
+	}
+	existing := map[string]Entity{}
+	for _, finding := range active {
+		existing[finding.AccountId+"/"+finding.Type] = finding
+	}
+
+	for _, finding := range findings {
+		key := finding.AccountId + "/" + finding.Type
+		stored, ok := existing[key]
+		delete(existing, key)
+		switch {
+		case !ok:
+			if err := s.repo.CreateTx(ctx, tx, finding, now); err != nil {
+				return fmt.Errorf("error creating finding of account %s in %s: %w", finding.AccountId, application, err)
+			}
+		case stored.State != StateOpen && sameEntitlements(stored, finding):
+			if err := s.repo.SeenTx(ctx, tx, stored.Id, now); err != nil {
+				return fmt.Errorf("error updating finding %d: %w", stored.Id, err)
+			}
+			continue
+		default:
+			finding.Id = stored.Id
+			if err := s.repo.ReopenTx(ctx, tx, finding, now); err != nil {
+				return fmt.Errorf("error updating finding %d: %w", stored.Id, err)
+			}
+		}
+		switch finding.Type {
+		case TypeOrphan:
+			report.Orphans++
+		case TypeMissing:
+			report.Missing++
+		case TypeDrift:
+			report.Drift++
+		}
+	}
+
+	for _, stored := range existing {
+		if err := s.repo.CloseTx(ctx, tx, stored.Id, now); err != nil {
+			return fmt.Errorf("error closing finding %d: %w", stored.Id, err)
+		}
+		report.Resolved++
+	}
+	return nil
+}
+
+// Remediate устраняет открытое расхождение: revoke отзывает лишние права в целевой системе,
+// adopt и ignore принимают расхождение, и следующие сверки не открывают его, пока оно не изменится.
+// adopt только связывает лишнюю учётную запись (orphan) с сотрудником: провижининг и сверка работают с ней,
+// а не с provisioning.AccountId, и её права становятся правами сотрудника. Лишние права (drift) adopt
+// не принимает: провижининг всё равно отзовёт их, если роли сотрудника их не дают
+func (s *Service) Remediate(ctx context.Context, id int64, request RemediateRequest, actor string) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("remediating account finding panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("remediating account finding: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("remediating account finding: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("remediating account finding: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	finding, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("account finding with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding account finding with id %d: %w", id, err)
+	}
+	if finding.State != StateOpen {
+		return Response{}, common.ConflictError{Message: fmt.Sprintf("account finding %d is already %s", id, finding.State)}
+	}
+
+	switch request.Action {
+	case ActionRevoke:
+		if err = s.revoke(ctx, finding); err != nil {
+			return Response{}, err
+		}
+		finding.State = StateRevoked
+	case ActionAdopt:
+		if finding.Type != TypeOrphan {
+			return Response{}, common.ConflictError{
+				Message: fmt.Sprintf("%s account cannot be adopted, only orphan account can be linked to employee", finding.Type),
+			}
+		}
+		if request.EmployeeId != 0 {
+			finding.EmployeeId = sql.NullInt64{Int64: request.EmployeeId, Valid: true}
+		}
+		if !finding.EmployeeId.Valid {
+			return Response{}, common.RequestValidatorError{Message: "employee_id is required to adopt account without employee"}
+		}
+		isExists, err := s.repo.EmployeeExistsTx(ctx, tx, finding.EmployeeId.Int64)
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding employee with id %d: %w", finding.EmployeeId.Int64, err)
+		}
+		if !isExists {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", finding.EmployeeId.Int64)}
+		}
+		err = s.repo.LinkAccountTx(ctx, tx, provisioning.AccountLinkEntity{
+			Application: finding.Application,
+			AccountId:   finding.AccountId,
+			EmployeeId:  finding.EmployeeId.Int64,
+		})
+		if err != nil {
+			return Response{}, fmt.Errorf("error linking account %s in %s to employee %d: %w", finding.AccountId,
+				finding.Application, finding.EmployeeId.Int64, err)
+		}
+		finding.State = StateAdopted
+	case ActionIgnore:
+		finding.State = StateIgnored
+	}
+
+	finding.ResolvedBy = sql.NullString{String: actor, Valid: true}
+	finding.Comment = sql.NullString{String: request.Comment, Valid: request.Comment != ""}
+	if finding, err = s.repo.ResolveTx(ctx, tx, finding); err != nil {
+		return Response{}, fmt.Errorf("error resolving account finding %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "account_finding." + request.Action,
+		EntityType: "account_finding",
+		EntityId:   id,
+		Details: map[string]any{
+			"application": finding.Application,
+			"account_id":  finding.AccountId,
+			"type":        finding.Type,
+			"comment":     request.Comment,
+		},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit log: %w", err)
+	}
+	return finding.toResponse(), nil
+}
+
+// revoke отзывает в целевой системе права, которых сотрудник не должен иметь;
+// учётная запись без ожидаемого владельца (orphan) блокируется
+func (s *Service) revoke(ctx context.Context, finding Entity) error {
+	if finding.Type == TypeMissing {
+		return common.ConflictError{Message: "missing account has nothing to revoke"}
+	}
+	connector, ok := s.registry.Get(finding.Application)
+	if !ok {
+		return common.ConflictError{Message: fmt.Sprintf("application %s has no connector", finding.Application)}
+	}
+	accounts, err := connector.ListAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing accounts of %s: %w", finding.Application, err)
+	}
+	i := slices.IndexFunc(accounts, func(item provisioning.Account) bool { return item.Id == finding.AccountId })
+	if i < 0 {
+		// учётную запись уже удалили в целевой системе
+		return nil
+	}
+	account := accounts[i]
+	for _, entitlement := range account.Entitlements {
+		if finding.Type == TypeOrphan || slices.Contains(finding.ExtraEntitlements, entitlement) {
+			if err := connector.RevokeEntitlement(ctx, account.Id, entitlement); err != nil {
+				return fmt.Errorf("error revoking %s from account %s in %s: %w", entitlement, account.Id, finding.Application, err)
+			}
+		}
+	}
+	if finding.Type == TypeOrphan && !account.Disabled {
+		if err := connector.DisableAccount(ctx, account.Id); err != nil {
+			return fmt.Errorf("error disabling account %s in %s: %w", account.Id, finding.Application, err)
+		}
+	}
+	return nil
+}
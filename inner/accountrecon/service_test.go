@@ -0,0 +1,342 @@
+package accountrecon
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/application"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/provisioning"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	activeEmployeesQuery = regexp.QuoteMeta("SELECT id FROM employee WHERE status = 'active' ORDER BY id")
+	linksQuery           = regexp.QuoteMeta("SELECT application, account_id, employee_id FROM provisioning_account")
+	unlinkQuery          = regexp.QuoteMeta("DELETE FROM provisioning_account WHERE application = $1 AND employee_id = $2")
+	linkQuery            = regexp.QuoteMeta("INSERT INTO provisioning_account (application, account_id, employee_id) VALUES ($1, $2, $3)")
+	pendingQuery         = regexp.QuoteMeta("SELECT employee_id FROM provisioning_task WHERE state = 'pending'")
+	lockQuery            = regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")
+	activeFindingsQuery  = regexp.QuoteMeta("SELECT * FROM account_finding WHERE application = $1 AND state IN ('open', 'ignored', 'adopted')")
+	createQuery          = regexp.QuoteMeta("INSERT INTO account_finding (application, account_id, employee_id, type, missing_entitlements,")
+	seenQuery            = regexp.QuoteMeta("UPDATE account_finding SET last_seen_at = $2 WHERE id = $1")
+	reopenQuery          = regexp.QuoteMeta("UPDATE account_finding SET employee_id = $2, missing_entitlements = $3, extra_entitlements = $4,")
+	closeQuery           = regexp.QuoteMeta("UPDATE account_finding SET state = 'resolved', resolved_at = $2 WHERE id = $1")
+	findTxQuery          = regexp.QuoteMeta("SELECT * FROM account_finding WHERE id = $1 FOR UPDATE")
+	resolveQuery         = regexp.QuoteMeta("UPDATE account_finding SET state = $2, employee_id = $3, resolved_by = $4, comment = $5")
+	employeeExistsQuery  = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE id = $1)")
+	auditQuery           = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	linkColumns          = []string{"application", "account_id", "employee_id"}
+	findingColumns       = []string{"id", "application", "account_id", "employee_id", "type", "missing_entitlements",
+		"extra_entitlements", "state", "resolved_by", "comment", "resolved_at", "first_seen_at", "last_seen_at"}
+)
+
+type MockEntitlementSvc struct {
+	mock.Mock
+}
+
+func (m *MockEntitlementSvc) EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (application.EmployeeEntitlementsResponse, error) {
+	args := m.Called(employeeId, applicationId)
+	return args.Get(0).(application.EmployeeEntitlementsResponse), args.Error(1)
+}
+
+// failingConnector целевая система, которая недоступна
+type failingConnector struct {
+	provisioning.Connector
+}
+
+func (c failingConnector) ListAccounts(ctx context.Context) ([]provisioning.Account, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newTestService(t *testing.T, registry *provisioning.Registry) (*Service, sqlmock.Sqlmock, *MockEntitlementSvc) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	entitlements := &MockEntitlementSvc{}
+	srv := NewService(NewAccountReconRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), entitlements, registry,
+		validator.NewValidator())
+	return srv, dbMock, entitlements
+}
+
+// registry с файловыми коннекторами систем ad и sap; в ad заведены учётные записи accounts
+func newTestRegistry(t *testing.T, accounts ...provisioning.Account) *provisioning.Registry {
+	registry, err := provisioning.NewFileRegistry(t.TempDir(), provisioning.FormatJson, "ad,sap")
+	assert.NoError(t, err)
+	connector, _ := registry.Get("ad")
+	for _, account := range accounts {
+		assert.NoError(t, connector.CreateAccount(context.Background(), account))
+		for _, entitlement := range account.Entitlements {
+			assert.NoError(t, connector.GrantEntitlement(context.Background(), account.Id, entitlement))
+		}
+		if account.Disabled {
+			assert.NoError(t, connector.DisableAccount(context.Background(), account.Id))
+		}
+	}
+	return registry
+}
+
+func adEntitlements(employeeId int64, names ...string) application.EmployeeEntitlementsResponse {
+	response := application.EmployeeEntitlementsResponse{EmployeeId: employeeId, Entitlements: []application.EmployeeEntitlement{}}
+	for _, name := range names {
+		response.Entitlements = append(response.Entitlements,
+			application.EmployeeEntitlement{EntitlementName: name, ApplicationName: "ad"})
+	}
+	return response
+}
+
+func TestService_Reconcile(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("should save new findings, keep accepted ones and close vanished ones", func(t *testing.T) {
+		registry := newTestRegistry(t,
+			provisioning.Account{Id: "7", EmployeeId: 7, Entitlements: []string{"vpn", "admin"}},
+			provisioning.Account{Id: "99", EmployeeId: 99, Entitlements: []string{"vpn"}},
+		)
+		srv, dbMock, entitlements := newTestService(t, registry)
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(adEntitlements(7, "vpn", "mail"), nil)
+		entitlements.On("EmployeeEntitlements", int64(8), int64(0)).Return(adEntitlements(8, "vpn"), nil)
+		entitlements.On("EmployeeEntitlements", int64(9), int64(0)).Return(adEntitlements(9, "vpn"), nil)
+		dbMock.ExpectQuery(activeEmployeesQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8).AddRow(9))
+		dbMock.ExpectQuery(linksQuery).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectQuery(pendingQuery).WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(9))
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("ad").WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "99", 99, TypeOrphan, "{}", "{vpn}", StateIgnored, "kc-admin", "test account", now, now, now).
+			AddRow(2, "ad", "50", 50, TypeOrphan, "{}", "{vpn}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectExec(createQuery).WithArgs("ad", "7", int64(7), TypeDrift, `{"mail"}`, `{"admin"}`, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(createQuery).WithArgs("ad", "8", int64(8), TypeMissing, `{"vpn"}`, "{}", now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(seenQuery).WithArgs(int64(1), now).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(closeQuery).WithArgs(int64(2), now).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows(findingColumns))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Reconcile(ctx, now)
+
+		a.NoError(err)
+		a.Equal(Report{Applications: 2, Accounts: 2, Missing: 1, Drift: 1, Resolved: 1, Errors: []string{}}, report)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should reopen accepted finding when it changes", func(t *testing.T) {
+		registry := newTestRegistry(t, provisioning.Account{Id: "99", EmployeeId: 99, Entitlements: []string{"vpn", "admin"}})
+		srv, dbMock, _ := newTestService(t, registry)
+		dbMock.ExpectQuery(activeEmployeesQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbMock.ExpectQuery(linksQuery).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectQuery(pendingQuery).WillReturnRows(sqlmock.NewRows([]string{"employee_id"}))
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("ad").WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "99", 99, TypeOrphan, "{}", "{vpn}", StateIgnored, "kc-admin", "test account", now, now, now))
+		dbMock.ExpectExec(reopenQuery).WithArgs(int64(1), int64(99), "{}", `{"admin","vpn"}`, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows(findingColumns))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Reconcile(ctx, now)
+
+		a.NoError(err)
+		a.Equal(1, report.Orphans)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should expect adopted account instead of default one", func(t *testing.T) {
+		registry := newTestRegistry(t, provisioning.Account{Id: "svc-backup", Entitlements: []string{"backup"}})
+		srv, dbMock, entitlements := newTestService(t, registry)
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(adEntitlements(7, "backup"), nil)
+		dbMock.ExpectQuery(activeEmployeesQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		dbMock.ExpectQuery(linksQuery).WillReturnRows(sqlmock.NewRows(linkColumns).AddRow("ad", "svc-backup", 7))
+		dbMock.ExpectQuery(pendingQuery).WillReturnRows(sqlmock.NewRows([]string{"employee_id"}))
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("ad").WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "svc-backup", 7, TypeOrphan, "{}", "{backup}", StateAdopted, "kc-admin", "backup", now, now, now))
+		dbMock.ExpectExec(closeQuery).WithArgs(int64(1), now).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows(findingColumns))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Reconcile(ctx, now)
+
+		a.NoError(err)
+		a.Equal(Report{Applications: 2, Accounts: 1, Resolved: 1, Errors: []string{}}, report)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should report unavailable application and reconcile the rest", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		registry.Register("crm", failingConnector{})
+		srv, dbMock, _ := newTestService(t, registry)
+		dbMock.ExpectQuery(activeEmployeesQuery).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		dbMock.ExpectQuery(linksQuery).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectQuery(pendingQuery).WillReturnRows(sqlmock.NewRows([]string{"employee_id"}))
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("ad").WillReturnRows(sqlmock.NewRows(findingColumns))
+		dbMock.ExpectQuery(activeFindingsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows(findingColumns))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Reconcile(ctx, now)
+
+		a.NoError(err)
+		a.Equal(2, report.Applications)
+		a.Equal([]string{"crm: connection refused"}, report.Errors)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Remediate(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("should disable orphan account and revoke its entitlements", func(t *testing.T) {
+		registry := newTestRegistry(t, provisioning.Account{Id: "99", EmployeeId: 99, Entitlements: []string{"vpn", "admin"}})
+		srv, dbMock, _ := newTestService(t, registry)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "99", 99, TypeOrphan, "{}", "{admin,vpn}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectQuery(resolveQuery).WithArgs(int64(1), StateRevoked, int64(99), "kc-admin", nil).
+			WillReturnRows(sqlmock.NewRows(findingColumns).
+				AddRow(1, "ad", "99", 99, TypeOrphan, "{}", "{admin,vpn}", StateRevoked, "kc-admin", nil, now, now, now))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "account_finding.revoke", "account_finding", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionRevoke}, "kc-admin")
+
+		a.NoError(err)
+		a.Equal(StateRevoked, got.State)
+		connector, _ := registry.Get("ad")
+		accounts, _ := connector.ListAccounts(ctx)
+		a.Equal([]provisioning.Account{{Id: "99", EmployeeId: 99, Disabled: true, Entitlements: []string{}}}, accounts)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should revoke only extra entitlements on drift", func(t *testing.T) {
+		registry := newTestRegistry(t, provisioning.Account{Id: "7", EmployeeId: 7, Entitlements: []string{"vpn", "admin"}})
+		srv, dbMock, _ := newTestService(t, registry)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "7", 7, TypeDrift, "{mail}", "{admin}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectQuery(resolveQuery).WithArgs(int64(1), StateRevoked, int64(7), "kc-admin", nil).
+			WillReturnRows(sqlmock.NewRows(findingColumns).
+				AddRow(1, "ad", "7", 7, TypeDrift, "{mail}", "{admin}", StateRevoked, "kc-admin", nil, now, now, now))
+		dbMock.ExpectExec(auditQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionRevoke}, "kc-admin")
+
+		a.NoError(err)
+		connector, _ := registry.Get("ad")
+		accounts, _ := connector.ListAccounts(ctx)
+		a.Equal([]provisioning.Account{{Id: "7", EmployeeId: 7, Entitlements: []string{"vpn"}}}, accounts)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should adopt orphan account with owner and link it to employee", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "svc-backup", nil, TypeOrphan, "{}", "{backup}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectQuery(employeeExistsQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectExec(unlinkQuery).WithArgs("ad", int64(7)).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectExec(linkQuery).WithArgs("ad", "svc-backup", int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(resolveQuery).WithArgs(int64(1), StateAdopted, int64(7), "kc-admin", "backup service account").
+			WillReturnRows(sqlmock.NewRows(findingColumns).
+				AddRow(1, "ad", "svc-backup", 7, TypeOrphan, "{}", "{backup}", StateAdopted, "kc-admin",
+					"backup service account", now, now, now))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "account_finding.adopt", "account_finding", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Remediate(ctx, 1,
+			RemediateRequest{Action: ActionAdopt, EmployeeId: 7, Comment: "backup service account"}, "kc-admin")
+
+		a.NoError(err)
+		a.Equal(StateAdopted, got.State)
+		a.Equal(int64(7), got.EmployeeId)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should require owner to adopt account without employee", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "svc-backup", nil, TypeOrphan, "{}", "{backup}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionAdopt, Comment: "backup"}, "kc-admin")
+
+		a.ErrorAs(err, &common.RequestValidatorError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for adopting drift", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "7", 7, TypeDrift, "{mail}", "{admin}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionAdopt, Comment: "admin is needed"}, "kc-admin")
+
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for revoke of missing account", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "8", 8, TypeMissing, "{vpn}", "{}", StateOpen, nil, nil, nil, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionRevoke}, "kc-admin")
+
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for finding that is not open", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(findingColumns).
+			AddRow(1, "ad", "8", 8, TypeMissing, "{vpn}", "{}", StateIgnored, "kc-admin", "on leave", now, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionIgnore, Comment: "again"}, "kc-admin")
+
+		a.ErrorAs(err, &common.ConflictError{})
+		a.ErrorContains(err, "account finding 1 is already ignored")
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should require comment to ignore finding", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, newTestRegistry(t))
+
+		_, err := srv.Remediate(ctx, 1, RemediateRequest{Action: ActionIgnore}, "kc-admin")
+
+		a.ErrorAs(err, &common.RequestValidatorError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Find(t *testing.T) {
+	var a = assert.New(t)
+
+	srv, _, _ := newTestService(t, provisioning.NewRegistry())
+	_, err := srv.Find(context.Background(), FilterRequest{Type: "unknown"})
+	a.ErrorAs(err, &common.RequestValidatorError{})
+}
@@ -0,0 +1,31 @@
+package accountrecon
+
+import (
+	"context"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Reconciler сверяет учётные записи целевых систем, реализуется Service
+type Reconciler interface {
+	Reconcile(ctx context.Context, now time.Time) (Report, error)
+}
+
+// ScheduledReconcile сверка учётных записей для планировщика: пишет отчёт в лог и возвращает число сверенных записей
+func ScheduledReconcile(reconciler Reconciler, logger *common.Logger) func(ctx context.Context, now time.Time) (int, error) {
+	return func(ctx context.Context, now time.Time) (int, error) {
+		report, err := reconciler.Reconcile(ctx, now)
+		if err != nil {
+			return 0, err
+		}
+		if len(report.Errors) > 0 {
+			logger.ErrorCtx(ctx, "account reconciliation: applications not reconciled", zap.Strings("errors", report.Errors))
+		}
+		logger.DebugCtx(ctx, "account reconciliation: reconciled accounts",
+			zap.Int("applications", report.Applications), zap.Int("accounts", report.Accounts),
+			zap.Int("orphans", report.Orphans), zap.Int("missing", report.Missing), zap.Int("drift", report.Drift),
+			zap.Int("resolved", report.Resolved))
+		return report.Accounts, nil
+	}
+}
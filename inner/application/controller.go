@@ -0,0 +1,658 @@
+package application
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server             *web.Server
+	applicationService Svc
+	employees          EmployeeVisibility
+	logger             *common.Logger
+}
+
+// интерфейс сервиса application.Service
+type Svc interface {
+	GetAll(ctx context.Context) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Create(ctx context.Context, request CreateRequest, actor string) (Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error)
+	Delete(ctx context.Context, id int64, actor string) error
+	GetEntitlements(ctx context.Context, applicationId int64) ([]EntitlementResponse, error)
+	AddEntitlement(ctx context.Context, applicationId int64, request EntitlementRequest, actor string) (EntitlementResponse, error)
+	RemoveEntitlement(ctx context.Context, applicationId int64, entitlementId int64, actor string) error
+	GetRoleEntitlements(ctx context.Context, roleId int64) ([]EntitlementResponse, error)
+	AddRoleEntitlement(ctx context.Context, roleId int64, request RoleEntitlementRequest, actor string) ([]EntitlementResponse, error)
+	RemoveRoleEntitlement(ctx context.Context, roleId int64, entitlementId int64, actor string) ([]EntitlementResponse, error)
+	EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (EmployeeEntitlementsResponse, error)
+}
+
+// EmployeeVisibility проверка, что сотрудник виден вызывающему
+type EmployeeVisibility interface {
+	CheckVisible(ctx context.Context, employeeId int64, principal common.Principal) error
+}
+
+func NewController(server *web.Server, svc Svc, employees EmployeeVisibility, logger *common.Logger) *Controller {
+	return &Controller{
+		server:             server,
+		applicationService: svc,
+		employees:          employees,
+		logger:             logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/applications", c.GetAllApplications)
+	c.server.GroupApiV1.Post("/applications", c.CreateApplication)
+	c.server.GroupApiV1.Get("/applications/:id", c.GetApplication)
+	c.server.GroupApiV1.Put("/applications/:id", c.UpdateApplication)
+	c.server.GroupApiV1.Delete("/applications/:id", c.DeleteApplication)
+	c.server.GroupApiV1.Get("/applications/:id/entitlements", c.GetEntitlements)
+	c.server.GroupApiV1.Post("/applications/:id/entitlements", c.AddEntitlement)
+	c.server.GroupApiV1.Delete("/applications/:id/entitlements/:entitlementId", c.RemoveEntitlement)
+	c.server.GroupApiV1.Get("/roles/:id/entitlements", c.GetRoleEntitlements)
+	c.server.GroupApiV1.Post("/roles/:id/entitlements", c.AddRoleEntitlement)
+	c.server.GroupApiV1.Delete("/roles/:id/entitlements/:entitlementId", c.RemoveRoleEntitlement)
+	c.server.GroupApiV1.Get("/employees/:id/entitlements", c.GetEmployeeEntitlements)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications"
+// @Description Get all applications (target systems).
+// @Summary get all applications
+// @ID get-all-applications
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[[]application.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications [get]
+func (c *Controller) GetAllApplications(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод GetAll сервиса application.Service
+	response, err := c.applicationService.GetAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all applications", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all applications", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications/:id"
+// @Description Get application by id.
+// @Summary get application
+// @ID get-application
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Success 200 {object} common.Response[application.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id} [get]
+func (c *Controller) GetApplication(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+
+	// вызываем метод FindById сервиса application.Service
+	response, err := c.applicationService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications"
+// @Description Create application (target system) without entitlements.
+// @Summary create application
+// @ID create-application
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body application.CreateRequest true "application"
+// @Success 200 {object} common.Response[application.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications [post]
+func (c *Controller) CreateApplication(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create application: received request", zap.Any("request", request))
+
+	// вызываем метод Create сервиса application.Service
+	response, err := c.applicationService.Create(ctx.Context(), request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create application", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create application", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/applications/:id"
+// @Description Update application name and description.
+// @Summary update application
+// @ID update-application
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Param request body application.UpdateRequest true "application"
+// @Success 200 {object} common.Response[application.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id} [put]
+func (c *Controller) UpdateApplication(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "update application: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод Update сервиса application.Service
+	response, err := c.applicationService.Update(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/applications/:id"
+// @Description Delete application with its entitlements. Roles stop granting them.
+// @Summary delete application
+// @ID delete-application
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id} [delete]
+func (c *Controller) DeleteApplication(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+
+	// вызываем метод Delete сервиса application.Service
+	if err := c.applicationService.Delete(ctx.Context(), id, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete application", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/applications/:id/entitlements"
+// @Description Get entitlements of application.
+// @Summary get application entitlements
+// @ID get-application-entitlements
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id}/entitlements [get]
+func (c *Controller) GetEntitlements(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+
+	// вызываем метод GetEntitlements сервиса application.Service
+	response, err := c.applicationService.GetEntitlements(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get application entitlements", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get application entitlements", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/applications/:id/entitlements"
+// @Description Add entitlement to application. Entitlement name is unique within application.
+// @Summary add application entitlement
+// @ID add-application-entitlement
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Param request body application.EntitlementRequest true "entitlement"
+// @Success 200 {object} common.Response[application.EntitlementResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id}/entitlements [post]
+func (c *Controller) AddEntitlement(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+	var request EntitlementRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "add application entitlement: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод AddEntitlement сервиса application.Service
+	response, err := c.applicationService.AddEntitlement(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add application entitlement", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add application entitlement", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/applications/:id/entitlements/:entitlementId"
+// @Description Remove entitlement from application. Roles stop granting it.
+// @Summary remove application entitlement
+// @ID remove-application-entitlement
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id application"
+// @Param entitlementId path int64 true "id entitlement"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /applications/{id}/entitlements/{entitlementId} [delete]
+func (c *Controller) RemoveEntitlement(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+	entitlementId, err := strconv.ParseInt(ctx.Params("entitlementId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid entitlement id")
+	}
+
+	// вызываем метод RemoveEntitlement сервиса application.Service
+	if err := c.applicationService.RemoveEntitlement(ctx.Context(), id, entitlementId, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove application entitlement", zap.Int64("id", id), zap.Int64("entitlementId", entitlementId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove application entitlement", zap.Int64("id", id), zap.Int64("entitlementId", entitlementId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/:id/entitlements"
+// @Description Get entitlements bundled into role across all applications.
+// @Summary get role entitlements
+// @ID get-role-entitlements
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id role"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/{id}/entitlements [get]
+func (c *Controller) GetRoleEntitlements(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+
+	// вызываем метод GetRoleEntitlements сервиса application.Service
+	response, err := c.applicationService.GetRoleEntitlements(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get role entitlements", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get role entitlements", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/roles/:id/entitlements"
+// @Description Add entitlement to role: every employee with the role gets it.
+// @Summary add role entitlement
+// @ID add-role-entitlement
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id role"
+// @Param request body application.RoleEntitlementRequest true "entitlement"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/{id}/entitlements [post]
+func (c *Controller) AddRoleEntitlement(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+	var request RoleEntitlementRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "add role entitlement: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод AddRoleEntitlement сервиса application.Service
+	response, err := c.applicationService.AddRoleEntitlement(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add role entitlement", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add role entitlement", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/roles/:id/entitlements/:entitlementId"
+// @Description Remove entitlement from role.
+// @Summary remove role entitlement
+// @ID remove-role-entitlement
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id role"
+// @Param entitlementId path int64 true "id entitlement"
+// @Success 200 {object} common.Response[[]application.EntitlementResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/{id}/entitlements/{entitlementId} [delete]
+func (c *Controller) RemoveRoleEntitlement(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+	entitlementId, err := strconv.ParseInt(ctx.Params("entitlementId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid entitlement id")
+	}
+
+	// вызываем метод RemoveRoleEntitlement сервиса application.Service
+	response, err := c.applicationService.RemoveRoleEntitlement(ctx.Context(), id, entitlementId, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove role entitlement", zap.Int64("id", id), zap.Int64("entitlementId", entitlementId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove role entitlement", zap.Int64("id", id), zap.Int64("entitlementId", entitlementId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/entitlements"
+// @Description Get entitlements the employee ends up with through all effective roles (direct, rule, group,
+// @Description delegation, break-glass) and the roles granting each entitlement.
+// @Summary get employee entitlements
+// @ID get-employee-entitlements
+// @Tags application
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param application_id query int64 false "only entitlements of this application"
+// @Success 200 {object} common.Response[application.EmployeeEntitlementsResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/entitlements [get]
+func (c *Controller) GetEmployeeEntitlements(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	applicationId, err := strconv.ParseInt(ctx.Query("application_id", "0"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid application id")
+	}
+
+	// невидимый вызывающему сотрудник считается не найденным
+	if err := c.employees.CheckVisible(ctx.Context(), employeeId, claims.Principal()); err != nil {
+		return err
+	}
+
+	// вызываем метод EmployeeEntitlements сервиса application.Service
+	response, err := c.applicationService.EmployeeEntitlements(ctx.Context(), employeeId, applicationId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee entitlements", zap.Int64("employeeId", employeeId),
+			zap.Int64("applicationId", applicationId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee entitlements", zap.Int64("employeeId", employeeId),
+			zap.Int64("applicationId", applicationId), zap.Error(err))
+		return err
+	}
+	return nil
+}
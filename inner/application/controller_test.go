@@ -0,0 +1,191 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса application.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, actor string) (Response, error) {
+	args := svc.Called(request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Delete(ctx context.Context, id int64, actor string) error {
+	args := svc.Called(id, actor)
+	return args.Error(0)
+}
+
+func (svc *MockService) GetEntitlements(ctx context.Context, applicationId int64) ([]EntitlementResponse, error) {
+	args := svc.Called(applicationId)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) AddEntitlement(
+	ctx context.Context,
+	applicationId int64,
+	request EntitlementRequest,
+	actor string,
+) (EntitlementResponse, error) {
+	args := svc.Called(applicationId, request, actor)
+	return args.Get(0).(EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) RemoveEntitlement(ctx context.Context, applicationId int64, entitlementId int64, actor string) error {
+	args := svc.Called(applicationId, entitlementId, actor)
+	return args.Error(0)
+}
+
+func (svc *MockService) GetRoleEntitlements(ctx context.Context, roleId int64) ([]EntitlementResponse, error) {
+	args := svc.Called(roleId)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) AddRoleEntitlement(
+	ctx context.Context,
+	roleId int64,
+	request RoleEntitlementRequest,
+	actor string,
+) ([]EntitlementResponse, error) {
+	args := svc.Called(roleId, request, actor)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) RemoveRoleEntitlement(
+	ctx context.Context,
+	roleId int64,
+	entitlementId int64,
+	actor string,
+) ([]EntitlementResponse, error) {
+	args := svc.Called(roleId, entitlementId, actor)
+	return args.Get(0).([]EntitlementResponse), args.Error(1)
+}
+
+func (svc *MockService) EmployeeEntitlements(
+	ctx context.Context,
+	employeeId int64,
+	applicationId int64,
+) (EmployeeEntitlementsResponse, error) {
+	args := svc.Called(employeeId, applicationId)
+	return args.Get(0).(EmployeeEntitlementsResponse), args.Error(1)
+}
+
+// StubVisibility скрывает от вызывающего перечисленных сотрудников
+type StubVisibility struct {
+	hidden []int64
+}
+
+func (s StubVisibility) CheckVisible(ctx context.Context, employeeId int64, principal common.Principal) error {
+	if slices.Contains(s.hidden, employeeId) {
+		return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+	return nil
+}
+
+func newTestServer(svc Svc, employees EmployeeVisibility, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-admin", roles...))
+	NewController(server, svc, employees, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_AddRoleEntitlement(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass actor to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmAdmin)
+		svc.On("AddRoleEntitlement", int64(3), RoleEntitlementRequest{EntitlementId: 5}, "kc-admin").
+			Return([]EntitlementResponse{{Id: 5}}, nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/3/entitlements", strings.NewReader(`{"entitlement_id": 5}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 for user", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/3/entitlements", strings.NewReader(`{"entitlement_id": 5}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "AddRoleEntitlement", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_GetEmployeeEntitlements(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should filter by application", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmUser)
+		svc.On("EmployeeEntitlements", int64(7), int64(1)).Return(EmployeeEntitlementsResponse{EmployeeId: 7}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/entitlements?application_id=1", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 404 for employee not visible to caller", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{hidden: []int64{7}}, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/entitlements", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+		svc.AssertNotCalled(t, "EmployeeEntitlements", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return 404 for unknown application", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, StubVisibility{}, web.IdmUser)
+		svc.On("EmployeeEntitlements", int64(7), int64(9)).
+			Return(EmployeeEntitlementsResponse{}, common.NotFoundError{Message: "application with id 9 not found"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/7/entitlements?application_id=9", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+}
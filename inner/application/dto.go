@@ -0,0 +1,95 @@
+package application
+
+import (
+	"time"
+)
+
+// Entity целевая система
+type Entity struct {
+	Id          int64     `db:"id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreateAt    time.Time `db:"create_at"`
+	UpdateAt    time.Time `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:          e.Id,
+		Name:        e.Name,
+		Description: e.Description,
+		CreateAt:    e.CreateAt,
+		UpdateAt:    e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	CreateAt    time.Time `json:"create_at"`
+	UpdateAt    time.Time `json:"update_at"`
+}
+
+// EntitlementEntity право в целевой системе
+type EntitlementEntity struct {
+	Id              int64     `db:"id"`
+	ApplicationId   int64     `db:"application_id"`
+	ApplicationName string    `db:"application_name"`
+	Name            string    `db:"name"`
+	Description     string    `db:"description"`
+	CreateAt        time.Time `db:"create_at"`
+	UpdateAt        time.Time `db:"update_at"`
+}
+
+func (e *EntitlementEntity) toResponse() EntitlementResponse {
+	return EntitlementResponse{
+		Id:              e.Id,
+		ApplicationId:   e.ApplicationId,
+		ApplicationName: e.ApplicationName,
+		Name:            e.Name,
+		Description:     e.Description,
+		CreateAt:        e.CreateAt,
+		UpdateAt:        e.UpdateAt,
+	}
+}
+
+type EntitlementResponse struct {
+	Id              int64     `json:"id"`
+	ApplicationId   int64     `json:"application_id"`
+	ApplicationName string    `json:"application_name"`
+	Name            string    `json:"name"`
+	Description     string    `json:"description,omitempty"`
+	CreateAt        time.Time `json:"create_at"`
+	UpdateAt        time.Time `json:"update_at"`
+}
+
+// RoleEntitlementEntity право, которое даёт роль
+type RoleEntitlementEntity struct {
+	RoleId int64 `db:"role_id"`
+	EntitlementEntity
+}
+
+// EmployeeEntitlementsResponse права сотрудника в целевых системах
+type EmployeeEntitlementsResponse struct {
+	EmployeeId   int64                 `json:"employee_id"`
+	EmployeeName string                `json:"employee_name"`
+	Status       string                `json:"status"`
+	Entitlements []EmployeeEntitlement `json:"entitlements"`
+}
+
+// EmployeeEntitlement право сотрудника и роли, через которые оно получено
+type EmployeeEntitlement struct {
+	EntitlementId   int64  `json:"entitlement_id"`
+	EntitlementName string `json:"entitlement_name"`
+	ApplicationId   int64  `json:"application_id"`
+	ApplicationName string `json:"application_name"`
+	Roles           []Role `json:"roles"`
+}
+
+// Role роль сотрудника, которая даёт право, и способы её получения (direct, rule, group, delegation, break_glass)
+type Role struct {
+	RoleId   int64    `json:"role_id"`
+	RoleName string   `json:"role_name"`
+	Sources  []string `json:"sources"`
+}
@@ -0,0 +1,223 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// права вместе с именем целевой системы
+const selectEntitlements = `SELECT e.id, e.application_id, a.name AS application_name, e.name, e.description,
+e.create_at, e.update_at
+FROM entitlement e JOIN application a ON a.id = e.application_id`
+
+// права роли во всех целевых системах
+const selectRoleEntitlements = selectEntitlements + ` JOIN role_entitlement re ON re.entitlement_id = e.id
+WHERE re.role_id = $1 ORDER BY a.name, e.name`
+
+// сотрудники, у которых есть роль из roles (начало запроса WITH RECURSIVE roles (id) AS (...)):
+// назначенная напрямую, по правилу или экстренным доступом, через группу или вложенную группу и по действующему замещению
+const selectRoleHolders = `, granted (id) AS (
+    SELECT group_id FROM group_role WHERE role_id IN (SELECT id FROM roles)
+    UNION
+    SELECT gc.child_id FROM group_child gc JOIN granted g ON gc.parent_id = g.id
+)
+SELECT employee_id FROM employee_role WHERE role_id IN (SELECT id FROM roles)
+UNION
+SELECT gm.employee_id FROM group_member gm JOIN granted g ON g.id = gm.group_id
+UNION
+SELECT d.deputy_id FROM delegation d JOIN delegation_role dr ON dr.delegation_id = d.id
+WHERE dr.role_id IN (SELECT id FROM roles) AND d.state IN ('scheduled', 'active') AND d.valid_from <= now()
+AND now() < d.valid_to
+ORDER BY 1`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewApplicationRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти все целевые системы
+func (r *Repository) GetAll(ctx context.Context) (applications []Entity, err error) {
+	err = r.db.SelectContext(ctx, &applications, "SELECT * FROM application ORDER BY name")
+	return applications, err
+}
+
+// найти целевую систему по id
+func (r *Repository) FindById(ctx context.Context, id int64) (application Entity, err error) {
+	err = r.db.GetContext(ctx, &application, "SELECT * FROM application WHERE id = $1", id)
+	return application, err
+}
+
+// найти целевую систему по id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (application Entity, err error) {
+	err = tx.GetContext(ctx, &application, "SELECT * FROM application WHERE id = $1", id)
+	return application, err
+}
+
+// поиск целевой системы по имени в рамках транзакции
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM application WHERE name = $1)"
+	err = tx.GetContext(ctx, &isExists, query, name)
+	return isExists, err
+}
+
+// добавить целевую систему в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, application Entity) (id int64, err error) {
+	query := "INSERT INTO application (name, description) VALUES ($1, $2) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, application.Name, application.Description)
+	return id, err
+}
+
+// изменить целевую систему в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, application Entity) error {
+	query := "UPDATE application SET name = $1, description = $2, update_at = now() WHERE id = $3"
+	_, err := tx.ExecContext(ctx, query, application.Name, application.Description, application.Id)
+	return err
+}
+
+// удалить целевую систему вместе с её правами в рамках транзакции; false, если системы нет
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM application WHERE id = $1", id)
+	return affected(result, err)
+}
+
+// найти права целевой системы
+func (r *Repository) FindEntitlements(ctx context.Context, applicationId int64) (entitlements []EntitlementEntity, err error) {
+	query := selectEntitlements + " WHERE e.application_id = $1 ORDER BY e.name"
+	err = r.db.SelectContext(ctx, &entitlements, query, applicationId)
+	return entitlements, err
+}
+
+// найти право по id в рамках транзакции
+func (r *Repository) FindEntitlementTx(ctx context.Context, tx *sqlx.Tx, id int64) (entitlement EntitlementEntity, err error) {
+	err = tx.GetContext(ctx, &entitlement, selectEntitlements+" WHERE e.id = $1", id)
+	return entitlement, err
+}
+
+// поиск права целевой системы по имени в рамках транзакции
+func (r *Repository) FindEntitlementByNameTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, name string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM entitlement WHERE application_id = $1 AND name = $2)"
+	err = tx.GetContext(ctx, &isExists, query, applicationId, name)
+	return isExists, err
+}
+
+// добавить право целевой системы в рамках транзакции
+func (r *Repository) CreateEntitlementTx(ctx context.Context, tx *sqlx.Tx, entitlement EntitlementEntity) (id int64, err error) {
+	query := "INSERT INTO entitlement (application_id, name, description) VALUES ($1, $2, $3) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, entitlement.ApplicationId, entitlement.Name, entitlement.Description)
+	return id, err
+}
+
+// удалить право целевой системы в рамках транзакции; false, если права в системе нет
+func (r *Repository) DeleteEntitlementTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, id int64) (bool, error) {
+	query := "DELETE FROM entitlement WHERE application_id = $1 AND id = $2"
+	result, err := tx.ExecContext(ctx, query, applicationId, id)
+	return affected(result, err)
+}
+
+// проверка существования роли
+func (r *Repository) RoleExists(ctx context.Context, roleId int64) (isExists bool, err error) {
+	err = r.db.GetContext(ctx, &isExists, "SELECT EXISTS(SELECT * FROM role WHERE id = $1)", roleId)
+	return isExists, err
+}
+
+// проверка существования роли в рамках транзакции
+func (r *Repository) RoleExistsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (isExists bool, err error) {
+	err = tx.GetContext(ctx, &isExists, "SELECT EXISTS(SELECT * FROM role WHERE id = $1)", roleId)
+	return isExists, err
+}
+
+// найти права, которые даёт роль
+func (r *Repository) FindRoleEntitlements(ctx context.Context, roleId int64) (entitlements []EntitlementEntity, err error) {
+	err = r.db.SelectContext(ctx, &entitlements, selectRoleEntitlements, roleId)
+	return entitlements, err
+}
+
+// найти права, которые даёт роль, в рамках транзакции
+func (r *Repository) FindRoleEntitlementsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (entitlements []EntitlementEntity, err error) {
+	err = tx.SelectContext(ctx, &entitlements, selectRoleEntitlements, roleId)
+	return entitlements, err
+}
+
+// добавить право в роль в рамках транзакции
+func (r *Repository) AddRoleEntitlementTx(ctx context.Context, tx *sqlx.Tx, roleId int64, entitlementId int64) error {
+	query := "INSERT INTO role_entitlement (role_id, entitlement_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, roleId, entitlementId)
+	return mapError(err, "entitlement not found")
+}
+
+// убрать право из роли в рамках транзакции; false, если роль его не давала
+func (r *Repository) RemoveRoleEntitlementTx(ctx context.Context, tx *sqlx.Tx, roleId int64, entitlementId int64) (bool, error) {
+	query := "DELETE FROM role_entitlement WHERE role_id = $1 AND entitlement_id = $2"
+	result, err := tx.ExecContext(ctx, query, roleId, entitlementId)
+	return affected(result, err)
+}
+
+// FindRoleHoldersTx находит сотрудников, у которых есть роль roleId: назначенная напрямую, по правилу
+// или экстренным доступом, через группу или вложенную группу и по действующему замещению
+func (r *Repository) FindRoleHoldersTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (employeeIds []int64, err error) {
+	query := "WITH RECURSIVE roles (id) AS (SELECT $1::bigint)" + selectRoleHolders
+	err = tx.SelectContext(ctx, &employeeIds, query, roleId)
+	return employeeIds, err
+}
+
+// FindEntitlementHoldersTx находит сотрудников, у которых есть роли, дающие право entitlementId целевой системы
+// applicationId; entitlementId 0 - любое право системы
+func (r *Repository) FindEntitlementHoldersTx(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	applicationId int64,
+	entitlementId int64,
+) (employeeIds []int64, err error) {
+	query := `WITH RECURSIVE roles (id) AS (
+    SELECT re.role_id FROM role_entitlement re JOIN entitlement e ON e.id = re.entitlement_id
+    WHERE e.application_id = $1 AND ($2 = 0 OR e.id = $2)
+)` + selectRoleHolders
+	err = tx.SelectContext(ctx, &employeeIds, query, applicationId, entitlementId)
+	return employeeIds, err
+}
+
+// FindByRoles права, которые дают роли roleIds; applicationId 0 - во всех целевых системах
+func (r *Repository) FindByRoles(ctx context.Context, roleIds []int64, applicationId int64) (entitlements []RoleEntitlementEntity, err error) {
+	query := `SELECT re.role_id, e.id, e.application_id, a.name AS application_name, e.name, e.description,
+e.create_at, e.update_at
+FROM role_entitlement re
+JOIN entitlement e ON e.id = re.entitlement_id
+JOIN application a ON a.id = e.application_id
+WHERE re.role_id = ANY($1) AND ($2::bigint = 0 OR e.application_id = $2)
+ORDER BY a.name, e.name, re.role_id`
+	err = r.db.SelectContext(ctx, &entitlements, query, pq.Int64Array(roleIds), applicationId)
+	return entitlements, err
+}
+
+// affected возвращает, затронул ли запрос хотя бы одну строку
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// mapError переводит нарушение внешнего ключа в NotFoundError
+func mapError(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: message}
+	}
+	return err
+}
@@ -0,0 +1,24 @@
+package application
+
+type CreateRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=155"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+func (r *CreateRequest) ToEntity() Entity {
+	return Entity{Name: r.Name, Description: r.Description}
+}
+
+type UpdateRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=155"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+type EntitlementRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=255"`
+	Description string `json:"description" validate:"max=1000"`
+}
+
+type RoleEntitlementRequest struct {
+	EntitlementId int64 `json:"entitlement_id" validate:"required,gt=0"`
+}
@@ -0,0 +1,559 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"slices"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	GetAll(ctx context.Context) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, application Entity) (int64, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, application Entity) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	FindEntitlements(ctx context.Context, applicationId int64) ([]EntitlementEntity, error)
+	FindEntitlementTx(ctx context.Context, tx *sqlx.Tx, id int64) (EntitlementEntity, error)
+	FindEntitlementByNameTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, name string) (bool, error)
+	CreateEntitlementTx(ctx context.Context, tx *sqlx.Tx, entitlement EntitlementEntity) (int64, error)
+	DeleteEntitlementTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, id int64) (bool, error)
+	RoleExists(ctx context.Context, roleId int64) (bool, error)
+	RoleExistsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (bool, error)
+	FindRoleEntitlements(ctx context.Context, roleId int64) ([]EntitlementEntity, error)
+	FindRoleEntitlementsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) ([]EntitlementEntity, error)
+	AddRoleEntitlementTx(ctx context.Context, tx *sqlx.Tx, roleId int64, entitlementId int64) error
+	RemoveRoleEntitlementTx(ctx context.Context, tx *sqlx.Tx, roleId int64, entitlementId int64) (bool, error)
+	FindRoleHoldersTx(ctx context.Context, tx *sqlx.Tx, roleId int64) ([]int64, error)
+	FindEntitlementHoldersTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, entitlementId int64) ([]int64, error)
+	FindByRoles(ctx context.Context, roleIds []int64, applicationId int64) ([]RoleEntitlementEntity, error)
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// AccessSvc фактический доступ сотрудника, по его ролям вычисляются права в целевых системах
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// ChangeListener получает сотрудников, у которых изменились права, потому что изменился состав прав роли.
+// Вызывается в транзакции изменения, например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	access    AccessSvc
+	validator Validator
+	listener  ChangeListener
+}
+
+func NewService(repo Repo, audit AuditRepo, access AccessSvc, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		access:    access,
+		validator: validator,
+	}
+}
+
+// SetChangeListener подключает получателя изменений прав сотрудников
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
+	applications, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all applications: %w", err)
+	}
+	response := make([]Response, 0, len(applications))
+	for _, item := range applications {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	application, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("application with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding application with id %d: %w", id, err)
+	}
+	return application.toResponse(), nil
+}
+
+// Create добавляет целевую систему без прав
+func (s *Service) Create(ctx context.Context, request CreateRequest, actor string) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating application panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating application: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating application: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating application: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding application by name: %s, %w", request.Name, err)
+	}
+	if isExist {
+		err = common.AlreadyExistsError{Message: fmt.Sprintf("application with name %s already exists", request.Name)}
+		return Response{}, err
+	}
+	id, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating application: %w", err)
+	}
+	application, err := s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding application with id %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "application.created",
+		EntityType: "application",
+		EntityId:   id,
+		Details:    application.toResponse(),
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return application.toResponse(), nil
+}
+
+// Update меняет имя и описание целевой системы. Имя должно остаться уникальным
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	err = s.change(ctx, id, actor, "application.updated", request, func(tx *sqlx.Tx, application Entity) error {
+		if application.Name != request.Name {
+			isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+			if err != nil {
+				return fmt.Errorf("error finding application by name: %s, %w", request.Name, err)
+			}
+			if isExist {
+				return common.AlreadyExistsError{Message: fmt.Sprintf("application with name %s already exists", request.Name)}
+			}
+		}
+		application.Name = request.Name
+		application.Description = request.Description
+		if err := s.repo.UpdateTx(ctx, tx, application); err != nil {
+			return fmt.Errorf("error updating application with id %d: %w", id, err)
+		}
+		updated, err := s.repo.FindByIdTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding application with id %d: %w", id, err)
+		}
+		response = updated.toResponse()
+		return nil
+	})
+	return response, err
+}
+
+// Delete удаляет целевую систему вместе с её правами; роли перестают их давать
+func (s *Service) Delete(ctx context.Context, id int64, actor string) error {
+	return s.change(ctx, id, actor, "application.deleted", nil, func(tx *sqlx.Tx, application Entity) error {
+		if err := s.notifyEntitlementHoldersTx(ctx, tx, id, 0); err != nil {
+			return err
+		}
+		if _, err := s.repo.DeleteTx(ctx, tx, id); err != nil {
+			return fmt.Errorf("error deleting application with id %d: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// GetEntitlements возвращает права целевой системы
+func (s *Service) GetEntitlements(ctx context.Context, applicationId int64) ([]EntitlementResponse, error) {
+	if _, err := s.FindById(ctx, applicationId); err != nil {
+		return []EntitlementResponse{}, err
+	}
+	entitlements, err := s.repo.FindEntitlements(ctx, applicationId)
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error finding entitlements of application %d: %w", applicationId, err)
+	}
+	return toEntitlementResponses(entitlements), nil
+}
+
+// AddEntitlement добавляет право в целевую систему. Имя права уникально в пределах системы
+func (s *Service) AddEntitlement(
+	ctx context.Context,
+	applicationId int64,
+	request EntitlementRequest,
+	actor string,
+) (response EntitlementResponse, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return EntitlementResponse{}, common.NewRequestValidatorError(err)
+	}
+	err = s.change(ctx, applicationId, actor, "application.entitlement_added", request, func(tx *sqlx.Tx, application Entity) error {
+		isExist, err := s.repo.FindEntitlementByNameTx(ctx, tx, applicationId, request.Name)
+		if err != nil {
+			return fmt.Errorf("error finding entitlement by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{
+				Message: fmt.Sprintf("entitlement with name %s already exists in application %s", request.Name, application.Name),
+			}
+		}
+		id, err := s.repo.CreateEntitlementTx(ctx, tx, EntitlementEntity{
+			ApplicationId: applicationId,
+			Name:          request.Name,
+			Description:   request.Description,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating entitlement: %w", err)
+		}
+		entitlement, err := s.repo.FindEntitlementTx(ctx, tx, id)
+		if err != nil {
+			return fmt.Errorf("error finding entitlement with id %d: %w", id, err)
+		}
+		response = entitlement.toResponse()
+		return nil
+	})
+	return response, err
+}
+
+// RemoveEntitlement удаляет право целевой системы; роли перестают его давать
+func (s *Service) RemoveEntitlement(ctx context.Context, applicationId int64, entitlementId int64, actor string) error {
+	details := map[string]any{"entitlement_id": entitlementId}
+	return s.change(ctx, applicationId, actor, "application.entitlement_removed", details, func(tx *sqlx.Tx, application Entity) error {
+		if err := s.notifyEntitlementHoldersTx(ctx, tx, applicationId, entitlementId); err != nil {
+			return err
+		}
+		removed, err := s.repo.DeleteEntitlementTx(ctx, tx, applicationId, entitlementId)
+		if err != nil {
+			return fmt.Errorf("error deleting entitlement with id %d: %w", entitlementId, err)
+		}
+		if !removed {
+			return common.NotFoundError{
+				Message: fmt.Sprintf("entitlement with id %d not found in application %d", entitlementId, applicationId),
+			}
+		}
+		return nil
+	})
+}
+
+// GetRoleEntitlements возвращает права, которые даёт роль, во всех целевых системах
+func (s *Service) GetRoleEntitlements(ctx context.Context, roleId int64) ([]EntitlementResponse, error) {
+	isExist, err := s.repo.RoleExists(ctx, roleId)
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error finding role with id %d: %w", roleId, err)
+	}
+	if !isExist {
+		return []EntitlementResponse{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", roleId)}
+	}
+	entitlements, err := s.repo.FindRoleEntitlements(ctx, roleId)
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error finding entitlements of role %d: %w", roleId, err)
+	}
+	return toEntitlementResponses(entitlements), nil
+}
+
+// AddRoleEntitlement добавляет право в роль: его получат все сотрудники с этой ролью
+func (s *Service) AddRoleEntitlement(
+	ctx context.Context,
+	roleId int64,
+	request RoleEntitlementRequest,
+	actor string,
+) ([]EntitlementResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return []EntitlementResponse{}, common.NewRequestValidatorError(err)
+	}
+	return s.changeRole(ctx, roleId, actor, "role.entitlement_added", request, func(tx *sqlx.Tx) error {
+		if err := s.repo.AddRoleEntitlementTx(ctx, tx, roleId, request.EntitlementId); err != nil {
+			return fmt.Errorf("error adding entitlement %d to role %d: %w", request.EntitlementId, roleId, err)
+		}
+		return nil
+	})
+}
+
+// RemoveRoleEntitlement убирает право из роли
+func (s *Service) RemoveRoleEntitlement(ctx context.Context, roleId int64, entitlementId int64, actor string) ([]EntitlementResponse, error) {
+	details := RoleEntitlementRequest{EntitlementId: entitlementId}
+	return s.changeRole(ctx, roleId, actor, "role.entitlement_removed", details, func(tx *sqlx.Tx) error {
+		removed, err := s.repo.RemoveRoleEntitlementTx(ctx, tx, roleId, entitlementId)
+		if err != nil {
+			return fmt.Errorf("error removing entitlement %d from role %d: %w", entitlementId, roleId, err)
+		}
+		if !removed {
+			return common.NotFoundError{Message: fmt.Sprintf("entitlement %d is not granted by role %d", entitlementId, roleId)}
+		}
+		return nil
+	})
+}
+
+// EmployeeEntitlements возвращает права сотрудника по всем его ролям (access.Service.Resolve)
+// и для каждого права роли, которые его дают. applicationId 0 - во всех целевых системах
+func (s *Service) EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (EmployeeEntitlementsResponse, error) {
+	if applicationId != 0 {
+		if _, err := s.FindById(ctx, applicationId); err != nil {
+			return EmployeeEntitlementsResponse{}, err
+		}
+	}
+	employeeAccess, err := s.access.Resolve(ctx, employeeId)
+	if err != nil {
+		return EmployeeEntitlementsResponse{}, err
+	}
+	response := EmployeeEntitlementsResponse{
+		EmployeeId:   employeeAccess.EmployeeId,
+		EmployeeName: employeeAccess.EmployeeName,
+		Status:       employeeAccess.Status,
+		Entitlements: []EmployeeEntitlement{},
+	}
+	if len(employeeAccess.Roles) == 0 {
+		return response, nil
+	}
+
+	roles := map[int64]Role{}
+	roleIds := make([]int64, 0, len(employeeAccess.Roles))
+	for _, item := range employeeAccess.Roles {
+		role := Role{RoleId: item.RoleId, RoleName: item.RoleName, Sources: []string{}}
+		for _, grant := range item.Grants {
+			if !slices.Contains(role.Sources, grant.Source) {
+				role.Sources = append(role.Sources, grant.Source)
+			}
+		}
+		roles[item.RoleId] = role
+		roleIds = append(roleIds, item.RoleId)
+	}
+	entitlements, err := s.repo.FindByRoles(ctx, roleIds, applicationId)
+	if err != nil {
+		return EmployeeEntitlementsResponse{}, fmt.Errorf("error finding entitlements of employee %d: %w", employeeId, err)
+	}
+
+	// строки упорядочены по праву, роли одного права идут подряд
+	for _, item := range entitlements {
+		last := len(response.Entitlements) - 1
+		if last < 0 || response.Entitlements[last].EntitlementId != item.Id {
+			response.Entitlements = append(response.Entitlements, EmployeeEntitlement{
+				EntitlementId:   item.Id,
+				EntitlementName: item.Name,
+				ApplicationId:   item.ApplicationId,
+				ApplicationName: item.ApplicationName,
+				Roles:           []Role{},
+			})
+			last++
+		}
+		response.Entitlements[last].Roles = append(response.Entitlements[last].Roles, roles[item.RoleId])
+	}
+	return response, nil
+}
+
+// change выполняет изменение целевой системы id в транзакции и пишет аудит
+func (s *Service) change(
+	ctx context.Context,
+	id int64,
+	actor string,
+	action string,
+	details any,
+	fn func(tx *sqlx.Tx, application Entity) error,
+) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("changing application panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing application: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing application: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("changing application: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	application, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.NotFoundError{Message: fmt.Sprintf("application with id %d not found", id)}
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error finding application with id %d: %w", id, err)
+	}
+	if err = fn(tx, application); err != nil {
+		return err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: "application",
+		EntityId:   id,
+		Details:    details,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// notifyRoleHoldersTx сообщает об изменении прав всех сотрудников, у которых есть роль roleId
+func (s *Service) notifyRoleHoldersTx(ctx context.Context, tx *sqlx.Tx, roleId int64) error {
+	if s.listener == nil {
+		return nil
+	}
+	employeeIds, err := s.repo.FindRoleHoldersTx(ctx, tx, roleId)
+	if err != nil {
+		return fmt.Errorf("error finding employees with role %d: %w", roleId, err)
+	}
+	if len(employeeIds) == 0 {
+		return nil
+	}
+	if err = s.listener.RolesChangedTx(ctx, tx, employeeIds); err != nil {
+		return fmt.Errorf("error notifying about entitlements of role %d changed: %w", roleId, err)
+	}
+	return nil
+}
+
+// notifyEntitlementHoldersTx сообщает об изменении прав сотрудников, которым роли дают право entitlementId
+// целевой системы applicationId (0 - любое её право). Вызывается до удаления прав, пока роли их дают
+func (s *Service) notifyEntitlementHoldersTx(ctx context.Context, tx *sqlx.Tx, applicationId int64, entitlementId int64) error {
+	if s.listener == nil {
+		return nil
+	}
+	employeeIds, err := s.repo.FindEntitlementHoldersTx(ctx, tx, applicationId, entitlementId)
+	if err != nil {
+		return fmt.Errorf("error finding employees with entitlements of application %d: %w", applicationId, err)
+	}
+	if len(employeeIds) == 0 {
+		return nil
+	}
+	if err = s.listener.RolesChangedTx(ctx, tx, employeeIds); err != nil {
+		return fmt.Errorf("error notifying about entitlements of application %d removed: %w", applicationId, err)
+	}
+	return nil
+}
+
+// changeRole выполняет изменение прав роли в транзакции, пишет аудит и возвращает права роли после изменения
+func (s *Service) changeRole(
+	ctx context.Context,
+	roleId int64,
+	actor string,
+	action string,
+	details any,
+	fn func(tx *sqlx.Tx) error,
+) (response []EntitlementResponse, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("changing role entitlements panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing role entitlements: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing role entitlements: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("changing role entitlements: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.RoleExistsTx(ctx, tx, roleId)
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error finding role with id %d: %w", roleId, err)
+	}
+	if !isExist {
+		err = common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", roleId)}
+		return []EntitlementResponse{}, err
+	}
+	if err = fn(tx); err != nil {
+		return []EntitlementResponse{}, err
+	}
+	if err = s.notifyRoleHoldersTx(ctx, tx, roleId); err != nil {
+		return []EntitlementResponse{}, err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: "role",
+		EntityId:   roleId,
+		Details:    details,
+	})
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	entitlements, err := s.repo.FindRoleEntitlementsTx(ctx, tx, roleId)
+	if err != nil {
+		return []EntitlementResponse{}, fmt.Errorf("error finding entitlements of role %d: %w", roleId, err)
+	}
+	return toEntitlementResponses(entitlements), nil
+}
+
+func toEntitlementResponses(entitlements []EntitlementEntity) []EntitlementResponse {
+	response := make([]EntitlementResponse, 0, len(entitlements))
+	for _, item := range entitlements {
+		response = append(response, item.toResponse())
+	}
+	return response
+}
@@ -0,0 +1,331 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	findQuery              = regexp.QuoteMeta("SELECT * FROM application WHERE id = $1")
+	existsQuery            = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM application WHERE name = $1)")
+	insertQuery            = regexp.QuoteMeta("INSERT INTO application (name, description) VALUES ($1, $2) RETURNING id")
+	entitlementExistsQuery = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM entitlement WHERE application_id = $1 AND name = $2)")
+	insertEntitlementQuery = regexp.QuoteMeta("INSERT INTO entitlement (application_id, name, description) VALUES ($1, $2, $3) RETURNING id")
+	findEntitlementQuery   = regexp.QuoteMeta(selectEntitlements + " WHERE e.id = $1")
+	roleExistsQuery        = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM role WHERE id = $1)")
+	addRoleQuery           = regexp.QuoteMeta("INSERT INTO role_entitlement (role_id, entitlement_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
+	roleEntitlementsQuery  = regexp.QuoteMeta(selectRoleEntitlements)
+	byRolesQuery           = regexp.QuoteMeta("SELECT re.role_id, e.id, e.application_id, a.name AS application_name")
+	roleHoldersQuery       = regexp.QuoteMeta("WITH RECURSIVE roles (id) AS (SELECT $1::bigint), granted (id) AS (")
+	holdersQuery           = regexp.QuoteMeta("WITH RECURSIVE roles (id) AS (\n    SELECT re.role_id FROM role_entitlement re")
+	deleteQuery            = regexp.QuoteMeta("DELETE FROM application WHERE id = $1")
+	deleteEntitlementQuery = regexp.QuoteMeta("DELETE FROM entitlement WHERE application_id = $1 AND id = $2")
+	auditQuery             = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	applicationColumns     = []string{"id", "name", "description", "create_at", "update_at"}
+	entitlementColumns     = []string{"id", "application_id", "application_name", "name", "description", "create_at", "update_at"}
+)
+
+type MockAccessSvc struct {
+	mock.Mock
+}
+
+func (m *MockAccessSvc) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).(access.Response), args.Error(1)
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *MockAccessSvc) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	accessSvc := &MockAccessSvc{}
+	srv := NewService(NewApplicationRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), accessSvc, validator.NewValidator())
+	return srv, mock, accessSvc
+}
+
+func TestService_Create(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create application", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(existsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectQuery(insertQuery).WithArgs("sap", "ERP").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "ERP", now, now))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "application.created", "application", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Create(context.Background(), CreateRequest{Name: "sap", Description: "ERP"}, "admin")
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, Name: "sap", Description: "ERP", CreateAt: now, UpdateAt: now}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError for taken name", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(existsQuery).WithArgs("sap").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "sap"}, "admin")
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_AddEntitlement(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should add entitlement to application", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "", now, now))
+		dbMock.ExpectQuery(entitlementExistsQuery).WithArgs(int64(1), "FI_APPROVE").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectQuery(insertEntitlementQuery).WithArgs(int64(1), "FI_APPROVE", "approve invoices").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(5)))
+		dbMock.ExpectQuery(findEntitlementQuery).WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows(entitlementColumns).AddRow(5, 1, "sap", "FI_APPROVE", "approve invoices", now, now))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "application.entitlement_added", "application", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.AddEntitlement(context.Background(), 1,
+			EntitlementRequest{Name: "FI_APPROVE", Description: "approve invoices"}, "admin")
+
+		a.Nil(err)
+		a.Equal(EntitlementResponse{Id: 5, ApplicationId: 1, ApplicationName: "sap", Name: "FI_APPROVE",
+			Description: "approve invoices", CreateAt: now, UpdateAt: now}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown application", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(9)).WillReturnRows(sqlmock.NewRows(applicationColumns))
+		dbMock.ExpectRollback()
+
+		_, err := srv.AddEntitlement(context.Background(), 9, EntitlementRequest{Name: "FI_APPROVE"}, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should notify listener about employees with entitlements of deleted application", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{1, 4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "", now, now))
+		dbMock.ExpectQuery(holdersQuery).WithArgs(int64(1), int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(1).AddRow(4))
+		dbMock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "application.deleted", "application", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+}
+
+func TestService_RemoveEntitlement(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should notify listener about employees with removed entitlement", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "", now, now))
+		dbMock.ExpectQuery(holdersQuery).WithArgs(int64(1), int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(4))
+		dbMock.ExpectExec(deleteEntitlementQuery).WithArgs(int64(1), int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "application.entitlement_removed", "application", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		err := srv.RemoveEntitlement(context.Background(), 1, 5, "admin")
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should return NotFoundError for unknown entitlement", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		srv.SetChangeListener(&MockChangeListener{})
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "", now, now))
+		dbMock.ExpectQuery(holdersQuery).WithArgs(int64(1), int64(5)).WillReturnRows(sqlmock.NewRows([]string{"employee_id"}))
+		dbMock.ExpectExec(deleteEntitlementQuery).WithArgs(int64(1), int64(5)).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectRollback()
+
+		err := srv.RemoveEntitlement(context.Background(), 1, 5, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_AddRoleEntitlement(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should bundle entitlement into role", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(roleExistsQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectExec(addRoleQuery).WithArgs(int64(3), int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "role.entitlement_added", "role", int64(3), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(roleEntitlementsQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows(entitlementColumns).
+			AddRow(7, 2, "ad", "vpn-users", "", now, now).
+			AddRow(5, 1, "sap", "FI_APPROVE", "", now, now))
+		dbMock.ExpectCommit()
+
+		got, err := srv.AddRoleEntitlement(context.Background(), 3, RoleEntitlementRequest{EntitlementId: 5}, "admin")
+
+		a.Nil(err)
+		a.Equal([]EntitlementResponse{
+			{Id: 7, ApplicationId: 2, ApplicationName: "ad", Name: "vpn-users", CreateAt: now, UpdateAt: now},
+			{Id: 5, ApplicationId: 1, ApplicationName: "sap", Name: "FI_APPROVE", CreateAt: now, UpdateAt: now},
+		}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should notify listener about employees with the role", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{1, 4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(roleExistsQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectExec(addRoleQuery).WithArgs(int64(3), int64(5)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(roleHoldersQuery).WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(1).AddRow(4))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "role.entitlement_added", "role", int64(3), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(roleEntitlementsQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows(entitlementColumns).
+			AddRow(5, 1, "sap", "FI_APPROVE", "", now, now))
+		dbMock.ExpectCommit()
+
+		_, err := srv.AddRoleEntitlement(context.Background(), 3, RoleEntitlementRequest{EntitlementId: 5}, "admin")
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should return NotFoundError for unknown role", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(roleExistsQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectRollback()
+
+		_, err := srv.AddRoleEntitlement(context.Background(), 3, RoleEntitlementRequest{EntitlementId: 5}, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.Equal("role with id 3 not found", notFoundErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_EmployeeEntitlements(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should collect entitlements of all effective roles", func(t *testing.T) {
+		srv, dbMock, accessSvc := newTestService(t)
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(applicationColumns).AddRow(1, "sap", "", now, now))
+		accessSvc.On("Resolve", int64(7)).Return(access.Response{EmployeeId: 7, EmployeeName: "john doe", Roles: []access.Role{
+			{RoleId: 2, RoleName: "accountant", Grants: []access.Grant{{Source: access.SourceDirect}, {Source: access.SourceRule}}},
+			{RoleId: 3, RoleName: "approver", Grants: []access.Grant{{Source: access.SourceDelegation}}},
+		}}, nil)
+		dbMock.ExpectQuery(byRolesQuery).WithArgs("{2,3}", int64(1)).
+			WillReturnRows(sqlmock.NewRows(append([]string{"role_id"}, entitlementColumns...)).
+				AddRow(2, 5, 1, "sap", "FI_APPROVE", "", now, now).
+				AddRow(3, 5, 1, "sap", "FI_APPROVE", "", now, now).
+				AddRow(2, 6, 1, "sap", "FI_DISPLAY", "", now, now))
+
+		got, err := srv.EmployeeEntitlements(context.Background(), 7, 1)
+
+		a.Nil(err)
+		accountant := Role{RoleId: 2, RoleName: "accountant", Sources: []string{access.SourceDirect, access.SourceRule}}
+		approver := Role{RoleId: 3, RoleName: "approver", Sources: []string{access.SourceDelegation}}
+		a.Equal(EmployeeEntitlementsResponse{EmployeeId: 7, EmployeeName: "john doe", Entitlements: []EmployeeEntitlement{
+			{EntitlementId: 5, EntitlementName: "FI_APPROVE", ApplicationId: 1, ApplicationName: "sap", Roles: []Role{accountant, approver}},
+			{EntitlementId: 6, EntitlementName: "FI_DISPLAY", ApplicationId: 1, ApplicationName: "sap", Roles: []Role{accountant}},
+		}}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return empty list for employee without roles", func(t *testing.T) {
+		srv, dbMock, accessSvc := newTestService(t)
+		accessSvc.On("Resolve", int64(7)).Return(access.Response{EmployeeId: 7, EmployeeName: "john doe"}, nil)
+
+		got, err := srv.EmployeeEntitlements(context.Background(), 7, 0)
+
+		a.Nil(err)
+		a.Equal(EmployeeEntitlementsResponse{EmployeeId: 7, EmployeeName: "john doe", Entitlements: []EmployeeEntitlement{}}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown application", func(t *testing.T) {
+		srv, dbMock, accessSvc := newTestService(t)
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(9)).WillReturnRows(sqlmock.NewRows(applicationColumns))
+
+		_, err := srv.EmployeeEntitlements(context.Background(), 7, 9)
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		accessSvc.AssertNotCalled(t, "Resolve", mock.Anything)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
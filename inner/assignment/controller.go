@@ -0,0 +1,189 @@
+package assignment
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server            *web.Server
+	assignmentService Svc
+	logger            *common.Logger
+}
+
+// интерфейс сервиса assignment.Service
+type Svc interface {
+	Assign(ctx context.Context, request AssignRequest, principal common.Principal) error
+	Revoke(ctx context.Context, employeeId int64, roleId int64, principal common.Principal) error
+	FindByEmployee(ctx context.Context, employeeId int64) ([]Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:            server,
+		assignmentService: svc,
+		logger:            logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/employees/:id/roles", c.GetEmployeeRoles)
+	c.server.GroupApiV1.Post("/employees/:id/roles", c.AssignRole)
+	c.server.GroupApiV1.Delete("/employees/:id/roles/:roleId", c.RevokeRole)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/roles"
+// @Description Get roles assigned to employee.
+// @Summary get employee roles
+// @ID get-employee-roles
+// @Tags assignment
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Success 200 {object} common.Response[[]assignment.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/roles [get]
+func (c *Controller) GetEmployeeRoles(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// вызываем метод FindByEmployee сервиса assignment.Service
+	response, err := c.assignmentService.FindByEmployee(ctx.Context(), employeeId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee roles", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee roles", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/:id/roles"
+// @Description Assign role to employee. Scoped admin may assign only whitelisted roles to employees of granted org units.
+// @Summary assign role
+// @ID assign-role
+// @Tags assignment
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param request body assignment.AssignRequest true "role id, employee id is taken from path"
+// @Success 200 {object} common.Response[assignment.AssignRequest]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/roles [post]
+func (c *Controller) AssignRole(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmScopedAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	var request AssignRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.EmployeeId = employeeId
+	c.logger.DebugCtx(ctx.Context(), "assign role: received request", zap.Any("request", request))
+
+	// вызываем метод Assign сервиса assignment.Service
+	if err := c.assignmentService.Assign(ctx.Context(), request, claims.Principal()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "assign role", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "assign role", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/:id/roles/:roleId"
+// @Description Revoke role from employee. Scoped admin may revoke only whitelisted roles from employees of granted org units.
+// @Summary revoke role
+// @ID revoke-role
+// @Tags assignment
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param roleId path int64 true "id role"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/roles/{roleId} [delete]
+func (c *Controller) RevokeRole(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmScopedAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	roleId, err := strconv.ParseInt(ctx.Params("roleId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+
+	// вызываем метод Revoke сервиса assignment.Service
+	if err := c.assignmentService.Revoke(ctx.Context(), employeeId, roleId, claims.Principal()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke role", zap.Int64("employeeId", employeeId),
+			zap.Int64("roleId", roleId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke role", zap.Error(err))
+		return err
+	}
+	return nil
+}
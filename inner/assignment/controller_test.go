@@ -0,0 +1,116 @@
+package assignment
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса assignment.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Assign(ctx context.Context, request AssignRequest, principal common.Principal) error {
+	args := svc.Called(request)
+	return args.Error(0)
+}
+
+func (svc *MockService) Revoke(ctx context.Context, employeeId int64, roleId int64, principal common.Principal) error {
+	args := svc.Called(employeeId, roleId)
+	return args.Error(0)
+}
+
+func (svc *MockService) FindByEmployee(ctx context.Context, employeeId int64) ([]Response, error) {
+	args := svc.Called(employeeId)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_GetEmployeeRoles(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmUser)
+	svc.On("FindByEmployee", int64(1)).Return([]Response{{EmployeeId: 1, RoleId: 2, RoleName: "admin"}}, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/1/roles", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	var body common.Response[[]Response]
+	data, _ := io.ReadAll(resp.Body)
+	a.Nil(json.Unmarshal(data, &body))
+	a.Equal("admin", body.Data[0].RoleName)
+}
+
+func TestController_AssignRole(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should assign role from path and body", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Assign", AssignRequest{EmployeeId: 1, RoleId: 2}).Return(nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/roles", strings.NewReader(`{"role_id": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 404 for unknown role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Assign", mock.Anything).Return(common.NotFoundError{Message: "employee or role not found"})
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/roles", strings.NewReader(`{"role_id": 9}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("should return 403 for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/roles", strings.NewReader(`{"role_id": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Assign", mock.Anything)
+	})
+}
+
+func TestController_RevokeRole(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("Revoke", int64(1), int64(2)).Return(nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/roles/2", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
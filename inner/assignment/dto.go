@@ -0,0 +1,41 @@
+package assignment
+
+import "time"
+
+// Источники назначения роли
+const (
+	// роль назначена вручную
+	SourceManual = "manual"
+	// роль назначена автоматически правилом по атрибутам сотрудника, отзывается, когда правило перестаёт подходить
+	SourceRule = "rule"
+)
+
+// Entity назначение роли сотруднику вместе с именами сотрудника и роли
+type Entity struct {
+	EmployeeId   int64     `db:"employee_id"`
+	EmployeeName string    `db:"employee_name"`
+	RoleId       int64     `db:"role_id"`
+	RoleName     string    `db:"role_name"`
+	Source       string    `db:"source"`
+	CreateAt     time.Time `db:"create_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		EmployeeId:   e.EmployeeId,
+		EmployeeName: e.EmployeeName,
+		RoleId:       e.RoleId,
+		RoleName:     e.RoleName,
+		Source:       e.Source,
+		CreateAt:     e.CreateAt,
+	}
+}
+
+type Response struct {
+	EmployeeId   int64     `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	RoleId       int64     `json:"role_id"`
+	RoleName     string    `json:"role_name"`
+	Source       string    `json:"source"`
+	CreateAt     time.Time `json:"create_at"`
+}
@@ -0,0 +1,108 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// выборка назначений с именами сотрудников и ролей
+const selectAssignments = `SELECT er.employee_id, e.name AS employee_name, er.role_id, r.name AS role_name, er.source, er.create_at
+FROM employee_role er
+JOIN employee e ON e.id = er.employee_id
+JOIN role r ON r.id = er.role_id`
+
+// ручное назначение роли, которая у сотрудника уже есть: назначение по правилу становится ручным,
+// а роль действующего экстренного доступа остаётся экстренной, чтобы её отозвали по его окончании
+const assignConflict = `ON CONFLICT (employee_id, role_id) DO UPDATE SET source = 'manual'
+WHERE employee_role.source <> 'break_glass' OR NOT EXISTS (SELECT * FROM break_glass_grant g
+    WHERE g.employee_id = employee_role.employee_id AND g.role_id = employee_role.role_id AND g.state = 'active')`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewAssignmentRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// назначить роль сотруднику вручную. Роль, назначенная ранее по правилу, становится назначенной вручную
+// и больше не отзывается, когда правило перестаёт подходить; роль экстренного доступа не меняется
+func (r *Repository) Assign(ctx context.Context, employeeId int64, roleId int64) error {
+	query := "INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'manual') " + assignConflict
+	_, err := r.db.ExecContext(ctx, query, employeeId, roleId)
+	return mapError(err)
+}
+
+// отозвать роль у сотрудника
+func (r *Repository) Revoke(ctx context.Context, employeeId int64, roleId int64) error {
+	query := "DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2"
+	_, err := r.db.ExecContext(ctx, query, employeeId, roleId)
+	return err
+}
+
+// найти назначения сотрудника
+func (r *Repository) FindByEmployee(ctx context.Context, employeeId int64) (assignments []Entity, err error) {
+	query := selectAssignments + " WHERE er.employee_id = $1 ORDER BY r.name"
+	err = r.db.SelectContext(ctx, &assignments, query, employeeId)
+	return assignments, err
+}
+
+// найти назначения роли
+func (r *Repository) FindByRole(ctx context.Context, roleId int64) (assignments []Entity, err error) {
+	query := selectAssignments + " WHERE er.role_id = $1 ORDER BY e.name"
+	err = r.db.SelectContext(ctx, &assignments, query, roleId)
+	return assignments, err
+}
+
+// найти все назначения
+func (r *Repository) GetAll(ctx context.Context) (assignments []Entity, err error) {
+	query := selectAssignments + " ORDER BY er.employee_id, er.role_id"
+	err = r.db.SelectContext(ctx, &assignments, query)
+	return assignments, err
+}
+
+// сотрудники, которым назначена роль, в рамках транзакции
+func (r *Repository) FindMemberIdsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) (employeeIds []int64, err error) {
+	err = tx.SelectContext(ctx, &employeeIds, "SELECT employee_id FROM employee_role WHERE role_id = $1", roleId)
+	return employeeIds, err
+}
+
+// удалить в рамках транзакции назначения роли всем сотрудникам, кроме перечисленных
+func (r *Repository) RevokeOthersTx(ctx context.Context, tx *sqlx.Tx, roleId int64, employeeIds []int64) error {
+	query := "DELETE FROM employee_role WHERE role_id = $1 AND NOT (employee_id = ANY($2))"
+	_, err := tx.ExecContext(ctx, query, roleId, pq.Int64Array(employeeIds))
+	return err
+}
+
+// назначить в рамках транзакции роль сотрудникам вручную; как и в Assign, назначение по правилу
+// становится ручным и больше не отзывается при пересчёте правил, роль экстренного доступа не меняется
+func (r *Repository) AssignManyTx(ctx context.Context, tx *sqlx.Tx, roleId int64, employeeIds []int64) error {
+	if len(employeeIds) == 0 {
+		return nil
+	}
+	query := `INSERT INTO employee_role (employee_id, role_id, source)
+SELECT employee_id, $1, 'manual' FROM unnest($2::bigint[]) AS employee_id
+` + assignConflict
+	_, err := tx.ExecContext(ctx, query, roleId, pq.Int64Array(employeeIds))
+	return mapError(err)
+}
+
+// mapError переводит нарушение внешнего ключа (нет такого сотрудника или роли) в NotFoundError
+func mapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: "employee or role not found"}
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+package assignment
+
+type AssignRequest struct {
+	EmployeeId int64 `json:"employee_id" validate:"required,gt=0"`
+	RoleId     int64 `json:"role_id" validate:"required,gt=0"`
+}
+
+type ReplaceMembersRequest struct {
+	RoleId      int64   `json:"role_id" validate:"required,gt=0"`
+	EmployeeIds []int64 `json:"employee_ids" validate:"dive,gt=0"`
+}
@@ -0,0 +1,197 @@
+package assignment
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	Assign(ctx context.Context, employeeId int64, roleId int64) error
+	Revoke(ctx context.Context, employeeId int64, roleId int64) error
+	FindByEmployee(ctx context.Context, employeeId int64) ([]Entity, error)
+	FindByRole(ctx context.Context, roleId int64) ([]Entity, error)
+	GetAll(ctx context.Context) ([]Entity, error)
+	RevokeOthersTx(ctx context.Context, tx *sqlx.Tx, roleId int64, employeeIds []int64) error
+	AssignManyTx(ctx context.Context, tx *sqlx.Tx, roleId int64, employeeIds []int64) error
+	FindMemberIdsTx(ctx context.Context, tx *sqlx.Tx, roleId int64) ([]int64, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// Authorizer проверяет права вызывающего на назначение роли сотруднику; отказ возвращается как ForbiddenError
+type Authorizer interface {
+	AuthorizeRole(ctx context.Context, principal common.Principal, employeeId int64, roleId int64) error
+}
+
+// ChangeListener получает сотрудников, которым назначили или у которых отозвали роли,
+// например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChanged(ctx context.Context, employeeIds []int64) error
+}
+
+type Service struct {
+	repo       Repo
+	validator  Validator
+	authorizer Authorizer
+	listener   ChangeListener
+}
+
+func NewService(repo Repo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// SetAuthorizer подключает проверку прав администраторов отделов при назначении и отзыве ролей
+func (s *Service) SetAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// SetChangeListener подключает получателя изменений назначений ролей
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+// notify сообщает об изменении ролей сотрудников после того, как изменение сохранено
+func (s *Service) notify(ctx context.Context, employeeIds []int64) error {
+	if s.listener == nil || len(employeeIds) == 0 {
+		return nil
+	}
+	if err := s.listener.RolesChanged(ctx, employeeIds); err != nil {
+		return fmt.Errorf("roles of employees %v changed, but listener failed: %w", employeeIds, err)
+	}
+	return nil
+}
+
+// authorize проверяет, может ли вызывающий назначать и отзывать роль roleId у сотрудника.
+// Без подключённой проверки это может только администратор
+func (s *Service) authorize(ctx context.Context, principal common.Principal, employeeId int64, roleId int64) error {
+	if s.authorizer == nil {
+		if principal.Admin {
+			return nil
+		}
+		return common.ForbiddenError{Message: "permission denied"}
+	}
+	return s.authorizer.AuthorizeRole(ctx, principal, employeeId, roleId)
+}
+
+// Assign назначает роль сотруднику
+func (s *Service) Assign(ctx context.Context, request AssignRequest, principal common.Principal) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.NewRequestValidatorError(err)
+	}
+	if err := s.authorize(ctx, principal, request.EmployeeId, request.RoleId); err != nil {
+		return err
+	}
+	if err := s.repo.Assign(ctx, request.EmployeeId, request.RoleId); err != nil {
+		return fmt.Errorf("error assigning role %d to employee %d: %w", request.RoleId, request.EmployeeId, err)
+	}
+	return s.notify(ctx, []int64{request.EmployeeId})
+}
+
+// Revoke отзывает роль у сотрудника
+func (s *Service) Revoke(ctx context.Context, employeeId int64, roleId int64, principal common.Principal) error {
+	if err := s.authorize(ctx, principal, employeeId, roleId); err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, employeeId, roleId); err != nil {
+		return fmt.Errorf("error revoking role %d from employee %d: %w", roleId, employeeId, err)
+	}
+	return s.notify(ctx, []int64{employeeId})
+}
+
+func (s *Service) FindByEmployee(ctx context.Context, employeeId int64) ([]Response, error) {
+	assignments, err := s.repo.FindByEmployee(ctx, employeeId)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error finding roles of employee %d: %w", employeeId, err)
+	}
+	return toResponses(assignments), nil
+}
+
+func (s *Service) FindByRole(ctx context.Context, roleId int64) ([]Response, error) {
+	assignments, err := s.repo.FindByRole(ctx, roleId)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error finding members of role %d: %w", roleId, err)
+	}
+	return toResponses(assignments), nil
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
+	assignments, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all assignments: %w", err)
+	}
+	return toResponses(assignments), nil
+}
+
+// ReplaceMembers заменяет всех сотрудников, которым назначена роль, на переданный список
+func (s *Service) ReplaceMembers(ctx context.Context, request ReplaceMembersRequest) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.NewRequestValidatorError(err)
+	}
+	changed, err := s.replaceMembers(ctx, request)
+	if err != nil {
+		return err
+	}
+	return s.notify(ctx, changed)
+}
+
+// replaceMembers заменяет сотрудников роли в транзакции и возвращает прежних и новых сотрудников,
+// если подключён получатель изменений
+func (s *Service) replaceMembers(ctx context.Context, request ReplaceMembersRequest) (changed []int64, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("replacing role members panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("replacing role members: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("replacing role members: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("replacing role members: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	if s.listener != nil {
+		if changed, err = s.repo.FindMemberIdsTx(ctx, tx, request.RoleId); err != nil {
+			return nil, fmt.Errorf("error finding members of role %d: %w", request.RoleId, err)
+		}
+		changed = append(changed, request.EmployeeIds...)
+	}
+	if err = s.repo.RevokeOthersTx(ctx, tx, request.RoleId, request.EmployeeIds); err != nil {
+		return nil, fmt.Errorf("error revoking role %d: %w", request.RoleId, err)
+	}
+	if err = s.repo.AssignManyTx(ctx, tx, request.RoleId, request.EmployeeIds); err != nil {
+		return nil, fmt.Errorf("error assigning role %d: %w", request.RoleId, err)
+	}
+	return changed, nil
+}
+
+func toResponses(assignments []Entity) []Response {
+	response := make([]Response, 0, len(assignments))
+	for _, item := range assignments {
+		response = append(response, item.toResponse())
+	}
+	return response
+}
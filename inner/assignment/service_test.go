@@ -0,0 +1,235 @@
+package assignment
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// администратор, которому доступны все операции
+var admin = common.Principal{Actor: "admin", Admin: true}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	return NewService(NewAssignmentRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator()), mock
+}
+
+func TestAssign(t *testing.T) {
+	a := assert.New(t)
+	insertQuery := regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'manual')")
+
+	t.Run("should assign role", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectExec(insertQuery).WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1, RoleId: 2}, admin)
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown employee or role", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectExec(insertQuery).WithArgs(int64(1), int64(2)).WillReturnError(&pq.Error{Code: foreignKeyViolation})
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1, RoleId: 2}, admin)
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+	})
+
+	t.Run("should return validation error", func(t *testing.T) {
+		srv, _ := newTestService(t)
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1}, admin)
+
+		var validatorErr common.RequestValidatorError
+		a.True(errors.As(err, &validatorErr))
+		a.Equal("$.role_id", validatorErr.Fields[0].JsonPath)
+	})
+}
+
+type MockAuthorizer struct {
+	mock.Mock
+}
+
+func (m *MockAuthorizer) AuthorizeRole(ctx context.Context, principal common.Principal, employeeId int64, roleId int64) error {
+	args := m.Called(principal.Subject, employeeId, roleId)
+	return args.Error(0)
+}
+
+func TestAssignAuthorization(t *testing.T) {
+	a := assert.New(t)
+	scopedAdmin := common.Principal{Subject: "kc-1", Actor: "kc-1"}
+
+	t.Run("should reject non-admin without authorizer", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1, RoleId: 2}, scopedAdmin)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not assign role out of scope", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		authorizer := &MockAuthorizer{}
+		srv.SetAuthorizer(authorizer)
+		authorizer.On("AuthorizeRole", "kc-1", int64(1), int64(2)).Return(common.ForbiddenError{Message: "role 2 is not allowed"})
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1, RoleId: 2}, scopedAdmin)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should revoke role in scope", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		authorizer := &MockAuthorizer{}
+		srv.SetAuthorizer(authorizer)
+		authorizer.On("AuthorizeRole", "kc-1", int64(1), int64(2)).Return(nil)
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2")).
+			WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := srv.Revoke(context.Background(), 1, 2, scopedAdmin)
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindByEmployee(t *testing.T) {
+	a := assert.New(t)
+	srv, mock := newTestService(t)
+	now := time.Now()
+	mock.ExpectQuery(regexp.QuoteMeta(selectAssignments + " WHERE er.employee_id = $1 ORDER BY r.name")).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "employee_name", "role_id", "role_name", "source", "create_at"}).
+			AddRow(1, "john doe", 2, "admin", SourceManual, now))
+
+	got, err := srv.FindByEmployee(context.Background(), 1)
+
+	a.Nil(err)
+	a.Equal([]Response{{EmployeeId: 1, EmployeeName: "john doe", RoleId: 2, RoleName: "admin", Source: SourceManual, CreateAt: now}}, got)
+}
+
+func TestReplaceMembers(t *testing.T) {
+	a := assert.New(t)
+	revokeQuery := regexp.QuoteMeta("DELETE FROM employee_role WHERE role_id = $1 AND NOT (employee_id = ANY($2))")
+	assignQuery := regexp.QuoteMeta(`INSERT INTO employee_role (employee_id, role_id, source)
+SELECT employee_id, $1, 'manual' FROM unnest($2::bigint[]) AS employee_id
+ON CONFLICT (employee_id, role_id) DO UPDATE SET source = 'manual'
+WHERE employee_role.source <> 'break_glass'`)
+
+	t.Run("should replace members in transaction", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeQuery).WithArgs(int64(2), pq.Int64Array{1, 3}).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(assignQuery).WithArgs(int64(2), pq.Int64Array{1, 3}).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := srv.ReplaceMembers(context.Background(), ReplaceMembersRequest{RoleId: 2, EmployeeIds: []int64{1, 3}})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should revoke all members without insert", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeQuery).WithArgs(int64(2), pq.Int64Array{}).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		err := srv.ReplaceMembers(context.Background(), ReplaceMembersRequest{RoleId: 2, EmployeeIds: []int64{}})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back when employee does not exist", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(revokeQuery).WithArgs(int64(2), pq.Int64Array{9}).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(assignQuery).WithArgs(int64(2), pq.Int64Array{9}).WillReturnError(&pq.Error{Code: foreignKeyViolation})
+		mock.ExpectRollback()
+
+		err := srv.ReplaceMembers(context.Background(), ReplaceMembersRequest{RoleId: 2, EmployeeIds: []int64{9}})
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChanged(ctx context.Context, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+func TestChangeListener(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should notify about assigned role", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'manual')")).
+			WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		listener.On("RolesChanged", []int64{1}).Return(nil)
+
+		err := srv.Assign(context.Background(), AssignRequest{EmployeeId: 1, RoleId: 2}, admin)
+
+		a.Nil(err)
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should notify previous and new members after commit", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT employee_id FROM employee_role WHERE role_id = $1")).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(5).AddRow(1))
+		dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM employee_role WHERE role_id = $1")).
+			WithArgs(int64(2), pq.Int64Array{1, 3}).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source)")).
+			WithArgs(int64(2), pq.Int64Array{1, 3}).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+		listener.On("RolesChanged", []int64{5, 1, 1, 3}).Return(nil)
+
+		err := srv.ReplaceMembers(context.Background(), ReplaceMembersRequest{RoleId: 2, EmployeeIds: []int64{1, 3}})
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should not notify when revoke fails", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		dbMock.ExpectExec(regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2")).
+			WithArgs(int64(1), int64(2)).WillReturnError(errors.New("database is down"))
+
+		err := srv.Revoke(context.Background(), 1, 2, admin)
+
+		a.ErrorContains(err, "database is down")
+		listener.AssertNotCalled(t, "RolesChanged", mock.Anything)
+	})
+}
@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// Entry запись журнала аудита: кто, что и с каким объектом сделал
+type Entry struct {
+	Actor      string
+	Action     string
+	EntityType string
+	// id объекта, 0 - если действие не относится к одному объекту
+	EntityId int64
+	// подробности действия, сохраняются в JSON
+	Details any
+}
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateTx добавляет запись в журнал в рамках транзакции, чтобы запись
+// сохранялась только вместе с изменениями, которые она описывает
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, entry Entry) error {
+	details, err := json.Marshal(entry.Details)
+	if err != nil {
+		return fmt.Errorf("error marshaling audit details: %w", err)
+	}
+	var entityId *int64
+	if entry.EntityId != 0 {
+		entityId = &entry.EntityId
+	}
+	query := "INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)"
+	_, err = tx.ExecContext(ctx, query, entry.Actor, entry.Action, entry.EntityType, entityId, details)
+	return err
+}
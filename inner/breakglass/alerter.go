@@ -0,0 +1,33 @@
+package breakglass
+
+import (
+	"context"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+)
+
+// Alerter оповещает о включении экстренного доступа. Ошибки доставки обрабатывает сама реализация:
+// доступ уже выдан и не откатывается из-за недоставленного оповещения
+type Alerter interface {
+	Alert(ctx context.Context, alert Alert)
+}
+
+// LogAlerter записывает оповещения в лог приложения с уровнем warn, откуда их забирает система мониторинга
+type LogAlerter struct {
+	logger *common.Logger
+}
+
+func NewLogAlerter(logger *common.Logger) *LogAlerter {
+	return &LogAlerter{logger: logger}
+}
+
+func (a *LogAlerter) Alert(ctx context.Context, alert Alert) {
+	a.logger.Warn("break-glass access activated",
+		zap.Int64("grant_id", alert.GrantId),
+		zap.Int64("employee_id", alert.EmployeeId),
+		zap.Int64("role_id", alert.RoleId),
+		zap.String("role_name", alert.RoleName),
+		zap.String("actor", alert.Actor),
+		zap.String("reason", alert.Reason),
+		zap.Time("expires_at", alert.ExpiresAt))
+}
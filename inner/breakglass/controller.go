@@ -0,0 +1,283 @@
+package breakglass
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server            *web.Server
+	breakGlassService Svc
+	logger            *common.Logger
+}
+
+// интерфейс сервиса breakglass.Service
+type Svc interface {
+	GetAll(ctx context.Context, openReviews bool) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Activate(ctx context.Context, request ActivateRequest, principal common.Principal) (Response, error)
+	Revoke(ctx context.Context, id int64, principal common.Principal) (Response, error)
+	Review(ctx context.Context, id int64, request ReviewRequest, principal common.Principal) (Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:            server,
+		breakGlassService: svc,
+		logger:            logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/break-glass", c.GetAllGrants)
+	c.server.GroupApiV1.Post("/break-glass", c.ActivateGrant)
+	c.server.GroupApiV1.Get("/break-glass/:id", c.GetGrant)
+	c.server.GroupApiV1.Delete("/break-glass/:id", c.RevokeGrant)
+	c.server.GroupApiV1.Post("/break-glass/:id/review", c.ReviewGrant)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/break-glass"
+// @Description Get break-glass grants, only grants with open post-incident review if open_reviews is true.
+// @Summary get break-glass grants
+// @ID get-all-break-glass-grants
+// @Tags break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param open_reviews query bool false "only grants with open review"
+// @Success 200 {object} common.Response[[]breakglass.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /break-glass [get]
+func (c *Controller) GetAllGrants(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	openReviews := ctx.QueryBool("open_reviews")
+
+	// вызываем метод GetAll сервиса breakglass.Service
+	response, err := c.breakGlassService.GetAll(ctx.Context(), openReviews)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all break-glass grants", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all break-glass grants", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/break-glass/:id"
+// @Description Get break-glass grant by id.
+// @Summary get break-glass grant
+// @ID get-break-glass-grant
+// @Tags break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id break-glass grant"
+// @Success 200 {object} common.Response[breakglass.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /break-glass/{id} [get]
+func (c *Controller) GetGrant(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid break-glass grant id")
+	}
+
+	// вызываем метод FindById сервиса breakglass.Service
+	response, err := c.breakGlassService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get break-glass grant", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get break-glass grant", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/break-glass"
+// @Description Activate break-glass access: the caller immediately gets an emergency role for ttl_minutes
+// @Description (BREAK_GLASS_MAX_TTL by default). The role is revoked at expiry, an alert is emitted and
+// @Description a post-incident review is opened. Only an active employee who does not already have the role
+// @Description by any path (effective access) can activate it.
+// @Summary activate break-glass access
+// @ID activate-break-glass
+// @Tags break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body breakglass.ActivateRequest true "break-glass request"
+// @Success 200 {object} common.Response[breakglass.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /break-glass [post]
+func (c *Controller) ActivateGrant(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmBreakGlass) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request ActivateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "activate break-glass: received request", zap.Any("request", request))
+
+	// вызываем метод Activate сервиса breakglass.Service
+	response, err := c.breakGlassService.Activate(ctx.Context(), request, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "activate break-glass", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "activate break-glass", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/break-glass/:id"
+// @Description Revoke break-glass access before expiry. Admins revoke any grant, others only their own.
+// @Summary revoke break-glass access
+// @ID revoke-break-glass
+// @Tags break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id break-glass grant"
+// @Success 200 {object} common.Response[breakglass.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /break-glass/{id} [delete]
+func (c *Controller) RevokeGrant(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmBreakGlass) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid break-glass grant id")
+	}
+
+	// вызываем метод Revoke сервиса breakglass.Service
+	response, err := c.breakGlassService.Revoke(ctx.Context(), id, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke break-glass", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke break-glass", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/break-glass/:id/review"
+// @Description Close post-incident review of ended break-glass access.
+// @Description The review must be closed by an admin other than the one who activated the access.
+// @Summary review break-glass access
+// @ID review-break-glass
+// @Tags break-glass
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id break-glass grant"
+// @Param request body breakglass.ReviewRequest true "review"
+// @Success 200 {object} common.Response[breakglass.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /break-glass/{id}/review [post]
+func (c *Controller) ReviewGrant(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid break-glass grant id")
+	}
+	var request ReviewRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// вызываем метод Review сервиса breakglass.Service
+	response, err := c.breakGlassService.Review(ctx.Context(), id, request, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "review break-glass", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "review break-glass", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
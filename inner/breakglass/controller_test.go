@@ -0,0 +1,114 @@
+package breakglass
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса breakglass.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context, openReviews bool) ([]Response, error) {
+	args := svc.Called(openReviews)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Activate(ctx context.Context, request ActivateRequest, principal common.Principal) (Response, error) {
+	args := svc.Called(request, principal)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Revoke(ctx context.Context, id int64, principal common.Principal) (Response, error) {
+	args := svc.Called(id, principal)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Review(ctx context.Context, id int64, request ReviewRequest, principal common.Principal) (Response, error) {
+	args := svc.Called(id, request, principal)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-7", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_ActivateGrant(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass caller to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmBreakGlass)
+		request := ActivateRequest{RoleId: 3, TtlMinutes: 30, Reason: reason}
+		caller := common.Principal{Subject: "kc-7", Roles: []string{web.IdmBreakGlass}, Actor: "kc-7"}
+		svc.On("Activate", request, caller).Return(Response{Id: 1}, nil)
+
+		body := strings.NewReader(`{"role_id": 3, "ttl_minutes": 30, "reason": "` + reason + `"}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/break-glass", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 without break-glass role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/break-glass", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Activate", mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_GetAllGrants(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("GetAll", true).Return([]Response{{Id: 1}}, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/break-glass?open_reviews=true", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestController_ReviewGrant(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("Review", int64(1), ReviewRequest{Comment: "ok"}, mock.Anything).
+		Return(Response{}, common.ConflictError{Message: "break-glass grant with id 1 is still active"})
+
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/break-glass/1/review", strings.NewReader(`{"comment": "ok"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.App.Test(req)
+
+	a.Nil(err)
+	a.Equal(http.StatusConflict, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
@@ -0,0 +1,86 @@
+package breakglass
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Состояния экстренного доступа
+const (
+	StateActive  = "active"
+	StateExpired = "expired"
+	StateRevoked = "revoked"
+)
+
+// Состояния разбора инцидента
+const (
+	ReviewOpen   = "open"
+	ReviewClosed = "closed"
+)
+
+// Entity экстренный доступ сотрудника к роли и разбор инцидента после него
+type Entity struct {
+	Id            int64          `db:"id"`
+	EmployeeId    int64          `db:"employee_id"`
+	RoleId        int64          `db:"role_id"`
+	Reason        string         `db:"reason"`
+	Actor         string         `db:"actor"`
+	ExpiresAt     time.Time      `db:"expires_at"`
+	State         string         `db:"state"`
+	EndAt         sql.NullTime   `db:"end_at"`
+	ReviewState   string         `db:"review_state"`
+	ReviewedBy    sql.NullString `db:"reviewed_by"`
+	ReviewComment sql.NullString `db:"review_comment"`
+	ReviewedAt    sql.NullTime   `db:"reviewed_at"`
+	CreateAt      time.Time      `db:"create_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	response := Response{
+		Id:            e.Id,
+		EmployeeId:    e.EmployeeId,
+		RoleId:        e.RoleId,
+		Reason:        e.Reason,
+		Actor:         e.Actor,
+		ExpiresAt:     e.ExpiresAt,
+		State:         e.State,
+		ReviewState:   e.ReviewState,
+		ReviewedBy:    e.ReviewedBy.String,
+		ReviewComment: e.ReviewComment.String,
+		CreateAt:      e.CreateAt,
+	}
+	if e.EndAt.Valid {
+		response.EndAt = &e.EndAt.Time
+	}
+	if e.ReviewedAt.Valid {
+		response.ReviewedAt = &e.ReviewedAt.Time
+	}
+	return response
+}
+
+type Response struct {
+	Id            int64      `json:"id"`
+	EmployeeId    int64      `json:"employee_id"`
+	RoleId        int64      `json:"role_id"`
+	Reason        string     `json:"reason"`
+	Actor         string     `json:"actor"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	State         string     `json:"state"`
+	EndAt         *time.Time `json:"end_at,omitempty"`
+	ReviewState   string     `json:"review_state"`
+	ReviewedBy    string     `json:"reviewed_by,omitempty"`
+	ReviewComment string     `json:"review_comment,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	CreateAt      time.Time  `json:"create_at"`
+}
+
+// Alert событие о включении экстренного доступа для дежурных и службы безопасности
+type Alert struct {
+	GrantId    int64
+	EmployeeId int64
+	RoleId     int64
+	RoleName   string
+	Actor      string
+	Reason     string
+	ExpiresAt  time.Time
+}
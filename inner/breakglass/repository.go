@@ -0,0 +1,106 @@
+package breakglass
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewBreakGlassRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти экстренные доступы; openReviews - только с незакрытым разбором
+func (r *Repository) FindAll(ctx context.Context, openReviews bool) (grants []Entity, err error) {
+	query := "SELECT * FROM break_glass_grant WHERE NOT $1 OR review_state = 'open' ORDER BY create_at DESC, id DESC"
+	err = r.db.SelectContext(ctx, &grants, query, openReviews)
+	return grants, err
+}
+
+// найти экстренный доступ по id
+func (r *Repository) FindById(ctx context.Context, id int64) (grant Entity, err error) {
+	err = r.db.GetContext(ctx, &grant, "SELECT * FROM break_glass_grant WHERE id = $1", id)
+	return grant, err
+}
+
+// найти экстренный доступ по id и заблокировать его до конца транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (grant Entity, err error) {
+	err = tx.GetContext(ctx, &grant, "SELECT * FROM break_glass_grant WHERE id = $1 FOR UPDATE", id)
+	return grant, err
+}
+
+// FindEmployeeIdBySubject сотрудник, связанный с пользователем Keycloak
+func (r *Repository) FindEmployeeIdBySubject(ctx context.Context, subject string) (id int64, err error) {
+	err = r.db.GetContext(ctx, &id, "SELECT id FROM employee WHERE subject = $1", subject)
+	return id, err
+}
+
+// найти имя роли
+func (r *Repository) FindRoleName(ctx context.Context, roleId int64) (name string, err error) {
+	err = r.db.GetContext(ctx, &name, "SELECT name FROM role WHERE id = $1", roleId)
+	return name, err
+}
+
+// проверка, есть ли у сотрудника действующий экстренный доступ к роли, в рамках транзакции
+func (r *Repository) ActiveExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM break_glass_grant WHERE employee_id = $1 AND role_id = $2 AND state = 'active')"
+	err = tx.GetContext(ctx, &isExists, query, employeeId, roleId)
+	return isExists, err
+}
+
+// добавить экстренный доступ в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, grant Entity) (created Entity, err error) {
+	query := `INSERT INTO break_glass_grant (employee_id, role_id, reason, actor, expires_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING *`
+	err = tx.GetContext(ctx, &created, query, grant.EmployeeId, grant.RoleId, grant.Reason, grant.Actor, grant.ExpiresAt)
+	return created, err
+}
+
+// GrantRoleTx назначает роль сотруднику; роль, которая у сотрудника уже есть, не меняется
+// и не отзывается по окончании экстренного доступа
+func (r *Repository) GrantRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error {
+	query := `INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'break_glass')
+ON CONFLICT (employee_id, role_id) DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, employeeId, roleId)
+	return err
+}
+
+// отозвать роль, назначенную экстренным доступом, в рамках транзакции
+func (r *Repository) RevokeRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error {
+	query := "DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2 AND source = 'break_glass'"
+	_, err := tx.ExecContext(ctx, query, employeeId, roleId)
+	return err
+}
+
+// завершить действующий экстренный доступ в рамках транзакции
+func (r *Repository) EndTx(ctx context.Context, tx *sqlx.Tx, id int64, state string) error {
+	query := "UPDATE break_glass_grant SET state = $1, end_at = now() WHERE id = $2 AND state = 'active'"
+	_, err := tx.ExecContext(ctx, query, state, id)
+	return err
+}
+
+// FindDueTx находит истёкший экстренный доступ и блокирует его. Заблокированные другими
+// экземплярами приложения доступы пропускаются
+func (r *Repository) FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (grant Entity, err error) {
+	query := `SELECT * FROM break_glass_grant WHERE state = 'active' AND expires_at <= $1
+ORDER BY expires_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	err = tx.GetContext(ctx, &grant, query, now)
+	return grant, err
+}
+
+// закрыть разбор инцидента в рамках транзакции
+func (r *Repository) CloseReviewTx(ctx context.Context, tx *sqlx.Tx, id int64, reviewer string, comment string) error {
+	query := `UPDATE break_glass_grant SET review_state = 'closed', reviewed_by = $1, review_comment = $2, reviewed_at = now()
+WHERE id = $3`
+	_, err := tx.ExecContext(ctx, query, reviewer, comment, id)
+	return err
+}
@@ -0,0 +1,14 @@
+package breakglass
+
+// ActivateRequest включение экстренного доступа вызывающему
+type ActivateRequest struct {
+	RoleId int64 `json:"role_id" validate:"required,gt=0"`
+	// длительность доступа в минутах; 0 - наибольшая допустимая длительность
+	TtlMinutes int    `json:"ttl_minutes" validate:"gte=0"`
+	Reason     string `json:"reason" validate:"required,min=10,max=500"`
+}
+
+// ReviewRequest закрытие разбора инцидента
+type ReviewRequest struct {
+	Comment string `json:"comment" validate:"required,max=1000"`
+}
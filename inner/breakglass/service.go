@@ -0,0 +1,483 @@
+package breakglass
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"slices"
+	"strings"
+	"time"
+)
+
+// сколько истёкших доступов отзывается за один запуск планировщика
+const maxDuePerRun = 100
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	FindAll(ctx context.Context, openReviews bool) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindEmployeeIdBySubject(ctx context.Context, subject string) (int64, error)
+	FindRoleName(ctx context.Context, roleId int64) (string, error)
+	ActiveExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, grant Entity) (Entity, error)
+	GrantRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error
+	RevokeRoleTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error
+	EndTx(ctx context.Context, tx *sqlx.Tx, id int64, state string) error
+	FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (Entity, error)
+	CloseReviewTx(ctx context.Context, tx *sqlx.Tx, id int64, reviewer string, comment string) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// AccessSvc фактический доступ сотрудника: экстренный доступ получает только активный сотрудник,
+// у которого ещё нет этой роли ни одним путём
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// ChangeListener получает сотрудника, который получил или потерял роль экстренного доступа.
+// Вызывается в транзакции изменения доступа, например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	access    AccessSvc
+	alerter   Alerter
+	validator Validator
+	listener  ChangeListener
+	// имена ролей, которые можно получить экстренным доступом
+	roles  []string
+	maxTtl time.Duration
+}
+
+func NewService(repo Repo, audit AuditRepo, access AccessSvc, alerter Alerter, validator Validator, roles []string,
+	maxTtl time.Duration) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		access:    access,
+		alerter:   alerter,
+		validator: validator,
+		roles:     roles,
+		maxTtl:    maxTtl,
+	}
+}
+
+// SetChangeListener подключает получателя изменений ролей экстренного доступа
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+// ParseRoles разбирает список ролей экстренного доступа через запятую
+func ParseRoles(value string) []string {
+	var roles []string
+	for _, role := range strings.Split(value, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// GetAll возвращает экстренные доступы; openReviews - только с незакрытым разбором инцидента
+func (s *Service) GetAll(ctx context.Context, openReviews bool) ([]Response, error) {
+	grants, err := s.repo.FindAll(ctx, openReviews)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting break-glass grants: %w", err)
+	}
+	response := make([]Response, 0, len(grants))
+	for _, item := range grants {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	grant, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("break-glass grant with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding break-glass grant with id %d: %w", id, err)
+	}
+	return grant.toResponse(), nil
+}
+
+// Activate сразу выдаёт вызывающему экстренную роль на время ttl, открывает разбор инцидента и
+// после фиксации отправляет оповещение
+func (s *Service) Activate(ctx context.Context, request ActivateRequest, principal common.Principal) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	ttl := time.Duration(request.TtlMinutes) * time.Minute
+	if ttl == 0 {
+		ttl = s.maxTtl
+	}
+	if ttl > s.maxTtl {
+		return Response{}, common.RequestValidatorError{Message: fmt.Sprintf("ttl must not exceed %s", s.maxTtl)}
+	}
+	roleName, err := s.repo.FindRoleName(ctx, request.RoleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", request.RoleId)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding role with id %d: %w", request.RoleId, err)
+	}
+	if !slices.Contains(s.roles, roleName) {
+		return Response{}, common.ForbiddenError{Message: fmt.Sprintf("role %s is not a break-glass role", roleName)}
+	}
+	if principal.Subject == "" {
+		return Response{}, common.ForbiddenError{Message: "token has no subject"}
+	}
+	employeeId, err := s.repo.FindEmployeeIdBySubject(ctx, principal.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.ForbiddenError{
+			Message: fmt.Sprintf("token subject %s is not linked to employee", principal.Subject),
+		}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with subject %s: %w", principal.Subject, err)
+	}
+	resolved, err := s.access.Resolve(ctx, employeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error resolving access of employee %d: %w", employeeId, err)
+	}
+	if resolved.Status != lifecycle.StatusActive {
+		return Response{}, common.ForbiddenError{
+			Message: fmt.Sprintf("employee %d is %s, only active employee can activate break-glass access", employeeId, resolved.Status),
+		}
+	}
+	for _, role := range resolved.Roles {
+		if role.RoleId == request.RoleId {
+			return Response{}, common.ConflictError{Message: fmt.Sprintf("employee %d already has role %s", employeeId, roleName)}
+		}
+	}
+
+	grant, err := s.activate(ctx, Entity{
+		EmployeeId: employeeId,
+		RoleId:     request.RoleId,
+		Reason:     strings.TrimSpace(request.Reason),
+		Actor:      principal.Actor,
+		ExpiresAt:  time.Now().Add(ttl),
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	s.alerter.Alert(ctx, Alert{
+		GrantId:    grant.Id,
+		EmployeeId: grant.EmployeeId,
+		RoleId:     grant.RoleId,
+		RoleName:   roleName,
+		Actor:      grant.Actor,
+		Reason:     grant.Reason,
+		ExpiresAt:  grant.ExpiresAt,
+	})
+	return grant.toResponse(), nil
+}
+
+func (s *Service) activate(ctx context.Context, grant Entity) (created Entity, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Entity{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("activating break-glass panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("activating break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("activating break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("activating break-glass: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.ActiveExistsTx(ctx, tx, grant.EmployeeId, grant.RoleId)
+	if err != nil {
+		return Entity{}, fmt.Errorf("error finding active break-glass grants: %w", err)
+	}
+	if isExist {
+		err = common.ConflictError{Message: fmt.Sprintf("employee %d already has break-glass access to role %d",
+			grant.EmployeeId, grant.RoleId)}
+		return Entity{}, err
+	}
+	created, err = s.repo.CreateTx(ctx, tx, grant)
+	if err != nil {
+		return Entity{}, fmt.Errorf("error creating break-glass grant: %w", err)
+	}
+	if err = s.repo.GrantRoleTx(ctx, tx, grant.EmployeeId, grant.RoleId); err != nil {
+		return Entity{}, fmt.Errorf("error granting role %d to employee %d: %w", grant.RoleId, grant.EmployeeId, err)
+	}
+	if err = s.notifyTx(ctx, tx, grant.EmployeeId); err != nil {
+		return Entity{}, err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      grant.Actor,
+		Action:     "break_glass.activated",
+		EntityType: "break_glass_grant",
+		EntityId:   created.Id,
+		Details:    created.toResponse(),
+	})
+	if err != nil {
+		return Entity{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return created, nil
+}
+
+// Revoke досрочно отзывает экстренный доступ. Отозвать может администратор или тот, кто его включил
+func (s *Service) Revoke(ctx context.Context, id int64, principal common.Principal) (response Response, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("revoking break-glass panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("revoking break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("revoking break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("revoking break-glass: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	grant, err := s.findByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	if !principal.Admin && principal.Actor != grant.Actor {
+		err = common.ForbiddenError{Message: "only own break-glass access can be revoked"}
+		return Response{}, err
+	}
+	if grant.State != StateActive {
+		err = common.ConflictError{Message: fmt.Sprintf("break-glass grant with id %d is already %s", id, grant.State)}
+		return Response{}, err
+	}
+	response, err = s.endTx(ctx, tx, grant, StateRevoked, principal.Actor)
+	return response, err
+}
+
+// Review закрывает разбор инцидента после окончания экстренного доступа. Разбор закрывает
+// администратор, который не включал этот доступ
+func (s *Service) Review(ctx context.Context, id int64, request ReviewRequest, principal common.Principal) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	if !principal.Admin {
+		return Response{}, common.ForbiddenError{Message: "review must be closed by admin"}
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reviewing break-glass panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reviewing break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reviewing break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("reviewing break-glass: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	grant, err := s.findByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	if principal.Actor == grant.Actor {
+		err = common.ForbiddenError{Message: "review must be closed by another admin"}
+		return Response{}, err
+	}
+	if grant.State == StateActive {
+		err = common.ConflictError{Message: fmt.Sprintf("break-glass grant with id %d is still active", id)}
+		return Response{}, err
+	}
+	if grant.ReviewState == ReviewClosed {
+		err = common.ConflictError{Message: fmt.Sprintf("review of break-glass grant with id %d is already closed", id)}
+		return Response{}, err
+	}
+	comment := strings.TrimSpace(request.Comment)
+	if err = s.repo.CloseReviewTx(ctx, tx, id, principal.Actor, comment); err != nil {
+		return Response{}, fmt.Errorf("error closing review of break-glass grant %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      principal.Actor,
+		Action:     "break_glass.reviewed",
+		EntityType: "break_glass_grant",
+		EntityId:   id,
+		Details:    map[string]any{"comment": comment},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	grant, err = s.findByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	return grant.toResponse(), nil
+}
+
+// ExecuteDue отзывает истёкший экстренный доступ, каждый в своей транзакции
+func (s *Service) ExecuteDue(ctx context.Context, now time.Time) (executed int, err error) {
+	for executed < maxDuePerRun {
+		found, err := s.expireNext(ctx, now)
+		if err != nil {
+			return executed, err
+		}
+		if !found {
+			break
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// expireNext отзывает один истёкший экстренный доступ; false, если таких нет
+func (s *Service) expireNext(ctx context.Context, now time.Time) (found bool, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return false, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("expiring break-glass panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("expiring break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("expiring break-glass: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("expiring break-glass: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	grant, err := s.repo.FindDueTx(ctx, tx, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error finding expired break-glass grants: %w", err)
+	}
+	if _, err = s.endTx(ctx, tx, grant, StateExpired, grant.Actor); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// endTx отзывает роль экстренного доступа и пишет аудит
+func (s *Service) endTx(ctx context.Context, tx *sqlx.Tx, grant Entity, state string, actor string) (Response, error) {
+	if err := s.repo.RevokeRoleTx(ctx, tx, grant.EmployeeId, grant.RoleId); err != nil {
+		return Response{}, fmt.Errorf("error revoking role %d of employee %d: %w", grant.RoleId, grant.EmployeeId, err)
+	}
+	if err := s.repo.EndTx(ctx, tx, grant.Id, state); err != nil {
+		return Response{}, fmt.Errorf("error ending break-glass grant %d: %w", grant.Id, err)
+	}
+	if err := s.notifyTx(ctx, tx, grant.EmployeeId); err != nil {
+		return Response{}, err
+	}
+	err := s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "break_glass." + state,
+		EntityType: "break_glass_grant",
+		EntityId:   grant.Id,
+		Details:    map[string]any{"employee_id": grant.EmployeeId, "role_id": grant.RoleId},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	ended, err := s.findByIdTx(ctx, tx, grant.Id)
+	if err != nil {
+		return Response{}, err
+	}
+	return ended.toResponse(), nil
+}
+
+// notifyTx сообщает об изменении ролей сотрудника в транзакции изменения экстренного доступа
+func (s *Service) notifyTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	if s.listener == nil {
+		return nil
+	}
+	if err := s.listener.RolesChangedTx(ctx, tx, []int64{employeeId}); err != nil {
+		return fmt.Errorf("error notifying about break-glass roles of employee %d changed: %w", employeeId, err)
+	}
+	return nil
+}
+
+func (s *Service) findByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	grant, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("break-glass grant with id %d not found", id)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("error finding break-glass grant with id %d: %w", id, err)
+	}
+	return grant, nil
+}
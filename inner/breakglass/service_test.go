@@ -0,0 +1,387 @@
+package breakglass
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	roleNameQuery   = regexp.QuoteMeta("SELECT name FROM role WHERE id = $1")
+	subjectQuery    = regexp.QuoteMeta("SELECT id FROM employee WHERE subject = $1")
+	activeQuery     = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM break_glass_grant WHERE employee_id = $1 AND role_id = $2 AND state = 'active')")
+	insertQuery     = regexp.QuoteMeta("INSERT INTO break_glass_grant (employee_id, role_id, reason, actor, expires_at)")
+	grantRoleQuery  = regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'break_glass')")
+	revokeRoleQuery = regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1 AND role_id = $2 AND source = 'break_glass'")
+	findTxQuery     = regexp.QuoteMeta("SELECT * FROM break_glass_grant WHERE id = $1 FOR UPDATE")
+	endQuery        = regexp.QuoteMeta("UPDATE break_glass_grant SET state = $1, end_at = now() WHERE id = $2 AND state = 'active'")
+	dueQuery        = regexp.QuoteMeta("SELECT * FROM break_glass_grant WHERE state = 'active' AND expires_at <= $1")
+	reviewQuery     = regexp.QuoteMeta("UPDATE break_glass_grant SET review_state = 'closed', reviewed_by = $1, review_comment = $2")
+	auditQuery      = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	grantColumns    = []string{"id", "employee_id", "role_id", "reason", "actor", "expires_at", "state", "end_at",
+		"review_state", "reviewed_by", "review_comment", "reviewed_at", "create_at"}
+	admin    = common.Principal{Subject: "kc-admin", Actor: "kc-admin", Admin: true}
+	engineer = common.Principal{Subject: "kc-7", Actor: "kc-7"}
+)
+
+const reason = "incident INC-42: database is down"
+
+type MockAlerter struct {
+	mock.Mock
+}
+
+func (m *MockAlerter) Alert(ctx context.Context, alert Alert) {
+	m.Called(alert)
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+// accessStub фактический доступ сотрудников; не указанный сотрудник активен и не имеет ролей
+type accessStub map[int64]access.Response
+
+func (s accessStub) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	resolved, ok := s[employeeId]
+	if !ok {
+		resolved = access.Response{EmployeeId: employeeId, Status: lifecycle.StatusActive}
+	}
+	return resolved, nil
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *MockAlerter) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	alerter := &MockAlerter{}
+	srv := NewService(NewBreakGlassRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), accessStub{}, alerter,
+		validator.NewValidator(), []string{"prod-db"}, time.Hour)
+	return srv, mock, alerter
+}
+
+// строка экстренного доступа 1 сотрудника 7 к роли 3
+func grantRow(state string, reviewState string, expiresAt time.Time, now time.Time) *sqlmock.Rows {
+	var endAt any
+	if state != StateActive {
+		endAt = now
+	}
+	return sqlmock.NewRows(grantColumns).
+		AddRow(1, 7, 3, reason, "kc-7", expiresAt, state, endAt, reviewState, nil, nil, nil, now)
+}
+
+func TestParseRoles(t *testing.T) {
+	var a = assert.New(t)
+
+	a.Equal([]string{"prod-db", "root"}, ParseRoles(" prod-db, ,root "))
+	a.Nil(ParseRoles(""))
+}
+
+func TestService_Activate(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should grant role immediately and send alert", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		now := time.Now()
+		expiresAt := now.Add(30 * time.Minute)
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("prod-db"))
+		dbMock.ExpectQuery(subjectQuery).WithArgs("kc-7").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(activeQuery).WithArgs(int64(7), int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectQuery(insertQuery).WithArgs(int64(7), int64(3), reason, "kc-7", sqlmock.AnyArg()).
+			WillReturnRows(grantRow(StateActive, ReviewOpen, expiresAt, now))
+		dbMock.ExpectExec(grantRoleQuery).WithArgs(int64(7), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-7", "break_glass.activated", "break_glass_grant", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+		alerter.On("Alert", Alert{GrantId: 1, EmployeeId: 7, RoleId: 3, RoleName: "prod-db", Actor: "kc-7", Reason: reason,
+			ExpiresAt: expiresAt}).Return()
+
+		got, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, TtlMinutes: 30, Reason: reason}, engineer)
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, EmployeeId: 7, RoleId: 3, Reason: reason, Actor: "kc-7", ExpiresAt: expiresAt,
+			State: StateActive, ReviewState: ReviewOpen, CreateAt: now}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+		alerter.AssertExpectations(t)
+	})
+
+	t.Run("should notify listener in activation transaction", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{7}).Return(errors.New("database error"))
+		now := time.Now()
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("prod-db"))
+		dbMock.ExpectQuery(subjectQuery).WithArgs("kc-7").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(activeQuery).WithArgs(int64(7), int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectQuery(insertQuery).WithArgs(int64(7), int64(3), reason, "kc-7", sqlmock.AnyArg()).
+			WillReturnRows(grantRow(StateActive, ReviewOpen, now.Add(30*time.Minute), now))
+		dbMock.ExpectExec(grantRoleQuery).WithArgs(int64(7), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, TtlMinutes: 30, Reason: reason}, engineer)
+
+		a.Error(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+		alerter.AssertNotCalled(t, "Alert", mock.Anything)
+	})
+
+	t.Run("should reject ttl longer than maximum", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, TtlMinutes: 120, Reason: reason}, engineer)
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.Equal("ttl must not exceed 1h0m0s", validationErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject request without reason", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3}, engineer)
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ForbiddenError for role not eligible for break-glass", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(4)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("developer"))
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 4, Reason: reason}, engineer)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.Equal("role developer is not a break-glass role", forbiddenErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+		alerter.AssertNotCalled(t, "Alert", mock.Anything)
+	})
+
+	t.Run("should return ForbiddenError for employee who is not active", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		srv.access = accessStub{7: {EmployeeId: 7, Status: lifecycle.StatusSuspended}}
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("prod-db"))
+		dbMock.ExpectQuery(subjectQuery).WithArgs("kc-7").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, Reason: reason}, engineer)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+		alerter.AssertNotCalled(t, "Alert", mock.Anything)
+	})
+
+	t.Run("should return ConflictError if employee already has role by any path", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		srv.access = accessStub{7: {EmployeeId: 7, Status: lifecycle.StatusActive, Roles: []access.Role{{
+			RoleId: 3, RoleName: "prod-db", Grants: []access.Grant{{Source: access.SourceGroup}},
+		}}}}
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("prod-db"))
+		dbMock.ExpectQuery(subjectQuery).WithArgs("kc-7").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, Reason: reason}, engineer)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("employee 7 already has role prod-db", conflictErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+		alerter.AssertNotCalled(t, "Alert", mock.Anything)
+	})
+
+	t.Run("should return ConflictError if access is already active", func(t *testing.T) {
+		srv, dbMock, alerter := newTestService(t)
+		dbMock.ExpectQuery(roleNameQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("prod-db"))
+		dbMock.ExpectQuery(subjectQuery).WithArgs("kc-7").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(activeQuery).WithArgs(int64(7), int64(3)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Activate(context.Background(), ActivateRequest{RoleId: 3, Reason: reason}, engineer)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+		alerter.AssertNotCalled(t, "Alert", mock.Anything)
+	})
+}
+
+func TestService_Revoke(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should revoke own active access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		expiresAt := now.Add(time.Hour)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateActive, ReviewOpen, expiresAt, now))
+		dbMock.ExpectExec(revokeRoleQuery).WithArgs(int64(7), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(endQuery).WithArgs(StateRevoked, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-7", "break_glass.revoked", "break_glass_grant", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateRevoked, ReviewOpen, expiresAt, now))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Revoke(context.Background(), 1, engineer)
+
+		a.Nil(err)
+		a.Equal(StateRevoked, got.State)
+		a.Equal(&now, got.EndAt)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ForbiddenError for access of another employee", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateActive, ReviewOpen, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Revoke(context.Background(), 1, common.Principal{Subject: "kc-8", Actor: "kc-8"})
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for expired access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateExpired, ReviewOpen, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Revoke(context.Background(), 1, admin)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("break-glass grant with id 1 is already expired", conflictErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Review(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should close review of ended access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateExpired, ReviewOpen, now, now))
+		dbMock.ExpectExec(reviewQuery).WithArgs("kc-admin", "restart was required", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "break_glass.reviewed", "break_glass_grant", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(grantColumns).
+			AddRow(1, 7, 3, reason, "kc-7", now, StateExpired, now, ReviewClosed, "kc-admin", "restart was required", now, now))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Review(context.Background(), 1, ReviewRequest{Comment: " restart was required "}, admin)
+
+		a.Nil(err)
+		a.Equal(ReviewClosed, got.ReviewState)
+		a.Equal("kc-admin", got.ReviewedBy)
+		a.Equal("restart was required", got.ReviewComment)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ForbiddenError for review by the one who activated access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateExpired, ReviewOpen, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Review(context.Background(), 1, ReviewRequest{Comment: "ok"},
+			common.Principal{Subject: "kc-7", Actor: "kc-7", Admin: true})
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for active access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateActive, ReviewOpen, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Review(context.Background(), 1, ReviewRequest{Comment: "ok"}, admin)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("break-glass grant with id 1 is still active", conflictErr.Message)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_ExecuteDue(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should expire due access and revoke its role", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(grantRow(StateActive, ReviewOpen, now, now))
+		dbMock.ExpectExec(revokeRoleQuery).WithArgs(int64(7), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(endQuery).WithArgs(StateExpired, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-7", "break_glass.expired", "break_glass_grant", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateExpired, ReviewOpen, now, now))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(grantColumns))
+		dbMock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should notify listener about expired access", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{7}).Return(nil)
+		now := time.Now()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(grantRow(StateActive, ReviewOpen, now, now))
+		dbMock.ExpectExec(revokeRoleQuery).WithArgs(int64(7), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(endQuery).WithArgs(StateExpired, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-7", "break_glass.expired", "break_glass_grant", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).WillReturnRows(grantRow(StateExpired, ReviewOpen, now, now))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(grantColumns))
+		dbMock.ExpectCommit()
+
+		_, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+}
@@ -6,20 +6,90 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
+	"time"
 )
 
+// маска, которой заменяются секреты при выводе конфигурации
+const redactedValue = "***"
+
+// шаблон пароля в DSN формата "key=value"
+var dsnPasswordRe = regexp.MustCompile(`(?i)(password=)('[^']*'|\S+)`)
+
 // Config общая конфигурация всего приложения
 type Config struct {
-	DbDriverName   string `validate:"required"`
-	DSN            string `validate:"required"`
-	AppName        string `validate:"required"`
-	AppVersion     string `validate:"required"`
-	LogLevel       string `validate:"required"`
-	LogDevelopMode bool   `validate:"required"`
-	SslCert        string `validate:"required"`
-	SslKey         string `validate:"required"`
-	KeycloakJwkUrl string `validate:"required"`
+	DbDriverName   string `json:"db_driver_name" validate:"required"`
+	DSN            string `json:"dsn" validate:"required"`
+	AppName        string `json:"app_name" validate:"required"`
+	AppVersion     string `json:"app_version" validate:"required"`
+	LogLevel       string `json:"log_level" validate:"required"`
+	LogDevelopMode bool   `json:"log_develop_mode" validate:"required"`
+	SslCert        string `json:"ssl_cert" validate:"required"`
+	SslKey         string `json:"ssl_key" validate:"required"`
+	KeycloakJwkUrl string `json:"keycloak_jwk_url" validate:"required"`
+	// лимит запросов клиента по умолчанию, например "100/1m"
+	RateLimitDefault string `json:"rate_limit_default"`
+	// лимиты отдельных маршрутов, например "POST /api/v1/employees/ids=10/1m;GET /api/v1/employees=30/1m"
+	RateLimitRoutes string `json:"rate_limit_routes"`
+	// хранилище лимитов: memory (по умолчанию) или postgres для нескольких экземпляров приложения
+	RateLimitStore string `json:"rate_limit_store"`
+	// интервал удаления из postgres корзин, которые не использовались дольше периода своего лимита
+	RateLimitPurgeInterval time.Duration `json:"rate_limit_purge_interval"`
+	// время хранения ответов на запросы с заголовком Idempotency-Key
+	IdempotencyTtl time.Duration `json:"idempotency_ttl"`
+	// на сколько ключ идемпотентности резервируется за выполняющимся запросом; если экземпляр приложения
+	// упал, не сохранив ответ, повтор с тем же ключом будет возможен через это время
+	IdempotencyLockTtl time.Duration `json:"idempotency_lock_ttl"`
+	// хранилище ключей идемпотентности: memory (по умолчанию) или postgres
+	IdempotencyStore string `json:"idempotency_store"`
+	// интервал удаления истёкших ключей идемпотентности из postgres
+	IdempotencyPurgeInterval time.Duration `json:"idempotency_purge_interval"`
+	// сколько readiness probe отвечает 503 перед закрытием соединений при остановке,
+	// чтобы балансировщик успел вывести экземпляр из-под нагрузки
+	ShutdownDrainDelay time.Duration `json:"shutdown_drain_delay"`
+	// интервал проверки запланированных смен статуса сотрудников
+	LifecycleInterval time.Duration `json:"lifecycle_interval"`
+	// что делать с вызывающим /me, не связанным с сотрудником: reject (по умолчанию) или provision
+	MeUnknownSubject string `json:"me_unknown_subject"`
+	// интервал проверки начала и окончания замещений
+	DelegationInterval time.Duration `json:"delegation_interval"`
+	// наибольшая длительность замещения
+	DelegationMaxDuration time.Duration `json:"delegation_max_duration"`
+	// правила видимости сотрудников по ролям, например "IDM_ADMIN=all;IDM_USER=self,org_unit,reports"
+	VisibilityPolicies string `json:"visibility_policies"`
+	// роли экстренного доступа через запятую, например "EMERGENCY_ADMIN,DB_ROOT"
+	BreakGlassRoles string `json:"break_glass_roles"`
+	// наибольшая длительность экстренного доступа
+	BreakGlassMaxTtl time.Duration `json:"break_glass_max_ttl"`
+	// интервал проверки истёкшего экстренного доступа
+	BreakGlassInterval time.Duration `json:"break_glass_interval"`
+	// каталог файлового коннектора провижининга; пустой - коннекторы не подключаются
+	ProvisioningDir string `json:"provisioning_dir"`
+	// формат файлов учётных записей: json (по умолчанию) или csv
+	ProvisioningFormat string `json:"provisioning_format"`
+	// целевые системы через запятую, которые обслуживает файловый коннектор, каждая в своём подкаталоге
+	ProvisioningApplications string `json:"provisioning_applications"`
+	// после стольких неудачных попыток задача провижининга больше не повторяется
+	ProvisioningMaxAttempts int `json:"provisioning_max_attempts"`
+	// интервал обработки очереди провижининга
+	ProvisioningInterval time.Duration `json:"provisioning_interval"`
+	// интервал сверки учётных записей целевых систем с правами сотрудников
+	AccountReconciliationInterval time.Duration `json:"account_reconciliation_interval"`
+	// адрес Keycloak для Admin REST API, например "https://keycloak:8443"; пустой - синхронизация ролей отключена
+	KeycloakAdminUrl string `json:"keycloak_admin_url"`
+	// область Keycloak, роли которой синхронизируются с ролями IDM
+	KeycloakRealm string `json:"keycloak_realm"`
+	// confidential client с service account, от имени которого IDM вызывает Admin REST API;
+	// секрет клиента наружу не выводится
+	KeycloakClientId     string `json:"keycloak_client_id"`
+	KeycloakClientSecret string `json:"-"`
+	// направление синхронизации ролей: push (по умолчанию), import или both
+	KeycloakSyncDirection string `json:"keycloak_sync_direction" validate:"oneof=push import both"`
+	// интервал синхронизации ролей с Keycloak
+	KeycloakSyncInterval time.Duration `json:"keycloak_sync_interval"`
 }
 
 // GetConfig получение конфигурации из .env файла или переменных окружения
@@ -31,15 +101,44 @@ func GetConfig(envFile string) Config {
 	}
 
 	cfg := Config{
-		DbDriverName:   os.Getenv("DB_DRIVER_NAME"),
-		DSN:            os.Getenv("DB_DSN"),
-		AppName:        os.Getenv("APP_NAME"),
-		AppVersion:     os.Getenv("APP_VERSION"),
-		LogLevel:       os.Getenv("LOG_LEVEL"),
-		LogDevelopMode: os.Getenv("LOG_DEVELOP_MODE") == "true",
-		SslCert:        os.Getenv("SSL_CERT"),
-		SslKey:         os.Getenv("SSL_KEY"),
-		KeycloakJwkUrl: os.Getenv("KEYCLOAK_JWK_URL"),
+		DbDriverName:                  os.Getenv("DB_DRIVER_NAME"),
+		DSN:                           os.Getenv("DB_DSN"),
+		AppName:                       os.Getenv("APP_NAME"),
+		AppVersion:                    os.Getenv("APP_VERSION"),
+		LogLevel:                      os.Getenv("LOG_LEVEL"),
+		LogDevelopMode:                os.Getenv("LOG_DEVELOP_MODE") == "true",
+		SslCert:                       os.Getenv("SSL_CERT"),
+		SslKey:                        os.Getenv("SSL_KEY"),
+		KeycloakJwkUrl:                os.Getenv("KEYCLOAK_JWK_URL"),
+		RateLimitDefault:              getEnvDefault("RATE_LIMIT_DEFAULT", "100/1m"),
+		RateLimitRoutes:               os.Getenv("RATE_LIMIT_ROUTES"),
+		RateLimitStore:                getEnvDefault("RATE_LIMIT_STORE", "memory"),
+		RateLimitPurgeInterval:        getEnvDuration("RATE_LIMIT_PURGE_INTERVAL", 10*time.Minute),
+		IdempotencyTtl:                getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		IdempotencyLockTtl:            getEnvDuration("IDEMPOTENCY_LOCK_TTL", time.Minute),
+		IdempotencyStore:              getEnvDefault("IDEMPOTENCY_STORE", "memory"),
+		IdempotencyPurgeInterval:      getEnvDuration("IDEMPOTENCY_PURGE_INTERVAL", 10*time.Minute),
+		ShutdownDrainDelay:            getEnvDuration("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		LifecycleInterval:             getEnvDuration("LIFECYCLE_INTERVAL", time.Minute),
+		MeUnknownSubject:              getEnvDefault("ME_UNKNOWN_SUBJECT", "reject"),
+		DelegationInterval:            getEnvDuration("DELEGATION_INTERVAL", time.Minute),
+		DelegationMaxDuration:         getEnvDuration("DELEGATION_MAX_DURATION", 30*24*time.Hour),
+		VisibilityPolicies:            getEnvDefault("VISIBILITY_POLICIES", "IDM_ADMIN=all;IDM_USER=self,org_unit,reports"),
+		BreakGlassRoles:               os.Getenv("BREAK_GLASS_ROLES"),
+		BreakGlassMaxTtl:              getEnvDuration("BREAK_GLASS_MAX_TTL", time.Hour),
+		BreakGlassInterval:            getEnvDuration("BREAK_GLASS_INTERVAL", time.Minute),
+		ProvisioningDir:               os.Getenv("PROVISIONING_DIR"),
+		ProvisioningFormat:            getEnvDefault("PROVISIONING_FORMAT", "json"),
+		ProvisioningApplications:      os.Getenv("PROVISIONING_APPLICATIONS"),
+		ProvisioningMaxAttempts:       getEnvInt("PROVISIONING_MAX_ATTEMPTS", 5),
+		ProvisioningInterval:          getEnvDuration("PROVISIONING_INTERVAL", time.Minute),
+		AccountReconciliationInterval: getEnvDuration("ACCOUNT_RECONCILIATION_INTERVAL", time.Hour),
+		KeycloakAdminUrl:              os.Getenv("KEYCLOAK_ADMIN_URL"),
+		KeycloakRealm:                 os.Getenv("KEYCLOAK_REALM"),
+		KeycloakClientId:              os.Getenv("KEYCLOAK_CLIENT_ID"),
+		KeycloakClientSecret:          os.Getenv("KEYCLOAK_CLIENT_SECRET"),
+		KeycloakSyncDirection:         getEnvDefault("KEYCLOAK_SYNC_DIRECTION", "push"),
+		KeycloakSyncInterval:          getEnvDuration("KEYCLOAK_SYNC_INTERVAL", time.Hour),
 	}
 
 	err = validator.New().Struct(&cfg)
@@ -52,3 +151,50 @@ func GetConfig(envFile string) Config {
 	}
 	return cfg
 }
+
+// getEnvDefault возвращает значение переменной окружения или значение по умолчанию, если она не задана
+func getEnvDefault(key string, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvDuration возвращает длительность из переменной окружения или значение по умолчанию,
+// если она не задана или задана некорректно
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvInt возвращает положительное число из переменной окружения или значение по умолчанию,
+// если она не задана или задана некорректно
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Redacted возвращает копию конфигурации, в которой скрыты секреты (пароль в DSN).
+// Используется для вывода активной конфигурации наружу
+func (c Config) Redacted() Config {
+	c.DSN = redactDsn(c.DSN)
+	return c
+}
+
+// redactDsn скрывает пароль в DSN как в формате URL, так и в формате "key=value"
+func redactDsn(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redactedValue)
+			return u.String()
+		}
+		return dsn
+	}
+	return dsnPasswordRe.ReplaceAllString(dsn, "${1}"+redactedValue)
+}
@@ -0,0 +1,272 @@
+package csvutil
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nihrom205/idm/inner/common"
+	"io"
+	"strings"
+)
+
+// Статусы строк импорта
+const (
+	RowCreated   = "created"
+	RowUpdated   = "updated"
+	RowUnchanged = "unchanged"
+	RowRejected  = "rejected"
+)
+
+// RowResult результат импорта одной строки CSV файла
+type RowResult struct {
+	// номер строки в файле, заголовок - строка 1
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	// id созданной или изменённой записи
+	Id      int64                   `json:"id,omitempty"`
+	Message string                  `json:"message,omitempty"`
+	Errors  common.ValidationErrors `json:"errors,omitempty"`
+}
+
+// ImportReport отчёт об импорте CSV файла
+type ImportReport struct {
+	// true, если изменения не были сохранены
+	DryRun    bool        `json:"dry_run"`
+	Created   int         `json:"created"`
+	Updated   int         `json:"updated"`
+	Unchanged int         `json:"unchanged"`
+	Rejected  int         `json:"rejected"`
+	Rows      []RowResult `json:"rows"`
+}
+
+// Add добавляет результат строки в отчёт
+func (r *ImportReport) Add(row RowResult) {
+	switch row.Status {
+	case RowCreated:
+		r.Created++
+	case RowUpdated:
+		r.Updated++
+	case RowUnchanged:
+		r.Unchanged++
+	case RowRejected:
+		r.Rejected++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// Localize переводит сообщения об ошибках валидации строк на указанный язык
+func (r ImportReport) Localize(lang string) ImportReport {
+	rows := make([]RowResult, len(r.Rows))
+	for i, row := range r.Rows {
+		if len(row.Errors) > 0 {
+			validateErr := common.RequestValidatorError{Message: row.Message, Fields: row.Errors}.Localize(lang)
+			row.Message = validateErr.Message
+			row.Errors = validateErr.Fields
+		}
+		rows[i] = row
+	}
+	r.Rows = rows
+	return r
+}
+
+// Rejected результат отклонённой строки
+func Rejected(line int, message string) RowResult {
+	return RowResult{Line: line, Status: RowRejected, Message: message}
+}
+
+// Invalid результат строки, не прошедшей валидацию
+func Invalid(line int, err error) RowResult {
+	validateErr := common.NewRequestValidatorError(err)
+	return RowResult{Line: line, Status: RowRejected, Message: validateErr.Message, Errors: validateErr.Fields}
+}
+
+// ParseMapping разбирает сопоставление полей и заголовков CSV файла в формате JSON,
+// например {"name": "ФИО"}. Пустая строка - заголовки совпадают с именами полей
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+	if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid header mapping: %w", err)
+	}
+	return mapping, nil
+}
+
+// Row строка CSV файла со значениями по именам полей
+type Row struct {
+	Line   int
+	Values map[string]string
+}
+
+// Reader читает строки CSV файла, сопоставляя столбцы с полями по заголовку
+type Reader struct {
+	reader *csv.Reader
+	// индекс столбца для каждого поля
+	columns map[string]int
+}
+
+// NewReader читает заголовок файла. Поле ищется в заголовке по имени из mapping,
+// а если его там нет - по имени самого поля (без учёта регистра).
+// Поля из required обязаны присутствовать в заголовке, остальные поля из fields необязательны
+func NewReader(r io.Reader, fields []string, required []string, mapping map[string]string) (*Reader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, common.RequestValidatorError{Message: "csv file is empty"}
+	}
+	if err != nil {
+		return nil, common.RequestValidatorError{Message: fmt.Sprintf("invalid csv header: %v", err)}
+	}
+
+	headerIndex := make(map[string]int, len(header))
+	for i, name := range header {
+		// Excel добавляет BOM в начало файла
+		name = strings.TrimPrefix(name, "\ufeff")
+		headerIndex[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	columns := make(map[string]int, len(fields))
+	for _, field := range fields {
+		column := field
+		if mapped, ok := mapping[field]; ok {
+			column = mapped
+		}
+		if i, ok := headerIndex[strings.ToLower(strings.TrimSpace(column))]; ok {
+			columns[field] = i
+		}
+	}
+	for _, field := range required {
+		if _, ok := columns[field]; !ok {
+			return nil, common.RequestValidatorError{Message: fmt.Sprintf("csv header does not contain column for field %s", field)}
+		}
+	}
+	return &Reader{reader: reader, columns: columns}, nil
+}
+
+// Read возвращает следующую непустую строку файла или io.EOF в конце файла
+func (r *Reader) Read() (Row, error) {
+	for {
+		record, err := r.reader.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return Row{}, io.EOF
+			}
+			return Row{}, common.RequestValidatorError{Message: fmt.Sprintf("invalid csv file: %v", err)}
+		}
+		line, _ := r.reader.FieldPos(0)
+		if isEmpty(record) {
+			continue
+		}
+
+		values := make(map[string]string, len(r.columns))
+		for field, i := range r.columns {
+			if i < len(record) {
+				values[field] = strings.TrimSpace(record[i])
+			}
+		}
+		return Row{Line: line, Values: values}, nil
+	}
+}
+
+func isEmpty(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Writer пишет CSV файл для выгрузки
+type Writer struct {
+	writer *csv.Writer
+}
+
+// NewWriter создаёт Writer и сразу пишет заголовок
+func NewWriter(w io.Writer, header []string) (*Writer, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{writer: writer}, nil
+}
+
+// Write пишет строку. Значения, которые табличный редактор выполнил бы как формулу, экранируются
+func (w *Writer) Write(record []string) error {
+	for i, value := range record {
+		record[i] = escapeFormula(value)
+	}
+	return w.writer.Write(record)
+}
+
+// Flush дописывает буферизованные строки
+func (w *Writer) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// escapeFormula защищает от CSV инъекций: значения, начинающиеся с =, +, - или @,
+// открываются табличными редакторами как формулы
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// Rows курсор по результату запроса к базе данных, например *sqlx.Rows
+type Rows interface {
+	Next() bool
+	Err() error
+	Close() error
+}
+
+// Export выгрузка в CSV файл по уже выполненному запросу: строки читаются из базы данных
+// по мере записи файла. Выгрузку нужно закрыть
+type Export interface {
+	// Write пишет заголовок и строки CSV файла
+	Write(w io.Writer) error
+	Close() error
+}
+
+// rowsExport выгрузка строк курсора
+type rowsExport struct {
+	rows   Rows
+	header []string
+	// читает текущую строку курсора и возвращает строку CSV файла
+	record func() ([]string, error)
+}
+
+// NewExport создаёт выгрузку строк курсора rows в CSV файл с заголовком header
+func NewExport(rows Rows, header []string, record func() ([]string, error)) Export {
+	return &rowsExport{rows: rows, header: header, record: record}
+}
+
+func (e *rowsExport) Write(w io.Writer) error {
+	writer, err := NewWriter(w, e.header)
+	if err != nil {
+		return fmt.Errorf("error writing csv header: %w", err)
+	}
+	for e.rows.Next() {
+		record, err := e.record()
+		if err != nil {
+			return err
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := e.rows.Err(); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func (e *rowsExport) Close() error {
+	return e.rows.Close()
+}
@@ -0,0 +1,155 @@
+package csvutil
+
+import (
+	"bytes"
+	"errors"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should map columns by header", func(t *testing.T) {
+		file := "\ufeffКод,ФИО,Отдел\n1, John ,IT\n\n,Jane\n"
+		reader, err := NewReader(strings.NewReader(file), []string{"id", "name"}, []string{"name"},
+			map[string]string{"id": "код", "name": "ФИО"})
+		a.Nil(err)
+
+		row, err := reader.Read()
+		a.Nil(err)
+		a.Equal(Row{Line: 2, Values: map[string]string{"id": "1", "name": "John"}}, row)
+
+		// пустая строка пропускается, недостающие значения считаются пустыми
+		row, err = reader.Read()
+		a.Nil(err)
+		a.Equal(Row{Line: 4, Values: map[string]string{"id": "", "name": "Jane"}}, row)
+
+		_, err = reader.Read()
+		a.ErrorIs(err, io.EOF)
+	})
+
+	t.Run("should use field name as header by default", func(t *testing.T) {
+		reader, err := NewReader(strings.NewReader("Name\nJohn\n"), []string{"id", "name"}, []string{"name"}, nil)
+		a.Nil(err)
+
+		row, err := reader.Read()
+		a.Nil(err)
+		a.Equal(map[string]string{"name": "John"}, row.Values)
+	})
+
+	t.Run("should return validation error for invalid file", func(t *testing.T) {
+		for _, file := range []string{"", "id,title\n1,John\n"} {
+			_, err := NewReader(strings.NewReader(file), []string{"id", "name"}, []string{"name"}, nil)
+			var validateErr common.RequestValidatorError
+			a.True(errors.As(err, &validateErr))
+		}
+
+		reader, err := NewReader(strings.NewReader("name\n\"John\"x\n"), []string{"name"}, []string{"name"}, nil)
+		a.Nil(err)
+		_, err = reader.Read()
+		var validateErr common.RequestValidatorError
+		a.True(errors.As(err, &validateErr))
+	})
+}
+
+func TestParseMapping(t *testing.T) {
+	a := assert.New(t)
+
+	mapping, err := ParseMapping(`{"name": "ФИО"}`)
+	a.Nil(err)
+	a.Equal(map[string]string{"name": "ФИО"}, mapping)
+
+	mapping, err = ParseMapping("")
+	a.Nil(err)
+	a.Empty(mapping)
+
+	_, err = ParseMapping("name=ФИО")
+	a.NotNil(err)
+}
+
+func TestWriter(t *testing.T) {
+	a := assert.New(t)
+	var buf bytes.Buffer
+
+	writer, err := NewWriter(&buf, []string{"id", "name"})
+	a.Nil(err)
+	a.Nil(writer.Write([]string{"1", "=HYPERLINK(\"http://evil\")"}))
+	a.Nil(writer.Write([]string{"2", "Smith, John"}))
+	a.Nil(writer.Flush())
+
+	a.Equal("id,name\n1,\"'=HYPERLINK(\"\"http://evil\"\")\"\n2,\"Smith, John\"\n", buf.String())
+}
+
+// sliceRows курсор по строкам в памяти
+type sliceRows struct {
+	records [][]string
+	current int
+	err     error
+	closed  bool
+}
+
+func (r *sliceRows) Next() bool {
+	r.current++
+	return r.current <= len(r.records)
+}
+
+func (r *sliceRows) Err() error {
+	return r.err
+}
+
+func (r *sliceRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should write header and rows", func(t *testing.T) {
+		rows := &sliceRows{records: [][]string{{"1", "John"}, {"2", "=cmd"}}}
+		export := NewExport(rows, []string{"id", "name"}, func() ([]string, error) {
+			return rows.records[rows.current-1], nil
+		})
+
+		var buf bytes.Buffer
+		a.Nil(export.Write(&buf))
+		a.Nil(export.Close())
+
+		a.Equal("id,name\n1,John\n2,'=cmd\n", buf.String())
+		a.True(rows.closed)
+	})
+
+	t.Run("should return error of cursor", func(t *testing.T) {
+		rows := &sliceRows{err: errors.New("connection lost")}
+		export := NewExport(rows, []string{"id"}, func() ([]string, error) {
+			return nil, nil
+		})
+
+		a.ErrorContains(export.Write(io.Discard), "connection lost")
+	})
+}
+
+func TestImportReport(t *testing.T) {
+	a := assert.New(t)
+
+	report := ImportReport{}
+	report.Add(RowResult{Line: 2, Status: RowCreated, Id: 1})
+	report.Add(RowResult{Line: 3, Status: RowUpdated, Id: 2})
+	report.Add(Rejected(4, "employee with id 3 not found"))
+	report.Add(Invalid(5, common.ValidationErrors{{
+		Field:        "name",
+		Message:      "name is a required field",
+		Translations: map[string]string{"en": "name is a required field", "ru": "name обязательное поле"},
+	}}))
+
+	a.Equal(1, report.Created)
+	a.Equal(1, report.Updated)
+	a.Equal(2, report.Rejected)
+	a.Equal("name is a required field", report.Rows[3].Message)
+	a.Equal("name обязательное поле", report.Localize("ru").Rows[3].Message)
+	a.Equal("name is a required field", report.Rows[3].Errors[0].Message)
+}
@@ -1,13 +1,78 @@
 package common
 
+import (
+	"errors"
+	"strings"
+)
+
+// язык сообщений об ошибках по умолчанию
+const DefaultLanguage = "en"
+
+// FieldError ошибка валидации одного поля запроса
+type FieldError struct {
+	// имя поля в JSON
+	Field string `json:"field"`
+	// путь к полю в теле запроса, например $.ids[0]
+	JsonPath string `json:"json_path"`
+	// нарушенное правило валидации (тег validate)
+	Rule string `json:"rule"`
+	// параметр правила, например 2 для min=2
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+	// сообщение на поддерживаемых языках
+	Translations map[string]string `json:"-"`
+}
+
+// Localize возвращает ошибку с сообщением на указанном языке, если для него есть перевод
+func (e FieldError) Localize(lang string) FieldError {
+	if msg, ok := e.Translations[lang]; ok {
+		e.Message = msg
+	}
+	return e
+}
+
+// ValidationErrors ошибки валидации полей запроса
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldErr := range e {
+		messages = append(messages, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
 type RequestValidatorError struct {
 	Message string
+	// ошибки отдельных полей, если запрос не прошёл валидацию структуры
+	Fields ValidationErrors
 }
 
 func (e RequestValidatorError) Error() string {
 	return e.Message
 }
 
+// NewRequestValidatorError оборачивает ошибку валидатора, сохраняя ошибки отдельных полей
+func NewRequestValidatorError(err error) RequestValidatorError {
+	var fields ValidationErrors
+	if errors.As(err, &fields) {
+		return RequestValidatorError{Message: fields.Error(), Fields: fields}
+	}
+	return RequestValidatorError{Message: err.Error()}
+}
+
+// Localize возвращает ошибку с сообщениями на указанном языке
+func (e RequestValidatorError) Localize(lang string) RequestValidatorError {
+	if len(e.Fields) == 0 {
+		return e
+	}
+	fields := make(ValidationErrors, 0, len(e.Fields))
+	for _, fieldErr := range e.Fields {
+		fields = append(fields, fieldErr.Localize(lang))
+	}
+	return RequestValidatorError{Message: fields.Error(), Fields: fields}
+}
+
 type AlreadyExistsError struct {
 	Message string
 }
@@ -16,6 +81,24 @@ func (e AlreadyExistsError) Error() string {
 	return e.Message
 }
 
+// ConflictError операция невозможна в текущем состоянии объекта
+type ConflictError struct {
+	Message string
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+// ForbiddenError у вызывающего нет доступа к объекту
+type ForbiddenError struct {
+	Message string
+}
+
+func (e ForbiddenError) Error() string {
+	return e.Message
+}
+
 type NotFoundError struct {
 	Message string
 }
@@ -3,6 +3,7 @@ package common
 import (
 	"context"
 	"github.com/gofiber/contrib/fiberzap/v2"
+	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"go.uber.org/zap"
@@ -12,6 +13,12 @@ import (
 // ключ для получения requestId из контекста
 var ridKey = requestid.ConfigDefault.ContextKey.(string)
 
+// DetachedContext контекст для работы, которая продолжается после выхода из хендлера (например, потоковой выгрузки):
+// он не связан с запросом fasthttp, но сохраняет requestId для логирования
+func DetachedContext(c *fiber.Ctx) context.Context {
+	return context.WithValue(context.Background(), ridKey, c.Locals(ridKey))
+}
+
 // Logger структура логгера
 type Logger struct {
 	*zap.Logger
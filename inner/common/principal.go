@@ -0,0 +1,13 @@
+package common
+
+// Principal вызывающий, от имени которого выполняется операция
+type Principal struct {
+	// claim sub
+	Subject string
+	// роли вызывающего в Keycloak
+	Roles []string
+	// идентификатор вызывающего для журнала аудита
+	Actor string
+	// вызывающий без ограничений: центральный администратор или внутренняя операция приложения
+	Admin bool
+}
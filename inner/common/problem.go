@@ -0,0 +1,161 @@
+package common
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+// MIMEApplicationProblemJSON тип содержимого ответа с ошибкой по RFC 7807
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Стабильные машиночитаемые коды ошибок API
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeAlreadyExists    = "already_exists"
+	CodeValidationFailed = "validation_failed"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeUnavailable      = "service_unavailable"
+	CodeInternal         = "internal_error"
+)
+
+// префикс URI типа ошибки, к нему добавляется код
+const problemTypePrefix = "urn:idm:problem:"
+
+// Problem описание ошибки в формате RFC 7807 (problem details)
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// путь запроса, при обработке которого произошла ошибка
+	Instance string `json:"instance,omitempty"`
+	// машиночитаемый код ошибки
+	Code string `json:"code"`
+	// идентификатор запроса для поиска в логах
+	RequestId string `json:"request_id,omitempty"`
+	// ошибки валидации отдельных полей
+	Errors ValidationErrors `json:"errors,omitempty"`
+}
+
+// NewProblem формирует описание ошибки с заданным статусом, кодом и текстом
+func NewProblem(status int, code string, detail string) Problem {
+	return Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// SendProblem отправляет описание ошибки с типом содержимого application/problem+json
+func SendProblem(c *fiber.Ctx, problem Problem) error {
+	problem.Instance = c.Path()
+	if rid, ok := c.Locals(ridKey).(string); ok {
+		problem.RequestId = rid
+	}
+	c.Status(problem.Status)
+	if err := c.JSON(problem); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, MIMEApplicationProblemJSON)
+	return nil
+}
+
+// NewErrorHandler центральный обработчик ошибок веб-сервера. Переводит ошибки сервисов
+// в ответ application/problem+json с кодом ошибки и HTTP статусом.
+// Внутренние ошибки (SQL, транзакции и т.п.) наружу не отдаются, а только логируются через logger
+func NewErrorHandler(logger *Logger) fiber.ErrorHandler {
+	return func(c *fiber.Ctx, err error) error {
+		problem := ProblemFromError(err, RequestLanguage(c))
+		LogProblem(c, logger, problem, err)
+		return SendProblem(c, problem)
+	}
+}
+
+// LogProblem логирует ошибку, если в ответ на неё отдаётся внутренняя ошибка сервера.
+// Запись содержит requestId, метод и путь запроса
+func LogProblem(c *fiber.Ctx, logger *Logger, problem Problem, err error) {
+	if problem.Status < fiber.StatusInternalServerError {
+		return
+	}
+	// строки fiber ссылаются на буфер запроса, который переиспользуется после ответа
+	logger.ErrorCtx(c.Context(), "request failed", zap.String("method", c.Method()),
+		zap.String("path", strings.Clone(c.Path())), zap.Int("status", problem.Status), zap.Error(err))
+}
+
+// RequestLanguage язык сообщений об ошибках, выбранный по заголовку Accept-Language
+func RequestLanguage(c *fiber.Ctx) string {
+	if lang := c.AcceptsLanguages(DefaultLanguage, "ru"); lang != "" {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// ProblemFromError сопоставляет ошибку с описанием ошибки API.
+// Сообщения об ошибках валидации полей переводятся на язык lang
+func ProblemFromError(err error, lang string) Problem {
+	var validatorErr RequestValidatorError
+	var alreadyExistsErr AlreadyExistsError
+	var notFoundErr NotFoundError
+	var conflictErr ConflictError
+	var forbiddenErr ForbiddenError
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &validatorErr):
+		validatorErr = validatorErr.Localize(lang)
+		problem := NewProblem(fiber.StatusUnprocessableEntity, CodeValidationFailed, validatorErr.Message)
+		problem.Errors = validatorErr.Fields
+		return problem
+	case errors.As(err, &alreadyExistsErr):
+		return NewProblem(fiber.StatusConflict, CodeAlreadyExists, alreadyExistsErr.Message)
+	case errors.As(err, &notFoundErr):
+		return NewProblem(fiber.StatusNotFound, CodeNotFound, notFoundErr.Message)
+	case errors.As(err, &conflictErr):
+		return NewProblem(fiber.StatusConflict, CodeConflict, conflictErr.Message)
+	case errors.As(err, &forbiddenErr):
+		return NewProblem(fiber.StatusForbidden, CodeForbidden, forbiddenErr.Message)
+	case errors.Is(err, sql.ErrNoRows):
+		return NewProblem(fiber.StatusNotFound, CodeNotFound, "resource not found")
+	case errors.As(err, &fiberErr):
+		return NewProblem(fiberErr.Code, codeForStatus(fiberErr.Code), fiberErr.Message)
+	default:
+		// RepositoryError и прочие внутренние ошибки
+		return NewProblem(fiber.StatusInternalServerError, CodeInternal, "internal server error")
+	}
+}
+
+// codeForStatus код ошибки по умолчанию для HTTP статуса
+func codeForStatus(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return CodeBadRequest
+	case fiber.StatusUnauthorized:
+		return CodeUnauthorized
+	case fiber.StatusForbidden:
+		return CodeForbidden
+	case fiber.StatusNotFound:
+		return CodeNotFound
+	case fiber.StatusConflict:
+		return CodeConflict
+	case fiber.StatusUnprocessableEntity:
+		return CodeValidationFailed
+	case fiber.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case fiber.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	if status >= fiber.StatusInternalServerError {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
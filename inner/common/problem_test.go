@@ -0,0 +1,147 @@
+package common
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemFromError(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"validation", RequestValidatorError{Message: "name is required"}, 422, CodeValidationFailed, "name is required"},
+		{"already exists", fmt.Errorf("create: %w", AlreadyExistsError{Message: "employee exists"}), 409, CodeAlreadyExists, "employee exists"},
+		{"not found", NotFoundError{Message: "employee not found"}, 404, CodeNotFound, "employee not found"},
+		{"conflict", ConflictError{Message: "transition is not allowed"}, 409, CodeConflict, "transition is not allowed"},
+		{"forbidden", ForbiddenError{Message: "employee is not linked to token subject"}, 403, CodeForbidden, "employee is not linked to token subject"},
+		{"no rows", fmt.Errorf("error finding employee with id 1: %w", sql.ErrNoRows), 404, CodeNotFound, "resource not found"},
+		{"repository", RepositoryError{Message: "pq: connection refused"}, 500, CodeInternal, "internal server error"},
+		{"unknown", errors.New("sql: transaction has already been committed"), 500, CodeInternal, "internal server error"},
+		{"fiber", fiber.NewError(fiber.StatusBadRequest, "invalid employee id"), 400, CodeBadRequest, "invalid employee id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProblemFromError(tt.err, DefaultLanguage)
+			assert.Equal(tt.status, got.Status)
+			assert.Equal(tt.code, got.Code)
+			assert.Equal(tt.detail, got.Detail)
+			assert.Equal("urn:idm:problem:"+tt.code, got.Type)
+			assert.NotEmpty(got.Title)
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	t.Run("should send problem json", func(t *testing.T) {
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(&Logger{Logger: zap.NewNop()})})
+		app.Get("/employees/:id", func(c *fiber.Ctx) error {
+			c.Locals("requestid", "request-1")
+			return fmt.Errorf("error finding employee with id 1: %w", sql.ErrNoRows)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/employees/1", nil))
+		assert.Nil(err)
+		assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+		assert.Equal(MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(err)
+		assert.NotContains(string(body), "sql: no rows")
+
+		var problem Problem
+		assert.Nil(json.Unmarshal(body, &problem))
+		assert.Equal("/employees/1", problem.Instance)
+		assert.Equal("request-1", problem.RequestId)
+	})
+
+	t.Run("should log internal error with request id", func(t *testing.T) {
+		core, logs := observer.New(zap.ErrorLevel)
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(&Logger{Logger: zap.New(core)})})
+		app.Get("/employees/:id", func(c *fiber.Ctx) error {
+			c.Locals("requestid", "request-1")
+			return errors.New("pq: connection refused")
+		})
+		app.Get("/roles/:id", func(c *fiber.Ctx) error {
+			return NotFoundError{Message: "role not found"}
+		})
+
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/employees/1", nil))
+		assert.Nil(err)
+		assert.Equal(fiber.StatusInternalServerError, resp.StatusCode)
+		resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/roles/1", nil))
+		assert.Nil(err)
+		assert.Equal(fiber.StatusNotFound, resp.StatusCode)
+
+		// ошибки клиента не логируются
+		entries := logs.FilterMessage("request failed").All()
+		assert.NotEmpty(entries)
+		for _, entry := range entries {
+			fields := entry.ContextMap()
+			assert.Equal("/employees/1", fields["path"])
+			assert.Equal("pq: connection refused", fields["error"])
+			assert.Equal("request-1", fields["requestid"])
+		}
+	})
+
+	t.Run("should send field errors localized by Accept-Language", func(t *testing.T) {
+		validateErr := NewRequestValidatorError(ValidationErrors{{
+			Field:    "name",
+			JsonPath: "$.name",
+			Rule:     "required",
+			Message:  "name is a required field",
+			Translations: map[string]string{
+				"en": "name is a required field",
+				"ru": "name обязательное поле",
+			},
+		}})
+		app := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(&Logger{Logger: zap.NewNop()})})
+		app.Post("/employees", func(c *fiber.Ctx) error {
+			return validateErr
+		})
+
+		tests := []struct {
+			acceptLanguage string
+			message        string
+		}{
+			{"", "name is a required field"},
+			{"ru-RU,ru;q=0.9,en;q=0.8", "name обязательное поле"},
+			{"en-US", "name is a required field"},
+			{"de", "name is a required field"},
+		}
+		for _, tt := range tests {
+			req := httptest.NewRequest(fiber.MethodPost, "/employees", nil)
+			req.Header.Set(fiber.HeaderAcceptLanguage, tt.acceptLanguage)
+			resp, err := app.Test(req)
+			assert.Nil(err)
+			assert.Equal(fiber.StatusUnprocessableEntity, resp.StatusCode)
+
+			var problem Problem
+			assert.Nil(json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(CodeValidationFailed, problem.Code)
+			assert.Equal(tt.message, problem.Detail)
+			assert.Len(problem.Errors, 1)
+			assert.Equal("name", problem.Errors[0].Field)
+			assert.Equal("$.name", problem.Errors[0].JsonPath)
+			assert.Equal("required", problem.Errors[0].Rule)
+			assert.Equal(tt.message, problem.Errors[0].Message)
+		}
+	})
+}
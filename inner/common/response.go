@@ -5,18 +5,14 @@ import (
 )
 
 type Response[T any] struct {
-	Success bool   `json:"success"`
-	Message string `json:"error"`
-	Data    T      `json:"data"`
+	Success bool `json:"success"`
+	Data    T    `json:"data"`
 }
 
+// ErrResponse отправляет ошибку в формате application/problem+json
+// с кодом ошибки по умолчанию для переданного HTTP статуса
 func ErrResponse(c *fiber.Ctx, code int, message string) error {
-	res := Response[any]{
-		Success: false,
-		Message: message,
-		Data:    nil,
-	}
-	return c.Status(code).JSON(res)
+	return SendProblem(c, NewProblem(code, codeForStatus(code), message))
 }
 
 func OkResponse[T any](c *fiber.Ctx, data T) error {
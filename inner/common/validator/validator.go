@@ -2,25 +2,100 @@ package validator
 
 import (
 	"errors"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	enTranslations "github.com/go-playground/validator/v10/translations/en"
+	ruTranslations "github.com/go-playground/validator/v10/translations/ru"
+	"github.com/nihrom205/idm/inner/common"
+	"reflect"
+	"strings"
 )
 
 type Validator struct {
 	validate *validator.Validate
+	// переводчики сообщений об ошибках по языкам
+	translators map[string]ut.Translator
 }
 
 func NewValidator() *Validator {
 	validate := validator.New()
-	return &Validator{validate: validate}
+
+	// в ошибках используем имена полей из JSON, а не из Go структуры
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	// регистрируем переводы сообщений один раз при создании валидатора
+	enLocale := en.New()
+	uni := ut.New(enLocale, enLocale, ru.New())
+	enTrans, _ := uni.GetTranslator("en")
+	ruTrans, _ := uni.GetTranslator("ru")
+	if err := enTranslations.RegisterDefaultTranslations(validate, enTrans); err != nil {
+		panic(err)
+	}
+	if err := ruTranslations.RegisterDefaultTranslations(validate, ruTrans); err != nil {
+		panic(err)
+	}
+
+	return &Validator{
+		validate: validate,
+		translators: map[string]ut.Translator{
+			"en": enTrans,
+			"ru": ruTrans,
+		},
+	}
 }
 
+// Validate проверяет структуру запроса. Если запрос не прошёл валидацию,
+// возвращает common.ValidationErrors с ошибками отдельных полей и переводами сообщений
 func (v *Validator) Validate(request any) error {
 	err := v.validate.Struct(request)
 	if err != nil {
 		var validateErr validator.ValidationErrors
 		if errors.As(err, &validateErr) {
-			return validateErr
+			return v.toFieldErrors(validateErr)
 		}
 	}
 	return err
 }
+
+// toFieldErrors переводит ошибки валидатора в ошибки полей со всеми переводами сообщений
+func (v *Validator) toFieldErrors(validateErr validator.ValidationErrors) common.ValidationErrors {
+	fields := make(common.ValidationErrors, 0, len(validateErr))
+	for _, fe := range validateErr {
+		translations := make(map[string]string, len(v.translators))
+		for lang, trans := range v.translators {
+			translations[lang] = fe.Translate(trans)
+		}
+		fields = append(fields, common.FieldError{
+			Field:        fe.Field(),
+			JsonPath:     jsonPath(fe.Namespace()),
+			Rule:         fe.Tag(),
+			Param:        fe.Param(),
+			Message:      translations[common.DefaultLanguage],
+			Translations: translations,
+		})
+	}
+	return fields
+}
+
+// jsonFieldName возвращает имя поля из тега json, а если его нет - имя поля структуры
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// jsonPath переводит пространство имён валидатора ("CreateRequest.ids[0]") в JSON path ("$.ids[0]")
+func jsonPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return "$"
+	}
+	return "$." + path
+}
@@ -0,0 +1,65 @@
+package validator
+
+import (
+	"errors"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testItem struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type testRequest struct {
+	Name  string     `json:"name" validate:"required,min=2,max=155"`
+	Ids   []int64    `json:"ids" validate:"dive,min=1"`
+	Items []testItem `json:"items" validate:"dive"`
+	Note  string     `validate:"max=3"`
+}
+
+func TestValidate(t *testing.T) {
+	a := assert.New(t)
+	v := NewValidator()
+
+	t.Run("should return nil for valid request", func(t *testing.T) {
+		err := v.Validate(testRequest{Name: "John", Ids: []int64{1}, Items: []testItem{{Code: "a"}}})
+		a.Nil(err)
+	})
+
+	t.Run("should return field errors with json names and translations", func(t *testing.T) {
+		err := v.Validate(testRequest{Name: "J", Ids: []int64{1, 0}, Items: []testItem{{}}, Note: "long"})
+
+		var fields common.ValidationErrors
+		a.True(errors.As(err, &fields))
+		a.Len(fields, 4)
+
+		a.Equal("name", fields[0].Field)
+		a.Equal("$.name", fields[0].JsonPath)
+		a.Equal("min", fields[0].Rule)
+		a.Equal("2", fields[0].Param)
+		a.Equal("name must be at least 2 characters in length", fields[0].Message)
+		a.Equal(fields[0].Message, fields[0].Translations["en"])
+		a.Contains(fields[0].Translations["ru"], "name")
+		a.NotEqual(fields[0].Translations["en"], fields[0].Translations["ru"])
+
+		a.Equal("ids[1]", fields[1].Field)
+		a.Equal("$.ids[1]", fields[1].JsonPath)
+
+		a.Equal("code", fields[2].Field)
+		a.Equal("$.items[0].code", fields[2].JsonPath)
+		a.Equal("required", fields[2].Rule)
+
+		// поле без тега json называется по имени в структуре
+		a.Equal("Note", fields[3].Field)
+		a.Equal("$.Note", fields[3].JsonPath)
+	})
+
+	t.Run("should keep field errors in request validator error", func(t *testing.T) {
+		err := common.NewRequestValidatorError(v.Validate(testRequest{}))
+		a.Len(err.Fields, 1)
+		a.Equal("name is a required field", err.Message)
+		a.Contains(err.Localize("ru").Message, "name")
+		a.NotEqual(err.Message, err.Localize("ru").Message)
+	})
+}
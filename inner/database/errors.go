@@ -0,0 +1,21 @@
+package database
+
+import (
+	"errors"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении ограничения уникальности
+const uniqueViolation = "23505"
+
+// MapUniqueViolation переводит нарушение ограничения уникальности в common.AlreadyExistsError с сообщением message.
+// Проверка существования перед вставкой не защищает от одновременных запросов, поэтому репозитории
+// пропускают через MapUniqueViolation ошибки вставки и изменения уникальных полей; прочие ошибки не меняются
+func MapUniqueViolation(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return common.AlreadyExistsError{Message: message}
+	}
+	return err
+}
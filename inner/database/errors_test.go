@@ -0,0 +1,31 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMapUniqueViolation(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should map unique violation to AlreadyExistsError", func(t *testing.T) {
+		err := &pq.Error{Code: "23505", Constraint: "role_name_lower_idx"}
+
+		got := MapUniqueViolation(fmt.Errorf("insert role: %w", err), "role with name admin already exists")
+
+		a.Equal(common.AlreadyExistsError{Message: "role with name admin already exists"}, got)
+	})
+
+	t.Run("should keep other errors", func(t *testing.T) {
+		foreignKeyErr := &pq.Error{Code: "23503"}
+		otherErr := errors.New("connection refused")
+
+		a.Same(foreignKeyErr, MapUniqueViolation(foreignKeyErr, "exists"))
+		a.Equal(otherErr, MapUniqueViolation(otherErr, "exists"))
+		a.Nil(MapUniqueViolation(nil, "exists"))
+	})
+}
@@ -0,0 +1,235 @@
+package delegation
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server            *web.Server
+	delegationService Svc
+	logger            *common.Logger
+}
+
+// интерфейс сервиса delegation.Service
+type Svc interface {
+	GetAll(ctx context.Context, employeeId int64) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Create(ctx context.Context, request CreateRequest, principal common.Principal) (Response, error)
+	Revoke(ctx context.Context, id int64, principal common.Principal) error
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:            server,
+		delegationService: svc,
+		logger:            logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/delegations", c.GetAllDelegations)
+	c.server.GroupApiV1.Post("/delegations", c.CreateDelegation)
+	c.server.GroupApiV1.Get("/delegations/:id", c.GetDelegation)
+	c.server.GroupApiV1.Delete("/delegations/:id", c.RevokeDelegation)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/delegations"
+// @Description Get delegations where the employee is absent or deputy, or all delegations without employee_id.
+// @Summary get delegations
+// @ID get-all-delegations
+// @Tags delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param employee_id query int64 false "id employee"
+// @Success 200 {object} common.Response[[]delegation.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /delegations [get]
+func (c *Controller) GetAllDelegations(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Query("employee_id", "0"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// вызываем метод GetAll сервиса delegation.Service
+	response, err := c.delegationService.GetAll(ctx.Context(), employeeId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all delegations", zap.Int64("employee_id", employeeId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all delegations", zap.Int64("employee_id", employeeId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/delegations/:id"
+// @Description Get delegation by id.
+// @Summary get delegation
+// @ID get-delegation
+// @Tags delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id delegation"
+// @Success 200 {object} common.Response[delegation.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /delegations/{id} [get]
+func (c *Controller) GetDelegation(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid delegation id")
+	}
+
+	// вызываем метод FindById сервиса delegation.Service
+	response, err := c.delegationService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get delegation", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get delegation", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/delegations"
+// @Description Delegate roles and approvals of an absent employee to a deputy for a period.
+// @Description The deputy gets the roles from valid_from to valid_to (at most DELEGATION_MAX_DURATION) while
+// @Description the absent employee has them directly, by rule or through a group; break-glass roles are not delegated.
+// @Description approvals is only recorded: IDM has no approval workflow yet, so approvals are not routed to the deputy.
+// @Description Admins create any delegation, users only delegations of themselves.
+// @Summary create delegation
+// @ID create-delegation
+// @Tags delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body delegation.CreateRequest true "delegation"
+// @Success 200 {object} common.Response[delegation.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /delegations [post]
+func (c *Controller) CreateDelegation(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create delegation: received request", zap.Any("request", request))
+
+	// вызываем метод Create сервиса delegation.Service
+	response, err := c.delegationService.Create(ctx.Context(), request, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create delegation", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create delegation", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/delegations/:id"
+// @Description Revoke delegation before its end. The deputy loses the delegated roles.
+// @Description Admins revoke any delegation, users only delegations of themselves.
+// @Summary revoke delegation
+// @ID revoke-delegation
+// @Tags delegation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id delegation"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /delegations/{id} [delete]
+func (c *Controller) RevokeDelegation(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid delegation id")
+	}
+
+	// вызываем метод Revoke сервиса delegation.Service
+	if err := c.delegationService.Revoke(ctx.Context(), id, claims.Principal()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke delegation", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "revoke delegation", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
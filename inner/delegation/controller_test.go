@@ -0,0 +1,97 @@
+package delegation
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Объявляем структуру мока сервиса delegation.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context, employeeId int64) ([]Response, error) {
+	args := svc.Called(employeeId)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, principal common.Principal) (Response, error) {
+	args := svc.Called(request, principal)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Revoke(ctx context.Context, id int64, principal common.Principal) error {
+	args := svc.Called(id, principal)
+	return args.Error(0)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-3", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_CreateDelegation(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass caller to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+		from := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2026, 11, 15, 0, 0, 0, 0, time.UTC)
+		request := CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to, Approvals: true}
+		caller := common.Principal{Subject: "kc-3", Roles: []string{web.IdmUser}, Actor: "kc-3"}
+		svc.On("Create", request, caller).Return(Response{Id: 1}, nil)
+
+		body := strings.NewReader(`{"delegator_id": 3, "deputy_id": 4, "valid_from": "2026-11-01T00:00:00Z",
+			"valid_to": "2026-11-15T00:00:00Z", "approvals": true}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 without idm role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/delegations", strings.NewReader(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_RevokeDelegation(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmUser)
+	svc.On("Revoke", int64(1), mock.Anything).Return(common.ForbiddenError{Message: "only own delegations can be managed"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/delegations/1", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusForbidden, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
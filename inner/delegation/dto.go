@@ -0,0 +1,102 @@
+package delegation
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Состояния замещения
+const (
+	// период замещения ещё не начался
+	StateScheduled = "scheduled"
+	// заместитель получил роли и согласования
+	StateActive = "active"
+	// период замещения закончился
+	StateEnded = "ended"
+	// замещение отменено до окончания периода
+	StateRevoked = "revoked"
+)
+
+// События замещения, о которых отправляются уведомления
+const (
+	EventStarted = "delegation.started"
+	EventEnded   = "delegation.ended"
+)
+
+// Entity замещение: с valid_from до valid_to сотрудник deputy получает роли role_ids сотрудника delegator
+// approvals только сохраняется: в IDM пока нет согласования заявок, которое можно передать заместителю
+type Entity struct {
+	Id          int64          `db:"id"`
+	DelegatorId int64          `db:"delegator_id"`
+	DeputyId    int64          `db:"deputy_id"`
+	ValidFrom   time.Time      `db:"valid_from"`
+	ValidTo     time.Time      `db:"valid_to"`
+	Approvals   bool           `db:"approvals"`
+	Reason      sql.NullString `db:"reason"`
+	State       string         `db:"state"`
+	Actor       string         `db:"actor"`
+	RoleIds     pq.Int64Array  `db:"role_ids"`
+	CreateAt    time.Time      `db:"create_at"`
+	UpdateAt    time.Time      `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	roleIds := []int64(e.RoleIds)
+	if roleIds == nil {
+		roleIds = []int64{}
+	}
+	return Response{
+		Id:          e.Id,
+		DelegatorId: e.DelegatorId,
+		DeputyId:    e.DeputyId,
+		ValidFrom:   e.ValidFrom,
+		ValidTo:     e.ValidTo,
+		Approvals:   e.Approvals,
+		Reason:      e.Reason.String,
+		State:       e.State,
+		Actor:       e.Actor,
+		RoleIds:     roleIds,
+		CreateAt:    e.CreateAt,
+		UpdateAt:    e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id          int64     `json:"id"`
+	DelegatorId int64     `json:"delegator_id"`
+	DeputyId    int64     `json:"deputy_id"`
+	ValidFrom   time.Time `json:"valid_from"`
+	ValidTo     time.Time `json:"valid_to"`
+	Approvals   bool      `json:"approvals"`
+	Reason      string    `json:"reason,omitempty"`
+	State       string    `json:"state"`
+	Actor       string    `json:"actor"`
+	RoleIds     []int64   `json:"role_ids"`
+	CreateAt    time.Time `json:"create_at"`
+	UpdateAt    time.Time `json:"update_at"`
+}
+
+// Notification уведомление о начале или окончании замещения
+type Notification struct {
+	// delegation.started или delegation.ended
+	Event        string
+	DelegationId int64
+	DelegatorId  int64
+	DeputyId     int64
+	ValidFrom    time.Time
+	ValidTo      time.Time
+	RoleIds      []int64
+}
+
+func (e *Entity) notification(event string) Notification {
+	return Notification{
+		Event:        event,
+		DelegationId: e.Id,
+		DelegatorId:  e.DelegatorId,
+		DeputyId:     e.DeputyId,
+		ValidFrom:    e.ValidFrom,
+		ValidTo:      e.ValidTo,
+		RoleIds:      e.RoleIds,
+	}
+}
@@ -0,0 +1,33 @@
+package delegation
+
+import (
+	"context"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+)
+
+// Notifier отправляет уведомления о начале и окончании замещения. Ошибки доставки обрабатывает
+// сама реализация: замещение начинается и заканчивается независимо от уведомления
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification)
+}
+
+// LogNotifier записывает уведомления в лог приложения, откуда их забирает система оповещений
+type LogNotifier struct {
+	logger *common.Logger
+}
+
+func NewLogNotifier(logger *common.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification Notification) {
+	n.logger.Info("delegation notification",
+		zap.String("event", notification.Event),
+		zap.Int64("delegation_id", notification.DelegationId),
+		zap.Int64("delegator_id", notification.DelegatorId),
+		zap.Int64("deputy_id", notification.DeputyId),
+		zap.Time("valid_from", notification.ValidFrom),
+		zap.Time("valid_to", notification.ValidTo),
+		zap.Int64s("role_ids", notification.RoleIds))
+}
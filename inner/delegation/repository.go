@@ -0,0 +1,131 @@
+package delegation
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+	"time"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// замещения вместе с переданными ролями
+const selectDelegations = `SELECT d.*,
+COALESCE(array_agg(dr.role_id ORDER BY dr.role_id) FILTER (WHERE dr.role_id IS NOT NULL), '{}') AS role_ids
+FROM delegation d LEFT JOIN delegation_role dr ON dr.delegation_id = d.id`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewDelegationRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти замещения, в которых сотрудник отсутствующий или заместитель; 0 - все замещения
+func (r *Repository) FindAll(ctx context.Context, employeeId int64) (delegations []Entity, err error) {
+	query := selectDelegations + ` WHERE $1 = 0 OR d.delegator_id = $1 OR d.deputy_id = $1
+GROUP BY d.id ORDER BY d.valid_from DESC, d.id DESC`
+	err = r.db.SelectContext(ctx, &delegations, query, employeeId)
+	return delegations, err
+}
+
+// найти замещение по id
+func (r *Repository) FindById(ctx context.Context, id int64) (delegation Entity, err error) {
+	query := selectDelegations + " WHERE d.id = $1 GROUP BY d.id"
+	err = r.db.GetContext(ctx, &delegation, query, id)
+	return delegation, err
+}
+
+// найти замещение по id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (delegation Entity, err error) {
+	query := selectDelegations + " WHERE d.id = $1 GROUP BY d.id"
+	err = tx.GetContext(ctx, &delegation, query, id)
+	return delegation, err
+}
+
+// FindEmployeeIdBySubject сотрудник, связанный с пользователем Keycloak
+func (r *Repository) FindEmployeeIdBySubject(ctx context.Context, subject string) (id int64, err error) {
+	err = r.db.GetContext(ctx, &id, "SELECT id FROM employee WHERE subject = $1", subject)
+	return id, err
+}
+
+// добавить замещение в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, delegation Entity) (id int64, err error) {
+	query := `INSERT INTO delegation (delegator_id, deputy_id, valid_from, valid_to, approvals, reason, state, actor)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+	err = tx.GetContext(ctx, &id, query, delegation.DelegatorId, delegation.DeputyId, delegation.ValidFrom,
+		delegation.ValidTo, delegation.Approvals, delegation.Reason, delegation.State, delegation.Actor)
+	return id, mapError(err, "employee not found")
+}
+
+// добавить роли замещения в рамках транзакции
+func (r *Repository) AddRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64) error {
+	query := `INSERT INTO delegation_role (delegation_id, role_id)
+SELECT $1, role_id FROM unnest($2::bigint[]) AS role_id
+ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, id, pq.Int64Array(roleIds))
+	return mapError(err, "role not found")
+}
+
+// изменить состояние замещения в рамках транзакции
+func (r *Repository) UpdateStateTx(ctx context.Context, tx *sqlx.Tx, id int64, state string) error {
+	query := "UPDATE delegation SET state = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, state, id)
+	return err
+}
+
+// RevokeTx отменяет незавершённое замещение и возвращает его прежнее состояние; sql.ErrNoRows, если отменять нечего
+func (r *Repository) RevokeTx(ctx context.Context, tx *sqlx.Tx, id int64) (oldState string, err error) {
+	query := `UPDATE delegation d SET state = 'revoked', update_at = now() FROM delegation old
+WHERE d.id = old.id AND d.id = $1 AND d.state IN ('scheduled', 'active') RETURNING old.state`
+	err = tx.GetContext(ctx, &oldState, query, id)
+	return oldState, err
+}
+
+// FindDueTx находит замещение, которое пора начать или завершить, и блокирует его.
+// Заблокированные другими экземплярами приложения замещения пропускаются
+func (r *Repository) FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (delegation Entity, err error) {
+	var id int64
+	query := `SELECT id FROM delegation
+WHERE (state = 'scheduled' AND valid_from <= $1) OR (state = 'active' AND valid_to <= $1)
+ORDER BY valid_from, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	if err = tx.GetContext(ctx, &id, query, now); err != nil {
+		return delegation, err
+	}
+	err = tx.GetContext(ctx, &delegation, selectDelegations+" WHERE d.id = $1 GROUP BY d.id", id)
+	return delegation, err
+}
+
+// FindOpenByEmployeeTx находит незавершённые замещения, в которых сотрудник отсутствующий или заместитель
+func (r *Repository) FindOpenByEmployeeTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (delegations []Entity, err error) {
+	query := selectDelegations + ` WHERE (d.delegator_id = $1 OR d.deputy_id = $1) AND d.state IN ('scheduled', 'active')
+GROUP BY d.id ORDER BY d.id`
+	err = tx.SelectContext(ctx, &delegations, query, employeeId)
+	return delegations, err
+}
+
+// RevokeByEmployeeTx отменяет незавершённые замещения, в которых сотрудник отсутствующий или заместитель
+func (r *Repository) RevokeByEmployeeTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	query := `UPDATE delegation SET state = 'revoked', update_at = now()
+WHERE (delegator_id = $1 OR deputy_id = $1) AND state IN ('scheduled', 'active')`
+	_, err := tx.ExecContext(ctx, query, employeeId)
+	return err
+}
+
+// mapError переводит нарушение внешнего ключа в NotFoundError
+func mapError(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: message}
+	}
+	return err
+}
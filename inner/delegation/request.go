@@ -0,0 +1,32 @@
+package delegation
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type CreateRequest struct {
+	DelegatorId int64     `json:"delegator_id" validate:"required,gt=0"`
+	DeputyId    int64     `json:"deputy_id" validate:"required,gt=0,nefield=DelegatorId"`
+	ValidFrom   time.Time `json:"valid_from" validate:"required"`
+	ValidTo     time.Time `json:"valid_to" validate:"required,gtfield=ValidFrom"`
+	// передать заместителю согласование заявок; пока только сохраняется, согласования заявок в IDM нет
+	Approvals bool    `json:"approvals"`
+	RoleIds   []int64 `json:"role_ids" validate:"dive,gt=0"`
+	Reason    string  `json:"reason" validate:"max=500"`
+}
+
+func (r *CreateRequest) ToEntity(actor string) Entity {
+	reason := strings.TrimSpace(r.Reason)
+	return Entity{
+		DelegatorId: r.DelegatorId,
+		DeputyId:    r.DeputyId,
+		ValidFrom:   r.ValidFrom,
+		ValidTo:     r.ValidTo,
+		Approvals:   r.Approvals,
+		Reason:      sql.NullString{String: reason, Valid: reason != ""},
+		State:       StateScheduled,
+		Actor:       actor,
+	}
+}
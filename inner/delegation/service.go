@@ -0,0 +1,442 @@
+package delegation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"time"
+)
+
+// сколько замещений начинается и завершается за один запуск планировщика
+const maxDuePerRun = 100
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	FindAll(ctx context.Context, employeeId int64) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindEmployeeIdBySubject(ctx context.Context, subject string) (int64, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, delegation Entity) (int64, error)
+	AddRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64) error
+	UpdateStateTx(ctx context.Context, tx *sqlx.Tx, id int64, state string) error
+	RevokeTx(ctx context.Context, tx *sqlx.Tx, id int64) (string, error)
+	FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (Entity, error)
+	FindOpenByEmployeeTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) ([]Entity, error)
+	RevokeByEmployeeTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// AccessSvc фактический доступ сотрудника: передать можно только роли, которые есть у отсутствующего
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// ChangeListener получает заместителей, которые получили или потеряли роли по замещению.
+// Вызывается в транзакции изменения замещения, например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	access    AccessSvc
+	notifier  Notifier
+	validator Validator
+	listener  ChangeListener
+	// наибольшая длительность замещения
+	maxDuration time.Duration
+}
+
+func NewService(repo Repo, audit AuditRepo, access AccessSvc, notifier Notifier, validator Validator,
+	maxDuration time.Duration) *Service {
+	return &Service{
+		repo:        repo,
+		audit:       audit,
+		access:      access,
+		notifier:    notifier,
+		validator:   validator,
+		maxDuration: maxDuration,
+	}
+}
+
+// SetChangeListener подключает получателя изменений ролей заместителей
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+// GetAll возвращает замещения, в которых сотрудник отсутствующий или заместитель; 0 - все замещения
+func (s *Service) GetAll(ctx context.Context, employeeId int64) ([]Response, error) {
+	delegations, err := s.repo.FindAll(ctx, employeeId)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting delegations: %w", err)
+	}
+	response := make([]Response, 0, len(delegations))
+	for _, item := range delegations {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	delegation, err := s.findById(ctx, id)
+	if err != nil {
+		return Response{}, err
+	}
+	return delegation.toResponse(), nil
+}
+
+// Create передаёт заместителю роли и согласования сотрудника на период замещения.
+// Администратор создаёт любые замещения, остальные - только замещения себя
+func (s *Service) Create(ctx context.Context, request CreateRequest, principal common.Principal) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	if len(request.RoleIds) == 0 && !request.Approvals {
+		return Response{}, common.RequestValidatorError{Message: "delegation must have role_ids or approvals"}
+	}
+	if !request.ValidTo.After(time.Now()) {
+		return Response{}, common.RequestValidatorError{Message: "valid_to must be in the future"}
+	}
+	if request.ValidTo.Sub(request.ValidFrom) > s.maxDuration {
+		return Response{}, common.RequestValidatorError{Message: fmt.Sprintf("delegation must not be longer than %s", s.maxDuration)}
+	}
+	if err = s.authorize(ctx, principal, request.DelegatorId); err != nil {
+		return Response{}, err
+	}
+	if err = s.checkRoles(ctx, request.DelegatorId, request.RoleIds); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating delegation panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating delegation: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	id, err := s.repo.CreateTx(ctx, tx, request.ToEntity(principal.Actor))
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating delegation: %w", err)
+	}
+	if err = s.repo.AddRolesTx(ctx, tx, id, request.RoleIds); err != nil {
+		return Response{}, fmt.Errorf("error saving roles of delegation %d: %w", id, err)
+	}
+	delegation, err := s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding delegation with id %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      principal.Actor,
+		Action:     "delegation.created",
+		EntityType: "delegation",
+		EntityId:   id,
+		Details:    delegation.toResponse(),
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return delegation.toResponse(), nil
+}
+
+// Revoke досрочно завершает замещение. Администратор отменяет любые замещения, остальные - только замещения себя
+func (s *Service) Revoke(ctx context.Context, id int64, principal common.Principal) error {
+	delegation, err := s.findById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = s.authorize(ctx, principal, delegation.DelegatorId); err != nil {
+		return err
+	}
+	oldState, err := s.revoke(ctx, delegation, principal.Actor)
+	if err != nil {
+		return err
+	}
+	if oldState == StateActive {
+		s.notifier.Notify(ctx, delegation.notification(EventEnded))
+	}
+	return nil
+}
+
+func (s *Service) revoke(ctx context.Context, delegation Entity, actor string) (oldState string, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return "", fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("revoking delegation panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("revoking delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("revoking delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("revoking delegation: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	oldState, err = s.repo.RevokeTx(ctx, tx, delegation.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.ConflictError{Message: fmt.Sprintf("delegation with id %d is already finished", delegation.Id)}
+		return "", err
+	}
+	if err != nil {
+		return "", fmt.Errorf("error revoking delegation with id %d: %w", delegation.Id, err)
+	}
+	if oldState == StateActive {
+		if err = s.notifyTx(ctx, tx, []int64{delegation.DeputyId}); err != nil {
+			return "", err
+		}
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "delegation.revoked",
+		EntityType: "delegation",
+		EntityId:   delegation.Id,
+		Details:    map[string]any{"state": oldState},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error writing audit: %w", err)
+	}
+	return oldState, nil
+}
+
+// ExecuteDue начинает и завершает замещения, период которых наступил, каждое в своей транзакции,
+// и после фиксации отправляет уведомления
+func (s *Service) ExecuteDue(ctx context.Context, now time.Time) (executed int, err error) {
+	for executed < maxDuePerRun {
+		notification, found, err := s.executeNext(ctx, now)
+		if err != nil {
+			return executed, err
+		}
+		if !found {
+			break
+		}
+		s.notifier.Notify(ctx, notification)
+		executed++
+	}
+	return executed, nil
+}
+
+// executeNext начинает или завершает одно замещение; false, если таких замещений нет
+func (s *Service) executeNext(ctx context.Context, now time.Time) (notification Notification, found bool, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Notification{}, false, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executing delegation panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing delegation: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("executing delegation: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	delegation, err := s.repo.FindDueTx(ctx, tx, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Notification{}, false, nil
+	}
+	if err != nil {
+		return Notification{}, false, fmt.Errorf("error finding due delegations: %w", err)
+	}
+
+	// замещение, период которого прошёл целиком, сразу завершается
+	state, event := StateEnded, EventEnded
+	if delegation.State == StateScheduled && delegation.ValidTo.After(now) {
+		state, event = StateActive, EventStarted
+	}
+	if err = s.repo.UpdateStateTx(ctx, tx, delegation.Id, state); err != nil {
+		return Notification{}, false, fmt.Errorf("error updating delegation %d: %w", delegation.Id, err)
+	}
+	// роли заместителя меняются, только если замещение начинается или завершается действующее
+	if state == StateActive || delegation.State == StateActive {
+		if err = s.notifyTx(ctx, tx, []int64{delegation.DeputyId}); err != nil {
+			return Notification{}, false, err
+		}
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      delegation.Actor,
+		Action:     event,
+		EntityType: "delegation",
+		EntityId:   delegation.Id,
+		Details:    map[string]any{"delegator_id": delegation.DelegatorId, "deputy_id": delegation.DeputyId},
+	})
+	if err != nil {
+		return Notification{}, false, fmt.Errorf("error writing audit: %w", err)
+	}
+	return delegation.notification(event), true, nil
+}
+
+// Hook отменяет незавершённые замещения уволенного сотрудника, отсутствующего или заместителя.
+// Регистрируется в lifecycle.Service
+func (s *Service) Hook(ctx context.Context, tx *sqlx.Tx, event lifecycle.Event) error {
+	if event.Employee.Status != lifecycle.StatusTerminated {
+		return nil
+	}
+	delegations, err := s.repo.FindOpenByEmployeeTx(ctx, tx, event.Employee.Id)
+	if err != nil {
+		return fmt.Errorf("error finding delegations of employee %d: %w", event.Employee.Id, err)
+	}
+	if len(delegations) == 0 {
+		return nil
+	}
+	if err = s.repo.RevokeByEmployeeTx(ctx, tx, event.Employee.Id); err != nil {
+		return fmt.Errorf("error revoking delegations of employee %d: %w", event.Employee.Id, err)
+	}
+	deputyIds := make([]int64, 0, len(delegations))
+	for _, delegation := range delegations {
+		if delegation.State == StateActive {
+			deputyIds = append(deputyIds, delegation.DeputyId)
+		}
+	}
+	if err = s.notifyTx(ctx, tx, deputyIds); err != nil {
+		return err
+	}
+	for _, delegation := range delegations {
+		err = s.audit.CreateTx(ctx, tx, audit.Entry{
+			Actor:      event.Actor,
+			Action:     "delegation.revoked",
+			EntityType: "delegation",
+			EntityId:   delegation.Id,
+			Details:    map[string]any{"state": delegation.State, "terminated_employee_id": event.Employee.Id},
+		})
+		if err != nil {
+			return fmt.Errorf("error writing audit: %w", err)
+		}
+	}
+	return nil
+}
+
+// notifyTx сообщает об изменении ролей заместителей в транзакции изменения замещения
+func (s *Service) notifyTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	if s.listener == nil || len(employeeIds) == 0 {
+		return nil
+	}
+	if err := s.listener.RolesChangedTx(ctx, tx, employeeIds); err != nil {
+		return fmt.Errorf("error notifying about roles of deputies %v changed: %w", employeeIds, err)
+	}
+	return nil
+}
+
+func (s *Service) findById(ctx context.Context, id int64) (Entity, error) {
+	delegation, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entity{}, common.NotFoundError{Message: fmt.Sprintf("delegation with id %d not found", id)}
+	}
+	if err != nil {
+		return Entity{}, fmt.Errorf("error finding delegation with id %d: %w", id, err)
+	}
+	return delegation, nil
+}
+
+// authorize проверяет, что вызывающий - администратор или сам отсутствующий сотрудник
+func (s *Service) authorize(ctx context.Context, principal common.Principal, delegatorId int64) error {
+	if principal.Admin {
+		return nil
+	}
+	denied := common.ForbiddenError{Message: "only own delegations can be managed"}
+	if principal.Subject == "" {
+		return denied
+	}
+	employeeId, err := s.repo.FindEmployeeIdBySubject(ctx, principal.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return denied
+	}
+	if err != nil {
+		return fmt.Errorf("error finding employee with subject %s: %w", principal.Subject, err)
+	}
+	if employeeId != delegatorId {
+		return denied
+	}
+	return nil
+}
+
+// checkRoles проверяет, что отсутствующий сотрудник сам имеет передаваемые роли.
+// Роли, полученные по замещению, дальше не передаются, а экстренный доступ не передаётся вовсе:
+// у него свой срок и обязательный разбор
+func (s *Service) checkRoles(ctx context.Context, delegatorId int64, roleIds []int64) error {
+	if len(roleIds) == 0 {
+		return nil
+	}
+	resolved, err := s.access.Resolve(ctx, delegatorId)
+	if err != nil {
+		return err
+	}
+	own := map[int64]bool{}
+	for _, role := range resolved.Roles {
+		for _, grant := range role.Grants {
+			if grant.Source != access.SourceDelegation && grant.Source != access.SourceBreakGlass {
+				own[role.RoleId] = true
+			}
+		}
+	}
+	for _, roleId := range roleIds {
+		if !own[roleId] {
+			return common.ConflictError{Message: fmt.Sprintf("employee %d does not have role %d to delegate", delegatorId, roleId)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,412 @@
+package delegation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	insertQuery       = regexp.QuoteMeta("INSERT INTO delegation (delegator_id, deputy_id, valid_from, valid_to, approvals, reason, state, actor)")
+	insertRolesQuery  = regexp.QuoteMeta("INSERT INTO delegation_role (delegation_id, role_id)")
+	findQuery         = regexp.QuoteMeta(selectDelegations + " WHERE d.id = $1 GROUP BY d.id")
+	subjectQuery      = regexp.QuoteMeta("SELECT id FROM employee WHERE subject = $1")
+	revokeQuery       = regexp.QuoteMeta("UPDATE delegation d SET state = 'revoked', update_at = now() FROM delegation old")
+	dueQuery          = regexp.QuoteMeta("SELECT id FROM delegation")
+	stateQuery        = regexp.QuoteMeta("UPDATE delegation SET state = $1, update_at = now() WHERE id = $2")
+	openQuery         = regexp.QuoteMeta(selectDelegations + " WHERE (d.delegator_id = $1 OR d.deputy_id = $1) AND d.state IN ('scheduled', 'active')")
+	revokeAllQuery    = regexp.QuoteMeta("UPDATE delegation SET state = 'revoked', update_at = now()")
+	auditQuery        = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	delegationColumns = []string{"id", "delegator_id", "deputy_id", "valid_from", "valid_to", "approvals", "reason",
+		"state", "actor", "create_at", "update_at", "role_ids"}
+	admin = common.Principal{Actor: "admin", Admin: true}
+)
+
+type MockAccessSvc struct {
+	mock.Mock
+}
+
+func (m *MockAccessSvc) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).(access.Response), args.Error(1)
+}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, notification Notification) {
+	m.Called(notification)
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *MockAccessSvc, *MockNotifier) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	accessSvc := &MockAccessSvc{}
+	notifier := &MockNotifier{}
+	srv := NewService(NewDelegationRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), accessSvc, notifier, validator.NewValidator(),
+		30*24*time.Hour)
+	return srv, mock, accessSvc, notifier
+}
+
+// доступ сотрудника 3: роль 2 назначена вручную, роль 5 получена по чужому замещению,
+// роль 6 - через экстренный доступ
+func delegatorAccess() access.Response {
+	return access.Response{EmployeeId: 3, Roles: []access.Role{
+		{RoleId: 2, Grants: []access.Grant{{Source: access.SourceDirect}}},
+		{RoleId: 5, Grants: []access.Grant{{Source: access.SourceDelegation}}},
+		{RoleId: 6, Grants: []access.Grant{{Source: access.SourceBreakGlass}}},
+	}}
+}
+
+func TestService_Create(t *testing.T) {
+	var a = assert.New(t)
+	from := time.Now().Add(time.Hour)
+	to := from.Add(7 * 24 * time.Hour)
+
+	t.Run("should create scheduled delegation of own roles", func(t *testing.T) {
+		srv, mock, accessSvc, _ := newTestService(t)
+		now := time.Now()
+		accessSvc.On("Resolve", int64(3)).Return(delegatorAccess(), nil)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-3").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectBegin()
+		mock.ExpectQuery(insertQuery).WithArgs(int64(3), int64(4), from, to, true, "vacation", StateScheduled, "kc-3").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+		mock.ExpectExec(insertRolesQuery).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, true, "vacation", StateScheduled, "kc-3", now, now, "{2}"))
+		mock.ExpectExec(auditQuery).WithArgs("kc-3", "delegation.created", "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to,
+			Approvals: true, RoleIds: []int64{2}, Reason: "vacation"}, common.Principal{Subject: "kc-3", Actor: "kc-3"})
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to, Approvals: true, Reason: "vacation",
+			State: StateScheduled, Actor: "kc-3", RoleIds: []int64{2}, CreateAt: now, UpdateAt: now}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ForbiddenError for delegation of another employee", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-4").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to,
+			Approvals: true}, common.Principal{Subject: "kc-4", Actor: "kc-4"})
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for role received by delegation", func(t *testing.T) {
+		srv, mock, accessSvc, _ := newTestService(t)
+		accessSvc.On("Resolve", int64(3)).Return(delegatorAccess(), nil)
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to,
+			RoleIds: []int64{5}}, admin)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("employee 3 does not have role 5 to delegate", conflictErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for break-glass role", func(t *testing.T) {
+		srv, mock, accessSvc, _ := newTestService(t)
+		accessSvc.On("Resolve", int64(3)).Return(delegatorAccess(), nil)
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to,
+			RoleIds: []int64{6}}, admin)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("employee 3 does not have role 6 to delegate", conflictErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject delegation longer than maximum duration", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from,
+			ValidTo: from.Add(31 * 24 * time.Hour), Approvals: true}, admin)
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.Equal("delegation must not be longer than 720h0m0s", validationErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject period ending before start", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: to, ValidTo: from,
+			Approvals: true}, admin)
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject delegation of nothing", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+
+		_, err := srv.Create(context.Background(), CreateRequest{DelegatorId: 3, DeputyId: 4, ValidFrom: from, ValidTo: to}, admin)
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.Equal("delegation must have role_ids or approvals", validationErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Revoke(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+
+	t.Run("should revoke active delegation and notify about its end", func(t *testing.T) {
+		srv, mock, _, notifier := newTestService(t)
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, false, nil, StateActive, "admin", now, now, "{2}"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(revokeQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(StateActive))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "delegation.revoked", "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		notifier.On("Notify", Notification{Event: EventEnded, DelegationId: 1, DelegatorId: 3, DeputyId: 4,
+			ValidFrom: from, ValidTo: to, RoleIds: []int64{2}}).Return()
+
+		err := srv.Revoke(context.Background(), 1, admin)
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("should notify listener about deputy who loses roles", func(t *testing.T) {
+		srv, dbMock, _, notifier := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(nil)
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, false, nil, StateActive, "admin", now, now, "{2}"))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(revokeQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow(StateActive))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "delegation.revoked", "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+		notifier.On("Notify", mock.Anything).Return()
+
+		err := srv.Revoke(context.Background(), 1, admin)
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should return ConflictError for finished delegation", func(t *testing.T) {
+		srv, dbMock, _, notifier := newTestService(t)
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, false, nil, StateEnded, "admin", now, now, "{2}"))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(revokeQuery).WithArgs(int64(1)).WillReturnError(sql.ErrNoRows)
+		dbMock.ExpectRollback()
+
+		err := srv.Revoke(context.Background(), 1, admin)
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.NoError(dbMock.ExpectationsWereMet())
+		notifier.AssertNotCalled(t, "Notify", mock.Anything)
+	})
+}
+
+func TestService_ExecuteDue(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+	from := now.Add(-time.Minute)
+	to := now.Add(time.Hour)
+
+	t.Run("should start delegation and notify after commit", func(t *testing.T) {
+		srv, mock, _, notifier := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, true, nil, StateScheduled, "admin", now, now, "{2}"))
+		mock.ExpectExec(stateQuery).WithArgs(StateActive, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WithArgs("admin", EventStarted, "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(dueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+		notifier.On("Notify", Notification{Event: EventStarted, DelegationId: 1, DelegatorId: 3, DeputyId: 4,
+			ValidFrom: from, ValidTo: to, RoleIds: []int64{2}}).Return()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(mock.ExpectationsWereMet())
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("should notify listener about deputy of started delegation", func(t *testing.T) {
+		srv, dbMock, _, notifier := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, to, true, nil, StateScheduled, "admin", now, now, "{2}"))
+		dbMock.ExpectExec(stateQuery).WithArgs(StateActive, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", EventStarted, "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		dbMock.ExpectCommit()
+		notifier.On("Notify", mock.Anything).Return()
+
+		_, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should not notify listener about delegation that never started", func(t *testing.T) {
+		srv, dbMock, _, notifier := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, from, true, nil, StateScheduled, "admin", now, now, "{2}"))
+		dbMock.ExpectExec(stateQuery).WithArgs(StateEnded, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", EventEnded, "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		dbMock.ExpectCommit()
+		notifier.On("Notify", mock.Anything).Return()
+
+		_, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertNotCalled(t, "RolesChangedTx", mock.Anything)
+	})
+
+	t.Run("should end delegation whose period has passed", func(t *testing.T) {
+		srv, dbMock, _, notifier := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, from, from, true, nil, StateActive, "admin", now, now, "{}"))
+		dbMock.ExpectExec(stateQuery).WithArgs(StateEnded, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", EventEnded, "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		dbMock.ExpectCommit()
+		notifier.On("Notify", mock.MatchedBy(func(n Notification) bool { return n.Event == EventEnded })).Return()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(dbMock.ExpectationsWereMet())
+		notifier.AssertExpectations(t)
+	})
+}
+
+func TestService_Hook(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should revoke delegations of terminated employee", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(openQuery).WithArgs(int64(4)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, now, now.Add(time.Hour), false, nil, StateActive, "admin", now, now, "{2}"))
+		mock.ExpectExec(revokeAllQuery).WithArgs(int64(4)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WithArgs("hr", "delegation.revoked", "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+
+		err = srv.Hook(context.Background(), tx, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 4, Status: lifecycle.StatusTerminated},
+			FromStatus: lifecycle.StatusActive,
+			Actor:      "hr",
+		})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should notify listener about deputies of terminated delegator", func(t *testing.T) {
+		srv, dbMock, _, _ := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(openQuery).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows(delegationColumns).
+			AddRow(1, 3, 4, now, now.Add(time.Hour), false, nil, StateActive, "admin", now, now, "{2}").
+			AddRow(2, 3, 5, now.Add(time.Hour), now.Add(2*time.Hour), false, nil, StateScheduled, "admin", now, now, "{2}"))
+		dbMock.ExpectExec(revokeAllQuery).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 2))
+		dbMock.ExpectExec(auditQuery).WithArgs("hr", "delegation.revoked", "delegation", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("hr", "delegation.revoked", "delegation", int64(2), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+
+		err = srv.Hook(context.Background(), tx, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 3, Status: lifecycle.StatusTerminated},
+			FromStatus: lifecycle.StatusActive,
+			Actor:      "hr",
+		})
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should ignore other statuses", func(t *testing.T) {
+		srv, mock, _, _ := newTestService(t)
+
+		err := srv.Hook(context.Background(), nil, lifecycle.Event{Employee: lifecycle.Employee{Id: 4, Status: lifecycle.StatusActive}})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
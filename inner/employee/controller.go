@@ -1,11 +1,11 @@
 package employee
 
 import (
+	"bufio"
 	"context"
-	"errors"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/web"
 	"go.uber.org/zap"
 	"slices"
@@ -20,13 +20,18 @@ type Controller struct {
 
 // интерфейс сервиса employee.Service
 type Svc interface {
-	Create(ctx context.Context, request CreateRequest) (int64, error)
-	FindById(ctx context.Context, id int64) (Response, error)
-	GetAll(ctx context.Context) ([]Response, error)
-	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
-	DeleteById(ctx context.Context, id int64) error
-	DeleteByIds(ctx context.Context, ids []int64) error
-	FindPage(ctx context.Context, req PageRequest) (PageResponse, error)
+	Create(ctx context.Context, request CreateRequest, principal common.Principal) (int64, error)
+	CreateBatch(ctx context.Context, request BatchCreateRequest, principal common.Principal) (BatchResponse, error)
+	FindVisibleById(ctx context.Context, id int64, principal common.Principal) (Response, error)
+	GetAllVisible(ctx context.Context, principal common.Principal) ([]Response, error)
+	FindVisibleByIds(ctx context.Context, ids []int64, principal common.Principal) ([]Response, error)
+	DeleteById(ctx context.Context, id int64, actor string) error
+	DeleteByIds(ctx context.Context, ids []int64, actor string) error
+	FindPage(ctx context.Context, req PageRequest, principal common.Principal) (PageResponse, error)
+	Export(ctx context.Context, textFilter string, principal common.Principal) (csvutil.Export, error)
+	Import(ctx context.Context, request ImportRequest) (csvutil.ImportReport, error)
+	LinkSubject(ctx context.Context, id int64, request LinkSubjectRequest) (Response, error)
+	SetManager(ctx context.Context, id int64, request ManagerRequest) (Response, error)
 }
 
 func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
@@ -39,16 +44,21 @@ func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controll
 
 func (c *Controller) RegisterRoutes() {
 	c.server.GroupApiV1.Post("/employees", c.CreateEmployee)
+	c.server.GroupApiV1.Post("/employees/batch", c.CreateEmployeeBatch)
+	c.server.GroupApiV1.Post("/employees/import", c.ImportEmployees)
 	c.server.GroupApiV1.Get("/employees/page", c.GetPageEmployee)
+	c.server.GroupApiV1.Get("/employees/export", c.ExportEmployees)
 	c.server.GroupApiV1.Get("/employees/:id", c.GetEmployee)
 	c.server.GroupApiV1.Get("/employees", c.GetAllEmployees)
 	c.server.GroupApiV1.Post("/employees/ids", c.GetEmployeeByIds)
+	c.server.GroupApiV1.Put("/employees/:id/subject", c.LinkEmployeeSubject)
+	c.server.GroupApiV1.Put("/employees/:id/manager", c.SetEmployeeManager)
 	c.server.GroupApiV1.Delete("/employees/ids", c.DeleteEmployeesByIds)
 	c.server.GroupApiV1.Delete("/employees/:id", c.DeleteEmployee)
 }
 
 // функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees"
-// @Description Create a new employee.
+// @Description Create a new employee. Scoped admin may create employees only in org units granted to the caller.
 // @Summary create a new employee
 // @ID create-employee
 // @Tags employee
@@ -57,19 +67,22 @@ func (c *Controller) RegisterRoutes() {
 // @Security BearerAuth
 // @Param request body employee.CreateRequest true "name employee"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees [post]
 func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
-	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmScopedAdmin) {
 		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
 	}
 
@@ -82,32 +95,81 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 	// логируем тело запроса
 	c.logger.DebugCtx(ctx.Context(), "create employee: received request", zap.Any("request", request))
 	// вызываем метод Create сервиса employee.Service
-	newEmployeeId, err := c.employeeService.Create(ctx.Context(), request)
+	newEmployeeId, err := c.employeeService.Create(ctx.Context(), request, claims.Principal())
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "create employee", zap.Error(err))
-		switch {
-		// если сервис возвращает ошибку RequestValidationError или AlreadyExistsError,
-		// то мы возвращаем ответ с кодом 400 (BadRequest)
-		case errors.As(err, &common.RequestValidatorError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-
-		// если сервис возвращает другую ошибку, то мы возвращаем ответ с кодом 500 (InternalServerError)
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 
 	// функция OkResponse() формирует и направляет ответ в случае успеха
 	if err = common.OkResponse(ctx, newEmployeeId); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "create employee", zap.Error(err))
-		_ = common.ErrResponse(ctx, fiber.StatusInternalServerError, "error returning created employee id")
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/batch"
+// @Description Create employees in batch. Returns status of each item in request order.
+// @Description Mode all_or_nothing creates employees only if all items are valid, mode best_effort skips failed items.
+// @Description Batch with employee outside org units of scoped admin is rejected with 403.
+// @Summary create employees in batch
+// @ID create-employee-batch
+// @Tags employee
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body employee.BatchCreateRequest true "employees and batch mode"
+// @Success 200 {object} common.Response[employee.BatchResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/batch [post]
+func (c *Controller) CreateEmployeeBatch(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmScopedAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request BatchCreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create employee batch: received request",
+		zap.String("mode", request.Mode), zap.Int("items", len(request.Items)))
+
+	// вызываем метод CreateBatch сервиса employee.Service
+	response, err := c.employeeService.CreateBatch(ctx.Context(), request, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create employee batch", zap.Error(err))
+		return err
+	}
+	for _, item := range response.Items {
+		if item.err != nil {
+			c.logger.ErrorCtx(ctx.Context(), "create employee batch: item failed", zap.Int("index", item.Index),
+				zap.Error(item.err))
+		}
+	}
+
+	// ошибки валидации элементов переводим на язык клиента
+	if err := common.OkResponse(ctx, response.Localize(common.RequestLanguage(ctx))); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create employee batch", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id"
-// @Description Get employee.
+// @Description Get employee. Employee not visible to caller by visibility policies is not found.
 // @Summary get employee
 // @ID get-employee
 // @Tags employee
@@ -116,16 +178,16 @@ func (c *Controller) CreateEmployee(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path int64 true "id employee"
 // @Success 200 {object} common.Response[employee.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 404 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees/{id} [get]
 func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -143,32 +205,23 @@ func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
 	}
 
-	// вызываем метод FindById сервиса employee.Service
-	response, err := c.employeeService.FindById(ctx.Context(), id)
+	// вызываем метод FindVisibleById сервиса employee.Service
+	response, err := c.employeeService.FindVisibleById(ctx.Context(), id, claims.Principal())
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get employee", zap.String("id", idParam), zap.Error(err))
-		switch {
-		case errors.As(err, &common.RequestValidatorError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		case errors.As(err, &common.RepositoryError{}):
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		case errors.As(err, &common.NotFoundError{}):
-			return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get employee", zap.String("id", idParam), zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees"
-// @Description Get all employee.
+// @Description Get all employees visible to caller.
 // @Summary get all employee
 // @ID get-all-employee
 // @Tags employee
@@ -176,15 +229,15 @@ func (c *Controller) GetEmployee(ctx *fiber.Ctx) error {
 // @Produce json
 // @Security BearerAuth
 // @Success 200 {object} common.Response[employee.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees [get]
 func (c *Controller) GetAllEmployees(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -193,23 +246,23 @@ func (c *Controller) GetAllEmployees(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
 	}
 
-	// вызываем метод GetAll сервиса employee.Service
-	response, err := c.employeeService.GetAll(ctx.Context())
+	// вызываем метод GetAllVisible сервиса employee.Service
+	response, err := c.employeeService.GetAllVisible(ctx.Context(), claims.Principal())
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get all employees", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get all employees", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/ids"
-// @Description Get employee by id.
+// @Description Get employees by ids. Employees not visible to the caller are skipped.
 // @Summary get employee by id
 // @ID get-employee-by-id
 // @Tags employee
@@ -218,20 +271,20 @@ func (c *Controller) GetAllEmployees(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param ids body employee.FindByIdsRequest true "ids employee"
 // @Success 200 {object} common.Response[employee.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees/ids [post]
 func (c *Controller) GetEmployeeByIds(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
 	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
-		slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
 		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
 	}
 
@@ -243,23 +296,23 @@ func (c *Controller) GetEmployeeByIds(ctx *fiber.Ctx) error {
 	}
 	c.logger.DebugCtx(ctx.Context(), "get employee by ids", zap.Any("request", request))
 
-	// вызываем метод FindByIds сервиса employee.Service
-	response, err := c.employeeService.FindByIds(ctx.Context(), request.Ids)
+	// вызываем метод FindVisibleByIds сервиса employee.Service
+	response, err := c.employeeService.FindVisibleByIds(ctx.Context(), request.Ids, claims.Principal())
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get employee by ids", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get employee by ids", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/:id"
-// @Description Delete employee by id.
+// @Description Terminate employee by id: the record is kept, roles are revoked and accounts are disabled.
 // @Summary delete employee by id
 // @ID delete-employee-by-id
 // @Tags employee
@@ -268,15 +321,15 @@ func (c *Controller) GetEmployeeByIds(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path int64 true "id employee"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees/{id} [delete]
 func (c *Controller) DeleteEmployee(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -294,29 +347,128 @@ func (c *Controller) DeleteEmployee(ctx *fiber.Ctx) error {
 	}
 
 	// вызываем метод DeleteById сервиса employee.Service
-	err = c.employeeService.DeleteById(ctx.Context(), id)
+	err = c.employeeService.DeleteById(ctx.Context(), id, claims.Principal().Actor)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete employee", zap.String("id", idParam), zap.Error(err))
-		switch {
-		case errors.As(err, &common.RequestValidatorError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		case errors.As(err, &common.RepositoryError{}):
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		case errors.As(err, &common.NotFoundError{}):
-			return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 	if err := common.OkResponse(ctx, struct{}{}); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete employee", zap.String("id", idParam), zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/employees/:id/subject"
+// @Description Link employee to Keycloak user by token subject (claim sub). Empty subject removes the link.
+// @Summary link employee to Keycloak user
+// @ID link-employee-subject
+// @Tags employee
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param request body employee.LinkSubjectRequest true "token subject"
+// @Success 200 {object} common.Response[employee.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/subject [put]
+func (c *Controller) LinkEmployeeSubject(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	var request LinkSubjectRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// вызываем метод LinkSubject сервиса employee.Service
+	response, err := c.employeeService.LinkSubject(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "link employee subject", zap.String("id", idParam), zap.Error(err))
+		return err
+	}
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "link employee subject", zap.String("id", idParam), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/employees/:id/manager"
+// @Description Set manager of employee. Manager can not be in reporting tree of employee. Zero manager_id removes manager.
+// @Summary set employee manager
+// @ID set-employee-manager
+// @Tags employee
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param request body employee.ManagerRequest true "id of manager"
+// @Success 200 {object} common.Response[employee.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/manager [put]
+func (c *Controller) SetEmployeeManager(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	var request ManagerRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	// вызываем метод SetManager сервиса employee.Service
+	response, err := c.employeeService.SetManager(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "set employee manager", zap.String("id", idParam), zap.Error(err))
+		return err
+	}
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "set employee manager", zap.String("id", idParam), zap.Error(err))
+		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/ids"
-// @Description Delete employee by list ids.
+// @Description Terminate employees by list ids: the records are kept, roles are revoked and accounts are disabled.
 // @Summary delete employee by list ids
 // @ID delete-employee-by-list-ids
 // @Tags employee
@@ -325,15 +477,15 @@ func (c *Controller) DeleteEmployee(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param ids body employee.DeleteByIdsRequest true "ids employee"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees/ids [delete]
 func (c *Controller) DeleteEmployeesByIds(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -350,23 +502,23 @@ func (c *Controller) DeleteEmployeesByIds(ctx *fiber.Ctx) error {
 	c.logger.DebugCtx(ctx.Context(), "delete employees by ids", zap.Any("request", request))
 
 	// вызываем метод DeleteByIds сервиса employee.Service
-	err = c.employeeService.DeleteByIds(ctx.Context(), request.Ids)
+	err = c.employeeService.DeleteByIds(ctx.Context(), request.Ids, claims.Principal().Actor)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete employees by ids", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, struct{}{}); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete employees by ids", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
 
 // GetEmployeesPage получает страницу сотрудников
 // функция-хендлер, которая будет вызываться при GET запросе по маршруту /api/v1/employees/page?pageNumber=x&pageSize=y
-// @Description Get employee by pagination.
+// @Description Get page of employees visible to caller. Total counts only visible employees.
 // @Summary get employee by pagination
 // @ID get-employee-by-pagination
 // @Tags employee
@@ -377,15 +529,16 @@ func (c *Controller) DeleteEmployeesByIds(ctx *fiber.Ctx) error {
 // @Param pageSize query integer false "Size page (default 1)"
 // @Param textFilter query string false "Size page (default 1)"
 // @Success 200 {object} common.Response[employee.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /employees/page [get]
 func (c *Controller) GetPageEmployee(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -396,7 +549,7 @@ func (c *Controller) GetPageEmployee(ctx *fiber.Ctx) error {
 
 	pageNumber, err := strconv.Atoi(ctx.Query("pageNumber", "0"))
 	if err != nil {
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid pageNumber")
 	}
 
 	pageSize, err := strconv.Atoi(ctx.Query("pageSize", "1"))
@@ -414,27 +567,139 @@ func (c *Controller) GetPageEmployee(ctx *fiber.Ctx) error {
 	}
 	c.logger.DebugCtx(ctx.Context(), "get page employee by pageNumber and pageSize", zap.Any("request", request))
 
-	page, err := c.employeeService.FindPage(ctx.Context(), request)
+	page, err := c.employeeService.FindPage(ctx.Context(), request, claims.Principal())
 	if err != nil {
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid pageSize")
+		c.logger.ErrorCtx(ctx.Context(), "get page employee by pageNumber and pageSize", zap.Error(err))
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, page); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get page employee by pageNumber and pageSize", zap.Error(err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/export"
+// @Description Export employees to CSV file. Rows are streamed without loading all employees into memory.
+// @Description Only employees visible to caller are exported.
+// @Summary export employees
+// @ID export-employees
+// @Tags employee
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "export format, only csv is supported" default(csv)
+// @Param textFilter query string false "filter by name"
+// @Success 200 {string} string "CSV file with columns id, name, org_unit, job_title, subject, status, create_at, update_at"
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/export [get]
+func (c *Controller) ExportEmployees(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
 	}
+
+	if format := ctx.Query("format", "csv"); format != "csv" {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "unsupported export format "+format)
+	}
+	textFilter := ctx.Query("textFilter")
+
+	// строки пишутся в ответ уже после выхода из хендлера, поэтому выгрузка работает в контексте,
+	// не связанном с запросом. Запрос к базе данных выполняется до начала ответа, и его ошибка
+	// возвращается обычным образом
+	exportCtx := common.DetachedContext(ctx)
+	export, err := c.employeeService.Export(exportCtx, textFilter, claims.Principal())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "export employees", zap.Error(err))
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="employees.csv"`)
+
+	// после начала выгрузки статус ответа уже не изменить, поэтому ошибки только логируются
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer export.Close()
+		if err := export.Write(w); err != nil {
+			c.logger.ErrorCtx(exportCtx, "export employees", zap.Error(err))
+		}
+	})
 	return nil
 }
 
-func getClaims(ctx *fiber.Ctx) (*web.IdmClaims, error) {
-	token, ok := ctx.Locals(web.JwtKey).(*jwt.Token)
-	if !ok || token == nil {
-		return nil, errors.New("missing or invalid token")
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/import"
+// @Description Import employees from CSV file. Rows without id create employees, rows with id update name, org unit,
+// @Description job title and subject of existing ones; absent optional columns are not changed. Status is changed only
+// @Description by lifecycle transitions, so rows with another status are rejected. Exported file can be imported back.
+// @Description Invalid rows are rejected and reported, other rows are imported.
+// @Summary import employees
+// @ID import-employees
+// @Tags employee
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file with header"
+// @Param mapping formData string false "JSON object mapping field names to CSV headers"
+// @Param dry_run query bool false "validate file and report changes without saving them"
+// @Success 200 {object} common.Response[csvutil.ImportReport]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/import [post]
+func (c *Controller) ImportEmployees(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "csv file is required")
 	}
-	claims, ok := token.Claims.(*web.IdmClaims)
-	if !ok || claims == nil {
-		return nil, errors.New("missing or invalid claims")
+	mapping, err := csvutil.ParseMapping(ctx.FormValue("mapping"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
 	}
-	return claims, nil
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import employees: open file", zap.Error(err))
+		return err
+	}
+	defer file.Close()
+
+	request := ImportRequest{File: file, Mapping: mapping, DryRun: ctx.QueryBool("dry_run"), Actor: claims.Actor()}
+	c.logger.DebugCtx(ctx.Context(), "import employees: received file",
+		zap.String("file", fileHeader.Filename), zap.Int64("size", fileHeader.Size), zap.Bool("dry_run", request.DryRun))
+
+	// вызываем метод Import сервиса employee.Service
+	report, err := c.employeeService.Import(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import employees", zap.Error(err))
+		return err
+	}
+
+	// ошибки валидации строк переводим на язык клиента
+	if err := common.OkResponse(ctx, report.Localize(common.RequestLanguage(ctx))); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import employees", zap.Error(err))
+		return err
+	}
+	return nil
 }
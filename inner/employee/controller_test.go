@@ -1,17 +1,21 @@
 package employee
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/web"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -25,38 +29,76 @@ type MockService struct {
 }
 
 // Реализуем функции мок-сервиса
-func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+func (svc *MockService) FindVisibleById(ctx context.Context, id int64, principal common.Principal) (Response, error) {
 	args := svc.Called(id)
 	return args.Get(0).(Response), args.Error(1)
 }
 
-func (svc *MockService) Create(ctx context.Context, request CreateRequest) (int64, error) {
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, principal common.Principal) (int64, error) {
 	args := svc.Called(request)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
+func (svc *MockService) CreateBatch(ctx context.Context, request BatchCreateRequest, principal common.Principal) (BatchResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(BatchResponse), args.Error(1)
+}
+
+func (svc *MockService) Export(ctx context.Context, textFilter string, principal common.Principal) (csvutil.Export, error) {
+	args := svc.Called(textFilter)
+	export, _ := args.Get(0).(csvutil.Export)
+	return export, args.Error(1)
+}
+
+// exportStub выгрузка готового CSV файла
+type exportStub string
+
+func (e exportStub) Write(w io.Writer) error {
+	_, err := io.WriteString(w, string(e))
+	return err
+}
+
+func (e exportStub) Close() error {
+	return nil
+}
+
+func (svc *MockService) Import(ctx context.Context, request ImportRequest) (csvutil.ImportReport, error) {
+	args := svc.Called(request.Mapping, request.DryRun)
+	return args.Get(0).(csvutil.ImportReport), args.Error(1)
+}
+
+func (svc *MockService) SetManager(ctx context.Context, id int64, request ManagerRequest) (Response, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) LinkSubject(ctx context.Context, id int64, request LinkSubjectRequest) (Response, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) GetAllVisible(ctx context.Context, principal common.Principal) ([]Response, error) {
 	args := svc.Called()
 	return args.Get(0).([]Response), args.Error(1)
 }
 
-func (svc *MockService) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
+func (svc *MockService) FindVisibleByIds(ctx context.Context, ids []int64, principal common.Principal) ([]Response, error) {
 	args := svc.Called(ids)
 	return args.Get(0).([]Response), args.Error(1)
 }
 
-func (svc *MockService) DeleteById(ctx context.Context, id int64) error {
-	args := svc.Called(id)
+func (svc *MockService) DeleteById(ctx context.Context, id int64, actor string) error {
+	args := svc.Called(id, actor)
 	return args.Error(0)
 }
 
-func (svc *MockService) DeleteByIds(ctx context.Context, ids []int64) error {
-	args := svc.Called(ids)
+func (svc *MockService) DeleteByIds(ctx context.Context, ids []int64, actor string) error {
+	args := svc.Called(ids, actor)
 	return args.Error(0)
 }
 
-func (svc *MockService) FindPage(ctx context.Context, req PageRequest) (PageResponse, error) {
-	args := svc.Called(req)
+func (svc *MockService) FindPage(ctx context.Context, req PageRequest, principal common.Principal) (PageResponse, error) {
+	args := svc.Called(req, principal)
 	return args.Get(0).(PageResponse), args.Error(1)
 }
 
@@ -81,7 +123,7 @@ func TestController_CreateEmployee(t *testing.T) {
 	// тестируем положительный сценарий: работника создали и получили его id
 	t.Run("should return created employee id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -109,12 +151,11 @@ func TestController_CreateEmployee(t *testing.T) {
 		a.Nil(err)
 		a.Equal(int64(123), responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return error if employee already exists", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -135,20 +176,20 @@ func TestController_CreateEmployee(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusConflict, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeAlreadyExists, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error validator error", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -169,20 +210,20 @@ func TestController_CreateEmployee(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeValidationFailed, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error transaction", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -205,17 +246,17 @@ func TestController_CreateEmployee(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error unmarshal", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -238,12 +279,324 @@ func TestController_CreateEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
+		a.Nil(err)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
+	})
+}
+
+func TestController_CreateEmployeeBatch(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+	claims := &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{
+			Roles: []string{web.IdmAdmin},
+		},
+	}
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+
+	t.Run("should return per-item results localized by Accept-Language", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		body := strings.NewReader(`{"mode": "best_effort", "items": [{"name": "john doe"}, {"name": ""}]}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/batch", body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept-Language", "ru")
+
+		fieldErr := common.FieldError{
+			Field:        "name",
+			JsonPath:     "$.name",
+			Rule:         "required",
+			Message:      "name is a required field",
+			Translations: map[string]string{"en": "name is a required field", "ru": "name обязательное поле"},
+		}
+		svc.On("CreateBatch", BatchCreateRequest{
+			Mode:  BatchModeBestEffort,
+			Items: []CreateRequest{{Name: "john doe"}, {Name: ""}},
+		}).Return(BatchResponse{
+			Mode:      BatchModeBestEffort,
+			Committed: true,
+			Created:   1,
+			Failed:    1,
+			Items: []BatchItemResult{
+				{Index: 0, Status: BatchItemCreated, Id: 123},
+				{Index: 1, Status: BatchItemInvalid, Message: fieldErr.Message, Errors: common.ValidationErrors{fieldErr}},
+			},
+		}, nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var responseBody common.Response[BatchResponse]
+		a.Nil(json.NewDecoder(resp.Body).Decode(&responseBody))
+		a.True(responseBody.Success)
+		a.Equal(1, responseBody.Data.Created)
+		a.Len(responseBody.Data.Items, 2)
+		a.Equal(int64(123), responseBody.Data.Items[0].Id)
+		a.Equal("name обязательное поле", responseBody.Data.Items[1].Message)
+		a.Equal("name обязательное поле", responseBody.Data.Items[1].Errors[0].Message)
+	})
+
+	t.Run("should log database errors of failed items", func(t *testing.T) {
+		core, logs := observer.New(zap.ErrorLevel)
+		observed := &common.Logger{Logger: zap.New(core)}
+		server := web.NewServer(observed)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, observed)
+		controller.RegisterRoutes()
+
+		body := strings.NewReader(`{"mode": "best_effort", "items": [{"name": "john doe"}]}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/batch", body)
+		req.Header.Set("Content-Type", "application/json")
+		svc.On("CreateBatch", mock.Anything).Return(BatchResponse{
+			Mode:      BatchModeBestEffort,
+			Committed: true,
+			Failed:    1,
+			Items: []BatchItemResult{{Index: 0, Status: BatchItemFailed, Message: "failed to create employee",
+				err: errors.New("pq: deadlock detected")}},
+		}, nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		responseBody, _ := io.ReadAll(resp.Body)
+		a.NotContains(string(responseBody), "deadlock")
+		entries := logs.FilterMessage("create employee batch: item failed").All()
+		a.NotEmpty(entries)
+		a.Equal("pq: deadlock detected", entries[0].ContextMap()["error"])
+	})
+
+	t.Run("should return validation error for invalid batch", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		body := strings.NewReader(`{"items": []}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/batch", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		svc.On("CreateBatch", mock.AnythingOfType("BatchCreateRequest")).
+			Return(BatchResponse{}, common.RequestValidatorError{Message: "items is a required field"})
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+		var problem common.Problem
+		a.Nil(json.NewDecoder(resp.Body).Decode(&problem))
+		a.Equal(common.CodeValidationFailed, problem.Code)
+	})
+
+	t.Run("should return forbidden without admin role", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}},
+			}})
+			return c.Next()
+		})
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		body := strings.NewReader(`{"items": [{"name": "john doe"}]}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/batch", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "CreateBatch", mock.Anything)
+	})
+}
+
+func TestController_ExportEmployees(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+	claims := &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{
+			Roles: []string{web.IdmUser},
+		},
+	}
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+
+	t.Run("should stream csv file", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/export?format=csv&textFilter=john", nil)
+		svc.On("Export", "john").Return(exportStub("id,name\n1,John Doe\n"), nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal("text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+		a.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "employees.csv")
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Equal("id,name\n1,John Doe\n", string(body))
+	})
+
+	t.Run("should return error before streaming if query failed", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/export", nil)
+		svc.On("Export", "").Return(nil, errors.New("database error"))
+
+		resp, err := server.App.Test(req)
+
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(http.StatusInternalServerError, resp.StatusCode)
+		a.NotEqual("text/csv; charset=utf-8", resp.Header.Get(fiber.HeaderContentType))
+	})
+
+	t.Run("should return bad request for unsupported format", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/api/v1/employees/export?format=xlsx", nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Export", mock.Anything)
+	})
+}
+
+func TestController_ImportEmployees(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+	claims := &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{
+			Roles: []string{web.IdmAdmin},
+		},
+	}
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+	// формирует multipart запрос с CSV файлом
+	newRequest := func(target string, mapping string, file string) *http.Request {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if mapping != "" {
+			a.Nil(writer.WriteField("mapping", mapping))
+		}
+		if file != "" {
+			part, err := writer.CreateFormFile("file", "employees.csv")
+			a.Nil(err)
+			_, err = part.Write([]byte(file))
+			a.Nil(err)
+		}
+		a.Nil(writer.Close())
+		req := httptest.NewRequest(fiber.MethodPost, target, &body)
+		req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+		return req
+	}
+
+	t.Run("should return import report", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		req := newRequest("/api/v1/employees/import?dry_run=true", `{"name": "ФИО"}`, "ФИО\nJohn\n")
+		svc.On("Import", map[string]string{"name": "ФИО"}, true).Return(csvutil.ImportReport{
+			DryRun:  true,
+			Created: 1,
+			Rows:    []csvutil.RowResult{{Line: 2, Status: csvutil.RowCreated}},
+		}, nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var responseBody common.Response[csvutil.ImportReport]
+		a.Nil(json.NewDecoder(resp.Body).Decode(&responseBody))
+		a.True(responseBody.Data.DryRun)
+		a.Equal(1, responseBody.Data.Created)
+		a.Len(responseBody.Data.Rows, 1)
+	})
+
+	t.Run("should return bad request without file", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		resp, err := server.App.Test(newRequest("/api/v1/employees/import", "", ""))
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Import", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return bad request for invalid mapping", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		resp, err := server.App.Test(newRequest("/api/v1/employees/import", "name=ФИО", "ФИО\nJohn\n"))
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("should return validation error for invalid file", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		svc.On("Import", map[string]string{}, false).
+			Return(csvutil.ImportReport{}, common.RequestValidatorError{Message: "csv header does not contain column for field name"})
+
+		resp, err := server.App.Test(newRequest("/api/v1/employees/import", "", "title\nJohn\n"))
+
+		a.Nil(err)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 	})
 }
 
@@ -267,7 +620,7 @@ func TestController_GetEmployee(t *testing.T) {
 
 	t.Run("should return employee", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -285,7 +638,7 @@ func TestController_GetEmployee(t *testing.T) {
 		}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindById", int64(123)).Return(response, nil)
+		svc.On("FindVisibleById", int64(123)).Return(response, nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -301,12 +654,11 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Nil(err)
 		a.Equal(response, responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err bad id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -325,16 +677,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err validation", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -345,7 +698,7 @@ func TestController_GetEmployee(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindById", int64(123)).Return(Response{}, common.RequestValidatorError{Message: "invalid employee"})
+		svc.On("FindVisibleById", int64(123)).Return(Response{}, common.RequestValidatorError{Message: "invalid employee"})
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -353,19 +706,20 @@ func TestController_GetEmployee(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeValidationFailed, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err transaction", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -376,7 +730,7 @@ func TestController_GetEmployee(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindById", int64(123)).Return(Response{}, common.RepositoryError{Message: "transaction error"})
+		svc.On("FindVisibleById", int64(123)).Return(Response{}, common.RepositoryError{Message: "transaction error"})
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -387,16 +741,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeInternal, problem.Code)
+		a.NotContains(string(bytesData), "transaction error")
 	})
 
 	t.Run("should return err not found", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -407,7 +762,7 @@ func TestController_GetEmployee(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindById", int64(123)).Return(Response{}, common.NotFoundError{Message: "employee not found"})
+		svc.On("FindVisibleById", int64(123)).Return(Response{}, common.NotFoundError{Message: "employee not found"})
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -418,16 +773,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusNotFound, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeNotFound, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -438,7 +794,7 @@ func TestController_GetEmployee(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindById", int64(123)).Return(Response{}, errors.New("error"))
+		svc.On("FindVisibleById", int64(123)).Return(Response{}, errors.New("error"))
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -449,11 +805,12 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -477,7 +834,7 @@ func TestController_GetAllEmployees(t *testing.T) {
 
 	t.Run("should success get all employees", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -503,7 +860,7 @@ func TestController_GetAllEmployees(t *testing.T) {
 		}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("GetAll").Return(responses, nil)
+		svc.On("GetAllVisible").Return(responses, nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -519,12 +876,11 @@ func TestController_GetAllEmployees(t *testing.T) {
 		a.Nil(err)
 		a.Equal(responses, responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -537,7 +893,7 @@ func TestController_GetAllEmployees(t *testing.T) {
 		responses := []Response{}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("GetAll").Return(responses, errors.New("error"))
+		svc.On("GetAllVisible").Return(responses, errors.New("error"))
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -548,12 +904,12 @@ func TestController_GetAllEmployees(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[[]Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Nil(responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -577,7 +933,7 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 
 	t.Run("should success get all employees by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -604,7 +960,7 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindByIds", []int64{123, 124}).Return(responses, nil)
+		svc.On("FindVisibleByIds", []int64{123, 124}).Return(responses, nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -620,12 +976,11 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		a.Nil(err)
 		a.Equal(len(responses), len(responseBody.Data))
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -639,7 +994,7 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		responses := []Response{}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("FindByIds", []int64{123, 124}).Return(responses, nil)
+		svc.On("FindVisibleByIds", []int64{123, 124}).Return(responses, nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -650,12 +1005,38 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[[]Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(len(responses), len(responseBody.Data))
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
+	})
+
+	t.Run("should return 403 without idm roles", func(t *testing.T) {
+		// Готовим тестовое окружение: токен только с ролью администратора отдела
+		server := web.NewServer(logger)
+		server.GroupApi.Use(func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{
+				RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmScopedAdmin}},
+			}})
+			return c.Next()
+		})
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		body := strings.NewReader("{\"ids\": [123,124]}")
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", body)
+		req.Header.Set("Content-Type", "application/json")
+
+		// Отправляем тестовый запрос на веб сервер
+		resp, err := server.App.Test(req)
+
+		// Выполняем проверки полученных данных
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "FindVisibleByIds", mock.Anything)
 	})
 }
 
@@ -667,6 +1048,7 @@ func TestController_DeleteEmployee(t *testing.T) {
 	}
 	// создаём тестовый токен аутентификации с ролью web.IdmAdmin
 	claims := &web.IdmClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"},
 		RealmAccess: web.RealmAccessClaims{
 			Roles: []string{web.IdmAdmin},
 		},
@@ -679,7 +1061,7 @@ func TestController_DeleteEmployee(t *testing.T) {
 
 	t.Run("should success del by id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -690,7 +1072,7 @@ func TestController_DeleteEmployee(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("DeleteById", int64(123)).Return(nil)
+		svc.On("DeleteById", int64(123), "admin").Return(nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -705,12 +1087,11 @@ func TestController_DeleteEmployee(t *testing.T) {
 		err = json.Unmarshal(bytesData, &responseBody)
 		a.Nil(err)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should success err invalid id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -729,11 +1110,12 @@ func TestController_DeleteEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -745,6 +1127,7 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 	}
 	// создаём тестовый токен аутентификации с ролью web.IdmAdmin
 	claims := &web.IdmClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: "admin"},
 		RealmAccess: web.RealmAccessClaims{
 			Roles: []string{web.IdmAdmin},
 		},
@@ -757,7 +1140,7 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 
 	t.Run("should success del by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -769,7 +1152,7 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("DeleteByIds", []int64{123, 124}).Return(nil)
+		svc.On("DeleteByIds", []int64{123, 124}, "admin").Return(nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -784,12 +1167,11 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 		err = json.Unmarshal(bytesData, &responseBody)
 		a.Nil(err)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should err bad request", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -809,16 +1191,17 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should success del by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -833,7 +1216,7 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 		req.Header.Set("Content-Type", "application/json")
 
 		// Настраиваем поведение мока в тесте
-		svc.On("DeleteByIds", []int64{123, 124}).Return(errors.New("error transaction"))
+		svc.On("DeleteByIds", []int64{123, 124}, "admin").Return(errors.New("error transaction"))
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -844,10 +1227,11 @@ func TestController_DeleteEmployeesByIds(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
@@ -1,26 +1,122 @@
 package employee
 
-import "time"
+import (
+	"database/sql"
+	"github.com/nihrom205/idm/inner/common"
+	"time"
+)
 
 type Entity struct {
-	Id       int64     `db:"id"`
-	Name     string    `db:"name"`
-	CreateAt time.Time `db:"create_at"`
-	UpdateAt time.Time `db:"update_at"`
+	Id   int64  `db:"id"`
+	Name string `db:"name"`
+	// табельный номер в HR системе, есть только у сотрудников из выгрузки HR
+	ExternalId sql.NullString `db:"external_id"`
+	OrgUnit    sql.NullString `db:"org_unit"`
+	JobTitle   sql.NullString `db:"job_title"`
+	// статус жизненного цикла: pre_hire, active, suspended, terminated
+	Status string `db:"status"`
+	// идентификатор пользователя в Keycloak (claim sub), по нему вызывающий находит свою запись
+	Subject sql.NullString `db:"subject"`
+	// руководитель сотрудника
+	ManagerId sql.NullInt64 `db:"manager_id"`
+	CreateAt  time.Time     `db:"create_at"`
+	UpdateAt  time.Time     `db:"update_at"`
 }
 
 func (e *Entity) toResponse() Response {
 	return Response{
-		Id:       e.Id,
-		Name:     e.Name,
-		CreateAt: e.CreateAt,
-		UpdateAt: e.UpdateAt,
+		Id:         e.Id,
+		Name:       e.Name,
+		ExternalId: e.ExternalId.String,
+		OrgUnit:    e.OrgUnit.String,
+		JobTitle:   e.JobTitle.String,
+		Status:     e.Status,
+		Subject:    e.Subject.String,
+		ManagerId:  e.ManagerId.Int64,
+		CreateAt:   e.CreateAt,
+		UpdateAt:   e.UpdateAt,
 	}
 }
 
 type Response struct {
-	Id       int64     `json:"id"`
-	Name     string    `json:"name"`
-	CreateAt time.Time `json:"create_at"`
-	UpdateAt time.Time `json:"update_at"`
+	Id         int64     `json:"id"`
+	Name       string    `json:"name"`
+	ExternalId string    `json:"external_id,omitempty"`
+	OrgUnit    string    `json:"org_unit,omitempty"`
+	JobTitle   string    `json:"job_title,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Subject    string    `json:"subject,omitempty"`
+	ManagerId  int64     `json:"manager_id,omitempty"`
+	CreateAt   time.Time `json:"create_at"`
+	UpdateAt   time.Time `json:"update_at"`
+}
+
+// Статусы элементов пакетного создания сотрудников
+const (
+	BatchItemCreated    = "created"
+	BatchItemDuplicate  = "duplicate"
+	BatchItemInvalid    = "validation_error"
+	BatchItemFailed     = "failed"
+	BatchItemRolledBack = "rolled_back"
+)
+
+// BatchItemResult результат создания одного сотрудника из пакета
+type BatchItemResult struct {
+	// позиция элемента в запросе
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	// id созданного сотрудника
+	Id      int64                   `json:"id,omitempty"`
+	Message string                  `json:"message,omitempty"`
+	Errors  common.ValidationErrors `json:"errors,omitempty"`
+	// ошибка базы данных элемента со статусом failed: клиенту не отдаётся, только логируется
+	err error
+}
+
+type BatchResponse struct {
+	Mode string `json:"mode"`
+	// false, если в режиме all_or_nothing ни один сотрудник не был создан
+	Committed bool              `json:"committed"`
+	Created   int               `json:"created"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+}
+
+// countFailed количество элементов пакета, которые не удалось создать
+func (r *BatchResponse) countFailed() int {
+	failed := 0
+	for _, item := range r.Items {
+		if item.Status != "" && item.Status != BatchItemCreated && item.Status != BatchItemRolledBack {
+			failed++
+		}
+	}
+	return failed
+}
+
+// rollBack помечает пакет отменённым: элементы без ошибок получают статус rolled_back
+func (r *BatchResponse) rollBack() {
+	r.Committed = false
+	r.Created = 0
+	r.Failed = r.countFailed()
+	for i := range r.Items {
+		if r.Items[i].Status == "" || r.Items[i].Status == BatchItemCreated {
+			r.Items[i].Status = BatchItemRolledBack
+			r.Items[i].Id = 0
+		}
+	}
+}
+
+// Localize переводит сообщения об ошибках валидации элементов на указанный язык
+func (r BatchResponse) Localize(lang string) BatchResponse {
+	items := make([]BatchItemResult, len(r.Items))
+	for i, item := range r.Items {
+		if len(item.Errors) > 0 {
+			validateErr := common.RequestValidatorError{Message: item.Message, Fields: item.Errors}.Localize(lang)
+			item.Message = validateErr.Message
+			item.Errors = validateErr.Fields
+		}
+		items[i] = item
+	}
+	r.Items = items
+	return r
 }
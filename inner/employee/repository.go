@@ -2,9 +2,12 @@ package employee
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/database"
 	"strings"
 	"unicode/utf8"
 )
@@ -27,9 +30,39 @@ func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
 // добавить новый элемент в коллекцию
 func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (int64, error) {
 	var id int64
-	query := "INSERT INTO employee (name) VALUES ($1) RETURNING id"
-	err := tx.QueryRowContext(ctx, query, employee.Name).Scan(&id)
-	return id, err
+	query := "INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id"
+	err := tx.QueryRowContext(ctx, query, employee.Name, employee.OrgUnit, employee.JobTitle, employee.Subject).Scan(&id)
+	return id, uniqueTaken(err, employee)
+}
+
+// ограничение уникальности subject, остальные уникальные поля при создании и изменении - имя
+const subjectKey = "employee_subject_key"
+
+// uniqueTaken ошибка уникального индекса, если имя (без учёта регистра) или subject сотрудника уже заняты
+func uniqueTaken(err error, employee Entity) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == subjectKey {
+		return database.MapUniqueViolation(err, fmt.Sprintf("employee with subject %s already exists", employee.Subject.String))
+	}
+	return database.MapUniqueViolation(err, fmt.Sprintf("employee with name %s already exists", employee.Name))
+}
+
+// SavepointTx создаёт точку сохранения в транзакции
+func (r *Repository) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+// RollbackToSavepointTx откатывает транзакцию к точке сохранения
+func (r *Repository) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// ReleaseSavepointTx удаляет точку сохранения, изменения после неё остаются в транзакции
+func (r *Repository) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
 }
 
 // найти элемент коллекции по его id
@@ -39,6 +72,20 @@ func (r *Repository) FindById(ctx context.Context, id int64) (employee Entity, e
 	return employee, err
 }
 
+// найти элемент коллекции по его id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (employee Entity, err error) {
+	query := "SELECT * FROM employee WHERE id=$1"
+	err = tx.GetContext(ctx, &employee, query, id)
+	return employee, err
+}
+
+// изменить имя сотрудника в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error {
+	query := "UPDATE employee SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, employee.Name, employee.OrgUnit, employee.JobTitle, employee.Id)
+	return uniqueTaken(err, employee)
+}
+
 // найти все элементы коллекции
 func (r *Repository) GetAll(ctx context.Context) (employee []Entity, err error) {
 	query := "SELECT * FROM employee"
@@ -79,15 +126,75 @@ func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) error {
 	return err
 }
 
-// поиск сотрудника по имени
+// поиск сотрудника по имени без учёта регистра
 func (r *Repository) FindByName(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
-	query := "SELECT EXISTS(SELECT * FROM employee WHERE name = $1)"
+	query := "SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))"
 	err = tx.GetContext(ctx, &isExists, query, name)
 	return isExists, err
 }
 
+// найти сотрудника по id, если он виден вызывающему
+func (r *Repository) FindVisibleById(ctx context.Context, id int64, scope Scope) (employee Entity, err error) {
+	where, args := scope.where([]any{id})
+	err = r.db.GetContext(ctx, &employee, "SELECT * FROM employee WHERE id = $1"+where, args...)
+	return employee, err
+}
+
+// найти сотрудников по слайсу их id среди видимых вызывающему
+func (r *Repository) FindVisibleByIds(ctx context.Context, ids []int64, scope Scope) (employees []Entity, err error) {
+	where, args := scope.where([]any{pq.Int64Array(ids)})
+	err = r.db.SelectContext(ctx, &employees, "SELECT * FROM employee WHERE id = ANY($1)"+where, args...)
+	return employees, err
+}
+
+// заблокировать смену руководителей до конца транзакции, чтобы параллельные изменения не создали цикл
+func (r *Repository) LockManagersTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('employee_manager'))")
+	return err
+}
+
+// проверить в рамках транзакции, входит ли сотрудник employeeId в подчинённую иерархию руководителя managerId
+func (r *Repository) IsReportTx(ctx context.Context, tx *sqlx.Tx, managerId int64, employeeId int64) (isReport bool, err error) {
+	query := `WITH RECURSIVE reports (id) AS (
+		SELECT id FROM employee WHERE manager_id = $1
+		UNION
+		SELECT e.id FROM employee e JOIN reports r ON e.manager_id = r.id
+	)
+	SELECT EXISTS(SELECT * FROM reports WHERE id = $2)`
+	err = tx.GetContext(ctx, &isReport, query, managerId, employeeId)
+	return isReport, err
+}
+
+// назначить сотруднику руководителя в рамках транзакции
+func (r *Repository) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId sql.NullInt64) error {
+	query := "UPDATE employee SET manager_id = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, managerId, id)
+	return err
+}
+
+// найти сотрудника по идентификатору пользователя в Keycloak
+func (r *Repository) FindBySubject(ctx context.Context, subject string) (employee Entity, err error) {
+	query := "SELECT * FROM employee WHERE subject = $1"
+	err = r.db.GetContext(ctx, &employee, query, subject)
+	return employee, err
+}
+
+// проверить в рамках транзакции, связан ли уже какой-либо сотрудник с пользователем Keycloak
+func (r *Repository) SubjectExistsTx(ctx context.Context, tx *sqlx.Tx, subject string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM employee WHERE subject = $1)"
+	err = tx.GetContext(ctx, &isExists, query, subject)
+	return isExists, err
+}
+
+// связать сотрудника с пользователем Keycloak в рамках транзакции
+func (r *Repository) UpdateSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64, subject sql.NullString) error {
+	query := "UPDATE employee SET subject = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, subject, id)
+	return err
+}
+
 // FindPage возвращает сотрудников с учетом пагинации (limit, offset)
-func (r *Repository) FindPage(ctx context.Context, offset int, limit int, textFilter string) ([]Entity, error) {
+func (r *Repository) FindPage(ctx context.Context, offset int, limit int, textFilter string, scope Scope) ([]Entity, error) {
 	var employees []Entity
 	sb := strings.Builder{}
 	var args []interface{}
@@ -96,19 +203,19 @@ func (r *Repository) FindPage(ctx context.Context, offset int, limit int, textFi
 	if utf8.RuneCountInString(textFilter) >= 3 {
 		sb.WriteString(" AND name ILIKE $1")
 		args = append(args, "%"+textFilter+"%")
-		sb.WriteString(" OFFSET $2 LIMIT $3")
-		args = append(args, offset, limit)
-	} else {
-		sb.WriteString(" OFFSET $1 LIMIT $2")
-		args = append(args, offset, limit)
 	}
+	// ограничение видимости добавляется в запрос, чтобы страница и общее количество считались одинаково
+	where, args := scope.where(args)
+	sb.WriteString(where)
+	args = append(args, offset, limit)
+	sb.WriteString(fmt.Sprintf(" OFFSET $%d LIMIT $%d", len(args)-1, len(args)))
 
 	err := r.db.SelectContext(ctx, &employees, sb.String(), args...)
 	return employees, err
 }
 
 // CountAll возвращает кол-во записей
-func (r *Repository) CountAll(ctx context.Context, textFilter string) (int64, error) {
+func (r *Repository) CountAll(ctx context.Context, textFilter string, scope Scope) (int64, error) {
 	var total int64
 	sb := strings.Builder{}
 	var args []interface{}
@@ -119,6 +226,47 @@ func (r *Repository) CountAll(ctx context.Context, textFilter string) (int64, er
 		sb.WriteString(" AND name ILIKE $1")
 		args = append(args, "%"+textFilter+"%")
 	}
+	where, args := scope.where(args)
+	sb.WriteString(where)
 	err := r.db.GetContext(ctx, &total, sb.String(), args...)
 	return total, err
 }
+
+// Query выполняет запрос сотрудников (с фильтром по имени) и возвращает курсор по ним,
+// не загружая всю таблицу в память. Курсор нужно закрыть
+func (r *Repository) Query(ctx context.Context, textFilter string, scope Scope) (*sqlx.Rows, error) {
+	sb := strings.Builder{}
+	var args []interface{}
+
+	sb.WriteString("SELECT * FROM employee WHERE 1=1")
+	if utf8.RuneCountInString(textFilter) >= 3 {
+		sb.WriteString(" AND name ILIKE $1")
+		args = append(args, "%"+textFilter+"%")
+	}
+	where, args := scope.where(args)
+	sb.WriteString(where)
+	sb.WriteString(" ORDER BY id")
+
+	return r.db.QueryxContext(ctx, sb.String(), args...)
+}
+
+// ForEach построчно читает сотрудников (с фильтром по имени) и вызывает fn для каждого,
+// не загружая всю таблицу в память
+func (r *Repository) ForEach(ctx context.Context, textFilter string, scope Scope, fn func(Entity) error) error {
+	rows, err := r.Query(ctx, textFilter, scope)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var employee Entity
+		if err := rows.StructScan(&employee); err != nil {
+			return err
+		}
+		if err := fn(employee); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
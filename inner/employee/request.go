@@ -1,11 +1,43 @@
 package employee
 
+import (
+	"database/sql"
+	"io"
+	"strings"
+)
+
 type CreateRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=155"`
+	Name     string `json:"name" validate:"required,min=2,max=155"`
+	OrgUnit  string `json:"org_unit" validate:"max=155"`
+	JobTitle string `json:"job_title" validate:"max=155"`
+	// идентификатор пользователя в Keycloak (claim sub)
+	Subject string `json:"subject" validate:"max=255"`
 }
 
 func (r *CreateRequest) ToEntity() Entity {
-	return Entity{Name: r.Name}
+	return Entity{
+		Name:     r.Name,
+		OrgUnit:  nullString(r.OrgUnit),
+		JobTitle: nullString(r.JobTitle),
+		Subject:  nullString(r.Subject),
+	}
+}
+
+type UpdateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=155"`
+	// отдел и должность меняются, только если переданы; пустая строка очищает значение
+	OrgUnit  *string `json:"org_unit" validate:"omitempty,max=155"`
+	JobTitle *string `json:"job_title" validate:"omitempty,max=155"`
+}
+
+// LinkSubjectRequest связывает сотрудника с пользователем Keycloak; пустой subject снимает связь
+type LinkSubjectRequest struct {
+	Subject string `json:"subject" validate:"max=255"`
+}
+
+// ManagerRequest назначает сотруднику руководителя; 0 снимает руководителя
+type ManagerRequest struct {
+	ManagerId int64 `json:"manager_id" validate:"min=0"`
 }
 
 type FindByIdRequest struct {
@@ -19,3 +51,36 @@ type FindByIdsRequest struct {
 type DeleteByIdsRequest struct {
 	Ids []int64 `json:"ids" validate:"required,min=1,dive,gt=0"`
 }
+
+// Режимы пакетного создания сотрудников
+const (
+	// BatchModeAllOrNothing сотрудники создаются, только если все элементы пакета корректны
+	BatchModeAllOrNothing = "all_or_nothing"
+	// BatchModeBestEffort создаются все корректные элементы, ошибочные пропускаются
+	BatchModeBestEffort = "best_effort"
+)
+
+type BatchCreateRequest struct {
+	// режим обработки пакета, по умолчанию all_or_nothing
+	Mode string `json:"mode" validate:"omitempty,oneof=all_or_nothing best_effort"`
+	// не больше 500 сотрудников в одном пакете; элементы валидируются по отдельности,
+	// чтобы вернуть ошибку для каждого из них
+	Items []CreateRequest `json:"items" validate:"required,min=1,max=500"`
+}
+
+type ImportRequest struct {
+	// CSV файл со столбцами name и необязательными id, org_unit, job_title, subject и status
+	File io.Reader
+	// имя столбца в заголовке файла для каждого поля, если оно отличается от имени поля
+	Mapping map[string]string
+	// проверить файл и вернуть отчёт без сохранения изменений
+	DryRun bool
+	// кто загружает файл, для журнала смены отдела и должности
+	Actor string
+}
+
+// nullString пустую строку сохраняет как NULL
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
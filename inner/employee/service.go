@@ -2,23 +2,50 @@ package employee
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"io"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// столбцы CSV файла выгрузки сотрудников
+var exportColumns = []string{"id", "name", "org_unit", "job_title", "subject", "status", "create_at", "update_at"}
+
+// столбцы CSV файла загрузки сотрудников: файл выгрузки загружается обратно без изменений
+var importColumns = []string{"id", "name", "org_unit", "job_title", "subject", "status"}
+
 type Repo interface {
 	CreateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (int64, error)
 	FindById(ctx context.Context, id int64) (Entity, error)
 	GetAll(ctx context.Context) (employee []Entity, err error)
 	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
-	DeleteById(ctx context.Context, id int64) error
-	DeleteByIds(ctx context.Context, ids []int64) error
 	FindByName(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
 	BeginTransaction() (*sqlx.Tx, error)
-	FindPage(ctx context.Context, offset int, limit int, textFilter string) ([]Entity, error)
-	CountAll(ctx context.Context, textFilter string) (int64, error)
+	FindPage(ctx context.Context, offset int, limit int, textFilter string, scope Scope) ([]Entity, error)
+	CountAll(ctx context.Context, textFilter string, scope Scope) (int64, error)
+	SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error
+	FindBySubject(ctx context.Context, subject string) (Entity, error)
+	SubjectExistsTx(ctx context.Context, tx *sqlx.Tx, subject string) (bool, error)
+	UpdateSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64, subject sql.NullString) error
+	Query(ctx context.Context, textFilter string, scope Scope) (*sqlx.Rows, error)
+	ForEach(ctx context.Context, textFilter string, scope Scope, fn func(Entity) error) error
+	FindVisibleById(ctx context.Context, id int64, scope Scope) (Entity, error)
+	FindVisibleByIds(ctx context.Context, ids []int64, scope Scope) ([]Entity, error)
+	LockManagersTx(ctx context.Context, tx *sqlx.Tx) error
+	IsReportTx(ctx context.Context, tx *sqlx.Tx, managerId int64, employeeId int64) (bool, error)
+	UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId sql.NullInt64) error
 }
 
 type PageResponse struct {
@@ -29,18 +56,40 @@ type PageResponse struct {
 }
 
 type PageRequest struct {
-	PageSize   int `validate:"min=1,max=100"`
-	PageNumber int `validate:"min=0"`
-	TextFilter string
+	PageSize   int    `json:"pageSize" validate:"min=1,max=100"`
+	PageNumber int    `json:"pageNumber" validate:"min=0"`
+	TextFilter string `json:"textFilter"`
 }
 
 type Validator interface {
 	Validate(request any) error
 }
 
+// RoleRules назначает сотруднику роли по правилам на основе отдела и должности
+type RoleRules interface {
+	ApplyTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+}
+
+// Lifecycle жизненный цикл сотрудника: удаление сотрудника заменяется увольнением,
+// смена отдела или должности запускает обработчики жизненного цикла
+type Lifecycle interface {
+	TransitionTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error)
+	AttributesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, actor string) error
+}
+
+// Authorizer проверяет права вызывающего на сотрудников отдела; отказ возвращается как ForbiddenError
+type Authorizer interface {
+	AuthorizeOrgUnit(ctx context.Context, principal common.Principal, orgUnit string) error
+}
+
 type Service struct {
-	repo      Repo
-	validator Validator
+	repo       Repo
+	validator  Validator
+	rules      RoleRules
+	authorizer Authorizer
+	lifecycle  Lifecycle
+	// политики видимости; если не заданы, то вызывающему видны все сотрудники
+	visibility VisibilityPolicies
 }
 
 func NewService(repo Repo, validator Validator) *Service {
@@ -50,17 +99,101 @@ func NewService(repo Repo, validator Validator) *Service {
 	}
 }
 
+// SetRoleRules подключает назначение ролей по правилам при создании сотрудника и смене его атрибутов
+func (s *Service) SetRoleRules(rules RoleRules) {
+	s.rules = rules
+}
+
+// SetLifecycle подключает жизненный цикл, через который сотрудники увольняются вместо удаления
+func (s *Service) SetLifecycle(lifecycle Lifecycle) {
+	s.lifecycle = lifecycle
+}
+
+// SetAuthorizer подключает проверку прав администраторов отделов при создании сотрудников
+func (s *Service) SetAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// authorize проверяет, может ли вызывающий создавать сотрудников отдела orgUnit.
+// Без подключённой проверки создавать сотрудников может только администратор
+func (s *Service) authorize(ctx context.Context, principal common.Principal, orgUnit string) error {
+	if s.authorizer == nil {
+		if principal.Admin {
+			return nil
+		}
+		return common.ForbiddenError{Message: "permission denied"}
+	}
+	return s.authorizer.AuthorizeOrgUnit(ctx, principal, strings.TrimSpace(orgUnit))
+}
+
+// SetVisibility подключает ограничение видимости сотрудников для вызывающих по их ролям
+func (s *Service) SetVisibility(policies VisibilityPolicies) {
+	s.visibility = policies
+}
+
+// scope определяет, каких сотрудников видит вызывающий
+func (s *Service) scope(ctx context.Context, principal common.Principal) (Scope, error) {
+	if s.visibility == nil {
+		return ScopeAll, nil
+	}
+	rules := s.visibility.rulesFor(principal.Roles)
+	if slices.Contains(rules, VisibleAll) {
+		return ScopeAll, nil
+	}
+	if principal.Subject == "" {
+		return Scope{}, nil
+	}
+	employee, err := s.repo.FindBySubject(ctx, principal.Subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		// вызывающий не связан с сотрудником и не видит никого
+		return Scope{}, nil
+	}
+	if err != nil {
+		return Scope{}, fmt.Errorf("error finding employee with subject %s: %w", principal.Subject, err)
+	}
+	scope := Scope{
+		EmployeeId: employee.Id,
+		Self:       slices.Contains(rules, VisibleSelf),
+		Reports:    slices.Contains(rules, VisibleReports),
+	}
+	if slices.Contains(rules, VisibleOrgUnit) {
+		scope.OrgUnit = employee.OrgUnit.String
+	}
+	return scope, nil
+}
+
 // Метод для создания нового сотрудника
 // принимает на вход CreateRequest - структура запроса на создание сотрудника
-func (s *Service) Create(ctx context.Context, request CreateRequest) (int64, error) {
+func (s *Service) Create(ctx context.Context, request CreateRequest, principal common.Principal) (int64, error) {
 
 	// валидируем запрос
 	err := s.validator.Validate(request)
 	if err != nil {
 		// возвращаем кастомную ошибку в случае, если запрос не прошёл валидацию
-		return 0, common.RequestValidatorError{Message: err.Error()}
+		return 0, common.NewRequestValidatorError(err)
 	}
+	// администратор отдела создаёт сотрудников только в своих отделах
+	if err = s.authorize(ctx, principal, request.OrgUnit); err != nil {
+		return 0, err
+	}
+	return s.create(ctx, request)
+}
 
+// Provision создаёт сотрудника для пользователя Keycloak, который сам запросил себе запись: сотрудник
+// создаётся без отдела и должности, поэтому права администраторов отделов не проверяются
+func (s *Service) Provision(ctx context.Context, name string, subject string) (int64, error) {
+	request := CreateRequest{Name: name, Subject: subject}
+	if err := s.validator.Validate(request); err != nil {
+		return 0, common.NewRequestValidatorError(err)
+	}
+	if strings.TrimSpace(subject) == "" {
+		return 0, common.RequestValidatorError{Message: "subject is required to provision employee"}
+	}
+	return s.create(ctx, request)
+}
+
+// create создаёт сотрудника, если имя и subject ещё не заняты, и назначает ему роли по правилам
+func (s *Service) create(ctx context.Context, request CreateRequest) (newEmployeeId int64, err error) {
 	tx, err := s.repo.BeginTransaction()
 
 	defer func() {
@@ -100,17 +233,490 @@ func (s *Service) Create(ctx context.Context, request CreateRequest) (int64, err
 	if isExist {
 		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("employee with name %s already exists", request.Name)}
 	}
+	// пользователь Keycloak может быть связан только с одним сотрудником
+	isExist, err = s.subjectExists(ctx, tx, request.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if isExist {
+		return 0, common.AlreadyExistsError{Message: fmt.Sprintf("employee with subject %s already exists", request.Subject)}
+	}
 
 	// в случае отсутствия сотрудника с таким же именем - в рамках этой же транзакции вызываем метод репозитория,
 	// который должен будет создать нового сотрудника
-	newEmployeeId, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+	newEmployeeId, err = s.repo.CreateTx(ctx, tx, request.ToEntity())
 	if err != nil {
 		return 0, fmt.Errorf("error failed to create employee with id %d: %w", newEmployeeId, err)
 	}
+	if err = s.applyRules(ctx, tx, newEmployeeId); err != nil {
+		return 0, err
+	}
 
 	return newEmployeeId, nil
 }
 
+// CreateBatch создаёт сотрудников из пакета в одной транзакции и возвращает результат по каждому элементу.
+// В режиме all_or_nothing при ошибке любого элемента транзакция откатывается целиком,
+// в режиме best_effort каждый элемент создаётся в своей точке сохранения и ошибочные элементы пропускаются
+func (s *Service) CreateBatch(ctx context.Context, request BatchCreateRequest, principal common.Principal) (response BatchResponse, err error) {
+	err = s.validator.Validate(request)
+	if err != nil {
+		return BatchResponse{}, common.NewRequestValidatorError(err)
+	}
+	// пакет с сотрудником чужого отдела отклоняется целиком
+	checked := map[string]bool{}
+	for _, item := range request.Items {
+		if checked[item.OrgUnit] {
+			continue
+		}
+		if err = s.authorize(ctx, principal, item.OrgUnit); err != nil {
+			return BatchResponse{}, err
+		}
+		checked[item.OrgUnit] = true
+	}
+
+	mode := request.Mode
+	if mode == "" {
+		mode = BatchModeAllOrNothing
+	}
+	response = BatchResponse{Mode: mode, Items: s.validateBatchItems(request.Items)}
+
+	// в режиме all_or_nothing некорректный элемент отменяет весь пакет ещё до обращения к базе данных
+	if mode == BatchModeAllOrNothing && response.countFailed() > 0 {
+		response.rollBack()
+		return response, nil
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return BatchResponse{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating employee batch panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating employee batch: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil || !response.Committed {
+			// если произошла ошибка или пакет отменён, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating employee batch: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating employee batch: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	for i, item := range request.Items {
+		if response.Items[i].Status != "" {
+			continue
+		}
+		var result BatchItemResult
+		if mode == BatchModeBestEffort {
+			result, err = s.createBatchItemSavepoint(ctx, tx, i, item)
+		} else {
+			result, err = s.createBatchItem(ctx, tx, i, item)
+		}
+		if duplicate, ok := duplicateBatchItem(i, err); ok {
+			// сотрудника с тем же именем создали параллельно: транзакция прервана, и пакет отменяется целиком
+			response.Items[i] = duplicate
+			err = nil
+			break
+		}
+		if err != nil {
+			return BatchResponse{}, err
+		}
+		response.Items[i] = result
+	}
+
+	if mode == BatchModeAllOrNothing && response.countFailed() > 0 {
+		response.rollBack()
+		return response, nil
+	}
+	response.Committed = true
+	response.Failed = response.countFailed()
+	response.Created = len(response.Items) - response.Failed
+	return response, nil
+}
+
+// validateBatchItems проверяет элементы пакета и повторы имён внутри пакета.
+// Для корректных элементов статус остаётся пустым
+func (s *Service) validateBatchItems(items []CreateRequest) []BatchItemResult {
+	results := make([]BatchItemResult, len(items))
+	// индекс первого элемента с таким именем
+	names := make(map[string]int, len(items))
+	for i, item := range items {
+		results[i].Index = i
+		if err := s.validator.Validate(item); err != nil {
+			validateErr := common.NewRequestValidatorError(err)
+			results[i].Status = BatchItemInvalid
+			results[i].Message = validateErr.Message
+			results[i].Errors = validateErr.Fields
+			continue
+		}
+		// имена сравниваются без учёта регистра, как в уникальном индексе
+		key := strings.ToLower(item.Name)
+		if first, ok := names[key]; ok {
+			results[i].Status = BatchItemDuplicate
+			results[i].Message = fmt.Sprintf("employee with name %s is duplicated in item %d", item.Name, first)
+			continue
+		}
+		names[key] = i
+	}
+	return results
+}
+
+// createBatchItem создаёт одного сотрудника из пакета, если сотрудника с таким именем ещё нет
+func (s *Service) createBatchItem(ctx context.Context, tx *sqlx.Tx, index int, item CreateRequest) (BatchItemResult, error) {
+	isExist, err := s.repo.FindByName(ctx, tx, item.Name)
+	if err != nil {
+		return BatchItemResult{}, fmt.Errorf("error finding employee by name: %s, %w", item.Name, err)
+	}
+	if isExist {
+		return BatchItemResult{
+			Index:   index,
+			Status:  BatchItemDuplicate,
+			Message: fmt.Sprintf("employee with name %s already exists", item.Name),
+		}, nil
+	}
+	isExist, err = s.subjectExists(ctx, tx, item.Subject)
+	if err != nil {
+		return BatchItemResult{}, err
+	}
+	if isExist {
+		return BatchItemResult{
+			Index:   index,
+			Status:  BatchItemDuplicate,
+			Message: fmt.Sprintf("employee with subject %s already exists", item.Subject),
+		}, nil
+	}
+
+	id, err := s.repo.CreateTx(ctx, tx, item.ToEntity())
+	if err != nil {
+		return BatchItemResult{}, fmt.Errorf("error creating employee with name %s: %w", item.Name, err)
+	}
+	if err := s.applyRules(ctx, tx, id); err != nil {
+		return BatchItemResult{}, err
+	}
+	return BatchItemResult{Index: index, Status: BatchItemCreated, Id: id}, nil
+}
+
+// duplicateBatchItem результат элемента пакета, если сотрудника с тем же именем или subject
+// создали параллельно и уникальный индекс отклонил вставку
+func duplicateBatchItem(index int, err error) (BatchItemResult, bool) {
+	var alreadyExistsErr common.AlreadyExistsError
+	if !errors.As(err, &alreadyExistsErr) {
+		return BatchItemResult{}, false
+	}
+	return BatchItemResult{Index: index, Status: BatchItemDuplicate, Message: alreadyExistsErr.Message}, true
+}
+
+// createBatchItemSavepoint создаёт сотрудника в точке сохранения: ошибка базы данных
+// откатывает только этот элемент, а не всю транзакцию
+func (s *Service) createBatchItemSavepoint(ctx context.Context, tx *sqlx.Tx, index int, item CreateRequest) (BatchItemResult, error) {
+	const savepoint = "employee_batch_item"
+	if err := s.repo.SavepointTx(ctx, tx, savepoint); err != nil {
+		return BatchItemResult{}, fmt.Errorf("error creating savepoint: %w", err)
+	}
+
+	result, err := s.createBatchItem(ctx, tx, index, item)
+	if err != nil {
+		if errRollback := s.repo.RollbackToSavepointTx(ctx, tx, savepoint); errRollback != nil {
+			return BatchItemResult{}, fmt.Errorf("error rolling back to savepoint: %w, %w", err, errRollback)
+		}
+		if duplicate, ok := duplicateBatchItem(index, err); ok {
+			return duplicate, nil
+		}
+		// текст ошибки базы данных клиенту не отдаём
+		return BatchItemResult{Index: index, Status: BatchItemFailed, Message: "failed to create employee", err: err}, nil
+	}
+
+	if err := s.repo.ReleaseSavepointTx(ctx, tx, savepoint); err != nil {
+		return BatchItemResult{}, fmt.Errorf("error releasing savepoint: %w", err)
+	}
+	return result, nil
+}
+
+// Update переименовывает сотрудника и меняет его отдел и должность. Имя должно остаться уникальным.
+// При смене отдела или должности выполняются обработчики жизненного цикла, как при сверке с HR
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (response Response, err error) {
+	err = s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("updating employee panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating employee: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating employee: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("updating employee: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	entity, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+
+	renamed := entity.Name != request.Name
+	// имена уникальны без учёта регистра, поэтому смена регистра не занимает чужое имя
+	if renamed && !strings.EqualFold(entity.Name, request.Name) {
+		isExist, err := s.repo.FindByName(ctx, tx, request.Name)
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding employee by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("employee with name %s already exists", request.Name)}
+		}
+	}
+
+	attributesChanged := false
+	if request.OrgUnit != nil && nullString(*request.OrgUnit) != entity.OrgUnit {
+		entity.OrgUnit = nullString(*request.OrgUnit)
+		attributesChanged = true
+	}
+	if request.JobTitle != nil && nullString(*request.JobTitle) != entity.JobTitle {
+		entity.JobTitle = nullString(*request.JobTitle)
+		attributesChanged = true
+	}
+	if !renamed && !attributesChanged {
+		return entity.toResponse(), nil
+	}
+
+	entity.Name = request.Name
+	if err = s.repo.UpdateTx(ctx, tx, entity); err != nil {
+		return Response{}, fmt.Errorf("error updating employee with id %d: %w", id, err)
+	}
+	if attributesChanged {
+		if err = s.attributesChanged(ctx, tx, id, actor); err != nil {
+			return Response{}, err
+		}
+	}
+	entity.UpdateAt = time.Now()
+	return entity.toResponse(), nil
+}
+
+// attributesChanged сообщает жизненному циклу о смене отдела или должности: его обработчики пересчитывают
+// роли по правилам и ставят сотрудника в очередь провижининга. Без жизненного цикла применяются только правила
+func (s *Service) attributesChanged(ctx context.Context, tx *sqlx.Tx, id int64, actor string) error {
+	if s.lifecycle == nil {
+		return s.applyRules(ctx, tx, id)
+	}
+	if err := s.lifecycle.AttributesChangedTx(ctx, tx, id, actor); err != nil {
+		return fmt.Errorf("error processing attributes change of employee with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// applyRules назначает сотруднику роли по правилам, если правила подключены
+func (s *Service) applyRules(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	if s.rules == nil {
+		return nil
+	}
+	if err := s.rules.ApplyTx(ctx, tx, id); err != nil {
+		return fmt.Errorf("error applying role rules to employee with id %d: %w", id, err)
+	}
+	return nil
+}
+
+// subjectExists проверяет, связан ли уже какой-либо сотрудник с пользователем Keycloak; пустой subject не проверяется
+func (s *Service) subjectExists(ctx context.Context, tx *sqlx.Tx, subject string) (bool, error) {
+	if subject == "" {
+		return false, nil
+	}
+	isExist, err := s.repo.SubjectExistsTx(ctx, tx, subject)
+	if err != nil {
+		return false, fmt.Errorf("error finding employee by subject: %s, %w", subject, err)
+	}
+	return isExist, nil
+}
+
+// LinkSubject связывает сотрудника с пользователем Keycloak, пустой subject снимает связь
+func (s *Service) LinkSubject(ctx context.Context, id int64, request LinkSubjectRequest) (response Response, err error) {
+	err = s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("linking employee subject panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("linking employee subject: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("linking employee subject: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("linking employee subject: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	entity, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+
+	subject := nullString(request.Subject)
+	if entity.Subject == subject {
+		return entity.toResponse(), nil
+	}
+	isExist, err := s.subjectExists(ctx, tx, request.Subject)
+	if err != nil {
+		return Response{}, err
+	}
+	if isExist {
+		return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("employee with subject %s already exists", request.Subject)}
+	}
+
+	if err = s.repo.UpdateSubjectTx(ctx, tx, id, subject); err != nil {
+		return Response{}, fmt.Errorf("error linking subject to employee with id %d: %w", id, err)
+	}
+	entity.Subject = subject
+	entity.UpdateAt = time.Now()
+	return entity.toResponse(), nil
+}
+
+// SetManager назначает сотруднику руководителя. Руководитель не может быть подчинённым сотрудника
+func (s *Service) SetManager(ctx context.Context, id int64, request ManagerRequest) (response Response, err error) {
+	err = s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("setting employee manager panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("setting employee manager: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("setting employee manager: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("setting employee manager: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	if err = s.repo.LockManagersTx(ctx, tx); err != nil {
+		return Response{}, fmt.Errorf("error locking employee managers: %w", err)
+	}
+	entity, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+
+	managerId := sql.NullInt64{Int64: request.ManagerId, Valid: request.ManagerId != 0}
+	if entity.ManagerId == managerId {
+		return entity.toResponse(), nil
+	}
+	if managerId.Valid {
+		if managerId.Int64 == id {
+			return Response{}, common.ConflictError{Message: fmt.Sprintf("employee %d cannot be managed by itself", id)}
+		}
+		_, err = s.repo.FindByIdTx(ctx, tx, managerId.Int64)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", managerId.Int64)}
+		}
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding employee with id %d: %w", managerId.Int64, err)
+		}
+		isReport, err := s.repo.IsReportTx(ctx, tx, id, managerId.Int64)
+		if err != nil {
+			return Response{}, fmt.Errorf("error checking reports of employee %d: %w", id, err)
+		}
+		if isReport {
+			return Response{}, common.ConflictError{
+				Message: fmt.Sprintf("employee %d cannot manage employee %d: reporting line would create a cycle", managerId.Int64, id),
+			}
+		}
+	}
+
+	if err = s.repo.UpdateManagerTx(ctx, tx, id, managerId); err != nil {
+		return Response{}, fmt.Errorf("error setting manager of employee with id %d: %w", id, err)
+	}
+	entity.ManagerId = managerId
+	entity.UpdateAt = time.Now()
+	return entity.toResponse(), nil
+}
+
+// FindBySubject находит сотрудника, связанного с пользователем Keycloak
+func (s *Service) FindBySubject(ctx context.Context, subject string) (Response, error) {
+	employee, err := s.repo.FindBySubject(ctx, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with subject %s not found", subject)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with subject %s: %w", subject, err)
+	}
+	return employee.toResponse(), nil
+}
+
 func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
 	employees, err := s.repo.FindById(ctx, id)
 	if err != nil {
@@ -120,6 +726,66 @@ func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
 	return employees.toResponse(), nil
 }
 
+// FindVisibleById возвращает сотрудника, если он виден вызывающему; невидимый сотрудник считается не найденным
+func (s *Service) FindVisibleById(ctx context.Context, id int64, principal common.Principal) (Response, error) {
+	scope, err := s.scope(ctx, principal)
+	if err != nil {
+		return Response{}, err
+	}
+	employee, err := s.repo.FindVisibleById(ctx, id, scope)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	return employee.toResponse(), nil
+}
+
+// CheckVisible возвращает NotFoundError, если сотрудник не виден вызывающему
+func (s *Service) CheckVisible(ctx context.Context, id int64, principal common.Principal) error {
+	_, err := s.FindVisibleById(ctx, id, principal)
+	return err
+}
+
+// FindVisibleByIds возвращает сотрудников из списка, видимых вызывающему; невидимые пропускаются
+func (s *Service) FindVisibleByIds(ctx context.Context, ids []int64, principal common.Principal) ([]Response, error) {
+	if len(ids) == 0 {
+		return []Response{}, common.RequestValidatorError{Message: "employee ids cannot be empty"}
+	}
+	scope, err := s.scope(ctx, principal)
+	if err != nil {
+		return []Response{}, err
+	}
+	employees, err := s.repo.FindVisibleByIds(ctx, ids, scope)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error finding employee with id %d: %w", ids, err)
+	}
+
+	response := make([]Response, 0, len(employees))
+	for _, item := range employees {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+// GetAllVisible возвращает всех сотрудников, видимых вызывающему
+func (s *Service) GetAllVisible(ctx context.Context, principal common.Principal) ([]Response, error) {
+	scope, err := s.scope(ctx, principal)
+	if err != nil {
+		return []Response{}, err
+	}
+	response := make([]Response, 0)
+	err = s.repo.ForEach(ctx, "", scope, func(employee Entity) error {
+		response = append(response, employee.toResponse())
+		return nil
+	})
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all employees: %w", err)
+	}
+	return response, nil
+}
+
 func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
 	employees, err := s.repo.GetAll(ctx)
 	if err != nil {
@@ -148,42 +814,89 @@ func (s *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error
 	return response, nil
 }
 
-func (s *Service) DeleteById(ctx context.Context, id int64) error {
-	err := s.repo.DeleteById(ctx, id)
-	if err != nil {
+// DeleteById увольняет сотрудника: запись сохраняется, статус меняется на terminated,
+// а действия жизненного цикла отзывают роли и блокируют учётные записи
+func (s *Service) DeleteById(ctx context.Context, id int64, actor string) error {
+	if err := s.DeleteByIds(ctx, []int64{id}, actor); err != nil {
 		return fmt.Errorf("error deleting employee with id %d: %w", id, err)
 	}
-
 	return nil
 }
 
-func (s *Service) DeleteByIds(ctx context.Context, ids []int64) error {
-	err := s.repo.DeleteByIds(ctx, ids)
+// DeleteByIds увольняет сотрудников в одной транзакции
+func (s *Service) DeleteByIds(ctx context.Context, ids []int64, actor string) (err error) {
+	if len(ids) == 0 {
+		return fmt.Errorf("employee ids cannot be empty")
+	}
+	if s.lifecycle == nil {
+		return fmt.Errorf("employee lifecycle is not configured")
+	}
+	tx, err := s.repo.BeginTransaction()
 	if err != nil {
-		return fmt.Errorf("error deleting employee with id %d: %w", ids, err)
+		return fmt.Errorf("error creating transaction: %w", err)
 	}
 
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("deleting employees panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("deleting employees: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	for _, id := range ids {
+		_, err = s.lifecycle.TransitionTx(ctx, tx, lifecycle.TransitionRequest{
+			EmployeeId: id,
+			Status:     lifecycle.StatusTerminated,
+			Reason:     "deleted",
+			Actor:      actor,
+		})
+		if err != nil {
+			return fmt.Errorf("error terminating employee with id %d: %w", id, err)
+		}
+	}
 	return nil
 }
 
-func (s *Service) FindPage(ctx context.Context, request PageRequest) (PageResponse, error) {
+// FindPage возвращает страницу сотрудников, видимых вызывающему
+func (s *Service) FindPage(ctx context.Context, request PageRequest, principal common.Principal) (PageResponse, error) {
 
 	// валидируем запрос
 	err := s.validator.Validate(request)
 	if err != nil {
 		// возвращаем кастомную ошибку в случае, если запрос не прошёл валидацию
-		return PageResponse{}, common.RequestValidatorError{Message: err.Error()}
+		return PageResponse{}, common.NewRequestValidatorError(err)
 	}
 
 	offset := request.PageNumber * request.PageSize
 
+	scope, err := s.scope(ctx, principal)
+	if err != nil {
+		return PageResponse{}, err
+	}
+
 	textFilter := strings.TrimSpace(request.TextFilter)
-	entities, err := s.repo.FindPage(ctx, offset, request.PageSize, textFilter)
+	entities, err := s.repo.FindPage(ctx, offset, request.PageSize, textFilter, scope)
 	if err != nil {
 		return PageResponse{}, fmt.Errorf("error finding page employee: %w", err)
 	}
 
-	total, err := s.repo.CountAll(ctx, textFilter)
+	total, err := s.repo.CountAll(ctx, textFilter, scope)
 	if err != nil {
 		return PageResponse{}, fmt.Errorf("error counting total employee: %w", err)
 	}
@@ -201,3 +914,228 @@ func (s *Service) FindPage(ctx context.Context, request PageRequest) (PageRespon
 	}
 	return resp, nil
 }
+
+// Export выгружает сотрудников (с фильтром по имени) в CSV. Запрос выполняется сразу, и его ошибка
+// возвращается до начала выгрузки, а строки читаются из базы данных по мере записи файла
+func (s *Service) Export(ctx context.Context, textFilter string, principal common.Principal) (csvutil.Export, error) {
+	scope, err := s.scope(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.Query(ctx, strings.TrimSpace(textFilter), scope)
+	if err != nil {
+		return nil, fmt.Errorf("error exporting employees: %w", err)
+	}
+
+	return csvutil.NewExport(rows, exportColumns, func() ([]string, error) {
+		var employee Entity
+		if err := rows.StructScan(&employee); err != nil {
+			return nil, fmt.Errorf("error exporting employees: %w", err)
+		}
+		return []string{
+			strconv.FormatInt(employee.Id, 10),
+			employee.Name,
+			employee.OrgUnit.String,
+			employee.JobTitle.String,
+			employee.Subject.String,
+			employee.Status,
+			employee.CreateAt.Format(time.RFC3339),
+			employee.UpdateAt.Format(time.RFC3339),
+		}, nil
+	}), nil
+}
+
+// Import загружает сотрудников из CSV файла в одной транзакции. Строка без id создаёт сотрудника,
+// строка с id меняет имя, отдел, должность и subject существующего. Ошибочные строки попадают в отчёт и не мешают загрузке остальных.
+// В режиме dry run транзакция откатывается, а отчёт показывает, что было бы сделано
+func (s *Service) Import(ctx context.Context, request ImportRequest) (report csvutil.ImportReport, err error) {
+	reader, err := csvutil.NewReader(request.File, importColumns, []string{"name"}, request.Mapping)
+	if err != nil {
+		return csvutil.ImportReport{}, err
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return csvutil.ImportReport{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("importing employees panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("importing employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil || request.DryRun {
+			// если произошла ошибка или это пробный запуск, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("importing employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("importing employees: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	report = csvutil.ImportReport{DryRun: request.DryRun, Rows: []csvutil.RowResult{}}
+	seen := importSeen{names: map[string]int{}, ids: map[int64]int{}}
+	for {
+		row, errRead := reader.Read()
+		if errors.Is(errRead, io.EOF) {
+			break
+		}
+		if errRead != nil {
+			err = errRead
+			return csvutil.ImportReport{}, err
+		}
+
+		result, errRow := s.importRow(ctx, tx, row, seen, request.Actor)
+		if errRow != nil {
+			err = fmt.Errorf("error importing line %d: %w", row.Line, errRow)
+			return csvutil.ImportReport{}, err
+		}
+		report.Add(result)
+	}
+	return report, nil
+}
+
+// importSeen строки, в которых уже встречались имена и id сотрудников
+type importSeen struct {
+	names map[string]int
+	ids   map[int64]int
+}
+
+// importRow создаёт сотрудника или изменяет существующего по строке CSV файла.
+// Столбцы org_unit, job_title и subject необязательны: если столбца нет в файле, значение не меняется.
+// Статус меняют только переходы жизненного цикла, поэтому столбец status лишь сверяется с текущим статусом
+func (s *Service) importRow(ctx context.Context, tx *sqlx.Tx, row csvutil.Row, seen importSeen, actor string) (csvutil.RowResult, error) {
+	request := CreateRequest{
+		Name:     row.Values["name"],
+		OrgUnit:  row.Values["org_unit"],
+		JobTitle: row.Values["job_title"],
+		Subject:  row.Values["subject"],
+	}
+	if err := s.validator.Validate(request); err != nil {
+		return csvutil.Invalid(row.Line, err), nil
+	}
+	status := row.Values["status"]
+
+	var id int64
+	if value := row.Values["id"]; value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("invalid employee id %s", value)), nil
+		}
+		id = parsed
+		if line, ok := seen.ids[id]; ok {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("employee with id %d is duplicated in line %d", id, line)), nil
+		}
+		seen.ids[id] = row.Line
+	}
+	key := strings.ToLower(request.Name)
+	if line, ok := seen.names[key]; ok {
+		return csvutil.Rejected(row.Line, fmt.Sprintf("employee with name %s is duplicated in line %d", request.Name, line)), nil
+	}
+	seen.names[key] = row.Line
+
+	// строка без id - новый сотрудник
+	if id == 0 {
+		isExist, err := s.repo.FindByName(ctx, tx, request.Name)
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error finding employee by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUnchanged,
+				Message: fmt.Sprintf("employee with name %s already exists", request.Name)}, nil
+		}
+		if status != "" && status != lifecycle.StatusActive {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("new employee is created %s, status is changed only by lifecycle transitions",
+				lifecycle.StatusActive)), nil
+		}
+		isExist, err = s.subjectExists(ctx, tx, request.Subject)
+		if err != nil {
+			return csvutil.RowResult{}, err
+		}
+		if isExist {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("employee with subject %s already exists", request.Subject)), nil
+		}
+		newId, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error creating employee with name %s: %w", request.Name, err)
+		}
+		if err := s.applyRules(ctx, tx, newId); err != nil {
+			return csvutil.RowResult{}, err
+		}
+		return csvutil.RowResult{Line: row.Line, Status: csvutil.RowCreated, Id: newId}, nil
+	}
+
+	// строка с id - изменение существующего сотрудника
+	employee, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return csvutil.Rejected(row.Line, fmt.Sprintf("employee with id %d not found", id)), nil
+	}
+	if err != nil {
+		return csvutil.RowResult{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	if status != "" && status != employee.Status {
+		return csvutil.Rejected(row.Line, fmt.Sprintf("status of employee with id %d is changed only by lifecycle transitions", id)), nil
+	}
+
+	renamed := employee.Name != request.Name
+	attributesChanged := false
+	if value, ok := row.Values["org_unit"]; ok && nullString(value) != employee.OrgUnit {
+		employee.OrgUnit = nullString(value)
+		attributesChanged = true
+	}
+	if value, ok := row.Values["job_title"]; ok && nullString(value) != employee.JobTitle {
+		employee.JobTitle = nullString(value)
+		attributesChanged = true
+	}
+	subject, ok := row.Values["subject"]
+	subjectChanged := ok && nullString(subject) != employee.Subject
+	if !renamed && !attributesChanged && !subjectChanged {
+		return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUnchanged, Id: id}, nil
+	}
+
+	if renamed && !strings.EqualFold(employee.Name, request.Name) {
+		isExist, err := s.repo.FindByName(ctx, tx, request.Name)
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error finding employee by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("employee with name %s already exists", request.Name)), nil
+		}
+	}
+	if subjectChanged {
+		isExist, err := s.subjectExists(ctx, tx, subject)
+		if err != nil {
+			return csvutil.RowResult{}, err
+		}
+		if isExist {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("employee with subject %s already exists", subject)), nil
+		}
+	}
+
+	if renamed || attributesChanged {
+		employee.Name = request.Name
+		if err := s.repo.UpdateTx(ctx, tx, employee); err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error updating employee with id %d: %w", id, err)
+		}
+	}
+	if subjectChanged {
+		if err := s.repo.UpdateSubjectTx(ctx, tx, id, nullString(subject)); err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error updating subject of employee with id %d: %w", id, err)
+		}
+	}
+	if attributesChanged {
+		if err := s.attributesChanged(ctx, tx, id, actor); err != nil {
+			return csvutil.RowResult{}, err
+		}
+	}
+	return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUpdated, Id: id}, nil
+}
@@ -2,6 +2,7 @@ package employee
 
 import (
 	"context"
+	"database/sql"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -31,28 +32,80 @@ func (s *StubRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
 	return []Entity{}, nil
 }
 
-func (s *StubRepo) DeleteById(ctx context.Context, id int64) error {
+func (s *StubRepo) FindByName(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	return false, nil
+}
+
+func (s *StubRepo) BeginTransaction() (*sqlx.Tx, error) {
+	return nil, nil
+}
+
+func (s *StubRepo) FindPage(ctx context.Context, offset int, limit int, textFilter string, scope Scope) ([]Entity, error) {
+	return []Entity{}, nil
+}
+
+func (s *StubRepo) CountAll(ctx context.Context, textFilter string, scope Scope) (int64, error) {
+	return 0, nil
+}
+
+func (s *StubRepo) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
 	return nil
 }
 
-func (s *StubRepo) DeleteByIds(ctx context.Context, ids []int64) error {
+func (s *StubRepo) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
 	return nil
 }
 
-func (s *StubRepo) FindByName(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
-	return false, nil
+func (s *StubRepo) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	return nil
 }
 
-func (s *StubRepo) BeginTransaction() (*sqlx.Tx, error) {
-	return nil, nil
+func (s *StubRepo) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	return Entity{}, nil
+}
+
+func (s *StubRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error {
+	return nil
+}
+
+func (s *StubRepo) FindVisibleById(ctx context.Context, id int64, scope Scope) (Entity, error) {
+	return Entity{}, nil
 }
 
-func (s *StubRepo) FindPage(ctx context.Context, offset int, limit int, textFilter string) ([]Entity, error) {
+func (s *StubRepo) FindVisibleByIds(ctx context.Context, ids []int64, scope Scope) ([]Entity, error) {
 	return []Entity{}, nil
 }
 
-func (s *StubRepo) CountAll(ctx context.Context, textFilter string) (int64, error) {
-	return 0, nil
+func (s *StubRepo) LockManagersTx(ctx context.Context, tx *sqlx.Tx) error {
+	return nil
+}
+
+func (s *StubRepo) IsReportTx(ctx context.Context, tx *sqlx.Tx, managerId int64, employeeId int64) (bool, error) {
+	return false, nil
+}
+
+func (s *StubRepo) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId sql.NullInt64) error {
+	return nil
+}
+
+func (s *StubRepo) FindBySubject(ctx context.Context, subject string) (Entity, error) {
+	return Entity{}, nil
+}
+
+func (s *StubRepo) SubjectExistsTx(ctx context.Context, tx *sqlx.Tx, subject string) (bool, error) {
+	return false, nil
+}
+
+func (s *StubRepo) UpdateSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64, subject sql.NullString) error {
+	return nil
+}
+
+func (s *StubRepo) Query(ctx context.Context, textFilter string, scope Scope) (*sqlx.Rows, error) {
+	return nil, nil
+}
+
+func (s *StubRepo) ForEach(ctx context.Context, textFilter string, scope Scope, fn func(Entity) error) error {
+	return nil
 }
 
 func TestStubFindById(t *testing.T) {
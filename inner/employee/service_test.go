@@ -2,16 +2,21 @@ package employee
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/brianvoe/gofakeit"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -40,16 +45,6 @@ func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) DeleteById(ctx context.Context, id int64) error {
-	args := m.Called(id)
-	return args.Error(0)
-}
-
-func (m *MockRepo) DeleteByIds(ctx context.Context, ids []int64) error {
-	args := m.Called(ids)
-	return args.Error(0)
-}
-
 func (m *MockRepo) FindByName(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
 	args := m.Called(tx, name)
 	return args.Bool(0), args.Error(1)
@@ -60,16 +55,132 @@ func (m *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
 	return args.Get(0).(*sqlx.Tx), args.Error(1)
 }
 
-func (m *MockRepo) FindPage(ctx context.Context, offset int, limit int, textFilter string) ([]Entity, error) {
+func (m *MockRepo) FindPage(ctx context.Context, offset int, limit int, textFilter string, scope Scope) ([]Entity, error) {
 	args := m.Called(offset, limit)
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
-func (m *MockRepo) CountAll(ctx context.Context, textFilter string) (int64, error) {
+func (m *MockRepo) CountAll(ctx context.Context, textFilter string, scope Scope) (int64, error) {
 	args := m.Called(textFilter)
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockRepo) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(tx, name)
+	return args.Error(0)
+}
+
+func (m *MockRepo) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(tx, name)
+	return args.Error(0)
+}
+
+func (m *MockRepo) ReleaseSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	args := m.Called(tx, name)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error {
+	args := m.Called(tx, employee)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindVisibleById(ctx context.Context, id int64, scope Scope) (Entity, error) {
+	args := m.Called(id, scope)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindVisibleByIds(ctx context.Context, ids []int64, scope Scope) ([]Entity, error) {
+	args := m.Called(ids, scope)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) LockManagersTx(ctx context.Context, tx *sqlx.Tx) error {
+	args := m.Called(tx)
+	return args.Error(0)
+}
+
+func (m *MockRepo) IsReportTx(ctx context.Context, tx *sqlx.Tx, managerId int64, employeeId int64) (bool, error) {
+	args := m.Called(tx, managerId, employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateManagerTx(ctx context.Context, tx *sqlx.Tx, id int64, managerId sql.NullInt64) error {
+	args := m.Called(tx, id, managerId)
+	return args.Error(0)
+}
+
+func (m *MockRepo) FindBySubject(ctx context.Context, subject string) (Entity, error) {
+	args := m.Called(subject)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) SubjectExistsTx(ctx context.Context, tx *sqlx.Tx, subject string) (bool, error) {
+	args := m.Called(tx, subject)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateSubjectTx(ctx context.Context, tx *sqlx.Tx, id int64, subject sql.NullString) error {
+	args := m.Called(tx, id, subject)
+	return args.Error(0)
+}
+
+func (m *MockRepo) Query(ctx context.Context, textFilter string, scope Scope) (*sqlx.Rows, error) {
+	args := m.Called(textFilter)
+	rows, _ := args.Get(0).(*sqlx.Rows)
+	return rows, args.Error(1)
+}
+
+func (m *MockRepo) ForEach(ctx context.Context, textFilter string, scope Scope, fn func(Entity) error) error {
+	args := m.Called(textFilter)
+	for _, employee := range args.Get(0).([]Entity) {
+		if err := fn(employee); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+type MockRoleRules struct {
+	mock.Mock
+}
+
+func (m *MockRoleRules) ApplyTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	args := m.Called(employeeId)
+	return args.Error(0)
+}
+
+type MockLifecycle struct {
+	mock.Mock
+}
+
+func (m *MockLifecycle) TransitionTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(lifecycle.StatusResponse), args.Error(1)
+}
+
+func (m *MockLifecycle) AttributesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, actor string) error {
+	args := m.Called(employeeId, actor)
+	return args.Error(0)
+}
+
+type MockAuthorizer struct {
+	mock.Mock
+}
+
+func (m *MockAuthorizer) AuthorizeOrgUnit(ctx context.Context, principal common.Principal, orgUnit string) error {
+	args := m.Called(principal.Subject, orgUnit)
+	return args.Error(0)
+}
+
+// администратор, которому доступны все операции
+var admin = common.Principal{Actor: "admin", Admin: true}
+
 func TestFindById(t *testing.T) {
 	a := assert.New(t)
 
@@ -182,66 +293,66 @@ func TestFindByIds(t *testing.T) {
 func TestDeleteById(t *testing.T) {
 	a := assert.New(t)
 
-	t.Run("should return error nil", func(t *testing.T) {
-		repo := &MockRepo{}
-		srv := NewService(repo, nil)
-		deleteById := int64(1)
+	newService := func() (*Service, *MockLifecycle, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), nil)
+		lifecycleSvc := &MockLifecycle{}
+		srv.SetLifecycle(lifecycleSvc)
+		return srv, lifecycleSvc, mock
+	}
+	terminate := func(id int64) lifecycle.TransitionRequest {
+		return lifecycle.TransitionRequest{EmployeeId: id, Status: lifecycle.StatusTerminated, Reason: "deleted", Actor: "admin"}
+	}
+
+	t.Run("should terminate employee instead of deleting", func(t *testing.T) {
+		srv, lifecycleSvc, mock := newService()
+		mock.ExpectBegin()
+		lifecycleSvc.On("TransitionTx", terminate(1)).Return(lifecycle.StatusResponse{EmployeeId: 1}, nil)
+		mock.ExpectCommit()
 
-		repo.On("DeleteById", deleteById).Return(nil)
-		err := srv.DeleteById(context.Background(), deleteById)
+		err := srv.DeleteById(context.Background(), 1, "admin")
 
 		a.Nil(err)
-		a.True(repo.AssertNumberOfCalls(t, "DeleteById", 1))
+		lifecycleSvc.AssertExpectations(t)
+		a.NoError(mock.ExpectationsWereMet())
 	})
 
-	t.Run("should return error", func(t *testing.T) {
-		repo := &MockRepo{}
-		srv := NewService(repo, nil)
-		deleteById := int64(1)
-
-		err := errors.New("database error")
-
-		want := fmt.Errorf("error deleting employee with id %d: %w", deleteById, err)
+	t.Run("should return not found for unknown employee", func(t *testing.T) {
+		srv, lifecycleSvc, mock := newService()
+		mock.ExpectBegin()
+		lifecycleSvc.On("TransitionTx", terminate(1)).
+			Return(lifecycle.StatusResponse{}, common.NotFoundError{Message: "employee with id 1 not found"})
+		mock.ExpectRollback()
 
-		repo.On("DeleteById", deleteById).Return(err)
-		got := srv.DeleteById(context.Background(), deleteById)
+		err := srv.DeleteById(context.Background(), 1, "admin")
 
-		a.NotNil(err)
-		a.Equal(want, got)
-		a.True(repo.AssertNumberOfCalls(t, "DeleteById", 1))
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
 	})
-}
-
-func TestDeleteByIds(t *testing.T) {
-	a := assert.New(t)
 
-	t.Run("should return error nil", func(t *testing.T) {
-		repo := &MockRepo{}
-		srv := NewService(repo, nil)
-		deleteByIds := []int64{1, 2, 3}
+	t.Run("should terminate all employees in one transaction", func(t *testing.T) {
+		srv, lifecycleSvc, mock := newService()
+		mock.ExpectBegin()
+		lifecycleSvc.On("TransitionTx", terminate(1)).Return(lifecycle.StatusResponse{EmployeeId: 1}, nil)
+		lifecycleSvc.On("TransitionTx", terminate(2)).Return(lifecycle.StatusResponse{}, errors.New("database error"))
+		mock.ExpectRollback()
 
-		repo.On("DeleteByIds", deleteByIds).Return(nil)
-		err := srv.DeleteByIds(context.Background(), deleteByIds)
+		err := srv.DeleteByIds(context.Background(), []int64{1, 2, 3}, "admin")
 
-		a.Nil(err)
-		a.True(repo.AssertNumberOfCalls(t, "DeleteByIds", 1))
+		a.ErrorContains(err, "error terminating employee with id 2: database error")
+		lifecycleSvc.AssertNotCalled(t, "TransitionTx", terminate(3))
+		a.NoError(mock.ExpectationsWereMet())
 	})
 
-	t.Run("should return error nil", func(t *testing.T) {
-		repo := &MockRepo{}
-		srv := NewService(repo, nil)
-		deleteByIds := []int64{1, 2, 3}
-
-		err := errors.New("database error")
-
-		want := fmt.Errorf("error deleting employee with id %d: %w", deleteByIds, err)
+	t.Run("should reject empty ids", func(t *testing.T) {
+		srv, lifecycleSvc, _ := newService()
 
-		repo.On("DeleteByIds", deleteByIds).Return(err)
-		got := srv.DeleteByIds(context.Background(), deleteByIds)
+		err := srv.DeleteByIds(context.Background(), []int64{}, "admin")
 
-		a.NotNil(err)
-		a.Equal(want, got)
-		a.True(repo.AssertNumberOfCalls(t, "DeleteByIds", 1))
+		a.ErrorContains(err, "employee ids cannot be empty")
+		lifecycleSvc.AssertNotCalled(t, "TransitionTx", mock.Anything)
 	})
 }
 
@@ -263,24 +374,72 @@ func TestCreateIfNotEmployee(t *testing.T) {
 		mock.ExpectBegin()
 
 		// Настраиваем mock для проверки существования сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE name = $1)")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
 			WithArgs(entity.Name).
 			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 
 		// Настраиваем mock для создания сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name) VALUES ($1) RETURNING id")).
-			WithArgs(entity.Name).
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")).
+			WithArgs(entity.Name, nil, nil, nil).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(entity.Id))
 
 		// Настраиваем mock для коммита транзакции
 		mock.ExpectCommit()
 
-		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name})
+		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name}, admin)
 		a.Nil(err)
 		a.NotNil(id)
 		a.Equal(entity.Id, id)
 	})
 
+	// имя может быть занято параллельным запросом после проверки
+	t.Run("should return AlreadyExistsError on unique violation", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
+			WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")).
+			WithArgs("John", nil, nil, nil).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "employee_name_lower_idx"})
+		mock.ExpectRollback()
+
+		_, err = srv.Create(context.Background(), CreateRequest{Name: "John"}, admin)
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.Equal("employee with name John already exists", existsErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	// новому сотруднику назначаются роли по правилам
+	t.Run("should apply role rules to created employee", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+
+		srv := NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
+			WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")).
+			WithArgs("John", "IT", "Developer", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(5)))
+		mock.ExpectCommit()
+		rules.On("ApplyTx", int64(5)).Return(nil)
+
+		id, err := srv.Create(context.Background(), CreateRequest{Name: "John", OrgUnit: "IT", JobTitle: "Developer"}, admin)
+		a.Nil(err)
+		a.Equal(int64(5), id)
+		a.NoError(mock.ExpectationsWereMet())
+		rules.AssertExpectations(t)
+	})
+
 	// не сохраняется сотрудник т.к. уже есть с таким именеи
 	t.Run("should return zero error nil", func(t *testing.T) {
 		db, mock, err := sqlmock.New()
@@ -296,14 +455,14 @@ func TestCreateIfNotEmployee(t *testing.T) {
 		mock.ExpectBegin()
 
 		// Настраиваем mock для проверки существования сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE name = $1)")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
 			WithArgs(entity.Name).
 			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
 
 		// Настраиваем mock для коммита транзакции
 		mock.ExpectCommit()
 
-		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name})
+		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name}, admin)
 		a.NotNil(err)
 		a.NotNil(id)
 		a.Equal(int64(0), id)
@@ -323,16 +482,16 @@ func TestCreateIfNotEmployee(t *testing.T) {
 		mock.ExpectBegin()
 
 		// Настраиваем mock для проверки существования сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE name = $1)")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
 			WithArgs(entity.Name).
 			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 
 		// Настраиваем mock для создания сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name) VALUES ($1) RETURNING id")).
-			WithArgs(entity.Name).
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")).
+			WithArgs(entity.Name, nil, nil, nil).
 			WillReturnError(errors.New("error insert failed"))
 
-		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name})
+		id, err := srv.Create(context.Background(), CreateRequest{Name: entity.Name}, admin)
 		a.Equal(int64(0), id)
 		a.NotNil(err)
 		a.ErrorContains(err, "error insert failed")
@@ -350,7 +509,7 @@ func TestCreateIfNotEmployee(t *testing.T) {
 
 		mock.ExpectBegin().WillReturnError(fmt.Errorf("error create tx"))
 
-		_, err = service.Create(context.Background(), CreateRequest{Name: getEntity().Name})
+		_, err = service.Create(context.Background(), CreateRequest{Name: getEntity().Name}, admin)
 		a.NotNil(err)
 		a.ErrorContains(err, "error create tx")
 	})
@@ -369,17 +528,903 @@ func TestCreateIfNotEmployee(t *testing.T) {
 		mock.ExpectBegin()
 
 		// Настраиваем mock для проверки существования сотрудника
-		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE name = $1)")).
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
 			WithArgs(entity.Name).
 			WillReturnError(errors.New("error find failed"))
 
-		id, err := service.Create(context.Background(), CreateRequest{Name: entity.Name})
+		id, err := service.Create(context.Background(), CreateRequest{Name: entity.Name}, admin)
 		a.Equal(int64(0), id)
 		a.NotNil(err)
 		a.ErrorContains(err, "error find failed")
 	})
 }
 
+func TestCreateAuthorization(t *testing.T) {
+	a := assert.New(t)
+	scopedAdmin := common.Principal{Subject: "kc-1", Actor: "kc-1"}
+
+	t.Run("should reject non-admin without authorizer", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "John", OrgUnit: "IT"}, scopedAdmin)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		repo.AssertNotCalled(t, "BeginTransaction")
+	})
+
+	t.Run("should return forbidden error for org unit out of scope", func(t *testing.T) {
+		repo := &MockRepo{}
+		authorizer := &MockAuthorizer{}
+		srv := NewService(repo, validator.NewValidator())
+		srv.SetAuthorizer(authorizer)
+		authorizer.On("AuthorizeOrgUnit", "kc-1", "Sales").Return(common.ForbiddenError{Message: "org unit Sales is out of scope"})
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "John", OrgUnit: " Sales "}, scopedAdmin)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		repo.AssertNotCalled(t, "BeginTransaction")
+	})
+
+	t.Run("should reject whole batch if one org unit is out of scope", func(t *testing.T) {
+		repo := &MockRepo{}
+		authorizer := &MockAuthorizer{}
+		srv := NewService(repo, validator.NewValidator())
+		srv.SetAuthorizer(authorizer)
+		authorizer.On("AuthorizeOrgUnit", "kc-1", "IT").Return(nil)
+		authorizer.On("AuthorizeOrgUnit", "kc-1", "Sales").Return(common.ForbiddenError{Message: "org unit Sales is out of scope"})
+
+		_, err := srv.CreateBatch(context.Background(), BatchCreateRequest{Items: []CreateRequest{
+			{Name: "John", OrgUnit: "IT"},
+			{Name: "Jane", OrgUnit: "IT"},
+			{Name: "Bob", OrgUnit: "Sales"},
+		}}, scopedAdmin)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		authorizer.AssertNumberOfCalls(t, "AuthorizeOrgUnit", 2)
+		repo.AssertNotCalled(t, "BeginTransaction")
+	})
+}
+
+func TestProvision(t *testing.T) {
+	a := assert.New(t)
+
+	// сотрудник создаётся для самого пользователя без отдела, права администраторов отделов не проверяются
+	t.Run("should create employee without authorization", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		authorizer := &MockAuthorizer{}
+		srv.SetAuthorizer(authorizer)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")).
+			WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE subject = $1)")).
+			WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject)")).
+			WithArgs("John", nil, nil, "kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		id, err := srv.Provision(context.Background(), "John", "kc-1")
+
+		a.Nil(err)
+		a.Equal(int64(7), id)
+		a.Nil(mock.ExpectationsWereMet())
+		authorizer.AssertNumberOfCalls(t, "AuthorizeOrgUnit", 0)
+	})
+
+	t.Run("should reject employee without subject", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		_, err := srv.Provision(context.Background(), "John", " ")
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		repo.AssertNotCalled(t, "BeginTransaction")
+	})
+}
+
+func TestCreateBatch(t *testing.T) {
+	a := assert.New(t)
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")
+	insertQuery := regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		repo := NewEmployeeRepository(sqlx.NewDb(db, "sqlmock"))
+		return NewService(repo, validator.NewValidator()), mock
+	}
+
+	t.Run("should create all employees in one transaction", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(existsQuery).WithArgs("Jane").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("Jane", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Items: []CreateRequest{{Name: "John"}, {Name: "Jane"}},
+		}, admin)
+		a.Nil(err)
+		a.Equal(BatchModeAllOrNothing, got.Mode)
+		a.True(got.Committed)
+		a.Equal(2, got.Created)
+		a.Equal(0, got.Failed)
+		a.Equal([]BatchItemResult{
+			{Index: 0, Status: BatchItemCreated, Id: 1},
+			{Index: 1, Status: BatchItemCreated, Id: 2},
+		}, got.Items)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not open transaction if item is invalid in all_or_nothing mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Mode:  BatchModeAllOrNothing,
+			Items: []CreateRequest{{Name: "John"}, {Name: "J"}, {Name: "John"}},
+		}, admin)
+		a.Nil(err)
+		a.False(got.Committed)
+		a.Equal(0, got.Created)
+		a.Equal(2, got.Failed)
+		a.Equal(BatchItemRolledBack, got.Items[0].Status)
+		a.Equal(BatchItemInvalid, got.Items[1].Status)
+		a.Len(got.Items[1].Errors, 1)
+		a.Equal("$.name", got.Items[1].Errors[0].JsonPath)
+		a.Equal(BatchItemDuplicate, got.Items[2].Status)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should find duplicates ignoring case", func(t *testing.T) {
+		srv, mock := newService()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Mode:  BatchModeAllOrNothing,
+			Items: []CreateRequest{{Name: "John"}, {Name: "JOHN"}},
+		}, admin)
+		a.Nil(err)
+		a.False(got.Committed)
+		a.Equal(BatchItemResult{Index: 1, Status: BatchItemDuplicate,
+			Message: "employee with name JOHN is duplicated in item 0"}, got.Items[1])
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back transaction if employee exists in all_or_nothing mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(existsQuery).WithArgs("Jane").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Items: []CreateRequest{{Name: "John"}, {Name: "Jane"}},
+		}, admin)
+		a.Nil(err)
+		a.False(got.Committed)
+		a.Equal([]BatchItemResult{
+			{Index: 0, Status: BatchItemRolledBack},
+			{Index: 1, Status: BatchItemDuplicate, Message: "employee with name Jane already exists"},
+		}, got.Items)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return error and roll back if insert failed in all_or_nothing mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnError(errors.New("error insert failed"))
+		mock.ExpectRollback()
+
+		_, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Items: []CreateRequest{{Name: "John"}},
+		}, admin)
+		a.ErrorContains(err, "error insert failed")
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should skip failed items in best_effort mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		// первый элемент создаётся
+		mock.ExpectExec("SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec("RELEASE SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		// второй элемент откатывается к точке сохранения
+		mock.ExpectExec("SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(existsQuery).WithArgs("Jane").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("Jane", nil, nil, nil).
+			WillReturnError(errors.New("error insert failed"))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Mode:  BatchModeBestEffort,
+			Items: []CreateRequest{{Name: "John"}, {Name: "Jane"}, {Name: ""}},
+		}, admin)
+		a.Nil(err)
+		a.True(got.Committed)
+		a.Equal(1, got.Created)
+		a.Equal(2, got.Failed)
+		a.Equal(BatchItemResult{Index: 0, Status: BatchItemCreated, Id: 1}, got.Items[0])
+		a.Equal(BatchItemResult{Index: 1, Status: BatchItemFailed, Message: "failed to create employee",
+			err: got.Items[1].err}, got.Items[1])
+		a.ErrorContains(got.Items[1].err, "error insert failed")
+		a.Equal(BatchItemInvalid, got.Items[2].Status)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should report employee created concurrently as duplicate in all_or_nothing mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectRollback()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Items: []CreateRequest{{Name: "John"}, {Name: "Jane"}},
+		}, admin)
+		a.Nil(err)
+		a.False(got.Committed)
+		a.Equal([]BatchItemResult{
+			{Index: 0, Status: BatchItemDuplicate, Message: "employee with name John already exists"},
+			{Index: 1, Status: BatchItemRolledBack},
+		}, got.Items)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should report employee created concurrently as duplicate in best_effort mode", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnError(&pq.Error{Code: "23505"})
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT employee_batch_item").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		got, err := srv.CreateBatch(context.Background(), BatchCreateRequest{
+			Mode:  BatchModeBestEffort,
+			Items: []CreateRequest{{Name: "John"}},
+		}, admin)
+		a.Nil(err)
+		a.True(got.Committed)
+		a.Equal(0, got.Created)
+		a.Equal(1, got.Failed)
+		a.Equal(BatchItemResult{Index: 0, Status: BatchItemDuplicate, Message: "employee with name John already exists"},
+			got.Items[0])
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return validation error for invalid batch", func(t *testing.T) {
+		srv, _ := newService()
+
+		for _, request := range []BatchCreateRequest{
+			{Items: []CreateRequest{}},
+			{Mode: "unknown", Items: []CreateRequest{{Name: "John"}}},
+			{Items: make([]CreateRequest, 501)},
+		} {
+			_, err := srv.CreateBatch(context.Background(), request, admin)
+			var validateErr common.RequestValidatorError
+			a.True(errors.As(err, &validateErr))
+			a.Len(validateErr.Fields, 1)
+		}
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	a := assert.New(t)
+	findQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id=$1")
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4")
+	columns := []string{"id", "name", "create_at", "update_at"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		return NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator()), mock
+	}
+
+	t.Run("should rename employee", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs("new name", nil, nil, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"}, "admin")
+
+		a.Nil(err)
+		a.Equal("new name", got.Name)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should change org unit and apply role rules", func(t *testing.T) {
+		srv, mock := newService()
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+		orgUnit := "IT"
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "name", time.Now(), time.Now()))
+		mock.ExpectExec(updateQuery).WithArgs("name", "IT", nil, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		rules.On("ApplyTx", int64(1)).Return(nil)
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "name", OrgUnit: &orgUnit}, "admin")
+
+		a.Nil(err)
+		a.Equal("IT", got.OrgUnit)
+		a.NoError(mock.ExpectationsWereMet())
+		rules.AssertExpectations(t)
+	})
+
+	t.Run("should run lifecycle hooks instead of role rules when lifecycle is configured", func(t *testing.T) {
+		srv, mock := newService()
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+		lifecycleSvc := &MockLifecycle{}
+		srv.SetLifecycle(lifecycleSvc)
+		jobTitle := "Developer"
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "name", time.Now(), time.Now()))
+		mock.ExpectExec(updateQuery).WithArgs("name", nil, "Developer", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		lifecycleSvc.On("AttributesChangedTx", int64(1), "admin").Return(nil)
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "name", JobTitle: &jobTitle}, "admin")
+
+		a.Nil(err)
+		a.Equal("Developer", got.JobTitle)
+		a.NoError(mock.ExpectationsWereMet())
+		lifecycleSvc.AssertExpectations(t)
+		rules.AssertNotCalled(t, "ApplyTx", int64(1))
+	})
+
+	t.Run("should not apply role rules on rename", func(t *testing.T) {
+		srv, mock := newService()
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs("new name", nil, nil, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"}, "admin")
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+		rules.AssertNotCalled(t, "ApplyTx", int64(1))
+	})
+
+	t.Run("should return AlreadyExistsError for taken name", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"}, "admin")
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should change case of employee name without name check", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "john smith", time.Now(), time.Now()))
+		mock.ExpectExec(updateQuery).WithArgs("John Smith", nil, nil, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "John Smith"}, "admin")
+
+		a.Nil(err)
+		a.Equal("John Smith", got.Name)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown employee", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"}, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestLinkSubject(t *testing.T) {
+	a := assert.New(t)
+	findQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id=$1")
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE subject = $1)")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET subject = $1, update_at = now() WHERE id = $2")
+	columns := []string{"id", "name", "create_at", "update_at"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		return NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator()), mock
+	}
+
+	t.Run("should link employee to subject", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs("kc-1", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.LinkSubject(context.Background(), 1, LinkSubjectRequest{Subject: "kc-1"})
+
+		a.Nil(err)
+		a.Equal("kc-1", got.Subject)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return already exists error if subject is linked to another employee", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.LinkSubject(context.Background(), 1, LinkSubjectRequest{Subject: "kc-1"})
+
+		var alreadyExistsErr common.AlreadyExistsError
+		a.True(errors.As(err, &alreadyExistsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should unlink subject if it is empty", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(append(columns, "subject")).AddRow(1, "John", time.Now(), time.Now(), "kc-1"))
+		mock.ExpectExec(updateQuery).WithArgs(nil, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.LinkSubject(context.Background(), 1, LinkSubjectRequest{})
+
+		a.Nil(err)
+		a.Empty(got.Subject)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return not found error if subject is not linked", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE subject = $1")).WithArgs("kc-2").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := srv.FindBySubject(context.Background(), "kc-2")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestFindPageVisibility(t *testing.T) {
+	a := assert.New(t)
+	subjectQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE subject = $1")
+	pageQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE 1=1 AND (id = $1 OR org_unit = $2) OFFSET $3 LIMIT $4")
+	countQuery := regexp.QuoteMeta("SELECT COUNT(*) FROM employee WHERE 1=1 AND (id = $1 OR org_unit = $2)")
+	policies := VisibilityPolicies{"IDM_ADMIN": {VisibleAll}, "IDM_USER": {VisibleSelf, VisibleOrgUnit}}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		srv.SetVisibility(policies)
+		return srv, mock
+	}
+
+	t.Run("should push visibility of user into page and count queries", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "org_unit"}).AddRow(7, "John", "IT"))
+		mock.ExpectQuery(pageQuery).WithArgs(int64(7), "IT", 0, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "John").AddRow(8, "Jane"))
+		mock.ExpectQuery(countQuery).WithArgs(int64(7), "IT").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		page, err := srv.FindPage(context.Background(), PageRequest{PageSize: 10}, common.Principal{Subject: "kc-1", Roles: []string{"IDM_USER"}})
+
+		a.Nil(err)
+		a.Equal(int64(2), page.Total)
+		a.Len(page.Result, 2)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not restrict admin", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE 1=1 OFFSET $1 LIMIT $2")).WithArgs(0, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM employee WHERE 1=1")).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		_, err := srv.FindPage(context.Background(), PageRequest{PageSize: 10}, common.Principal{Subject: "kc-1", Roles: []string{"IDM_ADMIN", "IDM_USER"}})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return not found for employee hidden from user", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "org_unit"}).AddRow(7, "John", "IT"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = $1 AND (id = $2 OR org_unit = $3)")).
+			WithArgs(int64(9), int64(7), "IT").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := srv.FindVisibleById(context.Background(), 9, common.Principal{Subject: "kc-1", Roles: []string{"IDM_USER"}})
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return only employees visible to user by ids", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "org_unit"}).AddRow(7, "John", "IT"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE id = ANY($1) AND (id = $2 OR org_unit = $3)")).
+			WithArgs(pq.Int64Array{7, 9}, int64(7), "IT").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(7, "John"))
+
+		got, err := srv.FindVisibleByIds(context.Background(), []int64{7, 9}, common.Principal{Subject: "kc-1", Roles: []string{"IDM_USER"}})
+
+		a.Nil(err)
+		a.Len(got, 1)
+		a.Equal(int64(7), got[0].Id)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return validation error for empty ids", func(t *testing.T) {
+		srv, mock := newService()
+
+		_, err := srv.FindVisibleByIds(context.Background(), []int64{}, common.Principal{Subject: "kc-1", Roles: []string{"IDM_USER"}})
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestSetManager(t *testing.T) {
+	a := assert.New(t)
+	lockQuery := regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext('employee_manager'))")
+	findQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id=$1")
+	reportQuery := regexp.QuoteMeta("WITH RECURSIVE reports (id) AS (")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET manager_id = $1, update_at = now() WHERE id = $2")
+	columns := []string{"id", "name"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		return NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator()), mock
+	}
+
+	t.Run("should set manager", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John"))
+		mock.ExpectQuery(findQuery).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Jane"))
+		mock.ExpectQuery(reportQuery).WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs(int64(2), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.SetManager(context.Background(), 1, ManagerRequest{ManagerId: 2})
+
+		a.Nil(err)
+		a.Equal(int64(2), got.ManagerId)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return conflict error if manager reports to employee", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John"))
+		mock.ExpectQuery(findQuery).WithArgs(int64(2)).WillReturnRows(sqlmock.NewRows(columns).AddRow(2, "Jane"))
+		mock.ExpectQuery(reportQuery).WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.SetManager(context.Background(), 1, ManagerRequest{ManagerId: 2})
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return conflict error if employee manages itself", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "John"))
+		mock.ExpectRollback()
+
+		_, err := srv.SetManager(context.Background(), 1, ManagerRequest{ManagerId: 1})
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestImport(t *testing.T) {
+	a := assert.New(t)
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE lower(name) = lower($1))")
+	insertQuery := regexp.QuoteMeta("INSERT INTO employee (name, org_unit, job_title, subject) VALUES ($1, $2, $3, $4) RETURNING id")
+	findQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE id=$1")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4")
+	columns := []string{"id", "name", "create_at", "update_at"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		repo := NewEmployeeRepository(sqlx.NewDb(db, "sqlmock"))
+		return NewService(repo, validator.NewValidator()), mock
+	}
+
+	t.Run("should create, update and reject rows", func(t *testing.T) {
+		srv, mock := newService()
+		file := "Код,ФИО\n" +
+			",John\n" + // новый сотрудник
+			"7,Jane\n" + // переименование
+			"8,Bob\n" + // без изменений
+			"9,Alice\n" + // нет сотрудника с таким id
+			",J\n" + // не прошёл валидацию
+			",John\n" + // повтор имени в файле
+			"x,Mike\n" // некорректный id
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(7, "Jane Doe", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("Jane").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs("Jane", nil, nil, int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(8)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(8, "Bob", time.Now(), time.Now()))
+		mock.ExpectQuery(findQuery).WithArgs(int64(9)).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		report, err := srv.Import(context.Background(), ImportRequest{
+			File:    strings.NewReader(file),
+			Mapping: map[string]string{"id": "Код", "name": "ФИО"},
+		})
+		a.Nil(err)
+		a.False(report.DryRun)
+		a.Equal(1, report.Created)
+		a.Equal(1, report.Updated)
+		a.Equal(1, report.Unchanged)
+		a.Equal(4, report.Rejected)
+		a.Equal(csvutil.RowResult{Line: 2, Status: csvutil.RowCreated, Id: 1}, report.Rows[0])
+		a.Equal(csvutil.RowResult{Line: 3, Status: csvutil.RowUpdated, Id: 7}, report.Rows[1])
+		a.Equal(csvutil.RowResult{Line: 4, Status: csvutil.RowUnchanged, Id: 8}, report.Rows[2])
+		a.Equal(csvutil.Rejected(5, "employee with id 9 not found"), report.Rows[3])
+		a.Equal(csvutil.RowRejected, report.Rows[4].Status)
+		a.Equal("$.name", report.Rows[4].Errors[0].JsonPath)
+		a.Equal(csvutil.Rejected(7, "employee with name John is duplicated in line 2"), report.Rows[5])
+		a.Equal(csvutil.Rejected(8, "invalid employee id x"), report.Rows[6])
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should import exported file with org unit, job title, subject and status", func(t *testing.T) {
+		srv, mock := newService()
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+		subjectQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE subject = $1)")
+		subjectUpdateQuery := regexp.QuoteMeta("UPDATE employee SET subject = $1, update_at = now() WHERE id = $2")
+		fullColumns := []string{"id", "name", "org_unit", "job_title", "status", "subject", "create_at", "update_at"}
+		file := "id,name,org_unit,job_title,subject,status,create_at,update_at\n" +
+			"7,Jane,Sales,Manager,kc-7,active,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n" + // смена отдела и subject
+			"8,Bob,IT,,,active,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n" + // без изменений
+			"9,Mike,IT,,,active,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n" + // статус меняет только жизненный цикл
+			",Alice,IT,,,suspended,,\n" // новый сотрудник создаётся активным
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(fullColumns).AddRow(7, "Jane", "IT", "Manager", "active", nil, time.Now(), time.Now()))
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-7").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs("Jane", "Sales", "Manager", int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(subjectUpdateQuery).WithArgs("kc-7", int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(8)).
+			WillReturnRows(sqlmock.NewRows(fullColumns).AddRow(8, "Bob", "IT", nil, "active", nil, time.Now(), time.Now()))
+		mock.ExpectQuery(findQuery).WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows(fullColumns).AddRow(9, "Mike", "IT", nil, "suspended", nil, time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("Alice").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectCommit()
+		rules.On("ApplyTx", int64(7)).Return(nil)
+
+		report, err := srv.Import(context.Background(), ImportRequest{File: strings.NewReader(file), Actor: "kc-admin"})
+		a.Nil(err)
+		a.Equal(csvutil.RowResult{Line: 2, Status: csvutil.RowUpdated, Id: 7}, report.Rows[0])
+		a.Equal(csvutil.RowResult{Line: 3, Status: csvutil.RowUnchanged, Id: 8}, report.Rows[1])
+		a.Equal(csvutil.Rejected(4, "status of employee with id 9 is changed only by lifecycle transitions"), report.Rows[2])
+		a.Equal(csvutil.Rejected(5, "new employee is created active, status is changed only by lifecycle transitions"), report.Rows[3])
+		rules.AssertExpectations(t)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should apply role rules to imported employee", func(t *testing.T) {
+		srv, mock := newService()
+		rules := &MockRoleRules{}
+		srv.SetRoleRules(rules)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectCommit()
+		rules.On("ApplyTx", int64(5)).Return(nil)
+
+		report, err := srv.Import(context.Background(), ImportRequest{File: strings.NewReader("name\nJohn\n")})
+		a.Nil(err)
+		a.Equal(1, report.Created)
+		rules.AssertExpectations(t)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back transaction in dry run", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("John", nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectRollback()
+
+		report, err := srv.Import(context.Background(), ImportRequest{
+			File:   strings.NewReader("name\nJohn\n"),
+			DryRun: true,
+		})
+		a.Nil(err)
+		a.True(report.DryRun)
+		a.Equal(1, report.Created)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return error and roll back if database failed", func(t *testing.T) {
+		srv, mock := newService()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("John").
+			WillReturnError(errors.New("error find failed"))
+		mock.ExpectRollback()
+
+		_, err := srv.Import(context.Background(), ImportRequest{File: strings.NewReader("name\nJohn\n")})
+		a.ErrorContains(err, "error find failed")
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return validation error if header has no name column", func(t *testing.T) {
+		srv, mock := newService()
+
+		_, err := srv.Import(context.Background(), ImportRequest{File: strings.NewReader("id,title\n1,John\n")})
+		var validateErr common.RequestValidatorError
+		a.True(errors.As(err, &validateErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestExport(t *testing.T) {
+	a := assert.New(t)
+	selectQuery := regexp.QuoteMeta("SELECT * FROM employee WHERE 1=1 AND name ILIKE $1 ORDER BY id")
+	columns := []string{"id", "name", "org_unit", "job_title", "status", "subject", "create_at", "update_at"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		return NewService(NewEmployeeRepository(sqlx.NewDb(db, "sqlmock")), nil), mock
+	}
+
+	t.Run("should write employees to csv", func(t *testing.T) {
+		srv, mock := newService()
+		createAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(selectQuery).WithArgs("%john%").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, "John Doe", "IT", "Developer", "active", "kc-1", createAt, createAt).
+			AddRow(2, "Doe, John", nil, nil, "suspended", nil, createAt, createAt))
+
+		export, err := srv.Export(context.Background(), " john ", common.Principal{})
+		a.Nil(err)
+		defer export.Close()
+		var buf strings.Builder
+		err = export.Write(&buf)
+
+		a.Nil(err)
+		a.Equal("id,name,org_unit,job_title,subject,status,create_at,update_at\n"+
+			"1,John Doe,IT,Developer,kc-1,active,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n"+
+			"2,\"Doe, John\",,,,suspended,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n", buf.String())
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return error before writing if query failed", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM employee WHERE 1=1 ORDER BY id")).
+			WillReturnError(errors.New("database error"))
+
+		_, err := srv.Export(context.Background(), "", common.Principal{})
+
+		a.ErrorContains(err, "database error")
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
 func TestRepositoryFindPage(t *testing.T) {
 	a := assert.New(t)
 
@@ -390,12 +1435,15 @@ func TestRepositoryFindPage(t *testing.T) {
 			PageSize:   0,
 			PageNumber: 1,
 		}
-		_, err := srv.FindPage(context.Background(), request)
+		_, err := srv.FindPage(context.Background(), request, common.Principal{})
 		a.NotNil(err)
 		var validateErr common.RequestValidatorError
 		ok := errors.As(err, &validateErr)
 		a.True(ok)
-		a.Contains(validateErr.Message, "Field validation")
+		a.Len(validateErr.Fields, 1)
+		a.Equal("pageSize", validateErr.Fields[0].Field)
+		a.Equal("min", validateErr.Fields[0].Rule)
+		a.Equal("1", validateErr.Fields[0].Param)
 	})
 
 	t.Run("should return err validation PageSize > 100", func(t *testing.T) {
@@ -405,12 +1453,15 @@ func TestRepositoryFindPage(t *testing.T) {
 			PageSize:   101,
 			PageNumber: 1,
 		}
-		_, err := srv.FindPage(context.Background(), request)
+		_, err := srv.FindPage(context.Background(), request, common.Principal{})
 		a.NotNil(err)
 		var validateErr common.RequestValidatorError
 		ok := errors.As(err, &validateErr)
 		a.True(ok)
-		a.Contains(validateErr.Message, "Field validation")
+		a.Len(validateErr.Fields, 1)
+		a.Equal("pageSize", validateErr.Fields[0].Field)
+		a.Equal("max", validateErr.Fields[0].Rule)
+		a.Equal("100", validateErr.Fields[0].Param)
 	})
 
 	t.Run("should return err validation PageNumber < 0", func(t *testing.T) {
@@ -420,12 +1471,15 @@ func TestRepositoryFindPage(t *testing.T) {
 			PageSize:   1,
 			PageNumber: -1,
 		}
-		_, err := srv.FindPage(context.Background(), request)
+		_, err := srv.FindPage(context.Background(), request, common.Principal{})
 		a.NotNil(err)
 		var validateErr common.RequestValidatorError
 		ok := errors.As(err, &validateErr)
 		a.True(ok)
-		a.Contains(validateErr.Message, "Field validation")
+		a.Len(validateErr.Fields, 1)
+		a.Equal("pageNumber", validateErr.Fields[0].Field)
+		a.Equal("min", validateErr.Fields[0].Rule)
+		a.Equal("0", validateErr.Fields[0].Param)
 	})
 }
 
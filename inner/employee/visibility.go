@@ -0,0 +1,100 @@
+package employee
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Правила видимости сотрудников
+const (
+	// все сотрудники
+	VisibleAll = "all"
+	// сотрудник, связанный с вызывающим
+	VisibleSelf = "self"
+	// сотрудники отдела вызывающего
+	VisibleOrgUnit = "org_unit"
+	// подчинённые вызывающего по всей иерархии
+	VisibleReports = "reports"
+)
+
+var visibilityRules = []string{VisibleAll, VisibleSelf, VisibleOrgUnit, VisibleReports}
+
+// VisibilityPolicies правила видимости сотрудников по ролям Keycloak
+type VisibilityPolicies map[string][]string
+
+// ParseVisibilityPolicies разбирает политики в формате "IDM_ADMIN=all;IDM_USER=self,org_unit,reports"
+func ParseVisibilityPolicies(value string) (VisibilityPolicies, error) {
+	policies := VisibilityPolicies{}
+	for _, policy := range strings.Split(value, ";") {
+		policy = strings.TrimSpace(policy)
+		if policy == "" {
+			continue
+		}
+		role, rules, ok := strings.Cut(policy, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("invalid visibility policy %q: expected format <ROLE>=<rule>,<rule>", policy)
+		}
+		for _, rule := range strings.Split(rules, ",") {
+			rule = strings.TrimSpace(rule)
+			if !slices.Contains(visibilityRules, rule) {
+				return nil, fmt.Errorf("invalid visibility policy %q: unknown rule %q", policy, rule)
+			}
+			policies[role] = append(policies[role], rule)
+		}
+	}
+	return policies, nil
+}
+
+// rulesFor возвращает объединение правил всех ролей вызывающего
+func (p VisibilityPolicies) rulesFor(roles []string) []string {
+	var rules []string
+	for _, role := range roles {
+		rules = append(rules, p[role]...)
+	}
+	return rules
+}
+
+// Scope ограничение видимости, которое репозиторий добавляет в условие запроса
+type Scope struct {
+	All bool
+	// сотрудник, связанный с вызывающим; 0 - вызывающий не связан с сотрудником
+	EmployeeId int64
+	Self       bool
+	// отдел вызывающего, пустой - сотрудники отдела не видны
+	OrgUnit string
+	Reports bool
+}
+
+// ScopeAll видны все сотрудники
+var ScopeAll = Scope{All: true}
+
+// where возвращает условие видимости для запроса к таблице employee.
+// Параметры добавляются в args и нумеруются после уже добавленных
+func (s Scope) where(args []any) (string, []any) {
+	if s.All {
+		return "", args
+	}
+	var conditions []string
+	if s.Self && s.EmployeeId != 0 {
+		args = append(args, s.EmployeeId)
+		conditions = append(conditions, fmt.Sprintf("id = $%d", len(args)))
+	}
+	if s.OrgUnit != "" {
+		args = append(args, s.OrgUnit)
+		conditions = append(conditions, fmt.Sprintf("org_unit = $%d", len(args)))
+	}
+	if s.Reports && s.EmployeeId != 0 {
+		args = append(args, s.EmployeeId)
+		conditions = append(conditions, fmt.Sprintf("id IN (WITH RECURSIVE reports (id) AS ("+
+			"SELECT id FROM employee WHERE manager_id = $%d "+
+			"UNION SELECT e.id FROM employee e JOIN reports r ON e.manager_id = r.id"+
+			") SELECT id FROM reports)", len(args)))
+	}
+	if len(conditions) == 0 {
+		// вызывающему не видно ни одного сотрудника
+		return " AND false", args
+	}
+	return " AND (" + strings.Join(conditions, " OR ") + ")", args
+}
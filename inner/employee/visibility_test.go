@@ -0,0 +1,64 @@
+package employee
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseVisibilityPolicies(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should parse rules of every role", func(t *testing.T) {
+		policies, err := ParseVisibilityPolicies("IDM_ADMIN=all; IDM_USER=self, org_unit,reports;")
+
+		a.Nil(err)
+		a.Equal(VisibilityPolicies{
+			"IDM_ADMIN": {VisibleAll},
+			"IDM_USER":  {VisibleSelf, VisibleOrgUnit, VisibleReports},
+		}, policies)
+	})
+
+	t.Run("should return error for unknown rule", func(t *testing.T) {
+		_, err := ParseVisibilityPolicies("IDM_USER=self,department")
+
+		a.ErrorContains(err, `unknown rule "department"`)
+	})
+
+	t.Run("should return error without role", func(t *testing.T) {
+		_, err := ParseVisibilityPolicies("self,org_unit")
+
+		a.NotNil(err)
+	})
+}
+
+func TestScope_where(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should not restrict all scope", func(t *testing.T) {
+		where, args := ScopeAll.where([]any{"%john%"})
+
+		a.Empty(where)
+		a.Equal([]any{"%john%"}, args)
+	})
+
+	t.Run("should number params after existing args", func(t *testing.T) {
+		where, args := Scope{EmployeeId: 7, Self: true, OrgUnit: "IT"}.where([]any{"%john%"})
+
+		a.Equal(" AND (id = $2 OR org_unit = $3)", where)
+		a.Equal([]any{"%john%", int64(7), "IT"}, args)
+	})
+
+	t.Run("should include reporting tree", func(t *testing.T) {
+		where, args := Scope{EmployeeId: 7, Reports: true}.where(nil)
+
+		a.Contains(where, "WHERE manager_id = $1")
+		a.Equal([]any{int64(7)}, args)
+	})
+
+	t.Run("should hide everyone from caller without employee", func(t *testing.T) {
+		where, args := Scope{Self: true, Reports: true}.where(nil)
+
+		a.Equal(" AND false", where)
+		a.Empty(args)
+	})
+}
@@ -0,0 +1,601 @@
+package group
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server       *web.Server
+	groupService Svc
+	logger       *common.Logger
+}
+
+// интерфейс сервиса group.Service
+type Svc interface {
+	GetAll(ctx context.Context) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Create(ctx context.Context, request CreateRequest, actor string) (Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error)
+	Delete(ctx context.Context, id int64, actor string) error
+	AddMember(ctx context.Context, id int64, request MemberRequest, actor string) (Response, error)
+	RemoveMember(ctx context.Context, id int64, employeeId int64, actor string) (Response, error)
+	AddChild(ctx context.Context, id int64, request ChildRequest, actor string) (Response, error)
+	RemoveChild(ctx context.Context, id int64, childId int64, actor string) (Response, error)
+	AddRole(ctx context.Context, id int64, request RoleRequest, actor string) (Response, error)
+	RemoveRole(ctx context.Context, id int64, roleId int64, actor string) (Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:       server,
+		groupService: svc,
+		logger:       logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/groups", c.GetAllGroups)
+	c.server.GroupApiV1.Post("/groups", c.CreateGroup)
+	c.server.GroupApiV1.Get("/groups/:id", c.GetGroup)
+	c.server.GroupApiV1.Put("/groups/:id", c.UpdateGroup)
+	c.server.GroupApiV1.Delete("/groups/:id", c.DeleteGroup)
+	c.server.GroupApiV1.Post("/groups/:id/members", c.AddMember)
+	c.server.GroupApiV1.Delete("/groups/:id/members/:employeeId", c.RemoveMember)
+	c.server.GroupApiV1.Post("/groups/:id/children", c.AddChild)
+	c.server.GroupApiV1.Delete("/groups/:id/children/:childId", c.RemoveChild)
+	c.server.GroupApiV1.Post("/groups/:id/roles", c.AddRole)
+	c.server.GroupApiV1.Delete("/groups/:id/roles/:roleId", c.RemoveRole)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/groups"
+// @Description Get all groups with members, nested groups and roles.
+// @Summary get all groups
+// @ID get-all-groups
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[[]group.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups [get]
+func (c *Controller) GetAllGroups(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод GetAll сервиса group.Service
+	response, err := c.groupService.GetAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all groups", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all groups", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/groups/:id"
+// @Description Get group by id.
+// @Summary get group
+// @ID get-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id} [get]
+func (c *Controller) GetGroup(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+
+	// вызываем метод FindById сервиса group.Service
+	response, err := c.groupService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/groups"
+// @Description Create group.
+// @Summary create group
+// @ID create-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body group.CreateRequest true "group"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups [post]
+func (c *Controller) CreateGroup(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create group: received request", zap.Any("request", request))
+
+	// вызываем метод Create сервиса group.Service
+	response, err := c.groupService.Create(ctx.Context(), request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create group", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create group", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/groups/:id"
+// @Description Rename group.
+// @Summary update group
+// @ID update-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param request body group.UpdateRequest true "group"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id} [put]
+func (c *Controller) UpdateGroup(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "update group: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод Update сервиса group.Service
+	response, err := c.groupService.Update(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/groups/:id"
+// @Description Delete group. Members lose roles of the group, nested groups are kept.
+// @Summary delete group
+// @ID delete-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id} [delete]
+func (c *Controller) DeleteGroup(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+
+	// вызываем метод Delete сервиса group.Service
+	if err := c.groupService.Delete(ctx.Context(), id, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/groups/:id/members"
+// @Description Add employee to group.
+// @Summary add group member
+// @ID add-group-member
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param request body group.MemberRequest true "employee"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/members [post]
+func (c *Controller) AddMember(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	var request MemberRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "add group member: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод AddMember сервиса group.Service
+	response, err := c.groupService.AddMember(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add group member", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add group member", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/groups/:id/members/:employeeId"
+// @Description Remove employee from group.
+// @Summary remove group member
+// @ID remove-group-member
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param employeeId path int64 true "id employee"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/members/{employeeId} [delete]
+func (c *Controller) RemoveMember(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	employeeId, err := strconv.ParseInt(ctx.Params("employeeId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// вызываем метод RemoveMember сервиса group.Service
+	response, err := c.groupService.RemoveMember(ctx.Context(), id, employeeId, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove group member", zap.Int64("id", id), zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove group member", zap.Int64("id", id), zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/groups/:id/children"
+// @Description Nest group into group: members of nested group get roles of parent group.
+// @Description Nesting that creates a cycle is rejected with 409.
+// @Summary add nested group
+// @ID add-nested-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param request body group.ChildRequest true "nested group"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/children [post]
+func (c *Controller) AddChild(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	var request ChildRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "add nested group: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод AddChild сервиса group.Service
+	response, err := c.groupService.AddChild(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add nested group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add nested group", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/groups/:id/children/:childId"
+// @Description Remove nested group from group.
+// @Summary remove nested group
+// @ID remove-nested-group
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param childId path int64 true "id nested group"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/children/{childId} [delete]
+func (c *Controller) RemoveChild(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	childId, err := strconv.ParseInt(ctx.Params("childId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid nested group id")
+	}
+
+	// вызываем метод RemoveChild сервиса group.Service
+	response, err := c.groupService.RemoveChild(ctx.Context(), id, childId, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove nested group", zap.Int64("id", id), zap.Int64("childId", childId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove nested group", zap.Int64("id", id), zap.Int64("childId", childId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/groups/:id/roles"
+// @Description Grant role to group: members of the group and of all nested groups get the role.
+// @Summary add group role
+// @ID add-group-role
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param request body group.RoleRequest true "role"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/roles [post]
+func (c *Controller) AddRole(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	var request RoleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "add group role: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод AddRole сервиса group.Service
+	response, err := c.groupService.AddRole(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add group role", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "add group role", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/groups/:id/roles/:roleId"
+// @Description Revoke role from group.
+// @Summary remove group role
+// @ID remove-group-role
+// @Tags group
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id group"
+// @Param roleId path int64 true "id role"
+// @Success 200 {object} common.Response[group.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /groups/{id}/roles/{roleId} [delete]
+func (c *Controller) RemoveRole(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid group id")
+	}
+	roleId, err := strconv.ParseInt(ctx.Params("roleId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+
+	// вызываем метод RemoveRole сервиса group.Service
+	response, err := c.groupService.RemoveRole(ctx.Context(), id, roleId, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove group role", zap.Int64("id", id), zap.Int64("roleId", roleId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "remove group role", zap.Int64("id", id), zap.Int64("roleId", roleId), zap.Error(err))
+		return err
+	}
+	return nil
+}
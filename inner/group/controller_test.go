@@ -0,0 +1,140 @@
+package group
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса group.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, actor string) (Response, error) {
+	args := svc.Called(request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Delete(ctx context.Context, id int64, actor string) error {
+	args := svc.Called(id, actor)
+	return args.Error(0)
+}
+
+func (svc *MockService) AddMember(ctx context.Context, id int64, request MemberRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) RemoveMember(ctx context.Context, id int64, employeeId int64, actor string) (Response, error) {
+	args := svc.Called(id, employeeId, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) AddChild(ctx context.Context, id int64, request ChildRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) RemoveChild(ctx context.Context, id int64, childId int64, actor string) (Response, error) {
+	args := svc.Called(id, childId, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) AddRole(ctx context.Context, id int64, request RoleRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) RemoveRole(ctx context.Context, id int64, roleId int64, actor string) (Response, error) {
+	args := svc.Called(id, roleId, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_AddChild(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should nest group with actor from token", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("AddChild", int64(1), ChildRequest{GroupId: 2}, "admin").
+			Return(Response{Id: 1, Name: "engineering", ChildIds: []int64{2}}, nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/groups/1/children", strings.NewReader(`{"group_id": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 409 for cycle", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("AddChild", int64(1), ChildRequest{GroupId: 2}, "admin").
+			Return(Response{}, common.ConflictError{Message: "nesting would create a cycle"})
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/groups/1/children", strings.NewReader(`{"group_id": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("should return 403 for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/groups/1/children", strings.NewReader(`{"group_id": 2}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "AddChild", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_RemoveMember(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("RemoveMember", int64(1), int64(7), "admin").Return(Response{Id: 1}, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/groups/1/members/7", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
@@ -0,0 +1,46 @@
+package group
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Entity группа сотрудников вместе с участниками, вложенными группами и ролями группы
+type Entity struct {
+	Id        int64         `db:"id"`
+	Name      string        `db:"name"`
+	MemberIds pq.Int64Array `db:"member_ids"`
+	ChildIds  pq.Int64Array `db:"child_ids"`
+	RoleIds   pq.Int64Array `db:"role_ids"`
+	CreateAt  time.Time     `db:"create_at"`
+	UpdateAt  time.Time     `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:        e.Id,
+		Name:      e.Name,
+		MemberIds: ids(e.MemberIds),
+		ChildIds:  ids(e.ChildIds),
+		RoleIds:   ids(e.RoleIds),
+		CreateAt:  e.CreateAt,
+		UpdateAt:  e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	MemberIds []int64   `json:"member_ids"`
+	ChildIds  []int64   `json:"child_ids"`
+	RoleIds   []int64   `json:"role_ids"`
+	CreateAt  time.Time `json:"create_at"`
+	UpdateAt  time.Time `json:"update_at"`
+}
+
+func ids(values pq.Int64Array) []int64 {
+	if values == nil {
+		return []int64{}
+	}
+	return values
+}
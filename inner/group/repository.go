@@ -0,0 +1,179 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// группы вместе с участниками, вложенными группами и ролями
+const selectGroups = `SELECT g.id, g.name, g.create_at, g.update_at,
+ARRAY(SELECT employee_id FROM group_member WHERE group_id = g.id ORDER BY employee_id) AS member_ids,
+ARRAY(SELECT child_id FROM group_child WHERE parent_id = g.id ORDER BY child_id) AS child_ids,
+ARRAY(SELECT role_id FROM group_role WHERE group_id = g.id ORDER BY role_id) AS role_ids
+FROM employee_group g`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewGroupRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти все группы
+func (r *Repository) GetAll(ctx context.Context) (groups []Entity, err error) {
+	query := selectGroups + " ORDER BY g.id"
+	err = r.db.SelectContext(ctx, &groups, query)
+	return groups, err
+}
+
+// найти группу по id
+func (r *Repository) FindById(ctx context.Context, id int64) (group Entity, err error) {
+	query := selectGroups + " WHERE g.id = $1"
+	err = r.db.GetContext(ctx, &group, query, id)
+	return group, err
+}
+
+// найти группу по id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (group Entity, err error) {
+	query := selectGroups + " WHERE g.id = $1"
+	err = tx.GetContext(ctx, &group, query, id)
+	return group, err
+}
+
+// поиск группы по имени в рамках транзакции
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM employee_group WHERE name = $1)"
+	err = tx.GetContext(ctx, &isExists, query, name)
+	return isExists, err
+}
+
+// добавить группу в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, group Entity) (id int64, err error) {
+	query := "INSERT INTO employee_group (name) VALUES ($1) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, group.Name)
+	return id, err
+}
+
+// переименовать группу в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, group Entity) error {
+	query := "UPDATE employee_group SET name = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, group.Name, group.Id)
+	return err
+}
+
+// удалить группу в рамках транзакции; false, если группы нет
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM employee_group WHERE id = $1", id)
+	return affected(result, err)
+}
+
+// добавить сотрудника в группу в рамках транзакции
+func (r *Repository) AddMemberTx(ctx context.Context, tx *sqlx.Tx, groupId int64, employeeId int64) error {
+	query := "INSERT INTO group_member (group_id, employee_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, groupId, employeeId)
+	return mapError(err, "employee not found")
+}
+
+// исключить сотрудника из группы в рамках транзакции; false, если сотрудник не входил в группу
+func (r *Repository) RemoveMemberTx(ctx context.Context, tx *sqlx.Tx, groupId int64, employeeId int64) (bool, error) {
+	query := "DELETE FROM group_member WHERE group_id = $1 AND employee_id = $2"
+	result, err := tx.ExecContext(ctx, query, groupId, employeeId)
+	return affected(result, err)
+}
+
+// исключить сотрудника из всех групп в рамках транзакции
+func (r *Repository) RemoveMembershipsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM group_member WHERE employee_id = $1", employeeId)
+	return err
+}
+
+// LockNestingTx блокирует изменение вложенности групп до конца транзакции,
+// чтобы параллельные добавления не создали цикл
+func (r *Repository) LockNestingTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "LOCK TABLE group_child IN SHARE ROW EXCLUSIVE MODE")
+	return err
+}
+
+// IsNestedTx проверяет, входит ли группа id в группу parentId, в том числе через вложенные группы.
+// Группа считается входящей сама в себя
+func (r *Repository) IsNestedTx(ctx context.Context, tx *sqlx.Tx, id int64, parentId int64) (isNested bool, err error) {
+	query := `WITH RECURSIVE nested (id) AS (
+    SELECT $2::bigint
+    UNION
+    SELECT gc.child_id FROM group_child gc JOIN nested n ON gc.parent_id = n.id
+)
+SELECT EXISTS(SELECT * FROM nested WHERE id = $1)`
+	err = tx.GetContext(ctx, &isNested, query, id, parentId)
+	return isNested, err
+}
+
+// MembersTx возвращает участников группы id и всех вложенных в неё групп - сотрудников, получающих роли группы
+func (r *Repository) MembersTx(ctx context.Context, tx *sqlx.Tx, id int64) (employeeIds []int64, err error) {
+	query := `WITH RECURSIVE nested (id) AS (
+    SELECT $1::bigint
+    UNION
+    SELECT gc.child_id FROM group_child gc JOIN nested n ON gc.parent_id = n.id
+)
+SELECT DISTINCT gm.employee_id FROM group_member gm JOIN nested n ON gm.group_id = n.id ORDER BY gm.employee_id`
+	err = tx.SelectContext(ctx, &employeeIds, query, id)
+	return employeeIds, err
+}
+
+// вложить группу childId в группу parentId в рамках транзакции
+func (r *Repository) AddChildTx(ctx context.Context, tx *sqlx.Tx, parentId int64, childId int64) error {
+	query := "INSERT INTO group_child (parent_id, child_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, parentId, childId)
+	return mapError(err, "group not found")
+}
+
+// убрать вложенную группу в рамках транзакции; false, если группа не была вложена
+func (r *Repository) RemoveChildTx(ctx context.Context, tx *sqlx.Tx, parentId int64, childId int64) (bool, error) {
+	query := "DELETE FROM group_child WHERE parent_id = $1 AND child_id = $2"
+	result, err := tx.ExecContext(ctx, query, parentId, childId)
+	return affected(result, err)
+}
+
+// назначить роль группе в рамках транзакции
+func (r *Repository) AddRoleTx(ctx context.Context, tx *sqlx.Tx, groupId int64, roleId int64) error {
+	query := "INSERT INTO group_role (group_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := tx.ExecContext(ctx, query, groupId, roleId)
+	return mapError(err, "role not found")
+}
+
+// отозвать роль у группы в рамках транзакции; false, если роль не была назначена
+func (r *Repository) RemoveRoleTx(ctx context.Context, tx *sqlx.Tx, groupId int64, roleId int64) (bool, error) {
+	query := "DELETE FROM group_role WHERE group_id = $1 AND role_id = $2"
+	result, err := tx.ExecContext(ctx, query, groupId, roleId)
+	return affected(result, err)
+}
+
+// affected возвращает, затронул ли запрос хотя бы одну строку
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// mapError переводит нарушение внешнего ключа в NotFoundError
+func mapError(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: message}
+	}
+	return err
+}
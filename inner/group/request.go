@@ -0,0 +1,25 @@
+package group
+
+type CreateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=155"`
+}
+
+func (r *CreateRequest) ToEntity() Entity {
+	return Entity{Name: r.Name}
+}
+
+type UpdateRequest struct {
+	Name string `json:"name" validate:"required,min=2,max=155"`
+}
+
+type MemberRequest struct {
+	EmployeeId int64 `json:"employee_id" validate:"required,gt=0"`
+}
+
+type ChildRequest struct {
+	GroupId int64 `json:"group_id" validate:"required,gt=0"`
+}
+
+type RoleRequest struct {
+	RoleId int64 `json:"role_id" validate:"required,gt=0"`
+}
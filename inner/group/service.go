@@ -0,0 +1,440 @@
+package group
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	GetAll(ctx context.Context) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, group Entity) (int64, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, group Entity) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	AddMemberTx(ctx context.Context, tx *sqlx.Tx, groupId int64, employeeId int64) error
+	RemoveMemberTx(ctx context.Context, tx *sqlx.Tx, groupId int64, employeeId int64) (bool, error)
+	RemoveMembershipsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+	LockNestingTx(ctx context.Context, tx *sqlx.Tx) error
+	IsNestedTx(ctx context.Context, tx *sqlx.Tx, id int64, parentId int64) (bool, error)
+	AddChildTx(ctx context.Context, tx *sqlx.Tx, parentId int64, childId int64) error
+	RemoveChildTx(ctx context.Context, tx *sqlx.Tx, parentId int64, childId int64) (bool, error)
+	AddRoleTx(ctx context.Context, tx *sqlx.Tx, groupId int64, roleId int64) error
+	RemoveRoleTx(ctx context.Context, tx *sqlx.Tx, groupId int64, roleId int64) (bool, error)
+	MembersTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error)
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// ChangeListener получает сотрудников, у которых изменились роли из групп.
+// Вызывается в транзакции изменения группы, например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	validator Validator
+	listener  ChangeListener
+}
+
+func NewService(repo Repo, audit AuditRepo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		validator: validator,
+	}
+}
+
+// SetChangeListener подключает получателя изменений ролей участников групп
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
+	groups, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all groups: %w", err)
+	}
+	response := make([]Response, 0, len(groups))
+	for _, item := range groups {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	group, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("group with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group with id %d: %w", id, err)
+	}
+	return group.toResponse(), nil
+}
+
+// Create добавляет группу без участников и ролей
+func (s *Service) Create(ctx context.Context, request CreateRequest, actor string) (response Response, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating group panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating group: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group by name: %s, %w", request.Name, err)
+	}
+	if isExist {
+		err = common.AlreadyExistsError{Message: fmt.Sprintf("group with name %s already exists", request.Name)}
+		return Response{}, err
+	}
+	id, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating group: %w", err)
+	}
+	group, err := s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group with id %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "group.created",
+		EntityType: "group",
+		EntityId:   id,
+		Details:    group.toResponse(),
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return group.toResponse(), nil
+}
+
+// Update переименовывает группу. Имя должно остаться уникальным
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	return s.change(ctx, id, actor, "group.updated", request, func(tx *sqlx.Tx, group Entity) error {
+		if group.Name == request.Name {
+			return nil
+		}
+		isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+		if err != nil {
+			return fmt.Errorf("error finding group by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return common.AlreadyExistsError{Message: fmt.Sprintf("group with name %s already exists", request.Name)}
+		}
+		group.Name = request.Name
+		if err := s.repo.UpdateTx(ctx, tx, group); err != nil {
+			return fmt.Errorf("error updating group with id %d: %w", id, err)
+		}
+		return nil
+	})
+}
+
+// Delete удаляет группу. Участники теряют роли группы, вложенные группы остаются
+func (s *Service) Delete(ctx context.Context, id int64, actor string) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("deleting group panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("deleting group: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	// участники группы и вложенных групп теряют её роли, поэтому их находим до удаления
+	members, err := s.membersTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.DeleteTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting group with id %d: %w", id, err)
+	}
+	if !deleted {
+		err = common.NotFoundError{Message: fmt.Sprintf("group with id %d not found", id)}
+		return err
+	}
+	if err = s.notifyTx(ctx, tx, members); err != nil {
+		return err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "group.deleted",
+		EntityType: "group",
+		EntityId:   id,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// AddMember добавляет сотрудника в группу
+func (s *Service) AddMember(ctx context.Context, id int64, request MemberRequest, actor string) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	return s.change(ctx, id, actor, "group.member_added", request, func(tx *sqlx.Tx, group Entity) error {
+		if err := s.repo.AddMemberTx(ctx, tx, id, request.EmployeeId); err != nil {
+			return fmt.Errorf("error adding employee %d to group %d: %w", request.EmployeeId, id, err)
+		}
+		return s.notifyTx(ctx, tx, []int64{request.EmployeeId})
+	})
+}
+
+// RemoveMember исключает сотрудника из группы
+func (s *Service) RemoveMember(ctx context.Context, id int64, employeeId int64, actor string) (Response, error) {
+	details := MemberRequest{EmployeeId: employeeId}
+	return s.change(ctx, id, actor, "group.member_removed", details, func(tx *sqlx.Tx, group Entity) error {
+		removed, err := s.repo.RemoveMemberTx(ctx, tx, id, employeeId)
+		if err != nil {
+			return fmt.Errorf("error removing employee %d from group %d: %w", employeeId, id, err)
+		}
+		if !removed {
+			return common.NotFoundError{Message: fmt.Sprintf("employee %d is not a member of group %d", employeeId, id)}
+		}
+		return s.notifyTx(ctx, tx, []int64{employeeId})
+	})
+}
+
+// AddChild вкладывает группу в группу: участники вложенной группы получают роли родительской.
+// Вложение, которое создаёт цикл, отклоняется
+func (s *Service) AddChild(ctx context.Context, id int64, request ChildRequest, actor string) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	return s.change(ctx, id, actor, "group.child_added", request, func(tx *sqlx.Tx, group Entity) error {
+		if err := s.repo.LockNestingTx(ctx, tx); err != nil {
+			return fmt.Errorf("error locking group nesting: %w", err)
+		}
+		// группа id уже входит в добавляемую группу - вложение замкнёт цикл
+		isCycle, err := s.repo.IsNestedTx(ctx, tx, id, request.GroupId)
+		if err != nil {
+			return fmt.Errorf("error checking nesting of group %d: %w", request.GroupId, err)
+		}
+		if isCycle {
+			return common.ConflictError{Message: fmt.Sprintf("group %d cannot be nested into group %d: nesting would create a cycle", request.GroupId, id)}
+		}
+		if err := s.repo.AddChildTx(ctx, tx, id, request.GroupId); err != nil {
+			return fmt.Errorf("error nesting group %d into group %d: %w", request.GroupId, id, err)
+		}
+		return s.notifyMembersTx(ctx, tx, request.GroupId)
+	})
+}
+
+// RemoveChild убирает вложенную группу
+func (s *Service) RemoveChild(ctx context.Context, id int64, childId int64, actor string) (Response, error) {
+	details := ChildRequest{GroupId: childId}
+	return s.change(ctx, id, actor, "group.child_removed", details, func(tx *sqlx.Tx, group Entity) error {
+		removed, err := s.repo.RemoveChildTx(ctx, tx, id, childId)
+		if err != nil {
+			return fmt.Errorf("error removing group %d from group %d: %w", childId, id, err)
+		}
+		if !removed {
+			return common.NotFoundError{Message: fmt.Sprintf("group %d is not nested into group %d", childId, id)}
+		}
+		return s.notifyMembersTx(ctx, tx, childId)
+	})
+}
+
+// AddRole назначает роль группе: её получают участники группы и всех вложенных групп
+func (s *Service) AddRole(ctx context.Context, id int64, request RoleRequest, actor string) (Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+	return s.change(ctx, id, actor, "group.role_added", request, func(tx *sqlx.Tx, group Entity) error {
+		if err := s.repo.AddRoleTx(ctx, tx, id, request.RoleId); err != nil {
+			return fmt.Errorf("error adding role %d to group %d: %w", request.RoleId, id, err)
+		}
+		return s.notifyMembersTx(ctx, tx, id)
+	})
+}
+
+// RemoveRole отзывает роль у группы
+func (s *Service) RemoveRole(ctx context.Context, id int64, roleId int64, actor string) (Response, error) {
+	details := RoleRequest{RoleId: roleId}
+	return s.change(ctx, id, actor, "group.role_removed", details, func(tx *sqlx.Tx, group Entity) error {
+		removed, err := s.repo.RemoveRoleTx(ctx, tx, id, roleId)
+		if err != nil {
+			return fmt.Errorf("error removing role %d from group %d: %w", roleId, id, err)
+		}
+		if !removed {
+			return common.NotFoundError{Message: fmt.Sprintf("role %d is not granted to group %d", roleId, id)}
+		}
+		return s.notifyMembersTx(ctx, tx, id)
+	})
+}
+
+// Hook исключает уволенного сотрудника из всех групп. Регистрируется в lifecycle.Service
+func (s *Service) Hook(ctx context.Context, tx *sqlx.Tx, event lifecycle.Event) error {
+	if event.Employee.Status != lifecycle.StatusTerminated {
+		return nil
+	}
+	if err := s.repo.RemoveMembershipsTx(ctx, tx, event.Employee.Id); err != nil {
+		return fmt.Errorf("error removing employee %d from groups: %w", event.Employee.Id, err)
+	}
+	return nil
+}
+
+// membersTx возвращает сотрудников, получающих роли группы id. Без получателя изменений они не нужны
+func (s *Service) membersTx(ctx context.Context, tx *sqlx.Tx, id int64) ([]int64, error) {
+	if s.listener == nil {
+		return nil, nil
+	}
+	members, err := s.repo.MembersTx(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error finding members of group %d: %w", id, err)
+	}
+	return members, nil
+}
+
+// notifyMembersTx сообщает об изменении ролей всех сотрудников, получающих роли группы id
+func (s *Service) notifyMembersTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	members, err := s.membersTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	return s.notifyTx(ctx, tx, members)
+}
+
+// notifyTx сообщает об изменении ролей сотрудников в транзакции изменения группы
+func (s *Service) notifyTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	if s.listener == nil || len(employeeIds) == 0 {
+		return nil
+	}
+	if err := s.listener.RolesChangedTx(ctx, tx, employeeIds); err != nil {
+		return fmt.Errorf("error notifying about roles of employees %v changed by group: %w", employeeIds, err)
+	}
+	return nil
+}
+
+// change выполняет изменение группы id в транзакции, пишет аудит и возвращает группу после изменения
+func (s *Service) change(
+	ctx context.Context,
+	id int64,
+	actor string,
+	action string,
+	details any,
+	fn func(tx *sqlx.Tx, group Entity) error,
+) (response Response, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("changing group panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing group: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("changing group: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	group, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.NotFoundError{Message: fmt.Sprintf("group with id %d not found", id)}
+		return Response{}, err
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group with id %d: %w", id, err)
+	}
+	if err = fn(tx, group); err != nil {
+		return Response{}, err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: "group",
+		EntityId:   id,
+		Details:    details,
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	group, err = s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding group with id %d: %w", id, err)
+	}
+	return group.toResponse(), nil
+}
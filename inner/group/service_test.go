@@ -0,0 +1,264 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	findQuery        = regexp.QuoteMeta(selectGroups + " WHERE g.id = $1")
+	existsQuery      = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee_group WHERE name = $1)")
+	insertQuery      = regexp.QuoteMeta("INSERT INTO employee_group (name) VALUES ($1) RETURNING id")
+	lockQuery        = regexp.QuoteMeta("LOCK TABLE group_child IN SHARE ROW EXCLUSIVE MODE")
+	nestedQuery      = regexp.QuoteMeta("WITH RECURSIVE nested (id) AS (")
+	addChildQuery    = regexp.QuoteMeta("INSERT INTO group_child (parent_id, child_id) VALUES ($1, $2) ON CONFLICT DO NOTHING")
+	removeRoleQuery  = regexp.QuoteMeta("DELETE FROM group_role WHERE group_id = $1 AND role_id = $2")
+	membershipsQuery = regexp.QuoteMeta("DELETE FROM group_member WHERE employee_id = $1")
+	membersQuery     = regexp.QuoteMeta("SELECT DISTINCT gm.employee_id FROM group_member gm JOIN nested n ON gm.group_id = n.id")
+	deleteQuery      = regexp.QuoteMeta("DELETE FROM employee_group WHERE id = $1")
+	auditQuery       = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	groupColumns     = []string{"id", "name", "create_at", "update_at", "member_ids", "child_ids", "role_ids"}
+)
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	return NewService(NewGroupRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), validator.NewValidator()), mock
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+func TestService_Create(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create group", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "backend", now, now, "{}", "{}", "{}"))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "group.created", "group", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Create(context.Background(), CreateRequest{Name: "backend"}, "admin")
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, Name: "backend", MemberIds: []int64{}, ChildIds: []int64{}, RoleIds: []int64{},
+			CreateAt: now, UpdateAt: now}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError for taken name", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("backend").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "backend"}, "admin")
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_AddChild(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should nest group", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "engineering", now, now, "{}", "{}", "{}"))
+		mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(nestedQuery).WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(addChildQuery).WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "group.child_added", "group", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "engineering", now, now, "{}", "{2}", "{}"))
+		mock.ExpectCommit()
+
+		got, err := srv.AddChild(context.Background(), 1, ChildRequest{GroupId: 2}, "admin")
+
+		a.Nil(err)
+		a.Equal([]int64{2}, got.ChildIds)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should notify listener about members of nested group", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{5, 6}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "engineering", now, now, "{}", "{}", "{}"))
+		dbMock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(nestedQuery).WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectExec(addChildQuery).WithArgs(int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectQuery(membersQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(5).AddRow(6))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "group.child_added", "group", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "engineering", now, now, "{}", "{2}", "{}"))
+		dbMock.ExpectCommit()
+
+		_, err := srv.AddChild(context.Background(), 1, ChildRequest{GroupId: 2}, "admin")
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should reject nesting that creates a cycle", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "backend", now, now, "{}", "{}", "{}"))
+		mock.ExpectExec(lockQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(nestedQuery).WithArgs(int64(1), int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.AddChild(context.Background(), 1, ChildRequest{GroupId: 2}, "admin")
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("group 2 cannot be nested into group 1: nesting would create a cycle", conflictErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown group", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(groupColumns))
+		mock.ExpectRollback()
+
+		_, err := srv.AddChild(context.Background(), 1, ChildRequest{GroupId: 2}, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_RemoveRole(t *testing.T) {
+	var a = assert.New(t)
+	srv, mock := newTestService(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(1, "backend", now, now, "{}", "{}", "{}"))
+	mock.ExpectExec(removeRoleQuery).WithArgs(int64(1), int64(3)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := srv.RemoveRole(context.Background(), 1, 3, "admin")
+
+	var notFoundErr common.NotFoundError
+	a.True(errors.As(err, &notFoundErr))
+	a.Equal("role 3 is not granted to group 1", notFoundErr.Message)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestService_Delete(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should notify listener about members who lose roles of deleted group", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(membersQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(4))
+		dbMock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "group.deleted", "group", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		a.Nil(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should roll back deletion when listener fails", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{4}).Return(errors.New("database error"))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(membersQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(4))
+		dbMock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectRollback()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		a.Error(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Hook(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should remove terminated employee from groups", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(membershipsQuery).WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 2))
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+
+		err = srv.Hook(context.Background(), tx, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 7, Status: lifecycle.StatusTerminated},
+			FromStatus: lifecycle.StatusActive,
+		})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should keep groups of suspended employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		err := srv.Hook(context.Background(), nil, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 7, Status: lifecycle.StatusSuspended},
+			FromStatus: lifecycle.StatusActive,
+		})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,52 @@
+package info
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Значения подставляются при сборке через ldflags:
+// go build -ldflags "-X github.com/nihrom205/idm/inner/info.Commit=$(git rev-parse HEAD) -X github.com/nihrom205/idm/inner/info.BuildTime=$(date -u +%FT%TZ)"
+var (
+	Commit    = ""
+	BuildTime = ""
+)
+
+// BuildInfo сведения о сборке приложения
+type BuildInfo struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified"`
+}
+
+// readBuildInfo собирает сведения о сборке: значения из ldflags имеют приоритет,
+// иначе используются VCS-метки, которые go build встраивает в бинарник
+func readBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info.GoVersion = bi.GoVersion
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
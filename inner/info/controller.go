@@ -2,37 +2,97 @@ package info
 
 import (
 	"context"
+	"database/sql"
 	"github.com/gofiber/fiber/v2"
 	"github.com/nihrom205/idm/inner/common"
 	"github.com/nihrom205/idm/inner/web"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Database interface {
 	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+	Querier
 }
 
 type Controller struct {
 	server *web.Server
 	cfg    common.Config
 	db     Database
+	// проверки зависимостей для readiness probe
+	checks []Check
+	// признак начала graceful shutdown
+	shuttingDown atomic.Bool
+	// сведения о сборке
+	build BuildInfo
+	// время запуска процесса
+	startedAt time.Time
 }
 
 func NewController(server *web.Server, cfg common.Config, db Database) *Controller {
 	return &Controller{
-		server: server,
-		cfg:    cfg,
-		db:     db,
+		server:    server,
+		cfg:       cfg,
+		db:        db,
+		checks:    []Check{NewDatabaseCheck(db)},
+		build:     readBuildInfo(),
+		startedAt: time.Now(),
 	}
 }
 
+// AddCheck добавляет проверку зависимости в readiness probe
+func (c *Controller) AddCheck(check Check) {
+	c.checks = append(c.checks, check)
+}
+
+// SetShuttingDown переводит readiness probe в состояние "недоступен",
+// чтобы балансировщик перестал направлять на нас трафик
+func (c *Controller) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
 type InfoResponse struct {
 	Name    string `json:"name"`
 	Version string `json:"version"`
+	// сведения о сборке
+	Build BuildInfo `json:"build"`
+	// сведения о запущенном процессе
+	Runtime RuntimeInfo `json:"runtime"`
+	// активная конфигурация без секретов
+	Config common.Config `json:"config"`
+	// состояние базы данных
+	Database DatabaseInfo `json:"database"`
+}
+
+type RuntimeInfo struct {
+	StartTime     time.Time `json:"start_time"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Goroutines    int       `json:"goroutines"`
+}
+
+type DatabaseInfo struct {
+	MigrationVersion int64  `json:"migration_version"`
+	Error            string `json:"error,omitempty"`
+	Pool             struct {
+		MaxOpenConnections int   `json:"max_open_connections"`
+		OpenConnections    int   `json:"open_connections"`
+		InUse              int   `json:"in_use"`
+		Idle               int   `json:"idle"`
+		WaitCount          int64 `json:"wait_count"`
+		WaitDurationMs     int64 `json:"wait_duration_ms"`
+		MaxIdleClosed      int64 `json:"max_idle_closed"`
+		MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+	} `json:"pool"`
 }
 
 func (c *Controller) RegisterRouters() {
 	c.server.GroupInternal.Get("/info", c.GetInfo)
 	c.server.GroupInternal.Get("/health", c.GetHealth)
+	c.server.GroupInternal.Get("/health/live", c.GetLiveness)
+	c.server.GroupInternal.Get("/health/ready", c.GetReadiness)
 }
 
 // GetInfo получение информации о приложении
@@ -40,6 +100,14 @@ func (c *Controller) GetInfo(ctx *fiber.Ctx) error {
 	resp := &InfoResponse{
 		Name:    c.cfg.AppName,
 		Version: c.cfg.AppVersion,
+		Build:   c.build,
+		Runtime: RuntimeInfo{
+			StartTime:     c.startedAt,
+			UptimeSeconds: int64(time.Since(c.startedAt).Seconds()),
+			Goroutines:    runtime.NumGoroutine(),
+		},
+		Config:   c.cfg.Redacted(),
+		Database: c.databaseInfo(ctx.Context()),
 	}
 
 	err := ctx.Status(fiber.StatusOK).JSON(resp)
@@ -49,12 +117,35 @@ func (c *Controller) GetInfo(ctx *fiber.Ctx) error {
 	return nil
 }
 
+// databaseInfo собирает статистику пула соединений и версию применённых миграций.
+// Ошибка получения версии миграции не ломает ответ, а попадает в поле Error
+func (c *Controller) databaseInfo(ctx context.Context) DatabaseInfo {
+	var info DatabaseInfo
+	stats := c.db.Stats()
+	info.Pool.MaxOpenConnections = stats.MaxOpenConnections
+	info.Pool.OpenConnections = stats.OpenConnections
+	info.Pool.InUse = stats.InUse
+	info.Pool.Idle = stats.Idle
+	info.Pool.WaitCount = stats.WaitCount
+	info.Pool.WaitDurationMs = stats.WaitDuration.Milliseconds()
+	info.Pool.MaxIdleClosed = stats.MaxIdleClosed
+	info.Pool.MaxLifetimeClosed = stats.MaxLifetimeClosed
+
+	dbCtx, cancel := context.WithTimeout(ctx, defaultCheckTimeout)
+	defer cancel()
+	version, err := MigrationVersion(dbCtx, c.db)
+	if err != nil {
+		info.Error = err.Error()
+	}
+	info.MigrationVersion = version
+	return info
+}
+
 // GetHealth проверка работоспособности приложения
 func (c *Controller) GetHealth(ctx *fiber.Ctx) error {
 	// Создаем контекст с таймаутом для проверки БД
-	//dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	//defer cancel()
-	dbCtx := context.Background()
+	dbCtx, cancel := context.WithTimeout(ctx.Context(), defaultCheckTimeout)
+	defer cancel()
 
 	// проверка к подключению БД
 	if err := c.db.PingContext(dbCtx); err != nil {
@@ -62,3 +153,44 @@ func (c *Controller) GetHealth(ctx *fiber.Ctx) error {
 	}
 	return nil
 }
+
+// GetLiveness liveness probe: процесс жив и обрабатывает запросы, зависимости не проверяются
+func (c *Controller) GetLiveness(ctx *fiber.Ctx) error {
+	return ctx.Status(fiber.StatusOK).JSON(&HealthResponse{Status: StatusUp})
+}
+
+// GetReadiness readiness probe: приложение готово принимать трафик,
+// если не начат graceful shutdown и все зависимости доступны
+func (c *Controller) GetReadiness(ctx *fiber.Ctx) error {
+	if c.shuttingDown.Load() {
+		return ctx.Status(fiber.StatusServiceUnavailable).JSON(&HealthResponse{Status: StatusShuttingDown})
+	}
+
+	resp := &HealthResponse{
+		Status: StatusUp,
+		Checks: c.runChecks(ctx.Context()),
+	}
+	status := fiber.StatusOK
+	for _, check := range resp.Checks {
+		if check.Status != StatusUp {
+			resp.Status = StatusDown
+			status = fiber.StatusServiceUnavailable
+		}
+	}
+	return ctx.Status(status).JSON(resp)
+}
+
+// runChecks выполняет все проверки параллельно, сохраняя порядок результатов
+func (c *Controller) runChecks(ctx context.Context) []CheckResult {
+	results := make([]CheckResult, len(c.checks))
+	wg := sync.WaitGroup{}
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}()
+	}
+	wg.Wait()
+	return results
+}
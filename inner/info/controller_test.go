@@ -2,6 +2,7 @@ package info
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"github.com/gofiber/fiber/v2"
@@ -9,9 +10,12 @@ import (
 	"github.com/nihrom205/idm/inner/web"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
 	"io"
+	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 // MockDatabase - мок для интерфейса Database
@@ -25,10 +29,22 @@ func (m *MockDatabase) PingContext(ctx context.Context) error {
 	return args.Error(0)
 }
 
+func (m *MockDatabase) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 20, OpenConnections: 2, InUse: 1, Idle: 1}
+}
+
+func (m *MockDatabase) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	called := m.Called(query)
+	if v, ok := dest.(*int64); ok && called.Get(0) != nil {
+		*v = called.Get(0).(int64)
+	}
+	return called.Error(1)
+}
+
 // setupTest инициализирует тестовое окружение
 func setupTest(t *testing.T) (*fiber.App, *MockDatabase) {
 	// Готовим тестовое окружение
-	server := web.NewServer()
+	server := web.NewServer(&common.Logger{Logger: zap.NewNop()})
 	cfg := common.Config{
 		DbDriverName: "postgres",
 		DSN:          "test_dsn",
@@ -54,7 +70,8 @@ func TestName(t *testing.T) {
 	var a = assert.New(t)
 
 	t.Run("Success", func(t *testing.T) {
-		app, _ := setupTest(t)
+		app, mockDB := setupTest(t)
+		mockDB.On("GetContext", mock.Anything).Return(int64(20250608075950), nil)
 		req := httptest.NewRequest(fiber.MethodGet, "/internal/info", nil)
 		resp, err := app.Test(req)
 		a.Nil(err)
@@ -73,6 +90,37 @@ func TestName(t *testing.T) {
 
 		a.Equal("test_app", responseBody.Name)
 		a.Equal("0.0.1", responseBody.Version)
+		a.NotEmpty(responseBody.Build.GoVersion)
+		a.False(responseBody.Runtime.StartTime.IsZero())
+		a.Equal(int64(20250608075950), responseBody.Database.MigrationVersion)
+		a.Equal(20, responseBody.Database.Pool.MaxOpenConnections)
+		a.Empty(responseBody.Database.Error)
+	})
+
+	t.Run("Success - secrets are redacted", func(t *testing.T) {
+		server := web.NewServer(&common.Logger{Logger: zap.NewNop()})
+		mockDB := &MockDatabase{}
+		mockDB.On("GetContext", mock.Anything).Return(nil, errors.New("relation \"goose_db_version\" does not exist"))
+		cfg := common.Config{
+			AppName: "test_app",
+			DSN:     "host=localhost user=user password=secret dbname=idm_db",
+		}
+		NewController(server, cfg, mockDB).RegisterRouters()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/info", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+
+		bytesData, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.NotContains(string(bytesData), "secret")
+
+		var responseBody InfoResponse
+		err = json.Unmarshal(bytesData, &responseBody)
+		a.Nil(err)
+		a.Equal("host=localhost user=user password=*** dbname=idm_db", responseBody.Config.DSN)
+		a.NotEmpty(responseBody.Database.Error)
 	})
 }
 func TestGetHealth(t *testing.T) {
@@ -100,17 +148,160 @@ func TestGetHealth(t *testing.T) {
 		a.Equal(fiber.StatusInternalServerError, resp.StatusCode)
 
 		bytesData, err := io.ReadAll(resp.Body)
-		var errResponse common.Response[string]
+		var problem common.Problem
 		if err != nil {
 			t.Fatal("Failed to read response body")
 		}
-		err = json.Unmarshal(bytesData, &errResponse)
+		err = json.Unmarshal(bytesData, &problem)
 		if err != nil {
 			t.Fatal("Failed to unmarshal response body")
 		}
-		a.False(errResponse.Success)
-		a.Equal("Database connection failed", errResponse.Message)
+		a.Equal(common.CodeInternal, problem.Code)
+		a.Equal("Database connection failed", problem.Detail)
 
 		mockDB.AssertCalled(t, "PingContext", mock.Anything)
 	})
 }
+
+func TestGetLiveness(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("Success - does not check dependencies", func(t *testing.T) {
+		app, mockDB := setupTest(t)
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/health/live", nil)
+		resp, err := app.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+
+		mockDB.AssertNotCalled(t, "PingContext", mock.Anything)
+	})
+}
+
+func TestGetReadiness(t *testing.T) {
+	var a = assert.New(t)
+
+	readResponse := func(t *testing.T, body io.Reader) HealthResponse {
+		var responseBody HealthResponse
+		bytesData, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal("Failed to read response body")
+		}
+		err = json.Unmarshal(bytesData, &responseBody)
+		if err != nil {
+			t.Fatal("Failed to unmarshal response body")
+		}
+		return responseBody
+	}
+
+	t.Run("Success - all checks up", func(t *testing.T) {
+		server := web.NewServer(&common.Logger{Logger: zap.NewNop()})
+		mockDB := &MockDatabase{}
+		mockDB.On("PingContext", mock.Anything).Return(nil)
+		controller := NewController(server, common.Config{}, mockDB)
+		controller.AddCheck(Check{
+			Name: "migrations",
+			Fn: func(ctx context.Context) (any, error) {
+				return map[string]int64{"version": 20250608075950}, nil
+			},
+		})
+		controller.RegisterRouters()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/health/ready", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusOK, resp.StatusCode)
+
+		body := readResponse(t, resp.Body)
+		a.Equal(StatusUp, body.Status)
+		a.Len(body.Checks, 2)
+		a.Equal("database", body.Checks[0].Name)
+		a.Equal(StatusUp, body.Checks[0].Status)
+		a.Equal("migrations", body.Checks[1].Name)
+		a.NotNil(body.Checks[1].Details)
+	})
+
+	t.Run("Error - database unavailable", func(t *testing.T) {
+		app, mockDB := setupTest(t)
+		mockDB.On("PingContext", mock.Anything).Return(errors.New("database connection failed"))
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/health/ready", nil)
+		resp, err := app.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusServiceUnavailable, resp.StatusCode)
+
+		body := readResponse(t, resp.Body)
+		a.Equal(StatusDown, body.Status)
+		a.Len(body.Checks, 1)
+		a.Equal(StatusDown, body.Checks[0].Status)
+		a.Equal("database connection failed", body.Checks[0].Error)
+	})
+
+	t.Run("Error - check timeout", func(t *testing.T) {
+		server := web.NewServer(&common.Logger{Logger: zap.NewNop()})
+		mockDB := &MockDatabase{}
+		mockDB.On("PingContext", mock.Anything).Return(nil)
+		controller := NewController(server, common.Config{}, mockDB)
+		controller.AddCheck(Check{
+			Name:    "slow",
+			Timeout: 10 * time.Millisecond,
+			Fn: func(ctx context.Context) (any, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		})
+		controller.RegisterRouters()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/health/ready", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusServiceUnavailable, resp.StatusCode)
+
+		body := readResponse(t, resp.Body)
+		a.Equal(StatusUp, body.Checks[0].Status)
+		a.Equal(StatusDown, body.Checks[1].Status)
+		a.Equal(context.DeadlineExceeded.Error(), body.Checks[1].Error)
+	})
+
+	t.Run("Error - graceful shutdown started", func(t *testing.T) {
+		server := web.NewServer(&common.Logger{Logger: zap.NewNop()})
+		mockDB := &MockDatabase{}
+		controller := NewController(server, common.Config{}, mockDB)
+		controller.RegisterRouters()
+		controller.SetShuttingDown()
+
+		req := httptest.NewRequest(fiber.MethodGet, "/internal/health/ready", nil)
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		a.Equal(fiber.StatusServiceUnavailable, resp.StatusCode)
+
+		body := readResponse(t, resp.Body)
+		a.Equal(StatusShuttingDown, body.Status)
+		mockDB.AssertNotCalled(t, "PingContext", mock.Anything)
+	})
+}
+
+func TestNewJwksCheck(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("Success - jwks reachable", func(t *testing.T) {
+		jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"keys":[]}`))
+		}))
+		defer jwks.Close()
+
+		result := NewJwksCheck(jwks.URL, nil).run(context.Background())
+		a.Equal(StatusUp, result.Status)
+	})
+
+	t.Run("Error - jwks returns error status", func(t *testing.T) {
+		jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer jwks.Close()
+
+		result := NewJwksCheck(jwks.URL, nil).run(context.Background())
+		a.Equal(StatusDown, result.Status)
+		a.Contains(result.Error, "502")
+	})
+}
@@ -0,0 +1,129 @@
+package info
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	StatusUp           = "up"
+	StatusDown         = "down"
+	StatusShuttingDown = "shutting_down"
+
+	// таймаут проверки зависимости по умолчанию
+	defaultCheckTimeout = 2 * time.Second
+)
+
+// CheckFunc функция проверки одной зависимости приложения.
+// Возвращает произвольные детали проверки, которые попадут в ответ
+type CheckFunc func(ctx context.Context) (any, error)
+
+// Check проверка зависимости, выполняемая при запросе готовности приложения
+type Check struct {
+	Name    string
+	Timeout time.Duration
+	Fn      CheckFunc
+}
+
+// CheckResult результат выполнения одной проверки
+type CheckResult struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Details   any    `json:"details,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthResponse ответ probe-эндпоинтов
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// run выполняет проверку с собственным таймаутом
+func (c Check) run(ctx context.Context) CheckResult {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	details, err := c.Fn(checkCtx)
+	result := CheckResult{
+		Name:      c.Name,
+		Status:    StatusUp,
+		LatencyMs: time.Since(start).Milliseconds(),
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// NewDatabaseCheck проверка доступности базы данных
+func NewDatabaseCheck(db Database) Check {
+	return Check{
+		Name: "database",
+		Fn: func(ctx context.Context) (any, error) {
+			return nil, db.PingContext(ctx)
+		},
+	}
+}
+
+// Querier минимальный интерфейс для чтения одного значения из базы данных
+type Querier interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// MigrationVersion возвращает версию последней применённой миграции goose
+func MigrationVersion(ctx context.Context, db Querier) (int64, error) {
+	var version int64
+	query := "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied"
+	err := db.GetContext(ctx, &version, query)
+	return version, err
+}
+
+// NewMigrationCheck проверка того, что миграции базы данных применены
+func NewMigrationCheck(db Querier) Check {
+	return Check{
+		Name: "migrations",
+		Fn: func(ctx context.Context) (any, error) {
+			version, err := MigrationVersion(ctx, db)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]int64{"version": version}, nil
+		},
+	}
+}
+
+// NewJwksCheck проверка доступности JWKS эндпоинта Keycloak
+func NewJwksCheck(url string, client *http.Client) Check {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return Check{
+		Name: "jwks",
+		Fn: func(ctx context.Context) (any, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected jwks status code: %d", resp.StatusCode)
+			}
+			return nil, nil
+		},
+	}
+}
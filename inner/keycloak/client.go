@@ -0,0 +1,218 @@
+package keycloak
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// размер страницы списков Admin REST API
+const pageSize = 100
+
+// токен обновляется заранее, чтобы не истечь посреди запроса
+const tokenLeeway = 30 * time.Second
+
+// Role роль области (realm role) Keycloak
+type Role struct {
+	Id          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Composite   bool   `json:"composite,omitempty"`
+}
+
+// User пользователь Keycloak; Id совпадает с subject токена сотрудника
+type User struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// StatusError ответ Admin REST API с неожиданным кодом
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("keycloak %s %s: unexpected status code %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// Client клиент Keycloak Admin REST API. Авторизуется как confidential client с service account
+// по client credentials; сервисной учётной записи нужны роли view-realm, manage-realm и manage-users
+type Client struct {
+	baseUrl      string
+	realm        string
+	clientId     string
+	clientSecret string
+	http         *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewClient(baseUrl string, realm string, clientId string, clientSecret string, client *http.Client) *Client {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{
+		baseUrl:      strings.TrimRight(baseUrl, "/"),
+		realm:        realm,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+		http:         client,
+	}
+}
+
+// Roles все роли области
+func (c *Client) Roles(ctx context.Context) ([]Role, error) {
+	roles := make([]Role, 0)
+	for first := 0; ; first += pageSize {
+		var page []Role
+		path := fmt.Sprintf("/roles?briefRepresentation=false&first=%d&max=%d", first, pageSize)
+		if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		roles = append(roles, page...)
+		if len(page) < pageSize {
+			return roles, nil
+		}
+	}
+}
+
+// CreateRole создаёт роль области
+func (c *Client) CreateRole(ctx context.Context, role Role) error {
+	return c.do(ctx, http.MethodPost, "/roles", Role{Name: role.Name, Description: role.Description}, nil)
+}
+
+// UpdateRole меняет описание роли области
+func (c *Client) UpdateRole(ctx context.Context, role Role) error {
+	body := Role{Name: role.Name, Description: role.Description}
+	return c.do(ctx, http.MethodPut, "/roles/"+url.PathEscape(role.Name), body, nil)
+}
+
+// RoleUsers пользователи, которым роль области назначена напрямую
+func (c *Client) RoleUsers(ctx context.Context, roleName string) ([]User, error) {
+	users := make([]User, 0)
+	for first := 0; ; first += pageSize {
+		var page []User
+		path := fmt.Sprintf("/roles/%s/users?first=%d&max=%d", url.PathEscape(roleName), first, pageSize)
+		if err := c.do(ctx, http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		if len(page) < pageSize {
+			return users, nil
+		}
+	}
+}
+
+// AddRealmRole назначает пользователю роль области
+func (c *Client) AddRealmRole(ctx context.Context, userId string, role Role) error {
+	path := fmt.Sprintf("/users/%s/role-mappings/realm", url.PathEscape(userId))
+	return c.do(ctx, http.MethodPost, path, []Role{role}, nil)
+}
+
+// RemoveRealmRole отзывает у пользователя роль области
+func (c *Client) RemoveRealmRole(ctx context.Context, userId string, role Role) error {
+	path := fmt.Sprintf("/users/%s/role-mappings/realm", url.PathEscape(userId))
+	return c.do(ctx, http.MethodDelete, path, []Role{role}, nil)
+}
+
+// do выполняет запрос к /admin/realms/{realm}{path}; body и out сериализуются в JSON
+func (c *Client) do(ctx context.Context, method string, path string, body any, out any) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+"/admin/realms/"+url.PathEscape(c.realm)+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if resp.StatusCode == http.StatusUnauthorized {
+			// токен мог быть отозван: следующий запрос получит новый
+			c.resetToken()
+		}
+		return newStatusError(method, path, resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// accessToken токен service account клиента; кешируется до истечения срока
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.expiresAt) {
+		return c.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {c.clientId},
+		"client_secret": {c.clientSecret},
+	}
+	path := "/realms/" + url.PathEscape(c.realm) + "/protocol/openid-connect/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", newStatusError(http.MethodPost, path, resp)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding keycloak token: %w", err)
+	}
+	c.token = token.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - tokenLeeway)
+	return c.token, nil
+}
+
+func (c *Client) resetToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+func newStatusError(method string, path string, resp *http.Response) StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return StatusError{Method: method, Path: path, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
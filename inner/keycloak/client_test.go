@@ -0,0 +1,262 @@
+package keycloak
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeKeycloak заменяет Keycloak в тестах: область test с ролями и их пользователями
+type fakeKeycloak struct {
+	mu      sync.Mutex
+	roles   []Role
+	members map[string][]string
+	// сколько раз выдавался токен
+	tokens int
+	// ответы с ошибкой по "METHOD path"
+	fail map[string]int
+	// изменяющие запросы в порядке поступления
+	changes []string
+}
+
+func newFakeKeycloak(t *testing.T, roles ...Role) (*fakeKeycloak, *Client) {
+	fake := &fakeKeycloak{members: map[string][]string{}, fail: map[string]int{}}
+	for _, role := range roles {
+		fake.addRole(role)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /realms/test/protocol/openid-connect/token", fake.token)
+	mux.HandleFunc("GET /admin/realms/test/roles", fake.admin(fake.listRoles))
+	mux.HandleFunc("POST /admin/realms/test/roles", fake.admin(fake.createRole))
+	mux.HandleFunc("PUT /admin/realms/test/roles/{name}", fake.admin(fake.updateRole))
+	mux.HandleFunc("GET /admin/realms/test/roles/{name}/users", fake.admin(fake.roleUsers))
+	mux.HandleFunc("POST /admin/realms/test/users/{id}/role-mappings/realm", fake.admin(fake.addMapping))
+	mux.HandleFunc("DELETE /admin/realms/test/users/{id}/role-mappings/realm", fake.admin(fake.removeMapping))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return fake, NewClient(server.URL+"/", "test", "idm-sync", "secret", server.Client())
+}
+
+func (f *fakeKeycloak) addRole(role Role, users ...string) {
+	if role.Id == "" {
+		role.Id = "kc-" + role.Name
+	}
+	f.roles = append(f.roles, role)
+	f.members[role.Name] = users
+}
+
+func (f *fakeKeycloak) role(name string) (Role, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, role := range f.roles {
+		if role.Name == name {
+			return role, true
+		}
+	}
+	return Role{}, false
+}
+
+func (f *fakeKeycloak) users(role string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Sorted(slices.Values(f.members[role]))
+}
+
+func (f *fakeKeycloak) token(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "idm-sync" ||
+		r.FormValue("client_secret") != "secret" {
+		http.Error(w, `{"error":"unauthorized_client"}`, http.StatusUnauthorized)
+		return
+	}
+	f.tokens++
+	_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "token-" + strconv.Itoa(f.tokens), "expires_in": 300})
+}
+
+// admin проверяет токен и подставляет ошибку из fail
+func (f *fakeKeycloak) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+			http.Error(w, `{"error":"HTTP 401 Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		if status, ok := f.fail[r.Method+" "+r.URL.Path]; ok {
+			http.Error(w, `{"error":"boom"}`, status)
+			return
+		}
+		if r.Method != http.MethodGet {
+			f.changes = append(f.changes, r.Method+" "+r.URL.Path)
+		}
+		next(w, r)
+	}
+}
+
+func (f *fakeKeycloak) listRoles(w http.ResponseWriter, r *http.Request) {
+	_ = json.NewEncoder(w).Encode(page(f.roles, r))
+}
+
+func (f *fakeKeycloak) createRole(w http.ResponseWriter, r *http.Request) {
+	var role Role
+	_ = json.NewDecoder(r.Body).Decode(&role)
+	if f.index(role.Name) >= 0 {
+		http.Error(w, `{"errorMessage":"Role with name exists"}`, http.StatusConflict)
+		return
+	}
+	f.addRole(role)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeKeycloak) updateRole(w http.ResponseWriter, r *http.Request) {
+	i := f.index(r.PathValue("name"))
+	if i < 0 {
+		http.Error(w, `{"error":"Could not find role"}`, http.StatusNotFound)
+		return
+	}
+	var role Role
+	_ = json.NewDecoder(r.Body).Decode(&role)
+	f.roles[i].Description = role.Description
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeKeycloak) roleUsers(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if f.index(name) < 0 {
+		http.Error(w, `{"error":"Could not find role"}`, http.StatusNotFound)
+		return
+	}
+	users := make([]User, 0)
+	for _, id := range f.members[name] {
+		users = append(users, User{Id: id, Username: "user-" + id})
+	}
+	_ = json.NewEncoder(w).Encode(page(users, r))
+}
+
+func (f *fakeKeycloak) addMapping(w http.ResponseWriter, r *http.Request) {
+	f.mapping(w, r, func(role string, userId string) {
+		if !slices.Contains(f.members[role], userId) {
+			f.members[role] = append(f.members[role], userId)
+		}
+	})
+}
+
+func (f *fakeKeycloak) removeMapping(w http.ResponseWriter, r *http.Request) {
+	f.mapping(w, r, func(role string, userId string) {
+		f.members[role] = slices.DeleteFunc(f.members[role], func(id string) bool { return id == userId })
+	})
+}
+
+func (f *fakeKeycloak) mapping(w http.ResponseWriter, r *http.Request, fn func(role string, userId string)) {
+	userId := r.PathValue("id")
+	if userId == "deleted" {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+	var roles []Role
+	_ = json.NewDecoder(r.Body).Decode(&roles)
+	for _, role := range roles {
+		if f.index(role.Name) < 0 {
+			http.Error(w, `{"error":"Could not find role"}`, http.StatusNotFound)
+			return
+		}
+		fn(role.Name, userId)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeKeycloak) index(name string) int {
+	return slices.IndexFunc(f.roles, func(role Role) bool { return role.Name == name })
+}
+
+// page страница списка по параметрам first и max
+func page[T any](items []T, r *http.Request) []T {
+	first, _ := strconv.Atoi(r.URL.Query().Get("first"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("max"))
+	if first >= len(items) {
+		return []T{}
+	}
+	return items[first:min(first+limit, len(items))]
+}
+
+func TestClient(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+
+	t.Run("should read all pages of roles and users", func(t *testing.T) {
+		fake, client := newFakeKeycloak(t)
+		users := make([]string, 0)
+		for i := range pageSize + 20 {
+			fake.addRole(Role{Name: fmt.Sprintf("role-%03d", i)})
+			users = append(users, fmt.Sprintf("user-%03d", i))
+		}
+		fake.members["role-000"] = users
+
+		roles, err := client.Roles(ctx)
+		a.NoError(err)
+		a.Len(roles, pageSize+20)
+		a.Equal(Role{Id: "kc-role-119", Name: "role-119"}, roles[pageSize+19])
+
+		got, err := client.RoleUsers(ctx, "role-000")
+		a.NoError(err)
+		a.Len(got, pageSize+20)
+		a.Equal(1, fake.tokens)
+	})
+
+	t.Run("should create, update and assign role", func(t *testing.T) {
+		fake, client := newFakeKeycloak(t)
+
+		a.NoError(client.CreateRole(ctx, Role{Name: "dev", Description: "developers"}))
+		a.NoError(client.UpdateRole(ctx, Role{Name: "dev", Description: "all developers"}))
+		a.NoError(client.AddRealmRole(ctx, "u-1", Role{Id: "kc-dev", Name: "dev"}))
+		a.NoError(client.AddRealmRole(ctx, "u-2", Role{Id: "kc-dev", Name: "dev"}))
+		a.NoError(client.RemoveRealmRole(ctx, "u-1", Role{Id: "kc-dev", Name: "dev"}))
+
+		role, _ := fake.role("dev")
+		a.Equal("all developers", role.Description)
+		a.Equal([]string{"u-2"}, fake.users("dev"))
+	})
+
+	t.Run("should return StatusError for unexpected status", func(t *testing.T) {
+		_, client := newFakeKeycloak(t, Role{Name: "dev"})
+
+		err := client.CreateRole(ctx, Role{Name: "dev"})
+
+		var statusErr StatusError
+		a.ErrorAs(err, &statusErr)
+		a.Equal(http.StatusConflict, statusErr.StatusCode)
+		a.Equal("/roles", statusErr.Path)
+		a.ErrorContains(err, "Role with name exists")
+	})
+
+	t.Run("should request new token after unauthorized response", func(t *testing.T) {
+		fake, client := newFakeKeycloak(t)
+		fake.fail["GET /admin/realms/test/roles"] = http.StatusUnauthorized
+
+		_, err := client.Roles(ctx)
+		a.Error(err)
+		delete(fake.fail, "GET /admin/realms/test/roles")
+		_, err = client.Roles(ctx)
+
+		a.NoError(err)
+		a.Equal(2, fake.tokens)
+	})
+
+	t.Run("should return error for wrong client secret", func(t *testing.T) {
+		_, client := newFakeKeycloak(t)
+		client.clientSecret = "wrong"
+
+		_, err := client.Roles(ctx)
+
+		a.ErrorContains(err, "unexpected status code 401")
+	})
+}
@@ -0,0 +1,114 @@
+package keycloak
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+)
+
+type Controller struct {
+	server          *web.Server
+	keycloakService Svc
+	logger          *common.Logger
+}
+
+// интерфейс сервиса keycloak.Service
+type Svc interface {
+	Sync(ctx context.Context, request SyncRequest) (Report, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:          server,
+		keycloakService: svc,
+		logger:          logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/keycloak-sync/plan", c.PlanSync)
+	c.server.GroupApiV1.Post("/keycloak-sync/apply", c.ApplySync)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/keycloak-sync/plan"
+// @Description Build the difference between IDM roles and Keycloak realm roles without applying it.
+// @Description Direction push, import or both; empty direction uses KEYCLOAK_SYNC_DIRECTION.
+// @Summary plan keycloak role sync
+// @ID plan-keycloak-sync
+// @Tags keycloak-sync
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body keycloak.SyncRequest false "sync direction"
+// @Success 200 {object} common.Response[keycloak.Report]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /keycloak-sync/plan [post]
+func (c *Controller) PlanSync(ctx *fiber.Ctx) error {
+	return c.sync(ctx, true)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/keycloak-sync/apply"
+// @Description Push IDM roles and assignments to Keycloak realm roles and/or import Keycloak realm roles into IDM.
+// @Description Keycloak roles are never deleted and users not linked to employees are not changed.
+// @Description Failed Keycloak actions are reported in errors, the rest is applied.
+// @Summary apply keycloak role sync
+// @ID apply-keycloak-sync
+// @Tags keycloak-sync
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body keycloak.SyncRequest false "sync direction"
+// @Success 200 {object} common.Response[keycloak.Report]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /keycloak-sync/apply [post]
+func (c *Controller) ApplySync(ctx *fiber.Ctx) error {
+	return c.sync(ctx, false)
+}
+
+func (c *Controller) sync(ctx *fiber.Ctx, dryRun bool) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// тело запроса необязательно
+	var request SyncRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&request); err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+		}
+	}
+	request.DryRun = dryRun
+	request.Actor = claims.Actor()
+
+	// вызываем метод Sync сервиса keycloak.Service
+	report, err := c.keycloakService.Sync(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "sync keycloak roles", zap.Bool("dry_run", dryRun), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, report); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "sync keycloak roles", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package keycloak
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса keycloak.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Sync(ctx context.Context, request SyncRequest) (Report, error) {
+	args := svc.Called(request)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_Sync(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should plan sync without body", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Sync", SyncRequest{DryRun: true, Actor: "kc-admin"}).
+			Return(Report{DryRun: true, Direction: DirectionPush, Actions: []Action{}, Errors: []string{}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/keycloak-sync/plan", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should apply sync in requested direction", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Sync", SyncRequest{Direction: DirectionImport, Actor: "kc-admin"}).
+			Return(Report{Direction: DirectionImport, RolesImported: 1}, nil)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/keycloak-sync/apply", strings.NewReader(`{"direction":"import"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 409 when keycloak is not configured", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Sync", mock.Anything).Return(Report{}, common.ConflictError{Message: "keycloak sync is not configured"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/keycloak-sync/apply", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("should return 403 without admin role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/keycloak-sync/apply", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Sync", mock.Anything)
+	})
+}
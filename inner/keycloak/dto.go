@@ -0,0 +1,100 @@
+package keycloak
+
+import (
+	"database/sql"
+)
+
+// Направления синхронизации
+const (
+	// роли и их назначения IDM переносятся в Keycloak
+	DirectionPush = "push"
+	// роли Keycloak, которых нет в IDM, заводятся в IDM вместе с назначениями сотрудникам
+	DirectionImport = "import"
+	// сначала import, затем push
+	DirectionBoth = "both"
+)
+
+// Типы действий синхронизации
+const (
+	ActionCreateRole       = "create_role"
+	ActionUpdateRole       = "update_role"
+	ActionAddUser          = "add_user"
+	ActionRemoveUser       = "remove_user"
+	ActionImportRole       = "import_role"
+	ActionImportAssignment = "import_assignment"
+)
+
+// система, которая записывается в role.external_system у импортированных ролей
+const ExternalSystem = "keycloak"
+
+// RoleEntity роль IDM в том виде, в котором её сравнивают с ролью Keycloak
+type RoleEntity struct {
+	Id             int64          `db:"id"`
+	Name           string         `db:"name"`
+	Description    string         `db:"description"`
+	ExternalSystem sql.NullString `db:"external_system"`
+	ExternalId     sql.NullString `db:"external_id"`
+}
+
+// EmployeeEntity сотрудник, связанный с пользователем Keycloak через subject токена
+type EmployeeEntity struct {
+	Id      int64  `db:"id"`
+	Subject string `db:"subject"`
+	Status  string `db:"status"`
+}
+
+// Action действие синхронизации; в пробном режиме - запланированное
+type Action struct {
+	Type string `json:"type"`
+	Role string `json:"role"`
+	// пользователь Keycloak, которому назначается или у которого отзывается роль
+	UserId     string `json:"user_id,omitempty"`
+	EmployeeId int64  `json:"employee_id,omitempty"`
+}
+
+// step запланированное действие в Keycloak и роль Keycloak, к которой оно относится
+type step struct {
+	action Action
+	role   Role
+}
+
+// roleMembers пользователи роли Keycloak или ошибка их чтения
+type roleMembers struct {
+	users []User
+	err   error
+}
+
+// Report разница между ролями IDM и Keycloak и результат синхронизации
+type Report struct {
+	// true, если изменения не применялись
+	DryRun              bool     `json:"dry_run"`
+	Direction           string   `json:"direction"`
+	RolesCreated        int      `json:"roles_created"`
+	RolesUpdated        int      `json:"roles_updated"`
+	UsersAdded          int      `json:"users_added"`
+	UsersRemoved        int      `json:"users_removed"`
+	RolesImported       int      `json:"roles_imported"`
+	AssignmentsImported int      `json:"assignments_imported"`
+	Actions             []Action `json:"actions"`
+	// действия, которые не удалось выполнить в Keycloak; остальные изменения применяются
+	Errors []string `json:"errors"`
+}
+
+// add добавляет действие в отчёт и учитывает его в счётчиках
+func (r *Report) add(action Action) {
+	r.Actions = append(r.Actions, action)
+	switch action.Type {
+	case ActionCreateRole:
+		r.RolesCreated++
+	case ActionUpdateRole:
+		r.RolesUpdated++
+	case ActionAddUser:
+		r.UsersAdded++
+	case ActionRemoveUser:
+		r.UsersRemoved++
+	case ActionImportRole:
+		r.RolesImported++
+	case ActionImportAssignment:
+		r.AssignmentsImported++
+	}
+}
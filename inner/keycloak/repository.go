@@ -0,0 +1,59 @@
+package keycloak
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/database"
+)
+
+// ключ advisory lock, не дающий запустить две синхронизации с Keycloak одновременно
+const lockKey = 20261021
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewKeycloakRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// LockTx ждёт окончания другой синхронизации; блокировка снимается по окончании транзакции
+func (r *Repository) LockTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey)
+	return err
+}
+
+// все роли IDM в рамках транзакции
+func (r *Repository) FindRolesTx(ctx context.Context, tx *sqlx.Tx) (roles []RoleEntity, err error) {
+	query := "SELECT id, name, description, external_system, external_id FROM role ORDER BY name"
+	err = tx.SelectContext(ctx, &roles, query)
+	return roles, err
+}
+
+// сотрудники, связанные с пользователями Keycloak
+func (r *Repository) FindLinkedEmployees(ctx context.Context) (employees []EmployeeEntity, err error) {
+	query := "SELECT id, subject, status FROM employee WHERE subject IS NOT NULL ORDER BY id"
+	err = r.db.SelectContext(ctx, &employees, query)
+	return employees, err
+}
+
+// добавить роль, импортированную из Keycloak, в рамках транзакции
+func (r *Repository) CreateRoleTx(ctx context.Context, tx *sqlx.Tx, role RoleEntity) (id int64, err error) {
+	query := `INSERT INTO role (name, description, external_system, external_id) VALUES ($1, $2, $3, $4) RETURNING id`
+	err = tx.GetContext(ctx, &id, query, role.Name, role.Description, role.ExternalSystem, role.ExternalId)
+	return id, database.MapUniqueViolation(err, fmt.Sprintf("role with name %s already exists", role.Name))
+}
+
+// назначить сотруднику импортированную роль в рамках транзакции
+func (r *Repository) AssignTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error {
+	query := `INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'manual')
+ON CONFLICT (employee_id, role_id) DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, employeeId, roleId)
+	return err
+}
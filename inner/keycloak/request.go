@@ -0,0 +1,11 @@
+package keycloak
+
+// SyncRequest запуск синхронизации ролей с Keycloak
+type SyncRequest struct {
+	// push, import или both; пусто - направление из конфигурации
+	Direction string `json:"direction" validate:"omitempty,oneof=push import both"`
+	// true - только построить разницу, ничего не меняя
+	DryRun bool `json:"-"`
+	// кто запустил синхронизацию, попадает в журнал аудита
+	Actor string `json:"-"`
+}
@@ -0,0 +1,456 @@
+package keycloak
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/nihrom205/idm/inner/web"
+	"slices"
+	"strings"
+)
+
+// роли Keycloak, которые не синхронизируются: по ролям IDM_* IDM проверяет доступ к своему API,
+// и синхронизация не должна лишать администраторов доступа; остальные - встроенные роли Keycloak
+var protectedRoles = []string{web.IdmAdmin, web.IdmUser, web.IdmScopedAdmin, web.IdmBreakGlass,
+	"offline_access", "uma_authorization"}
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	LockTx(ctx context.Context, tx *sqlx.Tx) error
+	FindRolesTx(ctx context.Context, tx *sqlx.Tx) ([]RoleEntity, error)
+	FindLinkedEmployees(ctx context.Context) ([]EmployeeEntity, error)
+	CreateRoleTx(ctx context.Context, tx *sqlx.Tx, role RoleEntity) (int64, error)
+	AssignTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, roleId int64) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции синхронизации
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// AccessSvc фактические роли сотрудника, реализуется access.Service
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+// Api Keycloak Admin REST API, реализуется Client
+type Api interface {
+	Roles(ctx context.Context) ([]Role, error)
+	CreateRole(ctx context.Context, role Role) error
+	UpdateRole(ctx context.Context, role Role) error
+	RoleUsers(ctx context.Context, roleName string) ([]User, error)
+	AddRealmRole(ctx context.Context, userId string, role Role) error
+	RemoveRealmRole(ctx context.Context, userId string, role Role) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	access    AccessSvc
+	api       Api
+	validator Validator
+	direction string
+}
+
+// NewService создаёт сервис синхронизации; если api == nil, синхронизация не настроена
+func NewService(repo Repo, audit AuditRepo, access AccessSvc, api Api, validator Validator, direction string) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		access:    access,
+		api:       api,
+		validator: validator,
+		direction: direction,
+	}
+}
+
+// Sync синхронизирует роли IDM с ролями области Keycloak. Роли сопоставляются по имени без учёта регистра,
+// пользователи Keycloak с сотрудниками - по subject. Push создаёт недостающие роли, обновляет описания
+// и приводит назначения ролей к фактическому доступу активных сотрудников; роли в Keycloak не удаляются,
+// а пользователи, не связанные с сотрудниками, не затрагиваются. Import заводит в IDM роли Keycloak
+// и назначает их связанным сотрудникам, чтобы следующий push их не отозвал.
+// Изменения IDM и план действий в Keycloak составляются в одной транзакции под блокировкой, а изменения
+// в Keycloak выполняются после её коммита, чтобы не держать блокировку на время запросов к Keycloak;
+// в пробном режиме ничего не меняется. Ошибки отдельных действий в Keycloak попадают в отчёт
+// и не мешают остальным
+func (s *Service) Sync(ctx context.Context, request SyncRequest) (Report, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return Report{}, common.NewRequestValidatorError(err)
+	}
+	if s.api == nil {
+		return Report{}, common.ConflictError{Message: "keycloak sync is not configured"}
+	}
+	direction := request.Direction
+	if direction == "" {
+		direction = s.direction
+	}
+
+	// желаемые назначения и состояние Keycloak читаются до транзакции
+	employees, err := s.repo.FindLinkedEmployees(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("error finding linked employees: %w", err)
+	}
+	desired, err := s.desired(ctx, employees)
+	if err != nil {
+		return Report{}, err
+	}
+	kcRoles, err := s.api.Roles(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("error listing keycloak roles: %w", err)
+	}
+	members := s.members(ctx, kcRoles)
+
+	report := Report{DryRun: request.DryRun, Direction: direction, Actions: []Action{}, Errors: []string{}}
+	steps, err := s.plan(ctx, request, direction, kcRoles, members, employees, desired, &report)
+	if err != nil {
+		return Report{}, err
+	}
+
+	// действия с ролью, которую не удалось создать, не выполняются
+	failed := make(map[string]bool)
+	for _, step := range steps {
+		if failed[step.action.Role] {
+			continue
+		}
+		if !s.apply(request.DryRun, &report, step.action, func() error { return s.call(ctx, step) }) {
+			failed[step.action.Role] = step.action.Type == ActionCreateRole
+		}
+	}
+	if request.DryRun {
+		return report, nil
+	}
+
+	if err = s.writeAudit(ctx, request.Actor, report); err != nil {
+		return Report{}, err
+	}
+	return report, nil
+}
+
+// plan под блокировкой импортирует роли Keycloak в IDM и составляет план действий в Keycloak.
+// В пробном режиме транзакция откатывается
+func (s *Service) plan(ctx context.Context, request SyncRequest, direction string, kcRoles []Role,
+	members map[string]roleMembers, employees []EmployeeEntity, desired map[string]map[string]bool,
+	report *Report) (steps []step, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("syncing keycloak roles panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("syncing keycloak roles: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil || request.DryRun {
+			// если произошла ошибка или это пробный запуск, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("syncing keycloak roles: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("syncing keycloak roles: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	if err = s.repo.LockTx(ctx, tx); err != nil {
+		return nil, fmt.Errorf("error locking keycloak sync: %w", err)
+	}
+	roles, err := s.repo.FindRolesTx(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding roles: %w", err)
+	}
+
+	if direction == DirectionImport || direction == DirectionBoth {
+		roles, err = s.importRoles(ctx, tx, request, kcRoles, members, roles, employees, desired, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if direction == DirectionPush || direction == DirectionBoth {
+		steps = s.push(kcRoles, members, roles, employees, desired, report)
+	}
+	return steps, nil
+}
+
+// members пользователи ролей Keycloak по имени роли; ошибка чтения попадает в отчёт, когда роль понадобится
+func (s *Service) members(ctx context.Context, kcRoles []Role) map[string]roleMembers {
+	members := make(map[string]roleMembers, len(kcRoles))
+	for _, kcRole := range kcRoles {
+		if protected(kcRole.Name) {
+			continue
+		}
+		users, err := s.api.RoleUsers(ctx, kcRole.Name)
+		members[kcRole.Name] = roleMembers{users: users, err: err}
+	}
+	return members
+}
+
+// writeAudit пишет в журнал аудита итог синхронизации
+func (s *Service) writeAudit(ctx context.Context, actor string, report Report) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("writing keycloak sync audit panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("writing keycloak sync audit: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("writing keycloak sync audit: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("writing keycloak sync audit: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "keycloak.synced",
+		EntityType: "keycloak",
+		Details: map[string]any{
+			"direction":            report.Direction,
+			"roles_created":        report.RolesCreated,
+			"roles_updated":        report.RolesUpdated,
+			"users_added":          report.UsersAdded,
+			"users_removed":        report.UsersRemoved,
+			"roles_imported":       report.RolesImported,
+			"assignments_imported": report.AssignmentsImported,
+			"errors":               len(report.Errors),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// desired subject пользователей, которым должна быть назначена роль, по имени роли.
+// Роли положены только активным сотрудникам: у остальных они отзываются
+func (s *Service) desired(ctx context.Context, employees []EmployeeEntity) (map[string]map[string]bool, error) {
+	desired := make(map[string]map[string]bool)
+	for _, employee := range employees {
+		if employee.Status != lifecycle.StatusActive {
+			continue
+		}
+		resolved, err := s.access.Resolve(ctx, employee.Id)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving access of employee %d: %w", employee.Id, err)
+		}
+		for _, role := range resolved.Roles {
+			addSubject(desired, role.RoleName, employee.Subject)
+		}
+	}
+	return desired, nil
+}
+
+// importRoles заводит в IDM роли Keycloak, которых в IDM нет, и назначает их связанным сотрудникам.
+// Возвращает роли IDM вместе с импортированными
+func (s *Service) importRoles(ctx context.Context, tx *sqlx.Tx, request SyncRequest, kcRoles []Role,
+	members map[string]roleMembers, roles []RoleEntity, employees []EmployeeEntity, desired map[string]map[string]bool,
+	report *Report) ([]RoleEntity, error) {
+	existing := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		// роль, имя которой отличается только регистром, второй раз не заводится
+		existing[strings.ToLower(role.Name)] = true
+	}
+	bySubject := make(map[string]EmployeeEntity, len(employees))
+	for _, employee := range employees {
+		bySubject[employee.Subject] = employee
+	}
+
+	for _, kcRole := range kcRoles {
+		// составные роли объединяют другие роли и в IDM не переносятся
+		if existing[strings.ToLower(kcRole.Name)] || kcRole.Composite || protected(kcRole.Name) {
+			continue
+		}
+		users, err := members[kcRole.Name].users, members[kcRole.Name].err
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("import role %s: %v", kcRole.Name, err))
+			continue
+		}
+		role := RoleEntity{
+			Name:           kcRole.Name,
+			Description:    kcRole.Description,
+			ExternalSystem: sql.NullString{String: ExternalSystem, Valid: true},
+			ExternalId:     sql.NullString{String: kcRole.Id, Valid: kcRole.Id != ""},
+		}
+		if !request.DryRun {
+			role.Id, err = s.repo.CreateRoleTx(ctx, tx, role)
+			if err != nil {
+				return nil, fmt.Errorf("error importing role %s: %w", role.Name, err)
+			}
+			err = s.audit.CreateTx(ctx, tx, audit.Entry{
+				Actor:      request.Actor,
+				Action:     "role.imported",
+				EntityType: "role",
+				EntityId:   role.Id,
+				Details:    map[string]string{"external_system": ExternalSystem, "external_id": kcRole.Id},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error writing audit: %w", err)
+			}
+		}
+		report.add(Action{Type: ActionImportRole, Role: role.Name})
+		roles = append(roles, role)
+		existing[strings.ToLower(role.Name)] = true
+
+		for _, user := range users {
+			employee, ok := bySubject[user.Id]
+			if !ok {
+				continue
+			}
+			if !request.DryRun {
+				if err := s.repo.AssignTx(ctx, tx, employee.Id, role.Id); err != nil {
+					return nil, fmt.Errorf("error importing role %s of employee %d: %w", role.Name, employee.Id, err)
+				}
+			}
+			report.add(Action{Type: ActionImportAssignment, Role: role.Name, UserId: user.Id, EmployeeId: employee.Id})
+			if employee.Status == lifecycle.StatusActive {
+				addSubject(desired, role.Name, user.Id)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// push составляет план действий, которые приводят роли и их назначения в Keycloak к ролям IDM
+func (s *Service) push(kcRoles []Role, members map[string]roleMembers, roles []RoleEntity,
+	employees []EmployeeEntity, desired map[string]map[string]bool, report *Report) []step {
+	byName := make(map[string]Role, len(kcRoles))
+	for _, kcRole := range kcRoles {
+		// из ролей, имена которых отличаются только регистром, берётся первая
+		if _, ok := byName[strings.ToLower(kcRole.Name)]; !ok {
+			byName[strings.ToLower(kcRole.Name)] = kcRole
+		}
+	}
+	linked := make(map[string]int64, len(employees))
+	for _, employee := range employees {
+		linked[employee.Subject] = employee.Id
+	}
+
+	var steps []step
+	for _, role := range roles {
+		if protected(role.Name) {
+			continue
+		}
+		kcRole, exists := byName[strings.ToLower(role.Name)]
+		switch {
+		case !exists:
+			kcRole = Role{Name: role.Name, Description: role.Description}
+			steps = append(steps, step{action: Action{Type: ActionCreateRole, Role: role.Name}, role: kcRole})
+		case kcRole.Description != role.Description:
+			kcRole.Description = role.Description
+			steps = append(steps, step{action: Action{Type: ActionUpdateRole, Role: role.Name}, role: kcRole})
+		}
+
+		// у только что созданной роли пользователей ещё нет
+		current := make(map[string]bool)
+		if exists {
+			if err := members[kcRole.Name].err; err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("list users of role %s: %v", role.Name, err))
+				continue
+			}
+			for _, user := range members[kcRole.Name].users {
+				current[user.Id] = true
+			}
+		}
+		mapped := Role{Id: kcRole.Id, Name: kcRole.Name}
+		for _, subject := range sortedKeys(desired[role.Name]) {
+			if current[subject] {
+				continue
+			}
+			action := Action{Type: ActionAddUser, Role: role.Name, UserId: subject, EmployeeId: linked[subject]}
+			steps = append(steps, step{action: action, role: mapped})
+		}
+		for _, userId := range sortedKeys(current) {
+			employeeId, ok := linked[userId]
+			if !ok || desired[role.Name][userId] {
+				continue
+			}
+			action := Action{Type: ActionRemoveUser, Role: role.Name, UserId: userId, EmployeeId: employeeId}
+			steps = append(steps, step{action: action, role: mapped})
+		}
+	}
+	return steps
+}
+
+// call выполняет запланированное действие в Keycloak
+func (s *Service) call(ctx context.Context, step step) error {
+	switch step.action.Type {
+	case ActionCreateRole:
+		return s.api.CreateRole(ctx, step.role)
+	case ActionUpdateRole:
+		return s.api.UpdateRole(ctx, step.role)
+	case ActionAddUser:
+		return s.api.AddRealmRole(ctx, step.action.UserId, step.role)
+	case ActionRemoveUser:
+		return s.api.RemoveRealmRole(ctx, step.action.UserId, step.role)
+	}
+	return fmt.Errorf("unknown keycloak sync action %s", step.action.Type)
+}
+
+// apply выполняет действие в Keycloak и учитывает его в отчёте; в пробном режиме только учитывает.
+// Возвращает false, если действие не удалось
+func (s *Service) apply(dryRun bool, report *Report, action Action, fn func() error) bool {
+	if !dryRun {
+		if err := fn(); err != nil {
+			target := action.Role
+			if action.UserId != "" {
+				target += " user " + action.UserId
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("%s %s: %v", action.Type, target, err))
+			return false
+		}
+	}
+	report.add(action)
+	return true
+}
+
+// protected роль не синхронизируется
+func protected(name string) bool {
+	return slices.Contains(protectedRoles, name) || strings.HasPrefix(name, "default-roles-")
+}
+
+func addSubject(desired map[string]map[string]bool, role string, subject string) {
+	if desired[role] == nil {
+		desired[role] = make(map[string]bool)
+	}
+	desired[role][subject] = true
+}
+
+func sortedKeys(items map[string]bool) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
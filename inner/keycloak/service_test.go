@@ -0,0 +1,256 @@
+package keycloak
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+var (
+	linkedQuery     = regexp.QuoteMeta("SELECT id, subject, status FROM employee WHERE subject IS NOT NULL ORDER BY id")
+	lockQuery       = regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")
+	rolesQuery      = regexp.QuoteMeta("SELECT id, name, description, external_system, external_id FROM role ORDER BY name")
+	createRoleQuery = regexp.QuoteMeta("INSERT INTO role (name, description, external_system, external_id) VALUES ($1, $2, $3, $4) RETURNING id")
+	assignQuery     = regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source) VALUES ($1, $2, 'manual')")
+	auditQuery      = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	roleColumns     = []string{"id", "name", "description", "external_system", "external_id"}
+	employeeColumns = []string{"id", "subject", "status"}
+)
+
+type MockAccessSvc struct {
+	mock.Mock
+}
+
+func (m *MockAccessSvc) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).(access.Response), args.Error(1)
+}
+
+func roles(employeeId int64, names ...string) access.Response {
+	response := access.Response{EmployeeId: employeeId, Status: "active", Roles: []access.Role{}}
+	for _, name := range names {
+		response.Roles = append(response.Roles, access.Role{RoleName: name})
+	}
+	return response
+}
+
+func newTestService(t *testing.T, api Api) (*Service, sqlmock.Sqlmock, *MockAccessSvc) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	accessSvc := &MockAccessSvc{}
+	srv := NewService(NewKeycloakRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), accessSvc, api,
+		validator.NewValidator(), DirectionPush)
+	return srv, dbMock, accessSvc
+}
+
+// pushFixture роли dev и db-admin в IDM; в Keycloak роль dev с устаревшим описанием и лишними пользователями
+func pushFixture(t *testing.T) (*fakeKeycloak, *Service, sqlmock.Sqlmock) {
+	fake, client := newFakeKeycloak(t)
+	fake.addRole(Role{Name: "dev", Description: "old"}, "u-2", "u-3", "svc-ci")
+	fake.addRole(Role{Name: "IDM_ADMIN"}, "u-admin")
+	fake.addRole(Role{Name: "default-roles-test", Composite: true}, "u-1")
+	fake.addRole(Role{Name: "offline_access"})
+	srv, dbMock, accessSvc := newTestService(t, client)
+	accessSvc.On("Resolve", int64(1)).Return(roles(1, "dev", "db-admin"), nil)
+	accessSvc.On("Resolve", int64(3)).Return(roles(3), nil)
+	dbMock.ExpectQuery(linkedQuery).WillReturnRows(sqlmock.NewRows(employeeColumns).
+		AddRow(1, "u-1", "active").AddRow(2, "u-2", "terminated").AddRow(3, "u-3", "active"))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	dbMock.ExpectQuery(rolesQuery).WillReturnRows(sqlmock.NewRows(roleColumns).
+		AddRow(10, "db-admin", "database administrators", nil, nil).
+		AddRow(11, "dev", "developers", nil, nil).
+		AddRow(12, "IDM_ADMIN", "", nil, nil))
+	return fake, srv, dbMock
+}
+
+// действия pushFixture в порядке выполнения
+var pushActions = []Action{
+	{Type: ActionCreateRole, Role: "db-admin"},
+	{Type: ActionAddUser, Role: "db-admin", UserId: "u-1", EmployeeId: 1},
+	{Type: ActionUpdateRole, Role: "dev"},
+	{Type: ActionAddUser, Role: "dev", UserId: "u-1", EmployeeId: 1},
+	{Type: ActionRemoveUser, Role: "dev", UserId: "u-2", EmployeeId: 2},
+	{Type: ActionRemoveUser, Role: "dev", UserId: "u-3", EmployeeId: 3},
+}
+
+func TestService_Sync(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+
+	t.Run("should push roles and assignments to keycloak", func(t *testing.T) {
+		fake, srv, dbMock := pushFixture(t)
+		dbMock.ExpectCommit()
+		// итог синхронизации пишется после изменений в Keycloak
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "keycloak.synced", "keycloak", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Sync(ctx, SyncRequest{Actor: "kc-admin"})
+
+		a.NoError(err)
+		a.Equal(Report{Direction: DirectionPush, RolesCreated: 1, RolesUpdated: 1, UsersAdded: 2, UsersRemoved: 2,
+			Actions: pushActions, Errors: []string{}}, report)
+		role, _ := fake.role("db-admin")
+		a.Equal("database administrators", role.Description)
+		a.Equal([]string{"u-1"}, fake.users("db-admin"))
+		role, _ = fake.role("dev")
+		a.Equal("developers", role.Description)
+		// пользователь, не связанный с сотрудником, остаётся
+		a.Equal([]string{"svc-ci", "u-1"}, fake.users("dev"))
+		// роли, по которым IDM проверяет доступ, не затрагиваются
+		a.Equal([]string{"u-admin"}, fake.users("IDM_ADMIN"))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should only plan changes in dry run", func(t *testing.T) {
+		fake, srv, dbMock := pushFixture(t)
+		dbMock.ExpectRollback()
+
+		report, err := srv.Sync(ctx, SyncRequest{DryRun: true, Actor: "kc-admin"})
+
+		a.NoError(err)
+		a.True(report.DryRun)
+		a.Equal(pushActions, report.Actions)
+		a.Empty(fake.changes)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should report failed keycloak actions and apply the rest", func(t *testing.T) {
+		fake, srv, dbMock := pushFixture(t)
+		fake.fail["POST /admin/realms/test/roles"] = http.StatusForbidden
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(auditQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Sync(ctx, SyncRequest{Actor: "kc-admin"})
+
+		a.NoError(err)
+		a.Len(report.Errors, 1)
+		a.Contains(report.Errors[0], "create_role db-admin: keycloak POST /roles: unexpected status code 403")
+		a.Equal(0, report.RolesCreated)
+		a.Equal(1, report.UsersAdded)
+		a.Equal([]string{"svc-ci", "u-1"}, fake.users("dev"))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should not change keycloak if transaction is not committed", func(t *testing.T) {
+		fake, srv, dbMock := pushFixture(t)
+		dbMock.ExpectCommit().WillReturnError(errors.New("connection reset"))
+
+		_, err := srv.Sync(ctx, SyncRequest{Actor: "kc-admin"})
+
+		a.ErrorContains(err, "commiting transaction error")
+		a.Empty(fake.changes)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should import keycloak roles with assignments of linked employees", func(t *testing.T) {
+		fake, client := newFakeKeycloak(t)
+		fake.addRole(Role{Name: "auditor", Description: "auditors"}, "u-1", "u-2", "svc-ci")
+		fake.addRole(Role{Name: "bundle", Composite: true}, "u-1")
+		fake.addRole(Role{Name: "Dev"})
+		// имена ролей уникальны без учёта регистра, вторая такая роль не импортируется
+		fake.addRole(Role{Name: "AUDITOR"}, "u-1")
+		srv, dbMock, accessSvc := newTestService(t, client)
+		accessSvc.On("Resolve", int64(1)).Return(roles(1, "dev"), nil)
+		dbMock.ExpectQuery(linkedQuery).WillReturnRows(sqlmock.NewRows(employeeColumns).
+			AddRow(1, "u-1", "active").AddRow(2, "u-2", "terminated"))
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		dbMock.ExpectQuery(rolesQuery).WillReturnRows(sqlmock.NewRows(roleColumns).AddRow(11, "dev", "", nil, nil))
+		dbMock.ExpectQuery(createRoleQuery).WithArgs("auditor", "auditors", ExternalSystem, "kc-auditor").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "role.imported", "role", int64(20), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(assignQuery).WithArgs(int64(1), int64(20)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectExec(assignQuery).WithArgs(int64(2), int64(20)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "keycloak.synced", "keycloak", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		report, err := srv.Sync(ctx, SyncRequest{Direction: DirectionBoth, Actor: "kc-admin"})
+
+		a.NoError(err)
+		a.Equal([]Action{
+			{Type: ActionImportRole, Role: "auditor"},
+			{Type: ActionImportAssignment, Role: "auditor", UserId: "u-1", EmployeeId: 1},
+			{Type: ActionImportAssignment, Role: "auditor", UserId: "u-2", EmployeeId: 2},
+			// роль dev сопоставляется с ролью Dev в Keycloak без учёта регистра
+			{Type: ActionAddUser, Role: "dev", UserId: "u-1", EmployeeId: 1},
+			// уволенный сотрудник теряет импортированную роль
+			{Type: ActionRemoveUser, Role: "auditor", UserId: "u-2", EmployeeId: 2},
+		}, report.Actions)
+		a.Equal([]string{"svc-ci", "u-1"}, fake.users("auditor"))
+		a.Equal([]string{"u-1"}, fake.users("Dev"))
+		_, created := fake.role("dev")
+		a.False(created)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return error when keycloak is unavailable", func(t *testing.T) {
+		fake, client := newFakeKeycloak(t)
+		fake.fail["GET /admin/realms/test/roles"] = http.StatusServiceUnavailable
+		srv, dbMock, _ := newTestService(t, client)
+		dbMock.ExpectQuery(linkedQuery).WillReturnRows(sqlmock.NewRows(employeeColumns))
+
+		_, err := srv.Sync(ctx, SyncRequest{Actor: "kc-admin"})
+
+		a.ErrorContains(err, "error listing keycloak roles")
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError when keycloak is not configured", func(t *testing.T) {
+		srv, _, _ := newTestService(t, nil)
+
+		_, err := srv.Sync(ctx, SyncRequest{Actor: "kc-admin"})
+
+		a.ErrorAs(err, &common.ConflictError{})
+	})
+
+	t.Run("should return RequestValidatorError for unknown direction", func(t *testing.T) {
+		_, client := newFakeKeycloak(t)
+		srv, _, _ := newTestService(t, client)
+
+		_, err := srv.Sync(ctx, SyncRequest{Direction: "pull", Actor: "kc-admin"})
+
+		a.ErrorAs(err, &common.RequestValidatorError{})
+	})
+}
+
+func TestProtected(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should protect every role IDM checks access by", func(t *testing.T) {
+		for _, name := range []string{web.IdmAdmin, web.IdmUser, web.IdmScopedAdmin, web.IdmBreakGlass} {
+			a.True(protected(name), name)
+		}
+	})
+
+	t.Run("should protect keycloak built-in roles", func(t *testing.T) {
+		for _, name := range []string{"offline_access", "uma_authorization", "default-roles-test"} {
+			a.True(protected(name), name)
+		}
+	})
+
+	t.Run("should sync other roles", func(t *testing.T) {
+		a.False(protected("dev"))
+		a.False(protected("IDM_AUDITOR"))
+	})
+}
@@ -0,0 +1,36 @@
+package keycloak
+
+import (
+	"context"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+	"time"
+)
+
+// от чьего имени синхронизация по расписанию пишется в журнал аудита
+const workerActor = "keycloak-sync"
+
+// Syncer синхронизирует роли с Keycloak, реализуется Service
+type Syncer interface {
+	Sync(ctx context.Context, request SyncRequest) (Report, error)
+}
+
+// ScheduledSync синхронизация ролей для планировщика в направлении из конфигурации: пишет отчёт в лог
+// и возвращает число выполненных действий
+func ScheduledSync(syncer Syncer, logger *common.Logger) func(ctx context.Context, now time.Time) (int, error) {
+	return func(ctx context.Context, now time.Time) (int, error) {
+		report, err := syncer.Sync(ctx, SyncRequest{Actor: workerActor})
+		if err != nil {
+			return 0, err
+		}
+		if len(report.Errors) > 0 {
+			logger.ErrorCtx(ctx, "keycloak sync: actions failed", zap.Strings("errors", report.Errors))
+		}
+		logger.DebugCtx(ctx, "keycloak sync: synced roles", zap.String("direction", report.Direction),
+			zap.Int("roles_created", report.RolesCreated), zap.Int("roles_updated", report.RolesUpdated),
+			zap.Int("users_added", report.UsersAdded), zap.Int("users_removed", report.UsersRemoved),
+			zap.Int("roles_imported", report.RolesImported), zap.Int("assignments_imported", report.AssignmentsImported))
+		return report.RolesCreated + report.RolesUpdated + report.UsersAdded + report.UsersRemoved +
+			report.RolesImported + report.AssignmentsImported, nil
+	}
+}
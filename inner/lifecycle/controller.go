@@ -0,0 +1,249 @@
+package lifecycle
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server           *web.Server
+	lifecycleService Svc
+	logger           *common.Logger
+}
+
+// интерфейс сервиса lifecycle.Service
+type Svc interface {
+	Transition(ctx context.Context, request TransitionRequest) (StatusResponse, error)
+	Schedule(ctx context.Context, request ScheduleRequest) (TransitionResponse, error)
+	FindTransitions(ctx context.Context, employeeId int64) ([]TransitionResponse, error)
+	CancelTransition(ctx context.Context, employeeId int64, id int64, actor string) error
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:           server,
+		lifecycleService: svc,
+		logger:           logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/employees/:id/status", c.ChangeStatus)
+	c.server.GroupApiV1.Get("/employees/:id/transitions", c.GetTransitions)
+	c.server.GroupApiV1.Post("/employees/:id/transitions", c.ScheduleTransition)
+	c.server.GroupApiV1.Delete("/employees/:id/transitions/:transitionId", c.CancelTransition)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/:id/status"
+// @Description Change employee status immediately. Termination revokes all roles, activation assigns roles by rules.
+// @Summary change employee status
+// @ID change-employee-status
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param request body lifecycle.TransitionRequest true "new status"
+// @Success 200 {object} common.Response[lifecycle.StatusResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/status [post]
+func (c *Controller) ChangeStatus(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	var request TransitionRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.EmployeeId = employeeId
+	request.Actor = claims.Actor()
+	c.logger.DebugCtx(ctx.Context(), "change employee status: received request", zap.Any("request", request))
+
+	// вызываем метод Transition сервиса lifecycle.Service
+	response, err := c.lifecycleService.Transition(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "change employee status", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "change employee status", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/employees/:id/transitions"
+// @Description Get scheduled and executed status transitions of employee.
+// @Summary get employee transitions
+// @ID get-employee-transitions
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Success 200 {object} common.Response[[]lifecycle.TransitionResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/transitions [get]
+func (c *Controller) GetTransitions(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// вызываем метод FindTransitions сервиса lifecycle.Service
+	response, err := c.lifecycleService.FindTransitions(ctx.Context(), employeeId)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee transitions", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get employee transitions", zap.Int64("employeeId", employeeId), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/employees/:id/transitions"
+// @Description Schedule employee status change, e.g. activation on start date or termination after last working day.
+// @Description A pending transition to the same status is replaced.
+// @Summary schedule employee transition
+// @ID schedule-employee-transition
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param request body lifecycle.ScheduleRequest true "status and effective date"
+// @Success 200 {object} common.Response[lifecycle.TransitionResponse]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/transitions [post]
+func (c *Controller) ScheduleTransition(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	var request ScheduleRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	request.EmployeeId = employeeId
+	request.Actor = claims.Actor()
+	c.logger.DebugCtx(ctx.Context(), "schedule employee transition: received request", zap.Any("request", request))
+
+	// вызываем метод Schedule сервиса lifecycle.Service
+	response, err := c.lifecycleService.Schedule(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "schedule employee transition", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "schedule employee transition", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/employees/:id/transitions/:transitionId"
+// @Description Cancel pending status transition of employee.
+// @Summary cancel employee transition
+// @ID cancel-employee-transition
+// @Tags lifecycle
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Param transitionId path int64 true "id transition"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /employees/{id}/transitions/{transitionId} [delete]
+func (c *Controller) CancelTransition(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	employeeId, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+	transitionId, err := strconv.ParseInt(ctx.Params("transitionId"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid transition id")
+	}
+
+	// вызываем метод CancelTransition сервиса lifecycle.Service
+	if err := c.lifecycleService.CancelTransition(ctx.Context(), employeeId, transitionId, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "cancel employee transition", zap.Int64("employeeId", employeeId),
+			zap.Int64("transitionId", transitionId), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "cancel employee transition", zap.Error(err))
+		return err
+	}
+	return nil
+}
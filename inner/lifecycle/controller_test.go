@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Объявляем структуру мока сервиса lifecycle.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Transition(ctx context.Context, request TransitionRequest) (StatusResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(StatusResponse), args.Error(1)
+}
+
+func (svc *MockService) Schedule(ctx context.Context, request ScheduleRequest) (TransitionResponse, error) {
+	args := svc.Called(request)
+	return args.Get(0).(TransitionResponse), args.Error(1)
+}
+
+func (svc *MockService) FindTransitions(ctx context.Context, employeeId int64) ([]TransitionResponse, error) {
+	args := svc.Called(employeeId)
+	return args.Get(0).([]TransitionResponse), args.Error(1)
+}
+
+func (svc *MockService) CancelTransition(ctx context.Context, employeeId int64, id int64, actor string) error {
+	args := svc.Called(employeeId, id, actor)
+	return args.Error(0)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_ChangeStatus(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should change status with actor from token", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		request := TransitionRequest{EmployeeId: 1, Status: StatusSuspended, Reason: "leave", Actor: "admin"}
+		svc.On("Transition", request).Return(StatusResponse{EmployeeId: 1, FromStatus: StatusActive, Status: StatusSuspended}, nil)
+
+		body := strings.NewReader(`{"status": "suspended", "reason": "leave"}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/status", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[StatusResponse]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(StatusActive, got.Data.FromStatus)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 409 for forbidden transition", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Transition", mock.Anything).Return(StatusResponse{}, common.ConflictError{Message: "cannot change status"})
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/status", strings.NewReader(`{"status": "suspended"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("should return 403 for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/status", strings.NewReader(`{"status": "terminated"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Transition", mock.Anything)
+	})
+}
+
+func TestController_ScheduleTransition(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	effectiveAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	svc.On("Schedule", ScheduleRequest{EmployeeId: 1, Status: StatusTerminated, EffectiveAt: effectiveAt, Actor: "admin"}).
+		Return(TransitionResponse{Id: 5, EmployeeId: 1, Status: StatusTerminated, State: StatePending}, nil)
+
+	body := strings.NewReader(`{"status": "terminated", "effective_at": "2026-11-01T00:00:00Z"}`)
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/1/transitions", body)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := server.App.Test(req)
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestController_CancelTransition(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("CancelTransition", int64(1), int64(5), "admin").Return(common.NotFoundError{Message: "not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/employees/1/transitions/5", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
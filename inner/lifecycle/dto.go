@@ -0,0 +1,108 @@
+package lifecycle
+
+import (
+	"database/sql"
+	"slices"
+	"time"
+)
+
+// Статусы жизненного цикла сотрудника
+const (
+	StatusPreHire    = "pre_hire"
+	StatusActive     = "active"
+	StatusSuspended  = "suspended"
+	StatusTerminated = "terminated"
+)
+
+// допустимые переходы между статусами
+var transitions = map[string][]string{
+	StatusPreHire:   {StatusActive, StatusTerminated},
+	StatusActive:    {StatusSuspended, StatusTerminated},
+	StatusSuspended: {StatusActive, StatusTerminated},
+	// повторный приём уволенного сотрудника
+	StatusTerminated: {StatusActive},
+}
+
+// CanTransition проверяет, можно ли перевести сотрудника из статуса from в статус to
+func CanTransition(from string, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// Состояния запланированной смены статуса
+const (
+	StatePending   = "pending"
+	StateDone      = "done"
+	StateFailed    = "failed"
+	StateCancelled = "cancelled"
+)
+
+// Employee сотрудник, статус которого меняется
+type Employee struct {
+	Id     int64  `db:"id"`
+	Name   string `db:"name"`
+	Status string `db:"status"`
+}
+
+// Event смена статуса или атрибутов сотрудника, передаётся в Hook
+type Event struct {
+	// сотрудник после изменения
+	Employee   Employee
+	FromStatus string
+	Actor      string
+}
+
+type StatusResponse struct {
+	EmployeeId int64  `json:"employee_id"`
+	FromStatus string `json:"from_status"`
+	Status     string `json:"status"`
+}
+
+// TransitionEntity запланированная смена статуса сотрудника
+type TransitionEntity struct {
+	Id          int64          `db:"id"`
+	EmployeeId  int64          `db:"employee_id"`
+	Status      string         `db:"status"`
+	EffectiveAt time.Time      `db:"effective_at"`
+	Reason      sql.NullString `db:"reason"`
+	Actor       string         `db:"actor"`
+	State       string         `db:"state"`
+	Error       sql.NullString `db:"error"`
+	CreateAt    time.Time      `db:"create_at"`
+	ExecuteAt   sql.NullTime   `db:"execute_at"`
+	// число неудачных попыток и время следующей
+	Attempts      int          `db:"attempts"`
+	NextAttemptAt sql.NullTime `db:"next_attempt_at"`
+}
+
+func (e *TransitionEntity) toResponse() TransitionResponse {
+	response := TransitionResponse{
+		Id:          e.Id,
+		EmployeeId:  e.EmployeeId,
+		Status:      e.Status,
+		EffectiveAt: e.EffectiveAt,
+		Reason:      e.Reason.String,
+		Actor:       e.Actor,
+		State:       e.State,
+		Error:       e.Error.String,
+		Attempts:    e.Attempts,
+		CreateAt:    e.CreateAt,
+	}
+	if e.ExecuteAt.Valid {
+		response.ExecuteAt = &e.ExecuteAt.Time
+	}
+	return response
+}
+
+type TransitionResponse struct {
+	Id          int64      `json:"id"`
+	EmployeeId  int64      `json:"employee_id"`
+	Status      string     `json:"status"`
+	EffectiveAt time.Time  `json:"effective_at"`
+	Reason      string     `json:"reason,omitempty"`
+	Actor       string     `json:"actor"`
+	State       string     `json:"state"`
+	Error       string     `json:"error,omitempty"`
+	Attempts    int        `json:"attempts"`
+	CreateAt    time.Time  `json:"create_at"`
+	ExecuteAt   *time.Time `json:"execute_at,omitempty"`
+}
@@ -0,0 +1,125 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+	"time"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewLifecycleRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти сотрудника и заблокировать его запись до конца транзакции
+func (r *Repository) FindEmployeeTx(ctx context.Context, tx *sqlx.Tx, id int64) (employee Employee, err error) {
+	query := "SELECT id, name, status FROM employee WHERE id = $1 FOR UPDATE"
+	err = tx.GetContext(ctx, &employee, query, id)
+	return employee, err
+}
+
+// изменить статус сотрудника в рамках транзакции
+func (r *Repository) UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, status string) error {
+	query := "UPDATE employee SET status = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, status, id)
+	return err
+}
+
+// отозвать у сотрудника все роли в рамках транзакции
+func (r *Repository) RevokeAllTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM employee_role WHERE employee_id = $1", employeeId)
+	return err
+}
+
+// отменить запланированные переходы сотрудника в статус status в рамках транзакции
+func (r *Repository) CancelPendingTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, status string) error {
+	query := "UPDATE employee_transition SET state = 'cancelled' WHERE employee_id = $1 AND status = $2 AND state = 'pending'"
+	_, err := tx.ExecContext(ctx, query, employeeId, status)
+	return err
+}
+
+// запланировать переход в рамках транзакции
+func (r *Repository) CreateTransitionTx(ctx context.Context, tx *sqlx.Tx, transition TransitionEntity) (created TransitionEntity, err error) {
+	query := `INSERT INTO employee_transition (employee_id, status, effective_at, reason, actor)
+VALUES ($1, $2, $3, $4, $5) RETURNING *`
+	err = tx.GetContext(ctx, &created, query,
+		transition.EmployeeId, transition.Status, transition.EffectiveAt, transition.Reason, transition.Actor)
+	return created, mapError(err, "employee not found")
+}
+
+// найти переходы сотрудника
+func (r *Repository) FindTransitions(ctx context.Context, employeeId int64) (result []TransitionEntity, err error) {
+	query := "SELECT * FROM employee_transition WHERE employee_id = $1 ORDER BY effective_at DESC, id DESC"
+	err = r.db.SelectContext(ctx, &result, query, employeeId)
+	return result, err
+}
+
+// отменить запланированный переход; false, если такого ожидающего перехода нет
+func (r *Repository) CancelTransitionTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, id int64) (bool, error) {
+	query := "UPDATE employee_transition SET state = 'cancelled' WHERE id = $1 AND employee_id = $2 AND state = 'pending'"
+	result, err := tx.ExecContext(ctx, query, id, employeeId)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// FindDueTx находит наступивший переход и блокирует его. Заблокированные другими
+// экземплярами приложения переходы пропускаются
+func (r *Repository) FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (transition TransitionEntity, err error) {
+	query := `SELECT * FROM employee_transition WHERE state = 'pending' AND effective_at <= $1
+AND (next_attempt_at IS NULL OR next_attempt_at <= $1)
+ORDER BY effective_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	err = tx.GetContext(ctx, &transition, query, now)
+	return transition, err
+}
+
+// отметить переход выполненным или невыполненным в рамках транзакции; attempts - число неудачных попыток
+func (r *Repository) FinishTransitionTx(ctx context.Context, tx *sqlx.Tx, id int64, state string, attempts int, errMessage string) error {
+	query := "UPDATE employee_transition SET state = $1, attempts = $2, error = NULLIF($3, ''), execute_at = now() WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, state, attempts, errMessage, id)
+	return err
+}
+
+// отложить переход после неудачной попытки в рамках транзакции
+func (r *Repository) RetryTransitionTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, nextAttemptAt time.Time, errMessage string) error {
+	query := "UPDATE employee_transition SET attempts = $2, next_attempt_at = $3, error = $4 WHERE id = $1"
+	_, err := tx.ExecContext(ctx, query, id, attempts, nextAttemptAt, errMessage)
+	return err
+}
+
+// SavepointTx создаёт точку сохранения в транзакции
+func (r *Repository) SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+// RollbackToSavepointTx откатывает транзакцию к точке сохранения
+func (r *Repository) RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// mapError переводит нарушение внешнего ключа в NotFoundError
+func mapError(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: message}
+	}
+	return err
+}
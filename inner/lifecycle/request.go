@@ -0,0 +1,21 @@
+package lifecycle
+
+import "time"
+
+// TransitionRequest немедленная смена статуса сотрудника
+type TransitionRequest struct {
+	EmployeeId int64  `json:"-" validate:"required,gt=0"`
+	Status     string `json:"status" validate:"required,oneof=pre_hire active suspended terminated"`
+	Reason     string `json:"reason" validate:"max=500"`
+	// кто меняет статус, попадает в журнал аудита
+	Actor string `json:"-"`
+}
+
+// ScheduleRequest смена статуса сотрудника в будущем, например в дату выхода или после последнего рабочего дня
+type ScheduleRequest struct {
+	EmployeeId  int64     `json:"-" validate:"required,gt=0"`
+	Status      string    `json:"status" validate:"required,oneof=pre_hire active suspended terminated"`
+	EffectiveAt time.Time `json:"effective_at" validate:"required"`
+	Reason      string    `json:"reason" validate:"max=500"`
+	Actor       string    `json:"-"`
+}
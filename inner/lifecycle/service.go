@@ -0,0 +1,430 @@
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"time"
+)
+
+const (
+	// сколько наступивших переходов выполняется за один запуск ExecuteDue
+	maxDuePerRun = 100
+	// после стольких неудачных попыток переход отмечается как failed
+	maxAttempts = 5
+	// задержка перед повтором перехода растёт с числом попыток
+	retryDelay = 5 * time.Minute
+	// точка сохранения перед выполнением перехода: изменения неудачной попытки откатываются до неё
+	transitionSavepoint = "transition"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	FindEmployeeTx(ctx context.Context, tx *sqlx.Tx, id int64) (Employee, error)
+	UpdateStatusTx(ctx context.Context, tx *sqlx.Tx, id int64, status string) error
+	RevokeAllTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error
+	CancelPendingTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, status string) error
+	CreateTransitionTx(ctx context.Context, tx *sqlx.Tx, transition TransitionEntity) (TransitionEntity, error)
+	FindTransitions(ctx context.Context, employeeId int64) ([]TransitionEntity, error)
+	CancelTransitionTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, id int64) (bool, error)
+	FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (TransitionEntity, error)
+	FinishTransitionTx(ctx context.Context, tx *sqlx.Tx, id int64, state string, attempts int, errMessage string) error
+	RetryTransitionTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, nextAttemptAt time.Time, errMessage string) error
+	SavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+	RollbackToSavepointTx(ctx context.Context, tx *sqlx.Tx, name string) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// Hook действие, которое выполняется в транзакции смены статуса или отдела сотрудника
+type Hook func(ctx context.Context, tx *sqlx.Tx, event Event) error
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	validator Validator
+	hooks     []Hook
+}
+
+// NewService создаёт сервис с действием по умолчанию: отзывом всех ролей при увольнении
+func NewService(repo Repo, audit AuditRepo, validator Validator) *Service {
+	s := &Service{
+		repo:      repo,
+		audit:     audit,
+		validator: validator,
+	}
+	s.hooks = []Hook{s.revokeOnTermination}
+	return s
+}
+
+// AddHook добавляет действие, выполняемое при смене статуса или атрибутов сотрудника
+func (s *Service) AddHook(hook Hook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// Transition сразу меняет статус сотрудника
+func (s *Service) Transition(ctx context.Context, request TransitionRequest) (response StatusResponse, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return StatusResponse{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("changing employee status panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing employee status: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("changing employee status: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("changing employee status: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	response, err = s.TransitionTx(ctx, tx, request)
+	return response, err
+}
+
+// TransitionTx меняет статус сотрудника в рамках транзакции вызывающего и выполняет действия Hook.
+// Недопустимый переход возвращает common.ConflictError, переход в текущий статус ничего не делает
+func (s *Service) TransitionTx(ctx context.Context, tx *sqlx.Tx, request TransitionRequest) (StatusResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return StatusResponse{}, common.NewRequestValidatorError(err)
+	}
+	employee, err := s.findEmployee(ctx, tx, request.EmployeeId)
+	if err != nil {
+		return StatusResponse{}, err
+	}
+	response := StatusResponse{EmployeeId: employee.Id, FromStatus: employee.Status, Status: request.Status}
+	if employee.Status == request.Status {
+		return response, nil
+	}
+	if !CanTransition(employee.Status, request.Status) {
+		return StatusResponse{}, common.ConflictError{
+			Message: fmt.Sprintf("employee %d cannot change status from %s to %s", employee.Id, employee.Status, request.Status),
+		}
+	}
+
+	if err := s.repo.UpdateStatusTx(ctx, tx, employee.Id, request.Status); err != nil {
+		return StatusResponse{}, fmt.Errorf("error updating status of employee %d: %w", employee.Id, err)
+	}
+	event := Event{
+		Employee:   employee,
+		FromStatus: employee.Status,
+		Actor:      request.Actor,
+	}
+	event.Employee.Status = request.Status
+	if err := s.runHooks(ctx, tx, event); err != nil {
+		return StatusResponse{}, err
+	}
+
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      request.Actor,
+		Action:     "employee.status_changed",
+		EntityType: "employee",
+		EntityId:   employee.Id,
+		Details:    map[string]string{"from": employee.Status, "to": request.Status, "reason": request.Reason},
+	})
+	if err != nil {
+		return StatusResponse{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return response, nil
+}
+
+// AttributesChangedTx выполняет действия Hook после того, как у сотрудника сменились отдел или должность
+func (s *Service) AttributesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, actor string) error {
+	employee, err := s.findEmployee(ctx, tx, employeeId)
+	if err != nil {
+		return err
+	}
+	return s.runHooks(ctx, tx, Event{
+		Employee:   employee,
+		FromStatus: employee.Status,
+		Actor:      actor,
+	})
+}
+
+func (s *Service) findEmployee(ctx context.Context, tx *sqlx.Tx, id int64) (Employee, error) {
+	employee, err := s.repo.FindEmployeeTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Employee{}, common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", id)}
+	}
+	if err != nil {
+		return Employee{}, fmt.Errorf("error finding employee with id %d: %w", id, err)
+	}
+	return employee, nil
+}
+
+func (s *Service) runHooks(ctx context.Context, tx *sqlx.Tx, event Event) error {
+	for _, hook := range s.hooks {
+		if err := hook(ctx, tx, event); err != nil {
+			return fmt.Errorf("error processing employee %d: %w", event.Employee.Id, err)
+		}
+	}
+	return nil
+}
+
+// revokeOnTermination отзывает все роли уволенного сотрудника
+func (s *Service) revokeOnTermination(ctx context.Context, tx *sqlx.Tx, event Event) error {
+	if event.Employee.Status != StatusTerminated {
+		return nil
+	}
+	return s.repo.RevokeAllTx(ctx, tx, event.Employee.Id)
+}
+
+// Schedule планирует смену статуса сотрудника на дату effective_at
+func (s *Service) Schedule(ctx context.Context, request ScheduleRequest) (response TransitionResponse, err error) {
+	if err = s.validator.Validate(request); err != nil {
+		return TransitionResponse{}, common.NewRequestValidatorError(err)
+	}
+	if !request.EffectiveAt.After(time.Now()) {
+		return TransitionResponse{}, common.RequestValidatorError{Message: "effective_at must be in the future"}
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return TransitionResponse{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("scheduling transition panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("scheduling transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("scheduling transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("scheduling transition: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	response, err = s.ScheduleTx(ctx, tx, request)
+	return response, err
+}
+
+// ScheduleTx планирует смену статуса в рамках транзакции вызывающего. Ранее запланированный
+// переход сотрудника в тот же статус отменяется, поэтому повторное планирование не создаёт дублей
+func (s *Service) ScheduleTx(ctx context.Context, tx *sqlx.Tx, request ScheduleRequest) (TransitionResponse, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return TransitionResponse{}, common.NewRequestValidatorError(err)
+	}
+	if err := s.repo.CancelPendingTx(ctx, tx, request.EmployeeId, request.Status); err != nil {
+		return TransitionResponse{}, fmt.Errorf("error cancelling pending transitions: %w", err)
+	}
+	created, err := s.repo.CreateTransitionTx(ctx, tx, TransitionEntity{
+		EmployeeId:  request.EmployeeId,
+		Status:      request.Status,
+		EffectiveAt: request.EffectiveAt,
+		Reason:      sql.NullString{String: request.Reason, Valid: request.Reason != ""},
+		Actor:       request.Actor,
+	})
+	if err != nil {
+		return TransitionResponse{}, fmt.Errorf("error scheduling transition: %w", err)
+	}
+
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      request.Actor,
+		Action:     "employee.transition_scheduled",
+		EntityType: "employee",
+		EntityId:   request.EmployeeId,
+		Details:    created.toResponse(),
+	})
+	if err != nil {
+		return TransitionResponse{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return created.toResponse(), nil
+}
+
+func (s *Service) FindTransitions(ctx context.Context, employeeId int64) ([]TransitionResponse, error) {
+	found, err := s.repo.FindTransitions(ctx, employeeId)
+	if err != nil {
+		return []TransitionResponse{}, fmt.Errorf("error finding transitions of employee %d: %w", employeeId, err)
+	}
+	response := make([]TransitionResponse, 0, len(found))
+	for _, item := range found {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+// CancelTransition отменяет запланированную смену статуса
+func (s *Service) CancelTransition(ctx context.Context, employeeId int64, id int64, actor string) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cancelling transition panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("cancelling transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("cancelling transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("cancelling transition: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	cancelled, err := s.repo.CancelTransitionTx(ctx, tx, employeeId, id)
+	if err != nil {
+		return fmt.Errorf("error cancelling transition %d: %w", id, err)
+	}
+	if !cancelled {
+		err = common.NotFoundError{Message: fmt.Sprintf("pending transition with id %d not found", id)}
+		return err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "employee.transition_cancelled",
+		EntityType: "employee",
+		EntityId:   employeeId,
+		Details:    map[string]int64{"transition_id": id},
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// ExecuteDue выполняет наступившие переходы, каждый в своей транзакции.
+// Переход, который стал недопустимым (сотрудник уже уволен или удалён и т.п.), отмечается как failed.
+// После других ошибок, в том числе ошибок действий Hook, переход откладывается и повторяется,
+// пока не исчерпает maxAttempts попыток, чтобы не задерживать следующие переходы
+func (s *Service) ExecuteDue(ctx context.Context, now time.Time) (executed int, err error) {
+	for executed < maxDuePerRun {
+		found, err := s.executeNext(ctx, now)
+		if err != nil {
+			return executed, err
+		}
+		if !found {
+			break
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// executeNext выполняет один наступивший переход; false, если таких переходов нет
+func (s *Service) executeNext(ctx context.Context, now time.Time) (found bool, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return false, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executing transition panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing transition: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("executing transition: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	transition, err := s.repo.FindDueTx(ctx, tx, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error finding due transitions: %w", err)
+	}
+
+	if err = s.repo.SavepointTx(ctx, tx, transitionSavepoint); err != nil {
+		return false, fmt.Errorf("error executing transition %d: %w", transition.Id, err)
+	}
+	_, errTransition := s.TransitionTx(ctx, tx, TransitionRequest{
+		EmployeeId: transition.EmployeeId,
+		Status:     transition.Status,
+		Reason:     transition.Reason.String,
+		Actor:      transition.Actor,
+	})
+	if errTransition == nil {
+		if err = s.repo.FinishTransitionTx(ctx, tx, transition.Id, StateDone, transition.Attempts, ""); err != nil {
+			return false, fmt.Errorf("error finishing transition %d: %w", transition.Id, err)
+		}
+		return true, nil
+	}
+
+	// в транзакции остаётся только отметка о неудачной попытке
+	if err = s.repo.RollbackToSavepointTx(ctx, tx, transitionSavepoint); err != nil {
+		return false, fmt.Errorf("error executing transition %d: %w, %w", transition.Id, errTransition, err)
+	}
+	attempts := transition.Attempts + 1
+	if permanentError(errTransition) || attempts >= maxAttempts {
+		if err = s.repo.FinishTransitionTx(ctx, tx, transition.Id, StateFailed, attempts, errTransition.Error()); err != nil {
+			return false, fmt.Errorf("error finishing transition %d: %w", transition.Id, err)
+		}
+		return true, nil
+	}
+	nextAttemptAt := now.Add(time.Duration(attempts) * retryDelay)
+	if err = s.repo.RetryTransitionTx(ctx, tx, transition.Id, attempts, nextAttemptAt, errTransition.Error()); err != nil {
+		return false, fmt.Errorf("error postponing transition %d: %w", transition.Id, err)
+	}
+	return true, nil
+}
+
+// permanentError ошибка, которая повторится при любой следующей попытке перехода
+func permanentError(err error) bool {
+	var conflictErr common.ConflictError
+	var notFoundErr common.NotFoundError
+	var validatorErr common.RequestValidatorError
+	return errors.As(err, &conflictErr) || errors.As(err, &notFoundErr) || errors.As(err, &validatorErr)
+}
@@ -0,0 +1,327 @@
+package lifecycle
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	findEmployeeQuery  = regexp.QuoteMeta("SELECT id, name, status FROM employee WHERE id = $1 FOR UPDATE")
+	updateStatusQuery  = regexp.QuoteMeta("UPDATE employee SET status = $1, update_at = now() WHERE id = $2")
+	revokeAllQuery     = regexp.QuoteMeta("DELETE FROM employee_role WHERE employee_id = $1")
+	auditQuery         = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	findDueQuery       = regexp.QuoteMeta("SELECT * FROM employee_transition WHERE state = 'pending' AND effective_at <= $1")
+	finishQuery        = regexp.QuoteMeta("UPDATE employee_transition SET state = $1, attempts = $2, error = NULLIF($3, ''), execute_at = now() WHERE id = $4")
+	retryQuery         = regexp.QuoteMeta("UPDATE employee_transition SET attempts = $2, next_attempt_at = $3, error = $4 WHERE id = $1")
+	savepointQuery     = regexp.QuoteMeta("SAVEPOINT transition")
+	rollbackToQuery    = regexp.QuoteMeta("ROLLBACK TO SAVEPOINT transition")
+	cancelPendingQuery = regexp.QuoteMeta("UPDATE employee_transition SET state = 'cancelled' WHERE employee_id = $1 AND status = $2 AND state = 'pending'")
+	createTransition   = regexp.QuoteMeta("INSERT INTO employee_transition (employee_id, status, effective_at, reason, actor)")
+	employeeColumns    = []string{"id", "name", "status"}
+	transitionColumns  = []string{"id", "employee_id", "status", "effective_at", "reason", "actor", "state", "error", "create_at", "execute_at"}
+)
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	return NewService(NewLifecycleRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), validator.NewValidator()), mock
+}
+
+func TestCanTransition(t *testing.T) {
+	var a = assert.New(t)
+
+	a.True(CanTransition(StatusPreHire, StatusActive))
+	a.True(CanTransition(StatusActive, StatusSuspended))
+	a.True(CanTransition(StatusSuspended, StatusTerminated))
+	a.True(CanTransition(StatusTerminated, StatusActive))
+	a.False(CanTransition(StatusTerminated, StatusSuspended))
+	a.False(CanTransition(StatusActive, StatusPreHire))
+	a.False(CanTransition("unknown", StatusActive))
+}
+
+func TestService_Transition(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should revoke all roles on termination", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusActive))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusTerminated, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(revokeAllQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "employee.status_changed", "employee", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 1, Status: StatusTerminated, Actor: "admin"})
+
+		a.Nil(err)
+		a.Equal(StatusResponse{EmployeeId: 1, FromStatus: StatusActive, Status: StatusTerminated}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should run added hook on activation", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		var got []Event
+		srv.AddHook(func(ctx context.Context, tx *sqlx.Tx, event Event) error {
+			got = append(got, event)
+			return nil
+		})
+		mock.ExpectBegin()
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusPreHire))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusActive, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 1, Status: StatusActive, Actor: "admin"})
+
+		a.Nil(err)
+		a.Equal([]Event{{
+			Employee:   Employee{Id: 1, Name: "john doe", Status: StatusActive},
+			FromStatus: StatusPreHire,
+			Actor:      "admin",
+		}}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject forbidden transition", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusTerminated))
+		mock.ExpectRollback()
+
+		_, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 1, Status: StatusSuspended})
+
+		var conflictErr common.ConflictError
+		a.True(errors.As(err, &conflictErr))
+		a.Equal("employee 1 cannot change status from terminated to suspended", conflictErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should do nothing for current status", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusActive))
+		mock.ExpectCommit()
+
+		_, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 1, Status: StatusActive})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return not found for unknown employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(9)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 9, Status: StatusActive})
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject unknown status", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		_, err := srv.Transition(context.Background(), TransitionRequest{EmployeeId: 1, Status: "retired"})
+
+		var validatorErr common.RequestValidatorError
+		a.True(errors.As(err, &validatorErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Schedule(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should replace pending transition to the same status", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		effectiveAt := time.Now().Add(24 * time.Hour)
+		mock.ExpectBegin()
+		mock.ExpectExec(cancelPendingQuery).WithArgs(int64(1), StatusTerminated).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(createTransition).WillReturnRows(sqlmock.NewRows(transitionColumns).
+			AddRow(5, 1, StatusTerminated, effectiveAt, nil, "admin", StatePending, nil, time.Now(), nil))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "employee.transition_scheduled", "employee", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Schedule(context.Background(), ScheduleRequest{
+			EmployeeId:  1,
+			Status:      StatusTerminated,
+			EffectiveAt: effectiveAt,
+			Actor:       "admin",
+		})
+
+		a.Nil(err)
+		a.Equal(int64(5), got.Id)
+		a.Equal(StatePending, got.State)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject date in the past", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		_, err := srv.Schedule(context.Background(), ScheduleRequest{
+			EmployeeId:  1,
+			Status:      StatusTerminated,
+			EffectiveAt: time.Now().Add(-time.Hour),
+		})
+
+		var validatorErr common.RequestValidatorError
+		a.True(errors.As(err, &validatorErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_ExecuteDue(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+	attemptColumns := append(transitionColumns, "attempts", "next_attempt_at")
+
+	t.Run("should postpone transition after database error and execute the next one", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		// на первом переходе ошибка базы данных: изменения откатываются, попытка записывается
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(transitionColumns).
+			AddRow(6, 2, StatusActive, now, nil, "admin", StatePending, nil, now, nil))
+		mock.ExpectExec(savepointQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(2, "jane doe", StatusSuspended))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusActive, int64(2)).WillReturnError(errors.New("database error"))
+		mock.ExpectExec(rollbackToQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(retryQuery).
+			WithArgs(int64(6), 1, now.Add(retryDelay), "error updating status of employee 2: database error").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		// следующий переход выполняется
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(transitionColumns).
+			AddRow(7, 1, StatusSuspended, now, nil, "admin", StatePending, nil, now, nil))
+		mock.ExpectExec(savepointQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusActive))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusSuspended, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(finishQuery).WithArgs(StateDone, 0, "", int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(2, executed)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should postpone transition when hook fails", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		srv.AddHook(func(ctx context.Context, tx *sqlx.Tx, event Event) error {
+			return errors.New("target system unavailable")
+		})
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(attemptColumns).
+			AddRow(5, 1, StatusSuspended, now, nil, "admin", StatePending, "previous error", now, nil, 2, now))
+		mock.ExpectExec(savepointQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusActive))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusSuspended, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(rollbackToQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(retryQuery).WithArgs(int64(5), 3, now.Add(3*retryDelay), "error processing employee 1: target system unavailable").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should mark transition failed after last attempt", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		srv.AddHook(func(ctx context.Context, tx *sqlx.Tx, event Event) error {
+			return errors.New("target system unavailable")
+		})
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(attemptColumns).
+			AddRow(5, 1, StatusSuspended, now, nil, "admin", StatePending, nil, now, nil, maxAttempts-1, now))
+		mock.ExpectExec(savepointQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusActive))
+		mock.ExpectExec(updateStatusQuery).WithArgs(StatusSuspended, int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(rollbackToQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(finishQuery).WithArgs(StateFailed, maxAttempts, "error processing employee 1: target system unavailable", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should mark forbidden transition failed", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(transitionColumns).
+			AddRow(5, 1, StatusSuspended, now, nil, "admin", StatePending, nil, now, nil))
+		mock.ExpectExec(savepointQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findEmployeeQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(employeeColumns).AddRow(1, "john doe", StatusTerminated))
+		mock.ExpectExec(rollbackToQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(finishQuery).
+			WithArgs(StateFailed, 1, "employee 1 cannot change status from terminated to suspended", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnError(sql.ErrNoRows)
+		mock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Nil(err)
+		a.Equal(1, executed)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should stop on database error when finding transitions", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findDueQuery).WithArgs(now).WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		executed, err := srv.ExecuteDue(context.Background(), now)
+
+		a.Equal(0, executed)
+		a.ErrorContains(err, "error finding due transitions")
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,171 @@
+package me
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+)
+
+type Controller struct {
+	server    *web.Server
+	meService Svc
+	logger    *common.Logger
+}
+
+// интерфейс сервиса me.Service
+type Svc interface {
+	Me(ctx context.Context, caller Caller) (employee.Response, error)
+	Provision(ctx context.Context, caller Caller) (employee.Response, error)
+	Roles(ctx context.Context, caller Caller) (access.Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:    server,
+		meService: svc,
+		logger:    logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/me", c.GetMe)
+	c.server.GroupApiV1.Post("/me", c.PostMe)
+	c.server.GroupApiV1.Get("/me/roles", c.GetMyRoles)
+	// GET /me/requests не реализован: в IDM нет заявок на доступ, которые можно было бы вернуть
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/me"
+// @Description Get employee linked to token subject (claim sub).
+// @Description Unknown subject gets 403, or 404 if ME_UNKNOWN_SUBJECT=provision and POST /me can create the employee.
+// @Summary get current employee
+// @ID get-me
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[employee.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /me [get]
+func (c *Controller) GetMe(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод Me сервиса me.Service
+	response, err := c.meService.Me(ctx.Context(), caller(claims))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get me", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get me", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/me"
+// @Description Create employee for token subject (claim sub) if it is not linked yet and return it.
+// @Description Allowed only if ME_UNKNOWN_SUBJECT=provision; name is taken from claim name, preferred_username or sub.
+// @Summary provision current employee
+// @ID post-me
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[employee.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /me [post]
+func (c *Controller) PostMe(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод Provision сервиса me.Service
+	response, err := c.meService.Provision(ctx.Context(), caller(claims))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "provision me", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "provision me", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/me/roles"
+// @Description Get effective access of employee linked to token subject: every role with all grants.
+// @Summary get roles of current employee
+// @ID get-my-roles
+// @Tags me
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[access.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /me/roles [get]
+func (c *Controller) GetMyRoles(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод Roles сервиса me.Service
+	response, err := c.meService.Roles(ctx.Context(), caller(claims))
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get my roles", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get my roles", zap.String("subject", claims.Subject), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// caller вызывающий из токена; имя берётся из claim name, затем preferred_username, затем sub
+func caller(claims *web.IdmClaims) Caller {
+	name := claims.Name
+	if name == "" {
+		name = claims.PreferredUsername
+	}
+	if name == "" {
+		name = claims.Subject
+	}
+	return Caller{Subject: claims.Subject, Name: name}
+}
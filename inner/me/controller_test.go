@@ -0,0 +1,147 @@
+package me
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса me.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Me(ctx context.Context, caller Caller) (employee.Response, error) {
+	args := svc.Called(caller)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (svc *MockService) Provision(ctx context.Context, caller Caller) (employee.Response, error) {
+	args := svc.Called(caller)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (svc *MockService) Roles(ctx context.Context, caller Caller) (access.Response, error) {
+	args := svc.Called(caller)
+	return args.Get(0).(access.Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, claims *web.IdmClaims) *web.Server {
+	server, logger := webtest.NewServer(claims)
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func userClaims(subject string, preferredUsername string, roles ...string) *web.IdmClaims {
+	claims := webtest.Claims(subject, roles...)
+	claims.PreferredUsername = preferredUsername
+	return claims
+}
+
+func TestController_GetMe(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should return employee of token subject", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "jdoe", web.IdmUser))
+		john := employee.Response{Id: 7, Name: "jdoe", Subject: "kc-1"}
+		svc.On("Me", Caller{Subject: "kc-1", Name: "jdoe"}).Return(john, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[employee.Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(john.Id, got.Data.Id)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 for unknown subject", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "", web.IdmUser))
+		svc.On("Me", Caller{Subject: "kc-1", Name: "kc-1"}).
+			Return(employee.Response{}, common.ForbiddenError{Message: "token subject kc-1 is not linked to employee"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("should return 403 without idm roles", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "jdoe"))
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Me", mock.Anything)
+	})
+}
+
+func TestController_PostMe(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should provision employee of token subject", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "jdoe", web.IdmUser))
+		john := employee.Response{Id: 7, Name: "jdoe", Subject: "kc-1"}
+		svc.On("Provision", Caller{Subject: "kc-1", Name: "jdoe"}).Return(john, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[employee.Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(john.Id, got.Data.Id)
+		svc.AssertNotCalled(t, "Me", mock.Anything)
+	})
+
+	t.Run("should return 403 if provisioning is disabled", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "jdoe", web.IdmUser))
+		svc.On("Provision", mock.Anything).
+			Return(employee.Response{}, common.ForbiddenError{Message: "provisioning of employees is disabled"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/me", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestController_GetMyRoles(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should return access of token subject", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, userClaims("kc-1", "jdoe", web.IdmAdmin))
+		want := access.Response{EmployeeId: 7, Roles: []access.Role{{RoleId: 1, RoleName: "developer"}}}
+		svc.On("Roles", Caller{Subject: "kc-1", Name: "jdoe"}).Return(want, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/me/roles", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[access.Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(want, got.Data)
+	})
+}
@@ -0,0 +1,111 @@
+package me
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+)
+
+// Режимы обработки вызывающего, не связанного с сотрудником
+const (
+	// вернуть 403
+	UnknownSubjectReject = "reject"
+	// создать сотрудника и связать его с пользователем Keycloak
+	UnknownSubjectProvision = "provision"
+)
+
+type EmployeeSvc interface {
+	Provision(ctx context.Context, name string, subject string) (int64, error)
+	FindById(ctx context.Context, id int64) (employee.Response, error)
+	FindBySubject(ctx context.Context, subject string) (employee.Response, error)
+}
+
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+// Caller вызывающий пользователь из токена
+type Caller struct {
+	// claim sub
+	Subject string
+	// имя для автоматически создаваемого сотрудника
+	Name string
+}
+
+type Service struct {
+	employees      EmployeeSvc
+	access         AccessSvc
+	unknownSubject string
+}
+
+func NewService(employees EmployeeSvc, access AccessSvc, unknownSubject string) *Service {
+	return &Service{
+		employees:      employees,
+		access:         access,
+		unknownSubject: unknownSubject,
+	}
+}
+
+// Me возвращает сотрудника, связанного с вызывающим. Если такого сотрудника нет, то возвращается
+// ForbiddenError, а в режиме provision - NotFoundError: сотрудник создаётся только запросом POST /me
+func (s *Service) Me(ctx context.Context, caller Caller) (employee.Response, error) {
+	if caller.Subject == "" {
+		return employee.Response{}, common.ForbiddenError{Message: "token has no subject"}
+	}
+	response, err := s.employees.FindBySubject(ctx, caller.Subject)
+	var notFoundErr common.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		return response, err
+	}
+	if s.unknownSubject != UnknownSubjectProvision {
+		return employee.Response{}, common.ForbiddenError{
+			Message: fmt.Sprintf("token subject %s is not linked to employee", caller.Subject),
+		}
+	}
+	return employee.Response{}, common.NotFoundError{
+		Message: fmt.Sprintf("token subject %s is not linked to employee, POST /me creates it", caller.Subject),
+	}
+}
+
+// Provision создаёт сотрудника для вызывающего, не связанного с сотрудником, и возвращает его.
+// Если сотрудник уже связан с вызывающим, то он возвращается без изменений
+func (s *Service) Provision(ctx context.Context, caller Caller) (employee.Response, error) {
+	if caller.Subject == "" {
+		return employee.Response{}, common.ForbiddenError{Message: "token has no subject"}
+	}
+	if s.unknownSubject != UnknownSubjectProvision {
+		return employee.Response{}, common.ForbiddenError{Message: "provisioning of employees is disabled"}
+	}
+	response, err := s.employees.FindBySubject(ctx, caller.Subject)
+	var notFoundErr common.NotFoundError
+	if !errors.As(err, &notFoundErr) {
+		return response, err
+	}
+	id, err := s.employees.Provision(ctx, caller.Name, caller.Subject)
+	var alreadyExistsErr common.AlreadyExistsError
+	if errors.As(err, &alreadyExistsErr) {
+		// сотрудник мог быть создан параллельным запросом того же пользователя;
+		// если нет, то имя занято другим сотрудником и его связывает администратор
+		response, errFind := s.employees.FindBySubject(ctx, caller.Subject)
+		if errFind == nil {
+			return response, nil
+		}
+		return employee.Response{}, err
+	}
+	if err != nil {
+		return employee.Response{}, fmt.Errorf("error provisioning employee for subject %s: %w", caller.Subject, err)
+	}
+	return s.employees.FindById(ctx, id)
+}
+
+// Roles возвращает фактический доступ сотрудника, связанного с вызывающим
+func (s *Service) Roles(ctx context.Context, caller Caller) (access.Response, error) {
+	me, err := s.Me(ctx, caller)
+	if err != nil {
+		return access.Response{}, err
+	}
+	return s.access.Resolve(ctx, me.Id)
+}
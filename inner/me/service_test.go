@@ -0,0 +1,174 @@
+package me
+
+import (
+	"context"
+	"errors"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса employee.Service
+type MockEmployeeSvc struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeSvc) Provision(ctx context.Context, name string, subject string) (int64, error) {
+	args := m.Called(name, subject)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) FindById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) FindBySubject(ctx context.Context, subject string) (employee.Response, error) {
+	args := m.Called(subject)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+// Объявляем структуру мока сервиса access.Service
+type MockAccessSvc struct {
+	mock.Mock
+}
+
+func (m *MockAccessSvc) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).(access.Response), args.Error(1)
+}
+
+func TestService_Me(t *testing.T) {
+	var a = assert.New(t)
+	caller := Caller{Subject: "kc-1", Name: "john doe"}
+	notFound := common.NotFoundError{Message: "employee with subject kc-1 not found"}
+
+	t.Run("should return linked employee", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectReject)
+		john := employee.Response{Id: 7, Name: "john doe", Subject: "kc-1"}
+		employees.On("FindBySubject", "kc-1").Return(john, nil)
+
+		got, err := srv.Me(context.Background(), caller)
+
+		a.Nil(err)
+		a.Equal(john, got)
+		employees.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject unknown subject", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectReject)
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{}, notFound)
+
+		_, err := srv.Me(context.Background(), caller)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		employees.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return NotFoundError for unknown subject without provisioning it", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectProvision)
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{}, notFound)
+
+		_, err := srv.Me(context.Background(), caller)
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		employees.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+}
+
+func TestService_Provision(t *testing.T) {
+	var a = assert.New(t)
+	caller := Caller{Subject: "kc-1", Name: "john doe"}
+	notFound := common.NotFoundError{Message: "employee with subject kc-1 not found"}
+
+	t.Run("should provision unknown subject", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectProvision)
+		john := employee.Response{Id: 7, Name: "john doe", Subject: "kc-1"}
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{}, notFound)
+		employees.On("Provision", "john doe", "kc-1").Return(int64(7), nil)
+		employees.On("FindById", int64(7)).Return(john, nil)
+
+		got, err := srv.Provision(context.Background(), caller)
+
+		a.Nil(err)
+		a.Equal(john, got)
+		employees.AssertExpectations(t)
+	})
+
+	t.Run("should return linked employee without provisioning", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectProvision)
+		john := employee.Response{Id: 7, Name: "john doe", Subject: "kc-1"}
+		employees.On("FindBySubject", "kc-1").Return(john, nil)
+
+		got, err := srv.Provision(context.Background(), caller)
+
+		a.Nil(err)
+		a.Equal(john, got)
+		employees.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return ForbiddenError if provisioning is disabled", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectReject)
+
+		_, err := srv.Provision(context.Background(), caller)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		employees.AssertNotCalled(t, "Provision", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return employee created by concurrent request", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectProvision)
+		john := employee.Response{Id: 7, Name: "john doe", Subject: "kc-1"}
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{}, notFound).Once()
+		employees.On("Provision", mock.Anything, mock.Anything).Return(int64(0), common.AlreadyExistsError{Message: "employee with subject kc-1 already exists"})
+		employees.On("FindBySubject", "kc-1").Return(john, nil).Once()
+
+		got, err := srv.Provision(context.Background(), caller)
+
+		a.Nil(err)
+		a.Equal(john, got)
+	})
+
+	t.Run("should return AlreadyExistsError if name is taken by another employee", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		srv := NewService(employees, &MockAccessSvc{}, UnknownSubjectProvision)
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{}, notFound)
+		employees.On("Provision", mock.Anything, mock.Anything).Return(int64(0), common.AlreadyExistsError{Message: "employee with name john doe already exists"})
+
+		_, err := srv.Provision(context.Background(), caller)
+
+		var alreadyExistsErr common.AlreadyExistsError
+		a.True(errors.As(err, &alreadyExistsErr))
+	})
+}
+
+func TestService_Roles(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should resolve access of linked employee", func(t *testing.T) {
+		employees := &MockEmployeeSvc{}
+		accessSvc := &MockAccessSvc{}
+		srv := NewService(employees, accessSvc, UnknownSubjectReject)
+		want := access.Response{EmployeeId: 7, Roles: []access.Role{{RoleId: 1, RoleName: "developer"}}}
+		employees.On("FindBySubject", "kc-1").Return(employee.Response{Id: 7}, nil)
+		accessSvc.On("Resolve", int64(7)).Return(want, nil)
+
+		got, err := srv.Roles(context.Background(), Caller{Subject: "kc-1"})
+
+		a.Nil(err)
+		a.Equal(want, got)
+	})
+}
@@ -0,0 +1,70 @@
+package provisioning
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Account учётная запись сотрудника в целевой системе
+type Account struct {
+	Id           string   `json:"id"`
+	EmployeeId   int64    `json:"employee_id"`
+	Name         string   `json:"name"`
+	Disabled     bool     `json:"disabled"`
+	Entitlements []string `json:"entitlements"`
+}
+
+// Connector доступ к учётным записям и правам одной целевой системы.
+// Права передаются по имени, как в каталоге целевых систем (application.EntitlementResponse.Name)
+type Connector interface {
+	// CreateAccount создаёт учётную запись без прав
+	CreateAccount(ctx context.Context, account Account) error
+	// UpdateAccount меняет имя и признак блокировки учётной записи, права не меняются
+	UpdateAccount(ctx context.Context, account Account) error
+	// DisableAccount блокирует учётную запись, права при этом сохраняются
+	DisableAccount(ctx context.Context, accountId string) error
+	// GrantEntitlement выдаёт право; уже выданное право не меняется
+	GrantEntitlement(ctx context.Context, accountId string, entitlement string) error
+	// RevokeEntitlement отзывает право; отсутствующее право не считается ошибкой
+	RevokeEntitlement(ctx context.Context, accountId string, entitlement string) error
+	// ListAccounts возвращает все учётные записи целевой системы
+	ListAccounts(ctx context.Context) ([]Account, error)
+}
+
+// Registry коннекторы целевых систем по имени системы в каталоге (application.Response.Name)
+type Registry struct {
+	mu         sync.RWMutex
+	connectors map[string]Connector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{connectors: map[string]Connector{}}
+}
+
+// Register подключает коннектор к целевой системе, заменяя ранее подключённый
+func (r *Registry) Register(application string, connector Connector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connectors[application] = connector
+}
+
+// Get возвращает коннектор целевой системы
+func (r *Registry) Get(application string) (Connector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	connector, ok := r.connectors[application]
+	return connector, ok
+}
+
+// Applications возвращает имена целевых систем с подключёнными коннекторами по алфавиту
+func (r *Registry) Applications() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	applications := make([]string, 0, len(r.connectors))
+	for application := range r.connectors {
+		applications = append(applications, application)
+	}
+	slices.Sort(applications)
+	return applications
+}
@@ -0,0 +1,172 @@
+package provisioning
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server              *web.Server
+	provisioningService Svc
+	logger              *common.Logger
+}
+
+// интерфейс сервиса provisioning.Service
+type Svc interface {
+	GetAll(ctx context.Context, state string) ([]Response, error)
+	Enqueue(ctx context.Context, employeeId int64) error
+	Retry(ctx context.Context, id int64, actor string) (Response, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:              server,
+		provisioningService: svc,
+		logger:              logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/provisioning/tasks", c.GetAllTasks)
+	c.server.GroupApiV1.Post("/provisioning/tasks/:id/retry", c.RetryTask)
+	c.server.GroupApiV1.Post("/provisioning/employees/:id", c.EnqueueEmployee)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/provisioning/tasks"
+// @Description Get provisioning queue tasks, only tasks in given state (pending, done, failed) if state is set.
+// @Summary get provisioning tasks
+// @ID get-all-provisioning-tasks
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param state query string false "task state"
+// @Success 200 {object} common.Response[[]provisioning.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /provisioning/tasks [get]
+func (c *Controller) GetAllTasks(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	state := ctx.Query("state")
+
+	// вызываем метод GetAll сервиса provisioning.Service
+	response, err := c.provisioningService.GetAll(ctx.Context(), state)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all provisioning tasks", zap.String("state", state), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all provisioning tasks", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/provisioning/tasks/:id/retry"
+// @Description Return failed provisioning task to the queue with reset attempts.
+// @Summary retry provisioning task
+// @ID retry-provisioning-task
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id provisioning task"
+// @Success 200 {object} common.Response[provisioning.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /provisioning/tasks/{id}/retry [post]
+func (c *Controller) RetryTask(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid provisioning task id")
+	}
+
+	// вызываем метод Retry сервиса provisioning.Service
+	response, err := c.provisioningService.Retry(ctx.Context(), id, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "retry provisioning task", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "retry provisioning task", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/provisioning/employees/:id"
+// @Description Enqueue synchronization of employee accounts in all connected target systems.
+// @Summary provision employee
+// @ID provision-employee
+// @Tags provisioning
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id employee"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /provisioning/employees/{id} [post]
+func (c *Controller) EnqueueEmployee(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+	}
+
+	// вызываем метод Enqueue сервиса provisioning.Service
+	if err := c.provisioningService.Enqueue(ctx.Context(), id); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "provision employee", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "provision employee", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
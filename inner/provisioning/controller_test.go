@@ -0,0 +1,122 @@
+package provisioning
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса provisioning.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context, state string) ([]Response, error) {
+	args := svc.Called(state)
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) Enqueue(ctx context.Context, employeeId int64) error {
+	args := svc.Called(employeeId)
+	return args.Error(0)
+}
+
+func (svc *MockService) Retry(ctx context.Context, id int64, actor string) (Response, error) {
+	args := svc.Called(id, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("kc-admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_GetAllTasks(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should filter tasks by state", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("GetAll", StateFailed).Return([]Response{{Id: 1, State: StateFailed}}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/provisioning/tasks?state=failed", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 without admin role", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/provisioning/tasks", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "GetAll", mock.Anything)
+	})
+}
+
+func TestController_RetryTask(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should pass actor to service", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Retry", int64(1), "kc-admin").Return(Response{Id: 1, State: StatePending}, nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/provisioning/tasks/1/retry", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 409 for task that is not failed", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Retry", int64(1), "kc-admin").
+			Return(Response{}, common.ConflictError{Message: "provisioning task 1 is done, only failed tasks can be retried"})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/provisioning/tasks/1/retry", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+	})
+}
+
+func TestController_EnqueueEmployee(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should enqueue employee", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Enqueue", int64(7)).Return(nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/provisioning/employees/7", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 400 for invalid employee id", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/provisioning/employees/abc", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Enqueue", mock.Anything)
+	})
+}
@@ -0,0 +1,67 @@
+package provisioning
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Состояния задачи провижининга
+const (
+	StatePending = "pending"
+	StateDone    = "done"
+	StateFailed  = "failed"
+)
+
+// Причины постановки задачи провижининга в очередь
+const (
+	ReasonAssignment = "assignment"
+	ReasonLifecycle  = "lifecycle"
+	ReasonManual     = "manual"
+)
+
+// AccountLinkEntity учётная запись целевой системы, принятая администратором как учётная запись сотрудника
+// (accountrecon, adopt). Без связи учётная запись сотрудника определяется по AccountId
+type AccountLinkEntity struct {
+	Application string `db:"application"`
+	AccountId   string `db:"account_id"`
+	EmployeeId  int64  `db:"employee_id"`
+}
+
+// Entity задача синхронизации учётных записей сотрудника во всех подключённых целевых системах
+type Entity struct {
+	Id            int64          `db:"id"`
+	EmployeeId    int64          `db:"employee_id"`
+	Reason        string         `db:"reason"`
+	State         string         `db:"state"`
+	Attempts      int            `db:"attempts"`
+	LastError     sql.NullString `db:"last_error"`
+	NextAttemptAt time.Time      `db:"next_attempt_at"`
+	CreateAt      time.Time      `db:"create_at"`
+	UpdateAt      time.Time      `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	return Response{
+		Id:            e.Id,
+		EmployeeId:    e.EmployeeId,
+		Reason:        e.Reason,
+		State:         e.State,
+		Attempts:      e.Attempts,
+		LastError:     e.LastError.String,
+		NextAttemptAt: e.NextAttemptAt,
+		CreateAt:      e.CreateAt,
+		UpdateAt:      e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id            int64     `json:"id"`
+	EmployeeId    int64     `json:"employee_id"`
+	Reason        string    `json:"reason"`
+	State         string    `json:"state"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreateAt      time.Time `json:"create_at"`
+	UpdateAt      time.Time `json:"update_at"`
+}
@@ -0,0 +1,261 @@
+package provisioning
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Форматы файла учётных записей FileConnector
+const (
+	FormatJson = "json"
+	FormatCsv  = "csv"
+)
+
+// заголовок CSV файла учётных записей; права записываются через ";"
+var csvHeader = []string{"id", "employee_id", "name", "disabled", "entitlements"}
+
+// FileConnector эталонный коннектор, который хранит учётные записи целевой системы в файле
+// accounts.json или accounts.csv каталога dir. Нужен, чтобы проверять провижининг без внешних систем
+type FileConnector struct {
+	mu     sync.Mutex
+	dir    string
+	format string
+}
+
+func NewFileConnector(dir string, format string) (*FileConnector, error) {
+	if format != FormatJson && format != FormatCsv {
+		return nil, fmt.Errorf("unknown provisioning file format %q, expected %s or %s", format, FormatJson, FormatCsv)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating provisioning directory %s: %w", dir, err)
+	}
+	return &FileConnector{dir: dir, format: format}, nil
+}
+
+// NewFileRegistry подключает файловый коннектор к каждой целевой системе из списка applications через запятую,
+// учётные записи системы хранятся в подкаталоге dir с её именем. Пустой dir - коннекторы не подключаются
+func NewFileRegistry(dir string, format string, applications string) (*Registry, error) {
+	registry := NewRegistry()
+	if dir == "" {
+		return registry, nil
+	}
+	for _, application := range strings.Split(applications, ",") {
+		if application = strings.TrimSpace(application); application == "" {
+			continue
+		}
+		if application != filepath.Base(application) || application == ".." {
+			return nil, fmt.Errorf("invalid provisioning application name %q", application)
+		}
+		connector, err := NewFileConnector(filepath.Join(dir, application), format)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register(application, connector)
+	}
+	return registry, nil
+}
+
+// Path путь к файлу учётных записей
+func (c *FileConnector) Path() string {
+	return filepath.Join(c.dir, "accounts."+c.format)
+}
+
+func (c *FileConnector) CreateAccount(_ context.Context, account Account) error {
+	return c.modify(func(accounts []Account) ([]Account, error) {
+		if slices.ContainsFunc(accounts, func(item Account) bool { return item.Id == account.Id }) {
+			return nil, fmt.Errorf("account %s already exists", account.Id)
+		}
+		account.Entitlements = []string{}
+		return append(accounts, account), nil
+	})
+}
+
+func (c *FileConnector) UpdateAccount(_ context.Context, account Account) error {
+	return c.modifyAccount(account.Id, func(item *Account) {
+		item.Name = account.Name
+		item.Disabled = account.Disabled
+	})
+}
+
+func (c *FileConnector) DisableAccount(_ context.Context, accountId string) error {
+	return c.modifyAccount(accountId, func(item *Account) {
+		item.Disabled = true
+	})
+}
+
+func (c *FileConnector) GrantEntitlement(_ context.Context, accountId string, entitlement string) error {
+	return c.modifyAccount(accountId, func(item *Account) {
+		if !slices.Contains(item.Entitlements, entitlement) {
+			item.Entitlements = append(item.Entitlements, entitlement)
+			slices.Sort(item.Entitlements)
+		}
+	})
+}
+
+func (c *FileConnector) RevokeEntitlement(_ context.Context, accountId string, entitlement string) error {
+	return c.modifyAccount(accountId, func(item *Account) {
+		item.Entitlements = slices.DeleteFunc(item.Entitlements, func(name string) bool { return name == entitlement })
+	})
+}
+
+func (c *FileConnector) ListAccounts(_ context.Context) ([]Account, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.load()
+}
+
+// modifyAccount меняет одну учётную запись; неизвестная запись - ошибка
+func (c *FileConnector) modifyAccount(accountId string, fn func(item *Account)) error {
+	return c.modify(func(accounts []Account) ([]Account, error) {
+		i := slices.IndexFunc(accounts, func(item Account) bool { return item.Id == accountId })
+		if i < 0 {
+			return nil, fmt.Errorf("account %s not found", accountId)
+		}
+		fn(&accounts[i])
+		return accounts, nil
+	})
+}
+
+// modify читает файл, меняет учётные записи и записывает файл целиком
+func (c *FileConnector) modify(fn func(accounts []Account) ([]Account, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	accounts, err := c.load()
+	if err != nil {
+		return err
+	}
+	if accounts, err = fn(accounts); err != nil {
+		return err
+	}
+	return c.save(accounts)
+}
+
+// load читает учётные записи; отсутствующий файл - пустая система
+func (c *FileConnector) load() ([]Account, error) {
+	file, err := os.Open(c.Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return []Account{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", c.Path(), err)
+	}
+	defer file.Close()
+
+	var accounts []Account
+	if c.format == FormatJson {
+		err = json.NewDecoder(file).Decode(&accounts)
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	} else {
+		accounts, err = readCsv(file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", c.Path(), err)
+	}
+	if accounts == nil {
+		accounts = []Account{}
+	}
+	return accounts, nil
+}
+
+// save записывает учётные записи во временный файл и переименовывает его,
+// чтобы при сбое не остался наполовину записанный файл
+func (c *FileConnector) save(accounts []Account) (err error) {
+	file, err := os.CreateTemp(c.dir, "accounts-*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file in %s: %w", c.dir, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+			_ = os.Remove(file.Name())
+		}
+	}()
+
+	if c.format == FormatJson {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(accounts)
+	} else {
+		err = writeCsv(file, accounts)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", file.Name(), err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("error closing %s: %w", file.Name(), err)
+	}
+	if err = os.Rename(file.Name(), c.Path()); err != nil {
+		return fmt.Errorf("error replacing %s: %w", c.Path(), err)
+	}
+	return nil
+}
+
+func readCsv(r io.Reader) ([]Account, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]Account, 0, len(records))
+	for i, record := range records {
+		// первая строка - заголовок
+		if i == 0 {
+			continue
+		}
+		if len(record) != len(csvHeader) {
+			return nil, fmt.Errorf("line %d: expected %d columns, got %d", i+1, len(csvHeader), len(record))
+		}
+		employeeId, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid employee_id: %w", i+1, err)
+		}
+		disabled, err := strconv.ParseBool(record[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid disabled: %w", i+1, err)
+		}
+		entitlements := []string{}
+		if record[4] != "" {
+			entitlements = strings.Split(record[4], ";")
+		}
+		accounts = append(accounts, Account{
+			Id:           record[0],
+			EmployeeId:   employeeId,
+			Name:         record[2],
+			Disabled:     disabled,
+			Entitlements: entitlements,
+		})
+	}
+	return accounts, nil
+}
+
+func writeCsv(w io.Writer, accounts []Account) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, account := range accounts {
+		record := []string{
+			account.Id,
+			strconv.FormatInt(account.EmployeeId, 10),
+			account.Name,
+			strconv.FormatBool(account.Disabled),
+			strings.Join(account.Entitlements, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
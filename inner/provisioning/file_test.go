@@ -0,0 +1,100 @@
+package provisioning
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileConnector(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+
+	for _, format := range []string{FormatJson, FormatCsv} {
+		t.Run("should keep accounts in "+format+" file", func(t *testing.T) {
+			connector, err := NewFileConnector(filepath.Join(t.TempDir(), "ad"), format)
+			a.NoError(err)
+
+			accounts, err := connector.ListAccounts(ctx)
+			a.NoError(err)
+			a.Empty(accounts)
+
+			a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7, Name: "john doe"}))
+			a.NoError(connector.GrantEntitlement(ctx, "7", "vpn"))
+			a.NoError(connector.GrantEntitlement(ctx, "7", "mail"))
+			a.NoError(connector.GrantEntitlement(ctx, "7", "vpn"))
+			a.NoError(connector.RevokeEntitlement(ctx, "7", "absent"))
+			a.NoError(connector.UpdateAccount(ctx, Account{Id: "7", Name: "john smith"}))
+			a.NoError(connector.CreateAccount(ctx, Account{Id: "8", EmployeeId: 8, Name: "jane, \"doe\""}))
+			a.NoError(connector.DisableAccount(ctx, "8"))
+
+			// новый коннектор читает состояние из файла
+			reopened, err := NewFileConnector(filepath.Dir(connector.Path()), format)
+			a.NoError(err)
+			accounts, err = reopened.ListAccounts(ctx)
+			a.NoError(err)
+			a.Equal([]Account{
+				{Id: "7", EmployeeId: 7, Name: "john smith", Entitlements: []string{"mail", "vpn"}},
+				{Id: "8", EmployeeId: 8, Name: "jane, \"doe\"", Disabled: true, Entitlements: []string{}},
+			}, accounts)
+
+			a.NoError(reopened.RevokeEntitlement(ctx, "7", "mail"))
+			accounts, err = connector.ListAccounts(ctx)
+			a.NoError(err)
+			a.Equal([]string{"vpn"}, accounts[0].Entitlements)
+		})
+	}
+
+	t.Run("should reject duplicate and unknown accounts", func(t *testing.T) {
+		connector, err := NewFileConnector(t.TempDir(), FormatJson)
+		a.NoError(err)
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7}))
+
+		a.ErrorContains(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7}), "account 7 already exists")
+		a.ErrorContains(connector.GrantEntitlement(ctx, "9", "vpn"), "account 9 not found")
+		a.ErrorContains(connector.DisableAccount(ctx, "9"), "account 9 not found")
+	})
+
+	t.Run("should return error for corrupted file", func(t *testing.T) {
+		connector, err := NewFileConnector(t.TempDir(), FormatCsv)
+		a.NoError(err)
+		a.NoError(os.WriteFile(connector.Path(), []byte("id,employee_id,name,disabled,entitlements\n7,x,john,false,\n"), 0o600))
+
+		_, err = connector.ListAccounts(ctx)
+		a.ErrorContains(err, "line 2: invalid employee_id")
+	})
+
+	t.Run("should reject unknown format", func(t *testing.T) {
+		_, err := NewFileConnector(t.TempDir(), "xml")
+		a.ErrorContains(err, `unknown provisioning file format "xml"`)
+	})
+}
+
+func TestNewFileRegistry(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should register connector per application", func(t *testing.T) {
+		dir := t.TempDir()
+		registry, err := NewFileRegistry(dir, FormatJson, " sap, ,ad ")
+		a.NoError(err)
+		a.Equal([]string{"ad", "sap"}, registry.Applications())
+
+		connector, ok := registry.Get("sap")
+		a.True(ok)
+		a.Equal(filepath.Join(dir, "sap", "accounts.json"), connector.(*FileConnector).Path())
+		a.DirExists(filepath.Join(dir, "ad"))
+	})
+
+	t.Run("should register nothing without directory", func(t *testing.T) {
+		registry, err := NewFileRegistry("", FormatJson, "ad")
+		a.NoError(err)
+		a.Empty(registry.Applications())
+	})
+
+	t.Run("should reject application name with path", func(t *testing.T) {
+		_, err := NewFileRegistry(t.TempDir(), FormatJson, "../etc")
+		a.ErrorContains(err, `invalid provisioning application name "../etc"`)
+	})
+}
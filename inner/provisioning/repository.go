@@ -0,0 +1,119 @@
+package provisioning
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+// постановка в очередь: ожидающая задача сотрудника не дублируется, а выполняется без ожидания повтора
+const enqueueConflict = `ON CONFLICT (employee_id) WHERE state = 'pending'
+DO UPDATE SET next_attempt_at = LEAST(provisioning_task.next_attempt_at, now()), update_at = now()`
+
+// постановка в очередь сотрудников вместе с их заместителями по действующим замещениям с ролями:
+// заместитель имеет роль, только пока она есть у отсутствующего
+const enqueueWithDeputies = `INSERT INTO provisioning_task (employee_id, reason)
+SELECT id, $2::text FROM unnest($1::bigint[]) id
+UNION
+SELECT d.deputy_id, $2::text FROM delegation d
+WHERE d.delegator_id = ANY($1) AND d.state IN ('scheduled', 'active') AND d.valid_from <= now() AND now() < d.valid_to
+AND EXISTS (SELECT * FROM delegation_role dr WHERE dr.delegation_id = d.id)
+` + enqueueConflict
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewProvisioningRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти задачи; пустой state - в любом состоянии
+func (r *Repository) FindAll(ctx context.Context, state string) (tasks []Entity, err error) {
+	query := "SELECT * FROM provisioning_task WHERE $1 = '' OR state = $1 ORDER BY create_at DESC, id DESC"
+	err = r.db.SelectContext(ctx, &tasks, query, state)
+	return tasks, err
+}
+
+// найти задачу по id и заблокировать её до конца транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (task Entity, err error) {
+	err = tx.GetContext(ctx, &task, "SELECT * FROM provisioning_task WHERE id = $1 FOR UPDATE", id)
+	return task, err
+}
+
+// проверка существования сотрудника
+func (r *Repository) EmployeeExists(ctx context.Context, employeeId int64) (isExists bool, err error) {
+	err = r.db.GetContext(ctx, &isExists, "SELECT EXISTS(SELECT * FROM employee WHERE id = $1)", employeeId)
+	return isExists, err
+}
+
+// найти принятые учётные записи сотрудника в целевых системах
+func (r *Repository) FindAccountLinks(ctx context.Context, employeeId int64) (links []AccountLinkEntity, err error) {
+	query := "SELECT application, account_id, employee_id FROM provisioning_account WHERE employee_id = $1"
+	err = r.db.SelectContext(ctx, &links, query, employeeId)
+	return links, err
+}
+
+// поставить в очередь синхронизацию сотрудников
+func (r *Repository) Enqueue(ctx context.Context, employeeIds []int64, reason string) error {
+	_, err := r.db.ExecContext(ctx, enqueueWithDeputies, pq.Int64Array(employeeIds), reason)
+	return err
+}
+
+// поставить в очередь синхронизацию сотрудников в рамках транзакции
+func (r *Repository) EnqueueTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64, reason string) error {
+	_, err := tx.ExecContext(ctx, enqueueWithDeputies, pq.Int64Array(employeeIds), reason)
+	return err
+}
+
+// FindDueTx находит ожидающую задачу, время попытки которой наступило, и блокирует её.
+// Задачи, заблокированные другими экземплярами приложения, пропускаются
+func (r *Repository) FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (task Entity, err error) {
+	query := `SELECT * FROM provisioning_task WHERE state = 'pending' AND next_attempt_at <= $1
+ORDER BY next_attempt_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	err = tx.GetContext(ctx, &task, query, now)
+	return task, err
+}
+
+// завершить задачу в рамках транзакции
+func (r *Repository) CompleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error {
+	query := "UPDATE provisioning_task SET state = 'done', last_error = NULL, update_at = now() WHERE id = $1"
+	_, err := tx.ExecContext(ctx, query, id)
+	return err
+}
+
+// отложить задачу до следующей попытки в рамках транзакции
+func (r *Repository) RetryTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	query := `UPDATE provisioning_task SET attempts = $2, next_attempt_at = $3, last_error = $4, update_at = now()
+WHERE id = $1`
+	_, err := tx.ExecContext(ctx, query, id, attempts, nextAttemptAt, lastError)
+	return err
+}
+
+// перевести задачу в failed после исчерпания попыток в рамках транзакции
+func (r *Repository) FailTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, lastError string) error {
+	query := "UPDATE provisioning_task SET state = 'failed', attempts = $2, last_error = $3, update_at = now() WHERE id = $1"
+	_, err := tx.ExecContext(ctx, query, id, attempts, lastError)
+	return err
+}
+
+// ResetTx возвращает задачу в очередь с обнулёнными попытками в рамках транзакции
+func (r *Repository) ResetTx(ctx context.Context, tx *sqlx.Tx, id int64) (task Entity, err error) {
+	query := `UPDATE provisioning_task SET state = 'pending', attempts = 0, next_attempt_at = now(), update_at = now()
+WHERE id = $1 RETURNING *`
+	err = tx.GetContext(ctx, &task, query, id)
+	return task, err
+}
+
+// есть ли у сотрудника ожидающая задача, в рамках транзакции
+func (r *Repository) PendingExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM provisioning_task WHERE employee_id = $1 AND state = 'pending')"
+	err = tx.GetContext(ctx, &isExists, query, employeeId)
+	return isExists, err
+}
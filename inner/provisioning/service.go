@@ -0,0 +1,383 @@
+package provisioning
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/application"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// сколько задач выполняется за один запуск планировщика
+const maxDuePerRun = 100
+
+// задержка перед повтором неудачной синхронизации удваивается с каждой попыткой, но не превышает retryMaxDelay
+const (
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = time.Hour
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	FindAll(ctx context.Context, state string) ([]Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	EmployeeExists(ctx context.Context, employeeId int64) (bool, error)
+	FindAccountLinks(ctx context.Context, employeeId int64) ([]AccountLinkEntity, error)
+	Enqueue(ctx context.Context, employeeIds []int64, reason string) error
+	EnqueueTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64, reason string) error
+	FindDueTx(ctx context.Context, tx *sqlx.Tx, now time.Time) (Entity, error)
+	CompleteTx(ctx context.Context, tx *sqlx.Tx, id int64) error
+	RetryTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	FailTx(ctx context.Context, tx *sqlx.Tx, id int64, attempts int, lastError string) error
+	ResetTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	PendingExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) (bool, error)
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// EntitlementSvc права сотрудника в целевых системах, реализуется application.Service
+type EntitlementSvc interface {
+	EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (application.EmployeeEntitlementsResponse, error)
+}
+
+type Service struct {
+	repo         Repo
+	audit        AuditRepo
+	entitlements EntitlementSvc
+	registry     *Registry
+	// после стольких неудачных попыток задача переходит в failed
+	maxAttempts int
+}
+
+func NewService(repo Repo, audit AuditRepo, entitlements EntitlementSvc, registry *Registry, maxAttempts int) *Service {
+	return &Service{
+		repo:         repo,
+		audit:        audit,
+		entitlements: entitlements,
+		registry:     registry,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// GetAll возвращает задачи провижининга; пустой state - в любом состоянии
+func (s *Service) GetAll(ctx context.Context, state string) ([]Response, error) {
+	if state != "" && !slices.Contains([]string{StatePending, StateDone, StateFailed}, state) {
+		return []Response{}, common.RequestValidatorError{
+			Message: fmt.Sprintf("state must be one of %s, %s, %s", StatePending, StateDone, StateFailed),
+		}
+	}
+	tasks, err := s.repo.FindAll(ctx, state)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting provisioning tasks: %w", err)
+	}
+	response := make([]Response, 0, len(tasks))
+	for _, task := range tasks {
+		response = append(response, task.toResponse())
+	}
+	return response, nil
+}
+
+// Enqueue ставит в очередь синхронизацию сотрудника по запросу администратора
+func (s *Service) Enqueue(ctx context.Context, employeeId int64) error {
+	isExists, err := s.repo.EmployeeExists(ctx, employeeId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", employeeId, err)
+	}
+	if !isExists {
+		return common.NotFoundError{Message: fmt.Sprintf("employee with id %d not found", employeeId)}
+	}
+	if err := s.repo.Enqueue(ctx, []int64{employeeId}, ReasonManual); err != nil {
+		return fmt.Errorf("error enqueuing provisioning of employee %d: %w", employeeId, err)
+	}
+	return nil
+}
+
+// RolesChanged ставит в очередь синхронизацию сотрудников, которым назначили или у которых отозвали роли
+func (s *Service) RolesChanged(ctx context.Context, employeeIds []int64) error {
+	if len(employeeIds) == 0 {
+		return nil
+	}
+	if err := s.repo.Enqueue(ctx, employeeIds, ReasonAssignment); err != nil {
+		return fmt.Errorf("error enqueuing provisioning of employees %v: %w", employeeIds, err)
+	}
+	return nil
+}
+
+// RolesChangedTx ставит в очередь синхронизацию сотрудников, у которых изменился набор ролей или прав,
+// в транзакции источника изменения: задача появится только вместе с изменением
+func (s *Service) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	if len(employeeIds) == 0 {
+		return nil
+	}
+	if err := s.repo.EnqueueTx(ctx, tx, employeeIds, ReasonAssignment); err != nil {
+		return fmt.Errorf("error enqueuing provisioning of employees %v: %w", employeeIds, err)
+	}
+	return nil
+}
+
+// Hook ставит в очередь синхронизацию сотрудника при смене его статуса или атрибутов (lifecycle.Hook)
+func (s *Service) Hook(ctx context.Context, tx *sqlx.Tx, event lifecycle.Event) error {
+	if err := s.repo.EnqueueTx(ctx, tx, []int64{event.Employee.Id}, ReasonLifecycle); err != nil {
+		return fmt.Errorf("error enqueuing provisioning of employee %d: %w", event.Employee.Id, err)
+	}
+	return nil
+}
+
+// Retry возвращает в очередь задачу, исчерпавшую попытки
+func (s *Service) Retry(ctx context.Context, id int64, actor string) (response Response, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("retrying provisioning task panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("retrying provisioning task: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("retrying provisioning task: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("retrying provisioning task: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	task, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("provisioning task with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding provisioning task with id %d: %w", id, err)
+	}
+	if task.State != StateFailed {
+		return Response{}, common.ConflictError{Message: fmt.Sprintf("provisioning task %d is %s, only failed tasks can be retried", id, task.State)}
+	}
+	pending, err := s.repo.PendingExistsTx(ctx, tx, task.EmployeeId)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding pending provisioning of employee %d: %w", task.EmployeeId, err)
+	}
+	if pending {
+		return Response{}, common.ConflictError{Message: fmt.Sprintf("employee %d already has pending provisioning task", task.EmployeeId)}
+	}
+	if task, err = s.repo.ResetTx(ctx, tx, id); err != nil {
+		return Response{}, fmt.Errorf("error resetting provisioning task %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "provisioning_task.retry",
+		EntityType: "provisioning_task",
+		EntityId:   id,
+		Details:    map[string]any{"employee_id": task.EmployeeId},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit log: %w", err)
+	}
+	return task.toResponse(), nil
+}
+
+// ExecuteDue выполняет задачи, время попытки которых наступило, каждую в своей транзакции
+func (s *Service) ExecuteDue(ctx context.Context, now time.Time) (executed int, err error) {
+	for executed < maxDuePerRun {
+		found, err := s.executeNext(ctx, now)
+		if err != nil {
+			return executed, err
+		}
+		if !found {
+			break
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// executeNext выполняет одну задачу; false, если таких нет.
+// Неудачная синхронизация не ошибка: задача откладывается или переходит в failed
+func (s *Service) executeNext(ctx context.Context, now time.Time) (found bool, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return false, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("executing provisioning task panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing provisioning task: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("executing provisioning task: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("executing provisioning task: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	task, err := s.repo.FindDueTx(ctx, tx, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error finding due provisioning tasks: %w", err)
+	}
+
+	errProvision := s.Provision(ctx, task.EmployeeId)
+	if errProvision == nil {
+		if err = s.repo.CompleteTx(ctx, tx, task.Id); err != nil {
+			return false, fmt.Errorf("error completing provisioning task %d: %w", task.Id, err)
+		}
+		return true, nil
+	}
+
+	attempts := task.Attempts + 1
+	if attempts >= s.maxAttempts {
+		if err = s.repo.FailTx(ctx, tx, task.Id, attempts, errProvision.Error()); err != nil {
+			return false, fmt.Errorf("error failing provisioning task %d: %w", task.Id, err)
+		}
+		return true, nil
+	}
+	if err = s.repo.RetryTx(ctx, tx, task.Id, attempts, now.Add(retryDelay(attempts)), errProvision.Error()); err != nil {
+		return false, fmt.Errorf("error postponing provisioning task %d: %w", task.Id, err)
+	}
+	return true, nil
+}
+
+// retryDelay задержка перед попыткой номер attempts+1
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
+
+// Provision приводит учётные записи сотрудника во всех подключённых целевых системах к его правам.
+// Учётная запись неактивного или удалённого сотрудника блокируется с сохранением прав
+func (s *Service) Provision(ctx context.Context, employeeId int64) error {
+	active := true
+	entitlements, err := s.entitlements.EmployeeEntitlements(ctx, employeeId, 0)
+	var notFound common.NotFoundError
+	if errors.As(err, &notFound) {
+		active = false
+	} else if err != nil {
+		return fmt.Errorf("error finding entitlements of employee %d: %w", employeeId, err)
+	} else {
+		active = entitlements.Status == lifecycle.StatusActive
+	}
+
+	links, err := s.repo.FindAccountLinks(ctx, employeeId)
+	if err != nil {
+		return fmt.Errorf("error finding accounts of employee %d: %w", employeeId, err)
+	}
+	accountIds := map[string]string{}
+	for _, link := range links {
+		accountIds[link.Application] = link.AccountId
+	}
+
+	desired := map[string][]string{}
+	for _, item := range entitlements.Entitlements {
+		desired[item.ApplicationName] = append(desired[item.ApplicationName], item.EntitlementName)
+	}
+
+	// ошибка одной системы не мешает синхронизации остальных
+	var errs []error
+	for _, name := range s.registry.Applications() {
+		connector, _ := s.registry.Get(name)
+		account := Account{Id: cmp.Or(accountIds[name], AccountId(employeeId)), EmployeeId: employeeId,
+			Name: entitlements.EmployeeName}
+		if err := provisionAccount(ctx, connector, account, active, desired[name]); err != nil {
+			errs = append(errs, fmt.Errorf("application %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AccountId идентификатор учётной записи сотрудника в целевых системах, если администратор
+// не принял для него другую учётную запись (AccountLinkEntity)
+func AccountId(employeeId int64) string {
+	return strconv.FormatInt(employeeId, 10)
+}
+
+// provisionAccount приводит учётную запись в одной системе к правам entitlements.
+// Учётная запись без прав не создаётся, а существующая блокируется
+func provisionAccount(ctx context.Context, connector Connector, account Account, active bool, entitlements []string) error {
+	accounts, err := connector.ListAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing accounts: %w", err)
+	}
+	i := slices.IndexFunc(accounts, func(item Account) bool { return item.Id == account.Id })
+	if !active {
+		if i >= 0 && !accounts[i].Disabled {
+			return connector.DisableAccount(ctx, account.Id)
+		}
+		return nil
+	}
+
+	var current Account
+	if i < 0 {
+		if len(entitlements) == 0 {
+			return nil
+		}
+		if err := connector.CreateAccount(ctx, account); err != nil {
+			return fmt.Errorf("error creating account %s: %w", account.Id, err)
+		}
+	} else {
+		current = accounts[i]
+		if len(entitlements) > 0 && (current.Disabled || current.Name != account.Name) {
+			if err := connector.UpdateAccount(ctx, account); err != nil {
+				return fmt.Errorf("error updating account %s: %w", account.Id, err)
+			}
+		}
+	}
+
+	for _, entitlement := range entitlements {
+		if !slices.Contains(current.Entitlements, entitlement) {
+			if err := connector.GrantEntitlement(ctx, account.Id, entitlement); err != nil {
+				return fmt.Errorf("error granting %s to account %s: %w", entitlement, account.Id, err)
+			}
+		}
+	}
+	for _, entitlement := range current.Entitlements {
+		if !slices.Contains(entitlements, entitlement) {
+			if err := connector.RevokeEntitlement(ctx, account.Id, entitlement); err != nil {
+				return fmt.Errorf("error revoking %s from account %s: %w", entitlement, account.Id, err)
+			}
+		}
+	}
+	if len(entitlements) == 0 && i >= 0 && !current.Disabled {
+		if err := connector.DisableAccount(ctx, account.Id); err != nil {
+			return fmt.Errorf("error disabling account %s: %w", account.Id, err)
+		}
+	}
+	return nil
+}
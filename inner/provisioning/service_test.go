@@ -0,0 +1,393 @@
+package provisioning
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/application"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	enqueueQuery  = regexp.QuoteMeta("INSERT INTO provisioning_task (employee_id, reason)\nSELECT id, $2::text FROM unnest($1::bigint[]) id\nUNION\nSELECT d.deputy_id, $2::text FROM delegation d\nWHERE d.delegator_id = ANY($1)")
+	dueQuery      = regexp.QuoteMeta("SELECT * FROM provisioning_task WHERE state = 'pending' AND next_attempt_at <= $1")
+	completeQuery = regexp.QuoteMeta("UPDATE provisioning_task SET state = 'done', last_error = NULL, update_at = now() WHERE id = $1")
+	retryQuery    = regexp.QuoteMeta("UPDATE provisioning_task SET attempts = $2, next_attempt_at = $3, last_error = $4")
+	failQuery     = regexp.QuoteMeta("UPDATE provisioning_task SET state = 'failed', attempts = $2, last_error = $3")
+	findTxQuery   = regexp.QuoteMeta("SELECT * FROM provisioning_task WHERE id = $1 FOR UPDATE")
+	pendingQuery  = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM provisioning_task WHERE employee_id = $1 AND state = 'pending')")
+	resetQuery    = regexp.QuoteMeta("UPDATE provisioning_task SET state = 'pending', attempts = 0, next_attempt_at = now()")
+	linksQuery    = regexp.QuoteMeta("SELECT application, account_id, employee_id FROM provisioning_account WHERE employee_id = $1")
+	auditQuery    = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	linkColumns   = []string{"application", "account_id", "employee_id"}
+	taskColumns   = []string{"id", "employee_id", "reason", "state", "attempts", "last_error", "next_attempt_at",
+		"create_at", "update_at"}
+)
+
+type MockEntitlementSvc struct {
+	mock.Mock
+}
+
+func (m *MockEntitlementSvc) EmployeeEntitlements(ctx context.Context, employeeId int64, applicationId int64) (application.EmployeeEntitlementsResponse, error) {
+	args := m.Called(employeeId, applicationId)
+	return args.Get(0).(application.EmployeeEntitlementsResponse), args.Error(1)
+}
+
+// failingConnector целевая система, которая недоступна
+type failingConnector struct {
+	Connector
+}
+
+func (c failingConnector) ListAccounts(ctx context.Context) ([]Account, error) {
+	return nil, errors.New("connection refused")
+}
+
+func newTestService(t *testing.T, registry *Registry) (*Service, sqlmock.Sqlmock, *MockEntitlementSvc) {
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	entitlements := &MockEntitlementSvc{}
+	srv := NewService(NewProvisioningRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), entitlements, registry, 3)
+	return srv, dbMock, entitlements
+}
+
+// registry с файловыми коннекторами систем ad и sap во временном каталоге
+func newTestRegistry(t *testing.T) *Registry {
+	registry, err := NewFileRegistry(t.TempDir(), FormatJson, "ad,sap")
+	assert.NoError(t, err)
+	return registry
+}
+
+func listAccounts(t *testing.T, registry *Registry, application string) []Account {
+	connector, _ := registry.Get(application)
+	accounts, err := connector.ListAccounts(context.Background())
+	assert.NoError(t, err)
+	return accounts
+}
+
+// права активного сотрудника 7: vpn и mail в ad
+func activeEntitlements(name string) application.EmployeeEntitlementsResponse {
+	return application.EmployeeEntitlementsResponse{
+		EmployeeId:   7,
+		EmployeeName: name,
+		Status:       lifecycle.StatusActive,
+		Entitlements: []application.EmployeeEntitlement{
+			{EntitlementId: 1, EntitlementName: "vpn", ApplicationId: 1, ApplicationName: "ad"},
+			{EntitlementId: 2, EntitlementName: "mail", ApplicationId: 1, ApplicationName: "ad"},
+		},
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	var a = assert.New(t)
+
+	a.Equal(time.Minute, retryDelay(1))
+	a.Equal(2*time.Minute, retryDelay(2))
+	a.Equal(32*time.Minute, retryDelay(6))
+	a.Equal(time.Hour, retryDelay(7))
+	a.Equal(time.Hour, retryDelay(100))
+}
+
+func TestService_Provision(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+
+	t.Run("should create account with entitlements only in systems where employee has them", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.Equal([]Account{{Id: "7", EmployeeId: 7, Name: "john doe", Entitlements: []string{"mail", "vpn"}}},
+			listAccounts(t, registry, "ad"))
+		a.Empty(listAccounts(t, registry, "sap"))
+	})
+
+	t.Run("should fix entitlement drift, rename and enable existing account", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		connector, _ := registry.Get("ad")
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7, Name: "john doe"}))
+		a.NoError(connector.GrantEntitlement(ctx, "7", "admin"))
+		a.NoError(connector.GrantEntitlement(ctx, "7", "vpn"))
+		a.NoError(connector.DisableAccount(ctx, "7"))
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john smith"), nil)
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.Equal([]Account{{Id: "7", EmployeeId: 7, Name: "john smith", Entitlements: []string{"mail", "vpn"}}},
+			listAccounts(t, registry, "ad"))
+	})
+
+	t.Run("should revoke entitlements and disable account when employee has none left", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		connector, _ := registry.Get("sap")
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7, Name: "john doe"}))
+		a.NoError(connector.GrantEntitlement(ctx, "7", "finance"))
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.Equal([]Account{{Id: "7", EmployeeId: 7, Name: "john doe", Disabled: true, Entitlements: []string{}}},
+			listAccounts(t, registry, "sap"))
+	})
+
+	t.Run("should provision adopted account instead of default one", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		connector, _ := registry.Get("ad")
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "jdoe", Name: "john doe"}))
+		a.NoError(connector.GrantEntitlement(ctx, "jdoe", "admin"))
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows(linkColumns).AddRow("ad", "jdoe", 7))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.Equal([]Account{{Id: "jdoe", Name: "john doe", Entitlements: []string{"mail", "vpn"}}},
+			listAccounts(t, registry, "ad"))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should disable account of suspended employee keeping entitlements", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		connector, _ := registry.Get("ad")
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7, Name: "john doe"}))
+		a.NoError(connector.GrantEntitlement(ctx, "7", "vpn"))
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		response := activeEntitlements("john doe")
+		response.Status = lifecycle.StatusSuspended
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(response, nil)
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.Equal([]Account{{Id: "7", EmployeeId: 7, Name: "john doe", Disabled: true, Entitlements: []string{"vpn"}}},
+			listAccounts(t, registry, "ad"))
+		a.Empty(listAccounts(t, registry, "sap"))
+	})
+
+	t.Run("should disable account of deleted employee", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		connector, _ := registry.Get("ad")
+		a.NoError(connector.CreateAccount(ctx, Account{Id: "7", EmployeeId: 7, Name: "john doe"}))
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).
+			Return(application.EmployeeEntitlementsResponse{}, common.NotFoundError{Message: "employee with id 7 not found"})
+
+		a.NoError(srv.Provision(ctx, 7))
+		a.True(listAccounts(t, registry, "ad")[0].Disabled)
+	})
+
+	t.Run("should provision other systems when one of them fails", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		registry.Register("crm", failingConnector{})
+		srv, dbMock, entitlements := newTestService(t, registry)
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+
+		err := srv.Provision(ctx, 7)
+		a.ErrorContains(err, "application crm: error listing accounts: connection refused")
+		a.Len(listAccounts(t, registry, "ad"), 1)
+	})
+}
+
+func TestService_Enqueue(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+
+	t.Run("should enqueue employees whose roles changed", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectExec(enqueueQuery).WithArgs(pq.Int64Array{7, 8}, ReasonAssignment).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		a.NoError(srv.RolesChanged(ctx, []int64{7, 8}))
+		a.NoError(srv.RolesChanged(ctx, []int64{}))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should enqueue employee in lifecycle transaction", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(enqueueQuery).WithArgs(pq.Int64Array{7}, ReasonLifecycle).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+		a.NoError(srv.Hook(ctx, tx, lifecycle.Event{Employee: lifecycle.Employee{Id: 7, Status: lifecycle.StatusTerminated}}))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should enqueue employees in transaction of grant source", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectExec(enqueueQuery).WithArgs(pq.Int64Array{7, 8}, ReasonAssignment).WillReturnResult(sqlmock.NewResult(0, 2))
+
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+		a.NoError(srv.RolesChangedTx(ctx, tx, []int64{7, 8}))
+		a.NoError(srv.RolesChangedTx(ctx, tx, nil))
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown employee", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE id = $1)")).WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		err := srv.Enqueue(ctx, 9)
+		a.ErrorAs(err, &common.NotFoundError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_ExecuteDue(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("should complete task after successful provisioning", func(t *testing.T) {
+		registry := newTestRegistry(t)
+		srv, dbMock, entitlements := newTestService(t, registry)
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StatePending, 0, nil, now, now, now))
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectExec(completeQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(taskColumns))
+		dbMock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(ctx, now)
+		a.NoError(err)
+		a.Equal(1, executed)
+		a.Len(listAccounts(t, registry, "ad"), 1)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should postpone failed task with backoff", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register("crm", failingConnector{})
+		srv, dbMock, entitlements := newTestService(t, registry)
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StatePending, 1, nil, now, now, now))
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectExec(retryQuery).
+			WithArgs(int64(1), 2, now.Add(2*time.Minute), "application crm: error listing accounts: connection refused").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(taskColumns))
+		dbMock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(ctx, now)
+		a.NoError(err)
+		a.Equal(1, executed)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should fail task after last attempt", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register("crm", failingConnector{})
+		srv, dbMock, entitlements := newTestService(t, registry)
+		entitlements.On("EmployeeEntitlements", int64(7), int64(0)).Return(activeEntitlements("john doe"), nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StatePending, 2, "timeout", now, now, now))
+		dbMock.ExpectQuery(linksQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(linkColumns))
+		dbMock.ExpectExec(failQuery).
+			WithArgs(int64(1), 3, "application crm: error listing accounts: connection refused").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(dueQuery).WithArgs(now).WillReturnRows(sqlmock.NewRows(taskColumns))
+		dbMock.ExpectCommit()
+
+		executed, err := srv.ExecuteDue(ctx, now)
+		a.NoError(err)
+		a.Equal(1, executed)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Retry(t *testing.T) {
+	var a = assert.New(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("should return failed task to queue", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StateFailed, 3, "timeout", now, now, now))
+		dbMock.ExpectQuery(pendingQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		dbMock.ExpectQuery(resetQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StatePending, 0, "timeout", now, now, now))
+		dbMock.ExpectExec(auditQuery).WithArgs("kc-admin", "provisioning_task.retry", "provisioning_task", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Retry(ctx, 1, "kc-admin")
+		a.NoError(err)
+		a.Equal(StatePending, got.State)
+		a.Equal(0, got.Attempts)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError for task that is not failed", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StateDone, 1, nil, now, now, now))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Retry(ctx, 1, "kc-admin")
+		a.ErrorAs(err, &common.ConflictError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return ConflictError if employee already has pending task", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(1, 7, ReasonAssignment, StateFailed, 3, "timeout", now, now, now))
+		dbMock.ExpectQuery(pendingQuery).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Retry(ctx, 1, "kc-admin")
+		a.ErrorAs(err, &common.ConflictError{})
+		a.ErrorContains(err, "employee 7 already has pending provisioning task")
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown task", func(t *testing.T) {
+		srv, dbMock, _ := newTestService(t, NewRegistry())
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(findTxQuery).WithArgs(int64(9)).WillReturnRows(sqlmock.NewRows(taskColumns))
+		dbMock.ExpectRollback()
+
+		_, err := srv.Retry(ctx, 9, "kc-admin")
+		a.ErrorAs(err, &common.NotFoundError{})
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_GetAll(t *testing.T) {
+	var a = assert.New(t)
+
+	srv, _, _ := newTestService(t, NewRegistry())
+	_, err := srv.GetAll(context.Background(), "unknown")
+	a.ErrorAs(err, &common.RequestValidatorError{})
+}
@@ -0,0 +1,149 @@
+package reconcile
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+)
+
+type Controller struct {
+	server           *web.Server
+	reconcileService Svc
+	logger           *common.Logger
+}
+
+// интерфейс сервиса reconcile.Service
+type Svc interface {
+	Reconcile(ctx context.Context, request FeedRequest) (Report, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:           server,
+		reconcileService: svc,
+		logger:           logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Post("/hr-feed/plan", c.PlanFeed)
+	c.server.GroupApiV1.Post("/hr-feed/apply", c.ApplyFeed)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/hr-feed/plan"
+// @Description Build reconciliation plan (joiners, movers, leavers) for a full HR export without applying it.
+// @Description Accepts JSON body with records or multipart CSV file with columns external_id, name, org_unit, job_title, start_date, last_working_day.
+// @Summary plan hr feed reconciliation
+// @ID plan-hr-feed
+// @Tags hr-feed
+// @Accept json,mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param request body reconcile.FeedRequest false "full HR export"
+// @Param file formData file false "csv file"
+// @Param mapping formData string false "json mapping of fields to csv columns"
+// @Success 200 {object} common.Response[reconcile.Report]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /hr-feed/plan [post]
+func (c *Controller) PlanFeed(ctx *fiber.Ctx) error {
+	return c.reconcile(ctx, true)
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/hr-feed/apply"
+// @Description Reconcile employees with a full HR export in one transaction. Conflicts are reported, not applied.
+// @Description Accepts JSON body with records or multipart CSV file with columns external_id, name, org_unit, job_title, start_date, last_working_day.
+// @Summary apply hr feed reconciliation
+// @ID apply-hr-feed
+// @Tags hr-feed
+// @Accept json,mpfd
+// @Produce json
+// @Security BearerAuth
+// @Param request body reconcile.FeedRequest false "full HR export"
+// @Param file formData file false "csv file"
+// @Param mapping formData string false "json mapping of fields to csv columns"
+// @Success 200 {object} common.Response[reconcile.Report]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /hr-feed/apply [post]
+func (c *Controller) ApplyFeed(ctx *fiber.Ctx) error {
+	return c.reconcile(ctx, false)
+}
+
+func (c *Controller) reconcile(ctx *fiber.Ctx, dryRun bool) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	request, err := c.readFeed(ctx)
+	if err != nil {
+		return err
+	}
+	request.DryRun = dryRun
+	request.Actor = claims.Actor()
+	c.logger.DebugCtx(ctx.Context(), "reconcile hr feed: received feed",
+		zap.Int("records", len(request.Records)), zap.Bool("dry_run", dryRun))
+
+	// вызываем метод Reconcile сервиса reconcile.Service
+	report, err := c.reconcileService.Reconcile(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "reconcile hr feed", zap.Bool("dry_run", dryRun), zap.Error(err))
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "reconcile hr feed: done", zap.Bool("dry_run", dryRun),
+		zap.Int("joiners", report.Joiners), zap.Int("movers", report.Movers),
+		zap.Int("leavers", report.Leavers), zap.Int("conflicts", len(report.Conflicts)))
+
+	if err := common.OkResponse(ctx, report.Localize(common.RequestLanguage(ctx))); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "reconcile hr feed", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// readFeed читает выгрузку из CSV файла (multipart/form-data) или из JSON тела запроса
+func (c *Controller) readFeed(ctx *fiber.Ctx) (FeedRequest, error) {
+	if fileHeader, err := ctx.FormFile("file"); err == nil {
+		mapping, err := csvutil.ParseMapping(ctx.FormValue("mapping"))
+		if err != nil {
+			return FeedRequest{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.logger.ErrorCtx(ctx.Context(), "reconcile hr feed: open file", zap.Error(err))
+			return FeedRequest{}, err
+		}
+		defer file.Close()
+		records, err := ReadCsv(file, mapping)
+		if err != nil {
+			return FeedRequest{}, err
+		}
+		return FeedRequest{Records: records}, nil
+	}
+
+	var request FeedRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return FeedRequest{}, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	for i := range request.Records {
+		request.Records[i].Position = i
+	}
+	return request, nil
+}
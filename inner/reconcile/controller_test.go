@@ -0,0 +1,101 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса reconcile.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) Reconcile(ctx context.Context, request FeedRequest) (Report, error) {
+	args := svc.Called(request)
+	return args.Get(0).(Report), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_PlanFeed(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should plan feed from json body", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("Reconcile", FeedRequest{
+			Records: []Record{{Position: 0, ExternalId: "E1", Name: "john doe"}},
+			DryRun:  true,
+			Actor:   "admin",
+		}).Return(Report{DryRun: true, Joiners: 1, Actions: []Action{{Type: ActionJoiner, ExternalId: "E1", Name: "john doe"}}}, nil)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/hr-feed/plan",
+			strings.NewReader(`{"records": [{"external_id": "E1", "name": "john doe"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var body common.Response[Report]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &body))
+		a.True(body.Data.DryRun)
+		a.Equal(1, body.Data.Joiners)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/hr-feed/plan", strings.NewReader(`{"records": []}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Reconcile", mock.Anything)
+	})
+}
+
+func TestController_ApplyFeed(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("Reconcile", FeedRequest{
+		Records: []Record{{Position: 2, ExternalId: "E1", Name: "john doe", OrgUnit: "it"}},
+		Actor:   "admin",
+	}).Return(Report{Movers: 1}, nil)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "feed.csv")
+	_, _ = part.Write([]byte("id,name,org_unit\nE1,john doe,it\n"))
+	_ = writer.WriteField("mapping", `{"external_id": "id"}`)
+	_ = writer.Close()
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/hr-feed/apply", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := server.App.Test(req)
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
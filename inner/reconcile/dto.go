@@ -0,0 +1,82 @@
+package reconcile
+
+import (
+	"database/sql"
+	"github.com/nihrom205/idm/inner/common"
+	"time"
+)
+
+// Типы действий плана сверки
+const (
+	ActionJoiner = "joiner"
+	ActionMover  = "mover"
+	ActionLeaver = "leaver"
+)
+
+// Entity сотрудник в том виде, в котором его сверяют с выгрузкой
+type Entity struct {
+	Id         int64          `db:"id"`
+	Name       string         `db:"name"`
+	ExternalId sql.NullString `db:"external_id"`
+	OrgUnit    sql.NullString `db:"org_unit"`
+	JobTitle   sql.NullString `db:"job_title"`
+	Status     string         `db:"status"`
+}
+
+// Change изменение атрибута сотрудника
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Action действие плана: принять (joiner), изменить (mover) или уволить (leaver) сотрудника
+type Action struct {
+	Type       string `json:"type"`
+	ExternalId string `json:"external_id"`
+	// id существующего сотрудника; у joiner заполняется после применения плана
+	EmployeeId int64             `json:"employee_id,omitempty"`
+	Name       string            `json:"name"`
+	Changes    map[string]Change `json:"changes,omitempty"`
+	// статус, в который переводится сотрудник: active у joiner, terminated у leaver
+	Status string `json:"status,omitempty"`
+	// дата запланированной смены статуса; пусто - статус меняется сразу
+	EffectiveAt *time.Time `json:"effective_at,omitempty"`
+	record      Record
+}
+
+// Conflict запись выгрузки, которую нельзя применить. Конфликты не останавливают сверку
+type Conflict struct {
+	Position   int                     `json:"position"`
+	ExternalId string                  `json:"external_id,omitempty"`
+	Message    string                  `json:"message"`
+	Errors     common.ValidationErrors `json:"errors,omitempty"`
+}
+
+// Report план сверки и результат его применения
+type Report struct {
+	// true, если план не применялся
+	DryRun    bool `json:"dry_run"`
+	Joiners   int  `json:"joiners"`
+	Movers    int  `json:"movers"`
+	Leavers   int  `json:"leavers"`
+	Unchanged int  `json:"unchanged"`
+	// сколько действий меняют статус не сразу, а в запланированную дату
+	Scheduled int        `json:"scheduled"`
+	Actions   []Action   `json:"actions"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// Localize переводит сообщения об ошибках валидации записей на указанный язык
+func (r Report) Localize(lang string) Report {
+	conflicts := make([]Conflict, len(r.Conflicts))
+	for i, conflict := range r.Conflicts {
+		if len(conflict.Errors) > 0 {
+			validateErr := common.RequestValidatorError{Message: conflict.Message, Fields: conflict.Errors}.Localize(lang)
+			conflict.Message = validateErr.Message
+			conflict.Errors = validateErr.Fields
+		}
+		conflicts[i] = conflict
+	}
+	r.Conflicts = conflicts
+	return r
+}
@@ -0,0 +1,228 @@
+package reconcile
+
+import (
+	"fmt"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"strings"
+	"time"
+)
+
+// формат дат выгрузки
+const dateLayout = "2006-01-02"
+
+// buildPlan сравнивает выгрузку с сотрудниками и строит план изменений на дату today.
+// Сверяются только сотрудники с external_id: созданные вручную сотрудники не увольняются и не меняются.
+// Уволенные сотрудники остаются в базе, поэтому имя сотрудника, в том числе уволенного, не может быть
+// занято другим: joiner или переименование в занятое имя попадает в конфликты.
+// Уволенный сотрудник, снова появившийся в выгрузке, принимается обратно
+func buildPlan(records []Record, employees []Entity, validator Validator, today time.Time) Report {
+	report := Report{Actions: []Action{}, Conflicts: []Conflict{}}
+
+	managed := make(map[string]Entity)
+	taken := make(map[string]bool)
+	for _, e := range employees {
+		// имена уникальны без учёта регистра
+		taken[strings.ToLower(e.Name)] = true
+		if e.ExternalId.Valid {
+			managed[e.ExternalId.String] = e
+		}
+	}
+
+	// external_id всех записей выгрузки, в том числе некорректных: такие сотрудники не увольняются
+	inFeed := make(map[string]int)
+	valid := make([]Record, 0, len(records))
+	for _, record := range records {
+		record = normalize(record)
+		if first, ok := inFeed[record.ExternalId]; ok && record.ExternalId != "" {
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Position:   record.Position,
+				ExternalId: record.ExternalId,
+				Message:    fmt.Sprintf("duplicate external_id %s, first seen at position %d", record.ExternalId, first),
+			})
+			continue
+		}
+		if record.ExternalId != "" {
+			inFeed[record.ExternalId] = record.Position
+		}
+		if err := validator.Validate(record); err != nil {
+			validateErr := common.NewRequestValidatorError(err)
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Position:   record.Position,
+				ExternalId: record.ExternalId,
+				Message:    validateErr.Message,
+				Errors:     validateErr.Fields,
+			})
+			continue
+		}
+		valid = append(valid, record)
+	}
+
+	for _, record := range valid {
+		current, exists := managed[record.ExternalId]
+		// дата увольнения: на следующий день после последнего рабочего дня
+		var leaveAt *time.Time
+		if record.LastWorkingDay != "" {
+			leaveAt = dateAt(record.LastWorkingDay, 1)
+		}
+		left := leaveAt != nil && !leaveAt.After(today)
+
+		if !exists && left {
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Position:   record.Position,
+				ExternalId: record.ExternalId,
+				Message:    fmt.Sprintf("new employee has already left on %s", record.LastWorkingDay),
+			})
+			continue
+		}
+
+		if exists && left {
+			if current.Status != lifecycle.StatusTerminated {
+				report.add(Action{
+					Type:       ActionLeaver,
+					ExternalId: record.ExternalId,
+					EmployeeId: current.Id,
+					Name:       current.Name,
+					Status:     lifecycle.StatusTerminated,
+				})
+			} else {
+				report.Unchanged++
+			}
+			continue
+		}
+
+		// смена регистра в своём имени не занимает чужое имя
+		renamed := !exists || !strings.EqualFold(current.Name, record.Name)
+		if renamed && taken[strings.ToLower(record.Name)] {
+			report.Conflicts = append(report.Conflicts, Conflict{
+				Position:   record.Position,
+				ExternalId: record.ExternalId,
+				Message:    fmt.Sprintf("employee with name %s already exists", record.Name),
+			})
+			continue
+		}
+		taken[strings.ToLower(record.Name)] = true
+
+		if !exists {
+			action := Action{
+				Type:       ActionJoiner,
+				ExternalId: record.ExternalId,
+				Name:       record.Name,
+				Status:     lifecycle.StatusActive,
+				record:     record,
+			}
+			if record.StartDate != "" {
+				if startAt := dateAt(record.StartDate, 0); startAt.After(today) {
+					action.EffectiveAt = startAt
+				}
+			}
+			report.add(action)
+			if leaveAt != nil {
+				// увольнение нового сотрудника запланируется вместе с приёмом
+				report.add(scheduledLeaver(action, leaveAt))
+			}
+			continue
+		}
+
+		changes := diff(current, record)
+		if len(changes) > 0 {
+			report.add(Action{
+				Type:       ActionMover,
+				ExternalId: record.ExternalId,
+				EmployeeId: current.Id,
+				Name:       record.Name,
+				Changes:    changes,
+				record:     record,
+			})
+		}
+		if leaveAt != nil {
+			report.add(scheduledLeaver(Action{ExternalId: record.ExternalId, EmployeeId: current.Id, Name: record.Name}, leaveAt))
+		}
+		if len(changes) == 0 && leaveAt == nil {
+			report.Unchanged++
+		}
+	}
+
+	for _, e := range employees {
+		if !e.ExternalId.Valid || e.Status == lifecycle.StatusTerminated {
+			continue
+		}
+		if _, ok := inFeed[e.ExternalId.String]; ok {
+			continue
+		}
+		report.add(Action{
+			Type:       ActionLeaver,
+			ExternalId: e.ExternalId.String,
+			EmployeeId: e.Id,
+			Name:       e.Name,
+			Status:     lifecycle.StatusTerminated,
+		})
+	}
+	return report
+}
+
+// add добавляет действие в план и учитывает его в счётчиках отчёта
+func (r *Report) add(action Action) {
+	r.Actions = append(r.Actions, action)
+	if action.EffectiveAt != nil {
+		r.Scheduled++
+	}
+	switch action.Type {
+	case ActionJoiner:
+		r.Joiners++
+	case ActionMover:
+		r.Movers++
+	case ActionLeaver:
+		if action.EffectiveAt == nil {
+			r.Leavers++
+		}
+	}
+}
+
+// scheduledLeaver запланированное увольнение сотрудника
+func scheduledLeaver(action Action, leaveAt *time.Time) Action {
+	return Action{
+		Type:        ActionLeaver,
+		ExternalId:  action.ExternalId,
+		EmployeeId:  action.EmployeeId,
+		Name:        action.Name,
+		Status:      lifecycle.StatusTerminated,
+		EffectiveAt: leaveAt,
+	}
+}
+
+// dateAt начало дня date (в UTC), сдвинутое на days дней. Дата уже проверена валидатором
+func dateAt(date string, days int) *time.Time {
+	parsed, _ := time.Parse(dateLayout, date)
+	parsed = parsed.AddDate(0, 0, days)
+	return &parsed
+}
+
+// normalize убирает пробелы по краям значений записи
+func normalize(record Record) Record {
+	record.ExternalId = strings.TrimSpace(record.ExternalId)
+	record.Name = strings.TrimSpace(record.Name)
+	record.OrgUnit = strings.TrimSpace(record.OrgUnit)
+	record.JobTitle = strings.TrimSpace(record.JobTitle)
+	record.StartDate = strings.TrimSpace(record.StartDate)
+	record.LastWorkingDay = strings.TrimSpace(record.LastWorkingDay)
+	return record
+}
+
+// diff изменённые атрибуты сотрудника. Уволенный сотрудник из выгрузки принимается обратно
+func diff(current Entity, record Record) map[string]Change {
+	changes := make(map[string]Change)
+	if current.Name != record.Name {
+		changes["name"] = Change{From: current.Name, To: record.Name}
+	}
+	if current.OrgUnit.String != record.OrgUnit {
+		changes["org_unit"] = Change{From: current.OrgUnit.String, To: record.OrgUnit}
+	}
+	if current.JobTitle.String != record.JobTitle {
+		changes["job_title"] = Change{From: current.JobTitle.String, To: record.JobTitle}
+	}
+	if current.Status == lifecycle.StatusTerminated {
+		changes["status"] = Change{From: current.Status, To: lifecycle.StatusActive}
+	}
+	return changes
+}
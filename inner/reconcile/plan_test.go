@@ -0,0 +1,164 @@
+package reconcile
+
+import (
+	"database/sql"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func managed(id int64, externalId string, name string, orgUnit string) Entity {
+	return Entity{
+		Id:         id,
+		Name:       name,
+		ExternalId: sql.NullString{String: externalId, Valid: true},
+		OrgUnit:    sql.NullString{String: orgUnit, Valid: orgUnit != ""},
+		Status:     lifecycle.StatusActive,
+	}
+}
+
+func TestBuildPlan(t *testing.T) {
+	var a = assert.New(t)
+	vld := validator.NewValidator()
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should find joiners, movers and leavers", func(t *testing.T) {
+		employees := []Entity{
+			managed(1, "E1", "john doe", "sales"),
+			managed(2, "E2", "jane doe", "sales"),
+			managed(3, "E3", "bob smith", "it"),
+			{Id: 4, Name: "manual user"},
+		}
+		records := []Record{
+			{Position: 2, ExternalId: "E1", Name: "john doe", OrgUnit: "sales"},
+			{Position: 3, ExternalId: "E2", Name: "jane smith", OrgUnit: "marketing"},
+			{Position: 4, ExternalId: "E5", Name: " alice  ", JobTitle: "engineer"},
+		}
+
+		report := buildPlan(records, employees, vld, today)
+
+		a.Equal(1, report.Joiners)
+		a.Equal(1, report.Movers)
+		a.Equal(1, report.Leavers)
+		a.Equal(1, report.Unchanged)
+		a.Empty(report.Conflicts)
+		a.Equal(map[string]Change{
+			"name":     {From: "jane doe", To: "jane smith"},
+			"org_unit": {From: "sales", To: "marketing"},
+		}, report.Actions[0].Changes)
+		a.Equal(ActionJoiner, report.Actions[1].Type)
+		a.Equal("alice", report.Actions[1].Name)
+		a.Equal(lifecycle.StatusActive, report.Actions[1].Status)
+		a.Nil(report.Actions[1].EffectiveAt)
+		a.Equal(Action{
+			Type:       ActionLeaver,
+			ExternalId: "E3",
+			EmployeeId: 3,
+			Name:       "bob smith",
+			Status:     lifecycle.StatusTerminated,
+		}, report.Actions[2])
+	})
+
+	t.Run("should report conflicts instead of failing", func(t *testing.T) {
+		employees := []Entity{
+			managed(1, "E1", "john doe", ""),
+			managed(2, "E2", "jane doe", ""),
+			{Id: 3, Name: "manual user"},
+		}
+		records := []Record{
+			{Position: 2, ExternalId: "E1", Name: "j"},
+			{Position: 3, ExternalId: "E2", Name: "jane doe"},
+			{Position: 4, ExternalId: "E2", Name: "jane doe"},
+			{Position: 5, ExternalId: "E6", Name: "manual user"},
+			{Position: 6, ExternalId: "E7", Name: "jane doe"},
+		}
+
+		report := buildPlan(records, employees, vld, today)
+
+		a.Equal(1, report.Unchanged)
+		a.Equal(0, report.Joiners)
+		// E1 есть в выгрузке с некорректной записью, поэтому не увольняется
+		a.Equal(0, report.Leavers)
+		a.Len(report.Conflicts, 4)
+		a.Equal("$.name", report.Conflicts[0].Errors[0].JsonPath)
+		a.Equal("duplicate external_id E2, first seen at position 3", report.Conflicts[1].Message)
+		a.Equal("employee with name manual user already exists", report.Conflicts[2].Message)
+		a.Equal("employee with name jane doe already exists", report.Conflicts[3].Message)
+	})
+
+	t.Run("should keep name of leaver taken", func(t *testing.T) {
+		employees := []Entity{managed(1, "E1", "john doe", "")}
+		records := []Record{{Position: 0, ExternalId: "E2", Name: "john doe"}}
+
+		report := buildPlan(records, employees, vld, today)
+
+		a.Equal(0, report.Joiners)
+		a.Equal(1, report.Leavers)
+		a.Equal("employee with name john doe already exists", report.Conflicts[0].Message)
+	})
+
+	t.Run("should compare names ignoring case", func(t *testing.T) {
+		employees := []Entity{
+			managed(1, "E1", "john doe", ""),
+			{Id: 2, Name: "Manual User"},
+		}
+		records := []Record{
+			{Position: 2, ExternalId: "E1", Name: "John Doe"},
+			{Position: 3, ExternalId: "E3", Name: "manual user"},
+		}
+
+		report := buildPlan(records, employees, vld, today)
+
+		a.Equal(1, report.Movers)
+		a.Equal(map[string]Change{"name": {From: "john doe", To: "John Doe"}}, report.Actions[0].Changes)
+		a.Equal(0, report.Joiners)
+		a.Equal("employee with name manual user already exists", report.Conflicts[0].Message)
+	})
+
+	t.Run("should rehire terminated employee and skip terminated leaver", func(t *testing.T) {
+		rehired := managed(1, "E1", "john doe", "sales")
+		rehired.Status = lifecycle.StatusTerminated
+		left := managed(2, "E2", "jane doe", "sales")
+		left.Status = lifecycle.StatusTerminated
+		records := []Record{{Position: 0, ExternalId: "E1", Name: "john doe", OrgUnit: "sales"}}
+
+		report := buildPlan(records, []Entity{rehired, left}, vld, today)
+
+		a.Equal(1, report.Movers)
+		a.Equal(0, report.Leavers)
+		a.Equal(map[string]Change{"status": {From: lifecycle.StatusTerminated, To: lifecycle.StatusActive}},
+			report.Actions[0].Changes)
+	})
+
+	t.Run("should schedule start date and last working day", func(t *testing.T) {
+		employees := []Entity{managed(1, "E1", "john doe", ""), managed(2, "E2", "jane doe", "")}
+		records := []Record{
+			{Position: 0, ExternalId: "E1", Name: "john doe", LastWorkingDay: "2026-10-31"},
+			{Position: 1, ExternalId: "E2", Name: "jane doe", LastWorkingDay: "2026-10-18"},
+			{Position: 2, ExternalId: "E3", Name: "alice", StartDate: "2026-11-01"},
+			{Position: 3, ExternalId: "E4", Name: "bob", LastWorkingDay: "2026-10-01"},
+			{Position: 4, ExternalId: "E5", Name: "eve", StartDate: "01.11.2026"},
+		}
+
+		report := buildPlan(records, employees, vld, today)
+
+		a.Equal(1, report.Joiners)
+		a.Equal(1, report.Leavers)
+		a.Equal(2, report.Scheduled)
+		a.Equal(0, report.Unchanged)
+		a.Len(report.Actions, 3)
+		// увольнение на следующий день после последнего рабочего дня
+		a.Equal(ActionLeaver, report.Actions[0].Type)
+		a.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), *report.Actions[0].EffectiveAt)
+		// последний рабочий день уже прошёл - увольнение сразу
+		a.Equal(ActionLeaver, report.Actions[1].Type)
+		a.Nil(report.Actions[1].EffectiveAt)
+		a.Equal(ActionJoiner, report.Actions[2].Type)
+		a.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), *report.Actions[2].EffectiveAt)
+		a.Len(report.Conflicts, 2)
+		a.Equal("$.start_date", report.Conflicts[0].Errors[0].JsonPath)
+		a.Equal("new employee has already left on 2026-10-01", report.Conflicts[1].Message)
+	})
+}
@@ -0,0 +1,62 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/database"
+)
+
+// ключ advisory lock, не дающий запустить две сверки одновременно
+const lockKey = 20261019
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewReconcileRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// LockTx ждёт окончания другой сверки; блокировка снимается по окончании транзакции
+func (r *Repository) LockTx(ctx context.Context, tx *sqlx.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", lockKey)
+	return err
+}
+
+// найти всех сотрудников в рамках транзакции
+func (r *Repository) FindAllTx(ctx context.Context, tx *sqlx.Tx) (employees []Entity, err error) {
+	query := "SELECT id, name, external_id, org_unit, job_title, status FROM employee ORDER BY id"
+	err = tx.SelectContext(ctx, &employees, query)
+	return employees, err
+}
+
+// добавить сотрудника из выгрузки в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (id int64, err error) {
+	query := "INSERT INTO employee (name, external_id, org_unit, job_title, status) VALUES ($1, $2, $3, $4, $5) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, employee.Name, employee.ExternalId, employee.OrgUnit, employee.JobTitle, employee.Status)
+	return id, nameTaken(err, employee.Name)
+}
+
+// изменить атрибуты сотрудника в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error {
+	query := "UPDATE employee SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, employee.Name, employee.OrgUnit, employee.JobTitle, employee.Id)
+	return nameTaken(err, employee.Name)
+}
+
+// nameTaken ошибка уникального индекса по имени, если имя занято сотрудником, появившимся после построения плана
+func nameTaken(err error, name string) error {
+	return database.MapUniqueViolation(err, fmt.Sprintf("employee with name %s already exists", name))
+}
+
+// nullString пустая строка сохраняется как NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
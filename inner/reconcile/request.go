@@ -0,0 +1,60 @@
+package reconcile
+
+import (
+	"errors"
+	"github.com/nihrom205/idm/inner/common/csvutil"
+	"io"
+)
+
+// Record запись выгрузки HR системы. Сотрудник сопоставляется с записью по табельному номеру external_id
+type Record struct {
+	// номер строки CSV файла или индекс записи в JSON запросе
+	Position   int    `json:"-"`
+	ExternalId string `json:"external_id" validate:"required,max=64"`
+	Name       string `json:"name" validate:"required,min=2,max=155"`
+	OrgUnit    string `json:"org_unit" validate:"max=155"`
+	JobTitle   string `json:"job_title" validate:"max=155"`
+	// дата выхода на работу в формате 2006-01-02; до неё новый сотрудник остаётся в статусе pre_hire
+	StartDate string `json:"start_date" validate:"omitempty,datetime=2006-01-02"`
+	// последний рабочий день; сотрудник увольняется на следующий день
+	LastWorkingDay string `json:"last_working_day" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// FeedRequest полная выгрузка сотрудников из HR системы
+type FeedRequest struct {
+	Records []Record `json:"records"`
+	// true - только построить план, ничего не меняя
+	DryRun bool `json:"-"`
+	// кто запустил сверку, попадает в журнал аудита
+	Actor string `json:"-"`
+}
+
+// поля CSV файла выгрузки
+var csvFields = []string{"external_id", "name", "org_unit", "job_title", "start_date", "last_working_day"}
+
+// ReadCsv читает записи выгрузки из CSV файла. mapping задаёт имена столбцов для полей
+func ReadCsv(r io.Reader, mapping map[string]string) ([]Record, error) {
+	reader, err := csvutil.NewReader(r, csvFields, []string{"external_id", "name"}, mapping)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, Record{
+			Position:       row.Line,
+			ExternalId:     row.Values["external_id"],
+			Name:           row.Values["name"],
+			OrgUnit:        row.Values["org_unit"],
+			JobTitle:       row.Values["job_title"],
+			StartDate:      row.Values["start_date"],
+			LastWorkingDay: row.Values["last_working_day"],
+		})
+	}
+}
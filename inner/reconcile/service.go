@@ -0,0 +1,226 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"time"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	LockTx(ctx context.Context, tx *sqlx.Tx) error
+	FindAllTx(ctx context.Context, tx *sqlx.Tx) ([]Entity, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) (int64, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, employee Entity) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции сверки
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// Lifecycle смена статусов сотрудников, реализуется lifecycle.Service
+type Lifecycle interface {
+	TransitionTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error)
+	ScheduleTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.ScheduleRequest) (lifecycle.TransitionResponse, error)
+	AttributesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, actor string) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	lifecycle Lifecycle
+	validator Validator
+}
+
+func NewService(repo Repo, audit AuditRepo, lifecycle Lifecycle, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		lifecycle: lifecycle,
+		validator: validator,
+	}
+}
+
+// Reconcile сверяет сотрудников с полной выгрузкой HR системы. План строится и применяется
+// в одной транзакции под блокировкой; в пробном режиме транзакция откатывается.
+// Некорректные записи и конфликты имён попадают в отчёт и не мешают применить остальной план
+func (s *Service) Reconcile(ctx context.Context, request FeedRequest) (report Report, err error) {
+	if len(request.Records) == 0 {
+		// пустая выгрузка означала бы увольнение всех сотрудников
+		return Report{}, common.RequestValidatorError{Message: "feed contains no records"}
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Report{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("reconciling employees panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil || request.DryRun {
+			// если произошла ошибка или это пробный запуск, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling employees: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("reconciling employees: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	if err = s.repo.LockTx(ctx, tx); err != nil {
+		return Report{}, fmt.Errorf("error locking reconciliation: %w", err)
+	}
+	employees, err := s.repo.FindAllTx(ctx, tx)
+	if err != nil {
+		return Report{}, fmt.Errorf("error finding employees: %w", err)
+	}
+
+	report = buildPlan(request.Records, employees, s.validator, today())
+	report.DryRun = request.DryRun
+	if len(report.Conflicts) == len(request.Records) {
+		// все записи отклонены: применять такой план - значит уволить всех
+		err = common.RequestValidatorError{Message: "feed contains no valid records"}
+		return Report{}, err
+	}
+	if request.DryRun {
+		return report, nil
+	}
+
+	if err = s.apply(ctx, tx, request.Actor, report.Actions); err != nil {
+		return Report{}, err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      request.Actor,
+		Action:     "hr_feed.reconciled",
+		EntityType: "hr_feed",
+		Details: map[string]int{
+			"joiners":   report.Joiners,
+			"movers":    report.Movers,
+			"leavers":   report.Leavers,
+			"unchanged": report.Unchanged,
+			"scheduled": report.Scheduled,
+			"conflicts": len(report.Conflicts),
+		},
+	})
+	if err != nil {
+		return Report{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return report, nil
+}
+
+// apply применяет действия плана в порядке плана: запланированное увольнение нового сотрудника
+// идёт сразу после его приёма и получает id созданного сотрудника
+func (s *Service) apply(ctx context.Context, tx *sqlx.Tx, actor string, actions []Action) error {
+	created := make(map[string]int64)
+	for i := range actions {
+		action := &actions[i]
+		if action.EmployeeId == 0 {
+			action.EmployeeId = created[action.ExternalId]
+		}
+		if err := s.applyAction(ctx, tx, actor, action); err != nil {
+			return fmt.Errorf("error applying %s %s: %w", action.Type, action.ExternalId, err)
+		}
+		if action.Type == ActionJoiner {
+			created[action.ExternalId] = action.EmployeeId
+		}
+		err := s.audit.CreateTx(ctx, tx, audit.Entry{
+			Actor:      actor,
+			Action:     "hr_feed." + action.Type,
+			EntityType: "employee",
+			EntityId:   action.EmployeeId,
+			Details:    action,
+		})
+		if err != nil {
+			return fmt.Errorf("error writing audit: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) applyAction(ctx context.Context, tx *sqlx.Tx, actor string, action *Action) error {
+	switch action.Type {
+	case ActionLeaver:
+		return s.changeStatus(ctx, tx, actor, action)
+	case ActionMover:
+		if err := s.repo.UpdateTx(ctx, tx, toEntity(action.EmployeeId, action.record)); err != nil {
+			return err
+		}
+		if status, ok := action.Changes["status"]; ok {
+			// при повторном приёме роли по правилам назначаются при смене статуса
+			action.Status = status.To
+			return s.changeStatus(ctx, tx, actor, action)
+		}
+		_, orgUnitChanged := action.Changes["org_unit"]
+		_, jobTitleChanged := action.Changes["job_title"]
+		if orgUnitChanged || jobTitleChanged {
+			return s.lifecycle.AttributesChangedTx(ctx, tx, action.EmployeeId, actor)
+		}
+		return nil
+	default:
+		// новый сотрудник создаётся в статусе pre_hire и сразу или в дату выхода становится active
+		employee := toEntity(0, action.record)
+		employee.Status = lifecycle.StatusPreHire
+		id, err := s.repo.CreateTx(ctx, tx, employee)
+		if err != nil {
+			return err
+		}
+		action.EmployeeId = id
+		return s.changeStatus(ctx, tx, actor, action)
+	}
+}
+
+// changeStatus меняет статус сотрудника сразу или планирует смену статуса на action.EffectiveAt
+func (s *Service) changeStatus(ctx context.Context, tx *sqlx.Tx, actor string, action *Action) error {
+	if action.EffectiveAt != nil {
+		_, err := s.lifecycle.ScheduleTx(ctx, tx, lifecycle.ScheduleRequest{
+			EmployeeId:  action.EmployeeId,
+			Status:      action.Status,
+			EffectiveAt: *action.EffectiveAt,
+			Reason:      "hr feed",
+			Actor:       actor,
+		})
+		return err
+	}
+	_, err := s.lifecycle.TransitionTx(ctx, tx, lifecycle.TransitionRequest{
+		EmployeeId: action.EmployeeId,
+		Status:     action.Status,
+		Reason:     "hr feed",
+		Actor:      actor,
+	})
+	return err
+}
+
+// today начало текущего дня в UTC, с ним сравниваются даты выгрузки
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func toEntity(id int64, record Record) Entity {
+	return Entity{
+		Id:         id,
+		Name:       record.Name,
+		ExternalId: nullString(record.ExternalId),
+		OrgUnit:    nullString(record.OrgUnit),
+		JobTitle:   nullString(record.JobTitle),
+	}
+}
@@ -0,0 +1,176 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	mock2 "github.com/stretchr/testify/mock"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type MockLifecycle struct {
+	mock2.Mock
+}
+
+func (m *MockLifecycle) TransitionTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error) {
+	args := m.Called(ctx, tx, request)
+	return args.Get(0).(lifecycle.StatusResponse), args.Error(1)
+}
+
+func (m *MockLifecycle) ScheduleTx(ctx context.Context, tx *sqlx.Tx, request lifecycle.ScheduleRequest) (lifecycle.TransitionResponse, error) {
+	args := m.Called(ctx, tx, request)
+	return args.Get(0).(lifecycle.TransitionResponse), args.Error(1)
+}
+
+func (m *MockLifecycle) AttributesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, actor string) error {
+	args := m.Called(ctx, tx, employeeId, actor)
+	return args.Error(0)
+}
+
+func TestReconcile(t *testing.T) {
+	a := assert.New(t)
+	lockQuery := regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")
+	findQuery := regexp.QuoteMeta("SELECT id, name, external_id, org_unit, job_title, status FROM employee ORDER BY id")
+	insertQuery := regexp.QuoteMeta("INSERT INTO employee (name, external_id, org_unit, job_title, status) VALUES ($1, $2, $3, $4, $5) RETURNING id")
+	updateQuery := regexp.QuoteMeta("UPDATE employee SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4")
+	auditQuery := regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	columns := []string{"id", "name", "external_id", "org_unit", "job_title", "status"}
+
+	newService := func() (*Service, sqlmock.Sqlmock, *MockLifecycle) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		sqlxDb := sqlx.NewDb(db, "sqlmock")
+		lifecycleSvc := &MockLifecycle{}
+		srv := NewService(NewReconcileRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), lifecycleSvc, validator.NewValidator())
+		return srv, mock, lifecycleSvc
+	}
+	records := []Record{
+		{Position: 0, ExternalId: "E1", Name: "john doe", OrgUnit: "it"},
+		{Position: 1, ExternalId: "E3", Name: "alice"},
+	}
+	existing := func() *sqlmock.Rows {
+		return sqlmock.NewRows(columns).
+			AddRow(1, "john doe", "E1", "sales", nil, lifecycle.StatusActive).
+			AddRow(2, "jane doe", "E2", nil, nil, lifecycle.StatusActive)
+	}
+
+	t.Run("should apply plan and write audit in one transaction", func(t *testing.T) {
+		srv, mock, lifecycleSvc := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WillReturnRows(existing())
+		mock.ExpectExec(updateQuery).WithArgs("john doe", nullString("it"), nullString(""), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "hr_feed.mover", "employee", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(insertQuery).
+			WithArgs("alice", nullString("E3"), nullString(""), nullString(""), lifecycle.StatusPreHire).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "hr_feed.joiner", "employee", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "hr_feed.leaver", "employee", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "hr_feed.reconciled", "hr_feed", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+		lifecycleSvc.On("AttributesChangedTx", mock2.Anything, mock2.Anything, int64(1), "admin").Return(nil)
+		lifecycleSvc.On("TransitionTx", mock2.Anything, mock2.Anything, lifecycle.TransitionRequest{
+			EmployeeId: 3, Status: lifecycle.StatusActive, Reason: "hr feed", Actor: "admin",
+		}).Return(lifecycle.StatusResponse{}, nil)
+		lifecycleSvc.On("TransitionTx", mock2.Anything, mock2.Anything, lifecycle.TransitionRequest{
+			EmployeeId: 2, Status: lifecycle.StatusTerminated, Reason: "hr feed", Actor: "admin",
+		}).Return(lifecycle.StatusResponse{}, nil)
+
+		report, err := srv.Reconcile(context.Background(), FeedRequest{Records: records, Actor: "admin"})
+
+		a.Nil(err)
+		a.Equal(1, report.Joiners)
+		a.Equal(1, report.Movers)
+		a.Equal(1, report.Leavers)
+		a.Equal(int64(3), report.Actions[1].EmployeeId)
+		a.NoError(mock.ExpectationsWereMet())
+		lifecycleSvc.AssertExpectations(t)
+	})
+
+	t.Run("should roll back in dry run", func(t *testing.T) {
+		srv, mock, _ := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WillReturnRows(existing())
+		mock.ExpectRollback()
+
+		report, err := srv.Reconcile(context.Background(), FeedRequest{Records: records, DryRun: true})
+
+		a.Nil(err)
+		a.True(report.DryRun)
+		a.Len(report.Actions, 3)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should refuse feed without valid records", func(t *testing.T) {
+		srv, mock, _ := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WillReturnRows(existing())
+		mock.ExpectRollback()
+
+		_, err := srv.Reconcile(context.Background(), FeedRequest{Records: []Record{{ExternalId: "E1"}}})
+
+		var validatorErr common.RequestValidatorError
+		a.True(errors.As(err, &validatorErr))
+		a.Equal("feed contains no valid records", validatorErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back on database error", func(t *testing.T) {
+		srv, mock, _ := newService()
+		mock.ExpectBegin()
+		mock.ExpectExec(lockQuery).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(findQuery).WillReturnRows(existing())
+		mock.ExpectExec(updateQuery).WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		_, err := srv.Reconcile(context.Background(), FeedRequest{Records: records})
+
+		a.ErrorContains(err, "error applying mover E1")
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject empty feed", func(t *testing.T) {
+		srv, mock, _ := newService()
+
+		_, err := srv.Reconcile(context.Background(), FeedRequest{})
+
+		var validatorErr common.RequestValidatorError
+		a.True(errors.As(err, &validatorErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestReadCsv(t *testing.T) {
+	a := assert.New(t)
+	file := "Табельный номер,ФИО,Отдел\n" +
+		"E1,John Doe,Sales\n" +
+		"\n" +
+		"E2,Jane Doe,\n"
+
+	records, err := ReadCsv(strings.NewReader(file), map[string]string{
+		"external_id": "Табельный номер",
+		"name":        "ФИО",
+		"org_unit":    "Отдел",
+	})
+
+	a.Nil(err)
+	a.Equal([]Record{
+		{Position: 2, ExternalId: "E1", Name: "John Doe", OrgUnit: "Sales"},
+		{Position: 4, ExternalId: "E2", Name: "Jane Doe"},
+	}, records)
+}
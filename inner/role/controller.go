@@ -1,11 +1,11 @@
 package role
 
 import (
+	"bufio"
 	"context"
-	"errors"
 	"github.com/gofiber/fiber/v2"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/web"
 	"go.uber.org/zap"
 	"slices"
@@ -22,10 +22,13 @@ type Controller struct {
 type Svc interface {
 	Create(ctx context.Context, request CreateRequest) (int64, error)
 	FindById(ctx context.Context, id int64) (Response, error)
-	GetAll(ctx context.Context) ([]Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest) (Response, error)
+	Find(ctx context.Context, request FilterRequest) ([]Response, error)
 	FindByIds(ctx context.Context, ids []int64) ([]Response, error)
 	DeleteById(ctx context.Context, id int64) error
 	DeleteByIds(ctx context.Context, ids []int64) error
+	Export(ctx context.Context, textFilter string) (csvutil.Export, error)
+	Import(ctx context.Context, request ImportRequest) (csvutil.ImportReport, error)
 }
 
 func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
@@ -38,7 +41,10 @@ func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controll
 
 func (c *Controller) RegisterRoutes() {
 	c.server.GroupApiV1.Post("/roles", c.CreateRole)
+	c.server.GroupApiV1.Post("/roles/import", c.ImportRoles)
+	c.server.GroupApiV1.Get("/roles/export", c.ExportRoles)
 	c.server.GroupApiV1.Get("/roles/:id", c.GetRole)
+	c.server.GroupApiV1.Put("/roles/:id", c.UpdateRole)
 	c.server.GroupApiV1.Get("/roles", c.GetAllRoles)
 	c.server.GroupApiV1.Post("/roles/ids", c.GetRoleByIds)
 	c.server.GroupApiV1.Delete("/roles/ids", c.DeleteRolesByIds)
@@ -55,15 +61,17 @@ func (c *Controller) RegisterRoutes() {
 // @Security BearerAuth
 // @Param request body role.CreateRequest true "name role"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /role [post]
 func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -83,22 +91,12 @@ func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 	newEmployeeId, err := c.roleService.Create(ctx.Context(), request)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "create role", zap.Any("request", request))
-		switch {
-		// если сервис возвращает ошибку RequestValidationError или AlreadyExistsError,
-		// то мы возвращаем ответ с кодом 400 (BadRequest)
-		case errors.As(err, &common.RequestValidatorError{}) || errors.As(err, &common.AlreadyExistsError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-
-		// если сервис возвращает другую ошибку, то мы возвращаем ответ с кодом 500 (InternalServerError)
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 
 	// функция OkResponse() формирует и направляет ответ в случае успеха
 	if err = common.OkResponse(ctx, newEmployeeId); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "create role", zap.Any("request", request))
-		_ = common.ErrResponse(ctx, fiber.StatusInternalServerError, "error returning created employee id")
 		return err
 	}
 	return nil
@@ -114,15 +112,15 @@ func (c *Controller) CreateRole(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path int64 true "id role"
 // @Success 200 {object} common.Response[role.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /roles/{id} [get]
 func (c *Controller) GetRole(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -137,51 +135,110 @@ func (c *Controller) GetRole(ctx *fiber.Ctx) error {
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get role: invalid id param", zap.Any("idParam", idParam))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
 	}
 
 	// вызываем метод FindById сервиса role.Service
 	response, err := c.roleService.FindById(ctx.Context(), id)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get role", zap.Any("request", idParam))
-		switch {
-		case errors.As(err, &common.RequestValidatorError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		case errors.As(err, &common.RepositoryError{}):
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		case errors.As(err, &common.NotFoundError{}):
-			return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get role", zap.Any("request", idParam))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/roles/:id"
+// @Description Update role name and catalog metadata. Omitted metadata fields keep their values.
+// @Summary update role
+// @ID update-role
+// @Tags role
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id role"
+// @Param request body role.UpdateRequest true "role"
+// @Success 200 {object} common.Response[role.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/{id} [put]
+func (c *Controller) UpdateRole(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// получаем ID из параметра маршрута
+	idParam := ctx.Params("id")
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role: invalid id param", zap.Any("idParam", idParam))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
+	}
+
+	// анмаршалим JSON body запроса в структуру UpdateRequest
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role: received request", zap.Error(err))
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "update role", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод Update сервиса role.Service
+	response, err := c.roleService.Update(ctx.Context(), id, request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role", zap.Int64("id", id), zap.Error(err))
+		return err
 	}
 	return nil
 }
 
 // функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles"
-// @Description Get all role.
+// @Description Get roles of the catalog filtered by name or description, owner, risk level, requestable flag,
+// @Description tag and external system.
 // @Summary get all role
 // @ID get-all-role
 // @Tags role
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} common.Response[role.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Param textFilter query string false "filter by name or description, at least 3 characters"
+// @Param owner_id query int64 false "owner employee id"
+// @Param risk_level query string false "risk level" Enums(low, medium, high, critical)
+// @Param requestable query bool false "requestable flag"
+// @Param tag query string false "tag"
+// @Param external_system query string false "external system"
+// @Success 200 {object} common.Response[[]role.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /roles [get]
 func (c *Controller) GetAllRoles(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -190,17 +247,37 @@ func (c *Controller) GetAllRoles(ctx *fiber.Ctx) error {
 		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
 	}
 
-	// вызываем метод GetAll сервиса role.Service
-	response, err := c.roleService.GetAll(ctx.Context())
+	request := FilterRequest{
+		TextFilter:     ctx.Query("textFilter"),
+		RiskLevel:      ctx.Query("risk_level"),
+		Tag:            ctx.Query("tag"),
+		ExternalSystem: ctx.Query("external_system"),
+	}
+	if ownerId := ctx.Query("owner_id"); ownerId != "" {
+		request.OwnerId, err = strconv.ParseInt(ownerId, 10, 64)
+		if err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid owner_id")
+		}
+	}
+	if requestable := ctx.Query("requestable"); requestable != "" {
+		value, err := strconv.ParseBool(requestable)
+		if err != nil {
+			return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid requestable")
+		}
+		request.Requestable = &value
+	}
+
+	// вызываем метод Find сервиса role.Service
+	response, err := c.roleService.Find(ctx.Context(), request)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get all roles", zap.Any("request", err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get all roles", zap.Any("request", err))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
@@ -215,15 +292,15 @@ func (c *Controller) GetAllRoles(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param ids body role.FindByIdsRequest true "ids role"
 // @Success 200 {object} common.Response[role.Response]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /roles/ids [post]
 func (c *Controller) GetRoleByIds(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -244,13 +321,13 @@ func (c *Controller) GetRoleByIds(ctx *fiber.Ctx) error {
 	response, err := c.roleService.FindByIds(ctx.Context(), request.Ids)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get role", zap.Any("request", request))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, response); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "get role", zap.Any("request", request))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
@@ -265,15 +342,15 @@ func (c *Controller) GetRoleByIds(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param id path int64 true "id role"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /role/{id} [delete]
 func (c *Controller) DeleteRole(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -287,27 +364,18 @@ func (c *Controller) DeleteRole(ctx *fiber.Ctx) error {
 	id, err := strconv.ParseInt(idParam, 10, 64)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete role: invalid id param", zap.Any("idParam", idParam))
-		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid employee id")
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid role id")
 	}
 
 	// вызываем метод DeleteById сервиса role.Service
 	err = c.roleService.DeleteById(ctx.Context(), id)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete role", zap.Any("request", idParam))
-		switch {
-		case errors.As(err, &common.RequestValidatorError{}):
-			return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
-		case errors.As(err, &common.RepositoryError{}):
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		case errors.As(err, &common.NotFoundError{}):
-			return common.ErrResponse(ctx, fiber.StatusNotFound, err.Error())
-		default:
-			return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
-		}
+		return err
 	}
 	if err := common.OkResponse(ctx, struct{}{}); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete role", zap.Any("request", idParam))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
@@ -322,15 +390,15 @@ func (c *Controller) DeleteRole(ctx *fiber.Ctx) error {
 // @Security BearerAuth
 // @Param ids body role.DeleteByIdsRequest true "ids role"
 // @Success 200 {object} common.Response[int64]
-// @Failure 400 {object} common.Response[string]
-// @Failure 401 {object} common.Response[string]
-// @Failure 403 {object} common.Response[string]
-// @Failure 500 {object} common.Response[string]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
 // @Router /role/ids [delete]
 func (c *Controller) DeleteRolesByIds(ctx *fiber.Ctx) error {
 
 	// проверяем наличие нужной роли в токене
-	claims, err := getClaims(ctx)
+	claims, err := web.GetClaims(ctx)
 	if err != nil {
 		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
@@ -350,25 +418,133 @@ func (c *Controller) DeleteRolesByIds(ctx *fiber.Ctx) error {
 	err = c.roleService.DeleteByIds(ctx.Context(), request.Ids)
 	if err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete roles", zap.Any("request", request))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 
 	// возвращаем успешный ответ
 	if err := common.OkResponse(ctx, struct{}{}); err != nil {
 		c.logger.ErrorCtx(ctx.Context(), "delete roles", zap.Any("request", request))
-		return common.ErrResponse(ctx, fiber.StatusInternalServerError, err.Error())
+		return err
 	}
 	return nil
 }
 
-func getClaims(ctx *fiber.Ctx) (*web.IdmClaims, error) {
-	token, ok := ctx.Locals(web.JwtKey).(*jwt.Token)
-	if !ok || token == nil {
-		return nil, errors.New("missing or invalid token")
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/roles/export"
+// @Description Export roles to CSV file. Rows are streamed without loading all roles into memory.
+// @Summary export roles
+// @ID export-roles
+// @Tags role
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "export format, only csv is supported" default(csv)
+// @Param textFilter query string false "filter by name"
+// @Success 200 {string} string "CSV file with columns id, name, create_at, update_at"
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/export [get]
+func (c *Controller) ExportRoles(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
 	}
-	claims, ok := token.Claims.(*web.IdmClaims)
-	if !ok || claims == nil {
-		return nil, errors.New("missing or invalid claims")
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	if format := ctx.Query("format", "csv"); format != "csv" {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "unsupported export format "+format)
 	}
-	return claims, nil
+	textFilter := ctx.Query("textFilter")
+
+	// строки пишутся в ответ уже после выхода из хендлера, поэтому выгрузка работает в контексте,
+	// не связанном с запросом. Запрос к базе данных выполняется до начала ответа, и его ошибка
+	// возвращается обычным образом
+	exportCtx := common.DetachedContext(ctx)
+	export, err := c.roleService.Export(exportCtx, textFilter)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "export roles", zap.Error(err))
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	ctx.Set(fiber.HeaderContentDisposition, `attachment; filename="roles.csv"`)
+
+	// после начала выгрузки статус ответа уже не изменить, поэтому ошибки только логируются
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer export.Close()
+		if err := export.Write(w); err != nil {
+			c.logger.ErrorCtx(exportCtx, "export roles", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/roles/import"
+// @Description Import roles from CSV file. Rows without id create roles, rows with id rename existing ones.
+// @Description Invalid rows are rejected and reported, other rows are imported.
+// @Summary import roles
+// @ID import-roles
+// @Tags role
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file with header"
+// @Param mapping formData string false "JSON object mapping field names to CSV headers"
+// @Param dry_run query bool false "validate file and report changes without saving them"
+// @Success 200 {object} common.Response[csvutil.ImportReport]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /roles/import [post]
+func (c *Controller) ImportRoles(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "csv file is required")
+	}
+	mapping, err := csvutil.ParseMapping(ctx.FormValue("mapping"))
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import roles: open file", zap.Error(err))
+		return err
+	}
+	defer file.Close()
+
+	request := ImportRequest{File: file, Mapping: mapping, DryRun: ctx.QueryBool("dry_run")}
+	c.logger.DebugCtx(ctx.Context(), "import roles: received file",
+		zap.String("file", fileHeader.Filename), zap.Int64("size", fileHeader.Size), zap.Bool("dry_run", request.DryRun))
+
+	// вызываем метод Import сервиса role.Service
+	report, err := c.roleService.Import(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import roles", zap.Error(err))
+		return err
+	}
+
+	// ошибки валидации строк переводим на язык клиента
+	if err := common.OkResponse(ctx, report.Localize(common.RequestLanguage(ctx))); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "import roles", zap.Error(err))
+		return err
+	}
+	return nil
 }
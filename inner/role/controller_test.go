@@ -1,17 +1,20 @@
 package role
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/web"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -35,8 +38,36 @@ func (svc *MockService) Create(ctx context.Context, request CreateRequest) (int6
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
-	args := svc.Called()
+func (svc *MockService) Update(ctx context.Context, id int64, request UpdateRequest) (Response, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Export(ctx context.Context, textFilter string) (csvutil.Export, error) {
+	args := svc.Called(textFilter)
+	export, _ := args.Get(0).(csvutil.Export)
+	return export, args.Error(1)
+}
+
+// exportStub выгрузка готового CSV файла
+type exportStub string
+
+func (e exportStub) Write(w io.Writer) error {
+	_, err := io.WriteString(w, string(e))
+	return err
+}
+
+func (e exportStub) Close() error {
+	return nil
+}
+
+func (svc *MockService) Import(ctx context.Context, request ImportRequest) (csvutil.ImportReport, error) {
+	args := svc.Called(request.Mapping, request.DryRun)
+	return args.Get(0).(csvutil.ImportReport), args.Error(1)
+}
+
+func (svc *MockService) Find(ctx context.Context, request FilterRequest) ([]Response, error) {
+	args := svc.Called(request)
 	return args.Get(0).([]Response), args.Error(1)
 }
 
@@ -76,7 +107,7 @@ func TestController_CreateRole(t *testing.T) {
 	// тестируем положительный сценарий: работника создали и получили его id
 	t.Run("should return created role id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -104,12 +135,11 @@ func TestController_CreateRole(t *testing.T) {
 		a.Nil(err)
 		a.Equal(int64(123), responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return error if role already exists", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -130,20 +160,20 @@ func TestController_CreateRole(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusConflict, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeAlreadyExists, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error validator error", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -164,20 +194,20 @@ func TestController_CreateRole(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeValidationFailed, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error transaction", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -200,17 +230,17 @@ func TestController_CreateRole(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return error unmarshal", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -233,12 +263,76 @@ func TestController_CreateRole(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[int64]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(int64(0), responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
+	})
+}
+
+func TestController_UpdateRole(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+	newServer := func(svc Svc, roles ...string) *web.Server {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(func(c *fiber.Ctx) error {
+			c.Locals(web.JwtKey, &jwt.Token{Claims: &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}})
+			return c.Next()
+		})
+		NewController(server, svc, logger).RegisterRoutes()
+		return server
+	}
+
+	t.Run("should update role metadata", func(t *testing.T) {
+		svc := &MockService{}
+		server := newServer(svc, web.IdmAdmin)
+		riskLevel := RiskHigh
+		ownerId := int64(7)
+		svc.On("Update", int64(3), UpdateRequest{Name: "db admin", RiskLevel: &riskLevel, OwnerId: &ownerId}).
+			Return(Response{Id: 3, Name: "db admin", RiskLevel: RiskHigh, OwnerId: 7}, nil)
+
+		body := strings.NewReader(`{"name": "db admin", "risk_level": "high", "owner_id": 7}`)
+		req := httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/3", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(RiskHigh, got.Data.RiskLevel)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 422 for high risk role without owner", func(t *testing.T) {
+		svc := &MockService{}
+		server := newServer(svc, web.IdmAdmin)
+		svc.On("Update", int64(3), mock.Anything).
+			Return(Response{}, common.RequestValidatorError{Message: "role with high risk level must have an owner"})
+
+		body := strings.NewReader(`{"name": "db admin", "risk_level": "high"}`)
+		req := httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/3", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+	})
+
+	t.Run("should return 403 for user", func(t *testing.T) {
+		svc := &MockService{}
+		server := newServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPut, "/api/v1/roles/3", strings.NewReader(`{"name": "db admin"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
 	})
 }
 
@@ -262,7 +356,7 @@ func TestController_GetEmployee(t *testing.T) {
 
 	t.Run("should return employee", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -296,12 +390,11 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Nil(err)
 		a.Equal(response, responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err bad id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -320,16 +413,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err validation", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -348,19 +442,20 @@ func TestController_GetEmployee(t *testing.T) {
 		// Выполняем проверки полученных данных
 		a.Nil(err)
 		a.NotEmpty(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeValidationFailed, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err transaction", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -382,16 +477,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeInternal, problem.Code)
+		a.NotContains(string(bytesData), "transaction error")
 	})
 
 	t.Run("should return err not found", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -413,16 +509,17 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusNotFound, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.Equal(common.CodeNotFound, problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -444,11 +541,12 @@ func TestController_GetEmployee(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -472,7 +570,7 @@ func TestController_GetAllRoles(t *testing.T) {
 
 	t.Run("should success get all roles", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -498,7 +596,7 @@ func TestController_GetAllRoles(t *testing.T) {
 		}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("GetAll").Return(responses, nil)
+		svc.On("Find", FilterRequest{}).Return(responses, nil)
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -514,12 +612,11 @@ func TestController_GetAllRoles(t *testing.T) {
 		a.Nil(err)
 		a.Equal(responses, responseBody.Data)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -532,7 +629,7 @@ func TestController_GetAllRoles(t *testing.T) {
 		responses := []Response{}
 
 		// Настраиваем поведение мока в тесте
-		svc.On("GetAll").Return(responses, errors.New("error"))
+		svc.On("Find", FilterRequest{}).Return(responses, errors.New("error"))
 
 		// Отправляем тестовый запрос на веб сервер
 		resp, err := server.App.Test(req)
@@ -543,12 +640,53 @@ func TestController_GetAllRoles(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[[]Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
+		a.Nil(err)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
+	})
+}
+
+func TestController_GetAllRolesFilter(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+	claims := &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: []string{web.IdmUser}}}
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+
+	t.Run("should pass filter to service", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		NewController(server, svc, logger).RegisterRoutes()
+		requestable := false
+		svc.On("Find", FilterRequest{OwnerId: 7, RiskLevel: RiskCritical, Requestable: &requestable, Tag: "finance",
+			ExternalSystem: "sap"}).Return([]Response{}, nil)
+
+		req := httptest.NewRequest(fiber.MethodGet,
+			"/api/v1/roles?owner_id=7&risk_level=critical&requestable=false&tag=finance&external_system=sap", nil)
+		resp, err := server.App.Test(req)
+
 		a.Nil(err)
-		a.Nil(responseBody.Data)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 400 for invalid requestable", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		NewController(server, svc, logger).RegisterRoutes()
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles?requestable=maybe", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		svc.AssertNotCalled(t, "Find", mock.Anything)
 	})
 }
 
@@ -572,7 +710,7 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 
 	t.Run("should success get all roles by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -615,12 +753,11 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		a.Nil(err)
 		a.Equal(len(responses), len(responseBody.Data))
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should return err", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -645,12 +782,12 @@ func TestController_GetEmployeeByIds(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[[]Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.Equal(len(responses), len(responseBody.Data))
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -674,7 +811,7 @@ func TestController_DeleteEmployee(t *testing.T) {
 
 	t.Run("should success del by id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -700,12 +837,11 @@ func TestController_DeleteEmployee(t *testing.T) {
 		err = json.Unmarshal(bytesData, &responseBody)
 		a.Nil(err)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should success err invalid id", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -724,11 +860,12 @@ func TestController_DeleteEmployee(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 }
 
@@ -752,7 +889,7 @@ func TestController_DeleteRolesByIds(t *testing.T) {
 
 	t.Run("should success del by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -779,12 +916,11 @@ func TestController_DeleteRolesByIds(t *testing.T) {
 		err = json.Unmarshal(bytesData, &responseBody)
 		a.Nil(err)
 		a.True(responseBody.Success)
-		a.Empty(responseBody.Message)
 	})
 
 	t.Run("should err bad request", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -804,16 +940,17 @@ func TestController_DeleteRolesByIds(t *testing.T) {
 		a.Equal(http.StatusBadRequest, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
 	})
 
 	t.Run("should success del by ids", func(t *testing.T) {
 		// Готовим тестовое окружение
-		server := web.NewServer()
+		server := web.NewServer(logger)
 		server.GroupApi.Use(auth)
 		svc := &MockService{}
 		controller := NewController(server, svc, logger)
@@ -839,10 +976,77 @@ func TestController_DeleteRolesByIds(t *testing.T) {
 		a.Equal(http.StatusInternalServerError, resp.StatusCode)
 		bytesData, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		var responseBody common.Response[Response]
-		err = json.Unmarshal(bytesData, &responseBody)
+		var problem common.Problem
+		err = json.Unmarshal(bytesData, &problem)
 		a.Nil(err)
-		a.False(responseBody.Success)
-		a.NotEmpty(responseBody.Message)
+		a.Equal(resp.StatusCode, problem.Status)
+		a.NotEmpty(problem.Code)
+		a.NotEmpty(problem.Detail)
+	})
+}
+
+func TestController_ExportImportRoles(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{
+		Logger: zap.NewNop(),
+	}
+	claims := &web.IdmClaims{
+		RealmAccess: web.RealmAccessClaims{
+			Roles: []string{web.IdmAdmin},
+		},
+	}
+	auth := func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+
+	t.Run("should stream csv file", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		svc.On("Export", "").Return(exportStub("id,name\n1,admin\n"), nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/roles/export", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "roles.csv")
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Equal("id,name\n1,admin\n", string(body))
+	})
+
+	t.Run("should return import report", func(t *testing.T) {
+		server := web.NewServer(logger)
+		server.GroupApi.Use(auth)
+		svc := &MockService{}
+		controller := NewController(server, svc, logger)
+		controller.RegisterRoutes()
+
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, err := writer.CreateFormFile("file", "roles.csv")
+		a.Nil(err)
+		_, err = part.Write([]byte("name\nadmin\n"))
+		a.Nil(err)
+		a.Nil(writer.Close())
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/roles/import", &body)
+		req.Header.Set(fiber.HeaderContentType, writer.FormDataContentType())
+
+		svc.On("Import", map[string]string{}, false).Return(csvutil.ImportReport{
+			Created: 1,
+			Rows:    []csvutil.RowResult{{Line: 2, Status: csvutil.RowCreated, Id: 1}},
+		}, nil)
+
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var responseBody common.Response[csvutil.ImportReport]
+		a.Nil(json.NewDecoder(resp.Body).Decode(&responseBody))
+		a.Equal(1, responseBody.Data.Created)
 	})
 }
@@ -1,26 +1,60 @@
 package role
 
-import "time"
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Уровни риска роли
+const (
+	RiskLow      = "low"
+	RiskMedium   = "medium"
+	RiskHigh     = "high"
+	RiskCritical = "critical"
+)
 
 type Entity struct {
-	Id       int64     `db:"id"`
-	Name     string    `db:"name"`
-	CreateAt time.Time `db:"create_at"`
-	UpdateAt time.Time `db:"update_at"`
+	Id          int64          `db:"id"`
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	OwnerId     sql.NullInt64  `db:"owner_id"`
+	RiskLevel   string         `db:"risk_level"`
+	Requestable bool           `db:"requestable"`
+	Tags        pq.StringArray `db:"tags"`
+	// система, в которой роль заведена, и идентификатор роли в ней
+	ExternalSystem sql.NullString `db:"external_system"`
+	ExternalId     sql.NullString `db:"external_id"`
+	CreateAt       time.Time      `db:"create_at"`
+	UpdateAt       time.Time      `db:"update_at"`
 }
 
 func (e *Entity) toResponse() Response {
 	return Response{
-		Id:       e.Id,
-		Name:     e.Name,
-		CreateAt: e.CreateAt,
-		UpdateAt: e.UpdateAt,
+		Id:             e.Id,
+		Name:           e.Name,
+		Description:    e.Description,
+		OwnerId:        e.OwnerId.Int64,
+		RiskLevel:      e.RiskLevel,
+		Requestable:    e.Requestable,
+		Tags:           e.Tags,
+		ExternalSystem: e.ExternalSystem.String,
+		ExternalId:     e.ExternalId.String,
+		CreateAt:       e.CreateAt,
+		UpdateAt:       e.UpdateAt,
 	}
 }
 
 type Response struct {
-	Id       int64     `json:"id"`
-	Name     string    `json:"name"`
-	CreateAt time.Time `json:"create_at"`
-	UpdateAt time.Time `json:"update_at"`
+	Id             int64     `json:"id"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	OwnerId        int64     `json:"owner_id,omitempty"`
+	RiskLevel      string    `json:"risk_level,omitempty"`
+	Requestable    bool      `json:"requestable"`
+	Tags           []string  `json:"tags,omitempty"`
+	ExternalSystem string    `json:"external_system,omitempty"`
+	ExternalId     string    `json:"external_id,omitempty"`
+	CreateAt       time.Time `json:"create_at"`
+	UpdateAt       time.Time `json:"update_at"`
 }
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/database"
+	"strings"
+	"unicode/utf8"
 )
 
 type Repository struct {
@@ -15,12 +18,69 @@ func NewRoleRepository(db *sqlx.DB) *Repository {
 	return &Repository{db: db}
 }
 
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+const insertRoleQuery = `INSERT INTO role (name, description, owner_id, risk_level, requestable, tags, external_system, external_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+
 // добавить новый элемент в коллекцию
 func (r *Repository) Create(ctx context.Context, role Entity) (int64, error) {
 	var id int64
-	query := "INSERT INTO role (name) VALUES ($1) RETURNING id"
-	err := r.db.QueryRowContext(ctx, query, role.Name).Scan(&id)
-	return id, err
+	err := r.db.QueryRowContext(ctx, insertRoleQuery, insertArgs(role)...).Scan(&id)
+	return id, nameTaken(err, role.Name)
+}
+
+// добавить новый элемент в коллекцию в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, insertRoleQuery, insertArgs(role)...).Scan(&id)
+	return id, nameTaken(err, role.Name)
+}
+
+// nameTaken ошибка уникального индекса по имени, если роль с таким именем без учёта регистра уже есть
+func nameTaken(err error, name string) error {
+	return database.MapUniqueViolation(err, fmt.Sprintf("role with name %s already exists", name))
+}
+
+func insertArgs(role Entity) []any {
+	tags := role.Tags
+	if tags == nil {
+		tags = pq.StringArray{}
+	}
+	return []any{role.Name, role.Description, role.OwnerId, role.RiskLevel, role.Requestable, tags,
+		role.ExternalSystem, role.ExternalId}
+}
+
+// проверка существования сотрудника, который назначается владельцем роли
+func (r *Repository) OwnerExists(ctx context.Context, employeeId int64) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM employee WHERE id = $1)"
+	err = r.db.GetContext(ctx, &isExists, query, employeeId)
+	return isExists, err
+}
+
+// найти элемент коллекции по его id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (role Entity, err error) {
+	query := "SELECT * FROM role WHERE id=$1"
+	err = tx.GetContext(ctx, &role, query, id)
+	return role, err
+}
+
+// поиск роли по имени без учёта регистра в рамках транзакции
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM role WHERE lower(name) = lower($1))"
+	err = tx.GetContext(ctx, &isExists, query, name)
+	return isExists, err
+}
+
+// изменить имя и поля каталога роли в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) error {
+	query := `UPDATE role SET name = $1, description = $2, owner_id = $3, risk_level = $4, requestable = $5, tags = $6,
+external_system = $7, external_id = $8, update_at = now() WHERE id = $9`
+	_, err := tx.ExecContext(ctx, query, append(insertArgs(role), role.Id)...)
+	return nameTaken(err, role.Name)
 }
 
 // найти элемент коллекции по его id
@@ -37,6 +97,42 @@ func (r *Repository) GetAll(ctx context.Context) (roles []Entity, err error) {
 	return roles, err
 }
 
+// найти роли каталога по фильтру
+func (r *Repository) FindAll(ctx context.Context, filter FilterRequest) (roles []Entity, err error) {
+	sb := strings.Builder{}
+	var args []interface{}
+
+	sb.WriteString("SELECT * FROM role WHERE 1=1")
+	if utf8.RuneCountInString(filter.TextFilter) >= 3 {
+		args = append(args, "%"+filter.TextFilter+"%")
+		sb.WriteString(fmt.Sprintf(" AND (name ILIKE $%d OR description ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.OwnerId != 0 {
+		args = append(args, filter.OwnerId)
+		sb.WriteString(fmt.Sprintf(" AND owner_id = $%d", len(args)))
+	}
+	if filter.RiskLevel != "" {
+		args = append(args, filter.RiskLevel)
+		sb.WriteString(fmt.Sprintf(" AND risk_level = $%d", len(args)))
+	}
+	if filter.Requestable != nil {
+		args = append(args, *filter.Requestable)
+		sb.WriteString(fmt.Sprintf(" AND requestable = $%d", len(args)))
+	}
+	if filter.Tag != "" {
+		args = append(args, filter.Tag)
+		sb.WriteString(fmt.Sprintf(" AND $%d = ANY(tags)", len(args)))
+	}
+	if filter.ExternalSystem != "" {
+		args = append(args, filter.ExternalSystem)
+		sb.WriteString(fmt.Sprintf(" AND external_system = $%d", len(args)))
+	}
+	sb.WriteString(" ORDER BY name, id")
+
+	err = r.db.SelectContext(ctx, &roles, sb.String(), args...)
+	return roles, err
+}
+
 // найти слайс элементов коллекции по слайсу их id
 func (r *Repository) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	if len(ids) == 0 {
@@ -68,3 +164,19 @@ func (r *Repository) DeleteByIds(ctx context.Context, ids []int64) error {
 	_, err := r.db.ExecContext(ctx, query, pq.Int64Array(ids))
 	return err
 }
+
+// Query выполняет запрос ролей (с фильтром по имени) и возвращает курсор по ним,
+// не загружая всю таблицу в память. Курсор нужно закрыть
+func (r *Repository) Query(ctx context.Context, textFilter string) (*sqlx.Rows, error) {
+	sb := strings.Builder{}
+	var args []interface{}
+
+	sb.WriteString("SELECT * FROM role WHERE 1=1")
+	if utf8.RuneCountInString(textFilter) >= 3 {
+		sb.WriteString(" AND name ILIKE $1")
+		args = append(args, "%"+textFilter+"%")
+	}
+	sb.WriteString(" ORDER BY id")
+
+	return r.db.QueryxContext(ctx, sb.String(), args...)
+}
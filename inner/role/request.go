@@ -1,11 +1,110 @@
 package role
 
+import (
+	"database/sql"
+	"io"
+	"slices"
+	"strings"
+)
+
 type CreateRequest struct {
-	Name string `json:"name" validate:"required,min=2,max=155"`
+	Name        string `json:"name" validate:"required,min=2,max=155"`
+	Description string `json:"description" validate:"max=1000"`
+	// сотрудник, ответственный за роль; 0 - владелец не назначен
+	OwnerId int64 `json:"owner_id" validate:"min=0"`
+	// уровень риска, по умолчанию low
+	RiskLevel   string   `json:"risk_level" validate:"omitempty,oneof=low medium high critical"`
+	Requestable bool     `json:"requestable"`
+	Tags        []string `json:"tags" validate:"max=20,dive,required,max=50"`
+	// система, в которой роль заведена, и идентификатор роли в ней
+	ExternalSystem string `json:"external_system" validate:"required_with=ExternalId,max=155"`
+	ExternalId     string `json:"external_id" validate:"max=255"`
 }
 
 func (r *CreateRequest) ToEntity() Entity {
-	return Entity{Name: r.Name}
+	riskLevel := r.RiskLevel
+	if riskLevel == "" {
+		riskLevel = RiskLow
+	}
+	return Entity{
+		Name:           r.Name,
+		Description:    strings.TrimSpace(r.Description),
+		OwnerId:        sql.NullInt64{Int64: r.OwnerId, Valid: r.OwnerId != 0},
+		RiskLevel:      riskLevel,
+		Requestable:    r.Requestable,
+		Tags:           normalizeTags(r.Tags),
+		ExternalSystem: sql.NullString{String: r.ExternalSystem, Valid: r.ExternalSystem != ""},
+		ExternalId:     sql.NullString{String: r.ExternalId, Valid: r.ExternalId != ""},
+	}
+}
+
+// normalizeTags приводит теги к нижнему регистру и убирает повторы
+func normalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// FilterRequest отбор ролей каталога; пустые поля не ограничивают выборку
+type FilterRequest struct {
+	// часть имени или описания роли, не короче 3 символов
+	TextFilter  string `json:"text_filter"`
+	OwnerId     int64  `json:"owner_id" validate:"min=0"`
+	RiskLevel   string `json:"risk_level" validate:"omitempty,oneof=low medium high critical"`
+	Requestable *bool  `json:"requestable"`
+	Tag         string `json:"tag" validate:"max=50"`
+	// система, в которой роль заведена
+	ExternalSystem string `json:"external_system" validate:"max=155"`
+}
+
+// UpdateRequest изменение роли; не переданные поля каталога остаются прежними
+type UpdateRequest struct {
+	Name        string  `json:"name" validate:"required,min=2,max=155"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+	// сотрудник, ответственный за роль; 0 - снять владельца
+	OwnerId *int64 `json:"owner_id" validate:"omitempty,min=0"`
+	// уровень риска, пустой - low
+	RiskLevel   *string `json:"risk_level" validate:"omitempty,oneof=low medium high critical"`
+	Requestable *bool   `json:"requestable"`
+	// пустой список снимает все теги
+	Tags []string `json:"tags" validate:"omitempty,max=20,dive,required,max=50"`
+	// система, в которой роль заведена, и идентификатор роли в ней; пустые значения снимают ссылку
+	ExternalSystem *string `json:"external_system" validate:"omitempty,max=155"`
+	ExternalId     *string `json:"external_id" validate:"omitempty,max=255"`
+}
+
+// apply переносит в роль переданные поля запроса
+func (r *UpdateRequest) apply(role *Entity) {
+	role.Name = r.Name
+	if r.Description != nil {
+		role.Description = strings.TrimSpace(*r.Description)
+	}
+	if r.OwnerId != nil {
+		role.OwnerId = sql.NullInt64{Int64: *r.OwnerId, Valid: *r.OwnerId != 0}
+	}
+	if r.RiskLevel != nil {
+		role.RiskLevel = *r.RiskLevel
+		if role.RiskLevel == "" {
+			role.RiskLevel = RiskLow
+		}
+	}
+	if r.Requestable != nil {
+		role.Requestable = *r.Requestable
+	}
+	if r.Tags != nil {
+		role.Tags = normalizeTags(r.Tags)
+	}
+	if r.ExternalSystem != nil {
+		role.ExternalSystem = sql.NullString{String: *r.ExternalSystem, Valid: *r.ExternalSystem != ""}
+	}
+	if r.ExternalId != nil {
+		role.ExternalId = sql.NullString{String: *r.ExternalId, Valid: *r.ExternalId != ""}
+	}
 }
 
 type FindByIdRequest struct {
@@ -19,3 +118,12 @@ type FindByIdsRequest struct {
 type DeleteByIdsRequest struct {
 	Ids []int64 `json:"ids" validate:"required,min=1,dive,gt=0"`
 }
+
+type ImportRequest struct {
+	// CSV файл со столбцами id (необязательный) и name
+	File io.Reader
+	// имя столбца в заголовке файла для каждого поля, если оно отличается от имени поля
+	Mapping map[string]string
+	// проверить файл и вернуть отчёт без сохранения изменений
+	DryRun bool
+}
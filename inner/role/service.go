@@ -2,17 +2,36 @@ package role
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"github.com/jmoiron/sqlx"
 	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// столбцы CSV файла выгрузки ролей
+var exportColumns = []string{"id", "name", "create_at", "update_at"}
+
 type Repo interface {
 	Create(ctx context.Context, role Entity) (int64, error)
 	FindById(ctx context.Context, id int64) (Entity, error)
 	GetAll(ctx context.Context) (role []Entity, err error)
+	FindAll(ctx context.Context, filter FilterRequest) ([]Entity, error)
+	OwnerExists(ctx context.Context, employeeId int64) (bool, error)
 	FindByIds(ctx context.Context, ids []int64) ([]Entity, error)
 	DeleteById(ctx context.Context, id int64) error
 	DeleteByIds(ctx context.Context, ids []int64) error
+	BeginTransaction() (*sqlx.Tx, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (int64, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) error
+	Query(ctx context.Context, textFilter string) (*sqlx.Rows, error)
 }
 
 type Validator interface {
@@ -37,15 +56,106 @@ func (s *Service) Create(ctx context.Context, request CreateRequest) (int64, err
 	err := s.validator.Validate(request)
 	if err != nil {
 		// возвращаем кастомную ошибку в случае, если запрос не прошёл валидацию
-		return 0, common.RequestValidatorError{Message: err.Error()}
+		return 0, common.NewRequestValidatorError(err)
+	}
+	if err = s.checkOwner(ctx, request.RiskLevel, request.OwnerId); err != nil {
+		return 0, err
 	}
 	id, err := s.repo.Create(ctx, request.ToEntity())
 	if err != nil {
-		return 0, fmt.Errorf("error failed to create employee with id %d: %w", id, err)
+		return 0, fmt.Errorf("error creating role with name %s: %w", request.Name, err)
 	}
 	return id, nil
 }
 
+// checkOwner проверяет владельца роли: за роли с высоким риском должен отвечать конкретный сотрудник,
+// и назначаемый владелец должен существовать
+func (s *Service) checkOwner(ctx context.Context, riskLevel string, ownerId int64) error {
+	if (riskLevel == RiskHigh || riskLevel == RiskCritical) && ownerId == 0 {
+		return common.RequestValidatorError{Message: fmt.Sprintf("role with %s risk level must have an owner", riskLevel)}
+	}
+	if ownerId == 0 {
+		return nil
+	}
+	isExist, err := s.repo.OwnerExists(ctx, ownerId)
+	if err != nil {
+		return fmt.Errorf("error finding employee with id %d: %w", ownerId, err)
+	}
+	if !isExist {
+		return common.NotFoundError{Message: fmt.Sprintf("owner employee with id %d not found", ownerId)}
+	}
+	return nil
+}
+
+// Update меняет имя и поля каталога роли с теми же проверками, что и Create. Имя должно остаться уникальным
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest) (response Response, err error) {
+	err = s.validator.Validate(request)
+	if err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("updating role panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating role: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating role: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("updating role: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	entity, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("role with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+	previousName := entity.Name
+	request.apply(&entity)
+	if entity.ExternalId.Valid && !entity.ExternalSystem.Valid {
+		return Response{}, common.RequestValidatorError{Message: "external_system is required with external_id"}
+	}
+	if err = s.checkOwner(ctx, entity.RiskLevel, entity.OwnerId.Int64); err != nil {
+		return Response{}, err
+	}
+
+	// имена уникальны без учёта регистра, поэтому смена регистра не занимает чужое имя
+	if !strings.EqualFold(previousName, request.Name) {
+		isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding role by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("role with name %s already exists", request.Name)}
+		}
+	}
+
+	if err = s.repo.UpdateTx(ctx, tx, entity); err != nil {
+		return Response{}, fmt.Errorf("error updating role with id %d: %w", id, err)
+	}
+	entity.UpdateAt = time.Now()
+	return entity.toResponse(), nil
+}
+
 func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
 	role, err := s.repo.FindById(ctx, id)
 	if err != nil {
@@ -69,6 +179,26 @@ func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
 	return response, nil
 }
 
+// Find возвращает роли каталога, подходящие под фильтр
+func (s *Service) Find(ctx context.Context, request FilterRequest) ([]Response, error) {
+	if err := s.validator.Validate(request); err != nil {
+		return []Response{}, common.NewRequestValidatorError(err)
+	}
+	request.TextFilter = strings.TrimSpace(request.TextFilter)
+	request.Tag = strings.ToLower(strings.TrimSpace(request.Tag))
+	roles, err := s.repo.FindAll(ctx, request)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error finding roles: %w", err)
+	}
+
+	response := make([]Response, 0, len(roles))
+	for _, item := range roles {
+		response = append(response, item.toResponse())
+	}
+
+	return response, nil
+}
+
 func (s *Service) FindByIds(ctx context.Context, ids []int64) ([]Response, error) {
 	roles, err := s.repo.FindByIds(ctx, ids)
 	if err != nil {
@@ -100,3 +230,160 @@ func (s *Service) DeleteByIds(ctx context.Context, ids []int64) error {
 
 	return nil
 }
+
+// Export выгружает роли (с фильтром по имени) в CSV. Запрос выполняется сразу, и его ошибка
+// возвращается до начала выгрузки, а строки читаются из базы данных по мере записи файла
+func (s *Service) Export(ctx context.Context, textFilter string) (csvutil.Export, error) {
+	rows, err := s.repo.Query(ctx, strings.TrimSpace(textFilter))
+	if err != nil {
+		return nil, fmt.Errorf("error exporting roles: %w", err)
+	}
+
+	return csvutil.NewExport(rows, exportColumns, func() ([]string, error) {
+		var role Entity
+		if err := rows.StructScan(&role); err != nil {
+			return nil, fmt.Errorf("error exporting roles: %w", err)
+		}
+		return []string{
+			strconv.FormatInt(role.Id, 10),
+			role.Name,
+			role.CreateAt.Format(time.RFC3339),
+			role.UpdateAt.Format(time.RFC3339),
+		}, nil
+	}), nil
+}
+
+// Import загружает роли из CSV файла в одной транзакции. Строка без id создаёт роль,
+// строка с id переименовывает существующую. Ошибочные строки попадают в отчёт и не мешают загрузке остальных.
+// В режиме dry run транзакция откатывается, а отчёт показывает, что было бы сделано
+func (s *Service) Import(ctx context.Context, request ImportRequest) (report csvutil.ImportReport, err error) {
+	reader, err := csvutil.NewReader(request.File, []string{"id", "name"}, []string{"name"}, request.Mapping)
+	if err != nil {
+		return csvutil.ImportReport{}, err
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return csvutil.ImportReport{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("importing roles panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("importing roles: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil || request.DryRun {
+			// если произошла ошибка или это пробный запуск, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("importing roles: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("importing roles: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	report = csvutil.ImportReport{DryRun: request.DryRun, Rows: []csvutil.RowResult{}}
+	seen := importSeen{names: map[string]int{}, ids: map[int64]int{}}
+	for {
+		row, errRead := reader.Read()
+		if errors.Is(errRead, io.EOF) {
+			break
+		}
+		if errRead != nil {
+			err = errRead
+			return csvutil.ImportReport{}, err
+		}
+
+		result, errRow := s.importRow(ctx, tx, row, seen)
+		if errRow != nil {
+			err = fmt.Errorf("error importing line %d: %w", row.Line, errRow)
+			return csvutil.ImportReport{}, err
+		}
+		report.Add(result)
+	}
+	return report, nil
+}
+
+// importSeen строки, в которых уже встречались имена и id ролей
+type importSeen struct {
+	names map[string]int
+	ids   map[int64]int
+}
+
+// importRow создаёт или переименовывает роль по строке CSV файла
+func (s *Service) importRow(ctx context.Context, tx *sqlx.Tx, row csvutil.Row, seen importSeen) (csvutil.RowResult, error) {
+	request := CreateRequest{Name: row.Values["name"]}
+	if err := s.validator.Validate(request); err != nil {
+		return csvutil.Invalid(row.Line, err), nil
+	}
+
+	var id int64
+	if value := row.Values["id"]; value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("invalid role id %s", value)), nil
+		}
+		id = parsed
+		if line, ok := seen.ids[id]; ok {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("role with id %d is duplicated in line %d", id, line)), nil
+		}
+		seen.ids[id] = row.Line
+	}
+	// имена сравниваются без учёта регистра, как в уникальном индексе
+	key := strings.ToLower(request.Name)
+	if line, ok := seen.names[key]; ok {
+		return csvutil.Rejected(row.Line, fmt.Sprintf("role with name %s is duplicated in line %d", request.Name, line)), nil
+	}
+	seen.names[key] = row.Line
+
+	// строка без id - новая роль
+	if id == 0 {
+		isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error finding role by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUnchanged,
+				Message: fmt.Sprintf("role with name %s already exists", request.Name)}, nil
+		}
+		newId, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error creating role with name %s: %w", request.Name, err)
+		}
+		return csvutil.RowResult{Line: row.Line, Status: csvutil.RowCreated, Id: newId}, nil
+	}
+
+	// строка с id - изменение существующей роли
+	role, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return csvutil.Rejected(row.Line, fmt.Sprintf("role with id %d not found", id)), nil
+	}
+	if err != nil {
+		return csvutil.RowResult{}, fmt.Errorf("error finding role with id %d: %w", id, err)
+	}
+	if role.Name == request.Name {
+		return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUnchanged, Id: id}, nil
+	}
+	if !strings.EqualFold(role.Name, request.Name) {
+		isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+		if err != nil {
+			return csvutil.RowResult{}, fmt.Errorf("error finding role by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return csvutil.Rejected(row.Line, fmt.Sprintf("role with name %s already exists", request.Name)), nil
+		}
+	}
+	role.Name = request.Name
+	if err := s.repo.UpdateTx(ctx, tx, role); err != nil {
+		return csvutil.RowResult{}, fmt.Errorf("error updating role with id %d: %w", id, err)
+	}
+	return csvutil.RowResult{Line: row.Line, Status: csvutil.RowUpdated, Id: id}, nil
+}
@@ -2,12 +2,21 @@ package role
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/brianvoe/gofakeit"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/csvutil"
 	"github.com/nihrom205/idm/inner/common/validator"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 )
@@ -31,6 +40,16 @@ func (m *MockRepo) GetAll(ctx context.Context) ([]Entity, error) {
 	return args.Get(0).([]Entity), args.Error(1)
 }
 
+func (m *MockRepo) FindAll(ctx context.Context, filter FilterRequest) ([]Entity, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]Entity), args.Error(1)
+}
+
+func (m *MockRepo) OwnerExists(ctx context.Context, employeeId int64) (bool, error) {
+	args := m.Called(employeeId)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockRepo) FindByIds(ctx context.Context, ids []int64) ([]Entity, error) {
 	args := m.Called(ids)
 	return args.Get(0).([]Entity), args.Error(1)
@@ -46,6 +65,37 @@ func (m *MockRepo) DeleteByIds(ctx context.Context, ids []int64) error {
 	return args.Error(0)
 }
 
+func (m *MockRepo) BeginTransaction() (*sqlx.Tx, error) {
+	args := m.Called()
+	return args.Get(0).(*sqlx.Tx), args.Error(1)
+}
+
+func (m *MockRepo) CreateTx(ctx context.Context, tx *sqlx.Tx, role Entity) (int64, error) {
+	args := m.Called(tx, role)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRepo) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error) {
+	args := m.Called(tx, id)
+	return args.Get(0).(Entity), args.Error(1)
+}
+
+func (m *MockRepo) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error) {
+	args := m.Called(tx, name)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepo) UpdateTx(ctx context.Context, tx *sqlx.Tx, role Entity) error {
+	args := m.Called(tx, role)
+	return args.Error(0)
+}
+
+func (m *MockRepo) Query(ctx context.Context, textFilter string) (*sqlx.Rows, error) {
+	args := m.Called(textFilter)
+	rows, _ := args.Get(0).(*sqlx.Rows)
+	return rows, args.Error(1)
+}
+
 func TestFindById(t *testing.T) {
 	a := assert.New(t)
 
@@ -114,6 +164,112 @@ func TestCreate(t *testing.T) {
 		a.NotNil(got)
 		a.True(repo.AssertNumberOfCalls(t, "Create", 0))
 	})
+
+	t.Run("should return AlreadyExistsError for name taken in another case", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO role")).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "role_name_lower_idx"})
+
+		_, err = srv.Create(context.Background(), CreateRequest{Name: "Admin"})
+
+		a.Equal(common.AlreadyExistsError{Message: "role with name Admin already exists"}, errors.Unwrap(err))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should create role with catalog metadata", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+		request := CreateRequest{Name: "R_4711", Description: " SAP finance approver ", OwnerId: 7, RiskLevel: RiskHigh,
+			Requestable: true, Tags: []string{"Finance", "sap", " finance "}, ExternalSystem: "sap", ExternalId: "R_4711"}
+
+		repo.On("OwnerExists", int64(7)).Return(true, nil)
+		repo.On("Create", Entity{Name: "R_4711", Description: "SAP finance approver",
+			OwnerId: sql.NullInt64{Int64: 7, Valid: true}, RiskLevel: RiskHigh, Requestable: true,
+			Tags:           pq.StringArray{"finance", "sap"},
+			ExternalSystem: sql.NullString{String: "sap", Valid: true},
+			ExternalId:     sql.NullString{String: "R_4711", Valid: true}}).Return(int64(1), nil)
+		id, err := srv.Create(context.Background(), request)
+
+		a.Nil(err)
+		a.Equal(int64(1), id)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("should require owner for critical role", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "root", RiskLevel: RiskCritical})
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.Equal("role with critical risk level must have an owner", validationErr.Message)
+		repo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should reject unknown risk level and external id without system", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "root", RiskLevel: "extreme", ExternalId: "R_1"})
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		repo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should return NotFoundError for unknown owner", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		repo.On("OwnerExists", int64(9)).Return(false, nil)
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "viewer", OwnerId: 9})
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		repo.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestFind(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should filter roles by catalog metadata", func(t *testing.T) {
+		db, dbMock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		requestable := true
+		now := time.Now()
+
+		dbMock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE 1=1 AND (name ILIKE $1 OR description ILIKE $1) "+
+			"AND owner_id = $2 AND risk_level = $3 AND requestable = $4 AND $5 = ANY(tags) AND external_system = $6 ORDER BY name, id")).
+			WithArgs("%finance%", int64(7), RiskHigh, true, "sap", "sap").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner_id", "risk_level", "requestable", "tags",
+				"external_system", "external_id", "create_at", "update_at"}).
+				AddRow(1, "R_4711", "finance approver", 7, RiskHigh, true, "{finance,sap}", "sap", "R_4711", now, now))
+
+		got, err := srv.Find(context.Background(), FilterRequest{TextFilter: " finance ", OwnerId: 7, RiskLevel: RiskHigh,
+			Requestable: &requestable, Tag: " SAP", ExternalSystem: "sap"})
+
+		a.Nil(err)
+		a.Equal([]Response{{Id: 1, Name: "R_4711", Description: "finance approver", OwnerId: 7, RiskLevel: RiskHigh,
+			Requestable: true, Tags: []string{"finance", "sap"}, ExternalSystem: "sap", ExternalId: "R_4711",
+			CreateAt: now, UpdateAt: now}}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject unknown risk level", func(t *testing.T) {
+		repo := &MockRepo{}
+		srv := NewService(repo, validator.NewValidator())
+
+		_, err := srv.Find(context.Background(), FilterRequest{RiskLevel: "extreme"})
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		repo.AssertNotCalled(t, "FindAll", mock.Anything)
+	})
 }
 
 func TestGetAll(t *testing.T) {
@@ -254,6 +410,295 @@ func TestDeleteByIds(t *testing.T) {
 	})
 }
 
+// updateArgs аргументы UPDATE роли, у которой из базы прочитаны только id и имя
+func updateArgs(name string, id int64) []driver.Value {
+	return []driver.Value{name, "", sql.NullInt64{}, "", false, pq.StringArray{}, sql.NullString{}, sql.NullString{}, id}
+}
+
+func TestUpdate(t *testing.T) {
+	a := assert.New(t)
+	findQuery := regexp.QuoteMeta("SELECT * FROM role WHERE id=$1")
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM role WHERE lower(name) = lower($1))")
+	updateQuery := regexp.QuoteMeta("UPDATE role SET name = $1, description = $2, owner_id = $3, risk_level = $4, requestable = $5, tags = $6,\nexternal_system = $7, external_id = $8, update_at = now() WHERE id = $9")
+	columns := []string{"id", "name", "create_at", "update_at"}
+
+	newService := func() (*Service, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		return NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator()), mock
+	}
+
+	t.Run("should rename role", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs(updateArgs("new name", 1)...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"})
+
+		a.Nil(err)
+		a.Equal("new name", got.Name)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError for taken name", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"})
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should change case of role name without name check", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "admin", time.Now(), time.Now()))
+		mock.ExpectExec(updateQuery).WithArgs(updateArgs("Admin", 1)...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "Admin"})
+
+		a.Nil(err)
+		a.Equal("Admin", got.Name)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError when name is taken concurrently", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, "old name", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("new name").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs(updateArgs("new name", 1)...).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "role_name_lower_idx"})
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"})
+
+		a.ErrorAs(err, &common.AlreadyExistsError{})
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown role", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "new name"})
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	metadataColumns := []string{"id", "name", "description", "owner_id", "risk_level", "requestable", "tags",
+		"external_system", "external_id"}
+	ownerQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM employee WHERE id = $1)")
+
+	t.Run("should update catalog metadata", func(t *testing.T) {
+		srv, mock := newService()
+		description := " grants database access "
+		ownerId := int64(7)
+		riskLevel := RiskCritical
+		requestable := true
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(metadataColumns).AddRow(1, "db", "", nil, RiskLow, false, "{}", nil, nil))
+		mock.ExpectQuery(ownerQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec(updateQuery).
+			WithArgs("db", "grants database access", sql.NullInt64{Int64: 7, Valid: true}, RiskCritical, true,
+				pq.StringArray{"prod"}, sql.NullString{}, sql.NullString{}, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "db", Description: &description,
+			OwnerId: &ownerId, RiskLevel: &riskLevel, Requestable: &requestable, Tags: []string{" Prod "}})
+
+		a.Nil(err)
+		a.Equal(int64(7), got.OwnerId)
+		a.Equal(RiskCritical, got.RiskLevel)
+		a.Equal([]string{"prod"}, got.Tags)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should keep metadata on rename", func(t *testing.T) {
+		srv, mock := newService()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(metadataColumns).
+				AddRow(1, "db", "grants database access", 7, RiskHigh, true, "{prod}", "sap", "R_4711"))
+		mock.ExpectQuery(ownerQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec(updateQuery).
+			WithArgs("DB", "grants database access", sql.NullInt64{Int64: 7, Valid: true}, RiskHigh, true,
+				pq.StringArray{"prod"}, sql.NullString{String: "sap", Valid: true}, sql.NullString{String: "R_4711", Valid: true}, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "DB"})
+
+		a.Nil(err)
+		a.Equal("R_4711", got.ExternalId)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should require owner when owner of high risk role is removed", func(t *testing.T) {
+		srv, mock := newService()
+		ownerId := int64(0)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(metadataColumns).AddRow(1, "db", "", 7, RiskHigh, false, "{}", nil, nil))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "db", OwnerId: &ownerId})
+
+		a.ErrorAs(err, &common.RequestValidatorError{})
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject external id without system and unknown risk level", func(t *testing.T) {
+		srv, mock := newService()
+		externalId := "R_4711"
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(metadataColumns).AddRow(1, "db", "", nil, RiskLow, false, "{}", nil, nil))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "db", ExternalId: &externalId})
+		a.ErrorAs(err, &common.RequestValidatorError{})
+
+		riskLevel := "extreme"
+		_, err = srv.Update(context.Background(), 1, UpdateRequest{Name: "db", RiskLevel: &riskLevel})
+		a.ErrorAs(err, &common.RequestValidatorError{})
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown owner", func(t *testing.T) {
+		srv, mock := newService()
+		ownerId := int64(9)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(metadataColumns).AddRow(1, "db", "", nil, RiskLow, false, "{}", nil, nil))
+		mock.ExpectQuery(ownerQuery).WithArgs(int64(9)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "db", OwnerId: &ownerId})
+
+		a.ErrorAs(err, &common.NotFoundError{})
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestImport(t *testing.T) {
+	a := assert.New(t)
+	existsQuery := regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM role WHERE lower(name) = lower($1))")
+	insertQuery := regexp.QuoteMeta("INSERT INTO role (name, description, owner_id, risk_level, requestable, tags, external_system, external_id)")
+	findQuery := regexp.QuoteMeta("SELECT * FROM role WHERE id=$1")
+	updateQuery := regexp.QuoteMeta("UPDATE role SET name = $1, description = $2, owner_id = $3, risk_level = $4, requestable = $5, tags = $6,\nexternal_system = $7, external_id = $8, update_at = now() WHERE id = $9")
+
+	t.Run("should create, update and reject rows", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+		file := "id,name\n" +
+			",admin\n" +
+			"2,manager\n" +
+			"3,user\n" + // роль user уже есть
+			",a\n" +
+			",Admin\n" // имена сравниваются без учёта регистра
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("admin", "", nil, RiskLow, false, "{}", nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(2)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "create_at", "update_at"}).
+				AddRow(2, "guest", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("manager").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec(updateQuery).WithArgs(updateArgs("manager", 2)...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(findQuery).WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "create_at", "update_at"}).
+				AddRow(3, "viewer", time.Now(), time.Now()))
+		mock.ExpectQuery(existsQuery).WithArgs("user").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectCommit()
+
+		report, err := srv.Import(context.Background(), ImportRequest{File: strings.NewReader(file)})
+
+		a.Nil(err)
+		a.Equal(1, report.Created)
+		a.Equal(1, report.Updated)
+		a.Equal(3, report.Rejected)
+		a.Equal(csvutil.Rejected(4, "role with name user already exists"), report.Rows[2])
+		a.Equal("$.name", report.Rows[3].Errors[0].JsonPath)
+		a.Equal(csvutil.Rejected(6, "role with name Admin is duplicated in line 2"), report.Rows[4])
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should roll back transaction in dry run", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		srv := NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), validator.NewValidator())
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		report, err := srv.Import(context.Background(), ImportRequest{
+			File:   strings.NewReader("name\nadmin\n"),
+			DryRun: true,
+		})
+
+		a.Nil(err)
+		a.Equal(1, report.Unchanged)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestExport(t *testing.T) {
+	a := assert.New(t)
+
+	db, mock, err := sqlmock.New()
+	a.NoError(err)
+	srv := NewService(NewRoleRepository(sqlx.NewDb(db, "sqlmock")), nil)
+	createAt := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM role WHERE 1=1 ORDER BY id")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "create_at", "update_at"}).AddRow(1, "admin", createAt, createAt))
+
+	export, err := srv.Export(context.Background(), "")
+	a.Nil(err)
+	defer export.Close()
+	var buf strings.Builder
+	err = export.Write(&buf)
+
+	a.Nil(err)
+	a.Equal("id,name,create_at,update_at\n1,admin,2026-10-19T09:00:00Z,2026-10-19T09:00:00Z\n", buf.String())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
 func getEntity() Entity {
 	roles := []string{"admin", "user", "manager", "guest"}
 	return Entity{
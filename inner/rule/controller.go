@@ -0,0 +1,319 @@
+package rule
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server      *web.Server
+	ruleService Svc
+	logger      *common.Logger
+}
+
+// интерфейс сервиса rule.Service
+type Svc interface {
+	GetAll(ctx context.Context) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Create(ctx context.Context, request CreateRequest, actor string) (Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error)
+	Delete(ctx context.Context, id int64, actor string) error
+	Recalculate(ctx context.Context, actor string) (RecalculateResponse, error)
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:      server,
+		ruleService: svc,
+		logger:      logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/role-rules", c.GetAllRules)
+	c.server.GroupApiV1.Post("/role-rules", c.CreateRule)
+	// статический маршрут регистрируем раньше маршрутов с :id
+	c.server.GroupApiV1.Post("/role-rules/recalculate", c.RecalculateRules)
+	c.server.GroupApiV1.Get("/role-rules/:id", c.GetRule)
+	c.server.GroupApiV1.Put("/role-rules/:id", c.UpdateRule)
+	c.server.GroupApiV1.Delete("/role-rules/:id", c.DeleteRule)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/role-rules"
+// @Description Get all role rules.
+// @Summary get all role rules
+// @ID get-all-role-rules
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[[]rule.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules [get]
+func (c *Controller) GetAllRules(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод GetAll сервиса rule.Service
+	response, err := c.ruleService.GetAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all role rules", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all role rules", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/role-rules/:id"
+// @Description Get role rule by id.
+// @Summary get role rule
+// @ID get-role-rule
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id rule"
+// @Success 200 {object} common.Response[rule.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules/{id} [get]
+func (c *Controller) GetRule(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) &&
+		!slices.Contains(claims.RealmAccess.Roles, web.IdmUser) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid rule id")
+	}
+
+	// вызываем метод FindById сервиса rule.Service
+	response, err := c.ruleService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get role rule", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get role rule", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/role-rules"
+// @Description Create role rule: employees matching org_unit and job_title get the roles of the rule.
+// @Description Roles are granted to matching active employees immediately.
+// @Summary create role rule
+// @ID create-role-rule
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body rule.CreateRequest true "rule"
+// @Success 200 {object} common.Response[rule.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules [post]
+func (c *Controller) CreateRule(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create role rule: received request", zap.Any("request", request))
+
+	// вызываем метод Create сервиса rule.Service
+	response, err := c.ruleService.Create(ctx.Context(), request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create role rule", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create role rule", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/role-rules/:id"
+// @Description Replace condition and roles of role rule. Roles granted by rules are recalculated.
+// @Summary update role rule
+// @ID update-role-rule
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id rule"
+// @Param request body rule.UpdateRequest true "rule"
+// @Success 200 {object} common.Response[rule.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules/{id} [put]
+func (c *Controller) UpdateRule(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid rule id")
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "update role rule: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод Update сервиса rule.Service
+	response, err := c.ruleService.Update(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role rule", zap.Int64("id", id), zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update role rule", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/role-rules/:id"
+// @Description Delete role rule. Roles granted only by this rule are revoked, manually assigned roles are kept.
+// @Summary delete role rule
+// @ID delete-role-rule
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id rule"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules/{id} [delete]
+func (c *Controller) DeleteRule(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid rule id")
+	}
+
+	// вызываем метод Delete сервиса rule.Service
+	if err := c.ruleService.Delete(ctx.Context(), id, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete role rule", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete role rule", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/role-rules/recalculate"
+// @Description Recalculate roles granted by rules for all employees: grant missing roles and revoke roles
+// @Description no rule grants anymore. Manually assigned roles are kept.
+// @Summary recalculate role rules
+// @ID recalculate-role-rules
+// @Tags role-rule
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[rule.RecalculateResponse]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /role-rules/recalculate [post]
+func (c *Controller) RecalculateRules(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод Recalculate сервиса rule.Service
+	response, err := c.ruleService.Recalculate(ctx.Context(), claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "recalculate role rules", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "recalculate role rules", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package rule
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса rule.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, actor string) (Response, error) {
+	args := svc.Called(request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Delete(ctx context.Context, id int64, actor string) error {
+	args := svc.Called(id, actor)
+	return args.Error(0)
+}
+
+func (svc *MockService) Recalculate(ctx context.Context, actor string) (RecalculateResponse, error) {
+	args := svc.Called(actor)
+	return args.Get(0).(RecalculateResponse), args.Error(1)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_CreateRule(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create rule with actor from token", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		request := CreateRequest{Name: "developers", OrgUnit: "IT", RoleIds: []int64{2}}
+		svc.On("Create", request, "admin").Return(Response{Id: 1, Name: "developers", OrgUnit: "IT", RoleIds: []int64{2}}, nil)
+
+		body := strings.NewReader(`{"name": "developers", "org_unit": "IT", "role_ids": [2]}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/role-rules", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(int64(1), got.Data.Id)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/role-rules", strings.NewReader(`{"name": "developers"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_GetRule(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmUser)
+	svc.On("FindById", int64(5)).Return(Response{}, common.NotFoundError{Message: "rule with id 5 not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/role-rules/5", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
+
+func TestController_RecalculateRules(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("Recalculate", "admin").Return(RecalculateResponse{Granted: 3, Revoked: 1}, nil)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/role-rules/recalculate", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	var got common.Response[RecalculateResponse]
+	data, _ := io.ReadAll(resp.Body)
+	a.Nil(json.Unmarshal(data, &got))
+	a.Equal(RecalculateResponse{Granted: 3, Revoked: 1}, got.Data)
+	svc.AssertExpectations(t)
+}
@@ -0,0 +1,51 @@
+package rule
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+// Entity правило назначения ролей: сотрудники отдела org_unit с должностью job_title получают роли role_ids.
+// Пустой атрибут правила подходит любому значению атрибута сотрудника
+type Entity struct {
+	Id       int64          `db:"id"`
+	Name     string         `db:"name"`
+	OrgUnit  sql.NullString `db:"org_unit"`
+	JobTitle sql.NullString `db:"job_title"`
+	RoleIds  pq.Int64Array  `db:"role_ids"`
+	CreateAt time.Time      `db:"create_at"`
+	UpdateAt time.Time      `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	roleIds := []int64(e.RoleIds)
+	if roleIds == nil {
+		roleIds = []int64{}
+	}
+	return Response{
+		Id:       e.Id,
+		Name:     e.Name,
+		OrgUnit:  e.OrgUnit.String,
+		JobTitle: e.JobTitle.String,
+		RoleIds:  roleIds,
+		CreateAt: e.CreateAt,
+		UpdateAt: e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id       int64     `json:"id"`
+	Name     string    `json:"name"`
+	OrgUnit  string    `json:"org_unit,omitempty"`
+	JobTitle string    `json:"job_title,omitempty"`
+	RoleIds  []int64   `json:"role_ids"`
+	CreateAt time.Time `json:"create_at"`
+	UpdateAt time.Time `json:"update_at"`
+}
+
+// RecalculateResponse сколько назначений по правилам добавлено и отозвано
+type RecalculateResponse struct {
+	Granted int64 `json:"granted"`
+	Revoked int64 `json:"revoked"`
+}
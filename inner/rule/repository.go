@@ -0,0 +1,128 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// правила вместе с их ролями
+const selectRules = `SELECT r.id, r.name, r.org_unit, r.job_title, r.create_at, r.update_at,
+COALESCE(array_agg(rr.role_id ORDER BY rr.role_id) FILTER (WHERE rr.role_id IS NOT NULL), '{}') AS role_ids
+FROM role_rule r LEFT JOIN role_rule_role rr ON rr.rule_id = r.id`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewRuleRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти все правила
+func (r *Repository) GetAll(ctx context.Context) (rules []Entity, err error) {
+	query := selectRules + " GROUP BY r.id ORDER BY r.id"
+	err = r.db.SelectContext(ctx, &rules, query)
+	return rules, err
+}
+
+// найти правило по id
+func (r *Repository) FindById(ctx context.Context, id int64) (rule Entity, err error) {
+	query := selectRules + " WHERE r.id = $1 GROUP BY r.id"
+	err = r.db.GetContext(ctx, &rule, query, id)
+	return rule, err
+}
+
+// найти правило по id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (rule Entity, err error) {
+	query := selectRules + " WHERE r.id = $1 GROUP BY r.id"
+	err = tx.GetContext(ctx, &rule, query, id)
+	return rule, err
+}
+
+// поиск правила по имени в рамках транзакции
+func (r *Repository) FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM role_rule WHERE name = $1)"
+	err = tx.GetContext(ctx, &isExists, query, name)
+	return isExists, err
+}
+
+// добавить правило в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, rule Entity) (id int64, err error) {
+	query := "INSERT INTO role_rule (name, org_unit, job_title) VALUES ($1, $2, $3) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, rule.Name, rule.OrgUnit, rule.JobTitle)
+	return id, err
+}
+
+// изменить правило в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, rule Entity) error {
+	query := "UPDATE role_rule SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4"
+	_, err := tx.ExecContext(ctx, query, rule.Name, rule.OrgUnit, rule.JobTitle, rule.Id)
+	return err
+}
+
+// удалить правило в рамках транзакции; false, если правила нет
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM role_rule WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// заменить роли правила в рамках транзакции
+func (r *Repository) ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, ruleId int64, roleIds []int64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM role_rule_role WHERE rule_id = $1", ruleId); err != nil {
+		return err
+	}
+	query := `INSERT INTO role_rule_role (rule_id, role_id)
+SELECT $1, role_id FROM unnest($2::bigint[]) AS role_id
+ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, ruleId, pq.Int64Array(roleIds))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: "role not found"}
+	}
+	return err
+}
+
+// RevokeUnmatchedTx отзывает назначенные правилами роли, которые больше не даёт ни одно правило, и возвращает
+// сотрудников отозванных назначений. employeeId ограничивает пересчёт одним сотрудником, NULL - все сотрудники
+func (r *Repository) RevokeUnmatchedTx(ctx context.Context, tx *sqlx.Tx, employeeId sql.NullInt64) (employeeIds []int64, err error) {
+	query := `DELETE FROM employee_role er USING employee e
+WHERE er.employee_id = e.id AND er.source = 'rule' AND ($1::bigint IS NULL OR e.id = $1)
+AND NOT EXISTS (
+    SELECT 1 FROM role_rule r JOIN role_rule_role rr ON rr.rule_id = r.id
+    WHERE rr.role_id = er.role_id
+    AND (r.org_unit IS NULL OR r.org_unit = e.org_unit)
+    AND (r.job_title IS NULL OR r.job_title = e.job_title))
+RETURNING er.employee_id`
+	err = tx.SelectContext(ctx, &employeeIds, query, employeeId)
+	return employeeIds, err
+}
+
+// GrantMatchedTx назначает активным сотрудникам роли подходящих правил и возвращает сотрудников новых назначений.
+// Роль, уже назначенная вручную, остаётся ручной. employeeId ограничивает пересчёт одним сотрудником, NULL - все сотрудники
+func (r *Repository) GrantMatchedTx(ctx context.Context, tx *sqlx.Tx, employeeId sql.NullInt64) (employeeIds []int64, err error) {
+	query := `INSERT INTO employee_role (employee_id, role_id, source)
+SELECT DISTINCT e.id, rr.role_id, 'rule' FROM employee e
+JOIN role_rule r ON (r.org_unit IS NULL OR r.org_unit = e.org_unit) AND (r.job_title IS NULL OR r.job_title = e.job_title)
+JOIN role_rule_role rr ON rr.rule_id = r.id
+WHERE e.status = 'active' AND ($1::bigint IS NULL OR e.id = $1)
+ON CONFLICT (employee_id, role_id) DO NOTHING
+RETURNING employee_id`
+	err = tx.SelectContext(ctx, &employeeIds, query, employeeId)
+	return employeeIds, err
+}
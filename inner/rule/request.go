@@ -0,0 +1,35 @@
+package rule
+
+import (
+	"database/sql"
+	"strings"
+)
+
+type CreateRequest struct {
+	Name     string  `json:"name" validate:"required,min=2,max=155"`
+	OrgUnit  string  `json:"org_unit" validate:"max=155"`
+	JobTitle string  `json:"job_title" validate:"max=155"`
+	RoleIds  []int64 `json:"role_ids" validate:"required,min=1,dive,gt=0"`
+}
+
+func (r *CreateRequest) ToEntity() Entity {
+	return Entity{
+		Name:     r.Name,
+		OrgUnit:  nullString(r.OrgUnit),
+		JobTitle: nullString(r.JobTitle),
+	}
+}
+
+// UpdateRequest заменяет условие и роли правила целиком
+type UpdateRequest struct {
+	Name     string  `json:"name" validate:"required,min=2,max=155"`
+	OrgUnit  string  `json:"org_unit" validate:"max=155"`
+	JobTitle string  `json:"job_title" validate:"max=155"`
+	RoleIds  []int64 `json:"role_ids" validate:"required,min=1,dive,gt=0"`
+}
+
+// nullString пустая строка сохраняется как NULL: атрибут правила подходит любому значению
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,370 @@
+package rule
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"slices"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	GetAll(ctx context.Context) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindByNameTx(ctx context.Context, tx *sqlx.Tx, name string) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, rule Entity) (int64, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, rule Entity) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, ruleId int64, roleIds []int64) error
+	RevokeUnmatchedTx(ctx context.Context, tx *sqlx.Tx, employeeId sql.NullInt64) ([]int64, error)
+	GrantMatchedTx(ctx context.Context, tx *sqlx.Tx, employeeId sql.NullInt64) ([]int64, error)
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+// ChangeListener получает сотрудников, которым правила назначили или у которых отозвали роли.
+// Вызывается в транзакции пересчёта, например для провижининга в целевые системы
+type ChangeListener interface {
+	RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	validator Validator
+	listener  ChangeListener
+}
+
+func NewService(repo Repo, audit AuditRepo, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		validator: validator,
+	}
+}
+
+// SetChangeListener подключает получателя изменений назначений по правилам
+func (s *Service) SetChangeListener(listener ChangeListener) {
+	s.listener = listener
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
+	rules, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all rules: %w", err)
+	}
+	response := make([]Response, 0, len(rules))
+	for _, item := range rules {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	rule, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("rule with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding rule with id %d: %w", id, err)
+	}
+	return rule.toResponse(), nil
+}
+
+// Create добавляет правило и сразу назначает его роли подходящим сотрудникам
+func (s *Service) Create(ctx context.Context, request CreateRequest, actor string) (response Response, err error) {
+	if err = s.validate(request, request.OrgUnit, request.JobTitle); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating rule panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating rule: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding rule by name: %s, %w", request.Name, err)
+	}
+	if isExist {
+		err = common.AlreadyExistsError{Message: fmt.Sprintf("rule with name %s already exists", request.Name)}
+		return Response{}, err
+	}
+	id, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating rule: %w", err)
+	}
+	response, err = s.saveRolesTx(ctx, tx, id, request.RoleIds, actor, "role_rule.created")
+	return response, err
+}
+
+// Update заменяет условие и роли правила и пересчитывает назначения по правилам
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (response Response, err error) {
+	if err = s.validate(request, request.OrgUnit, request.JobTitle); err != nil {
+		return Response{}, err
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("updating rule panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("updating rule: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	rule, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.NotFoundError{Message: fmt.Sprintf("rule with id %d not found", id)}
+		return Response{}, err
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding rule with id %d: %w", id, err)
+	}
+	if rule.Name != request.Name {
+		isExist, err := s.repo.FindByNameTx(ctx, tx, request.Name)
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding rule by name: %s, %w", request.Name, err)
+		}
+		if isExist {
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("rule with name %s already exists", request.Name)}
+		}
+	}
+
+	rule.Name = request.Name
+	rule.OrgUnit = nullString(request.OrgUnit)
+	rule.JobTitle = nullString(request.JobTitle)
+	if err = s.repo.UpdateTx(ctx, tx, rule); err != nil {
+		return Response{}, fmt.Errorf("error updating rule with id %d: %w", id, err)
+	}
+	response, err = s.saveRolesTx(ctx, tx, id, request.RoleIds, actor, "role_rule.updated")
+	return response, err
+}
+
+// Delete удаляет правило и отзывает роли, которые давало только оно
+func (s *Service) Delete(ctx context.Context, id int64, actor string) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("deleting rule panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting rule: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("deleting rule: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	deleted, err := s.repo.DeleteTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting rule with id %d: %w", id, err)
+	}
+	if !deleted {
+		err = common.NotFoundError{Message: fmt.Sprintf("rule with id %d not found", id)}
+		return err
+	}
+	result, err := s.recalculateTx(ctx, tx, sql.NullInt64{})
+	if err != nil {
+		return err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "role_rule.deleted",
+		EntityType: "role_rule",
+		EntityId:   id,
+		Details:    result,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// Recalculate приводит назначения по правилам всех сотрудников в соответствие с правилами
+func (s *Service) Recalculate(ctx context.Context, actor string) (response RecalculateResponse, err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return RecalculateResponse{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("recalculating rules panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("recalculating rules: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("recalculating rules: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("recalculating rules: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	response, err = s.recalculateTx(ctx, tx, sql.NullInt64{})
+	if err != nil {
+		return RecalculateResponse{}, err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "role_rule.recalculated",
+		EntityType: "role_rule",
+		Details:    response,
+	})
+	if err != nil {
+		return RecalculateResponse{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return response, nil
+}
+
+// ApplyTx пересчитывает назначения по правилам одного сотрудника в транзакции вызывающего,
+// вызывается при создании сотрудника и изменении его отдела или должности
+func (s *Service) ApplyTx(ctx context.Context, tx *sqlx.Tx, employeeId int64) error {
+	_, err := s.recalculateTx(ctx, tx, sql.NullInt64{Int64: employeeId, Valid: true})
+	return err
+}
+
+// Hook пересчитывает роли сотрудника по правилам, когда он становится активным или меняет атрибуты.
+// Регистрируется в lifecycle.Service
+func (s *Service) Hook(ctx context.Context, tx *sqlx.Tx, event lifecycle.Event) error {
+	if event.Employee.Status != lifecycle.StatusActive {
+		return nil
+	}
+	return s.ApplyTx(ctx, tx, event.Employee.Id)
+}
+
+// validate проверяет запрос: правило без отдела и должности подходило бы всем сотрудникам
+func (s *Service) validate(request any, orgUnit string, jobTitle string) error {
+	if err := s.validator.Validate(request); err != nil {
+		return common.NewRequestValidatorError(err)
+	}
+	if !nullString(orgUnit).Valid && !nullString(jobTitle).Valid {
+		return common.RequestValidatorError{Message: "rule must have org_unit or job_title"}
+	}
+	return nil
+}
+
+// saveRolesTx сохраняет роли правила, пересчитывает назначения и пишет аудит
+func (s *Service) saveRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64, actor string, action string) (Response, error) {
+	if err := s.repo.ReplaceRolesTx(ctx, tx, id, roleIds); err != nil {
+		return Response{}, fmt.Errorf("error saving roles of rule %d: %w", id, err)
+	}
+	result, err := s.recalculateTx(ctx, tx, sql.NullInt64{})
+	if err != nil {
+		return Response{}, err
+	}
+	rule, err := s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding rule with id %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: "role_rule",
+		EntityId:   id,
+		Details:    map[string]any{"rule": rule.toResponse(), "granted": result.Granted, "revoked": result.Revoked},
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return rule.toResponse(), nil
+}
+
+// recalculateTx отзывает роли, которые правила больше не дают, и назначает недостающие
+func (s *Service) recalculateTx(ctx context.Context, tx *sqlx.Tx, employeeId sql.NullInt64) (RecalculateResponse, error) {
+	revoked, err := s.repo.RevokeUnmatchedTx(ctx, tx, employeeId)
+	if err != nil {
+		return RecalculateResponse{}, fmt.Errorf("error revoking roles by rules: %w", err)
+	}
+	granted, err := s.repo.GrantMatchedTx(ctx, tx, employeeId)
+	if err != nil {
+		return RecalculateResponse{}, fmt.Errorf("error granting roles by rules: %w", err)
+	}
+	if s.listener != nil && len(revoked)+len(granted) > 0 {
+		employeeIds := slices.Compact(slices.Sorted(slices.Values(append(revoked, granted...))))
+		if err = s.listener.RolesChangedTx(ctx, tx, employeeIds); err != nil {
+			return RecalculateResponse{}, fmt.Errorf("error notifying about roles changed by rules: %w", err)
+		}
+	}
+	return RecalculateResponse{Granted: int64(len(granted)), Revoked: int64(len(revoked))}, nil
+}
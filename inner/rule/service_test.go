@@ -0,0 +1,276 @@
+package rule
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	existsQuery      = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM role_rule WHERE name = $1)")
+	insertQuery      = regexp.QuoteMeta("INSERT INTO role_rule (name, org_unit, job_title) VALUES ($1, $2, $3) RETURNING id")
+	updateQuery      = regexp.QuoteMeta("UPDATE role_rule SET name = $1, org_unit = $2, job_title = $3, update_at = now() WHERE id = $4")
+	deleteQuery      = regexp.QuoteMeta("DELETE FROM role_rule WHERE id = $1")
+	deleteRolesQuery = regexp.QuoteMeta("DELETE FROM role_rule_role WHERE rule_id = $1")
+	insertRolesQuery = regexp.QuoteMeta("INSERT INTO role_rule_role (rule_id, role_id)")
+	findQuery        = regexp.QuoteMeta(selectRules + " WHERE r.id = $1 GROUP BY r.id")
+	revokeQuery      = regexp.QuoteMeta("DELETE FROM employee_role er USING employee e")
+	grantQuery       = regexp.QuoteMeta("INSERT INTO employee_role (employee_id, role_id, source)")
+	auditQuery       = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	ruleColumns      = []string{"id", "name", "org_unit", "job_title", "create_at", "update_at", "role_ids"}
+)
+
+func employeeIdRows(ids ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"employee_id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	return rows
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	return NewService(NewRuleRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), validator.NewValidator()), mock
+}
+
+func TestService_Create(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create rule and grant roles to matching employees", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("developers").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs("developers", "IT", "Developer").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+		mock.ExpectExec(deleteRolesQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(insertRolesQuery).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows())
+		mock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1, 2, 3, 4, 5, 6))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(ruleColumns).AddRow(1, "developers", "IT", "Developer", now, now, "{2,3}"))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "role_rule.created", "role_rule", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Create(context.Background(),
+			CreateRequest{Name: "developers", OrgUnit: "IT", JobTitle: "Developer", RoleIds: []int64{2, 3}}, "admin")
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, Name: "developers", OrgUnit: "IT", JobTitle: "Developer", RoleIds: []int64{2, 3},
+			CreateAt: now, UpdateAt: now}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should reject rule without condition", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "everyone", RoleIds: []int64{1}}, "admin")
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.Equal("rule must have org_unit or job_title", validationErr.Message)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError for taken name", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs("developers").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.Create(context.Background(), CreateRequest{Name: "developers", OrgUnit: "IT", RoleIds: []int64{1}}, "admin")
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Update(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should revoke roles when rule stops matching", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(ruleColumns).AddRow(1, "developers", "IT", nil, now, now, "{2}"))
+		mock.ExpectExec(updateQuery).WithArgs("developers", "IT", "Developer", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(deleteRolesQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(insertRolesQuery).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1, 2, 3, 4))
+		mock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows())
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(ruleColumns).AddRow(1, "developers", "IT", "Developer", now, now, "{2}"))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "role_rule.updated", "role_rule", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Update(context.Background(), 1,
+			UpdateRequest{Name: "developers", OrgUnit: "IT", JobTitle: "Developer", RoleIds: []int64{2}}, "admin")
+
+		a.Nil(err)
+		a.Equal("Developer", got.JobTitle)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown rule", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).WillReturnRows(sqlmock.NewRows(ruleColumns))
+		mock.ExpectRollback()
+
+		_, err := srv.Update(context.Background(), 1, UpdateRequest{Name: "developers", OrgUnit: "IT", RoleIds: []int64{2}}, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should delete rule and revoke its roles", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1, 2, 3))
+		mock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows())
+		mock.ExpectExec(auditQuery).WithArgs("admin", "role_rule.deleted", "role_rule", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return NotFoundError for unknown rule", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+type MockChangeListener struct {
+	mock.Mock
+}
+
+func (m *MockChangeListener) RolesChangedTx(ctx context.Context, tx *sqlx.Tx, employeeIds []int64) error {
+	args := m.Called(employeeIds)
+	return args.Error(0)
+}
+
+func TestService_Recalculate(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should revoke and grant roles by rules", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1))
+		dbMock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1, 2))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "role_rule.recalculated", "role_rule", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Recalculate(context.Background(), "admin")
+
+		a.Nil(err)
+		a.Equal(RecalculateResponse{Granted: 2, Revoked: 1}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("should notify listener about every changed employee once", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{3, 5, 8}).Return(nil)
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows(8, 3))
+		dbMock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows(5, 3))
+		dbMock.ExpectExec(auditQuery).WithArgs("admin", "role_rule.recalculated", "role_rule", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		dbMock.ExpectCommit()
+
+		got, err := srv.Recalculate(context.Background(), "admin")
+
+		a.Nil(err)
+		a.Equal(RecalculateResponse{Granted: 2, Revoked: 2}, got)
+		a.NoError(dbMock.ExpectationsWereMet())
+		listener.AssertExpectations(t)
+	})
+
+	t.Run("should roll back recalculation when listener fails", func(t *testing.T) {
+		srv, dbMock := newTestService(t)
+		listener := &MockChangeListener{}
+		srv.SetChangeListener(listener)
+		listener.On("RolesChangedTx", []int64{1}).Return(errors.New("database error"))
+		dbMock.ExpectBegin()
+		dbMock.ExpectQuery(revokeQuery).WithArgs(nil).WillReturnRows(employeeIdRows(1))
+		dbMock.ExpectQuery(grantQuery).WithArgs(nil).WillReturnRows(employeeIdRows())
+		dbMock.ExpectRollback()
+
+		_, err := srv.Recalculate(context.Background(), "admin")
+
+		a.Error(err)
+		a.NoError(dbMock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Hook(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should apply rules to activated employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(revokeQuery).WithArgs(int64(7)).WillReturnRows(employeeIdRows())
+		mock.ExpectQuery(grantQuery).WithArgs(int64(7)).WillReturnRows(employeeIdRows(7))
+		tx, err := srv.repo.BeginTransaction()
+		a.NoError(err)
+
+		err = srv.Hook(context.Background(), tx, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 7, Status: lifecycle.StatusActive},
+			FromStatus: lifecycle.StatusPreHire,
+		})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should skip suspended employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		err := srv.Hook(context.Background(), nil, lifecycle.Event{
+			Employee:   lifecycle.Employee{Id: 7, Status: lifecycle.StatusSuspended},
+			FromStatus: lifecycle.StatusActive,
+		})
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Job фоновая задача: обрабатывает всё, что наступило к моменту now, и возвращает число обработанных записей
+type Job func(ctx context.Context, now time.Time) (int, error)
+
+type entry struct {
+	name     string
+	interval time.Duration
+	job      Job
+}
+
+// Scheduler запускает фоновые задачи, каждую со своим интервалом
+type Scheduler struct {
+	entries []entry
+	logger  *common.Logger
+}
+
+func NewScheduler(logger *common.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Add регистрирует задачу name, которая выполняется раз в interval
+func (s *Scheduler) Add(name string, interval time.Duration, job Job) {
+	s.entries = append(s.entries, entry{name: name, interval: interval, job: job})
+}
+
+// Run выполняет задачи по расписанию, пока не будет отменён ctx; возвращается после остановки всех задач
+func (s *Scheduler) Run(ctx context.Context) {
+	wg := &sync.WaitGroup{}
+	for _, e := range s.entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.run(ctx, e)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		s.tick(ctx, e)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context, e entry) {
+	processed, err := e.job(ctx, time.Now())
+	if err != nil {
+		s.logger.ErrorCtx(ctx, "scheduler: job failed", zap.String("job", e.name),
+			zap.Int("processed", processed), zap.Error(err))
+		return
+	}
+	if processed > 0 {
+		s.logger.DebugCtx(ctx, "scheduler: job done", zap.String("job", e.name), zap.Int("processed", processed))
+	}
+}
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_Run(t *testing.T) {
+	a := assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+
+	t.Run("should run every job with its own interval until ctx is cancelled", func(t *testing.T) {
+		var fast, slow atomic.Int32
+		jobs := NewScheduler(logger)
+		jobs.Add("fast", time.Millisecond, func(ctx context.Context, now time.Time) (int, error) {
+			fast.Add(1)
+			return 1, nil
+		})
+		jobs.Add("slow", time.Hour, func(ctx context.Context, now time.Time) (int, error) {
+			slow.Add(1)
+			return 0, nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			jobs.Run(ctx)
+			close(done)
+		}()
+		a.Eventually(func() bool { return fast.Load() >= 3 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+
+		// медленная задача выполняется сразу при запуске и больше не успевает
+		a.Equal(int32(1), slow.Load())
+	})
+
+	t.Run("should keep running job after error", func(t *testing.T) {
+		var calls atomic.Int32
+		jobs := NewScheduler(logger)
+		jobs.Add("failing", time.Millisecond, func(ctx context.Context, now time.Time) (int, error) {
+			calls.Add(1)
+			return 0, errors.New("database error")
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go jobs.Run(ctx)
+
+		a.Eventually(func() bool { return calls.Load() >= 2 }, time.Second, time.Millisecond)
+	})
+}
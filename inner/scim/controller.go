@@ -0,0 +1,352 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type Controller struct {
+	server      *web.Server
+	scimService Svc
+	logger      *common.Logger
+}
+
+// интерфейс сервиса scim.Service
+type Svc interface {
+	ListUsers(ctx context.Context, query ListQuery) (ListResponse[User], error)
+	GetUser(ctx context.Context, id string) (User, error)
+	CreateUser(ctx context.Context, request UserRequest) (User, error)
+	ReplaceUser(ctx context.Context, id string, request UserRequest) (User, error)
+	PatchUser(ctx context.Context, id string, request PatchRequest) (User, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context, query ListQuery) (ListResponse[Group], error)
+	GetGroup(ctx context.Context, id string) (Group, error)
+	CreateGroup(ctx context.Context, request GroupRequest) (Group, error)
+	ReplaceGroup(ctx context.Context, id string, request GroupRequest) (Group, error)
+	PatchGroup(ctx context.Context, id string, request PatchRequest) (Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:      server,
+		scimService: svc,
+		logger:      logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	// ошибки переводит в формат SCIM ErrorMiddleware, который регистрируется до аутентификации
+	c.server.GroupScim.Use(c.requireAdmin)
+
+	c.server.GroupScim.Get("/ServiceProviderConfig", c.GetServiceProviderConfig)
+	c.server.GroupScim.Get("/ResourceTypes", c.GetResourceTypes)
+	c.server.GroupScim.Get("/Schemas", c.GetSchemas)
+
+	c.server.GroupScim.Get("/Users", c.ListUsers)
+	c.server.GroupScim.Post("/Users", c.CreateUser)
+	c.server.GroupScim.Get("/Users/:id", c.GetUser)
+	c.server.GroupScim.Put("/Users/:id", c.ReplaceUser)
+	c.server.GroupScim.Patch("/Users/:id", c.PatchUser)
+	c.server.GroupScim.Delete("/Users/:id", c.DeleteUser)
+
+	c.server.GroupScim.Get("/Groups", c.ListGroups)
+	c.server.GroupScim.Post("/Groups", c.CreateGroup)
+	c.server.GroupScim.Get("/Groups/:id", c.GetGroup)
+	c.server.GroupScim.Put("/Groups/:id", c.ReplaceGroup)
+	c.server.GroupScim.Patch("/Groups/:id", c.PatchGroup)
+	c.server.GroupScim.Delete("/Groups/:id", c.DeleteGroup)
+}
+
+// ErrorMiddleware переводит ошибки SCIM API в ответ SCIM (RFC 7644, раздел 3.12), а не в
+// application/problem+json. Регистрируется на группе /scim первым, чтобы в формате SCIM
+// отдавались и ошибки аутентификации и ограничения частоты запросов
+func ErrorMiddleware(logger *common.Logger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		err := ctx.Next()
+		if err == nil {
+			return nil
+		}
+		scimErr := toError(err, common.RequestLanguage(ctx))
+		if scimErr.StatusCode() >= http.StatusInternalServerError {
+			logger.ErrorCtx(ctx.Context(), "scim request failed", zap.String("method", ctx.Method()),
+				zap.String("path", strings.Clone(ctx.Path())), zap.Error(err))
+		}
+		return send(ctx, scimErr.StatusCode(), scimErr)
+	}
+}
+
+// toError сопоставляет ошибку сервисов с ошибкой SCIM
+func toError(err error, lang string) *Error {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return scimErr
+	}
+
+	problem := common.ProblemFromError(err, lang)
+	switch problem.Status {
+	case http.StatusUnprocessableEntity:
+		messages := make([]string, 0, len(problem.Errors))
+		for _, field := range problem.Errors {
+			messages = append(messages, field.Message)
+		}
+		if len(messages) == 0 {
+			return newError(http.StatusBadRequest, ErrInvalidValue, "%s", problem.Detail)
+		}
+		return newError(http.StatusBadRequest, ErrInvalidValue, "%s", strings.Join(messages, "; "))
+	case http.StatusConflict:
+		// недопустимая смена статуса - не нарушение уникальности
+		if problem.Code != common.CodeAlreadyExists {
+			return newError(http.StatusConflict, "", "%s", problem.Detail)
+		}
+		return newError(http.StatusConflict, ErrUniqueness, "%s", problem.Detail)
+	}
+	return newError(problem.Status, "", "%s", problem.Detail)
+}
+
+// requireAdmin пропускает только клиентов с ролью администратора: SCIM API меняет любые учётные записи
+func (c *Controller) requireAdmin(ctx *fiber.Ctx) error {
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return newError(http.StatusUnauthorized, "", "%s", err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return newError(http.StatusForbidden, "", "Permission denied")
+	}
+	return ctx.Next()
+}
+
+func (c *Controller) GetServiceProviderConfig(ctx *fiber.Ctx) error {
+	return send(ctx, fiber.StatusOK, serviceProviderConfig())
+}
+
+func (c *Controller) GetResourceTypes(ctx *fiber.Ctx) error {
+	resources := resourceTypes()
+	return send(ctx, fiber.StatusOK, page(resources, ListQuery{Count: len(resources)}))
+}
+
+func (c *Controller) GetSchemas(ctx *fiber.Ctx) error {
+	resources := schemas()
+	return send(ctx, fiber.StatusOK, page(resources, ListQuery{Count: len(resources)}))
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/scim/v2/Users"
+func (c *Controller) ListUsers(ctx *fiber.Ctx) error {
+	query := listQuery(ctx)
+	c.logger.DebugCtx(ctx.Context(), "scim list users", zap.Any("query", query))
+
+	response, err := c.scimService.ListUsers(ctx.Context(), query)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim list users", zap.Any("query", query), zap.Error(err))
+		return err
+	}
+	for i := range response.Resources {
+		response.Resources[i] = absoluteUser(ctx, response.Resources[i])
+	}
+	return send(ctx, fiber.StatusOK, response)
+}
+
+func (c *Controller) GetUser(ctx *fiber.Ctx) error {
+	user, err := c.scimService.GetUser(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteUser(ctx, user))
+}
+
+func (c *Controller) CreateUser(ctx *fiber.Ctx) error {
+	var request UserRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim create user", zap.Any("request", request))
+
+	user, err := c.scimService.CreateUser(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim create user", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	user = absoluteUser(ctx, user)
+	ctx.Set(fiber.HeaderLocation, user.Meta.Location)
+	return send(ctx, fiber.StatusCreated, user)
+}
+
+func (c *Controller) ReplaceUser(ctx *fiber.Ctx) error {
+	var request UserRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim replace user", zap.String("id", ctx.Params("id")), zap.Any("request", request))
+
+	user, err := c.scimService.ReplaceUser(ctx.Context(), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim replace user", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteUser(ctx, user))
+}
+
+func (c *Controller) PatchUser(ctx *fiber.Ctx) error {
+	var request PatchRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim patch user", zap.String("id", ctx.Params("id")), zap.Any("request", request))
+
+	user, err := c.scimService.PatchUser(ctx.Context(), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim patch user", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteUser(ctx, user))
+}
+
+func (c *Controller) DeleteUser(ctx *fiber.Ctx) error {
+	if err := c.scimService.DeleteUser(ctx.Context(), ctx.Params("id")); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim delete user", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/scim/v2/Groups"
+func (c *Controller) ListGroups(ctx *fiber.Ctx) error {
+	query := listQuery(ctx)
+	c.logger.DebugCtx(ctx.Context(), "scim list groups", zap.Any("query", query))
+
+	response, err := c.scimService.ListGroups(ctx.Context(), query)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim list groups", zap.Any("query", query), zap.Error(err))
+		return err
+	}
+	for i := range response.Resources {
+		response.Resources[i] = absoluteGroup(ctx, response.Resources[i])
+	}
+	return send(ctx, fiber.StatusOK, response)
+}
+
+func (c *Controller) GetGroup(ctx *fiber.Ctx) error {
+	group, err := c.scimService.GetGroup(ctx.Context(), ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteGroup(ctx, group))
+}
+
+func (c *Controller) CreateGroup(ctx *fiber.Ctx) error {
+	var request GroupRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim create group", zap.Any("request", request))
+
+	group, err := c.scimService.CreateGroup(ctx.Context(), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim create group", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	group = absoluteGroup(ctx, group)
+	ctx.Set(fiber.HeaderLocation, group.Meta.Location)
+	return send(ctx, fiber.StatusCreated, group)
+}
+
+func (c *Controller) ReplaceGroup(ctx *fiber.Ctx) error {
+	var request GroupRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim replace group", zap.String("id", ctx.Params("id")), zap.Any("request", request))
+
+	group, err := c.scimService.ReplaceGroup(ctx.Context(), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim replace group", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteGroup(ctx, group))
+}
+
+func (c *Controller) PatchGroup(ctx *fiber.Ctx) error {
+	var request PatchRequest
+	if err := parseBody(ctx, &request); err != nil {
+		return err
+	}
+	c.logger.DebugCtx(ctx.Context(), "scim patch group", zap.String("id", ctx.Params("id")), zap.Any("request", request))
+
+	group, err := c.scimService.PatchGroup(ctx.Context(), ctx.Params("id"), request)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim patch group", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return send(ctx, fiber.StatusOK, absoluteGroup(ctx, group))
+}
+
+func (c *Controller) DeleteGroup(ctx *fiber.Ctx) error {
+	if err := c.scimService.DeleteGroup(ctx.Context(), ctx.Params("id")); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "scim delete group", zap.String("id", ctx.Params("id")), zap.Error(err))
+		return err
+	}
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// send отправляет ответ с типом содержимого application/scim+json
+func send(ctx *fiber.Ctx, status int, body any) error {
+	ctx.Status(status)
+	if err := ctx.JSON(body); err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, MIMEApplicationScimJSON)
+	return nil
+}
+
+// parseBody разбирает JSON тело запроса. BodyParser не подходит: клиенты SCIM
+// присылают тип содержимого application/scim+json
+func parseBody(ctx *fiber.Ctx, out any) error {
+	if err := json.Unmarshal(ctx.Body(), out); err != nil {
+		return newError(http.StatusBadRequest, ErrInvalidSyntax, "invalid request body: %s", err.Error())
+	}
+	return nil
+}
+
+func listQuery(ctx *fiber.Ctx) ListQuery {
+	return ListQuery{
+		Filter:     ctx.Query("filter"),
+		StartIndex: ctx.QueryInt("startIndex", 1),
+		Count:      ctx.QueryInt("count", defaultCount),
+	}
+}
+
+// absoluteUser дополняет ссылки пользователя адресом сервера
+func absoluteUser(ctx *fiber.Ctx, user User) User {
+	user.Meta.Location = ctx.BaseURL() + user.Meta.Location
+	user.Groups = absoluteRefs(ctx, user.Groups)
+	return user
+}
+
+// absoluteGroup дополняет ссылки группы адресом сервера
+func absoluteGroup(ctx *fiber.Ctx, group Group) Group {
+	group.Meta.Location = ctx.BaseURL() + group.Meta.Location
+	group.Members = absoluteRefs(ctx, group.Members)
+	return group
+}
+
+func absoluteRefs(ctx *fiber.Ctx, refs []MemberRef) []MemberRef {
+	if refs == nil {
+		return nil
+	}
+	result := make([]MemberRef, len(refs))
+	for i, ref := range refs {
+		if ref.Ref != "" {
+			ref.Ref = ctx.BaseURL() + ref.Ref
+		}
+		result[i] = ref
+	}
+	return result
+}
@@ -0,0 +1,330 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса scim.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) ListUsers(ctx context.Context, query ListQuery) (ListResponse[User], error) {
+	args := svc.Called(query)
+	return args.Get(0).(ListResponse[User]), args.Error(1)
+}
+
+func (svc *MockService) GetUser(ctx context.Context, id string) (User, error) {
+	args := svc.Called(id)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) CreateUser(ctx context.Context, request UserRequest) (User, error) {
+	args := svc.Called(request)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) ReplaceUser(ctx context.Context, id string, request UserRequest) (User, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) PatchUser(ctx context.Context, id string, request PatchRequest) (User, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(User), args.Error(1)
+}
+
+func (svc *MockService) DeleteUser(ctx context.Context, id string) error {
+	args := svc.Called(id)
+	return args.Error(0)
+}
+
+func (svc *MockService) ListGroups(ctx context.Context, query ListQuery) (ListResponse[Group], error) {
+	args := svc.Called(query)
+	return args.Get(0).(ListResponse[Group]), args.Error(1)
+}
+
+func (svc *MockService) GetGroup(ctx context.Context, id string) (Group, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) CreateGroup(ctx context.Context, request GroupRequest) (Group, error) {
+	args := svc.Called(request)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) ReplaceGroup(ctx context.Context, id string, request GroupRequest) (Group, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) PatchGroup(ctx context.Context, id string, request PatchRequest) (Group, error) {
+	args := svc.Called(id, request)
+	return args.Get(0).(Group), args.Error(1)
+}
+
+func (svc *MockService) DeleteGroup(ctx context.Context, id string) error {
+	args := svc.Called(id)
+	return args.Error(0)
+}
+
+// newTestServer создаёт сервер с контроллером SCIM и клиентом с ролями roles
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	logger := webtest.Logger()
+	server := web.NewServer(logger)
+	server.GroupScim.Use(ErrorMiddleware(logger), webtest.Auth(webtest.Claims("", roles...)))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func readError(a *assert.Assertions, resp *http.Response) Error {
+	a.Equal(MIMEApplicationScimJSON, resp.Header.Get(fiber.HeaderContentType))
+	var body Error
+	data, err := io.ReadAll(resp.Body)
+	a.Nil(err)
+	a.Nil(json.Unmarshal(data, &body))
+	a.Equal([]string{SchemaError}, body.Schemas)
+	return body
+}
+
+func TestController_Users(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should list users with absolute locations", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		query := ListQuery{Filter: `userName eq "john"`, StartIndex: 1, Count: 10}
+		svc.On("ListUsers", query).Return(page([]User{{
+			Id:     "1",
+			Groups: []MemberRef{{Value: "10", Ref: "/scim/v2/Groups/10"}},
+			Meta:   Meta{Location: "/scim/v2/Users/1"},
+		}}, query), nil)
+
+		req := httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users?filter=userName%20eq%20%22john%22&count=10", nil)
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal(MIMEApplicationScimJSON, resp.Header.Get(fiber.HeaderContentType))
+		var body ListResponse[User]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &body))
+		a.Equal(1, body.TotalResults)
+		a.Equal("http://example.com/scim/v2/Users/1", body.Resources[0].Meta.Location)
+		a.Equal("http://example.com/scim/v2/Groups/10", body.Resources[0].Groups[0].Ref)
+	})
+
+	t.Run("should create user from scim+json body", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("CreateUser", UserRequest{Schemas: []string{SchemaUser}, UserName: "john"}).
+			Return(User{Id: "1", UserName: "john", Meta: Meta{Location: "/scim/v2/Users/1"}}, nil)
+
+		body := strings.NewReader(`{"schemas": ["` + SchemaUser + `"], "userName": "john"}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/scim/v2/Users", body)
+		req.Header.Set(fiber.HeaderContentType, MIMEApplicationScimJSON)
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusCreated, resp.StatusCode)
+		a.Equal("http://example.com/scim/v2/Users/1", resp.Header.Get(fiber.HeaderLocation))
+	})
+
+	t.Run("should return uniqueness error for existing user", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("CreateUser", mock.Anything).Return(User{}, common.AlreadyExistsError{Message: "employee already exists"})
+
+		req := httptest.NewRequest(fiber.MethodPost, "/scim/v2/Users", strings.NewReader(`{"userName": "john"}`))
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+		body := readError(a, resp)
+		a.Equal("409", body.Status)
+		a.Equal(ErrUniqueness, body.ScimType)
+	})
+
+	t.Run("should return conflict without scimType for invalid status change", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("PatchUser", "1", mock.Anything).
+			Return(User{}, common.ConflictError{Message: "employee 1 cannot change status from terminated to suspended"})
+
+		body := strings.NewReader(`{"schemas": ["` + SchemaPatchOp + `"], "Operations": [{"op": "replace", "path": "active", "value": false}]}`)
+		req := httptest.NewRequest(fiber.MethodPatch, "/scim/v2/Users/1", body)
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+		a.Empty(readError(a, resp).ScimType)
+	})
+
+	t.Run("should return invalidValue error for failed validation", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		validationErr := common.NewRequestValidatorError(common.ValidationErrors{{
+			Field:   "name",
+			Message: "name must be at least 2 characters in length",
+		}})
+		svc.On("CreateUser", mock.Anything).Return(User{}, validationErr)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/scim/v2/Users", strings.NewReader(`{"userName": "j"}`))
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		body := readError(a, resp)
+		a.Equal(ErrInvalidValue, body.ScimType)
+		a.Equal("name must be at least 2 characters in length", body.Detail)
+	})
+
+	t.Run("should return invalidSyntax error for malformed body", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+
+		req := httptest.NewRequest(fiber.MethodPatch, "/scim/v2/Users/1", strings.NewReader(`{"Operations": [`))
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(ErrInvalidSyntax, readError(a, resp).ScimType)
+		svc.AssertNotCalled(t, "PatchUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("GetUser", "5").Return(User{}, notFound("User", "5"))
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users/5", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNotFound, resp.StatusCode)
+		a.Equal("User 5 not found", readError(a, resp).Detail)
+	})
+
+	t.Run("should delete user", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		svc.On("DeleteUser", "1").Return(nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/scim/v2/Users/1", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusNoContent, resp.StatusCode)
+	})
+
+	t.Run("should return 403 in scim format for non-admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmUser)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		a.Equal("403", readError(a, resp).Status)
+		svc.AssertNotCalled(t, "ListUsers", mock.Anything)
+	})
+
+	t.Run("should return authentication error in scim format", func(t *testing.T) {
+		svc := &MockService{}
+		logger := webtest.Logger()
+		server := web.NewServer(logger)
+		// так ошибку возвращает AuthMiddleware при отсутствии токена
+		server.GroupScim.Use(ErrorMiddleware(logger), func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusUnauthorized, "missing or malformed JWT")
+		})
+		NewController(server, svc, logger).RegisterRoutes()
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusUnauthorized, resp.StatusCode)
+		body := readError(a, resp)
+		a.Equal("401", body.Status)
+		a.Equal("missing or malformed JWT", body.Detail)
+		svc.AssertNotCalled(t, "ListUsers", mock.Anything)
+	})
+}
+
+func TestController_Groups(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should patch group", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		request := PatchRequest{
+			Schemas:    []string{SchemaPatchOp},
+			Operations: []PatchOperation{{Op: "remove", Path: `members[value eq "7"]`}},
+		}
+		svc.On("PatchGroup", "1", request).Return(Group{
+			Id:      "1",
+			Members: []MemberRef{{Value: "8", Ref: "/scim/v2/Users/8"}},
+			Meta:    Meta{Location: "/scim/v2/Groups/1"},
+		}, nil)
+
+		body := `{"schemas": ["` + SchemaPatchOp + `"], "Operations": [{"op": "remove", "path": "members[value eq \"7\"]"}]}`
+		req := httptest.NewRequest(fiber.MethodPatch, "/scim/v2/Groups/1", strings.NewReader(body))
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var group Group
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &group))
+		a.Equal("http://example.com/scim/v2/Users/8", group.Members[0].Ref)
+	})
+
+	t.Run("should list groups with default paging", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		query := ListQuery{StartIndex: 1, Count: defaultCount}
+		svc.On("ListGroups", query).Return(page([]Group{}, query), nil)
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Groups", nil))
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		svc.AssertExpectations(t)
+	})
+}
+
+func TestController_Discovery(t *testing.T) {
+	var a = assert.New(t)
+	server := newTestServer(&MockService{}, web.IdmAdmin)
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/ServiceProviderConfig", nil))
+	a.Nil(err)
+	a.Equal(http.StatusOK, resp.StatusCode)
+	var config ServiceProviderConfig
+	data, _ := io.ReadAll(resp.Body)
+	a.Nil(json.Unmarshal(data, &config))
+	a.True(config.Patch.Supported)
+	a.True(config.Filter.Supported)
+	a.Equal(maxCount, config.Filter.MaxResults)
+	a.False(config.Bulk.Supported)
+
+	for _, path := range []string{"/scim/v2/ResourceTypes", "/scim/v2/Schemas"} {
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode, path)
+		var list ListResponse[map[string]any]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &list))
+		a.Equal(2, list.TotalResults, path)
+	}
+}
@@ -0,0 +1,157 @@
+package scim
+
+// ServiceProviderConfig возможности SCIM сервера (RFC 7643, раздел 5)
+type ServiceProviderConfig struct {
+	Schemas               []string        `json:"schemas"`
+	DocumentationUri      string          `json:"documentationUri,omitempty"`
+	Patch                 Supported       `json:"patch"`
+	Bulk                  BulkSupported   `json:"bulk"`
+	Filter                FilterSupported `json:"filter"`
+	ChangePassword        Supported       `json:"changePassword"`
+	Sort                  Supported       `json:"sort"`
+	Etag                  Supported       `json:"etag"`
+	AuthenticationSchemes []AuthScheme    `json:"authenticationSchemes"`
+	Meta                  map[string]any  `json:"meta"`
+}
+
+type Supported struct {
+	Supported bool `json:"supported"`
+}
+
+type BulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type FilterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type AuthScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// ResourceType описание типа ресурса (RFC 7643, раздел 6)
+type ResourceType struct {
+	Schemas     []string       `json:"schemas"`
+	Id          string         `json:"id"`
+	Name        string         `json:"name"`
+	Endpoint    string         `json:"endpoint"`
+	Description string         `json:"description"`
+	Schema      string         `json:"schema"`
+	Meta        map[string]any `json:"meta"`
+}
+
+// Schema описание схемы ресурса (RFC 7643, раздел 7)
+type Schema struct {
+	Schemas     []string          `json:"schemas"`
+	Id          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  []SchemaAttribute `json:"attributes"`
+	Meta        map[string]any    `json:"meta"`
+}
+
+type SchemaAttribute struct {
+	Name          string            `json:"name"`
+	Type          string            `json:"type"`
+	MultiValued   bool              `json:"multiValued"`
+	Required      bool              `json:"required"`
+	CaseExact     bool              `json:"caseExact"`
+	Mutability    string            `json:"mutability"`
+	Returned      string            `json:"returned"`
+	Uniqueness    string            `json:"uniqueness"`
+	SubAttributes []SchemaAttribute `json:"subAttributes,omitempty"`
+}
+
+func serviceProviderConfig() ServiceProviderConfig {
+	return ServiceProviderConfig{
+		Schemas: []string{SchemaServiceProviderConfig},
+		Patch:   Supported{Supported: true},
+		Bulk:    BulkSupported{},
+		Filter:  FilterSupported{Supported: true, MaxResults: maxCount},
+		AuthenticationSchemes: []AuthScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication with a Keycloak access token",
+			Primary:     true,
+		}},
+		Meta: map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     basePath + "/ServiceProviderConfig",
+		},
+	}
+}
+
+func resourceTypes() []ResourceType {
+	return []ResourceType{
+		{
+			Schemas:     []string{SchemaResourceType},
+			Id:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "Employee",
+			Schema:      SchemaUser,
+			Meta:        map[string]any{"resourceType": "ResourceType", "location": basePath + "/ResourceTypes/User"},
+		},
+		{
+			Schemas:     []string{SchemaResourceType},
+			Id:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Role, members are employees with the role assigned",
+			Schema:      SchemaGroup,
+			Meta:        map[string]any{"resourceType": "ResourceType", "location": basePath + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+func schemas() []Schema {
+	reference := func(name string, mutability string) SchemaAttribute {
+		return SchemaAttribute{
+			Name:        name,
+			Type:        "complex",
+			MultiValued: true,
+			Mutability:  mutability,
+			Returned:    "default",
+			Uniqueness:  "none",
+			SubAttributes: []SchemaAttribute{
+				{Name: "value", Type: "string", Mutability: "immutable", Returned: "default", Uniqueness: "none"},
+				{Name: "display", Type: "string", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+				{Name: "$ref", Type: "reference", Mutability: "immutable", Returned: "default", Uniqueness: "none"},
+			},
+		}
+	}
+	return []Schema{
+		{
+			Schemas:     []string{SchemaSchema},
+			Id:          SchemaUser,
+			Name:        "User",
+			Description: "Employee",
+			Attributes: []SchemaAttribute{
+				{Name: "externalId", Type: "string", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+				{Name: "userName", Type: "string", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "server"},
+				{Name: "displayName", Type: "string", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+				{Name: "active", Type: "boolean", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				reference("groups", "readOnly"),
+			},
+			Meta: map[string]any{"resourceType": "Schema", "location": basePath + "/Schemas/" + SchemaUser},
+		},
+		{
+			Schemas:     []string{SchemaSchema},
+			Id:          SchemaGroup,
+			Name:        "Group",
+			Description: "Role",
+			Attributes: []SchemaAttribute{
+				{Name: "displayName", Type: "string", Required: true, Mutability: "readWrite", Returned: "default", Uniqueness: "server"},
+				reference("members", "readWrite"),
+			},
+			Meta: map[string]any{"resourceType": "Schema", "location": basePath + "/Schemas/" + SchemaGroup},
+		},
+	}
+}
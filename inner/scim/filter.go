@@ -0,0 +1,534 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter условие отбора ресурсов (RFC 7644, раздел 3.4.2.2).
+// Ресурс передаётся в виде JSON объекта, разобранного в map
+type Filter interface {
+	Match(resource map[string]any) bool
+}
+
+// ParseFilter разбирает выражение фильтра, например
+// userName eq "john" and (displayName co "doe" or not (members pr))
+func ParseFilter(expression string) (Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, invalidFilter("unexpected %q", p.peek().value)
+	}
+	return filter, nil
+}
+
+func invalidFilter(format string, args ...any) *Error {
+	return newError(http.StatusBadRequest, ErrInvalidFilter, "invalid filter: "+format, args...)
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// tokenize разбивает выражение на слова, строки в кавычках и скобки
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")"})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, value: "["})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, value: "]"})
+			i++
+		case r == '"':
+			// строка в формате JSON, с экранированием через \
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, invalidFilter("unterminated string")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(string(runes[i:j+1])), &value); err != nil {
+				return nil, invalidFilter("invalid string %s", string(runes[i:j+1]))
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value})
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(runes) && !unicode.IsSpace(runes[j]) && !strings.ContainsRune("()[]\"", runes[j]); j++ {
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[i:j])})
+			i = j
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, invalidFilter("empty expression")
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// keyword проверяет, что следующий токен - слово word (без учёта регистра), и пропускает его
+func (p *filterParser) keyword(word string) bool {
+	if !p.done() && p.peek().kind == tokenWord && strings.EqualFold(p.peek().value, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) expect(kind tokenKind, value string) error {
+	if p.done() || p.peek().kind != kind {
+		return invalidFilter("expected %q", value)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.done() {
+		return nil, invalidFilter("unexpected end of expression")
+	}
+	if p.keyword("not") {
+		filter, err := p.parseGroup()
+		if err != nil {
+			return nil, err
+		}
+		return notFilter{filter: filter}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		return p.parseGroup()
+	}
+	return p.parseAttribute()
+}
+
+// parseGroup разбирает выражение в круглых скобках
+func (p *filterParser) parseGroup() (Filter, error) {
+	if err := p.expect(tokenLParen, "("); err != nil {
+		return nil, err
+	}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenRParen, ")"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseAttribute разбирает сравнение атрибута: attr op value, attr pr или attr[filter]
+func (p *filterParser) parseAttribute() (Filter, error) {
+	t := p.peek()
+	if t.kind != tokenWord {
+		return nil, invalidFilter("expected attribute, got %q", t.value)
+	}
+	p.pos++
+	path := parseAttrPath(t.value)
+
+	if !p.done() && p.peek().kind == tokenLBracket {
+		p.pos++
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenRBracket, "]"); err != nil {
+			return nil, err
+		}
+		return valuePathFilter{path: path, filter: filter}, nil
+	}
+
+	if p.done() || p.peek().kind != tokenWord {
+		return nil, invalidFilter("expected operator after %s", t.value)
+	}
+	op := strings.ToLower(p.peek().value)
+	p.pos++
+	if op == "pr" {
+		return presentFilter{path: path}, nil
+	}
+	if !isCompareOperator(op) {
+		return nil, invalidFilter("unknown operator %s", op)
+	}
+
+	if p.done() {
+		return nil, invalidFilter("expected value after %s", op)
+	}
+	value, err := parseValue(p.peek())
+	if err != nil {
+		return nil, err
+	}
+	p.pos++
+	return compareFilter{path: path, op: op, value: value}, nil
+}
+
+func isCompareOperator(op string) bool {
+	switch op {
+	case "eq", "ne", "co", "sw", "ew", "gt", "ge", "lt", "le":
+		return true
+	}
+	return false
+}
+
+// parseValue разбирает значение для сравнения: строку, число, true, false или null
+func parseValue(t token) (any, error) {
+	switch t.kind {
+	case tokenString:
+		return t.value, nil
+	case tokenWord:
+		switch strings.ToLower(t.value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		if number, err := strconv.ParseFloat(t.value, 64); err == nil {
+			return number, nil
+		}
+	}
+	return nil, invalidFilter("invalid value %q", t.value)
+}
+
+// attrPath путь к атрибуту: имя атрибута и, возможно, имя вложенного атрибута
+type attrPath struct {
+	attr    string
+	subAttr string
+}
+
+// parseAttrPath разбирает путь к атрибуту; префикс схемы (urn:...:User:userName) отбрасывается
+func parseAttrPath(value string) attrPath {
+	if strings.HasPrefix(strings.ToLower(value), "urn:") {
+		if i := strings.LastIndex(value, ":"); i >= 0 {
+			value = value[i+1:]
+		}
+	}
+	attr, subAttr, _ := strings.Cut(value, ".")
+	return attrPath{attr: attr, subAttr: subAttr}
+}
+
+// values возвращает значения атрибута ресурса. Для многозначных атрибутов возвращаются
+// все значения, у объектов без указания вложенного атрибута берётся value
+func (p attrPath) values(resource map[string]any) []any {
+	value, ok := lookup(resource, p.attr)
+	if !ok || value == nil {
+		return nil
+	}
+	items, multi := value.([]any)
+	if !multi {
+		items = []any{value}
+	}
+
+	var result []any
+	for _, item := range items {
+		object, isObject := item.(map[string]any)
+		switch {
+		case p.subAttr != "":
+			if isObject {
+				if sub, ok := lookup(object, p.subAttr); ok && sub != nil {
+					result = append(result, sub)
+				}
+			}
+		case isObject && multi:
+			if sub, ok := lookup(object, "value"); ok && sub != nil {
+				result = append(result, sub)
+			}
+		default:
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// lookup ищет атрибут без учёта регистра имени (RFC 7643, раздел 2.1)
+func lookup(object map[string]any, name string) (any, bool) {
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+type andFilter struct{ left, right Filter }
+
+func (f andFilter) Match(resource map[string]any) bool {
+	return f.left.Match(resource) && f.right.Match(resource)
+}
+
+type orFilter struct{ left, right Filter }
+
+func (f orFilter) Match(resource map[string]any) bool {
+	return f.left.Match(resource) || f.right.Match(resource)
+}
+
+type notFilter struct{ filter Filter }
+
+func (f notFilter) Match(resource map[string]any) bool {
+	return !f.filter.Match(resource)
+}
+
+type presentFilter struct{ path attrPath }
+
+func (f presentFilter) Match(resource map[string]any) bool {
+	for _, value := range f.path.values(resource) {
+		if s, ok := value.(string); !ok || s != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePathFilter отбирает ресурсы, у которых хотя бы одно значение многозначного атрибута
+// удовлетворяет фильтру, например members[value eq "1"]
+type valuePathFilter struct {
+	path   attrPath
+	filter Filter
+}
+
+func (f valuePathFilter) Match(resource map[string]any) bool {
+	value, ok := lookup(resource, f.path.attr)
+	if !ok {
+		return false
+	}
+	items, multi := value.([]any)
+	if !multi {
+		items = []any{value}
+	}
+	for _, item := range items {
+		if object, ok := item.(map[string]any); ok && f.filter.Match(object) {
+			return true
+		}
+	}
+	return false
+}
+
+type compareFilter struct {
+	path  attrPath
+	op    string
+	value any
+}
+
+func (f compareFilter) Match(resource map[string]any) bool {
+	values := f.path.values(resource)
+	if f.value == nil {
+		// сравнение с null: eq - атрибута нет, ne - атрибут есть
+		return (f.op == "eq") == (len(values) == 0)
+	}
+	if f.op == "ne" {
+		for _, value := range values {
+			if compare(value, "eq", f.value) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, value := range values {
+		if compare(value, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare сравнивает значение атрибута со значением из фильтра. Строки сравниваются без учёта регистра
+func compare(actual any, op string, expected any) bool {
+	switch want := expected.(type) {
+	case string:
+		got, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		got, want = strings.ToLower(got), strings.ToLower(want)
+		switch op {
+		case "eq":
+			return got == want
+		case "co":
+			return strings.Contains(got, want)
+		case "sw":
+			return strings.HasPrefix(got, want)
+		case "ew":
+			return strings.HasSuffix(got, want)
+		case "gt":
+			return got > want
+		case "ge":
+			return got >= want
+		case "lt":
+			return got < want
+		case "le":
+			return got <= want
+		}
+	case bool:
+		got, ok := actual.(bool)
+		return ok && op == "eq" && got == want
+	case float64:
+		got, ok := actual.(float64)
+		if !ok {
+			// id ресурсов - строки с числом
+			s, isString := actual.(string)
+			parsed, err := strconv.ParseFloat(s, 64)
+			if !isString || err != nil {
+				return false
+			}
+			got = parsed
+		}
+		switch op {
+		case "eq":
+			return got == want
+		case "gt":
+			return got > want
+		case "ge":
+			return got >= want
+		case "lt":
+			return got < want
+		case "le":
+			return got <= want
+		}
+	}
+	return false
+}
+
+// sqlCondition условие WHERE, в которое переведён фильтр, и значения его параметров
+type sqlCondition struct {
+	clause string
+	args   []any
+}
+
+// toSql переводит фильтр в условие SQL. Поддерживаются сравнения eq, sw и co строковых атрибутов
+// из columns и их сочетания через and и or; для остальных фильтров ok=false, и ресурсы
+// отбираются в памяти через Match
+func toSql(filter Filter, columns map[string]string) (condition sqlCondition, ok bool) {
+	if filter == nil {
+		return sqlCondition{clause: "TRUE"}, true
+	}
+	condition.clause, ok = filterSql(filter, columns, &condition.args)
+	return condition, ok
+}
+
+func filterSql(filter Filter, columns map[string]string, args *[]any) (string, bool) {
+	switch f := filter.(type) {
+	case andFilter:
+		return joinSql("AND", f.left, f.right, columns, args)
+	case orFilter:
+		return joinSql("OR", f.left, f.right, columns, args)
+	case compareFilter:
+		column, known := columns[strings.ToLower(f.path.attr)]
+		value, isString := f.value.(string)
+		if !known || !isString || f.path.subAttr != "" {
+			return "", false
+		}
+		// строки сравниваются без учёта регистра, как в compare
+		value = strings.ToLower(value)
+		switch f.op {
+		case "eq":
+			*args = append(*args, value)
+			return "lower(" + column + ") = $" + strconv.Itoa(len(*args)), true
+		case "sw":
+			*args = append(*args, escapeLike(value)+"%")
+			return "lower(" + column + ") LIKE $" + strconv.Itoa(len(*args)), true
+		case "co":
+			*args = append(*args, "%"+escapeLike(value)+"%")
+			return "lower(" + column + ") LIKE $" + strconv.Itoa(len(*args)), true
+		}
+	}
+	return "", false
+}
+
+func joinSql(operator string, left, right Filter, columns map[string]string, args *[]any) (string, bool) {
+	leftSql, ok := filterSql(left, columns, args)
+	if !ok {
+		return "", false
+	}
+	rightSql, ok := filterSql(right, columns, args)
+	if !ok {
+		return "", false
+	}
+	return "(" + leftSql + " " + operator + " " + rightSql + ")", true
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
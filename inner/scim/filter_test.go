@@ -0,0 +1,127 @@
+package scim
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	var a = assert.New(t)
+	user := map[string]any{
+		"id":          "7",
+		"userName":    "John Doe",
+		"displayName": "John Doe",
+		"active":      true,
+		"groups": []any{
+			map[string]any{"value": "1", "display": "admin"},
+			map[string]any{"value": "2", "display": "viewer"},
+		},
+		"meta": map[string]any{"resourceType": "User"},
+	}
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{`userName eq "john doe"`, true},
+		{`USERNAME EQ "John Doe"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "John Doe"`, true},
+		{`userName eq "jane"`, false},
+		{`userName ne "jane"`, true},
+		{`userName co "doe"`, true},
+		{`userName sw "john"`, true},
+		{`userName sw "doe"`, false},
+		{`userName ew "doe"`, true},
+		{`userName gt "a" and userName lt "z"`, true},
+		{`id eq 7`, true},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`userName eq "jane" or displayName co "john"`, true},
+		{`userName eq "jane" or userName eq "bob"`, false},
+		{`userName co "john" and not (active eq false)`, true},
+		{`(userName eq "jane" or userName eq "John Doe") and active eq true`, true},
+		{`groups pr`, true},
+		{`emails pr`, false},
+		{`emails eq null`, true},
+		{`groups eq "2"`, true},
+		{`groups.display eq "viewer"`, true},
+		{`groups[value eq "1" and display eq "admin"]`, true},
+		{`groups[value eq "1" and display eq "viewer"]`, false},
+		{`meta.resourceType eq "User"`, true},
+		{`userName eq "a \"quoted\" name"`, false},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.filter)
+		a.Nil(err, tt.filter)
+		a.Equal(tt.match, filter.Match(user), tt.filter)
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	var a = assert.New(t)
+	invalid := []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName like "john"`,
+		`userName eq "john`,
+		`userName eq john`,
+		`(userName eq "john"`,
+		`userName eq "john" and`,
+		`userName eq "john" extra`,
+		`groups[value eq "1"`,
+	}
+	for _, expression := range invalid {
+		filter, err := ParseFilter(expression)
+		a.Nil(filter, expression)
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr), expression)
+		a.Equal(ErrInvalidFilter, scimErr.ScimType, expression)
+		a.Equal(400, scimErr.StatusCode(), expression)
+	}
+}
+
+func TestToSql(t *testing.T) {
+	var a = assert.New(t)
+
+	tests := []struct {
+		filter string
+		clause string
+		args   []any
+	}{
+		{`userName eq "John"`, "lower(name) = $1", []any{"john"}},
+		{`externalId sw "HR_"`, `lower(external_id) LIKE $1`, []any{`hr\_%`}},
+		{`userName co "doe" and (displayName sw "j" or externalId eq "7")`,
+			"(lower(name) LIKE $1 AND (lower(name) LIKE $2 OR lower(external_id) = $3))",
+			[]any{"%doe%", "j%", "7"}},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.filter)
+		a.Nil(err, test.filter)
+
+		got, ok := toSql(filter, userColumns)
+
+		a.True(ok, test.filter)
+		a.Equal(sqlCondition{clause: test.clause, args: test.args}, got, test.filter)
+	}
+
+	// остальные фильтры выполняются в памяти
+	for _, expression := range []string{
+		`userName ew "doe"`,
+		`userName ne "doe"`,
+		`active eq true`,
+		`not (userName eq "doe")`,
+		`userName pr`,
+		`groups[display eq "admin"]`,
+		`userName eq "doe" and groups.value eq "1"`,
+		`name.givenName eq "john"`,
+	} {
+		filter, err := ParseFilter(expression)
+		a.Nil(err, expression)
+
+		_, ok := toSql(filter, userColumns)
+
+		a.False(ok, expression)
+	}
+}
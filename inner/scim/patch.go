@@ -0,0 +1,149 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatchRequest запрос на частичное изменение ресурса (RFC 7644, раздел 3.5.2)
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation операция add, remove или replace над атрибутом по пути path
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Операции PATCH запроса
+const (
+	opAdd     = "add"
+	opRemove  = "remove"
+	opReplace = "replace"
+)
+
+// validate проверяет схему запроса и операции, приводя их имена к нижнему регистру
+func (r *PatchRequest) validate() error {
+	if !slices.Contains(r.Schemas, SchemaPatchOp) {
+		return newError(http.StatusBadRequest, ErrInvalidSyntax, "patch request must use schema %s", SchemaPatchOp)
+	}
+	if len(r.Operations) == 0 {
+		return newError(http.StatusBadRequest, ErrInvalidSyntax, "patch request has no operations")
+	}
+	for i := range r.Operations {
+		// Azure AD присылает операции с заглавной буквы
+		op := strings.ToLower(r.Operations[i].Op)
+		if op != opAdd && op != opRemove && op != opReplace {
+			return newError(http.StatusBadRequest, ErrInvalidSyntax, "unknown patch operation %s", r.Operations[i].Op)
+		}
+		r.Operations[i].Op = op
+		if op == opRemove && r.Operations[i].Path == "" {
+			return newError(http.StatusBadRequest, ErrNoTarget, "remove operation requires path")
+		}
+		if op != opRemove && len(r.Operations[i].Value) == 0 {
+			return newError(http.StatusBadRequest, ErrInvalidValue, "%s operation requires value", op)
+		}
+	}
+	return nil
+}
+
+// patchPath разобранный путь операции: атрибут, необязательный фильтр значений и вложенный атрибут
+type patchPath struct {
+	attr    string
+	filter  Filter
+	subAttr string
+}
+
+// parsePatchPath разбирает путь вида displayName, name.givenName или members[value eq "1"]
+func parsePatchPath(path string) (patchPath, error) {
+	attrPart, rest, hasFilter := strings.Cut(path, "[")
+	if !hasFilter {
+		parsed := parseAttrPath(path)
+		return patchPath{attr: parsed.attr, subAttr: parsed.subAttr}, nil
+	}
+
+	expression, subAttr, ok := strings.Cut(rest, "]")
+	if !ok {
+		return patchPath{}, newError(http.StatusBadRequest, ErrInvalidPath, "invalid path %s", path)
+	}
+	filter, err := ParseFilter(expression)
+	if err != nil {
+		return patchPath{}, newError(http.StatusBadRequest, ErrInvalidPath, "invalid path %s: %s", path, err.Error())
+	}
+	return patchPath{
+		attr:    parseAttrPath(attrPart).attr,
+		filter:  filter,
+		subAttr: strings.TrimPrefix(subAttr, "."),
+	}, nil
+}
+
+// is проверяет имя атрибута пути без учёта регистра
+func (p patchPath) is(attr string) bool {
+	return strings.EqualFold(p.attr, attr)
+}
+
+// patchAttributes значения атрибутов из операции без пути: {"displayName": "...", "members": [...]}
+func patchAttributes(value json.RawMessage) (map[string]json.RawMessage, error) {
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(value, &attributes); err != nil {
+		return nil, newError(http.StatusBadRequest, ErrInvalidValue, "operation without path requires object value")
+	}
+	// имена атрибутов нечувствительны к регистру
+	result := make(map[string]json.RawMessage, len(attributes))
+	for name, value := range attributes {
+		result[strings.ToLower(parseAttrPath(name).attr)] = value
+	}
+	return result, nil
+}
+
+// stringValue разбирает строковое значение атрибута
+func stringValue(attr string, value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", newError(http.StatusBadRequest, ErrInvalidValue, "%s must be a string", attr)
+	}
+	return s, nil
+}
+
+// boolValue разбирает логическое значение; Azure AD передаёт его строкой "True"/"False"
+func boolValue(attr string, value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		if parsed, err := strconv.ParseBool(strings.ToLower(s)); err == nil {
+			return parsed, nil
+		}
+	}
+	return false, newError(http.StatusBadRequest, ErrInvalidValue, "%s must be a boolean", attr)
+}
+
+// memberIds разбирает список участников [{"value": "1"}, ...] в список id сотрудников
+func memberIds(value json.RawMessage) ([]int64, error) {
+	var members []MemberRef
+	if err := json.Unmarshal(value, &members); err != nil {
+		// один участник может быть передан объектом
+		var member MemberRef
+		if errOne := json.Unmarshal(value, &member); errOne != nil {
+			return nil, newError(http.StatusBadRequest, ErrInvalidValue, "members must be a list of objects with value")
+		}
+		members = []MemberRef{member}
+	}
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, newError(http.StatusBadRequest, ErrInvalidValue, "invalid member value %q", member.Value)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,130 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/role"
+	"strconv"
+	"time"
+)
+
+// атрибуты SCIM, фильтры по которым выполняются в БД, и соответствующие им колонки
+var (
+	userColumns = map[string]string{
+		"username":    "name",
+		"displayname": "name",
+		"externalid":  "external_id",
+	}
+	groupColumns = map[string]string{
+		"displayname": "name",
+	}
+)
+
+// UserEntity сотрудник с атрибутами, которые отдаются как SCIM User
+type UserEntity struct {
+	Id         int64          `db:"id"`
+	Name       string         `db:"name"`
+	ExternalId sql.NullString `db:"external_id"`
+	Status     string         `db:"status"`
+	CreateAt   time.Time      `db:"create_at"`
+	UpdateAt   time.Time      `db:"update_at"`
+}
+
+func (e *UserEntity) toResponse() employee.Response {
+	return employee.Response{
+		Id:         e.Id,
+		Name:       e.Name,
+		ExternalId: e.ExternalId.String,
+		Status:     e.Status,
+		CreateAt:   e.CreateAt,
+		UpdateAt:   e.UpdateAt,
+	}
+}
+
+// GroupEntity роль с атрибутами, которые отдаются как SCIM Group
+type GroupEntity struct {
+	Id       int64     `db:"id"`
+	Name     string    `db:"name"`
+	CreateAt time.Time `db:"create_at"`
+	UpdateAt time.Time `db:"update_at"`
+}
+
+func (e *GroupEntity) toResponse() role.Response {
+	return role.Response{
+		Id:       e.Id,
+		Name:     e.Name,
+		CreateAt: e.CreateAt,
+		UpdateAt: e.UpdateAt,
+	}
+}
+
+// MemberEntity назначение роли сотруднику
+type MemberEntity struct {
+	EmployeeId   int64  `db:"employee_id"`
+	EmployeeName string `db:"employee_name"`
+	RoleId       int64  `db:"role_id"`
+	RoleName     string `db:"role_name"`
+}
+
+const selectMembers = `SELECT er.employee_id, e.name AS employee_name, er.role_id, r.name AS role_name
+FROM employee_role er
+JOIN employee e ON e.id = er.employee_id
+JOIN role r ON r.id = er.role_id`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewScimRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// количество сотрудников, удовлетворяющих условию
+func (r *Repository) CountUsers(ctx context.Context, where sqlCondition) (count int, err error) {
+	err = r.db.GetContext(ctx, &count, "SELECT count(*) FROM employee WHERE "+where.clause, where.args...)
+	return count, err
+}
+
+// страница сотрудников, удовлетворяющих условию, в порядке id
+func (r *Repository) FindUsers(ctx context.Context, where sqlCondition, offset int, limit int) (users []UserEntity, err error) {
+	query := "SELECT id, name, external_id, status, create_at, update_at FROM employee WHERE " + where.clause +
+		" ORDER BY id" + limitOffset(len(where.args))
+	err = r.db.SelectContext(ctx, &users, query, append(where.args, limit, offset)...)
+	return users, err
+}
+
+// количество ролей, удовлетворяющих условию
+func (r *Repository) CountGroups(ctx context.Context, where sqlCondition) (count int, err error) {
+	err = r.db.GetContext(ctx, &count, "SELECT count(*) FROM role WHERE "+where.clause, where.args...)
+	return count, err
+}
+
+// страница ролей, удовлетворяющих условию, в порядке id
+func (r *Repository) FindGroups(ctx context.Context, where sqlCondition, offset int, limit int) (groups []GroupEntity, err error) {
+	query := "SELECT id, name, create_at, update_at FROM role WHERE " + where.clause +
+		" ORDER BY id" + limitOffset(len(where.args))
+	err = r.db.SelectContext(ctx, &groups, query, append(where.args, limit, offset)...)
+	return groups, err
+}
+
+// назначения ролей перечисленным сотрудникам
+func (r *Repository) FindGroupsOfUsers(ctx context.Context, employeeIds []int64) (members []MemberEntity, err error) {
+	query := selectMembers + " WHERE er.employee_id = ANY($1) ORDER BY er.employee_id, er.role_id"
+	err = r.db.SelectContext(ctx, &members, query, pq.Array(employeeIds))
+	return members, err
+}
+
+// назначения перечисленных ролей
+func (r *Repository) FindMembersOfGroups(ctx context.Context, roleIds []int64) (members []MemberEntity, err error) {
+	query := selectMembers + " WHERE er.role_id = ANY($1) ORDER BY er.role_id, er.employee_id"
+	err = r.db.SelectContext(ctx, &members, query, pq.Array(roleIds))
+	return members, err
+}
+
+// limitOffset параметры LIMIT и OFFSET, идущие после параметров условия
+func limitOffset(argCount int) string {
+	return " LIMIT $" + strconv.Itoa(argCount+1) + " OFFSET $" + strconv.Itoa(argCount+2)
+}
@@ -0,0 +1,162 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MIMEApplicationScimJSON тип содержимого запросов и ответов SCIM
+const MIMEApplicationScimJSON = "application/scim+json"
+
+// Идентификаторы схем SCIM 2.0 (RFC 7643, RFC 7644)
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// Типы ошибок SCIM (поле scimType)
+const (
+	ErrInvalidFilter = "invalidFilter"
+	ErrInvalidSyntax = "invalidSyntax"
+	ErrInvalidPath   = "invalidPath"
+	ErrInvalidValue  = "invalidValue"
+	ErrNoTarget      = "noTarget"
+	ErrMutability    = "mutability"
+	ErrUniqueness    = "uniqueness"
+	ErrTooMany       = "tooMany"
+)
+
+// параметры постраничной выдачи списков
+const (
+	defaultCount = 100
+	maxCount     = 200
+)
+
+// базовый путь SCIM API, относительно которого строятся ссылки на ресурсы
+const basePath = "/scim/v2"
+
+// Meta метаданные ресурса
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// MemberRef ссылка на участника группы или на группу пользователя
+type MemberRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User сотрудник в представлении SCIM
+type User struct {
+	Schemas     []string    `json:"schemas"`
+	Id          string      `json:"id"`
+	ExternalId  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Active      bool        `json:"active"`
+	Groups      []MemberRef `json:"groups,omitempty"`
+	Meta        Meta        `json:"meta"`
+}
+
+// Group роль в представлении SCIM, участники - сотрудники, которым назначена роль
+type Group struct {
+	Schemas     []string    `json:"schemas"`
+	Id          string      `json:"id"`
+	DisplayName string      `json:"displayName"`
+	Members     []MemberRef `json:"members"`
+	Meta        Meta        `json:"meta"`
+}
+
+// ListResponse страница результатов поиска ресурсов
+type ListResponse[T any] struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []T      `json:"Resources"`
+}
+
+// ListQuery параметры поиска ресурсов
+type ListQuery struct {
+	Filter string
+	// номер первого результата, начиная с 1
+	StartIndex int
+	Count      int
+}
+
+// bounds номер первого результата и размер страницы, ограниченный maxCount
+func (q ListQuery) bounds() (startIndex int, count int) {
+	return max(q.StartIndex, 1), min(max(q.Count, 0), maxCount)
+}
+
+// page возвращает страницу ресурсов по параметрам запроса
+func page[T any](resources []T, query ListQuery) ListResponse[T] {
+	startIndex, count := query.bounds()
+	from := min(startIndex-1, len(resources))
+	to := min(from+count, len(resources))
+	return ListResponse[T]{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: to - from,
+		Resources:    resources[from:to],
+	}
+}
+
+// emptyPage страница без ресурсов с общим числом найденных ресурсов total
+func emptyPage[T any](total int, query ListQuery) ListResponse[T] {
+	startIndex, _ := query.bounds()
+	return ListResponse[T]{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		Resources:    []T{},
+	}
+}
+
+// Error ошибка в формате SCIM (RFC 7644, раздел 3.12)
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// StatusCode HTTP статус ошибки
+func (e *Error) StatusCode() int {
+	status, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return status
+}
+
+func newError(status int, scimType string, format string, args ...any) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   fmt.Sprintf(format, args...),
+	}
+}
+
+// notFound ошибка для ресурса с неизвестным id
+func notFound(resourceType string, id string) *Error {
+	return newError(http.StatusNotFound, "", "%s %s not found", resourceType, id)
+}
@@ -0,0 +1,725 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/nihrom205/idm/inner/assignment"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/nihrom205/idm/inner/role"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// EmployeeSvc сервис сотрудников, сотрудники отдаются как SCIM User
+type EmployeeSvc interface {
+	Create(ctx context.Context, request employee.CreateRequest, principal common.Principal) (int64, error)
+	FindById(ctx context.Context, id int64) (employee.Response, error)
+	GetAll(ctx context.Context) ([]employee.Response, error)
+	Update(ctx context.Context, id int64, request employee.UpdateRequest, actor string) (employee.Response, error)
+	DeleteById(ctx context.Context, id int64, actor string) error
+}
+
+// RoleSvc сервис ролей, роли отдаются как SCIM Group
+type RoleSvc interface {
+	Create(ctx context.Context, request role.CreateRequest) (int64, error)
+	FindById(ctx context.Context, id int64) (role.Response, error)
+	GetAll(ctx context.Context) ([]role.Response, error)
+	Update(ctx context.Context, id int64, request role.UpdateRequest) (role.Response, error)
+	DeleteById(ctx context.Context, id int64) error
+}
+
+// AssignmentSvc сервис назначений ролей, из которых строятся участники групп
+type AssignmentSvc interface {
+	FindByEmployee(ctx context.Context, employeeId int64) ([]assignment.Response, error)
+	FindByRole(ctx context.Context, roleId int64) ([]assignment.Response, error)
+	GetAll(ctx context.Context) ([]assignment.Response, error)
+	ReplaceMembers(ctx context.Context, request assignment.ReplaceMembersRequest) error
+}
+
+// LifecycleSvc смена статуса сотрудника: active=false приостанавливает учётную запись
+type LifecycleSvc interface {
+	Transition(ctx context.Context, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error)
+}
+
+// Repo поиск пользователей и групп, фильтр которых переводится в SQL, с постраничной выдачей в БД
+type Repo interface {
+	CountUsers(ctx context.Context, where sqlCondition) (int, error)
+	FindUsers(ctx context.Context, where sqlCondition, offset int, limit int) ([]UserEntity, error)
+	CountGroups(ctx context.Context, where sqlCondition) (int, error)
+	FindGroups(ctx context.Context, where sqlCondition, offset int, limit int) ([]GroupEntity, error)
+	FindGroupsOfUsers(ctx context.Context, employeeIds []int64) ([]MemberEntity, error)
+	FindMembersOfGroups(ctx context.Context, roleIds []int64) ([]MemberEntity, error)
+}
+
+// UserRequest тело запроса на создание или замену пользователя
+type UserRequest struct {
+	Schemas     []string `json:"schemas"`
+	UserName    string   `json:"userName"`
+	DisplayName string   `json:"displayName"`
+	// nil, если атрибут не передан
+	Active *bool `json:"active"`
+}
+
+// GroupRequest тело запроса на создание или замену группы
+type GroupRequest struct {
+	Schemas     []string    `json:"schemas"`
+	DisplayName string      `json:"displayName"`
+	Members     []MemberRef `json:"members"`
+}
+
+// principal от имени которого SCIM клиент создаёт сотрудников; доступ к SCIM есть только у администратора
+var principal = common.Principal{Actor: "scim", Admin: true}
+
+type Service struct {
+	repo        Repo
+	employees   EmployeeSvc
+	roles       RoleSvc
+	assignments AssignmentSvc
+	lifecycle   LifecycleSvc
+}
+
+func NewService(repo Repo, employees EmployeeSvc, roles RoleSvc, assignments AssignmentSvc, lifecycle LifecycleSvc) *Service {
+	return &Service{
+		repo:        repo,
+		employees:   employees,
+		roles:       roles,
+		assignments: assignments,
+		lifecycle:   lifecycle,
+	}
+}
+
+// ListUsers ищет пользователей по фильтру и возвращает страницу результатов. Фильтры по userName,
+// displayName и externalId выполняются в БД, остальные - в памяти по всем сотрудникам
+func (s *Service) ListUsers(ctx context.Context, query ListQuery) (ListResponse[User], error) {
+	filter, err := parseOptionalFilter(query.Filter)
+	if err != nil {
+		return ListResponse[User]{}, err
+	}
+	if where, ok := toSql(filter, userColumns); ok {
+		return s.findUsers(ctx, where, query)
+	}
+	employees, err := s.employees.GetAll(ctx)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("error listing users: %w", err)
+	}
+	assignments, err := s.assignments.GetAll(ctx)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("error listing users: %w", err)
+	}
+
+	groups := make(map[int64][]MemberRef)
+	for _, item := range assignments {
+		groups[item.EmployeeId] = append(groups[item.EmployeeId], groupRef(item.RoleId, item.RoleName))
+	}
+	slices.SortFunc(employees, func(a, b employee.Response) int { return compareIds(a.Id, b.Id) })
+
+	users := make([]User, 0, len(employees))
+	for _, item := range employees {
+		user := toUser(item, groups[item.Id])
+		if matches(filter, user) {
+			users = append(users, user)
+		}
+	}
+	return page(users, query), nil
+}
+
+// findUsers возвращает страницу пользователей, отобранных условием в БД
+func (s *Service) findUsers(ctx context.Context, where sqlCondition, query ListQuery) (ListResponse[User], error) {
+	total, err := s.repo.CountUsers(ctx, where)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("error listing users: %w", err)
+	}
+	response := emptyPage[User](total, query)
+	startIndex, count := query.bounds()
+	if count == 0 || startIndex > total {
+		return response, nil
+	}
+
+	found, err := s.repo.FindUsers(ctx, where, startIndex-1, count)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("error listing users: %w", err)
+	}
+	ids := make([]int64, 0, len(found))
+	for _, item := range found {
+		ids = append(ids, item.Id)
+	}
+	memberships, err := s.repo.FindGroupsOfUsers(ctx, ids)
+	if err != nil {
+		return ListResponse[User]{}, fmt.Errorf("error listing users: %w", err)
+	}
+
+	groups := make(map[int64][]MemberRef)
+	for _, item := range memberships {
+		groups[item.EmployeeId] = append(groups[item.EmployeeId], groupRef(item.RoleId, item.RoleName))
+	}
+	for _, item := range found {
+		response.Resources = append(response.Resources, toUser(item.toResponse(), groups[item.Id]))
+	}
+	response.ItemsPerPage = len(response.Resources)
+	return response, nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id string) (User, error) {
+	employeeId, err := parseId("User", id)
+	if err != nil {
+		return User{}, err
+	}
+	found, err := s.employees.FindById(ctx, employeeId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, notFound("User", id)
+	}
+	if err != nil {
+		return User{}, fmt.Errorf("error getting user %s: %w", id, err)
+	}
+	assignments, err := s.assignments.FindByEmployee(ctx, employeeId)
+	if err != nil {
+		return User{}, fmt.Errorf("error getting user %s: %w", id, err)
+	}
+
+	groups := make([]MemberRef, 0, len(assignments))
+	for _, item := range assignments {
+		groups = append(groups, groupRef(item.RoleId, item.RoleName))
+	}
+	return toUser(found, groups), nil
+}
+
+func (s *Service) CreateUser(ctx context.Context, request UserRequest) (User, error) {
+	if err := validateUser(request); err != nil {
+		return User{}, err
+	}
+	id, err := s.employees.Create(ctx, employee.CreateRequest{Name: request.UserName}, principal)
+	if err != nil {
+		return User{}, err
+	}
+	if request.Active != nil {
+		// новый сотрудник создаётся активным
+		if err := s.setActive(ctx, id, true, *request.Active); err != nil {
+			return User{}, err
+		}
+	}
+	return s.GetUser(ctx, strconv.FormatInt(id, 10))
+}
+
+// ReplaceUser заменяет атрибуты пользователя (PUT)
+func (s *Service) ReplaceUser(ctx context.Context, id string, request UserRequest) (User, error) {
+	employeeId, err := parseId("User", id)
+	if err != nil {
+		return User{}, err
+	}
+	if err := validateUser(request); err != nil {
+		return User{}, err
+	}
+	updated, err := s.employees.Update(ctx, employeeId, employee.UpdateRequest{Name: request.UserName}, principal.Actor)
+	if err != nil {
+		return User{}, err
+	}
+	if request.Active != nil {
+		if err := s.setActive(ctx, employeeId, updated.Status == lifecycle.StatusActive, *request.Active); err != nil {
+			return User{}, err
+		}
+	}
+	return s.GetUser(ctx, id)
+}
+
+// setActive приостанавливает (active=false) или снова активирует учётную запись сотрудника
+func (s *Service) setActive(ctx context.Context, employeeId int64, isActive bool, active bool) error {
+	if isActive == active {
+		return nil
+	}
+	status := lifecycle.StatusSuspended
+	if active {
+		status = lifecycle.StatusActive
+	}
+	_, err := s.lifecycle.Transition(ctx, lifecycle.TransitionRequest{
+		EmployeeId: employeeId,
+		Status:     status,
+		Reason:     "scim",
+		Actor:      "scim",
+	})
+	return err
+}
+
+// PatchUser применяет к пользователю операции PATCH запроса
+func (s *Service) PatchUser(ctx context.Context, id string, request PatchRequest) (User, error) {
+	if err := request.validate(); err != nil {
+		return User{}, err
+	}
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return User{}, err
+	}
+
+	userName, active := user.UserName, user.Active
+	for _, op := range request.Operations {
+		if op.Path == "" {
+			attributes, err := patchAttributes(op.Value)
+			if err != nil {
+				return User{}, err
+			}
+			for attr, value := range attributes {
+				if err := patchUserAttribute(&userName, &active, attr, value); err != nil {
+					return User{}, err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return User{}, err
+		}
+		if op.Op == opRemove {
+			if path.is("userName") {
+				return User{}, newError(http.StatusBadRequest, ErrMutability, "userName is required and cannot be removed")
+			}
+			continue
+		}
+		if path.filter == nil && path.subAttr == "" {
+			if err := patchUserAttribute(&userName, &active, strings.ToLower(path.attr), op.Value); err != nil {
+				return User{}, err
+			}
+		}
+	}
+
+	if userName == user.UserName && active == user.Active {
+		return user, nil
+	}
+	employeeId, _ := strconv.ParseInt(id, 10, 64)
+	if userName != user.UserName {
+		if _, err := s.employees.Update(ctx, employeeId, employee.UpdateRequest{Name: userName}, principal.Actor); err != nil {
+			return User{}, err
+		}
+	}
+	if err := s.setActive(ctx, employeeId, user.Active, active); err != nil {
+		return User{}, err
+	}
+	return s.GetUser(ctx, id)
+}
+
+// patchUserAttribute изменяет атрибут пользователя. Атрибуты, которых нет у сотрудника
+// (name, emails и т.п.), игнорируются: клиенты SCIM присылают их всегда
+func patchUserAttribute(userName *string, active *bool, attr string, value json.RawMessage) error {
+	switch attr {
+	case "username":
+		name, err := stringValue("userName", value)
+		if err != nil {
+			return err
+		}
+		*userName = name
+	case "active":
+		value, err := boolValue("active", value)
+		if err != nil {
+			return err
+		}
+		*active = value
+	}
+	return nil
+}
+
+// DeleteUser увольняет сотрудника; запись сотрудника сохраняется
+func (s *Service) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	employeeId, _ := strconv.ParseInt(user.Id, 10, 64)
+	if err := s.employees.DeleteById(ctx, employeeId, principal.Actor); err != nil {
+		return fmt.Errorf("error deleting user %s: %w", id, err)
+	}
+	return nil
+}
+
+// ListGroups ищет группы по фильтру и возвращает страницу результатов. Фильтры по displayName
+// выполняются в БД, остальные - в памяти по всем ролям
+func (s *Service) ListGroups(ctx context.Context, query ListQuery) (ListResponse[Group], error) {
+	filter, err := parseOptionalFilter(query.Filter)
+	if err != nil {
+		return ListResponse[Group]{}, err
+	}
+	if where, ok := toSql(filter, groupColumns); ok {
+		return s.findGroups(ctx, where, query)
+	}
+	roles, err := s.roles.GetAll(ctx)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("error listing groups: %w", err)
+	}
+	assignments, err := s.assignments.GetAll(ctx)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("error listing groups: %w", err)
+	}
+
+	members := make(map[int64][]MemberRef)
+	for _, item := range assignments {
+		members[item.RoleId] = append(members[item.RoleId], userRef(item.EmployeeId, item.EmployeeName))
+	}
+	slices.SortFunc(roles, func(a, b role.Response) int { return compareIds(a.Id, b.Id) })
+
+	groups := make([]Group, 0, len(roles))
+	for _, item := range roles {
+		group := toGroup(item, members[item.Id])
+		if matches(filter, group) {
+			groups = append(groups, group)
+		}
+	}
+	return page(groups, query), nil
+}
+
+// findGroups возвращает страницу групп, отобранных условием в БД
+func (s *Service) findGroups(ctx context.Context, where sqlCondition, query ListQuery) (ListResponse[Group], error) {
+	total, err := s.repo.CountGroups(ctx, where)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("error listing groups: %w", err)
+	}
+	response := emptyPage[Group](total, query)
+	startIndex, count := query.bounds()
+	if count == 0 || startIndex > total {
+		return response, nil
+	}
+
+	found, err := s.repo.FindGroups(ctx, where, startIndex-1, count)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("error listing groups: %w", err)
+	}
+	ids := make([]int64, 0, len(found))
+	for _, item := range found {
+		ids = append(ids, item.Id)
+	}
+	memberships, err := s.repo.FindMembersOfGroups(ctx, ids)
+	if err != nil {
+		return ListResponse[Group]{}, fmt.Errorf("error listing groups: %w", err)
+	}
+
+	members := make(map[int64][]MemberRef)
+	for _, item := range memberships {
+		members[item.RoleId] = append(members[item.RoleId], userRef(item.EmployeeId, item.EmployeeName))
+	}
+	for _, item := range found {
+		response.Resources = append(response.Resources, toGroup(item.toResponse(), members[item.Id]))
+	}
+	response.ItemsPerPage = len(response.Resources)
+	return response, nil
+}
+
+func (s *Service) GetGroup(ctx context.Context, id string) (Group, error) {
+	roleId, err := parseId("Group", id)
+	if err != nil {
+		return Group{}, err
+	}
+	found, err := s.roles.FindById(ctx, roleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Group{}, notFound("Group", id)
+	}
+	if err != nil {
+		return Group{}, fmt.Errorf("error getting group %s: %w", id, err)
+	}
+	assignments, err := s.assignments.FindByRole(ctx, roleId)
+	if err != nil {
+		return Group{}, fmt.Errorf("error getting group %s: %w", id, err)
+	}
+
+	members := make([]MemberRef, 0, len(assignments))
+	for _, item := range assignments {
+		members = append(members, userRef(item.EmployeeId, item.EmployeeName))
+	}
+	return toGroup(found, members), nil
+}
+
+func (s *Service) CreateGroup(ctx context.Context, request GroupRequest) (Group, error) {
+	memberIds, err := validateGroup(request)
+	if err != nil {
+		return Group{}, err
+	}
+	id, err := s.roles.Create(ctx, role.CreateRequest{Name: request.DisplayName})
+	if err != nil {
+		return Group{}, err
+	}
+	if len(memberIds) > 0 {
+		err := s.assignments.ReplaceMembers(ctx, assignment.ReplaceMembersRequest{RoleId: id, EmployeeIds: memberIds})
+		if err != nil {
+			// группа без участников клиенту не нужна: удаляем её, чтобы повтор запроса не упёрся в дубликат
+			if errDelete := s.roles.DeleteById(ctx, id); errDelete != nil {
+				return Group{}, fmt.Errorf("error deleting group %d: %w, %w", id, err, errDelete)
+			}
+			return Group{}, err
+		}
+	}
+	return s.GetGroup(ctx, strconv.FormatInt(id, 10))
+}
+
+// ReplaceGroup заменяет имя и участников группы (PUT)
+func (s *Service) ReplaceGroup(ctx context.Context, id string, request GroupRequest) (Group, error) {
+	roleId, err := parseId("Group", id)
+	if err != nil {
+		return Group{}, err
+	}
+	memberIds, err := validateGroup(request)
+	if err != nil {
+		return Group{}, err
+	}
+	if _, err := s.roles.Update(ctx, roleId, role.UpdateRequest{Name: request.DisplayName}); err != nil {
+		return Group{}, err
+	}
+	err = s.assignments.ReplaceMembers(ctx, assignment.ReplaceMembersRequest{RoleId: roleId, EmployeeIds: memberIds})
+	if err != nil {
+		return Group{}, err
+	}
+	return s.GetGroup(ctx, id)
+}
+
+// PatchGroup применяет к группе операции PATCH запроса: изменение имени,
+// добавление, удаление и замена участников
+func (s *Service) PatchGroup(ctx context.Context, id string, request PatchRequest) (Group, error) {
+	if err := request.validate(); err != nil {
+		return Group{}, err
+	}
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return Group{}, err
+	}
+
+	displayName := group.DisplayName
+	members := make([]MemberRef, len(group.Members))
+	copy(members, group.Members)
+
+	for _, op := range request.Operations {
+		if op.Path == "" {
+			attributes, err := patchAttributes(op.Value)
+			if err != nil {
+				return Group{}, err
+			}
+			for attr, value := range attributes {
+				if displayName, members, err = patchGroupAttribute(op.Op, attr, value, displayName, members); err != nil {
+					return Group{}, err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(op.Path)
+		if err != nil {
+			return Group{}, err
+		}
+		switch {
+		case path.is("members") && op.Op == opRemove:
+			members, err = removeMembers(members, path.filter, op.Value)
+			if err != nil {
+				return Group{}, err
+			}
+		case path.is("displayName") && op.Op == opRemove:
+			return Group{}, newError(http.StatusBadRequest, ErrMutability, "displayName is required and cannot be removed")
+		case path.filter == nil && path.subAttr == "":
+			if displayName, members, err = patchGroupAttribute(op.Op, strings.ToLower(path.attr), op.Value, displayName, members); err != nil {
+				return Group{}, err
+			}
+		}
+	}
+
+	roleId, _ := strconv.ParseInt(id, 10, 64)
+	if displayName != group.DisplayName {
+		if _, err := s.roles.Update(ctx, roleId, role.UpdateRequest{Name: displayName}); err != nil {
+			return Group{}, err
+		}
+	}
+	if !sameMembers(members, group.Members) {
+		request := assignment.ReplaceMembersRequest{RoleId: roleId, EmployeeIds: refIds(members)}
+		if err := s.assignments.ReplaceMembers(ctx, request); err != nil {
+			return Group{}, err
+		}
+	}
+	return s.GetGroup(ctx, id)
+}
+
+// patchGroupAttribute выполняет операцию add или replace над атрибутом группы.
+// Неподдерживаемые атрибуты (externalId и т.п.) игнорируются
+func patchGroupAttribute(op string, attr string, value json.RawMessage, displayName string, members []MemberRef) (string, []MemberRef, error) {
+	switch attr {
+	case "displayname":
+		name, err := stringValue("displayName", value)
+		if err != nil {
+			return "", nil, err
+		}
+		return name, members, nil
+	case "members":
+		ids, err := memberIds(value)
+		if err != nil {
+			return "", nil, err
+		}
+		if op == opReplace {
+			members = nil
+		}
+		for _, memberId := range ids {
+			if !slices.Contains(refIds(members), memberId) {
+				members = append(members, userRef(memberId, ""))
+			}
+		}
+	}
+	return displayName, members, nil
+}
+
+// removeMembers удаляет участников по фильтру пути (members[value eq "1"]),
+// по списку из значения операции или, если не задано ни то, ни другое, всех участников
+func removeMembers(members []MemberRef, filter Filter, value json.RawMessage) ([]MemberRef, error) {
+	if filter != nil {
+		return slices.DeleteFunc(members, func(member MemberRef) bool {
+			return filter.Match(map[string]any{"value": member.Value, "display": member.Display})
+		}), nil
+	}
+	if len(value) == 0 {
+		return nil, nil
+	}
+	ids, err := memberIds(value)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(members, func(member MemberRef) bool {
+		memberId, _ := strconv.ParseInt(member.Value, 10, 64)
+		return slices.Contains(ids, memberId)
+	}), nil
+}
+
+func (s *Service) DeleteGroup(ctx context.Context, id string) error {
+	group, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	roleId, _ := strconv.ParseInt(group.Id, 10, 64)
+	if err := s.roles.DeleteById(ctx, roleId); err != nil {
+		return fmt.Errorf("error deleting group %s: %w", id, err)
+	}
+	return nil
+}
+
+func validateUser(request UserRequest) error {
+	if strings.TrimSpace(request.UserName) == "" {
+		return newError(http.StatusBadRequest, ErrInvalidValue, "userName is required")
+	}
+	return nil
+}
+
+// validateGroup проверяет запрос и возвращает id участников группы
+func validateGroup(request GroupRequest) ([]int64, error) {
+	if strings.TrimSpace(request.DisplayName) == "" {
+		return nil, newError(http.StatusBadRequest, ErrInvalidValue, "displayName is required")
+	}
+	if len(request.Members) == 0 {
+		return []int64{}, nil
+	}
+	members, err := json.Marshal(request.Members)
+	if err != nil {
+		return nil, err
+	}
+	return memberIds(members)
+}
+
+func parseOptionalFilter(expression string) (Filter, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, nil
+	}
+	return ParseFilter(expression)
+}
+
+// matches проверяет ресурс фильтром; ресурс приводится к JSON объекту, чтобы фильтр видел имена атрибутов SCIM
+func matches(filter Filter, resource any) bool {
+	if filter == nil {
+		return true
+	}
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return false
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return false
+	}
+	return filter.Match(object)
+}
+
+// parseId разбирает id ресурса; ресурса с нечисловым id не существует
+func parseId(resourceType string, id string) (int64, error) {
+	parsed, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, notFound(resourceType, id)
+	}
+	return parsed, nil
+}
+
+func compareIds(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toUser(e employee.Response, groups []MemberRef) User {
+	id := strconv.FormatInt(e.Id, 10)
+	return User{
+		Schemas:     []string{SchemaUser},
+		Id:          id,
+		ExternalId:  e.ExternalId,
+		UserName:    e.Name,
+		DisplayName: e.Name,
+		Active:      e.Status == lifecycle.StatusActive,
+		Groups:      groups,
+		Meta: Meta{
+			ResourceType: "User",
+			Created:      e.CreateAt,
+			LastModified: e.UpdateAt,
+			Location:     basePath + "/Users/" + id,
+		},
+	}
+}
+
+func toGroup(r role.Response, members []MemberRef) Group {
+	id := strconv.FormatInt(r.Id, 10)
+	if members == nil {
+		members = []MemberRef{}
+	}
+	return Group{
+		Schemas:     []string{SchemaGroup},
+		Id:          id,
+		DisplayName: r.Name,
+		Members:     members,
+		Meta: Meta{
+			ResourceType: "Group",
+			Created:      r.CreateAt,
+			LastModified: r.UpdateAt,
+			Location:     basePath + "/Groups/" + id,
+		},
+	}
+}
+
+func userRef(id int64, name string) MemberRef {
+	value := strconv.FormatInt(id, 10)
+	return MemberRef{Value: value, Display: name, Ref: basePath + "/Users/" + value}
+}
+
+func groupRef(id int64, name string) MemberRef {
+	value := strconv.FormatInt(id, 10)
+	return MemberRef{Value: value, Display: name, Ref: basePath + "/Groups/" + value}
+}
+
+func refIds(refs []MemberRef) []int64 {
+	ids := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		id, _ := strconv.ParseInt(ref.Value, 10, 64)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func sameMembers(a, b []MemberRef) bool {
+	idsA, idsB := refIds(a), refIds(b)
+	slices.Sort(idsA)
+	slices.Sort(idsB)
+	return slices.Equal(idsA, idsB)
+}
@@ -0,0 +1,587 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/nihrom205/idm/inner/assignment"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/employee"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/nihrom205/idm/inner/role"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type MockEmployeeSvc struct {
+	mock.Mock
+}
+
+func (m *MockEmployeeSvc) Create(ctx context.Context, request employee.CreateRequest, principal common.Principal) (int64, error) {
+	args := m.Called(request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) FindById(ctx context.Context, id int64) (employee.Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) GetAll(ctx context.Context) ([]employee.Response, error) {
+	args := m.Called()
+	return args.Get(0).([]employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) Update(ctx context.Context, id int64, request employee.UpdateRequest, actor string) (employee.Response, error) {
+	args := m.Called(id, request)
+	return args.Get(0).(employee.Response), args.Error(1)
+}
+
+func (m *MockEmployeeSvc) DeleteById(ctx context.Context, id int64, actor string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockRoleSvc struct {
+	mock.Mock
+}
+
+func (m *MockRoleSvc) Create(ctx context.Context, request role.CreateRequest) (int64, error) {
+	args := m.Called(request)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockRoleSvc) FindById(ctx context.Context, id int64) (role.Response, error) {
+	args := m.Called(id)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleSvc) GetAll(ctx context.Context) ([]role.Response, error) {
+	args := m.Called()
+	return args.Get(0).([]role.Response), args.Error(1)
+}
+
+func (m *MockRoleSvc) Update(ctx context.Context, id int64, request role.UpdateRequest) (role.Response, error) {
+	args := m.Called(id, request)
+	return args.Get(0).(role.Response), args.Error(1)
+}
+
+func (m *MockRoleSvc) DeleteById(ctx context.Context, id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+type MockAssignmentSvc struct {
+	mock.Mock
+}
+
+func (m *MockAssignmentSvc) FindByEmployee(ctx context.Context, employeeId int64) ([]assignment.Response, error) {
+	args := m.Called(employeeId)
+	return args.Get(0).([]assignment.Response), args.Error(1)
+}
+
+func (m *MockAssignmentSvc) FindByRole(ctx context.Context, roleId int64) ([]assignment.Response, error) {
+	args := m.Called(roleId)
+	return args.Get(0).([]assignment.Response), args.Error(1)
+}
+
+func (m *MockAssignmentSvc) GetAll(ctx context.Context) ([]assignment.Response, error) {
+	args := m.Called()
+	return args.Get(0).([]assignment.Response), args.Error(1)
+}
+
+func (m *MockAssignmentSvc) ReplaceMembers(ctx context.Context, request assignment.ReplaceMembersRequest) error {
+	args := m.Called(request)
+	return args.Error(0)
+}
+
+type MockLifecycleSvc struct {
+	mock.Mock
+}
+
+func (m *MockLifecycleSvc) Transition(ctx context.Context, request lifecycle.TransitionRequest) (lifecycle.StatusResponse, error) {
+	args := m.Called(request)
+	return args.Get(0).(lifecycle.StatusResponse), args.Error(1)
+}
+
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) CountUsers(ctx context.Context, where sqlCondition) (int, error) {
+	args := m.Called(where)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepo) FindUsers(ctx context.Context, where sqlCondition, offset int, limit int) ([]UserEntity, error) {
+	args := m.Called(where, offset, limit)
+	return args.Get(0).([]UserEntity), args.Error(1)
+}
+
+func (m *MockRepo) CountGroups(ctx context.Context, where sqlCondition) (int, error) {
+	args := m.Called(where)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepo) FindGroups(ctx context.Context, where sqlCondition, offset int, limit int) ([]GroupEntity, error) {
+	args := m.Called(where, offset, limit)
+	return args.Get(0).([]GroupEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindGroupsOfUsers(ctx context.Context, employeeIds []int64) ([]MemberEntity, error) {
+	args := m.Called(employeeIds)
+	return args.Get(0).([]MemberEntity), args.Error(1)
+}
+
+func (m *MockRepo) FindMembersOfGroups(ctx context.Context, roleIds []int64) ([]MemberEntity, error) {
+	args := m.Called(roleIds)
+	return args.Get(0).([]MemberEntity), args.Error(1)
+}
+
+func newTestService() (*Service, *MockEmployeeSvc, *MockRoleSvc, *MockAssignmentSvc, *MockLifecycleSvc) {
+	employees := &MockEmployeeSvc{}
+	roles := &MockRoleSvc{}
+	assignments := &MockAssignmentSvc{}
+	lifecycleSvc := &MockLifecycleSvc{}
+	return NewService(&MockRepo{}, employees, roles, assignments, lifecycleSvc), employees, roles, assignments, lifecycleSvc
+}
+
+func patch(operations ...PatchOperation) PatchRequest {
+	return PatchRequest{Schemas: []string{SchemaPatchOp}, Operations: operations}
+}
+
+func TestService_ListUsers(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should filter and paginate users in database", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		repo := svc.repo.(*MockRepo)
+		where := sqlCondition{clause: "lower(name) LIKE $1", args: []any{"john%"}}
+		repo.On("CountUsers", where).Return(2, nil)
+		repo.On("FindUsers", where, 0, 1).Return([]UserEntity{
+			{Id: 1, Name: "john doe", ExternalId: sql.NullString{String: "hr-1", Valid: true}, Status: lifecycle.StatusActive},
+		}, nil)
+		repo.On("FindGroupsOfUsers", []int64{1}).Return([]MemberEntity{
+			{EmployeeId: 1, EmployeeName: "john doe", RoleId: 10, RoleName: "admin"},
+		}, nil)
+
+		got, err := svc.ListUsers(context.Background(), ListQuery{Filter: `userName sw "John"`, StartIndex: 1, Count: 1})
+
+		a.Nil(err)
+		a.Equal(2, got.TotalResults)
+		a.Equal(1, got.ItemsPerPage)
+		a.Equal("1", got.Resources[0].Id)
+		a.Equal("john doe", got.Resources[0].UserName)
+		a.Equal("hr-1", got.Resources[0].ExternalId)
+		a.True(got.Resources[0].Active)
+		a.Equal([]MemberRef{{Value: "10", Display: "admin", Ref: "/scim/v2/Groups/10"}}, got.Resources[0].Groups)
+		a.Equal("/scim/v2/Users/1", got.Resources[0].Meta.Location)
+		employees.AssertNotCalled(t, "GetAll")
+		assignments.AssertNotCalled(t, "GetAll")
+	})
+
+	t.Run("should not load users beyond the last page", func(t *testing.T) {
+		svc, _, _, _, _ := newTestService()
+		repo := svc.repo.(*MockRepo)
+		where := sqlCondition{clause: "TRUE"}
+		repo.On("CountUsers", where).Return(3, nil)
+
+		got, err := svc.ListUsers(context.Background(), ListQuery{StartIndex: 5, Count: 10})
+
+		a.Nil(err)
+		a.Equal(3, got.TotalResults)
+		a.Equal(5, got.StartIndex)
+		a.Equal(0, got.ItemsPerPage)
+		a.Empty(got.Resources)
+		repo.AssertNotCalled(t, "FindUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should filter users by group", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		employees.On("GetAll").Return([]employee.Response{{Id: 1, Name: "john doe"}, {Id: 2, Name: "jane doe"}}, nil)
+		assignments.On("GetAll").Return([]assignment.Response{{EmployeeId: 2, RoleId: 10, RoleName: "admin"}}, nil)
+
+		got, err := svc.ListUsers(context.Background(), ListQuery{Filter: `groups[display eq "admin"]`, Count: defaultCount})
+
+		a.Nil(err)
+		a.Equal(1, got.TotalResults)
+		a.Equal("2", got.Resources[0].Id)
+	})
+
+	t.Run("should return invalidFilter error", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+
+		_, err := svc.ListUsers(context.Background(), ListQuery{Filter: `userName eq`})
+
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(ErrInvalidFilter, scimErr.ScimType)
+		employees.AssertNotCalled(t, "GetAll")
+	})
+}
+
+func TestService_GetUser(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should return user with groups", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe", Status: lifecycle.StatusActive}, nil)
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{{EmployeeId: 1, RoleId: 10, RoleName: "admin"}}, nil)
+
+		got, err := svc.GetUser(context.Background(), "1")
+
+		a.Nil(err)
+		a.Equal([]string{SchemaUser}, got.Schemas)
+		a.Equal("john doe", got.UserName)
+		a.True(got.Active)
+		a.Len(got.Groups, 1)
+	})
+
+	t.Run("should return not found for unknown or invalid id", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+		employees.On("FindById", int64(5)).Return(employee.Response{}, sql.ErrNoRows)
+
+		for _, id := range []string{"5", "abc", "-1"} {
+			_, err := svc.GetUser(context.Background(), id)
+
+			var scimErr *Error
+			a.True(errors.As(err, &scimErr), id)
+			a.Equal(404, scimErr.StatusCode(), id)
+		}
+	})
+}
+
+func TestService_CreateUser(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create employee", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		employees.On("Create", employee.CreateRequest{Name: "john doe"}).Return(int64(1), nil)
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe"}, nil)
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+
+		got, err := svc.CreateUser(context.Background(), UserRequest{Schemas: []string{SchemaUser}, UserName: "john doe"})
+
+		a.Nil(err)
+		a.Equal("1", got.Id)
+	})
+
+	t.Run("should reject user without userName", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+
+		_, err := svc.CreateUser(context.Background(), UserRequest{})
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(ErrInvalidValue, scimErr.ScimType)
+		employees.AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should suspend user created inactive", func(t *testing.T) {
+		svc, employees, _, assignments, lifecycleSvc := newTestService()
+		inactive := false
+		employees.On("Create", employee.CreateRequest{Name: "john doe"}).Return(int64(1), nil)
+		lifecycleSvc.On("Transition", lifecycle.TransitionRequest{
+			EmployeeId: 1, Status: lifecycle.StatusSuspended, Reason: "scim", Actor: "scim",
+		}).Return(lifecycle.StatusResponse{}, nil)
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe", Status: lifecycle.StatusSuspended}, nil)
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+
+		got, err := svc.CreateUser(context.Background(), UserRequest{UserName: "john doe", Active: &inactive})
+
+		a.Nil(err)
+		a.False(got.Active)
+		lifecycleSvc.AssertExpectations(t)
+	})
+
+	t.Run("should return employee service error", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+		existsErr := common.AlreadyExistsError{Message: "employee already exists"}
+		employees.On("Create", employee.CreateRequest{Name: "john doe"}).Return(int64(0), existsErr)
+
+		_, err := svc.CreateUser(context.Background(), UserRequest{UserName: "john doe"})
+
+		a.ErrorIs(err, existsErr)
+	})
+}
+
+func TestService_PatchUser(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should rename user", func(t *testing.T) {
+		svc, employees, _, assignments, lifecycleSvc := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe", Status: lifecycle.StatusActive}, nil).Once()
+		employees.On("Update", int64(1), employee.UpdateRequest{Name: "john smith"}).Return(employee.Response{Id: 1, Name: "john smith"}, nil)
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john smith"}, nil).Once()
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+
+		got, err := svc.PatchUser(context.Background(), "1", patch(
+			PatchOperation{Op: "Replace", Value: json.RawMessage(`{"userName": "john smith", "name.givenName": "John"}`)},
+			PatchOperation{Op: "add", Path: "active", Value: json.RawMessage(`"True"`)},
+		))
+
+		a.Nil(err)
+		a.Equal("john smith", got.UserName)
+		employees.AssertExpectations(t)
+		lifecycleSvc.AssertNotCalled(t, "Transition", mock.Anything)
+	})
+
+	t.Run("should not update unchanged user", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe"}, nil)
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+
+		_, err := svc.PatchUser(context.Background(), "1", patch(
+			PatchOperation{Op: "replace", Path: "displayName", Value: json.RawMessage(`"johnny"`)},
+		))
+
+		a.Nil(err)
+		employees.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should suspend deactivated user", func(t *testing.T) {
+		svc, employees, _, assignments, lifecycleSvc := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe", Status: lifecycle.StatusActive}, nil).Once()
+		lifecycleSvc.On("Transition", lifecycle.TransitionRequest{
+			EmployeeId: 1, Status: lifecycle.StatusSuspended, Reason: "scim", Actor: "scim",
+		}).Return(lifecycle.StatusResponse{}, nil)
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe", Status: lifecycle.StatusSuspended}, nil).Once()
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+
+		got, err := svc.PatchUser(context.Background(), "1", patch(
+			PatchOperation{Op: "replace", Path: "active", Value: json.RawMessage(`false`)},
+		))
+
+		a.Nil(err)
+		a.False(got.Active)
+		employees.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		lifecycleSvc.AssertExpectations(t)
+	})
+
+	t.Run("should reject request without PatchOp schema", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+
+		_, err := svc.PatchUser(context.Background(), "1", PatchRequest{Operations: []PatchOperation{{Op: "remove", Path: "title"}}})
+
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(ErrInvalidSyntax, scimErr.ScimType)
+		employees.AssertNotCalled(t, "FindById", mock.Anything)
+	})
+}
+
+func TestService_DeleteUser(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should delete existing user", func(t *testing.T) {
+		svc, employees, _, assignments, _ := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{Id: 1, Name: "john doe"}, nil)
+		assignments.On("FindByEmployee", int64(1)).Return([]assignment.Response{}, nil)
+		employees.On("DeleteById", int64(1)).Return(nil)
+
+		a.Nil(svc.DeleteUser(context.Background(), "1"))
+		employees.AssertExpectations(t)
+	})
+
+	t.Run("should return not found for unknown user", func(t *testing.T) {
+		svc, employees, _, _, _ := newTestService()
+		employees.On("FindById", int64(1)).Return(employee.Response{}, sql.ErrNoRows)
+
+		err := svc.DeleteUser(context.Background(), "1")
+
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(404, scimErr.StatusCode())
+		employees.AssertNotCalled(t, "DeleteById", mock.Anything)
+	})
+}
+
+func TestService_ListGroups(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should filter groups by displayName in database", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		repo := svc.repo.(*MockRepo)
+		where := sqlCondition{clause: "(lower(name) = $1 OR lower(name) LIKE $2)", args: []any{"viewer", `%100\%%`}}
+		repo.On("CountGroups", where).Return(2, nil)
+		repo.On("FindGroups", where, 0, defaultCount).Return([]GroupEntity{{Id: 1, Name: "viewer"}, {Id: 3, Name: "100% admin"}}, nil)
+		repo.On("FindMembersOfGroups", []int64{1, 3}).Return([]MemberEntity{
+			{EmployeeId: 7, EmployeeName: "john doe", RoleId: 3, RoleName: "100% admin"},
+		}, nil)
+
+		got, err := svc.ListGroups(context.Background(), ListQuery{Filter: `displayName eq "VIEWER" or displayName co "100%"`, Count: defaultCount})
+
+		a.Nil(err)
+		a.Equal(2, got.TotalResults)
+		a.Equal(2, got.ItemsPerPage)
+		a.Equal([]MemberRef{}, got.Resources[0].Members)
+		a.Equal([]MemberRef{{Value: "7", Display: "john doe", Ref: "/scim/v2/Users/7"}}, got.Resources[1].Members)
+		roles.AssertNotCalled(t, "GetAll")
+		assignments.AssertNotCalled(t, "GetAll")
+	})
+
+	t.Run("should filter groups by members in memory", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		roles.On("GetAll").Return([]role.Response{{Id: 2, Name: "viewer"}, {Id: 1, Name: "admin"}}, nil)
+		assignments.On("GetAll").Return([]assignment.Response{
+			{EmployeeId: 7, EmployeeName: "john doe", RoleId: 1, RoleName: "admin"},
+		}, nil)
+
+		got, err := svc.ListGroups(context.Background(), ListQuery{Filter: `members[value eq "7"] or displayName eq "VIEWER"`, Count: defaultCount})
+
+		a.Nil(err)
+		a.Equal(2, got.TotalResults)
+		a.Equal("1", got.Resources[0].Id)
+		a.Equal([]MemberRef{{Value: "7", Display: "john doe", Ref: "/scim/v2/Users/7"}}, got.Resources[0].Members)
+		a.Equal([]MemberRef{}, got.Resources[1].Members)
+		svc.repo.(*MockRepo).AssertNotCalled(t, "CountGroups", mock.Anything)
+	})
+}
+
+func TestService_CreateGroup(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should create role with members", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		roles.On("Create", role.CreateRequest{Name: "admin"}).Return(int64(1), nil)
+		assignments.On("ReplaceMembers", assignment.ReplaceMembersRequest{RoleId: 1, EmployeeIds: []int64{7, 8}}).Return(nil)
+		roles.On("FindById", int64(1)).Return(role.Response{Id: 1, Name: "admin"}, nil)
+		assignments.On("FindByRole", int64(1)).Return([]assignment.Response{
+			{EmployeeId: 7, EmployeeName: "john", RoleId: 1},
+			{EmployeeId: 8, EmployeeName: "jane", RoleId: 1},
+		}, nil)
+
+		got, err := svc.CreateGroup(context.Background(), GroupRequest{
+			DisplayName: "admin",
+			Members:     []MemberRef{{Value: "7"}, {Value: "8"}},
+		})
+
+		a.Nil(err)
+		a.Equal("admin", got.DisplayName)
+		a.Len(got.Members, 2)
+		assignments.AssertExpectations(t)
+	})
+
+	t.Run("should delete created role when members are not assigned", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		notFoundErr := common.NotFoundError{Message: "employee or role not found"}
+		roles.On("Create", role.CreateRequest{Name: "admin"}).Return(int64(1), nil)
+		assignments.On("ReplaceMembers", assignment.ReplaceMembersRequest{RoleId: 1, EmployeeIds: []int64{99}}).Return(notFoundErr)
+		roles.On("DeleteById", int64(1)).Return(nil)
+
+		_, err := svc.CreateGroup(context.Background(), GroupRequest{DisplayName: "admin", Members: []MemberRef{{Value: "99"}}})
+
+		a.ErrorIs(err, notFoundErr)
+		roles.AssertExpectations(t)
+	})
+
+	t.Run("should reject invalid member value", func(t *testing.T) {
+		svc, _, roles, _, _ := newTestService()
+
+		_, err := svc.CreateGroup(context.Background(), GroupRequest{DisplayName: "admin", Members: []MemberRef{{Value: "x"}}})
+
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(ErrInvalidValue, scimErr.ScimType)
+		roles.AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestService_PatchGroup(t *testing.T) {
+	var a = assert.New(t)
+	members := []assignment.Response{
+		{EmployeeId: 7, EmployeeName: "john", RoleId: 1},
+		{EmployeeId: 8, EmployeeName: "jane", RoleId: 1},
+	}
+
+	tests := []struct {
+		name       string
+		operations []PatchOperation
+		want       []int64
+	}{
+		{
+			name:       "add members",
+			operations: []PatchOperation{{Op: "add", Path: "members", Value: json.RawMessage(`[{"value": "8"}, {"value": "9"}]`)}},
+			want:       []int64{7, 8, 9},
+		},
+		{
+			name:       "remove member by filter",
+			operations: []PatchOperation{{Op: "remove", Path: `members[value eq "7"]`}},
+			want:       []int64{8},
+		},
+		{
+			name:       "remove members by value",
+			operations: []PatchOperation{{Op: "remove", Path: "members", Value: json.RawMessage(`[{"value": "8"}]`)}},
+			want:       []int64{7},
+		},
+		{
+			name:       "remove all members",
+			operations: []PatchOperation{{Op: "remove", Path: "members"}},
+			want:       []int64{},
+		},
+		{
+			name:       "replace members without path",
+			operations: []PatchOperation{{Op: "replace", Value: json.RawMessage(`{"members": [{"value": "9"}]}`)}},
+			want:       []int64{9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run("should "+tt.name, func(t *testing.T) {
+			svc, _, roles, assignments, _ := newTestService()
+			roles.On("FindById", int64(1)).Return(role.Response{Id: 1, Name: "admin"}, nil)
+			assignments.On("FindByRole", int64(1)).Return(members, nil)
+			assignments.On("ReplaceMembers", assignment.ReplaceMembersRequest{RoleId: 1, EmployeeIds: tt.want}).Return(nil)
+
+			_, err := svc.PatchGroup(context.Background(), "1", patch(tt.operations...))
+
+			a.Nil(err)
+			assignments.AssertExpectations(t)
+			roles.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("should rename group without changing members", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		roles.On("FindById", int64(1)).Return(role.Response{Id: 1, Name: "admin"}, nil)
+		assignments.On("FindByRole", int64(1)).Return(members, nil)
+		roles.On("Update", int64(1), role.UpdateRequest{Name: "admins"}).Return(role.Response{Id: 1, Name: "admins"}, nil)
+
+		_, err := svc.PatchGroup(context.Background(), "1", patch(
+			PatchOperation{Op: "replace", Path: "displayName", Value: json.RawMessage(`"admins"`)},
+			PatchOperation{Op: "add", Path: "members", Value: json.RawMessage(`{"value": "7"}`)},
+		))
+
+		a.Nil(err)
+		roles.AssertExpectations(t)
+		assignments.AssertNotCalled(t, "ReplaceMembers", mock.Anything)
+	})
+
+	t.Run("should reject removing displayName", func(t *testing.T) {
+		svc, _, roles, assignments, _ := newTestService()
+		roles.On("FindById", int64(1)).Return(role.Response{Id: 1, Name: "admin"}, nil)
+		assignments.On("FindByRole", int64(1)).Return(members, nil)
+
+		_, err := svc.PatchGroup(context.Background(), "1", patch(PatchOperation{Op: "remove", Path: "displayName"}))
+
+		var scimErr *Error
+		a.True(errors.As(err, &scimErr))
+		a.Equal(ErrMutability, scimErr.ScimType)
+	})
+}
+
+func TestService_ReplaceGroup(t *testing.T) {
+	var a = assert.New(t)
+	svc, _, roles, assignments, _ := newTestService()
+	roles.On("Update", int64(1), role.UpdateRequest{Name: "admins"}).Return(role.Response{Id: 1, Name: "admins"}, nil)
+	assignments.On("ReplaceMembers", assignment.ReplaceMembersRequest{RoleId: 1, EmployeeIds: []int64{}}).Return(nil)
+	roles.On("FindById", int64(1)).Return(role.Response{Id: 1, Name: "admins"}, nil)
+	assignments.On("FindByRole", int64(1)).Return([]assignment.Response{}, nil)
+
+	got, err := svc.ReplaceGroup(context.Background(), "1", GroupRequest{DisplayName: "admins"})
+
+	a.Nil(err)
+	a.Equal("admins", got.DisplayName)
+	a.Empty(got.Members)
+	assignments.AssertExpectations(t)
+}
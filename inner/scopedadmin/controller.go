@@ -0,0 +1,275 @@
+package scopedadmin
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+	"slices"
+	"strconv"
+)
+
+type Controller struct {
+	server             *web.Server
+	scopedAdminService Svc
+	logger             *common.Logger
+}
+
+// интерфейс сервиса scopedadmin.Service
+type Svc interface {
+	GetAll(ctx context.Context) ([]Response, error)
+	FindById(ctx context.Context, id int64) (Response, error)
+	Create(ctx context.Context, request CreateRequest, actor string) (Response, error)
+	Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error)
+	Delete(ctx context.Context, id int64, actor string) error
+}
+
+func NewController(server *web.Server, svc Svc, logger *common.Logger) *Controller {
+	return &Controller{
+		server:             server,
+		scopedAdminService: svc,
+		logger:             logger,
+	}
+}
+
+func (c *Controller) RegisterRoutes() {
+	c.server.GroupApiV1.Get("/scoped-admins", c.GetAllScopedAdmins)
+	c.server.GroupApiV1.Post("/scoped-admins", c.CreateScopedAdmin)
+	c.server.GroupApiV1.Get("/scoped-admins/:id", c.GetScopedAdmin)
+	c.server.GroupApiV1.Put("/scoped-admins/:id", c.UpdateScopedAdmin)
+	c.server.GroupApiV1.Delete("/scoped-admins/:id", c.DeleteScopedAdmin)
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/scoped-admins"
+// @Description Get all scoped admins.
+// @Summary get all scoped admins
+// @ID get-all-scoped-admins
+// @Tags scoped-admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} common.Response[[]scopedadmin.Response]
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /scoped-admins [get]
+func (c *Controller) GetAllScopedAdmins(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	// вызываем метод GetAll сервиса scopedadmin.Service
+	response, err := c.scopedAdminService.GetAll(ctx.Context())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all scoped admins", zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get all scoped admins", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при GET запросе по маршруту "/api/v1/scoped-admins/:id"
+// @Description Get scoped admin by id.
+// @Summary get scoped admin
+// @ID get-scoped-admin
+// @Tags scoped-admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id scoped admin"
+// @Success 200 {object} common.Response[scopedadmin.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /scoped-admins/{id} [get]
+func (c *Controller) GetScopedAdmin(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid scoped admin id")
+	}
+
+	// вызываем метод FindById сервиса scopedadmin.Service
+	response, err := c.scopedAdminService.FindById(ctx.Context(), id)
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get scoped admin", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "get scoped admin", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при POST запросе по маршруту "/api/v1/scoped-admins"
+// @Description Grant scoped admin rights: the employee with IDM_SCOPED_ADMIN role may create employees
+// @Description of org_unit and its subunits ("IT/Backend" for "IT") and assign them the listed roles.
+// @Summary create scoped admin
+// @ID create-scoped-admin
+// @Tags scoped-admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body scopedadmin.CreateRequest true "scoped admin"
+// @Success 200 {object} common.Response[scopedadmin.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /scoped-admins [post]
+func (c *Controller) CreateScopedAdmin(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	var request CreateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "create scoped admin: received request", zap.Any("request", request))
+
+	// вызываем метод Create сервиса scopedadmin.Service
+	response, err := c.scopedAdminService.Create(ctx.Context(), request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create scoped admin", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "create scoped admin", zap.Any("request", request), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при PUT запросе по маршруту "/api/v1/scoped-admins/:id"
+// @Description Replace org unit and allowed roles of scoped admin.
+// @Summary update scoped admin
+// @ID update-scoped-admin
+// @Tags scoped-admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id scoped admin"
+// @Param request body scopedadmin.UpdateRequest true "scoped admin"
+// @Success 200 {object} common.Response[scopedadmin.Response]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 409 {object} common.Problem
+// @Failure 422 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /scoped-admins/{id} [put]
+func (c *Controller) UpdateScopedAdmin(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid scoped admin id")
+	}
+	var request UpdateRequest
+	if err := ctx.BodyParser(&request); err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, err.Error())
+	}
+	c.logger.DebugCtx(ctx.Context(), "update scoped admin: received request", zap.Int64("id", id), zap.Any("request", request))
+
+	// вызываем метод Update сервиса scopedadmin.Service
+	response, err := c.scopedAdminService.Update(ctx.Context(), id, request, claims.Actor())
+	if err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update scoped admin", zap.Int64("id", id), zap.Any("request", request), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, response); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "update scoped admin", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// функция-хендлер, которая будет вызываться при DELETE запросе по маршруту "/api/v1/scoped-admins/:id"
+// @Description Revoke scoped admin rights. Roles already assigned by the scoped admin are kept.
+// @Summary delete scoped admin
+// @ID delete-scoped-admin
+// @Tags scoped-admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int64 true "id scoped admin"
+// @Success 200 {object} common.Response[int64]
+// @Failure 400 {object} common.Problem
+// @Failure 401 {object} common.Problem
+// @Failure 403 {object} common.Problem
+// @Failure 404 {object} common.Problem
+// @Failure 500 {object} common.Problem
+// @Router /scoped-admins/{id} [delete]
+func (c *Controller) DeleteScopedAdmin(ctx *fiber.Ctx) error {
+
+	// проверяем наличие нужной роли в токене
+	claims, err := web.GetClaims(ctx)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusUnauthorized, err.Error())
+	}
+	if !slices.Contains(claims.RealmAccess.Roles, web.IdmAdmin) {
+		return common.ErrResponse(ctx, fiber.StatusForbidden, "Permission denied")
+	}
+
+	id, err := strconv.ParseInt(ctx.Params("id"), 10, 64)
+	if err != nil {
+		return common.ErrResponse(ctx, fiber.StatusBadRequest, "invalid scoped admin id")
+	}
+
+	// вызываем метод Delete сервиса scopedadmin.Service
+	if err := c.scopedAdminService.Delete(ctx.Context(), id, claims.Actor()); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete scoped admin", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+
+	if err := common.OkResponse(ctx, struct{}{}); err != nil {
+		c.logger.ErrorCtx(ctx.Context(), "delete scoped admin", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
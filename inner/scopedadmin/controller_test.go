@@ -0,0 +1,103 @@
+package scopedadmin
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"github.com/nihrom205/idm/inner/web/webtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Объявляем структуру мока сервиса scopedadmin.Service
+type MockService struct {
+	mock.Mock
+}
+
+func (svc *MockService) GetAll(ctx context.Context) ([]Response, error) {
+	args := svc.Called()
+	return args.Get(0).([]Response), args.Error(1)
+}
+
+func (svc *MockService) FindById(ctx context.Context, id int64) (Response, error) {
+	args := svc.Called(id)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Create(ctx context.Context, request CreateRequest, actor string) (Response, error) {
+	args := svc.Called(request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (Response, error) {
+	args := svc.Called(id, request, actor)
+	return args.Get(0).(Response), args.Error(1)
+}
+
+func (svc *MockService) Delete(ctx context.Context, id int64, actor string) error {
+	args := svc.Called(id, actor)
+	return args.Error(0)
+}
+
+func newTestServer(svc Svc, roles ...string) *web.Server {
+	server, logger := webtest.NewServer(webtest.Claims("admin", roles...))
+	NewController(server, svc, logger).RegisterRoutes()
+	return server
+}
+
+func TestController_CreateScopedAdmin(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should grant scoped admin rights with actor from token", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmAdmin)
+		request := CreateRequest{EmployeeId: 5, OrgUnit: "IT", RoleIds: []int64{2}}
+		svc.On("Create", request, "admin").Return(Response{Id: 1, EmployeeId: 5, OrgUnit: "IT", RoleIds: []int64{2}}, nil)
+
+		body := strings.NewReader(`{"employee_id": 5, "org_unit": "IT", "role_ids": [2]}`)
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/scoped-admins", body)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		var got common.Response[Response]
+		data, _ := io.ReadAll(resp.Body)
+		a.Nil(json.Unmarshal(data, &got))
+		a.Equal(int64(1), got.Data.Id)
+		svc.AssertExpectations(t)
+	})
+
+	t.Run("should return 403 for scoped admin", func(t *testing.T) {
+		svc := &MockService{}
+		server := newTestServer(svc, web.IdmScopedAdmin)
+
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/scoped-admins", strings.NewReader(`{"employee_id": 5}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := server.App.Test(req)
+
+		a.Nil(err)
+		a.Equal(http.StatusForbidden, resp.StatusCode)
+		svc.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+}
+
+func TestController_DeleteScopedAdmin(t *testing.T) {
+	var a = assert.New(t)
+	svc := &MockService{}
+	server := newTestServer(svc, web.IdmAdmin)
+	svc.On("Delete", int64(5), "admin").Return(common.NotFoundError{Message: "scoped admin with id 5 not found"})
+
+	resp, err := server.App.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/scoped-admins/5", nil))
+
+	a.Nil(err)
+	a.Equal(http.StatusNotFound, resp.StatusCode)
+	svc.AssertExpectations(t)
+}
@@ -0,0 +1,41 @@
+package scopedadmin
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// Entity права администратора отдела: сотрудник employee_id создаёт сотрудников отдела org_unit
+// и его подотделов и назначает им роли role_ids
+type Entity struct {
+	Id         int64         `db:"id"`
+	EmployeeId int64         `db:"employee_id"`
+	OrgUnit    string        `db:"org_unit"`
+	RoleIds    pq.Int64Array `db:"role_ids"`
+	CreateAt   time.Time     `db:"create_at"`
+	UpdateAt   time.Time     `db:"update_at"`
+}
+
+func (e *Entity) toResponse() Response {
+	roleIds := []int64(e.RoleIds)
+	if roleIds == nil {
+		roleIds = []int64{}
+	}
+	return Response{
+		Id:         e.Id,
+		EmployeeId: e.EmployeeId,
+		OrgUnit:    e.OrgUnit,
+		RoleIds:    roleIds,
+		CreateAt:   e.CreateAt,
+		UpdateAt:   e.UpdateAt,
+	}
+}
+
+type Response struct {
+	Id         int64     `json:"id"`
+	EmployeeId int64     `json:"employee_id"`
+	OrgUnit    string    `json:"org_unit"`
+	RoleIds    []int64   `json:"role_ids"`
+	CreateAt   time.Time `json:"create_at"`
+	UpdateAt   time.Time `json:"update_at"`
+}
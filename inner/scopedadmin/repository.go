@@ -0,0 +1,118 @@
+package scopedadmin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nihrom205/idm/inner/common"
+)
+
+// код ошибки Postgres при нарушении внешнего ключа
+const foreignKeyViolation = "23503"
+
+// администраторы отделов вместе с разрешёнными ролями
+const selectScopedAdmins = `SELECT a.id, a.employee_id, a.org_unit, a.create_at, a.update_at,
+COALESCE(array_agg(ar.role_id ORDER BY ar.role_id) FILTER (WHERE ar.role_id IS NOT NULL), '{}') AS role_ids
+FROM scoped_admin a LEFT JOIN scoped_admin_role ar ON ar.scoped_admin_id = a.id`
+
+type Repository struct {
+	db *sqlx.DB
+}
+
+func NewScopedAdminRepository(db *sqlx.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// запрос транзакции у БД
+func (r *Repository) BeginTransaction() (*sqlx.Tx, error) {
+	return r.db.Beginx()
+}
+
+// найти всех администраторов отделов
+func (r *Repository) GetAll(ctx context.Context) (admins []Entity, err error) {
+	query := selectScopedAdmins + " GROUP BY a.id ORDER BY a.id"
+	err = r.db.SelectContext(ctx, &admins, query)
+	return admins, err
+}
+
+// найти администратора отдела по id
+func (r *Repository) FindById(ctx context.Context, id int64) (admin Entity, err error) {
+	query := selectScopedAdmins + " WHERE a.id = $1 GROUP BY a.id"
+	err = r.db.GetContext(ctx, &admin, query, id)
+	return admin, err
+}
+
+// найти администратора отдела по id в рамках транзакции
+func (r *Repository) FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (admin Entity, err error) {
+	query := selectScopedAdmins + " WHERE a.id = $1 GROUP BY a.id"
+	err = tx.GetContext(ctx, &admin, query, id)
+	return admin, err
+}
+
+// FindBySubject права администратора отделов у активного сотрудника, связанного с пользователем Keycloak
+func (r *Repository) FindBySubject(ctx context.Context, subject string) (admins []Entity, err error) {
+	query := selectScopedAdmins + ` JOIN employee e ON e.id = a.employee_id
+WHERE e.subject = $1 AND e.status = 'active' GROUP BY a.id ORDER BY a.id`
+	err = r.db.SelectContext(ctx, &admins, query, subject)
+	return admins, err
+}
+
+// FindEmployeeOrgUnit отдел сотрудника; NULL, если отдел не указан
+func (r *Repository) FindEmployeeOrgUnit(ctx context.Context, employeeId int64) (orgUnit sql.NullString, err error) {
+	err = r.db.GetContext(ctx, &orgUnit, "SELECT org_unit FROM employee WHERE id = $1", employeeId)
+	return orgUnit, err
+}
+
+// проверка, есть ли у сотрудника права администратора отдела в рамках транзакции
+func (r *Repository) ExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, orgUnit string) (isExists bool, err error) {
+	query := "SELECT EXISTS(SELECT * FROM scoped_admin WHERE employee_id = $1 AND org_unit = $2)"
+	err = tx.GetContext(ctx, &isExists, query, employeeId, orgUnit)
+	return isExists, err
+}
+
+// добавить администратора отдела в рамках транзакции
+func (r *Repository) CreateTx(ctx context.Context, tx *sqlx.Tx, admin Entity) (id int64, err error) {
+	query := "INSERT INTO scoped_admin (employee_id, org_unit) VALUES ($1, $2) RETURNING id"
+	err = tx.GetContext(ctx, &id, query, admin.EmployeeId, admin.OrgUnit)
+	return id, mapError(err, "employee not found")
+}
+
+// изменить отдел администратора в рамках транзакции
+func (r *Repository) UpdateTx(ctx context.Context, tx *sqlx.Tx, admin Entity) error {
+	query := "UPDATE scoped_admin SET org_unit = $1, update_at = now() WHERE id = $2"
+	_, err := tx.ExecContext(ctx, query, admin.OrgUnit, admin.Id)
+	return err
+}
+
+// удалить администратора отдела в рамках транзакции; false, если его нет
+func (r *Repository) DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM scoped_admin WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// заменить разрешённые роли администратора отдела в рамках транзакции
+func (r *Repository) ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM scoped_admin_role WHERE scoped_admin_id = $1", id); err != nil {
+		return err
+	}
+	query := `INSERT INTO scoped_admin_role (scoped_admin_id, role_id)
+SELECT $1, role_id FROM unnest($2::bigint[]) AS role_id
+ON CONFLICT DO NOTHING`
+	_, err := tx.ExecContext(ctx, query, id, pq.Int64Array(roleIds))
+	return mapError(err, "role not found")
+}
+
+// mapError переводит нарушение внешнего ключа в NotFoundError
+func mapError(err error, message string) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return common.NotFoundError{Message: message}
+	}
+	return err
+}
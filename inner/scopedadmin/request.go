@@ -0,0 +1,20 @@
+package scopedadmin
+
+type CreateRequest struct {
+	EmployeeId int64   `json:"employee_id" validate:"required,gt=0"`
+	OrgUnit    string  `json:"org_unit" validate:"required,max=155"`
+	RoleIds    []int64 `json:"role_ids" validate:"dive,gt=0"`
+}
+
+func (r *CreateRequest) ToEntity() Entity {
+	return Entity{
+		EmployeeId: r.EmployeeId,
+		OrgUnit:    r.OrgUnit,
+	}
+}
+
+// UpdateRequest заменяет отдел и роли администратора отдела целиком
+type UpdateRequest struct {
+	OrgUnit string  `json:"org_unit" validate:"required,max=155"`
+	RoleIds []int64 `json:"role_ids" validate:"dive,gt=0"`
+}
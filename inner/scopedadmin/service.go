@@ -0,0 +1,398 @@
+package scopedadmin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"slices"
+	"strings"
+)
+
+type Repo interface {
+	BeginTransaction() (*sqlx.Tx, error)
+	GetAll(ctx context.Context) ([]Entity, error)
+	FindById(ctx context.Context, id int64) (Entity, error)
+	FindByIdTx(ctx context.Context, tx *sqlx.Tx, id int64) (Entity, error)
+	FindBySubject(ctx context.Context, subject string) ([]Entity, error)
+	FindEmployeeOrgUnit(ctx context.Context, employeeId int64) (sql.NullString, error)
+	ExistsTx(ctx context.Context, tx *sqlx.Tx, employeeId int64, orgUnit string) (bool, error)
+	CreateTx(ctx context.Context, tx *sqlx.Tx, admin Entity) (int64, error)
+	UpdateTx(ctx context.Context, tx *sqlx.Tx, admin Entity) error
+	DeleteTx(ctx context.Context, tx *sqlx.Tx, id int64) (bool, error)
+	ReplaceRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64) error
+}
+
+// AuditRepo журнал аудита, записи пишутся в транзакции изменения
+type AuditRepo interface {
+	CreateTx(ctx context.Context, tx *sqlx.Tx, entry audit.Entry) error
+}
+
+// AccessSvc фактический доступ сотрудника: права администратора отдела действуют, пока он активный сотрудник
+type AccessSvc interface {
+	Resolve(ctx context.Context, employeeId int64) (access.Response, error)
+}
+
+type Validator interface {
+	Validate(request any) error
+}
+
+type Service struct {
+	repo      Repo
+	audit     AuditRepo
+	access    AccessSvc
+	validator Validator
+}
+
+func NewService(repo Repo, audit AuditRepo, access AccessSvc, validator Validator) *Service {
+	return &Service{
+		repo:      repo,
+		audit:     audit,
+		access:    access,
+		validator: validator,
+	}
+}
+
+func (s *Service) GetAll(ctx context.Context) ([]Response, error) {
+	admins, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return []Response{}, fmt.Errorf("error getting all scoped admins: %w", err)
+	}
+	response := make([]Response, 0, len(admins))
+	for _, item := range admins {
+		response = append(response, item.toResponse())
+	}
+	return response, nil
+}
+
+func (s *Service) FindById(ctx context.Context, id int64) (Response, error) {
+	admin, err := s.repo.FindById(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Response{}, common.NotFoundError{Message: fmt.Sprintf("scoped admin with id %d not found", id)}
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding scoped admin with id %d: %w", id, err)
+	}
+	return admin.toResponse(), nil
+}
+
+// Create выдаёт сотруднику права администратора отдела
+func (s *Service) Create(ctx context.Context, request CreateRequest, actor string) (response Response, err error) {
+	request.OrgUnit = strings.TrimSpace(request.OrgUnit)
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("creating scoped admin panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("creating scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("creating scoped admin: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	isExist, err := s.repo.ExistsTx(ctx, tx, request.EmployeeId, request.OrgUnit)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding scoped admin of employee %d: %w", request.EmployeeId, err)
+	}
+	if isExist {
+		err = common.AlreadyExistsError{Message: fmt.Sprintf("employee %d is already admin of org unit %s",
+			request.EmployeeId, request.OrgUnit)}
+		return Response{}, err
+	}
+	id, err := s.repo.CreateTx(ctx, tx, request.ToEntity())
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating scoped admin: %w", err)
+	}
+	response, err = s.saveRolesTx(ctx, tx, id, request.RoleIds, actor, "scoped_admin.created")
+	return response, err
+}
+
+// Update заменяет отдел и разрешённые роли администратора отдела
+func (s *Service) Update(ctx context.Context, id int64, request UpdateRequest, actor string) (response Response, err error) {
+	request.OrgUnit = strings.TrimSpace(request.OrgUnit)
+	if err = s.validator.Validate(request); err != nil {
+		return Response{}, common.NewRequestValidatorError(err)
+	}
+
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return Response{}, fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("updating scoped admin panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("updating scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("updating scoped admin: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	admin, err := s.repo.FindByIdTx(ctx, tx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = common.NotFoundError{Message: fmt.Sprintf("scoped admin with id %d not found", id)}
+		return Response{}, err
+	}
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding scoped admin with id %d: %w", id, err)
+	}
+	if admin.OrgUnit != request.OrgUnit {
+		isExist, err := s.repo.ExistsTx(ctx, tx, admin.EmployeeId, request.OrgUnit)
+		if err != nil {
+			return Response{}, fmt.Errorf("error finding scoped admin of employee %d: %w", admin.EmployeeId, err)
+		}
+		if isExist {
+			return Response{}, common.AlreadyExistsError{Message: fmt.Sprintf("employee %d is already admin of org unit %s",
+				admin.EmployeeId, request.OrgUnit)}
+		}
+	}
+
+	admin.OrgUnit = request.OrgUnit
+	if err = s.repo.UpdateTx(ctx, tx, admin); err != nil {
+		return Response{}, fmt.Errorf("error updating scoped admin with id %d: %w", id, err)
+	}
+	response, err = s.saveRolesTx(ctx, tx, id, request.RoleIds, actor, "scoped_admin.updated")
+	return response, err
+}
+
+// Delete отзывает права администратора отдела
+func (s *Service) Delete(ctx context.Context, id int64, actor string) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("deleting scoped admin panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("deleting scoped admin: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("deleting scoped admin: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	deleted, err := s.repo.DeleteTx(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("error deleting scoped admin with id %d: %w", id, err)
+	}
+	if !deleted {
+		err = common.NotFoundError{Message: fmt.Sprintf("scoped admin with id %d not found", id)}
+		return err
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     "scoped_admin.deleted",
+		EntityType: "scoped_admin",
+		EntityId:   id,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// AuthorizeOrgUnit разрешает создавать сотрудников отдела orgUnit администратору
+// и администратору отдела, которому принадлежит orgUnit. Реализует employee.Authorizer
+func (s *Service) AuthorizeOrgUnit(ctx context.Context, principal common.Principal, orgUnit string) error {
+	if principal.Admin {
+		return nil
+	}
+	admins, err := s.findByPrincipal(ctx, principal)
+	if err != nil {
+		return err
+	}
+	for _, admin := range admins {
+		if covers(admin.OrgUnit, orgUnit) {
+			return nil
+		}
+	}
+	return s.deny(ctx, principal, "employee.create", 0, map[string]any{"org_unit": orgUnit})
+}
+
+// AuthorizeRole разрешает назначать и отзывать роль roleId у сотрудника администратору и администратору
+// отдела сотрудника, которому разрешена эта роль. Реализует assignment.Authorizer
+func (s *Service) AuthorizeRole(ctx context.Context, principal common.Principal, employeeId int64, roleId int64) error {
+	if principal.Admin {
+		return nil
+	}
+	admins, err := s.findByPrincipal(ctx, principal)
+	if err != nil {
+		return err
+	}
+	details := map[string]any{"role_id": roleId}
+	if len(admins) == 0 {
+		return s.deny(ctx, principal, "employee_role.change", employeeId, details)
+	}
+	orgUnit, err := s.repo.FindEmployeeOrgUnit(ctx, employeeId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("error finding org unit of employee %d: %w", employeeId, err)
+	}
+	// несуществующий сотрудник и сотрудник без отдела не входят ни в один отдел
+	details["org_unit"] = orgUnit.String
+	for _, admin := range admins {
+		if covers(admin.OrgUnit, orgUnit.String) && slices.Contains(admin.RoleIds, roleId) {
+			return nil
+		}
+	}
+	return s.deny(ctx, principal, "employee_role.change", employeeId, details)
+}
+
+// findByPrincipal права администратора отделов вызывающего. Статус сотрудника берётся из его фактического
+// доступа: у приостановленного или уволенного администратора прав нет, даже если они ещё не отозваны
+func (s *Service) findByPrincipal(ctx context.Context, principal common.Principal) ([]Entity, error) {
+	if principal.Subject == "" {
+		return nil, nil
+	}
+	admins, err := s.repo.FindBySubject(ctx, principal.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("error finding scoped admins with subject %s: %w", principal.Subject, err)
+	}
+	if len(admins) == 0 {
+		return nil, nil
+	}
+	// subject связан с одним сотрудником, поэтому все права принадлежат ему
+	resolved, err := s.access.Resolve(ctx, admins[0].EmployeeId)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving access of employee %d: %w", admins[0].EmployeeId, err)
+	}
+	if resolved.Status != lifecycle.StatusActive {
+		return nil, nil
+	}
+	return admins, nil
+}
+
+// deny записывает отказ в журнал аудита и возвращает ForbiddenError
+func (s *Service) deny(ctx context.Context, principal common.Principal, operation string, employeeId int64, details map[string]any) error {
+	details["operation"] = operation
+	if err := s.writeDenial(ctx, principal, employeeId, details); err != nil {
+		return err
+	}
+	return common.ForbiddenError{Message: fmt.Sprintf("permission denied: %s is out of admin scope", operation)}
+}
+
+// writeDenial пишет запись об отказе в отдельной транзакции: запрещённая операция ничего не изменяет
+func (s *Service) writeDenial(ctx context.Context, principal common.Principal, employeeId int64, details map[string]any) (err error) {
+	tx, err := s.repo.BeginTransaction()
+	if err != nil {
+		return fmt.Errorf("error creating transaction: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("writing denial audit panic: %v", r)
+			// если была паника, то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("writing denial audit: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else if err != nil {
+			// если произошла другая ошибка (не паника), то откатываем транзакцию
+			errTx := tx.Rollback()
+			if errTx != nil {
+				err = fmt.Errorf("writing denial audit: rolling back transaction errors: %w, %w", err, errTx)
+			}
+		} else {
+			// если ошибок нет, то коммитим транзакцию
+			errTx := tx.Commit()
+			if errTx != nil {
+				err = fmt.Errorf("writing denial audit: commiting transaction error: %w", errTx)
+			}
+		}
+	}()
+
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      principal.Actor,
+		Action:     "scoped_admin.denied",
+		EntityType: "employee",
+		EntityId:   employeeId,
+		Details:    details,
+	})
+	if err != nil {
+		return fmt.Errorf("error writing audit: %w", err)
+	}
+	return nil
+}
+
+// saveRolesTx сохраняет разрешённые роли администратора отдела и пишет аудит
+func (s *Service) saveRolesTx(ctx context.Context, tx *sqlx.Tx, id int64, roleIds []int64, actor string, action string) (Response, error) {
+	if err := s.repo.ReplaceRolesTx(ctx, tx, id, roleIds); err != nil {
+		return Response{}, fmt.Errorf("error saving roles of scoped admin %d: %w", id, err)
+	}
+	admin, err := s.repo.FindByIdTx(ctx, tx, id)
+	if err != nil {
+		return Response{}, fmt.Errorf("error finding scoped admin with id %d: %w", id, err)
+	}
+	err = s.audit.CreateTx(ctx, tx, audit.Entry{
+		Actor:      actor,
+		Action:     action,
+		EntityType: "scoped_admin",
+		EntityId:   id,
+		Details:    admin.toResponse(),
+	})
+	if err != nil {
+		return Response{}, fmt.Errorf("error writing audit: %w", err)
+	}
+	return admin.toResponse(), nil
+}
+
+// covers проверяет, входит ли отдел orgUnit в отдел scope: подотделы записываются через "/", например "IT/Backend"
+func covers(scope string, orgUnit string) bool {
+	if orgUnit == "" {
+		return false
+	}
+	return orgUnit == scope || strings.HasPrefix(orgUnit, scope+"/")
+}
@@ -0,0 +1,240 @@
+package scopedadmin
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/access"
+	"github.com/nihrom205/idm/inner/audit"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/common/validator"
+	"github.com/nihrom205/idm/inner/lifecycle"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var (
+	existsQuery      = regexp.QuoteMeta("SELECT EXISTS(SELECT * FROM scoped_admin WHERE employee_id = $1 AND org_unit = $2)")
+	insertQuery      = regexp.QuoteMeta("INSERT INTO scoped_admin (employee_id, org_unit) VALUES ($1, $2) RETURNING id")
+	deleteQuery      = regexp.QuoteMeta("DELETE FROM scoped_admin WHERE id = $1")
+	deleteRolesQuery = regexp.QuoteMeta("DELETE FROM scoped_admin_role WHERE scoped_admin_id = $1")
+	insertRolesQuery = regexp.QuoteMeta("INSERT INTO scoped_admin_role (scoped_admin_id, role_id)")
+	findQuery        = regexp.QuoteMeta(selectScopedAdmins + " WHERE a.id = $1 GROUP BY a.id")
+	subjectQuery     = regexp.QuoteMeta(selectScopedAdmins + " JOIN employee e ON e.id = a.employee_id")
+	orgUnitQuery     = regexp.QuoteMeta("SELECT org_unit FROM employee WHERE id = $1")
+	auditQuery       = regexp.QuoteMeta("INSERT INTO audit_log (actor, action, entity_type, entity_id, details) VALUES ($1, $2, $3, $4, $5)")
+	adminColumns     = []string{"id", "employee_id", "org_unit", "create_at", "update_at", "role_ids"}
+	scopedPrincipal  = common.Principal{Subject: "kc-1", Actor: "kc-1"}
+)
+
+// accessStub статусы сотрудников в их фактическом доступе; не указанный сотрудник активен
+type accessStub map[int64]string
+
+func (s accessStub) Resolve(ctx context.Context, employeeId int64) (access.Response, error) {
+	status, ok := s[employeeId]
+	if !ok {
+		status = lifecycle.StatusActive
+	}
+	return access.Response{EmployeeId: employeeId, Status: status}, nil
+}
+
+func newTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	sqlxDb := sqlx.NewDb(db, "sqlmock")
+	return NewService(NewScopedAdminRepository(sqlxDb), audit.NewAuditRepository(sqlxDb), accessStub{},
+		validator.NewValidator()), mock
+}
+
+func TestService_Create(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should grant scoped admin rights with allowed roles", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs(int64(5), "IT").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectQuery(insertQuery).WithArgs(int64(5), "IT").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+		mock.ExpectExec(deleteRolesQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(insertRolesQuery).WithArgs(int64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(findQuery).WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2,3}"))
+		mock.ExpectExec(auditQuery).WithArgs("admin", "scoped_admin.created", "scoped_admin", int64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		got, err := srv.Create(context.Background(), CreateRequest{EmployeeId: 5, OrgUnit: " IT ", RoleIds: []int64{2, 3}}, "admin")
+
+		a.Nil(err)
+		a.Equal(Response{Id: 1, EmployeeId: 5, OrgUnit: "IT", RoleIds: []int64{2, 3}, CreateAt: now, UpdateAt: now}, got)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return AlreadyExistsError for granted org unit", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(existsQuery).WithArgs(int64(5), "IT").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		_, err := srv.Create(context.Background(), CreateRequest{EmployeeId: 5, OrgUnit: "IT"}, "admin")
+
+		var existsErr common.AlreadyExistsError
+		a.True(errors.As(err, &existsErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return validation error without org unit", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		_, err := srv.Create(context.Background(), CreateRequest{EmployeeId: 5, OrgUnit: "  "}, "admin")
+
+		var validationErr common.RequestValidatorError
+		a.True(errors.As(err, &validationErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	var a = assert.New(t)
+
+	t.Run("should return NotFoundError for unknown scoped admin", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec(deleteQuery).WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := srv.Delete(context.Background(), 1, "admin")
+
+		var notFoundErr common.NotFoundError
+		a.True(errors.As(err, &notFoundErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_AuthorizeOrgUnit(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should allow admin without checking grants", func(t *testing.T) {
+		srv, mock := newTestService(t)
+
+		err := srv.AuthorizeOrgUnit(context.Background(), common.Principal{Actor: "admin", Admin: true}, "HR")
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should allow subunit of granted org unit", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2}"))
+
+		err := srv.AuthorizeOrgUnit(context.Background(), scopedPrincipal, "IT/Backend")
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should deny other org unit and write audit", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2}"))
+		mock.ExpectBegin()
+		mock.ExpectExec(auditQuery).WithArgs("kc-1", "scoped_admin.denied", "employee", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := srv.AuthorizeOrgUnit(context.Background(), scopedPrincipal, "ITSM")
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should deny scoped admin who is not active employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		srv.access = accessStub{5: lifecycle.StatusSuspended}
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2}"))
+		mock.ExpectBegin()
+		mock.ExpectExec(auditQuery).WithArgs("kc-1", "scoped_admin.denied", "employee", nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := srv.AuthorizeOrgUnit(context.Background(), scopedPrincipal, "IT")
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_AuthorizeRole(t *testing.T) {
+	var a = assert.New(t)
+	now := time.Now()
+
+	t.Run("should allow whitelisted role in granted org unit", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2,3}"))
+		mock.ExpectQuery(orgUnitQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"org_unit"}).AddRow("IT"))
+
+		err := srv.AuthorizeRole(context.Background(), scopedPrincipal, 7, 3)
+
+		a.Nil(err)
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should deny role that is not whitelisted", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2,3}"))
+		mock.ExpectQuery(orgUnitQuery).WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"org_unit"}).AddRow("IT"))
+		mock.ExpectBegin()
+		mock.ExpectExec(auditQuery).WithArgs("kc-1", "scoped_admin.denied", "employee", int64(7), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := srv.AuthorizeRole(context.Background(), scopedPrincipal, 7, 1)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should deny unknown employee", func(t *testing.T) {
+		srv, mock := newTestService(t)
+		mock.ExpectQuery(subjectQuery).WithArgs("kc-1").
+			WillReturnRows(sqlmock.NewRows(adminColumns).AddRow(1, 5, "IT", now, now, "{2,3}"))
+		mock.ExpectQuery(orgUnitQuery).WithArgs(int64(7)).WillReturnError(sql.ErrNoRows)
+		mock.ExpectBegin()
+		mock.ExpectExec(auditQuery).WithArgs("kc-1", "scoped_admin.denied", "employee", int64(7), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := srv.AuthorizeRole(context.Background(), scopedPrincipal, 7, 2)
+
+		var forbiddenErr common.ForbiddenError
+		a.True(errors.As(err, &forbiddenErr))
+		a.NoError(mock.ExpectationsWereMet())
+	})
+}
+
+func TestCovers(t *testing.T) {
+	var a = assert.New(t)
+
+	a.True(covers("IT", "IT"))
+	a.True(covers("IT", "IT/Backend/Go"))
+	a.False(covers("IT", "ITSM"))
+	a.False(covers("IT/Backend", "IT"))
+	a.False(covers("IT", ""))
+}
@@ -1,21 +1,32 @@
 package web
 
 import (
+	"errors"
 	jwtMiddleware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/nihrom205/idm/inner/common"
 	"go.uber.org/zap"
+	"slices"
 )
 
 const (
 	JwtKey   = "jwt"
 	IdmAdmin = "IDM_ADMIN"
 	IdmUser  = "IDM_USER"
+	// администратор отдела: права ограничены выданными ему отделами и ролями
+	IdmScopedAdmin = "IDM_SCOPED_ADMIN"
+	// дежурный, который может включить себе экстренный доступ
+	IdmBreakGlass = "IDM_BREAK_GLASS"
 )
 
 type IdmClaims struct {
 	RealmAccess RealmAccessClaims `json:"realm_access"`
+	// client id приложения, которому выдан токен
+	AuthorizedParty string `json:"azp"`
+	// имя пользователя, используется при автоматическом создании сотрудника
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
 	jwt.RegisteredClaims
 }
 
@@ -23,6 +34,37 @@ type RealmAccessClaims struct {
 	Roles []string `json:"roles"`
 }
 
+// GetClaims возвращает claims токена, который AuthMiddleware сохранил в контексте запроса
+func GetClaims(ctx *fiber.Ctx) (*IdmClaims, error) {
+	token, ok := ctx.Locals(JwtKey).(*jwt.Token)
+	if !ok || token == nil {
+		return nil, errors.New("missing or invalid token")
+	}
+	claims, ok := token.Claims.(*IdmClaims)
+	if !ok || claims == nil {
+		return nil, errors.New("missing or invalid claims")
+	}
+	return claims, nil
+}
+
+// Actor кто выполняет запрос для журнала аудита: пользователь или клиентское приложение
+func (c *IdmClaims) Actor() string {
+	if c.Subject != "" {
+		return c.Subject
+	}
+	return c.AuthorizedParty
+}
+
+// Principal вызывающий из токена; от его ролей зависят видимость данных и права на их изменение
+func (c *IdmClaims) Principal() common.Principal {
+	return common.Principal{
+		Subject: c.Subject,
+		Roles:   c.RealmAccess.Roles,
+		Actor:   c.Actor(),
+		Admin:   slices.Contains(c.RealmAccess.Roles, IdmAdmin),
+	}
+}
+
 var AuthMiddleware = func(logger *common.Logger) fiber.Handler {
 	config := jwtMiddleware.Config{
 		ContextKey:   JwtKey,
@@ -36,11 +78,8 @@ var AuthMiddleware = func(logger *common.Logger) fiber.Handler {
 func createJwtErrorHandler(logger *common.Logger) fiber.ErrorHandler {
 	return func(ctx *fiber.Ctx, err error) error {
 		logger.ErrorCtx(ctx.Context(), "failed autentication", zap.Error(err))
-		// Если токен не может быть прочитан, то возвращаем 401
-		return common.ErrResponse(
-			ctx,
-			fiber.StatusUnauthorized,
-			err.Error(),
-		)
+		// Если токен не может быть прочитан, то возвращаем 401. Ответ формирует обработчик ошибок
+		// группы маршрутов: problem+json для /api, формат SCIM для /scim
+		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
 	}
 }
@@ -1,10 +1,13 @@
 package web
 
 import (
+	"encoding/json"
 	jwtMiddleware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/nihrom205/idm/inner/common"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"net/http"
 	"net/http/httptest"
 	"slices"
@@ -106,6 +109,60 @@ func TestJWT_Auth(t *testing.T) {
 
 }
 
+func TestJwtErrorHandler(t *testing.T) {
+	var a = assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+	server := NewServer(logger)
+	server.GroupApi.Use(jwtMiddleware.New(jwtMiddleware.Config{
+		SigningKey:   jwtMiddleware.SigningKey{Key: []byte(testSecret)},
+		ErrorHandler: createJwtErrorHandler(logger),
+	}))
+	server.GroupApi.Get("/protected", func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	// ошибка аутентификации отдаётся обработчиком ошибок сервера в формате problem+json
+	resp, err := server.App.Test(httptest.NewRequest("GET", "/api/protected", nil))
+
+	a.NoError(err)
+	a.Equal(http.StatusUnauthorized, resp.StatusCode)
+	a.Equal(common.MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+	var problem common.Problem
+	a.NoError(json.NewDecoder(resp.Body).Decode(&problem))
+	a.Equal(common.CodeUnauthorized, problem.Code)
+}
+
+func TestGetClaims(t *testing.T) {
+	var a = assert.New(t)
+	app := fiber.New()
+	app.Get("/token", func(c *fiber.Ctx) error {
+		claims, err := GetClaims(c)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).SendString(err.Error())
+		}
+		return c.JSON(claims.Principal())
+	})
+	app.Get("/client", func(c *fiber.Ctx) error {
+		c.Locals(JwtKey, &jwt.Token{Claims: &IdmClaims{
+			AuthorizedParty: "hr-sync",
+			RealmAccess:     RealmAccessClaims{Roles: []string{IdmAdmin}},
+		}})
+		claims, _ := GetClaims(c)
+		return c.JSON(claims.Principal())
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/token", nil))
+	a.NoError(err)
+	a.Equal(http.StatusUnauthorized, resp.StatusCode)
+
+	// клиентское приложение без пользователя записывается в журнал по client id
+	resp, err = app.Test(httptest.NewRequest("GET", "/client", nil))
+	a.NoError(err)
+	var principal common.Principal
+	a.NoError(json.NewDecoder(resp.Body).Decode(&principal))
+	a.Equal(common.Principal{Actor: "hr-sync", Roles: []string{IdmAdmin}, Admin: true}, principal)
+}
+
 func buildToken(exp time.Duration, roles []string, secret ...string) string {
 	key := testSecret
 	if len(secret) > 0 {
@@ -0,0 +1,200 @@
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// заголовок ответа, которым помечаются повторно отданные сохранённые ответы
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+
+	// максимальная длина ключа идемпотентности
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyRecord сохранённый запрос с ключом идемпотентности и ответ на него
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	// false, пока первый запрос с этим ключом ещё обрабатывается
+	Completed   bool
+	Status      int
+	ContentType string
+	Body        []byte
+	ExpireAt    time.Time
+}
+
+// IdempotencyStore хранилище ключей идемпотентности
+type IdempotencyStore interface {
+	// Lock резервирует ключ за запросом. Если ключ уже занят и не истёк,
+	// возвращает существующую запись и false
+	Lock(ctx context.Context, key string, requestHash string, ttl time.Duration) (IdempotencyRecord, bool, error)
+	// Save сохраняет ответ на запрос
+	Save(ctx context.Context, record IdempotencyRecord) error
+	// Delete освобождает ключ, чтобы клиент мог повторить запрос
+	Delete(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware обрабатывает заголовок Idempotency-Key для POST и DELETE запросов:
+// повторный запрос с тем же ключом и телом получает сохранённый ответ без повторного выполнения,
+// запрос с тем же ключом и другим телом отклоняется с кодом 422.
+// Ответы с кодом 5xx не сохраняются, такой запрос можно повторить с тем же ключом.
+// ttl - время хранения ответа, lockTtl - на сколько ключ резервируется за выполняющимся запросом:
+// если экземпляр приложения упал, не сохранив ответ, ключ освободится через lockTtl
+func IdempotencyMiddleware(store IdempotencyStore, ttl time.Duration, lockTtl time.Duration, logger *common.Logger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		method := ctx.Method()
+		if method != fiber.MethodPost && method != fiber.MethodDelete {
+			return ctx.Next()
+		}
+		idempotencyKey := ctx.Get(HeaderIdempotencyKey)
+		if idempotencyKey == "" {
+			return ctx.Next()
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			return errorResponse(ctx, fiber.StatusBadRequest, "idempotency key is too long")
+		}
+
+		// ключи разных клиентов не пересекаются
+		key := clientKey(ctx) + "|" + idempotencyKey
+		requestHash := hashRequest(ctx)
+
+		record, locked, err := store.Lock(ctx.Context(), key, requestHash, lockTtl)
+		if err != nil {
+			logger.ErrorCtx(ctx.Context(), "idempotency store: lock", zap.Error(err))
+			return errorResponse(ctx, fiber.StatusInternalServerError, "error checking idempotency key")
+		}
+		if !locked {
+			return replay(ctx, record, requestHash)
+		}
+
+		// ключ освобождается, если запрос не завершился: ответ 5xx, ошибка обработки ответа
+		// или паника обработчика (recover выполняется выше этого middleware)
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := store.Delete(ctx.Context(), key); err != nil {
+				logger.ErrorCtx(ctx.Context(), "idempotency store: delete", zap.Error(err))
+			}
+		}()
+
+		// выполняем запрос; ошибку сразу превращаем в ответ, чтобы сохранить его
+		if err := ctx.Next(); err != nil {
+			if errHandler := ctx.App().Config().ErrorHandler(ctx, err); errHandler != nil {
+				return errHandler
+			}
+		}
+
+		status := ctx.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			return nil
+		}
+		completed = true
+
+		record = IdempotencyRecord{
+			Key:         key,
+			RequestHash: requestHash,
+			Completed:   true,
+			Status:      status,
+			ContentType: string(ctx.Response().Header.ContentType()),
+			Body:        append([]byte(nil), ctx.Response().Body()...),
+			ExpireAt:    time.Now().Add(ttl),
+		}
+		if err := store.Save(ctx.Context(), record); err != nil {
+			logger.ErrorCtx(ctx.Context(), "idempotency store: save", zap.Error(err))
+		}
+		return nil
+	}
+}
+
+// replay отвечает на повторный запрос с уже использованным ключом
+func replay(ctx *fiber.Ctx, record IdempotencyRecord, requestHash string) error {
+	if record.RequestHash != requestHash {
+		return errorResponse(ctx, fiber.StatusUnprocessableEntity,
+			"idempotency key has already been used with a different request")
+	}
+	if !record.Completed {
+		return errorResponse(ctx, fiber.StatusConflict,
+			"request with this idempotency key is still being processed")
+	}
+	ctx.Set(HeaderIdempotentReplayed, "true")
+	if record.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return ctx.Status(record.Status).Send(record.Body)
+}
+
+// hashRequest считает хеш метода, пути и тела запроса
+func hashRequest(ctx *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(ctx.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(ctx.OriginalURL()))
+	h.Write([]byte{0})
+	h.Write(ctx.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MemoryIdempotencyStore хранилище ключей в памяти процесса (для одного экземпляра приложения)
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+	// время последней очистки истёкших ключей
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: map[string]IdempotencyRecord{},
+		sweptAt: time.Now(),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryIdempotencyStore) Lock(_ context.Context, key string, requestHash string, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	// периодически удаляем истёкшие ключи
+	if now.Sub(s.sweptAt) >= storeSweepInterval {
+		for k, record := range s.records {
+			if now.After(record.ExpireAt) {
+				delete(s.records, k)
+			}
+		}
+		s.sweptAt = now
+	}
+
+	if record, ok := s.records[key]; ok && !now.After(record.ExpireAt) {
+		return record, false, nil
+	}
+	record := IdempotencyRecord{Key: key, RequestHash: requestHash, ExpireAt: now.Add(ttl)}
+	s.records[key] = record
+	return record, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Save(_ context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Key] = record
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
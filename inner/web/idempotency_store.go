@@ -0,0 +1,86 @@
+package web
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// PostgresIdempotencyStore хранилище ключей идемпотентности в базе данных, общее для нескольких экземпляров приложения
+type PostgresIdempotencyStore struct {
+	db *sqlx.DB
+}
+
+func NewPostgresIdempotencyStore(db *sqlx.DB) *PostgresIdempotencyStore {
+	return &PostgresIdempotencyStore{db: db}
+}
+
+type idempotencyRow struct {
+	Key         string         `db:"key"`
+	RequestHash string         `db:"request_hash"`
+	Status      sql.NullInt64  `db:"status"`
+	ContentType sql.NullString `db:"content_type"`
+	Body        []byte         `db:"body"`
+	ExpireAt    time.Time      `db:"expire_at"`
+}
+
+func (r idempotencyRow) toRecord() IdempotencyRecord {
+	return IdempotencyRecord{
+		Key:         r.Key,
+		RequestHash: r.RequestHash,
+		Completed:   r.Status.Valid,
+		Status:      int(r.Status.Int64),
+		ContentType: r.ContentType.String,
+		Body:        r.Body,
+		ExpireAt:    r.ExpireAt,
+	}
+}
+
+func (s *PostgresIdempotencyStore) Lock(ctx context.Context, key string, requestHash string, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	// вставляем новый ключ или перехватываем истёкший
+	query := `INSERT INTO idempotency_key (key, request_hash, expire_at) VALUES ($1, $2, now() + $3 * interval '1 second')
+		ON CONFLICT (key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status = NULL, content_type = NULL,
+			body = NULL, expire_at = EXCLUDED.expire_at, create_at = now()
+		WHERE idempotency_key.expire_at < now()
+		RETURNING key, request_hash, status, content_type, body, expire_at`
+	var row idempotencyRow
+	err := s.db.GetContext(ctx, &row, query, key, requestHash, ttl.Seconds())
+	if err == nil {
+		return row.toRecord(), true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return IdempotencyRecord{}, false, fmt.Errorf("error locking idempotency key: %w", err)
+	}
+
+	// ключ занят другим запросом
+	query = "SELECT key, request_hash, status, content_type, body, expire_at FROM idempotency_key WHERE key = $1"
+	err = s.db.GetContext(ctx, &row, query, key)
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("error finding idempotency key: %w", err)
+	}
+	return row.toRecord(), false, nil
+}
+
+func (s *PostgresIdempotencyStore) Save(ctx context.Context, record IdempotencyRecord) error {
+	query := "UPDATE idempotency_key SET status = $2, content_type = $3, body = $4, expire_at = $5 WHERE key = $1"
+	_, err := s.db.ExecContext(ctx, query, record.Key, record.Status, record.ContentType, record.Body, record.ExpireAt)
+	return err
+}
+
+func (s *PostgresIdempotencyStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE key = $1", key)
+	return err
+}
+
+// DeleteExpired удаляет истёкшие ключи и возвращает их число
+func (s *PostgresIdempotencyStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_key WHERE expire_at < $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired idempotency keys: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
@@ -0,0 +1,301 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	a := assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+
+	// сервер, который создаёт новую сущность на каждый POST и считает вызовы
+	buildServer := func(store IdempotencyStore, status int) (*Server, *int) {
+		calls := 0
+		server := NewServer(logger)
+		server.GroupApi.Use(IdempotencyMiddleware(store, time.Hour, time.Minute, logger))
+		server.GroupApiV1.Post("/employees", func(c *fiber.Ctx) error {
+			calls++
+			if status != fiber.StatusOK {
+				return common.ErrResponse(c, status, "error")
+			}
+			return common.OkResponse(c, int64(calls))
+		})
+		server.GroupApiV1.Get("/employees", func(c *fiber.Ctx) error {
+			calls++
+			return common.OkResponse(c, int64(calls))
+		})
+		return server, &calls
+	}
+
+	send := func(server *Server, method string, key string, body string) (*http.Response, common.Response[int64]) {
+		req := httptest.NewRequest(method, "/api/v1/employees", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		resp, err := server.App.Test(req)
+		a.Nil(err)
+		data, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var response common.Response[int64]
+		_ = json.Unmarshal(data, &response)
+		return resp, response
+	}
+
+	t.Run("should replay stored response on retry", func(t *testing.T) {
+		server, calls := buildServer(NewMemoryIdempotencyStore(), fiber.StatusOK)
+
+		resp, first := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Empty(resp.Header.Get(HeaderIdempotentReplayed))
+
+		resp, second := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal("true", resp.Header.Get(HeaderIdempotentReplayed))
+		a.Equal(fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+		a.Equal(first, second)
+		a.Equal(1, *calls)
+	})
+
+	t.Run("should return 422 when key is reused with different body", func(t *testing.T) {
+		server, calls := buildServer(NewMemoryIdempotencyStore(), fiber.StatusOK)
+
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		resp, body := send(server, fiber.MethodPost, "key-1", `{"name": "jane doe"}`)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
+		a.False(body.Success)
+		a.Equal(1, *calls)
+	})
+
+	t.Run("should return 409 while first request is in progress", func(t *testing.T) {
+		store := NewMemoryIdempotencyStore()
+		server, calls := buildServer(store, fiber.StatusOK)
+		// первый запрос с этим ключом ещё не завершён
+		store.records["ip:0.0.0.0|key-1"] = IdempotencyRecord{
+			Key:         "ip:0.0.0.0|key-1",
+			RequestHash: hashRequestFor(t, fiber.MethodPost, `{"name": "john doe"}`),
+			ExpireAt:    time.Now().Add(time.Hour),
+		}
+
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusConflict, resp.StatusCode)
+		a.Equal(0, *calls)
+	})
+
+	t.Run("should not store server errors", func(t *testing.T) {
+		server, calls := buildServer(NewMemoryIdempotencyStore(), fiber.StatusInternalServerError)
+
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusInternalServerError, resp.StatusCode)
+		resp, _ = send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusInternalServerError, resp.StatusCode)
+		a.Empty(resp.Header.Get(HeaderIdempotentReplayed))
+		a.Equal(2, *calls)
+	})
+
+	t.Run("should store client errors", func(t *testing.T) {
+		server, calls := buildServer(NewMemoryIdempotencyStore(), fiber.StatusBadRequest)
+
+		send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal("true", resp.Header.Get(HeaderIdempotentReplayed))
+		a.Equal(1, *calls)
+	})
+
+	t.Run("should ignore requests without key and safe methods", func(t *testing.T) {
+		server, calls := buildServer(NewMemoryIdempotencyStore(), fiber.StatusOK)
+
+		send(server, fiber.MethodPost, "", `{"name": "john doe"}`)
+		send(server, fiber.MethodPost, "", `{"name": "john doe"}`)
+		send(server, fiber.MethodGet, "key-1", "")
+		send(server, fiber.MethodGet, "key-1", "")
+		a.Equal(4, *calls)
+	})
+
+	t.Run("should lock key for lock ttl while request is in progress", func(t *testing.T) {
+		store := NewMemoryIdempotencyStore()
+		server := NewServer(logger)
+		server.GroupApi.Use(IdempotencyMiddleware(store, time.Hour, time.Minute, logger))
+		var lockedUntil time.Time
+		server.GroupApiV1.Post("/employees", func(c *fiber.Ctx) error {
+			lockedUntil = store.records["ip:0.0.0.0|key-1"].ExpireAt
+			return common.OkResponse(c, int64(1))
+		})
+
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.WithinDuration(time.Now().Add(time.Minute), lockedUntil, 5*time.Second)
+		// сохранённый ответ хранится ttl
+		a.WithinDuration(time.Now().Add(time.Hour), store.records["ip:0.0.0.0|key-1"].ExpireAt, 5*time.Second)
+	})
+
+	t.Run("should release key when handler panics", func(t *testing.T) {
+		store := NewMemoryIdempotencyStore()
+		server := NewServer(logger)
+		server.GroupApi.Use(IdempotencyMiddleware(store, time.Hour, time.Minute, logger))
+		calls := 0
+		server.GroupApiV1.Post("/employees", func(c *fiber.Ctx) error {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			return common.OkResponse(c, int64(calls))
+		})
+
+		resp, _ := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusInternalServerError, resp.StatusCode)
+		resp, body := send(server, fiber.MethodPost, "key-1", `{"name": "john doe"}`)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal(int64(2), body.Data)
+		a.Equal(2, calls)
+	})
+
+	t.Run("should return SCIM errors to SCIM error middleware", func(t *testing.T) {
+		store := NewMemoryIdempotencyStore()
+		server := NewServer(logger)
+		// вместо scim.ErrorMiddleware: ошибка middleware должна вернуться, а не быть записана в ответ
+		server.GroupScim.Use(func(c *fiber.Ctx) error {
+			var fiberErr *fiber.Error
+			if err := c.Next(); errors.As(err, &fiberErr) {
+				return c.Status(fiberErr.Code).SendString("scim: " + fiberErr.Message)
+			}
+			return nil
+		}, IdempotencyMiddleware(store, time.Hour, time.Minute, logger))
+		server.GroupScim.Post("/Users", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusCreated)
+		})
+		send := func(body string) (int, string) {
+			req := httptest.NewRequest(fiber.MethodPost, "/scim/v2/Users", strings.NewReader(body))
+			req.Header.Set(HeaderIdempotencyKey, "key-1")
+			resp, err := server.App.Test(req)
+			a.Nil(err)
+			data, err := io.ReadAll(resp.Body)
+			a.Nil(err)
+			return resp.StatusCode, string(data)
+		}
+
+		status, _ := send(`{"userName": "jdoe"}`)
+		a.Equal(http.StatusCreated, status)
+		status, body := send(`{"userName": "jsmith"}`)
+		a.Equal(http.StatusUnprocessableEntity, status)
+		a.Equal("scim: idempotency key has already been used with a different request", body)
+	})
+
+	t.Run("should accept retry after key expired", func(t *testing.T) {
+		now := time.Now()
+		store := NewMemoryIdempotencyStore()
+		store.now = func() time.Time { return now }
+
+		_, locked, _ := store.Lock(context.Background(), "key", "hash", time.Minute)
+		a.True(locked)
+		_, locked, _ = store.Lock(context.Background(), "key", "hash", time.Minute)
+		a.False(locked)
+
+		now = now.Add(2 * time.Minute)
+		_, locked, _ = store.Lock(context.Background(), "key", "other", time.Minute)
+		a.True(locked)
+	})
+}
+
+func TestPostgresIdempotencyStore(t *testing.T) {
+	a := assert.New(t)
+	columns := []string{"key", "request_hash", "status", "content_type", "body", "expire_at"}
+
+	t.Run("should lock new key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		store := NewPostgresIdempotencyStore(sqlx.NewDb(db, "sqlmock"))
+		expireAt := time.Now().Add(time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO idempotency_key")).
+			WithArgs("key", "hash", float64(3600)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("key", "hash", nil, nil, nil, expireAt))
+
+		record, locked, err := store.Lock(context.Background(), "key", "hash", time.Hour)
+		a.Nil(err)
+		a.True(locked)
+		a.False(record.Completed)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return stored response for used key", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		store := NewPostgresIdempotencyStore(sqlx.NewDb(db, "sqlmock"))
+		expireAt := time.Now().Add(time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO idempotency_key")).
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT key, request_hash, status, content_type, body, expire_at FROM idempotency_key WHERE key = $1")).
+			WithArgs("key").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("key", "hash", 200, "application/json", []byte(`{}`), expireAt))
+
+		record, locked, err := store.Lock(context.Background(), "key", "hash", time.Hour)
+		a.Nil(err)
+		a.False(locked)
+		a.True(record.Completed)
+		a.Equal(200, record.Status)
+		a.Equal([]byte(`{}`), record.Body)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return error when lock fails", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		store := NewPostgresIdempotencyStore(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO idempotency_key")).WillReturnError(errors.New("db down"))
+
+		_, _, err = store.Lock(context.Background(), "key", "hash", time.Hour)
+		a.ErrorContains(err, "db down")
+	})
+
+	t.Run("should delete expired keys", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		now := time.Now()
+		store := NewPostgresIdempotencyStore(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_key WHERE expire_at < $1")).
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		deleted, err := store.DeleteExpired(context.Background(), now)
+		a.Nil(err)
+		a.Equal(2, deleted)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+}
+
+// hashRequestFor считает хеш запроса так же, как это делает middleware
+func hashRequestFor(t *testing.T, method string, body string) string {
+	var hash string
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		hash = hashRequest(c)
+		return nil
+	})
+	_, err := app.Test(httptest.NewRequest(method, "/api/v1/employees", strings.NewReader(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
@@ -5,6 +5,8 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/google/uuid"
+	"github.com/nihrom205/idm/inner/common"
+	"strings"
 )
 
 func registerMiddleware(app *fiber.App) {
@@ -22,3 +24,12 @@ func registerMiddleware(app *fiber.App) {
 	})
 	app.Use(logger.New())
 }
+
+// errorResponse отвечает ошибкой middleware. Для SCIM ошибка возвращается, и её переводит в формат SCIM
+// scim.ErrorMiddleware, подключённый к группе первым; для остального API - ответ common.ErrResponse
+func errorResponse(ctx *fiber.Ctx, status int, message string) error {
+	if strings.HasPrefix(ctx.Path(), scimPath+"/") {
+		return fiber.NewError(status, message)
+	}
+	return common.ErrResponse(ctx, status, message)
+}
@@ -3,7 +3,9 @@ package web
 import (
 	"encoding/json"
 	"github.com/gofiber/fiber/v2"
+	"github.com/nihrom205/idm/inner/common"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -13,8 +15,8 @@ import (
 func TestRecoverMiddleware(t *testing.T) {
 	a := assert.New(t)
 
-	t.Run("should recover from panic and return 500", func(t *testing.T) {
-		server := NewServer()
+	t.Run("should recover from panic and return 500 without internal details", func(t *testing.T) {
+		server := NewServer(&common.Logger{Logger: zap.NewNop()})
 
 		// Настраиваем роут для теста
 		route := func(app *fiber.App) {
@@ -35,7 +37,8 @@ func TestRecoverMiddleware(t *testing.T) {
 		// Читаем тело ответа
 		body, err := io.ReadAll(resp.Body)
 		a.Nil(err)
-		a.Contains(string(body), "test panic")
+		a.NotContains(string(body), "test panic")
+		a.Contains(string(body), common.CodeInternal)
 	})
 }
 
@@ -43,7 +46,7 @@ func TestRequestIdMiddleware(t *testing.T) {
 	a := assert.New(t)
 
 	t.Run("should use provided request ID", func(t *testing.T) {
-		server := NewServer()
+		server := NewServer(&common.Logger{Logger: zap.NewNop()})
 
 		// Добавляем тестовый роут, который возвращает Request ID
 		server.App.Get("/test", func(c *fiber.Ctx) error {
@@ -80,7 +83,7 @@ func TestRequestIdMiddleware(t *testing.T) {
 	})
 
 	t.Run("should use note provided request ID", func(t *testing.T) {
-		server := NewServer()
+		server := NewServer(&common.Logger{Logger: zap.NewNop()})
 
 		// Добавляем тестовый роут, который возвращает Request ID
 		server.App.Get("/test", func(c *fiber.Ctx) error {
@@ -0,0 +1,230 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nihrom205/idm/inner/common"
+	"go.uber.org/zap"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// интервал очистки неиспользуемых записей в хранилищах в памяти
+const storeSweepInterval = time.Minute
+
+// RateLimit лимит запросов: не более Limit запросов за Period
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// refillRate скорость пополнения корзины токенов (токенов в секунду)
+func (l RateLimit) refillRate() float64 {
+	return float64(l.Limit) / l.Period.Seconds()
+}
+
+// RateLimitResult результат попытки взять токен из корзины
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// время до полного восстановления корзины
+	Reset time.Duration
+	// через сколько можно повторить запрос, если он был отклонён
+	RetryAfter time.Duration
+}
+
+// RateLimitStore хранилище корзин токенов
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+}
+
+// RateLimitConfig конфигурация лимитов: лимит по умолчанию и лимиты отдельных маршрутов
+type RateLimitConfig struct {
+	Default RateLimit
+	// ключ - "METHOD /path", например "POST /api/v1/employees/ids"
+	Routes map[string]RateLimit
+}
+
+// limitFor возвращает лимит для маршрута и идентификатор корзины маршрута
+func (c RateLimitConfig) limitFor(method, path string) (RateLimit, string) {
+	route := method + " " + strings.TrimRight(path, "/")
+	if limit, ok := c.Routes[route]; ok {
+		return limit, route
+	}
+	return c.Default, "default"
+}
+
+// ParseRateLimit разбирает лимит в формате "100/1m"
+func ParseRateLimit(value string) (RateLimit, error) {
+	limitStr, periodStr, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected format <limit>/<period>", value)
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: limit must be a positive number", value)
+	}
+	period, err := time.ParseDuration(strings.TrimSpace(periodStr))
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: period must be a positive duration", value)
+	}
+	return RateLimit{Limit: limit, Period: period}, nil
+}
+
+// ParseRateLimitConfig разбирает лимит по умолчанию ("100/1m") и лимиты маршрутов
+// в формате "POST /api/v1/employees/ids=10/1m;GET /api/v1/employees=30/1m"
+func ParseRateLimitConfig(defaultLimit string, routes string) (RateLimitConfig, error) {
+	cfg := RateLimitConfig{Routes: map[string]RateLimit{}}
+	limit, err := ParseRateLimit(defaultLimit)
+	if err != nil {
+		return RateLimitConfig{}, err
+	}
+	cfg.Default = limit
+
+	for _, rule := range strings.Split(routes, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(rule, "=")
+		if !ok {
+			return RateLimitConfig{}, fmt.Errorf("invalid route rate limit %q: expected format <METHOD /path>=<limit>/<period>", rule)
+		}
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok {
+			return RateLimitConfig{}, fmt.Errorf("invalid route rate limit %q: route must contain method and path", rule)
+		}
+		limit, err := ParseRateLimit(value)
+		if err != nil {
+			return RateLimitConfig{}, err
+		}
+		key := strings.ToUpper(method) + " " + strings.TrimRight(strings.TrimSpace(path), "/")
+		cfg.Routes[key] = limit
+	}
+	return cfg, nil
+}
+
+// RateLimitMiddleware ограничивает частоту запросов клиента.
+// Клиент определяется по subject из JWT, затем по client id (azp), иначе по IP адресу.
+// Должен подключаться после AuthMiddleware, чтобы в контексте были claims
+func RateLimitMiddleware(cfg RateLimitConfig, store RateLimitStore, logger *common.Logger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		limit, route := cfg.limitFor(ctx.Method(), ctx.Path())
+		key := route + "|" + clientKey(ctx)
+
+		result, err := store.Take(ctx.Context(), key, limit)
+		if err != nil {
+			// недоступность хранилища лимитов не должна останавливать работу API
+			logger.ErrorCtx(ctx.Context(), "rate limit store", zap.Error(err))
+			return ctx.Next()
+		}
+
+		ctx.Set("RateLimit-Limit", strconv.Itoa(limit.Limit))
+		ctx.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			return errorResponse(ctx, fiber.StatusTooManyRequests, "too many requests")
+		}
+		return ctx.Next()
+	}
+}
+
+// clientKey определяет клиента, которому принадлежит запрос: subject, client id или IP адрес
+func clientKey(ctx *fiber.Ctx) string {
+	if token, ok := ctx.Locals(JwtKey).(*jwt.Token); ok && token != nil {
+		if claims, ok := token.Claims.(*IdmClaims); ok && claims != nil {
+			if claims.Subject != "" {
+				return "sub:" + claims.Subject
+			}
+			if claims.AuthorizedParty != "" {
+				return "client:" + claims.AuthorizedParty
+			}
+		}
+	}
+	return "ip:" + ctx.IP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucket корзина токенов
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	period    time.Duration
+}
+
+// take пополняет корзину за прошедшее время и пытается взять из неё один токен
+func (b *bucket) take(now time.Time, limit RateLimit) RateLimitResult {
+	rate := limit.refillRate()
+	capacity := float64(limit.Limit)
+	elapsed := now.Sub(b.updatedAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+	}
+	b.updatedAt = now
+	b.period = limit.Period
+
+	result := RateLimitResult{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = time.Duration((capacity - b.tokens) / rate * float64(time.Second))
+	return result
+}
+
+// MemoryRateLimitStore хранилище корзин в памяти процесса (для одного экземпляра приложения)
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	// время последней очистки устаревших корзин
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[string]*bucket{},
+		sweptAt: time.Now(),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Limit), updatedAt: now}
+		s.buckets[key] = b
+	}
+	return b.take(now, limit), nil
+}
+
+// sweep удаляет корзины, которые не использовались дольше периода своего лимита и уже полностью восстановились
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < storeSweepInterval {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.updatedAt) > b.period {
+			delete(s.buckets, key)
+		}
+	}
+	s.sweptAt = now
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+// PostgresRateLimitStore хранилище корзин в базе данных, общее для нескольких экземпляров приложения.
+// Корзина блокируется на время пересчёта (SELECT ... FOR UPDATE), поэтому параллельные запросы
+// одного клиента на разные экземпляры не могут потратить один и тот же токен
+type PostgresRateLimitStore struct {
+	db  *sqlx.DB
+	now func() time.Time
+}
+
+func NewPostgresRateLimitStore(db *sqlx.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db, now: time.Now}
+}
+
+func (s *PostgresRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (result RateLimitResult, err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit: begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		if errTx := tx.Commit(); errTx != nil {
+			err = fmt.Errorf("rate limit: commit transaction: %w", errTx)
+		}
+	}()
+
+	now := s.now()
+	// создаём полную корзину, если клиент пришёл впервые
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO rate_limit_bucket (key, tokens, update_at, expire_at) VALUES ($1, $2, $3, $4) ON CONFLICT (key) DO NOTHING",
+		key, float64(limit.Limit), now, now.Add(limit.Period))
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit: create bucket %s: %w", key, err)
+	}
+
+	var b bucket
+	err = tx.QueryRowxContext(ctx,
+		"SELECT tokens, update_at FROM rate_limit_bucket WHERE key = $1 FOR UPDATE", key).
+		Scan(&b.tokens, &b.updatedAt)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit: lock bucket %s: %w", key, err)
+	}
+
+	result = b.take(now, limit)
+
+	// через период лимита без запросов корзина снова полна, и её можно удалить
+	_, err = tx.ExecContext(ctx,
+		"UPDATE rate_limit_bucket SET tokens = $2, update_at = $3, expire_at = $4 WHERE key = $1",
+		key, b.tokens, b.updatedAt, b.updatedAt.Add(limit.Period))
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit: update bucket %s: %w", key, err)
+	}
+	return result, nil
+}
+
+// DeleteExpired удаляет корзины, которые не использовались дольше периода своего лимита, и возвращает их число
+func (s *PostgresRateLimitStore) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM rate_limit_bucket WHERE expire_at < $1", now)
+	if err != nil {
+		return 0, fmt.Errorf("rate limit: delete expired buckets: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
@@ -0,0 +1,256 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type failingRateLimitStore struct{}
+
+func (s failingRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestParseRateLimitConfig(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should parse default and route limits", func(t *testing.T) {
+		cfg, err := ParseRateLimitConfig("100/1m", "post /api/v1/employees/ids/=10/1s; GET /api/v1/employees=30/1h")
+		a.Nil(err)
+		a.Equal(RateLimit{Limit: 100, Period: time.Minute}, cfg.Default)
+		a.Equal(RateLimit{Limit: 10, Period: time.Second}, cfg.Routes["POST /api/v1/employees/ids"])
+		a.Equal(RateLimit{Limit: 30, Period: time.Hour}, cfg.Routes["GET /api/v1/employees"])
+	})
+
+	t.Run("should return error on invalid limit", func(t *testing.T) {
+		_, err := ParseRateLimitConfig("100", "")
+		a.NotNil(err)
+		_, err = ParseRateLimitConfig("0/1m", "")
+		a.NotNil(err)
+		_, err = ParseRateLimitConfig("100/1m", "GET=1/1m")
+		a.NotNil(err)
+		_, err = ParseRateLimitConfig("100/1m", "GET /api/v1/employees=1/never")
+		a.NotNil(err)
+	})
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	a := assert.New(t)
+	limit := RateLimit{Limit: 2, Period: 2 * time.Second}
+
+	t.Run("should reject when bucket is empty and refill over time", func(t *testing.T) {
+		now := time.Now()
+		store := NewMemoryRateLimitStore()
+		store.now = func() time.Time { return now }
+
+		first, _ := store.Take(context.Background(), "client", limit)
+		a.True(first.Allowed)
+		a.Equal(1, first.Remaining)
+
+		second, _ := store.Take(context.Background(), "client", limit)
+		a.True(second.Allowed)
+		a.Equal(0, second.Remaining)
+
+		third, _ := store.Take(context.Background(), "client", limit)
+		a.False(third.Allowed)
+		a.Equal(time.Second, third.RetryAfter)
+		a.Equal(2*time.Second, third.Reset)
+
+		// другой клиент имеет свою корзину
+		other, _ := store.Take(context.Background(), "other", limit)
+		a.True(other.Allowed)
+
+		now = now.Add(time.Second)
+		fourth, _ := store.Take(context.Background(), "client", limit)
+		a.True(fourth.Allowed)
+	})
+
+	t.Run("should drop unused buckets", func(t *testing.T) {
+		now := time.Now()
+		store := NewMemoryRateLimitStore()
+		store.now = func() time.Time { return now }
+
+		_, _ = store.Take(context.Background(), "client", limit)
+		now = now.Add(storeSweepInterval + time.Second)
+		_, _ = store.Take(context.Background(), "other", limit)
+
+		a.Len(store.buckets, 1)
+		a.Contains(store.buckets, "other")
+	})
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	a := assert.New(t)
+	logger := &common.Logger{Logger: zap.NewNop()}
+	cfg, err := ParseRateLimitConfig("100/1m", "POST /api/v1/employees/ids=1/1m")
+	a.Nil(err)
+
+	buildServer := func(store RateLimitStore, subject string) *Server {
+		server := NewServer(logger)
+		server.GroupApi.Use(func(c *fiber.Ctx) error {
+			claims := &IdmClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}}
+			c.Locals(JwtKey, &jwt.Token{Claims: claims})
+			return c.Next()
+		})
+		server.GroupApi.Use(RateLimitMiddleware(cfg, store, logger))
+		server.GroupApiV1.Post("/employees/ids", func(c *fiber.Ctx) error {
+			return common.OkResponse(c, struct{}{})
+		})
+		server.GroupApiV1.Get("/employees", func(c *fiber.Ctx) error {
+			return common.OkResponse(c, struct{}{})
+		})
+		return server
+	}
+
+	t.Run("should return 429 with retry headers when route limit exceeded", func(t *testing.T) {
+		server := buildServer(NewMemoryRateLimitStore(), "user-1")
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal("1", resp.Header.Get("RateLimit-Limit"))
+		a.Equal("0", resp.Header.Get("RateLimit-Remaining"))
+
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", nil))
+		a.Nil(err)
+		a.Equal(http.StatusTooManyRequests, resp.StatusCode)
+		a.Equal("60", resp.Header.Get(fiber.HeaderRetryAfter))
+		a.Equal("60", resp.Header.Get("RateLimit-Reset"))
+
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		var problem common.Problem
+		a.Nil(json.Unmarshal(body, &problem))
+		a.Equal(common.CodeTooManyRequests, problem.Code)
+		a.Equal(common.MIMEApplicationProblemJSON, resp.Header.Get(fiber.HeaderContentType))
+
+		// лимит маршрута не влияет на остальные маршруты
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/employees", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal("100", resp.Header.Get("RateLimit-Limit"))
+	})
+
+	t.Run("should return 429 to SCIM error middleware", func(t *testing.T) {
+		server := NewServer(logger)
+		// вместо scim.ErrorMiddleware: ошибка middleware должна вернуться, а не быть записана в ответ
+		server.GroupScim.Use(func(c *fiber.Ctx) error {
+			var fiberErr *fiber.Error
+			if err := c.Next(); errors.As(err, &fiberErr) {
+				return c.Status(fiberErr.Code).SendString("scim: " + fiberErr.Message)
+			}
+			return nil
+		}, RateLimitMiddleware(RateLimitConfig{Default: RateLimit{Limit: 1, Period: time.Minute}}, NewMemoryRateLimitStore(), logger))
+		server.GroupScim.Get("/Users", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		resp, err = server.App.Test(httptest.NewRequest(fiber.MethodGet, "/scim/v2/Users", nil))
+		a.Nil(err)
+		a.Equal(http.StatusTooManyRequests, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		a.Nil(err)
+		a.Equal("scim: too many requests", string(body))
+	})
+
+	t.Run("should keep separate limits per subject", func(t *testing.T) {
+		store := NewMemoryRateLimitStore()
+		first := buildServer(store, "user-1")
+		second := buildServer(store, "user-2")
+
+		resp, err := first.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+
+		resp, err = second.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should allow request when store fails", func(t *testing.T) {
+		server := buildServer(failingRateLimitStore{}, "user-1")
+
+		resp, err := server.App.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/employees/ids", nil))
+		a.Nil(err)
+		a.Equal(http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestPostgresRateLimitStore(t *testing.T) {
+	a := assert.New(t)
+
+	t.Run("should take token from locked bucket", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		now := time.Now()
+		store := NewPostgresRateLimitStore(sqlx.NewDb(db, "sqlmock"))
+		store.now = func() time.Time { return now }
+		limit := RateLimit{Limit: 10, Period: time.Minute}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO rate_limit_bucket (key, tokens, update_at, expire_at) VALUES ($1, $2, $3, $4) ON CONFLICT (key) DO NOTHING")).
+			WithArgs("client", float64(10), now, now.Add(time.Minute)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tokens, update_at FROM rate_limit_bucket WHERE key = $1 FOR UPDATE")).
+			WithArgs("client").
+			WillReturnRows(sqlmock.NewRows([]string{"tokens", "update_at"}).AddRow(float64(3), now))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE rate_limit_bucket SET tokens = $2, update_at = $3, expire_at = $4 WHERE key = $1")).
+			WithArgs("client", float64(2), now, now.Add(time.Minute)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := store.Take(context.Background(), "client", limit)
+		a.Nil(err)
+		a.True(result.Allowed)
+		a.Equal(2, result.Remaining)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should rollback on error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		store := NewPostgresRateLimitStore(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO rate_limit_bucket").WillReturnError(errors.New("db down"))
+		mock.ExpectRollback()
+
+		_, err = store.Take(context.Background(), "client", RateLimit{Limit: 1, Period: time.Minute})
+		a.ErrorContains(err, "db down")
+		a.Nil(mock.ExpectationsWereMet())
+	})
+
+	t.Run("should delete expired buckets", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		a.NoError(err)
+		now := time.Now()
+		store := NewPostgresRateLimitStore(sqlx.NewDb(db, "sqlmock"))
+
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM rate_limit_bucket WHERE expire_at < $1")).
+			WithArgs(now).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+
+		deleted, err := store.DeleteExpired(context.Background(), now)
+		a.Nil(err)
+		a.Equal(3, deleted)
+		a.Nil(mock.ExpectationsWereMet())
+	})
+}
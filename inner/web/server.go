@@ -3,8 +3,12 @@ package web
 import (
 	"github.com/gofiber/fiber/v2"
 	_ "github.com/nihrom205/idm/docs" // обязательно импортируем наш пакет с документацией
+	"github.com/nihrom205/idm/inner/common"
 )
 
+// префикс маршрутов SCIM 2.0 API
+const scimPath = "/scim/v2"
+
 type Server struct {
 	App *fiber.App
 	// группа публичного API
@@ -13,15 +17,20 @@ type Server struct {
 	GroupApiV1 fiber.Router
 	// группа непубличного API
 	GroupInternal fiber.Router
+	// группа SCIM 2.0 API для провижининга учётных записей
+	GroupScim fiber.Router
 }
 
 type AuthMiddlewareInterface interface {
 	ProtectWithJwt() func(*fiber.Ctx) error
 }
 
-func NewServer() *Server {
+func NewServer(logger *common.Logger) *Server {
 	// создаём новый веб-вервер
-	app := fiber.New()
+	// ошибки обработчиков переводятся в ответы application/problem+json, внутренние ошибки логируются
+	app := fiber.New(fiber.Config{
+		ErrorHandler: common.NewErrorHandler(logger),
+	})
 
 	// подключаем middleware
 	registerMiddleware(app)
@@ -32,10 +41,13 @@ func NewServer() *Server {
 
 	groupApiV1 := groupApi.Group("/v1")
 
+	groupScim := app.Group(scimPath)
+
 	return &Server{
 		App:           app,
 		GroupApi:      groupApi,
 		GroupApiV1:    groupApiV1,
 		GroupInternal: groupInternal,
+		GroupScim:     groupScim,
 	}
 }
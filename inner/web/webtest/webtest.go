@@ -0,0 +1,38 @@
+// Package webtest тестовое окружение контроллеров: веб-сервер, запросы к которому выполняются с заданным токеном
+package webtest
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nihrom205/idm/inner/common"
+	"github.com/nihrom205/idm/inner/web"
+	"go.uber.org/zap"
+)
+
+// Logger логгер, который ничего не пишет
+func Logger() *common.Logger {
+	return &common.Logger{Logger: zap.NewNop()}
+}
+
+// Claims токен пользователя Keycloak subject с ролями области roles
+func Claims(subject string, roles ...string) *web.IdmClaims {
+	claims := &web.IdmClaims{RealmAccess: web.RealmAccessClaims{Roles: roles}}
+	claims.Subject = subject
+	return claims
+}
+
+// Auth stub middleware аутентификации: подставляет токен с claims вместо проверки JWT
+func Auth(claims *web.IdmClaims) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(web.JwtKey, &jwt.Token{Claims: claims})
+		return c.Next()
+	}
+}
+
+// NewServer сервер, запросы к публичному API которого выполняются с claims, и его логгер
+func NewServer(claims *web.IdmClaims) (*web.Server, *common.Logger) {
+	logger := Logger()
+	server := web.NewServer(logger)
+	server.GroupApi.Use(Auth(claims))
+	return server, logger
+}
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS idempotency_key (
+    key text primary key not null,
+    request_hash text not null,
+    status int,
+    content_type text,
+    body bytea,
+    expire_at timestamptz not null,
+    create_at timestamptz default now()
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_key_expire_at_idx ON idempotency_key (expire_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE idempotency_key;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS employee_role (
+    employee_id bigint not null references employee (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (employee_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS employee_role_role_id_idx ON employee_role (role_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE employee_role;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS external_id text;
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS org_unit text;
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS job_title text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS employee_external_id_idx ON employee (external_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS employee_external_id_idx;
+
+ALTER TABLE employee DROP COLUMN IF EXISTS job_title;
+ALTER TABLE employee DROP COLUMN IF EXISTS org_unit;
+ALTER TABLE employee DROP COLUMN IF EXISTS external_id;
+-- +goose StatementEnd
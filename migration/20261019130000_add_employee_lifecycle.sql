@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS status text not null default 'active';
+ALTER TABLE employee ADD CONSTRAINT employee_status_check
+    CHECK (status IN ('pre_hire', 'active', 'suspended', 'terminated'));
+
+-- источник назначения роли: manual - назначена вручную, birthright - по отделу сотрудника
+ALTER TABLE employee_role ADD COLUMN IF NOT EXISTS source text not null default 'manual';
+
+CREATE TABLE IF NOT EXISTS birthright_role (
+    org_unit text not null,
+    role_id bigint not null references role (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (org_unit, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS employee_transition (
+    id bigint generated always as IDENTITY primary key not null,
+    employee_id bigint not null references employee (id) on delete cascade,
+    status text not null,
+    effective_at timestamptz not null,
+    reason text,
+    actor text not null,
+    state text not null default 'pending',
+    error text,
+    create_at timestamptz default now(),
+    execute_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS employee_transition_pending_idx ON employee_transition (effective_at) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS employee_transition_employee_id_idx ON employee_transition (employee_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE employee_transition;
+
+DROP TABLE birthright_role;
+
+ALTER TABLE employee_role DROP COLUMN IF EXISTS source;
+
+ALTER TABLE employee DROP CONSTRAINT IF EXISTS employee_status_check;
+ALTER TABLE employee DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
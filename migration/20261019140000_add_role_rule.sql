@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+-- правило назначения ролей по атрибутам сотрудника; пустой атрибут подходит любому значению
+CREATE TABLE IF NOT EXISTS role_rule (
+    id bigint generated always as IDENTITY primary key not null,
+    name text not null unique,
+    org_unit text,
+    job_title text,
+    create_at timestamptz default now(),
+    update_at timestamptz default now(),
+    CONSTRAINT role_rule_condition_check CHECK (org_unit IS NOT NULL OR job_title IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS role_rule_role (
+    rule_id bigint not null references role_rule (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    primary key (rule_id, role_id)
+);
+
+-- роли отделов становятся правилами по отделу
+INSERT INTO role_rule (name, org_unit)
+SELECT DISTINCT 'org unit ' || org_unit, org_unit FROM birthright_role;
+
+INSERT INTO role_rule_role (rule_id, role_id)
+SELECT r.id, b.role_id FROM birthright_role b JOIN role_rule r ON r.org_unit = b.org_unit AND r.job_title IS NULL;
+
+-- источник назначения роли: manual - назначена вручную, rule - по правилу
+UPDATE employee_role SET source = 'rule' WHERE source = 'birthright';
+
+DROP TABLE birthright_role;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS birthright_role (
+    org_unit text not null,
+    role_id bigint not null references role (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (org_unit, role_id)
+);
+
+-- обратно переносятся только правила по отделу без должности
+INSERT INTO birthright_role (org_unit, role_id)
+SELECT r.org_unit, rr.role_id FROM role_rule r JOIN role_rule_role rr ON rr.rule_id = r.id
+WHERE r.job_title IS NULL
+ON CONFLICT DO NOTHING;
+
+UPDATE employee_role SET source = 'birthright' WHERE source = 'rule';
+
+DROP TABLE role_rule_role;
+
+DROP TABLE role_rule;
+-- +goose StatementEnd
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS employee_group (
+    id bigint generated always as IDENTITY primary key not null,
+    name text not null unique,
+    create_at timestamptz default now(),
+    update_at timestamptz default now()
+);
+
+CREATE TABLE IF NOT EXISTS group_member (
+    group_id bigint not null references employee_group (id) on delete cascade,
+    employee_id bigint not null references employee (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (group_id, employee_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_member_employee_id_idx ON group_member (employee_id);
+
+-- вложенные группы: участники дочерней группы входят и в родительскую
+CREATE TABLE IF NOT EXISTS group_child (
+    parent_id bigint not null references employee_group (id) on delete cascade,
+    child_id bigint not null references employee_group (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (parent_id, child_id),
+    CONSTRAINT group_child_self_check CHECK (parent_id <> child_id)
+);
+
+CREATE INDEX IF NOT EXISTS group_child_child_id_idx ON group_child (child_id);
+
+CREATE TABLE IF NOT EXISTS group_role (
+    group_id bigint not null references employee_group (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    create_at timestamptz default now(),
+    primary key (group_id, role_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE group_role;
+
+DROP TABLE group_child;
+
+DROP TABLE group_member;
+
+DROP TABLE employee_group;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- идентификатор пользователя в Keycloak (claim sub), по нему вызывающий находит свою запись
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS subject text UNIQUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employee DROP COLUMN IF EXISTS subject;
+-- +goose StatementEnd
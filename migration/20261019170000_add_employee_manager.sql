@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- руководитель сотрудника, по нему строится подчинённая иерархия для правил видимости
+ALTER TABLE employee ADD COLUMN IF NOT EXISTS manager_id bigint references employee (id) on delete set null;
+ALTER TABLE employee ADD CONSTRAINT employee_manager_check CHECK (manager_id <> id);
+CREATE INDEX IF NOT EXISTS employee_manager_id_idx ON employee (manager_id);
+CREATE INDEX IF NOT EXISTS employee_org_unit_idx ON employee (org_unit);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS employee_org_unit_idx;
+DROP INDEX IF EXISTS employee_manager_id_idx;
+ALTER TABLE employee DROP CONSTRAINT IF EXISTS employee_manager_check;
+ALTER TABLE employee DROP COLUMN IF EXISTS manager_id;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- администратор отдела: создаёт сотрудников и назначает разрешённые роли в отделе org_unit и его подотделах
+CREATE TABLE IF NOT EXISTS scoped_admin (
+    id bigint generated always as IDENTITY primary key not null,
+    employee_id bigint not null references employee (id) on delete cascade,
+    org_unit text not null,
+    create_at timestamptz default now(),
+    update_at timestamptz default now(),
+    CONSTRAINT scoped_admin_employee_org_unit_key UNIQUE (employee_id, org_unit)
+);
+
+-- роли, которые администратор отдела может назначать
+CREATE TABLE IF NOT EXISTS scoped_admin_role (
+    scoped_admin_id bigint not null references scoped_admin (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    primary key (scoped_admin_id, role_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE scoped_admin_role;
+
+DROP TABLE scoped_admin;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- замещение: на время отсутствия сотрудника delegator его роли и согласования передаются сотруднику deputy
+CREATE TABLE IF NOT EXISTS delegation (
+    id bigint generated always as IDENTITY primary key not null,
+    delegator_id bigint not null references employee (id) on delete cascade,
+    deputy_id bigint not null references employee (id) on delete cascade,
+    valid_from timestamptz not null,
+    valid_to timestamptz not null,
+    -- заместитель согласует заявки вместо отсутствующего сотрудника
+    approvals boolean not null default false,
+    reason text,
+    -- scheduled, active, ended, revoked
+    state text not null default 'scheduled',
+    actor text not null,
+    create_at timestamptz default now(),
+    update_at timestamptz default now(),
+    CONSTRAINT delegation_self_check CHECK (delegator_id <> deputy_id),
+    CONSTRAINT delegation_period_check CHECK (valid_from < valid_to)
+);
+
+CREATE INDEX IF NOT EXISTS delegation_deputy_id_idx ON delegation (deputy_id);
+CREATE INDEX IF NOT EXISTS delegation_delegator_id_idx ON delegation (delegator_id);
+-- замещения, которые планировщик должен начать или завершить
+CREATE INDEX IF NOT EXISTS delegation_pending_idx ON delegation (state, valid_from, valid_to) WHERE state IN ('scheduled', 'active');
+
+CREATE TABLE IF NOT EXISTS delegation_role (
+    delegation_id bigint not null references delegation (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    primary key (delegation_id, role_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE delegation_role;
+
+DROP TABLE delegation;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- экстренный доступ: сотрудник на время expires_at получает роль role_id, после чего второй администратор
+-- закрывает разбор инцидента
+CREATE TABLE IF NOT EXISTS break_glass_grant (
+    id bigint generated always as IDENTITY primary key not null,
+    employee_id bigint not null references employee (id) on delete cascade,
+    role_id bigint not null references role (id) on delete cascade,
+    reason text not null,
+    actor text not null,
+    expires_at timestamptz not null,
+    -- active, expired, revoked
+    state text not null default 'active',
+    end_at timestamptz,
+    -- open, closed
+    review_state text not null default 'open',
+    reviewed_by text,
+    review_comment text,
+    reviewed_at timestamptz,
+    create_at timestamptz default now()
+);
+
+-- одновременно у сотрудника может быть только один экстренный доступ к роли
+CREATE UNIQUE INDEX IF NOT EXISTS break_glass_grant_active_idx ON break_glass_grant (employee_id, role_id) WHERE state = 'active';
+CREATE INDEX IF NOT EXISTS break_glass_grant_expires_at_idx ON break_glass_grant (expires_at) WHERE state = 'active';
+CREATE INDEX IF NOT EXISTS break_glass_grant_review_idx ON break_glass_grant (create_at) WHERE review_state = 'open';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM employee_role WHERE source = 'break_glass';
+
+DROP TABLE break_glass_grant;
+-- +goose StatementEnd
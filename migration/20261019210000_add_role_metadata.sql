@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- описание роли для каталога: что роль даёт, кто за неё отвечает и насколько она опасна
+ALTER TABLE role ADD COLUMN IF NOT EXISTS description text not null default '';
+ALTER TABLE role ADD COLUMN IF NOT EXISTS owner_id bigint references employee (id) on delete set null;
+ALTER TABLE role ADD COLUMN IF NOT EXISTS risk_level text not null default 'low';
+ALTER TABLE role ADD CONSTRAINT role_risk_level_check CHECK (risk_level IN ('low', 'medium', 'high', 'critical'));
+-- роль можно запросить самостоятельно
+ALTER TABLE role ADD COLUMN IF NOT EXISTS requestable boolean not null default false;
+ALTER TABLE role ADD COLUMN IF NOT EXISTS tags text[] not null default '{}';
+-- роль во внешней системе, например ("sap", "R_4711")
+ALTER TABLE role ADD COLUMN IF NOT EXISTS external_system text;
+ALTER TABLE role ADD COLUMN IF NOT EXISTS external_id text;
+ALTER TABLE role ADD CONSTRAINT role_external_check CHECK (external_id IS NULL OR external_system IS NOT NULL);
+CREATE INDEX IF NOT EXISTS role_owner_id_idx ON role (owner_id);
+CREATE INDEX IF NOT EXISTS role_tags_idx ON role USING gin (tags);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS role_tags_idx;
+DROP INDEX IF EXISTS role_owner_id_idx;
+ALTER TABLE role DROP CONSTRAINT IF EXISTS role_external_check;
+ALTER TABLE role DROP COLUMN IF EXISTS external_id;
+ALTER TABLE role DROP COLUMN IF EXISTS external_system;
+ALTER TABLE role DROP COLUMN IF EXISTS tags;
+ALTER TABLE role DROP COLUMN IF EXISTS requestable;
+ALTER TABLE role DROP CONSTRAINT IF EXISTS role_risk_level_check;
+ALTER TABLE role DROP COLUMN IF EXISTS risk_level;
+ALTER TABLE role DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE role DROP COLUMN IF EXISTS description;
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- целевая система, в которой роли дают реальные права
+CREATE TABLE IF NOT EXISTS application (
+    id bigint generated always as IDENTITY primary key not null,
+    name text not null unique,
+    description text not null default '',
+    create_at timestamptz default now(),
+    update_at timestamptz default now()
+);
+
+-- право в целевой системе, например группа AD или полномочие SAP
+CREATE TABLE IF NOT EXISTS entitlement (
+    id bigint generated always as IDENTITY primary key not null,
+    application_id bigint not null references application (id) on delete cascade,
+    name text not null,
+    description text not null default '',
+    create_at timestamptz default now(),
+    update_at timestamptz default now(),
+    CONSTRAINT entitlement_application_name_key UNIQUE (application_id, name)
+);
+
+-- права, которые даёт роль, в том числе в разных системах
+CREATE TABLE IF NOT EXISTS role_entitlement (
+    role_id bigint not null references role (id) on delete cascade,
+    entitlement_id bigint not null references entitlement (id) on delete cascade,
+    primary key (role_id, entitlement_id)
+);
+CREATE INDEX IF NOT EXISTS role_entitlement_entitlement_id_idx ON role_entitlement (entitlement_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE role_entitlement;
+
+DROP TABLE entitlement;
+
+DROP TABLE application;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- очередь синхронизации учётных записей сотрудника в целевых системах
+CREATE TABLE IF NOT EXISTS provisioning_task (
+    id bigint generated always as IDENTITY primary key not null,
+    employee_id bigint not null references employee (id) on delete cascade,
+    -- assignment, lifecycle, manual
+    reason text not null,
+    -- pending, done, failed
+    state text not null default 'pending',
+    attempts int not null default 0,
+    last_error text,
+    next_attempt_at timestamptz not null default now(),
+    create_at timestamptz default now(),
+    update_at timestamptz default now()
+);
+
+-- у сотрудника может быть только одна ожидающая задача, повторные изменения её не дублируют
+CREATE UNIQUE INDEX IF NOT EXISTS provisioning_task_pending_idx ON provisioning_task (employee_id) WHERE state = 'pending';
+CREATE INDEX IF NOT EXISTS provisioning_task_next_attempt_at_idx ON provisioning_task (next_attempt_at) WHERE state = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE provisioning_task;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- расхождение между учётными записями целевой системы и тем, что ожидает IDM
+CREATE TABLE IF NOT EXISTS account_finding (
+    id bigint generated always as IDENTITY primary key not null,
+    application text not null,
+    account_id text not null,
+    -- сотрудник учётной записи по данным целевой системы или IDM, после adopt - владелец учётной записи
+    employee_id bigint,
+    -- orphan, missing, drift
+    type text not null,
+    missing_entitlements text[] not null default '{}',
+    extra_entitlements text[] not null default '{}',
+    -- open, ignored, adopted, revoked, resolved
+    state text not null default 'open',
+    resolved_by text,
+    comment text,
+    resolved_at timestamptz,
+    first_seen_at timestamptz not null default now(),
+    last_seen_at timestamptz not null default now()
+);
+
+-- одно действующее расхождение каждого типа на учётную запись; принятые (ignored, adopted) остаются действующими,
+-- чтобы следующая сверка не открывала их заново
+CREATE UNIQUE INDEX IF NOT EXISTS account_finding_active_idx ON account_finding (application, account_id, type)
+    WHERE state IN ('open', 'ignored', 'adopted');
+CREATE INDEX IF NOT EXISTS account_finding_state_idx ON account_finding (state, last_seen_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE account_finding;
+-- +goose StatementEnd
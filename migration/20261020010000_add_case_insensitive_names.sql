@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+-- имена, различающиеся только регистром, не переименовываются автоматически: миграция останавливается
+-- со списком конфликтующих записей, администратор исправляет их и повторяет миграцию
+DO $$
+DECLARE
+    conflicts text;
+BEGIN
+    SELECT string_agg(format('%s %s: %s', entity, lower_name, names), '; ' ORDER BY entity, lower_name)
+    INTO conflicts
+    FROM (
+        SELECT 'role' AS entity, lower(name) AS lower_name,
+               string_agg(format('"%s" (id %s)', name, id), ', ' ORDER BY id) AS names
+        FROM role GROUP BY lower(name) HAVING count(*) > 1
+        UNION ALL
+        SELECT 'employee', lower(name), string_agg(format('"%s" (id %s)', name, id), ', ' ORDER BY id)
+        FROM employee GROUP BY lower(name) HAVING count(*) > 1
+    ) c;
+    IF conflicts IS NOT NULL THEN
+        RAISE EXCEPTION 'names differ only in case, rename them before migration: %', conflicts;
+    END IF;
+END
+$$;
+
+-- имена ролей и сотрудников уникальны без учёта регистра
+ALTER TABLE role DROP CONSTRAINT IF EXISTS role_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS role_name_lower_idx ON role (lower(name));
+CREATE UNIQUE INDEX IF NOT EXISTS employee_name_lower_idx ON employee (lower(name));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS employee_name_lower_idx;
+DROP INDEX IF EXISTS role_name_lower_idx;
+ALTER TABLE role ADD CONSTRAINT role_name_key UNIQUE (name);
+-- +goose StatementEnd
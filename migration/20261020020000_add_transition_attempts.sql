@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- попытки выполнить запланированный переход: после ошибки переход откладывается до next_attempt_at,
+-- текст последней ошибки хранится в error
+ALTER TABLE employee_transition ADD COLUMN IF NOT EXISTS attempts int not null default 0;
+ALTER TABLE employee_transition ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE employee_transition DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE employee_transition DROP COLUMN IF EXISTS attempts;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- корзина, не использовавшаяся дольше периода своего лимита, снова полна и может быть удалена
+ALTER TABLE rate_limit_bucket ADD COLUMN expire_at timestamptz;
+UPDATE rate_limit_bucket SET expire_at = update_at + interval '1 hour';
+ALTER TABLE rate_limit_bucket ALTER COLUMN expire_at SET NOT NULL;
+CREATE INDEX rate_limit_bucket_expire_at_idx ON rate_limit_bucket (expire_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX rate_limit_bucket_expire_at_idx;
+ALTER TABLE rate_limit_bucket DROP COLUMN expire_at;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- сотрудника, отвечающего за роль, нельзя удалить: иначе роль с высоким риском осталась бы без владельца
+ALTER TABLE role DROP CONSTRAINT IF EXISTS role_owner_id_fkey;
+ALTER TABLE role ADD CONSTRAINT role_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES employee (id) ON DELETE RESTRICT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE role DROP CONSTRAINT IF EXISTS role_owner_id_fkey;
+ALTER TABLE role ADD CONSTRAINT role_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES employee (id) ON DELETE SET NULL;
+-- +goose StatementEnd
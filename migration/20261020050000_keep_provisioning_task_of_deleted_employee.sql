@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- задача провижининга остаётся после удаления сотрудника: по ней его учётные записи блокируются в целевых системах
+ALTER TABLE provisioning_task DROP CONSTRAINT IF EXISTS provisioning_task_employee_id_fkey;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM provisioning_task t WHERE NOT EXISTS(SELECT * FROM employee e WHERE e.id = t.employee_id);
+ALTER TABLE provisioning_task ADD CONSTRAINT provisioning_task_employee_id_fkey
+    FOREIGN KEY (employee_id) REFERENCES employee (id) ON DELETE CASCADE;
+-- +goose StatementEnd
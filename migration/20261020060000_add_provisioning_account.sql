@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- учётная запись целевой системы, принятая администратором как учётная запись сотрудника (adopt при сверке).
+-- Провижининг и сверка используют её вместо учётной записи по id сотрудника. Связь остаётся после удаления
+-- сотрудника, чтобы провижининг заблокировал его учётную запись
+CREATE TABLE IF NOT EXISTS provisioning_account (
+    application text not null,
+    account_id text not null,
+    employee_id bigint not null,
+    create_at timestamptz default now(),
+    primary key (application, account_id)
+);
+
+-- у сотрудника одна учётная запись в каждой целевой системе
+CREATE UNIQUE INDEX IF NOT EXISTS provisioning_account_employee_idx ON provisioning_account (application, employee_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE provisioning_account;
+-- +goose StatementEnd
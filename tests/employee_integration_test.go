@@ -93,13 +93,13 @@ func TestGetPageEmployee(t *testing.T) {
 		resp, err := app.Test(req)
 		a.Nil(err)
 		a.NotNil(resp)
-		a.Equal(http.StatusBadRequest, resp.StatusCode)
+		a.Equal(http.StatusUnprocessableEntity, resp.StatusCode)
 
-		var errResp map[string]interface{}
-		err = json.NewDecoder(resp.Body).Decode(&errResp)
+		var problem common.Problem
+		err = json.NewDecoder(resp.Body).Decode(&problem)
 		a.Nil(err)
-		a.NotNil(errResp)
-		a.Contains(errResp["error"], "invalid pageSize")
+		a.Equal(common.CodeValidationFailed, problem.Code)
+		a.Contains(problem.Detail, "PageSize")
 	})
 
 	t.Run("should use default PageNumber", func(t *testing.T) {
@@ -199,7 +199,7 @@ func initTestApp(db *sqlx.DB) *fiber.App {
 	employeeService := employee.NewService(employeeRepo, vld)
 
 	// Создаем сервер и контроллер
-	server := web.NewServer()
+	server := web.NewServer(logger)
 	server.GroupApi.Use(auth)
 	employeeController := employee.NewController(server, employeeService, logger)
 	employeeController.RegisterRoutes()
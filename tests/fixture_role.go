@@ -15,7 +15,8 @@ func NewFixtureRole(role *role.Repository) *FixtureRole {
 
 func (f *FixtureRole) Role(name string) int64 {
 	entity := role.Entity{
-		Name: name,
+		Name:      name,
+		RiskLevel: role.RiskLow,
 	}
 	newId, err := f.role.Create(context.Background(), entity)
 	if err != nil {
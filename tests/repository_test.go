@@ -18,10 +18,17 @@ func createTableEmployee(db *sqlx.DB) {
 	query := `CREATE TABLE IF NOT EXISTS  employee (
     id bigint generated always as IDENTITY primary key not null,
     name text not null,
+    external_id text unique,
+    org_unit text,
+    job_title text,
+    status text not null default 'active',
+    subject text unique,
+    manager_id bigint references employee (id) on delete set null,
     create_at timestamptz default now(),
     update_at timestamptz default now())`
 
 	db.MustExec(query)
+	db.MustExec("CREATE UNIQUE INDEX IF NOT EXISTS employee_name_lower_idx ON employee (lower(name))")
 }
 
 func clearDatabaseRole(db *sqlx.DB) {
@@ -32,6 +39,13 @@ func createTableRole(db *sqlx.DB) {
 	query := `CREATE TABLE IF NOT EXISTS  role (
     id bigint generated always as IDENTITY primary key not null,
     name text not null unique,
+    description text not null default '',
+    owner_id bigint,
+    risk_level text not null default 'low',
+    requestable boolean not null default false,
+    tags text[] not null default '{}',
+    external_system text,
+    external_id text,
     create_at timestamptz default now(),
     update_at timestamptz default now())`
 